@@ -20,11 +20,16 @@ import (
 	"charm.land/fantasy/providers/openrouter"
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
 	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/artifact"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/fact"
+	"github.com/charmbracelet/crush/internal/health"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/spending"
+	"github.com/charmbracelet/crush/internal/tokencount"
 )
 
 //go:embed templates/title.md
@@ -33,6 +38,9 @@ var titlePrompt []byte
 //go:embed templates/summary.md
 var summaryPrompt []byte
 
+//go:embed templates/plan.md
+var planPrompt []byte
+
 type SessionAgentCall struct {
 	SessionID        string
 	Prompt           string
@@ -44,6 +52,35 @@ type SessionAgentCall struct {
 	TopK             *int64
 	FrequencyPenalty *float64
 	PresencePenalty  *float64
+	// CompactToolSchemas sends abbreviated tool schemas once the full ones
+	// were sent to the session.
+	CompactToolSchemas bool
+	// PairToolResults repairs the session history so each tool call is
+	// immediately followed by its result.
+	PairToolResults bool
+	// ExtraSystemPrompt is appended to the system prompt for this call.
+	ExtraSystemPrompt string
+	// SkipPlanMode runs the call without plan mode even if the session is
+	// in plan mode, to carry out the plan the user approved.
+	SkipPlanMode bool
+	// ParallelToolCalls allows or forbids the model calling several tools
+	// in one step, nil leaves it to the provider.
+	ParallelToolCalls *bool
+	// Cache is what of the prompt the provider is asked to cache, nil for
+	// DefaultCachePolicy.
+	Cache *CachePolicy
+	// VetoStep inspects each finished step, returning an error stops the
+	// run with an error wrapping ErrStepVetoed.
+	VetoStep func(step fantasy.StepResult) error
+	// OnCompactionStart is called when the run is compacted, before the
+	// conversation is summarized.
+	OnCompactionStart func()
+	// OnCompactionFinish is called with the summary recorded in the session
+	// once the run was compacted, or the error compacting it.
+	OnCompactionFinish func(summary message.Message, err error)
+	// Callbacks are called as the run streams, once the agent handled what
+	// was streamed.
+	Callbacks StreamCallbacks
 }
 
 type SessionAgent interface {
@@ -56,7 +93,20 @@ type SessionAgent interface {
 	IsBusy() bool
 	QueuedPrompts(sessionID string) int
 	ClearQueue(sessionID string)
+	SetDryRun(sessionID string, dryRun bool)
+	IsDryRun(sessionID string) bool
+	SetPlanMode(sessionID string, planMode bool)
+	IsPlanMode(sessionID string) bool
+	// ConfirmStopPhrase lets the next prompt of a session stopped at a stop
+	// phrase go past it.
+	ConfirmStopPhrase(sessionID string)
 	Summarize(context.Context, string, fantasy.ProviderOptions) error
+	// TopicChanged reports whether the prompt starts another topic than the
+	// previous prompts of the session, always false without topic detection.
+	TopicChanged(ctx context.Context, sessionID, prompt string) (bool, error)
+	// StartNewTopic creates a session starting from a brief summary of the
+	// session, to go on with another topic.
+	StartNewTopic(ctx context.Context, sessionID string) (session.Session, error)
 	Model() Model
 }
 
@@ -64,6 +114,9 @@ type Model struct {
 	Model      fantasy.LanguageModel
 	CatwalkCfg catwalk.Model
 	ModelCfg   config.SelectedModel
+	// Tokens counts the tokens of prompts the way the model does, nil when
+	// the provider can't.
+	Tokens tokencount.Counter
 }
 
 type sessionAgent struct {
@@ -76,9 +129,33 @@ type sessionAgent struct {
 	messages             message.Service
 	disableAutoSummarize bool
 	isYolo               bool
+	planMode             bool
+	loopDetection        config.LoopDetection
+	health               health.Service
+	runSummary           bool
+	toolOutput           config.ToolOutput
+	stopPhrases          []string
+	spending             spending.Service
+	compaction           *CompactionStrategy
+	summarizers          map[SummaryKind]Summarizer
+	topics               *TopicDetection
+	facts                fact.Service
+	artifacts            artifact.Service
+	prefetchDir          string
+	toolConcurrency      int
+	toolTimeout          time.Duration
+	toolMiddleware       []ToolMiddleware
 
 	messageQueue   *csync.Map[string, []SessionAgentCall]
-	activeRequests *csync.Map[string, context.CancelFunc]
+	activeRequests *csync.Map[string, context.CancelCauseFunc]
+	dryRunSessions *csync.Map[string, bool]
+	// Sessions that toggled plan mode, the others follow planMode.
+	planSessions *csync.Map[string, bool]
+	// Stop phrase each session stopped at, until the user confirms going
+	// past it.
+	stoppedSessions *csync.Map[string, string]
+	// Fingerprint of the tools whose full schemas were sent to each session.
+	fullToolSchemas *csync.Map[string, string]
 }
 
 type SessionAgentOptions struct {
@@ -88,9 +165,43 @@ type SessionAgentOptions struct {
 	SystemPrompt         string
 	DisableAutoSummarize bool
 	IsYolo               bool
+	PlanMode             bool
 	Sessions             session.Service
 	Messages             message.Service
 	Tools                []fantasy.AgentTool
+	LoopDetection        config.LoopDetection
+	Health               health.Service
+	RunSummary           bool
+	ToolOutput           config.ToolOutput
+	StopPhrases          []string
+	Spending             spending.Service
+	// Compaction compacts runs approaching the context window instead of
+	// stopping them to summarize the session, nil to stop them.
+	Compaction *CompactionStrategy
+	// Summarizers makes the summaries of each kind, the model falling back
+	// to extractive summaries for the kinds left out.
+	Summarizers map[SummaryKind]Summarizer
+	// Topics detects prompts starting another topic than the one of their
+	// session, nil to never detect them.
+	Topics *TopicDetection
+	// Facts are the facts recorded during the session, given to the model
+	// with each step. Nil to leave them out.
+	Facts fact.Service
+	// Artifacts stores the audio of spoken responses, nil to only keep
+	// their transcript.
+	Artifacts artifact.Service
+	// PrefetchDir is the working directory of the view and grep calls
+	// prefetched while they stream, empty to not prefetch them.
+	PrefetchDir string
+	// ToolConcurrency bounds the tool calls of a step running at once, 0
+	// for no bound.
+	ToolConcurrency int
+	// ToolTimeout stops the tool calls running for longer, 0 for no
+	// timeout.
+	ToolTimeout time.Duration
+	// ToolMiddleware wraps the execution of the tools, the first one being
+	// the outermost.
+	ToolMiddleware []ToolMiddleware
 }
 
 func NewSessionAgent(
@@ -106,11 +217,42 @@ func NewSessionAgent(
 		disableAutoSummarize: opts.DisableAutoSummarize,
 		tools:                opts.Tools,
 		isYolo:               opts.IsYolo,
+		planMode:             opts.PlanMode,
+		loopDetection:        opts.LoopDetection,
+		health:               opts.Health,
+		runSummary:           opts.RunSummary,
+		toolOutput:           opts.ToolOutput,
+		stopPhrases:          opts.StopPhrases,
+		spending:             opts.Spending,
+		compaction:           opts.Compaction,
+		summarizers:          opts.Summarizers,
+		topics:               opts.Topics,
+		facts:                opts.Facts,
+		artifacts:            opts.Artifacts,
+		prefetchDir:          opts.PrefetchDir,
+		toolConcurrency:      opts.ToolConcurrency,
+		toolTimeout:          opts.ToolTimeout,
+		toolMiddleware:       opts.ToolMiddleware,
 		messageQueue:         csync.NewMap[string, []SessionAgentCall](),
-		activeRequests:       csync.NewMap[string, context.CancelFunc](),
+		activeRequests:       csync.NewMap[string, context.CancelCauseFunc](),
+		dryRunSessions:       csync.NewMap[string, bool](),
+		planSessions:         csync.NewMap[string, bool](),
+		stoppedSessions:      csync.NewMap[string, string](),
+		fullToolSchemas:      csync.NewMap[string, string](),
 	}
 }
 
+// runTools wraps the tools for a run, from the innermost: their results are
+// shortened to the output budget, identical read-only calls are served from
+// the cache of the run, the middleware is applied and the scheduler runs
+// them.
+func (a *sessionAgent) runTools(agentTools []fantasy.AgentTool, scheduler *toolScheduler) []fantasy.AgentTool {
+	agentTools = a.budgetTools(agentTools)
+	agentTools = dedupTools(agentTools)
+	agentTools = chainTools(agentTools, a.toolMiddleware)
+	return scheduleTools(agentTools, scheduler)
+}
+
 func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy.AgentResult, error) {
 	if call.Prompt == "" {
 		return nil, ErrEmptyPrompt
@@ -118,6 +260,9 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 	if call.SessionID == "" {
 		return nil, ErrSessionMissing
 	}
+	if call.MaxOutputTokens <= 0 {
+		call.MaxOutputTokens = a.largeModel.ModelCfg.MaxOutputTokens(a.largeModel.CatwalkCfg)
+	}
 
 	// Queue the message if busy
 	if a.IsSessionBusy(call.SessionID) {
@@ -130,16 +275,31 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		return nil, nil
 	}
 
+	cachePolicy := call.cachePolicy()
 	if len(a.tools) > 0 {
 		// add anthropic caching to the last tool
-		a.tools[len(a.tools)-1].SetProviderOptions(a.getCacheControlOptions())
+		toolOptions := fantasy.ProviderOptions{}
+		if cachePolicy.CacheSystemPrompt {
+			toolOptions = a.getCacheControlOptions()
+		}
+		a.tools[len(a.tools)-1].SetProviderOptions(toolOptions)
+	}
+	providerOptions := withParallelToolUse(call.ProviderOptions, call.ParallelToolCalls)
+	if cachePolicy.Enabled() {
+		providerOptions = withPromptCacheKey(providerOptions, call.SessionID)
 	}
 
-	agent := fantasy.NewAgent(
-		a.largeModel.Model,
-		fantasy.WithSystemPrompt(a.systemPrompt),
-		fantasy.WithTools(a.tools...),
-	)
+	systemPrompt := a.systemPrompt
+	if call.ExtraSystemPrompt != "" {
+		systemPrompt += "\n\n" + call.ExtraSystemPrompt
+	}
+	planning := !call.SkipPlanMode && a.IsPlanMode(call.SessionID)
+	if planning {
+		systemPrompt += "\n\n" + string(planPrompt)
+	}
+	if phrase, stopped := a.stoppedSessions.Take(call.SessionID); stopped {
+		systemPrompt += "\n\n" + stopPhraseDeclined(phrase)
+	}
 
 	sessionLock := sync.Mutex{}
 	currentSession, err := a.sessions.Get(ctx, call.SessionID)
@@ -152,9 +312,17 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		return nil, fmt.Errorf("failed to get session messages: %w", err)
 	}
 
+	scheduler := newToolScheduler(a.toolConcurrency, a.toolTimeout)
+	agent := fantasy.NewAgent(
+		a.largeModel.Model,
+		fantasy.WithSystemPrompt(systemPrompt),
+		fantasy.WithTools(a.runTools(a.toolsForCall(call.SessionID, msgs, call.CompactToolSchemas), scheduler)...),
+	)
+
 	var wg sync.WaitGroup
-	// Generate title if first message
-	if len(msgs) == 0 {
+	// Generate title if first message, sessions carrying the summary of
+	// another one start with it
+	if len(msgs) == 0 || (currentSession.MessageCount == 1 && msgs[0].IsSummaryMessage) {
 		wg.Go(func() {
 			sessionLock.Lock()
 			a.generateTitle(ctx, &currentSession, call.Prompt)
@@ -163,32 +331,58 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 	}
 
 	// Add the user message to the session
-	_, err = a.createUserMessage(ctx, call)
+	userMessage, err := a.createUserMessage(ctx, call)
 	if err != nil {
 		return nil, err
 	}
 
 	// add the session to the context
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, call.SessionID)
+	if planning || a.IsDryRun(call.SessionID) {
+		ctx = context.WithValue(ctx, tools.DryRunContextKey, true)
+	}
+	var prefetcher *tools.Prefetcher
+	if a.prefetchDir != "" {
+		prefetcher = tools.NewPrefetcher(a.prefetchDir)
+		defer prefetcher.Reset()
+		ctx = context.WithValue(ctx, tools.PrefetcherContextKey, prefetcher)
+	}
 
-	genCtx, cancel := context.WithCancel(ctx)
+	var stopSeqs *stopSequences
+	ctx, stopSeqs = withStopSequences(ctx, a.stopPhrases)
+	ctx = withParallelToolCalls(ctx, call.ParallelToolCalls)
+
+	genCtx, cancel := context.WithCancelCause(ctx)
 	a.activeRequests.Set(call.SessionID, cancel)
 
-	defer cancel()
+	defer cancel(nil)
 	defer a.activeRequests.Del(call.SessionID)
 
-	history, files := a.preparePrompt(msgs, call.Attachments...)
+	history := buildHistory(msgs, call.PairToolResults)
+	files := message.FileParts(call.Attachments)
 
 	startTime := time.Now()
 	a.eventPromptSent(call.SessionID)
 
 	var currentAssistant *message.Message
 	var shouldSummarize bool
-	result, err := agent.Stream(genCtx, fantasy.AgentStreamCall{
-		Prompt:           call.Prompt,
+	// Set when the run is stuck, the model then gets a last step without
+	// tools to wrap up.
+	var loop *LoopDetected
+	var wrapUp bool
+	// Set once the run was compacted.
+	var compacted *compaction
+	// fantasy ignores the errors of OnStepFinish, they are kept here to stop
+	// the run and returned once it's done.
+	var stepErr error
+	// Audio streamed by models that respond with speech, kept until the step
+	// finishes.
+	var currentAudio []byte
+	result, err := agent.Stream(genCtx, withCallbacks(genCtx, call.SessionID, fantasy.AgentStreamCall{
+		Prompt:           message.PromptText(call.Prompt, call.Attachments),
 		Files:            files,
 		Messages:         history,
-		ProviderOptions:  call.ProviderOptions,
+		ProviderOptions:  providerOptions,
 		MaxOutputTokens:  &call.MaxOutputTokens,
 		TopP:             call.TopP,
 		Temperature:      call.Temperature,
@@ -197,11 +391,8 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		FrequencyPenalty: call.FrequencyPenalty,
 		// Before each step create the new assistant message
 		PrepareStep: func(callContext context.Context, options fantasy.PrepareStepFunctionOptions) (_ context.Context, prepared fantasy.PrepareStepResult, err error) {
-			prepared.Messages = options.Messages
-			// reset all cached items
-			for i := range prepared.Messages {
-				prepared.Messages[i].ProviderOptions = nil
-			}
+			scheduler.startStep()
+			prepared.Messages = compacted.apply(options.Messages)
 
 			queuedCalls, _ := a.messageQueue.Get(call.SessionID)
 			a.messageQueue.Del(call.SessionID)
@@ -213,26 +404,53 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 				prepared.Messages = append(prepared.Messages, userMessage.ToAIMessage()...)
 			}
 
-			lastSystemRoleInx := 0
-			systemMessageUpdated := false
-			for i, msg := range prepared.Messages {
-				// only add cache control to the last message
-				if msg.Role == fantasy.MessageRoleSystem {
-					lastSystemRoleInx = i
-				} else if !systemMessageUpdated {
-					prepared.Messages[lastSystemRoleInx].ProviderOptions = a.getCacheControlOptions()
-					systemMessageUpdated = true
+			if a.needsCompaction(call.MaxOutputTokens, prepared.Messages) {
+				if call.OnCompactionStart != nil {
+					call.OnCompactionStart()
+				}
+				summary, msgs, usage, compactErr := a.compact(callContext, call, providerOptions, prepared.Messages)
+				if compactErr == nil {
+					compacted = &compaction{messages: msgs, replaced: len(options.Messages)}
+					prepared.Messages = msgs
+					sessionLock.Lock()
+					a.updateSessionUsage(callContext, a.largeModel, &currentSession, usage, nil)
+					currentSession.SummaryMessageID = summary.ID
+					currentSession.CompletionTokens = usage.OutputTokens
+					currentSession.PromptTokens = 0
+					_, compactErr = a.sessions.Save(callContext, currentSession)
+					sessionLock.Unlock()
+					// The summary replaces the turns the full tool schemas were sent with.
+					a.fullToolSchemas.Del(call.SessionID)
 				}
-				// than add cache control to the last 2 messages
-				if i > len(prepared.Messages)-3 {
-					prepared.Messages[i].ProviderOptions = a.getCacheControlOptions()
+				if compactErr != nil {
+					// The context window check below fails the run if it
+					// doesn't fit without.
+					slog.Warn("Failed to compact run", "session_id", call.SessionID, "error", compactErr)
+				}
+				if call.OnCompactionFinish != nil {
+					call.OnCompactionFinish(summary, compactErr)
 				}
 			}
 
+			applyCachePolicy(prepared.Messages, cachePolicy, a.getCacheControlOptions())
+
+			prepared.Messages = a.withFacts(callContext, call.SessionID, prepared.Messages)
+
 			if a.systemPromptPrefix != "" {
 				prepared.Messages = append([]fantasy.Message{fantasy.NewSystemMessage(a.systemPromptPrefix)}, prepared.Messages...)
 			}
 
+			if loop != nil {
+				prepared.Messages = append(prepared.Messages, fantasy.NewUserMessage(loop.Guidance()))
+				prepared.DisableAllTools = true
+				wrapUp = true
+			}
+
+			// Fail early instead of letting the provider reject the request.
+			if err = checkContextWindow(callContext, a.largeModel, call.MaxOutputTokens, prepared.Messages, a.tools); err != nil {
+				return callContext, prepared, err
+			}
+
 			var assistantMsg message.Message
 			assistantMsg, err = a.messages.Create(callContext, call.SessionID, message.CreateMessageParams{
 				Role:     message.Assistant,
@@ -277,6 +495,28 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 		},
 		OnTextDelta: func(id string, text string) error {
 			currentAssistant.AppendContent(text)
+			if err := a.messages.Update(genCtx, *currentAssistant); err != nil {
+				return err
+			}
+			if phrase := matchStopPhrase(currentAssistant.Content().Text, text, a.stopPhrases); phrase != "" {
+				return &StopPhraseError{Phrase: phrase}
+			}
+			return nil
+		},
+		OnChunk: func(part fantasy.StreamPart) error {
+			chunk, ok := audioChunkFromPart(part)
+			if !ok {
+				return nil
+			}
+			data, err := chunk.Audio()
+			if err != nil {
+				return err
+			}
+			currentAudio = append(currentAudio, data...)
+			if chunk.Transcript == "" {
+				return nil
+			}
+			currentAssistant.AppendContent(chunk.Transcript)
 			return a.messages.Update(genCtx, *currentAssistant)
 		},
 		OnToolInputStart: func(id string, toolName string) error {
@@ -287,10 +527,21 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 				Finished:         false,
 			}
 			currentAssistant.AddToolCall(toolCall)
+			if prefetcher != nil {
+				prefetcher.Start(id, toolName)
+			}
+			return a.messages.Update(genCtx, *currentAssistant)
+		},
+		OnToolInputDelta: func(id string, delta string) error {
+			if prefetcher != nil {
+				prefetcher.Input(id, delta)
+			}
+			// Partial input lets the UI preview the call before it's complete.
+			currentAssistant.AppendToolCallInput(id, delta)
 			return a.messages.Update(genCtx, *currentAssistant)
 		},
 		OnRetry: func(err *fantasy.APICallError, delay time.Duration) {
-			// TODO: implement
+			a.recordProviderHealth(err)
 		},
 		OnToolCall: func(tc fantasy.ToolCallContent) error {
 			toolCall := message.ToolCall{
@@ -300,44 +551,51 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 				ProviderExecuted: false,
 				Finished:         true,
 			}
+			scheduler.add(tc.ToolCallID)
 			currentAssistant.AddToolCall(toolCall)
 			return a.messages.Update(genCtx, *currentAssistant)
 		},
 		OnToolResult: func(result fantasy.ToolResultContent) error {
-			var resultContent string
-			isError := false
-			switch result.Result.GetType() {
-			case fantasy.ToolResultContentTypeText:
-				r, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentText](result.Result)
-				if ok {
-					resultContent = r.Text
+			// Stored in the order of the calls, whatever the order they end in.
+			return scheduler.deliver(genCtx, result.ToolCallID, func() error {
+				if prefetcher != nil {
+					prefetcher.Done(result.ToolCallID, result.ToolName)
 				}
-			case fantasy.ToolResultContentTypeError:
-				r, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentError](result.Result)
-				if ok {
-					isError = true
-					resultContent = r.Error.Error()
+				var resultContent string
+				isError := false
+				switch result.Result.GetType() {
+				case fantasy.ToolResultContentTypeText:
+					r, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentText](result.Result)
+					if ok {
+						resultContent = r.Text
+					}
+				case fantasy.ToolResultContentTypeError:
+					r, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentError](result.Result)
+					if ok {
+						isError = true
+						resultContent = r.Error.Error()
+					}
+				case fantasy.ToolResultContentTypeMedia:
+					// TODO: handle this message type
 				}
-			case fantasy.ToolResultContentTypeMedia:
-				// TODO: handle this message type
-			}
-			toolResult := message.ToolResult{
-				ToolCallID: result.ToolCallID,
-				Name:       result.ToolName,
-				Content:    resultContent,
-				IsError:    isError,
-				Metadata:   result.ClientMetadata,
-			}
-			_, createMsgErr := a.messages.Create(genCtx, currentAssistant.SessionID, message.CreateMessageParams{
-				Role: message.Tool,
-				Parts: []message.ContentPart{
-					toolResult,
-				},
+				toolResult := message.ToolResult{
+					ToolCallID: result.ToolCallID,
+					Name:       result.ToolName,
+					Content:    resultContent,
+					IsError:    isError,
+					Metadata:   result.ClientMetadata,
+				}
+				_, createMsgErr := a.messages.Create(genCtx, currentAssistant.SessionID, message.CreateMessageParams{
+					Role: message.Tool,
+					Parts: []message.ContentPart{
+						toolResult,
+					},
+				})
+				if createMsgErr != nil {
+					return createMsgErr
+				}
+				return nil
 			})
-			if createMsgErr != nil {
-				return createMsgErr
-			}
-			return nil
 		},
 		OnStepFinish: func(stepResult fantasy.StepResult) error {
 			finishReason := message.FinishReasonUnknown
@@ -349,17 +607,57 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 			case fantasy.FinishReasonToolCalls:
 				finishReason = message.FinishReasonToolUse
 			}
-			currentAssistant.AddFinish(finishReason, "", "")
-			a.updateSessionUsage(a.largeModel, &currentSession, stepResult.Usage, a.openrouterCost(stepResult.ProviderMetadata))
+			if len(currentAudio) > 0 {
+				if err := a.saveAudio(genCtx, *currentAssistant, currentAudio); err != nil {
+					slog.Warn("Failed to save audio response", "session_id", call.SessionID, "error", err)
+				}
+				currentAudio = nil
+			}
+			if tokens := logprobs(stepResult.ProviderMetadata); len(tokens) > 0 {
+				currentAssistant.AddLogprobs(tokens)
+			}
+			if seams := resumeSeams(stepResult.ProviderMetadata); len(seams) > 0 {
+				currentAssistant.AddResumeSeams(seams)
+			}
+			for _, warning := range stepResult.Warnings {
+				slog.Warn("Model call warning", "session_id", call.SessionID, "type", warning.Type, "message", warning.Message)
+			}
+			switch {
+			case wrapUp:
+				currentAssistant.AddFinish(message.FinishReasonLoopDetected, "Loop detected", loop.Detail)
+			case stepResult.FinishReason == fantasy.FinishReasonContentFilter:
+				currentAssistant.AddFinish(message.FinishReasonError, "Response blocked", warningMessages(stepResult.Warnings))
+			default:
+				currentAssistant.AddFinish(finishReason, "", "")
+			}
+			a.updateSessionUsage(genCtx, a.largeModel, &currentSession, stepResult.Usage, a.openrouterCost(stepResult.ProviderMetadata))
 			sessionLock.Lock()
 			_, sessionErr := a.sessions.Save(genCtx, currentSession)
 			sessionLock.Unlock()
 			if sessionErr != nil {
-				return sessionErr
+				stepErr = sessionErr
+				return stepErr
 			}
-			return a.messages.Update(genCtx, *currentAssistant)
+			if updateErr := a.messages.Update(genCtx, *currentAssistant); updateErr != nil {
+				stepErr = updateErr
+				return stepErr
+			}
+			if phrase := stopSeqs.match(); phrase != "" {
+				stepErr = &StopPhraseError{Phrase: phrase}
+				return stepErr
+			}
+			if call.VetoStep != nil {
+				if vetoErr := call.VetoStep(stepResult); vetoErr != nil {
+					stepErr = fmt.Errorf("%w: %w", ErrStepVetoed, vetoErr)
+					return stepErr
+				}
+			}
+			return nil
 		},
 		StopWhen: []fantasy.StopCondition{
+			func(_ []fantasy.StepResult) bool {
+				return stepErr != nil
+			},
 			func(_ []fantasy.StepResult) bool {
 				cw := int64(a.largeModel.CatwalkCfg.ContextWindow)
 				tokens := currentSession.CompletionTokens + currentSession.PromptTokens
@@ -370,19 +668,37 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 				} else {
 					threshold = int64(float64(cw) * 0.2)
 				}
-				if (remaining <= threshold) && !a.disableAutoSummarize {
+				// Compacted runs go on instead.
+				if (remaining <= threshold) && !a.disableAutoSummarize && a.compaction == nil {
 					shouldSummarize = true
 					return true
 				}
 				return false
 			},
+			func(steps []fantasy.StepResult) bool {
+				if wrapUp {
+					return true
+				}
+				if loop == nil {
+					loop = detectLoop(steps, a.loopDetection)
+					if loop != nil {
+						slog.Warn("Agent loop detected", "session_id", call.SessionID, "reason", loop.Reason, "steps", loop.Steps, "detail", loop.Detail)
+					}
+				}
+				return false
+			},
 		},
-	})
+	}, call.Callbacks, &stepErr))
 
 	a.eventPromptResponded(call.SessionID, time.Since(startTime).Truncate(time.Second))
 
+	if err == nil && stepErr != nil {
+		err = stepErr
+	}
+	a.recordProviderHealth(err)
 	if err != nil {
-		isCancelErr := errors.Is(err, context.Canceled)
+		reason := cancelReason(genCtx, err)
+		isCancelErr := reason != ""
 		isPermissionErr := errors.Is(err, permission.ErrorPermissionDenied)
 		if currentAssistant == nil {
 			return result, err
@@ -424,8 +740,10 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 				continue
 			}
 			content := "There was an error while executing the tool"
-			if isCancelErr {
+			if reason == message.CancelReasonUser {
 				content = "Tool execution canceled by user"
+			} else if isCancelErr {
+				content = "Tool execution canceled: " + strings.ToLower(reason.Description())
 			} else if isPermissionErr {
 				content = "Permission denied"
 			}
@@ -445,10 +763,17 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 				return nil, createErr
 			}
 		}
-		if isCancelErr {
-			currentAssistant.AddFinish(message.FinishReasonCanceled, "Request cancelled", "")
+		if reason == message.CancelReasonPolicy {
+			currentAssistant.AddCancelFinish(reason, err.Error())
+		} else if isCancelErr {
+			currentAssistant.AddCancelFinish(reason, "")
 		} else if isPermissionErr {
 			currentAssistant.AddFinish(message.FinishReasonPermissionDenied, "Permission denied", "")
+		} else if overflowErr := (*ContextOverflowError)(nil); errors.As(err, &overflowErr) {
+			currentAssistant.AddFinish(message.FinishReasonError, "Context window exceeded", err.Error())
+		} else if stopErr := (*StopPhraseError)(nil); errors.As(err, &stopErr) {
+			a.stoppedSessions.Set(call.SessionID, stopErr.Phrase)
+			currentAssistant.AddFinish(message.FinishReasonStopPhrase, "Stop phrase", fmt.Sprintf("Stopped at %q, waiting for you to confirm going past it", stopErr.Phrase))
 		} else {
 			currentAssistant.AddFinish(message.FinishReasonError, "API Error", err.Error())
 		}
@@ -461,6 +786,10 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 	}
 	wg.Wait()
 
+	if a.runSummary && len(result.Steps) > 1 {
+		a.addRunSummary(ctx, userMessage, currentAssistant)
+	}
+
 	if shouldSummarize {
 		a.activeRequests.Del(call.SessionID)
 		if summarizeErr := a.Summarize(genCtx, call.SessionID, call.ProviderOptions); summarizeErr != nil {
@@ -480,7 +809,7 @@ func (a *sessionAgent) Run(ctx context.Context, call SessionAgentCall) (*fantasy
 
 	// release active request before processing queued messages
 	a.activeRequests.Del(call.SessionID)
-	cancel()
+	cancel(nil)
 
 	queuedMessages, ok := a.messageQueue.Get(call.SessionID)
 	if !ok || len(queuedMessages) == 0 {
@@ -510,16 +839,15 @@ func (a *sessionAgent) Summarize(ctx context.Context, sessionID string, opts fan
 		return nil
 	}
 
-	aiMsgs, _ := a.preparePrompt(msgs)
+	// The history is always repaired, a summary is not worth failing over
+	// tool results the provider may reject.
+	aiMsgs := buildHistory(msgs, true)
 
-	genCtx, cancel := context.WithCancel(ctx)
+	genCtx, cancel := context.WithCancelCause(ctx)
 	a.activeRequests.Set(sessionID, cancel)
 	defer a.activeRequests.Del(sessionID)
-	defer cancel()
+	defer cancel(nil)
 
-	agent := fantasy.NewAgent(a.largeModel.Model,
-		fantasy.WithSystemPrompt(string(summaryPrompt)),
-	)
 	summaryMessage, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
 		Role:             message.Assistant,
 		Model:            a.largeModel.Model.Model(),
@@ -530,25 +858,11 @@ func (a *sessionAgent) Summarize(ctx context.Context, sessionID string, opts fan
 		return err
 	}
 
-	resp, err := agent.Stream(genCtx, fantasy.AgentStreamCall{
-		Prompt:          "Provide a detailed summary of our conversation above.",
+	summary, err := a.summarizer(SummarySession).Summarize(genCtx, SummaryRequest{
+		Kind:            SummarySession,
 		Messages:        aiMsgs,
 		ProviderOptions: opts,
-		OnReasoningDelta: func(id string, text string) error {
-			summaryMessage.AppendReasoningContent(text)
-			return a.messages.Update(genCtx, summaryMessage)
-		},
-		OnReasoningEnd: func(id string, reasoning fantasy.ReasoningContent) error {
-			// handle anthropic signature
-			if anthropicData, ok := reasoning.ProviderMetadata["anthropic"]; ok {
-				if signature, ok := anthropicData.(*anthropic.ReasoningOptionMetadata); ok && signature.Signature != "" {
-					summaryMessage.AppendReasoningSignature(signature.Signature)
-				}
-			}
-			summaryMessage.FinishThinking()
-			return a.messages.Update(genCtx, summaryMessage)
-		},
-		OnTextDelta: func(id, text string) error {
+		OnTextDelta: func(text string) error {
 			summaryMessage.AppendContent(text)
 			return a.messages.Update(genCtx, summaryMessage)
 		},
@@ -563,35 +877,42 @@ func (a *sessionAgent) Summarize(ctx context.Context, sessionID string, opts fan
 		return err
 	}
 
+	// What was streamed before a summarizer failed is replaced.
+	summaryMessage.Parts = []message.ContentPart{message.TextContent{Text: summary.Text}}
 	summaryMessage.AddFinish(message.FinishReasonEndTurn, "", "")
 	err = a.messages.Update(genCtx, summaryMessage)
 	if err != nil {
 		return err
 	}
 
-	var openrouterCost *float64
-	for _, step := range resp.Steps {
-		stepCost := a.openrouterCost(step.ProviderMetadata)
-		if stepCost != nil {
-			newCost := *stepCost
-			if openrouterCost != nil {
-				newCost += *openrouterCost
-			}
-			openrouterCost = &newCost
-		}
-	}
-
-	a.updateSessionUsage(a.largeModel, &currentSession, resp.TotalUsage, openrouterCost)
+	a.updateSessionUsage(genCtx, a.largeModel, &currentSession, summary.Usage, a.openrouterCost(summary.ProviderMetadata))
 
-	// just in case get just the last usage
-	usage := resp.Response.Usage
 	currentSession.SummaryMessageID = summaryMessage.ID
-	currentSession.CompletionTokens = usage.OutputTokens
+	currentSession.CompletionTokens = summary.Usage.OutputTokens
 	currentSession.PromptTokens = 0
 	_, err = a.sessions.Save(genCtx, currentSession)
+	// The summary replaces the turns the full tool schemas were sent with.
+	a.fullToolSchemas.Del(sessionID)
 	return err
 }
 
+// buildHistory converts the session messages to the messages sent to the
+// model. The pairing of tool calls and results is repaired if asked to, and
+// otherwise when the history holds results the providers would reject.
+func buildHistory(msgs []message.Message, pairToolResults bool) []fantasy.Message {
+	history := message.NewPromptBuilder().History(msgs...).Messages()
+	if !pairToolResults && message.ValidatePrompt(history) == nil {
+		return history
+	}
+	// Sessions stored by older versions may hold results without a call or
+	// answering a call twice, better sent repaired than not at all.
+	history, repairs := message.RepairToolResults(history)
+	for _, repair := range repairs {
+		slog.Warn("Repaired session history", "repair", repair)
+	}
+	return history
+}
+
 func (a *sessionAgent) getCacheControlOptions() fantasy.ProviderOptions {
 	if t, _ := strconv.ParseBool(os.Getenv("CRUSH_DISABLE_ANTHROPIC_CACHE")); t {
 		return fantasy.ProviderOptions{}
@@ -623,31 +944,6 @@ func (a *sessionAgent) createUserMessage(ctx context.Context, call SessionAgentC
 	return msg, nil
 }
 
-func (a *sessionAgent) preparePrompt(msgs []message.Message, attachments ...message.Attachment) ([]fantasy.Message, []fantasy.FilePart) {
-	var history []fantasy.Message
-	for _, m := range msgs {
-		if len(m.Parts) == 0 {
-			continue
-		}
-		// Assistant message without content or tool calls (cancelled before it returned anything)
-		if m.Role == message.Assistant && len(m.ToolCalls()) == 0 && m.Content().Text == "" && m.ReasoningContent().String() == "" {
-			continue
-		}
-		history = append(history, m.ToAIMessage()...)
-	}
-
-	var files []fantasy.FilePart
-	for _, attachment := range attachments {
-		files = append(files, fantasy.FilePart{
-			Filename:  attachment.FileName,
-			Data:      attachment.Content,
-			MediaType: attachment.MimeType,
-		})
-	}
-
-	return history, files
-}
-
 func (a *sessionAgent) getSessionMessages(ctx context.Context, session session.Session) ([]message.Message, error) {
 	msgs, err := a.messages.List(ctx, session.ID)
 	if err != nil {
@@ -722,7 +1018,7 @@ func (a *sessionAgent) generateTitle(ctx context.Context, session *session.Sessi
 		}
 	}
 
-	a.updateSessionUsage(a.smallModel, session, resp.TotalUsage, openrouterCost)
+	a.updateSessionUsage(ctx, a.smallModel, session, resp.TotalUsage, openrouterCost)
 	_, saveErr := a.sessions.Save(ctx, *session)
 	if saveErr != nil {
 		slog.Error("failed to save session title & usage", "error", saveErr)
@@ -743,7 +1039,26 @@ func (a *sessionAgent) openrouterCost(metadata fantasy.ProviderMetadata) *float6
 	return &opts.Usage.Cost
 }
 
-func (a *sessionAgent) updateSessionUsage(model Model, session *session.Session, usage fantasy.Usage, overrideCost *float64) {
+// logprobs returns the log probabilities of the tokens of a step, which
+// OpenAI and compatible providers return when asked with the log_probs
+// provider option.
+func logprobs(metadata fantasy.ProviderMetadata) []message.TokenLogprob {
+	openaiMetadata, ok := metadata[openai.Name].(*openai.ProviderMetadata)
+	if !ok {
+		return nil
+	}
+	tokens := make([]message.TokenLogprob, 0, len(openaiMetadata.Logprobs))
+	for _, logprob := range openaiMetadata.Logprobs {
+		token := message.TokenLogprob{Token: logprob.Token, Logprob: logprob.Logprob}
+		for _, top := range logprob.TopLogprobs {
+			token.TopLogprobs = append(token.TopLogprobs, message.TopLogprob{Token: top.Token, Logprob: top.Logprob})
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func (a *sessionAgent) updateSessionUsage(ctx context.Context, model Model, session *session.Session, usage fantasy.Usage, overrideCost *float64) {
 	modelConfig := model.CatwalkCfg
 	cost := modelConfig.CostPer1MInCached/1e6*float64(usage.CacheCreationTokens) +
 		modelConfig.CostPer1MOutCached/1e6*float64(usage.CacheReadTokens) +
@@ -753,9 +1068,19 @@ func (a *sessionAgent) updateSessionUsage(model Model, session *session.Session,
 	a.eventTokensUsed(session.ID, model, usage, cost)
 
 	if overrideCost != nil {
-		session.Cost += *overrideCost
-	} else {
-		session.Cost += cost
+		cost = *overrideCost
+	}
+	session.Cost += cost
+	if a.spending != nil && cost > 0 {
+		_, err := a.spending.Record(context.WithoutCancel(ctx), spending.Record{
+			SessionID: session.ID,
+			Provider:  model.ModelCfg.Provider,
+			Model:     model.ModelCfg.Model,
+			Cost:      cost,
+		})
+		if err != nil {
+			slog.Error("failed to record spending", "error", err)
+		}
 	}
 
 	session.CompletionTokens = usage.OutputTokens + usage.CacheReadTokens
@@ -763,16 +1088,21 @@ func (a *sessionAgent) updateSessionUsage(model Model, session *session.Session,
 }
 
 func (a *sessionAgent) Cancel(sessionID string) {
+	a.cancel(sessionID, message.CancelReasonUser)
+}
+
+func (a *sessionAgent) cancel(sessionID string, reason message.CancelReason) {
+	cause := &CancelError{Reason: reason}
 	// Cancel regular requests
 	if cancel, ok := a.activeRequests.Take(sessionID); ok && cancel != nil {
-		slog.Info("Request cancellation initiated", "session_id", sessionID)
-		cancel()
+		slog.Info("Request cancellation initiated", "session_id", sessionID, "reason", reason)
+		cancel(cause)
 	}
 
 	// Also check for summarize requests
 	if cancel, ok := a.activeRequests.Take(sessionID + "-summarize"); ok && cancel != nil {
-		slog.Info("Summarize cancellation initiated", "session_id", sessionID)
-		cancel()
+		slog.Info("Summarize cancellation initiated", "session_id", sessionID, "reason", reason)
+		cancel(cause)
 	}
 
 	if a.QueuedPrompts(sessionID) > 0 {
@@ -793,7 +1123,7 @@ func (a *sessionAgent) CancelAll() {
 		return
 	}
 	for key := range a.activeRequests.Seq2() {
-		a.Cancel(key) // key is sessionID
+		a.cancel(key, message.CancelReasonShutdown) // key is sessionID
 	}
 
 	timeout := time.After(5 * time.Second)
@@ -831,6 +1161,38 @@ func (a *sessionAgent) QueuedPrompts(sessionID string) int {
 	return len(l)
 }
 
+// SetDryRun toggles dry-run mode for a session. While enabled, mutation tools
+// describe what they would do instead of doing it.
+func (a *sessionAgent) SetDryRun(sessionID string, dryRun bool) {
+	if dryRun {
+		a.dryRunSessions.Set(sessionID, true)
+		return
+	}
+	a.dryRunSessions.Del(sessionID)
+}
+
+func (a *sessionAgent) IsDryRun(sessionID string) bool {
+	dryRun, _ := a.dryRunSessions.Get(sessionID)
+	return dryRun
+}
+
+// SetPlanMode toggles plan mode for a session. While enabled, the agent only
+// produces a plan for the user to approve, without making changes.
+func (a *sessionAgent) SetPlanMode(sessionID string, planMode bool) {
+	a.planSessions.Set(sessionID, planMode)
+}
+
+func (a *sessionAgent) ConfirmStopPhrase(sessionID string) {
+	a.stoppedSessions.Del(sessionID)
+}
+
+func (a *sessionAgent) IsPlanMode(sessionID string) bool {
+	if planMode, ok := a.planSessions.Get(sessionID); ok {
+		return planMode
+	}
+	return a.planMode
+}
+
 func (a *sessionAgent) SetModels(large Model, small Model) {
 	a.largeModel = large
 	a.smallModel = small
@@ -843,3 +1205,18 @@ func (a *sessionAgent) SetTools(tools []fantasy.AgentTool) {
 func (a *sessionAgent) Model() Model {
 	return a.largeModel
 }
+
+// recordProviderHealth tells the health service how the last request to the
+// provider went, so repeated failures get checked against its status page.
+func (a *sessionAgent) recordProviderHealth(err error) {
+	if a.health == nil {
+		return
+	}
+	provider := a.largeModel.ModelCfg.Provider
+	switch {
+	case err == nil:
+		a.health.RecordSuccess(provider)
+	case health.IsProviderFailure(err):
+		a.health.RecordFailure(provider)
+	}
+}
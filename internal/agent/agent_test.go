@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -8,9 +9,11 @@ import (
 	"testing"
 
 	"charm.land/fantasy"
+	"charm.land/fantasy/providers/openai"
 	"github.com/charmbracelet/crush/internal/agent/tools"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/shell"
+	openaisdk "github.com/openai/openai-go/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/dnaeon/go-vcr.v4/pkg/recorder"
@@ -617,3 +620,138 @@ func TestCoderAgent(t *testing.T) {
 		})
 	}
 }
+
+func TestSessionAgentPlanMode(t *testing.T) {
+	t.Parallel()
+
+	agent := NewSessionAgent(SessionAgentOptions{PlanMode: true})
+	require.True(t, agent.IsPlanMode("a"))
+
+	agent.SetPlanMode("a", false)
+	require.False(t, agent.IsPlanMode("a"))
+	require.True(t, agent.IsPlanMode("b"))
+
+	agent = NewSessionAgent(SessionAgentOptions{})
+	require.False(t, agent.IsPlanMode("a"))
+	agent.SetPlanMode("a", true)
+	require.True(t, agent.IsPlanMode("a"))
+}
+
+func TestSessionAgentApprovedPlan(t *testing.T) {
+	env := testEnv(t)
+	large := &scriptedModel{}
+	agent := testSessionAgent(env, large, &scriptedModel{}, "system")
+
+	session, err := env.sessions.Create(t.Context(), "New Session")
+	require.NoError(t, err)
+	agent.SetPlanMode(session.ID, true)
+	system := func(prompt fantasy.Prompt) string {
+		return prompt[0].Content[0].(fantasy.TextPart).Text
+	}
+
+	_, err = agent.Run(t.Context(), SessionAgentCall{Prompt: "Plan it", SessionID: session.ID, MaxOutputTokens: 1000})
+	require.NoError(t, err)
+	require.Contains(t, system(large.prompts[0]), string(planPrompt))
+
+	_, err = agent.Run(t.Context(), SessionAgentCall{Prompt: "Carry it out", SessionID: session.ID, MaxOutputTokens: 1000, SkipPlanMode: true})
+	require.NoError(t, err)
+	require.NotContains(t, system(large.prompts[1]), string(planPrompt))
+	require.True(t, agent.IsPlanMode(session.ID), "only the approved run skips plan mode")
+}
+
+func TestSessionAgentVetoStep(t *testing.T) {
+	env := testEnv(t)
+	var greps int
+	large := &scriptedModel{steps: [][]fantasy.StreamPart{
+		toolCallStep("call-1", tools.GrepToolName, `{"pattern": "foo", "path": "."}`),
+		toolCallStep("call-2", tools.GrepToolName, `{"pattern": "bar", "path": "."}`),
+	}}
+	agent := testSessionAgent(env, large, &scriptedModel{}, "system", newCountingTool(tools.GrepToolName, &greps))
+
+	session, err := env.sessions.Create(t.Context(), "New Session")
+	require.NoError(t, err)
+
+	var vetoed []fantasy.StepResult
+	_, err = agent.Run(t.Context(), SessionAgentCall{
+		Prompt:          "Search",
+		SessionID:       session.ID,
+		MaxOutputTokens: 1000,
+		VetoStep: func(step fantasy.StepResult) error {
+			vetoed = append(vetoed, step)
+			return errors.New("too many searches")
+		},
+	})
+	require.ErrorIs(t, err, ErrStepVetoed)
+	require.ErrorContains(t, err, "too many searches")
+	require.Len(t, vetoed, 1)
+	require.Equal(t, 1, large.Calls(), "no step runs after the veto")
+	require.Equal(t, 1, greps)
+
+	msgs, err := env.messages.List(t.Context(), session.ID)
+	require.NoError(t, err)
+	var assistant *message.Message
+	for _, msg := range msgs {
+		if msg.Role == message.Assistant {
+			assistant = &msg
+		}
+	}
+	require.NotNil(t, assistant)
+	require.Equal(t, message.FinishReasonCanceled, assistant.FinishReason())
+	require.Equal(t, message.CancelReasonPolicy, assistant.FinishPart().CancelReason)
+	require.Contains(t, assistant.FinishPart().Details, "step vetoed")
+}
+
+func TestSessionAgentLogprobs(t *testing.T) {
+	env := testEnv(t)
+	large := &scriptedModel{steps: [][]fantasy.StreamPart{{
+		{Type: fantasy.StreamPartTypeTextStart, ID: "text"},
+		{Type: fantasy.StreamPartTypeTextDelta, ID: "text", Delta: "Hi there"},
+		{Type: fantasy.StreamPartTypeTextEnd, ID: "text"},
+		{
+			Type:         fantasy.StreamPartTypeFinish,
+			FinishReason: fantasy.FinishReasonStop,
+			ProviderMetadata: fantasy.ProviderMetadata{openai.Name: &openai.ProviderMetadata{
+				Logprobs: []openaisdk.ChatCompletionTokenLogprob{
+					{Token: "Hi", Logprob: -0.01, TopLogprobs: []openaisdk.ChatCompletionTokenLogprobTopLogprob{{Token: "Hi", Logprob: -0.01}, {Token: "Hello", Logprob: -4.6}}},
+					{Token: " there", Logprob: -1.2},
+				},
+			}},
+		},
+	}}}
+	agent := testSessionAgent(env, large, &scriptedModel{}, "system")
+
+	session, err := env.sessions.Create(t.Context(), "New Session")
+	require.NoError(t, err)
+	_, err = agent.Run(t.Context(), SessionAgentCall{Prompt: "Hello", SessionID: session.ID, MaxOutputTokens: 1000})
+	require.NoError(t, err)
+
+	msgs, err := env.messages.List(t.Context(), session.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	require.Equal(t, []message.TokenLogprob{
+		{Token: "Hi", Logprob: -0.01, TopLogprobs: []message.TopLogprob{{Token: "Hi", Logprob: -0.01}, {Token: "Hello", Logprob: -4.6}}},
+		{Token: " there", Logprob: -1.2},
+	}, msgs[1].Logprobs())
+	require.Nil(t, msgs[0].Logprobs())
+}
+
+func TestBuildHistoryRepairsLegacySessions(t *testing.T) {
+	t.Parallel()
+
+	msgs := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "List the files"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.ToolCall{ID: "call", Name: "ls", Input: "{}", Finished: true}}},
+		{Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "call", Name: "ls", Content: "main.go"},
+			message.ToolResult{ToolCallID: "call", Name: "ls", Content: "main.go"},
+			message.ToolResult{ToolCallID: "gone", Name: "ls", Content: "main.go"},
+		}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "There is main.go."}}},
+	}
+	for _, pair := range []bool{false, true} {
+		history := buildHistory(msgs, pair)
+		require.NoError(t, message.ValidatePrompt(history))
+		require.Len(t, history, 4)
+		require.Len(t, history[2].Content, 1, "the duplicate and orphaned results are dropped")
+	}
+}
@@ -6,12 +6,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 
 	"charm.land/fantasy"
 
 	"github.com/charmbracelet/crush/internal/agent/prompt"
 	"github.com/charmbracelet/crush/internal/agent/tools"
 	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
 )
 
 //go:embed templates/agent_tool.md
@@ -66,10 +70,7 @@ func (c *coordinator) agentTool(ctx context.Context) (fantasy.AgentTool, error)
 				return fantasy.ToolResponse{}, fmt.Errorf("error creating session: %s", err)
 			}
 			model := agent.Model()
-			maxTokens := model.CatwalkCfg.DefaultMaxTokens
-			if model.ModelCfg.MaxTokens != 0 {
-				maxTokens = model.ModelCfg.MaxTokens
-			}
+			maxTokens := model.ModelCfg.MaxOutputTokens(model.CatwalkCfg)
 
 			providerCfg, ok := c.cfg.Providers.Get(model.ModelCfg.Provider)
 			if !ok {
@@ -77,7 +78,7 @@ func (c *coordinator) agentTool(ctx context.Context) (fantasy.AgentTool, error)
 			}
 			result, err := agent.Run(ctx, SessionAgentCall{
 				SessionID:        session.ID,
-				Prompt:           params.Prompt,
+				Prompt:           c.sharedContext(ctx, sessionID, agentCfg.ContextSharing) + params.Prompt,
 				MaxOutputTokens:  maxTokens,
 				ProviderOptions:  getProviderOptions(model, providerCfg),
 				Temperature:      model.ModelCfg.Temperature,
@@ -85,6 +86,8 @@ func (c *coordinator) agentTool(ctx context.Context) (fantasy.AgentTool, error)
 				TopK:             model.ModelCfg.TopK,
 				FrequencyPenalty: model.ModelCfg.FrequencyPenalty,
 				PresencePenalty:  model.ModelCfg.PresencePenalty,
+
+				ParallelToolCalls: model.ModelCfg.ParallelToolCalls,
 			})
 			if err != nil {
 				return fantasy.NewTextErrorResponse("error generating response"), nil
@@ -107,3 +110,61 @@ func (c *coordinator) agentTool(ctx context.Context) (fantasy.AgentTool, error)
 			return fantasy.NewTextResponse(result.Response.Content.Text()), nil
 		}), nil
 }
+
+// sharedContext returns the context of the parent session to prepend to the
+// prompt of a sub-agent, according to what the agent is configured to share.
+func (c *coordinator) sharedContext(ctx context.Context, parentSessionID string, sharing config.ContextSharing) string {
+	if !sharing.Handoff && !sharing.PinnedMessages {
+		return ""
+	}
+	parent, err := c.sessions.Get(ctx, parentSessionID)
+	if err != nil {
+		slog.Warn("Failed to get parent session to share its context", "session", parentSessionID, "error", err)
+		return ""
+	}
+
+	var handoff string
+	if sharing.Handoff {
+		handoff = parent.Handoff
+		if handoff == "" && parent.SummaryMessageID != "" {
+			summary, err := c.messages.Get(ctx, parent.SummaryMessageID)
+			if err != nil {
+				slog.Warn("Failed to get parent session summary", "session", parentSessionID, "error", err)
+			} else {
+				handoff = summary.Content().Text
+			}
+		}
+	}
+
+	var pinned []message.Message
+	if sharing.PinnedMessages {
+		for _, id := range parent.PinnedMessageIDs {
+			msg, err := c.messages.Get(ctx, id)
+			if err != nil {
+				slog.Warn("Failed to get pinned message", "session", parentSessionID, "message", id, "error", err)
+				continue
+			}
+			pinned = append(pinned, msg)
+		}
+	}
+	return formatSharedContext(parent, handoff, pinned)
+}
+
+func formatSharedContext(parent session.Session, handoff string, pinned []message.Message) string {
+	var sb strings.Builder
+	if handoff = strings.TrimSpace(handoff); handoff != "" {
+		fmt.Fprintf(&sb, "<handoff>\n%s\n</handoff>\n", handoff)
+	}
+	for _, msg := range pinned {
+		text := strings.TrimSpace(msg.Content().Text)
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "<pinned_message role=%q>\n%s\n</pinned_message>\n", msg.Role, text)
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return fmt.Sprintf("<parent_context session=%q>\nContext shared by the session that gave you this task.\n%s</parent_context>\n\n",
+		parent.Title, sb.String())
+}
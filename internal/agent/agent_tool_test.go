@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatSharedContext(t *testing.T) {
+	t.Parallel()
+
+	parent := session.Session{Title: "Refactor auth"}
+	require.Empty(t, formatSharedContext(parent, " ", nil))
+
+	pinned := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Never touch the legacy package."}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "  "}}},
+	}
+	require.Equal(t, `<parent_context session="Refactor auth">
+Context shared by the session that gave you this task.
+<handoff>
+Tokens are now stored in the keyring.
+</handoff>
+<pinned_message role="user">
+Never touch the legacy package.
+</pinned_message>
+</parent_context>
+
+`, formatSharedContext(parent, "Tokens are now stored in the keyring.\n", pinned))
+}
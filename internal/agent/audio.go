@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/openai"
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	openaisdk "github.com/openai/openai-go/v2"
+)
+
+const (
+	// StreamPartTypeAudioDelta is emitted for each chunk of audio streamed
+	// by models that respond with speech.
+	StreamPartTypeAudioDelta fantasy.StreamPartType = "audio_delta"
+
+	defaultAudioVoice  = "alloy"
+	defaultAudioFormat = "pcm16"
+
+	// audioMetadataKey is the provider metadata key holding the AudioChunk of
+	// an audio delta stream part.
+	audioMetadataKey = "audio"
+)
+
+// AudioChunk is a piece of a streamed audio response.
+type AudioChunk struct {
+	ID string `json:"id,omitempty"`
+	// Data is base64 encoded audio in the requested format.
+	Data       string `json:"data,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+}
+
+func (*AudioChunk) Options() {}
+
+// Audio returns the decoded audio data of the chunk.
+func (c *AudioChunk) Audio() ([]byte, error) {
+	if c.Data == "" {
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(c.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode audio chunk: %w", err)
+	}
+	return data, nil
+}
+
+// audioChunkFromPart returns the audio chunk carried by the stream part, if
+// any.
+func audioChunkFromPart(part fantasy.StreamPart) (*AudioChunk, bool) {
+	if part.Type != StreamPartTypeAudioDelta {
+		return nil, false
+	}
+	chunk, ok := part.ProviderMetadata[audioMetadataKey].(*AudioChunk)
+	return chunk, ok
+}
+
+// audioFileName returns the name of the file storing the audio of the
+// given format responding in the message.
+func audioFileName(messageID, format string) string {
+	switch format {
+	case "", defaultAudioFormat:
+		return messageID + ".pcm"
+	default:
+		return messageID + "." + format
+	}
+}
+
+// saveAudio stores the audio of a spoken response as an artifact of the
+// session, the message only keeps its transcript.
+func (a *sessionAgent) saveAudio(ctx context.Context, msg message.Message, data []byte) error {
+	if a.artifacts == nil {
+		return nil
+	}
+	f, err := os.CreateTemp("", "crush-audio-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	var format string
+	if audio := a.largeModel.ModelCfg.Audio; audio != nil {
+		format = audio.Format
+	}
+	_, err = a.artifacts.Register(ctx, artifact.RegisterParams{
+		SessionID:   msg.SessionID,
+		SourcePath:  f.Name(),
+		Name:        audioFileName(msg.ID, format),
+		Kind:        artifact.KindAudio,
+		Description: "Spoken response",
+	})
+	return err
+}
+
+// audioLanguageModelOptions returns the openai language model options needed
+// to request and stream audio output.
+func audioLanguageModelOptions(cfg *config.ModelAudio) []openai.LanguageModelOption {
+	voice := cmp.Or(cfg.Voice, defaultAudioVoice)
+	format := cmp.Or(cfg.Format, defaultAudioFormat)
+	return []openai.LanguageModelOption{
+		openai.WithLanguageModelPrepareCallFunc(func(model fantasy.LanguageModel, params *openaisdk.ChatCompletionNewParams, call fantasy.Call) ([]fantasy.CallWarning, error) {
+			warnings, err := openai.DefaultPrepareCallFunc(model, params, call)
+			if err != nil {
+				return warnings, err
+			}
+			params.Modalities = []string{"text", "audio"}
+			params.Audio = openaisdk.ChatCompletionAudioParam{
+				Voice:  openaisdk.ChatCompletionAudioParamVoice(voice),
+				Format: openaisdk.ChatCompletionAudioParamFormat(format),
+			}
+			return warnings, nil
+		}),
+		openai.WithLanguageModelStreamExtraFunc(audioStreamExtra),
+	}
+}
+
+// audioStreamExtra surfaces the audio field of streamed chat completion
+// deltas as audio delta stream parts.
+func audioStreamExtra(chunk openaisdk.ChatCompletionChunk, yield func(fantasy.StreamPart) bool, ctx map[string]any) (map[string]any, bool) {
+	if len(chunk.Choices) == 0 {
+		return ctx, true
+	}
+	field, ok := chunk.Choices[0].Delta.JSON.ExtraFields["audio"]
+	if !ok || field.Raw() == "" {
+		return ctx, true
+	}
+	var audio AudioChunk
+	if err := json.Unmarshal([]byte(field.Raw()), &audio); err != nil {
+		yield(fantasy.StreamPart{
+			Type:  fantasy.StreamPartTypeError,
+			Error: fmt.Errorf("failed to parse audio delta: %w", err),
+		})
+		return ctx, false
+	}
+	if audio.Data == "" && strings.TrimSpace(audio.Transcript) == "" {
+		return ctx, true
+	}
+	return ctx, yield(fantasy.StreamPart{
+		Type:  StreamPartTypeAudioDelta,
+		ID:    audio.ID,
+		Delta: audio.Transcript,
+		ProviderMetadata: fantasy.ProviderMetadata{
+			audioMetadataKey: &audio,
+		},
+	})
+}
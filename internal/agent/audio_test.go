@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"encoding/base64"
+	"os"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	openaisdk "github.com/openai/openai-go/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudioStreamExtra(t *testing.T) {
+	t.Parallel()
+
+	t.Run("yields audio delta", func(t *testing.T) {
+		t.Parallel()
+
+		data := base64.StdEncoding.EncodeToString([]byte("pcm"))
+		var chunk openaisdk.ChatCompletionChunk
+		err := chunk.UnmarshalJSON([]byte(`{"id":"c1","choices":[{"index":0,"delta":{"audio":{"id":"audio_1","data":"` + data + `","transcript":"Hello"}}}]}`))
+		require.NoError(t, err)
+
+		var parts []fantasy.StreamPart
+		_, ok := audioStreamExtra(chunk, func(part fantasy.StreamPart) bool {
+			parts = append(parts, part)
+			return true
+		}, map[string]any{})
+		require.True(t, ok)
+		require.Len(t, parts, 1)
+		require.Equal(t, StreamPartTypeAudioDelta, parts[0].Type)
+		require.Equal(t, "Hello", parts[0].Delta)
+
+		audio, ok := audioChunkFromPart(parts[0])
+		require.True(t, ok)
+		require.Equal(t, "audio_1", audio.ID)
+		decoded, err := audio.Audio()
+		require.NoError(t, err)
+		require.Equal(t, []byte("pcm"), decoded)
+	})
+
+	t.Run("ignores text deltas", func(t *testing.T) {
+		t.Parallel()
+
+		var chunk openaisdk.ChatCompletionChunk
+		err := chunk.UnmarshalJSON([]byte(`{"id":"c1","choices":[{"index":0,"delta":{"content":"Hello"}}]}`))
+		require.NoError(t, err)
+
+		_, ok := audioStreamExtra(chunk, func(part fantasy.StreamPart) bool {
+			t.Fatalf("unexpected stream part: %v", part.Type)
+			return true
+		}, map[string]any{})
+		require.True(t, ok)
+	})
+}
+
+func TestAudioFileName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "msg.pcm", audioFileName("msg", ""))
+	require.Equal(t, "msg.pcm", audioFileName("msg", "pcm16"))
+	require.Equal(t, "msg.mp3", audioFileName("msg", "mp3"))
+}
+
+func TestSaveAudio(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sess, err := session.NewService(q).Create(t.Context(), "audio")
+	require.NoError(t, err)
+	msg := message.Message{ID: "msg-1", SessionID: sess.ID}
+
+	// Without artifacts only the transcript is kept.
+	require.NoError(t, (&sessionAgent{}).saveAudio(t.Context(), msg, []byte("pcm")))
+
+	artifacts := artifact.NewService(q, t.TempDir())
+	require.NoError(t, (&sessionAgent{artifacts: artifacts}).saveAudio(t.Context(), msg, []byte("pcm")))
+	saved, err := artifacts.ListBySession(t.Context(), sess.ID)
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	require.Equal(t, "msg-1.pcm", saved[0].Name)
+	require.Equal(t, artifact.KindAudio, saved[0].Kind)
+	data, err := os.ReadFile(saved[0].Path)
+	require.NoError(t, err)
+	require.Equal(t, []byte("pcm"), data)
+}
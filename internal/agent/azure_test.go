@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/azure"
+	"charm.land/fantasy/providers/openai"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeploymentName(t *testing.T) {
+	t.Parallel()
+
+	azureCfg := config.ProviderConfig{
+		Type:        azure.Name,
+		Deployments: map[string]string{"gpt-4o": "prod-gpt4o"},
+	}
+	require.Equal(t, "prod-gpt4o", deploymentName(azureCfg, "gpt-4o"))
+	require.Equal(t, "gpt-4.1", deploymentName(azureCfg, "gpt-4.1"))
+
+	openaiCfg := config.ProviderConfig{
+		Type:        openai.Name,
+		Deployments: map[string]string{"gpt-4o": "prod-gpt4o"},
+	}
+	require.Equal(t, "gpt-4o", deploymentName(openaiCfg, "gpt-4o"))
+}
+
+func TestAzureDeploymentRouting(t *testing.T) {
+	t.Parallel()
+
+	var path, version, apiKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		version = r.URL.Query().Get("api-version")
+		apiKey = r.Header.Get("Api-Key")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	c := &coordinator{}
+	provider, err := c.buildAzureProvider(server.URL, "secret", "2024-10-21", nil, nil)
+	require.NoError(t, err)
+	cfg := config.ProviderConfig{Type: azure.Name, Deployments: map[string]string{"gpt-4o": "prod-gpt4o"}}
+	model, err := provider.LanguageModel(t.Context(), deploymentName(cfg, "gpt-4o"))
+	require.NoError(t, err)
+
+	_, err = model.Generate(t.Context(), fantasy.Call{Prompt: fantasy.Prompt{fantasy.NewUserMessage("hi")}})
+	require.NoError(t, err)
+	require.Equal(t, "/openai/deployments/prod-gpt4o/chat/completions", path)
+	require.Equal(t, "2024-10-21", version)
+	require.Equal(t, "secret", apiKey)
+}
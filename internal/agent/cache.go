@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/openai"
+)
+
+// CachePolicy is what of the prompt providers are asked to cache, whatever
+// the provider. Anthropic and Bedrock get cache_control breakpoints on the
+// cached parts, OpenAI gets the session as prompt_cache_key so requests of
+// the session go where their prefix is cached.
+type CachePolicy struct {
+	// CacheSystemPrompt caches the tool definitions and the system prompt.
+	CacheSystemPrompt bool
+	// CacheLastNMessages caches the conversation up to each of the last N
+	// messages.
+	CacheLastNMessages int
+}
+
+// DefaultCachePolicy is the policy of calls that don't set one.
+var DefaultCachePolicy = CachePolicy{CacheSystemPrompt: true, CacheLastNMessages: 2}
+
+// Enabled reports whether anything is cached.
+func (p CachePolicy) Enabled() bool {
+	return p.CacheSystemPrompt || p.CacheLastNMessages > 0
+}
+
+func (c SessionAgentCall) cachePolicy() CachePolicy {
+	if c.Cache == nil {
+		return DefaultCachePolicy
+	}
+	return *c.Cache
+}
+
+// applyCachePolicy sets the cache control options on the messages the policy
+// caches, and clears them on the others.
+func applyCachePolicy(msgs []fantasy.Message, policy CachePolicy, cacheControl fantasy.ProviderOptions) {
+	for i := range msgs {
+		msgs[i].ProviderOptions = nil
+	}
+	if policy.CacheSystemPrompt {
+		// The breakpoint goes on the last of the leading system messages.
+		last := -1
+		for i, msg := range msgs {
+			if msg.Role != fantasy.MessageRoleSystem {
+				break
+			}
+			last = i
+		}
+		if last >= 0 {
+			msgs[last].ProviderOptions = cacheControl
+		}
+	}
+	for i := max(0, len(msgs)-policy.CacheLastNMessages); i < len(msgs); i++ {
+		msgs[i].ProviderOptions = cacheControl
+	}
+}
+
+// withPromptCacheKey returns the options with the prompt cache key of OpenAI
+// set to key, unless the configuration sets one. The options are copied, as
+// they're shared between calls.
+func withPromptCacheKey(opts fantasy.ProviderOptions, key string) fantasy.ProviderOptions {
+	var withKey fantasy.ProviderOptionsData
+	switch o := opts[openai.Name].(type) {
+	case *openai.ProviderOptions:
+		if o.PromptCacheKey != nil {
+			return opts
+		}
+		copied := *o
+		copied.PromptCacheKey = &key
+		withKey = &copied
+	case *openai.ResponsesProviderOptions:
+		if o.PromptCacheKey != nil {
+			return opts
+		}
+		copied := *o
+		copied.PromptCacheKey = &key
+		withKey = &copied
+	default:
+		return opts
+	}
+	result := make(fantasy.ProviderOptions, len(opts))
+	for name, data := range opts {
+		result[name] = data
+	}
+	result[openai.Name] = withKey
+	return result
+}
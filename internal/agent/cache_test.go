@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/openai"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyCachePolicy(t *testing.T) {
+	t.Parallel()
+
+	cacheControl := fantasy.ProviderOptions{
+		anthropic.Name: &anthropic.ProviderCacheControlOptions{CacheControl: anthropic.CacheControl{Type: "ephemeral"}},
+	}
+	newMessages := func() []fantasy.Message {
+		msgs := []fantasy.Message{
+			fantasy.NewSystemMessage("prefix"),
+			fantasy.NewSystemMessage("system"),
+			fantasy.NewUserMessage("one"),
+			fantasy.NewUserMessage("two"),
+			fantasy.NewUserMessage("three"),
+		}
+		// Left over from the previous step.
+		msgs[2].ProviderOptions = cacheControl
+		return msgs
+	}
+	cached := func(msgs []fantasy.Message) []int {
+		var indexes []int
+		for i, msg := range msgs {
+			if msg.ProviderOptions != nil {
+				indexes = append(indexes, i)
+			}
+		}
+		return indexes
+	}
+
+	msgs := newMessages()
+	applyCachePolicy(msgs, DefaultCachePolicy, cacheControl)
+	require.Equal(t, []int{1, 3, 4}, cached(msgs))
+
+	msgs = newMessages()
+	applyCachePolicy(msgs, CachePolicy{CacheLastNMessages: 10}, cacheControl)
+	require.Equal(t, []int{0, 1, 2, 3, 4}, cached(msgs))
+
+	msgs = newMessages()
+	applyCachePolicy(msgs, CachePolicy{}, cacheControl)
+	require.Empty(t, cached(msgs))
+}
+
+func TestWithPromptCacheKey(t *testing.T) {
+	t.Parallel()
+
+	shared := &openai.ProviderOptions{}
+	opts := fantasy.ProviderOptions{openai.Name: shared}
+	withKey := withPromptCacheKey(opts, "session")
+	require.Equal(t, "session", *withKey[openai.Name].(*openai.ProviderOptions).PromptCacheKey)
+	require.Nil(t, shared.PromptCacheKey)
+
+	responses := fantasy.ProviderOptions{openai.Name: &openai.ResponsesProviderOptions{}}
+	withKey = withPromptCacheKey(responses, "session")
+	require.Equal(t, "session", *withKey[openai.Name].(*openai.ResponsesProviderOptions).PromptCacheKey)
+
+	// The configured key wins.
+	configured := "mine"
+	opts = fantasy.ProviderOptions{openai.Name: &openai.ProviderOptions{PromptCacheKey: &configured}}
+	require.Equal(t, "mine", *withPromptCacheKey(opts, "session")[openai.Name].(*openai.ProviderOptions).PromptCacheKey)
+
+	other := fantasy.ProviderOptions{anthropic.Name: &anthropic.ProviderOptions{}}
+	require.Equal(t, other, withPromptCacheKey(other, "session"))
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+
+	"charm.land/fantasy"
+)
+
+// CallbackContext is passed to the stream callbacks of a run.
+type CallbackContext struct {
+	context.Context
+	// Step is the number of the step of the run streaming, from 0.
+	Step      int
+	SessionID string
+}
+
+// StreamCallbacks follow a run as it streams, each called with the
+// CallbackContext of the step. Returning an error stops the run.
+type StreamCallbacks struct {
+	OnTextDelta      func(cc CallbackContext, id, text string) error
+	OnReasoningDelta func(cc CallbackContext, id, text string) error
+	OnReasoningEnd   func(cc CallbackContext, id string, reasoning fantasy.ReasoningContent) error
+	OnToolCall       func(cc CallbackContext, call fantasy.ToolCallContent) error
+	OnToolResult     func(cc CallbackContext, result fantasy.ToolResultContent) error
+	// OnStepFinish is called once a step finished, returning an error stops
+	// the run before the next step.
+	OnStepFinish func(cc CallbackContext, step fantasy.StepResult) error
+}
+
+// withCallbacks wraps the callbacks of the stream call to call the ones of
+// the run after them. fantasy ignores the errors of OnStepFinish, the error
+// of the callback of the run is kept in stepErr to stop the run.
+func withCallbacks(ctx context.Context, sessionID string, stream fantasy.AgentStreamCall, callbacks StreamCallbacks, stepErr *error) fantasy.AgentStreamCall {
+	// Tool results are delivered from the goroutines of the tools.
+	var step atomic.Int64
+	cc := func() CallbackContext {
+		return CallbackContext{Context: ctx, Step: int(step.Load()), SessionID: sessionID}
+	}
+
+	onStepStart := stream.OnStepStart
+	stream.OnStepStart = func(stepNumber int) error {
+		step.Store(int64(stepNumber))
+		if onStepStart != nil {
+			return onStepStart(stepNumber)
+		}
+		return nil
+	}
+	stream.OnTextDelta = chainCallback2(stream.OnTextDelta, callbacks.OnTextDelta, cc)
+	stream.OnReasoningDelta = chainCallback2(stream.OnReasoningDelta, callbacks.OnReasoningDelta, cc)
+	stream.OnReasoningEnd = chainCallback2(stream.OnReasoningEnd, callbacks.OnReasoningEnd, cc)
+	stream.OnToolCall = chainCallback(stream.OnToolCall, callbacks.OnToolCall, cc)
+	stream.OnToolResult = chainCallback(stream.OnToolResult, callbacks.OnToolResult, cc)
+	if callbacks.OnStepFinish != nil {
+		onStepFinish := stream.OnStepFinish
+		stream.OnStepFinish = func(result fantasy.StepResult) error {
+			if onStepFinish != nil {
+				if err := onStepFinish(result); err != nil {
+					return err
+				}
+			}
+			if err := callbacks.OnStepFinish(cc(), result); err != nil {
+				*stepErr = err
+				return err
+			}
+			return nil
+		}
+	}
+	return stream
+}
+
+func chainCallback[A any](first func(A) error, then func(CallbackContext, A) error, cc func() CallbackContext) func(A) error {
+	if then == nil {
+		return first
+	}
+	return func(a A) error {
+		if first != nil {
+			if err := first(a); err != nil {
+				return err
+			}
+		}
+		return then(cc(), a)
+	}
+}
+
+func chainCallback2[A, B any](first func(A, B) error, then func(CallbackContext, A, B) error, cc func() CallbackContext) func(A, B) error {
+	if then == nil {
+		return first
+	}
+	return func(a A, b B) error {
+		if first != nil {
+			if err := first(a, b); err != nil {
+				return err
+			}
+		}
+		return then(cc(), a, b)
+	}
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCallbacks(t *testing.T) {
+	t.Parallel()
+
+	env := testEnv(t)
+	var greps int
+	agent := NewSessionAgent(SessionAgentOptions{
+		LargeModel: Model{
+			Model: &scriptedModel{steps: [][]fantasy.StreamPart{
+				toolCallStep("call-1", tools.GrepToolName, `{"pattern": "foo", "path": "."}`),
+			}},
+			CatwalkCfg: catwalk.Model{ContextWindow: 200000, DefaultMaxTokens: 1000},
+		},
+		SmallModel: Model{Model: &scriptedModel{}},
+		Sessions:   env.sessions,
+		Messages:   env.messages,
+		Tools:      []fantasy.AgentTool{newCountingTool(tools.GrepToolName, &greps)},
+	})
+	sess, err := env.sessions.Create(t.Context(), "callbacks")
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var events []string
+	record := func(cc CallbackContext, event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, sess.ID, cc.SessionID)
+		require.NotNil(t, cc.Context)
+		events = append(events, fmt.Sprintf("%d %s", cc.Step, event))
+	}
+	_, err = agent.Run(t.Context(), SessionAgentCall{
+		SessionID: sess.ID,
+		Prompt:    "find foo",
+		Callbacks: StreamCallbacks{
+			OnTextDelta: func(cc CallbackContext, _, text string) error {
+				record(cc, "text "+text)
+				return nil
+			},
+			OnToolCall: func(cc CallbackContext, call fantasy.ToolCallContent) error {
+				record(cc, "call "+call.ToolName)
+				return nil
+			},
+			OnToolResult: func(cc CallbackContext, result fantasy.ToolResultContent) error {
+				record(cc, "result "+result.ToolName)
+				return nil
+			},
+			OnStepFinish: func(cc CallbackContext, _ fantasy.StepResult) error {
+				record(cc, "finish")
+				return nil
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"0 call grep",
+		"0 result grep",
+		"0 finish",
+		"1 text done",
+		"1 finish",
+	}, events)
+}
+
+func TestRunCallbacksStopRun(t *testing.T) {
+	t.Parallel()
+
+	env := testEnv(t)
+	var greps int
+	large := &scriptedModel{steps: [][]fantasy.StreamPart{
+		toolCallStep("call-1", tools.GrepToolName, `{"pattern": "foo", "path": "."}`),
+	}}
+	agent := NewSessionAgent(SessionAgentOptions{
+		LargeModel: Model{
+			Model:      large,
+			CatwalkCfg: catwalk.Model{ContextWindow: 200000, DefaultMaxTokens: 1000},
+		},
+		SmallModel: Model{Model: &scriptedModel{}},
+		Sessions:   env.sessions,
+		Messages:   env.messages,
+		Tools:      []fantasy.AgentTool{newCountingTool(tools.GrepToolName, &greps)},
+	})
+	sess, err := env.sessions.Create(t.Context(), "callbacks")
+	require.NoError(t, err)
+
+	errStop := errors.New("stop")
+	_, err = agent.Run(t.Context(), SessionAgentCall{
+		SessionID: sess.ID,
+		Prompt:    "find foo",
+		Callbacks: StreamCallbacks{
+			OnStepFinish: func(CallbackContext, fantasy.StepResult) error {
+				return errStop
+			},
+		},
+	})
+	require.ErrorIs(t, err, errStop)
+	// The step after the tool call never ran.
+	require.Equal(t, 1, large.Calls())
+}
@@ -0,0 +1,227 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+// defaultChaosRate is how often faults are injected when chaos mode is
+// merely turned on.
+const defaultChaosRate = 0.1
+
+// maxChaosLatency is the longest delay injected.
+const maxChaosLatency = 5 * time.Second
+
+type fault int
+
+const (
+	faultNone fault = iota
+	faultLatency
+	faultRateLimit
+	faultDisconnect
+	faultMalformedInput
+)
+
+func (f fault) String() string {
+	switch f {
+	case faultLatency:
+		return "latency"
+	case faultRateLimit:
+		return "rate limit"
+	case faultDisconnect:
+		return "disconnect"
+	case faultMalformedInput:
+		return "malformed input"
+	}
+	return "none"
+}
+
+// chaos injects faults in the models and tools of the agents, so the retry,
+// repair and recovery paths can be exercised without waiting for providers
+// to misbehave. It's meant for testing Crush itself, so it's only turned on
+// with the CRUSH_CHAOS environment variable, set to true or to the rate of
+// calls to disrupt. CRUSH_CHAOS_SEED makes the faults reproducible.
+type chaos struct {
+	rate float64
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// newChaosFromEnv returns the chaos of the environment, or nil when chaos
+// mode is off.
+func newChaosFromEnv() *chaos {
+	value := os.Getenv("CRUSH_CHAOS")
+	if value == "" {
+		return nil
+	}
+	rate := defaultChaosRate
+	if on, err := strconv.ParseBool(value); err == nil {
+		if !on {
+			return nil
+		}
+	} else if r, err := strconv.ParseFloat(value, 64); err == nil && r > 0 && r <= 1 {
+		rate = r
+	} else {
+		slog.Warn("Ignoring invalid CRUSH_CHAOS, expected a boolean or a rate between 0 and 1", "value", value)
+		return nil
+	}
+
+	seed, err := strconv.ParseUint(os.Getenv("CRUSH_CHAOS_SEED"), 10, 64)
+	if err != nil {
+		seed = rand.Uint64()
+	}
+	slog.Warn("Chaos mode is on, models and tools will fail on purpose", "rate", rate, "seed", seed)
+	return newChaos(rate, seed)
+}
+
+func newChaos(rate float64, seed uint64) *chaos {
+	return &chaos{rate: rate, rng: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// pick returns one of the faults at the rate of the chaos, or faultNone.
+func (c *chaos) pick(faults ...fault) fault {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rng.Float64() >= c.rate {
+		return faultNone
+	}
+	return faults[c.rng.IntN(len(faults))]
+}
+
+func (c *chaos) intN(n int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rng.IntN(n)
+}
+
+// sleep waits for a random delay, or until the context is done.
+func (c *chaos) sleep(ctx context.Context) error {
+	delay := time.Duration(c.intN(int(maxChaosLatency/time.Millisecond))) * time.Millisecond
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// model wraps the model so its calls fail at the rate of the chaos.
+func (c *chaos) model(model fantasy.LanguageModel) fantasy.LanguageModel {
+	return &chaosModel{LanguageModel: model, chaos: c}
+}
+
+// tools is the middleware failing the calls of the tools at the rate of the
+// chaos.
+func (c *chaos) tools(next ToolHandler) ToolHandler {
+	return func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+		f := c.pick(faultLatency, faultMalformedInput)
+		if f != faultNone {
+			slog.Debug("Injecting tool fault", "fault", f, "tool", call.Name)
+		}
+		switch f {
+		case faultLatency:
+			if err := c.sleep(ctx); err != nil {
+				return fantasy.ToolResponse{}, err
+			}
+		case faultMalformedInput:
+			call.Input = malformed(call.Input)
+		}
+		return next(ctx, call)
+	}
+}
+
+type chaosModel struct {
+	fantasy.LanguageModel
+	chaos *chaos
+}
+
+func (m *chaosModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	f := m.chaos.pick(faultLatency, faultRateLimit, faultMalformedInput)
+	if f != faultNone {
+		slog.Debug("Injecting model fault", "fault", f, "model", m.Model())
+	}
+	switch f {
+	case faultLatency:
+		if err := m.chaos.sleep(ctx); err != nil {
+			return nil, err
+		}
+	case faultRateLimit:
+		return nil, rateLimitError()
+	}
+	resp, err := m.LanguageModel.Generate(ctx, call)
+	if err != nil || f != faultMalformedInput {
+		return resp, err
+	}
+	for i, content := range resp.Content {
+		if toolCall, ok := content.(fantasy.ToolCallContent); ok {
+			toolCall.Input = malformed(toolCall.Input)
+			resp.Content[i] = toolCall
+			break
+		}
+	}
+	return resp, nil
+}
+
+func (m *chaosModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	f := m.chaos.pick(faultLatency, faultRateLimit, faultDisconnect, faultMalformedInput)
+	if f != faultNone {
+		slog.Debug("Injecting model fault", "fault", f, "model", m.Model())
+	}
+	switch f {
+	case faultLatency:
+		if err := m.chaos.sleep(ctx); err != nil {
+			return nil, err
+		}
+	case faultRateLimit:
+		return nil, rateLimitError()
+	}
+	stream, err := m.LanguageModel.Stream(ctx, call)
+	if err != nil || (f != faultDisconnect && f != faultMalformedInput) {
+		return stream, err
+	}
+
+	// Disconnects happen early on, while there's still something to cut.
+	cut := m.chaos.intN(20)
+	return func(yield func(fantasy.StreamPart) bool) {
+		parts := 0
+		corrupted := false
+		for part := range stream {
+			if f == faultDisconnect && parts == cut && part.Type != fantasy.StreamPartTypeFinish {
+				yield(fantasy.StreamPart{
+					Type:  fantasy.StreamPartTypeError,
+					Error: fantasy.NewAPICallError("chaos: stream disconnected", "", "", 0, nil, "", nil, true),
+				})
+				return
+			}
+			parts++
+			if f == faultMalformedInput && !corrupted && part.Type == fantasy.StreamPartTypeToolCall {
+				part.ToolCallInput = malformed(part.ToolCallInput)
+				corrupted = true
+			}
+			if !yield(part) {
+				return
+			}
+		}
+	}, nil
+}
+
+func rateLimitError() error {
+	return fantasy.NewAPICallError("chaos: rate limited", "", "", http.StatusTooManyRequests, nil, "", nil, true)
+}
+
+// malformed cuts the JSON input in half, as if it was cut short.
+func malformed(input string) string {
+	if len(input) < 2 {
+		return "{"
+	}
+	return input[:len(input)/2]
+}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+type streamModel struct {
+	fantasy.LanguageModel
+	parts []fantasy.StreamPart
+}
+
+func (m *streamModel) Model() string { return "test" }
+
+func (m *streamModel) Stream(context.Context, fantasy.Call) (fantasy.StreamResponse, error) {
+	return func(yield func(fantasy.StreamPart) bool) {
+		for _, part := range m.parts {
+			if !yield(part) {
+				return
+			}
+		}
+	}, nil
+}
+
+func TestChaosSeed(t *testing.T) {
+	t.Parallel()
+
+	faults := func(seed uint64) []fault {
+		c := newChaos(0.5, seed)
+		var faults []fault
+		for range 50 {
+			faults = append(faults, c.pick(faultLatency, faultRateLimit, faultDisconnect))
+		}
+		return faults
+	}
+	require.Equal(t, faults(42), faults(42))
+	require.NotEqual(t, faults(42), faults(43))
+	require.Contains(t, faults(42), faultNone)
+	require.Contains(t, faults(42), faultRateLimit)
+}
+
+func TestChaosModelStream(t *testing.T) {
+	t.Parallel()
+
+	var parts []fantasy.StreamPart
+	for range 30 {
+		parts = append(parts, fantasy.StreamPart{Type: fantasy.StreamPartTypeTextDelta, Delta: "a"})
+	}
+	parts = append(parts,
+		fantasy.StreamPart{Type: fantasy.StreamPartTypeToolCall, ToolCallInput: `{"path":"main.go"}`},
+		fantasy.StreamPart{Type: fantasy.StreamPartTypeFinish},
+	)
+
+	seen := make(map[fault]bool)
+	for seed := range uint64(50) {
+		c := newChaos(1, seed)
+		// Replay the seed to know the fault the model will get.
+		f := newChaos(1, seed).pick(faultLatency, faultRateLimit, faultDisconnect, faultMalformedInput)
+		if f == faultLatency {
+			continue
+		}
+		seen[f] = true
+
+		stream, err := c.model(&streamModel{parts: parts}).Stream(t.Context(), fantasy.Call{})
+		if f == faultRateLimit {
+			var apiErr *fantasy.APICallError
+			require.True(t, errors.As(err, &apiErr))
+			require.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+			require.True(t, apiErr.IsRetryable)
+			continue
+		}
+		require.NoError(t, err)
+
+		var got []fantasy.StreamPart
+		for part := range stream {
+			got = append(got, part)
+		}
+		switch f {
+		case faultDisconnect:
+			last := got[len(got)-1]
+			require.Equal(t, fantasy.StreamPartTypeError, last.Type)
+			require.ErrorContains(t, last.Error, "disconnected")
+			require.Less(t, len(got), len(parts))
+		case faultMalformedInput:
+			require.Len(t, got, len(parts))
+			require.Equal(t, `{"path":"`, got[30].ToolCallInput)
+		}
+	}
+	require.Len(t, seen, 3, "every fault but latency is exercised")
+}
+
+func TestMalformed(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, `{"a"`, malformed(`{"a":"b"}`))
+	require.Equal(t, "{", malformed(""))
+}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
@@ -110,7 +112,7 @@ func testEnv(t *testing.T) env {
 	require.NoError(t, err)
 	q := db.New(conn)
 	sessions := session.NewService(q)
-	messages := message.NewService(q)
+	messages := message.NewService(q, conn)
 	permissions := permission.NewPermissionService(workingDir, true, []string{})
 	history := history.NewService(q, conn)
 	lspClients := csync.NewMap[string, *lsp.Client]()
@@ -145,7 +147,15 @@ func testSessionAgent(env env, large, small fantasy.LanguageModel, systemPrompt
 			DefaultMaxTokens: 10000,
 		},
 	}
-	agent := NewSessionAgent(SessionAgentOptions{largeModel, smallModel, "", systemPrompt, false, true, env.sessions, env.messages, tools})
+	agent := NewSessionAgent(SessionAgentOptions{
+		LargeModel:   largeModel,
+		SmallModel:   smallModel,
+		SystemPrompt: systemPrompt,
+		IsYolo:       true,
+		Sessions:     env.sessions,
+		Messages:     env.messages,
+		Tools:        tools,
+	})
 	return agent
 }
 
@@ -172,7 +182,7 @@ func coderAgent(r *recorder.Recorder, env env, large, small fantasy.LanguageMode
 		return nil, err
 	}
 	allTools := []fantasy.AgentTool{
-		tools.NewBashTool(env.permissions, env.workingDir, cfg.Options.Attribution),
+		tools.NewBashTool(env.permissions, env.workingDir, cfg.Options.Attribution, nil),
 		tools.NewDownloadTool(env.permissions, env.workingDir, r.GetDefaultClient()),
 		tools.NewEditTool(env.lspClients, env.permissions, env.history, env.workingDir),
 		tools.NewMultiEditTool(env.lspClients, env.permissions, env.history, env.workingDir),
@@ -209,3 +219,55 @@ func main() {
 	err = os.WriteFile(dir+"/main.go", []byte(mainGo), 0o644)
 	require.NoError(t, err)
 }
+
+// scriptedModel is a language model streaming the given steps in order, and
+// a short text once they are all used.
+type scriptedModel struct {
+	mu    sync.Mutex
+	steps [][]fantasy.StreamPart
+	calls int
+	// prompts are the prompts of the streamed calls.
+	prompts []fantasy.Prompt
+}
+
+func (m *scriptedModel) Generate(context.Context, fantasy.Call) (*fantasy.Response, error) {
+	return &fantasy.Response{
+		Content:      fantasy.ResponseContent{fantasy.TextContent{Text: "done"}},
+		FinishReason: fantasy.FinishReasonStop,
+	}, nil
+}
+
+func (m *scriptedModel) Stream(_ context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prompts = append(m.prompts, call.Prompt)
+	parts := []fantasy.StreamPart{
+		{Type: fantasy.StreamPartTypeTextStart, ID: "text"},
+		{Type: fantasy.StreamPartTypeTextDelta, ID: "text", Delta: "done"},
+		{Type: fantasy.StreamPartTypeTextEnd, ID: "text"},
+		{Type: fantasy.StreamPartTypeFinish, FinishReason: fantasy.FinishReasonStop},
+	}
+	if m.calls < len(m.steps) {
+		parts = m.steps[m.calls]
+	}
+	m.calls++
+	return slices.Values(parts), nil
+}
+
+func (m *scriptedModel) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+func (m *scriptedModel) Provider() string { return "scripted" }
+
+func (m *scriptedModel) Model() string { return "scripted" }
+
+// toolCallStep is a scripted step calling a tool.
+func toolCallStep(id, name, input string) []fantasy.StreamPart {
+	return []fantasy.StreamPart{
+		{Type: fantasy.StreamPartTypeToolCall, ID: id, ToolCallName: name, ToolCallInput: input},
+		{Type: fantasy.StreamPartTypeFinish, FinishReason: fantasy.FinishReasonToolCalls},
+	}
+}
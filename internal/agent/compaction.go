@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// DefaultCompactionThreshold is the share of the context window a run may
+// fill before it's compacted when the strategy doesn't set one.
+const DefaultCompactionThreshold = 0.8
+
+// compactionPrompt follows the summary a compacted run goes on from.
+const compactionPrompt = "The conversation above was compacted into this summary because it got too long. Continue with the task from where it was left off."
+
+// CompactionStrategy has runs compacted as they approach the context window
+// of the model: the conversation so far is summarized, the summary is
+// recorded in the session and the run goes on from it. Without one, the run
+// stops to summarize the session and is queued again.
+type CompactionStrategy struct {
+	// Threshold is the share of the context window the prompt may fill
+	// before it's compacted, DefaultCompactionThreshold when 0.
+	Threshold float64
+}
+
+func (s CompactionStrategy) threshold() float64 {
+	if s.Threshold <= 0 || s.Threshold > 1 {
+		return DefaultCompactionThreshold
+	}
+	return s.Threshold
+}
+
+// compactionStrategy returns the strategy configured, nil when compaction is
+// disabled.
+func compactionStrategy(cfg config.Compaction) *CompactionStrategy {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &CompactionStrategy{Threshold: cfg.Threshold}
+}
+
+// compaction is the summary a run was compacted into. The steps of a run are
+// given all of its messages, the ones the summary replaces are swapped for
+// it in each of them.
+type compaction struct {
+	messages []fantasy.Message
+	replaced int
+}
+
+// apply returns the messages of a step with the compacted ones replaced.
+func (c *compaction) apply(msgs []fantasy.Message) []fantasy.Message {
+	if c == nil || len(msgs) < c.replaced {
+		return msgs
+	}
+	return append(slices.Clone(c.messages), msgs[c.replaced:]...)
+}
+
+// needsCompaction reports whether the prompt, whose messages start with the
+// system prompt, fills the share of the context window set by the strategy.
+func (a *sessionAgent) needsCompaction(maxOutputTokens int64, msgs []fantasy.Message) bool {
+	if a.compaction == nil {
+		return false
+	}
+	limit := contextLimit(a.largeModel, maxOutputTokens)
+	if limit == 0 {
+		return false
+	}
+	total, _ := estimatePromptTokens(msgs, a.tools)
+	return float64(total) > a.compaction.threshold()*float64(limit)
+}
+
+// compact summarizes the conversation of the run and records the summary in
+// the session as a summary message. It returns the summary, the messages the
+// run goes on with, the system messages followed by the summary, and the
+// usage of the summarization.
+func (a *sessionAgent) compact(ctx context.Context, call SessionAgentCall, opts fantasy.ProviderOptions, msgs []fantasy.Message) (message.Message, []fantasy.Message, fantasy.Usage, error) {
+	leading := 0
+	for leading < len(msgs) && msgs[leading].Role == fantasy.MessageRoleSystem {
+		leading++
+	}
+	conversation := msgs[leading:]
+	if len(conversation) == 0 {
+		return message.Message{}, nil, fantasy.Usage{}, errors.New("nothing to compact")
+	}
+
+	result, err := a.summarizer(SummaryCompaction).Summarize(ctx, SummaryRequest{
+		Kind:            SummaryCompaction,
+		Messages:        conversation,
+		ProviderOptions: opts,
+	})
+	if err != nil {
+		return message.Message{}, nil, fantasy.Usage{}, err
+	}
+	text := result.Text
+
+	summary, err := a.messages.Create(ctx, call.SessionID, message.CreateMessageParams{
+		Role:             message.Assistant,
+		Parts:            []message.ContentPart{message.TextContent{Text: text}},
+		Model:            a.largeModel.Model.Model(),
+		Provider:         a.largeModel.Model.Provider(),
+		IsSummaryMessage: true,
+	})
+	if err != nil {
+		return message.Message{}, nil, fantasy.Usage{}, err
+	}
+	summary.AddFinish(message.FinishReasonEndTurn, "", "")
+	if err := a.messages.Update(ctx, summary); err != nil {
+		return message.Message{}, nil, fantasy.Usage{}, err
+	}
+
+	compacted := append(slices.Clone(msgs[:leading]), fantasy.NewUserMessage(text+"\n\n"+compactionPrompt))
+	return summary, compacted, result.Usage, nil
+}
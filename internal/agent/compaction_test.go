@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCompaction(t *testing.T) {
+	t.Parallel()
+
+	env := testEnv(t)
+	var greps int
+	large := &scriptedModel{steps: [][]fantasy.StreamPart{
+		toolCallStep("call-1", tools.GrepToolName, `{"pattern": "foo", "path": "."}`),
+	}}
+	agent := NewSessionAgent(SessionAgentOptions{
+		LargeModel: Model{
+			Model:      large,
+			CatwalkCfg: catwalk.Model{ContextWindow: 2000, DefaultMaxTokens: 1000},
+		},
+		SmallModel: Model{Model: &scriptedModel{}},
+		Sessions:   env.sessions,
+		Messages:   env.messages,
+		Tools:      []fantasy.AgentTool{newCountingTool(tools.GrepToolName, &greps)},
+		Compaction: &CompactionStrategy{},
+	})
+
+	sess, err := env.sessions.Create(t.Context(), "compaction")
+	require.NoError(t, err)
+
+	var starts int
+	var summaries []message.Message
+	// Fills most of the 1000 tokens left for the prompt.
+	result, err := agent.Run(t.Context(), SessionAgentCall{
+		SessionID: sess.ID,
+		Prompt:    strings.Repeat("find foo ", 400),
+		OnCompactionStart: func() {
+			starts++
+		},
+		OnCompactionFinish: func(summary message.Message, err error) {
+			require.NoError(t, err)
+			summaries = append(summaries, summary)
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.Equal(t, 1, greps)
+
+	// Once compacted, the run went on from the summary.
+	require.Equal(t, 1, starts)
+	require.Len(t, summaries, 1)
+	require.True(t, summaries[0].IsSummaryMessage)
+	require.Equal(t, "done", summaries[0].Content().Text)
+
+	sess, err = env.sessions.Get(t.Context(), sess.ID)
+	require.NoError(t, err)
+	require.Equal(t, summaries[0].ID, sess.SummaryMessageID)
+
+	stored, err := env.messages.Get(t.Context(), summaries[0].ID)
+	require.NoError(t, err)
+	require.True(t, stored.IsSummaryMessage)
+	require.Equal(t, message.FinishReasonEndTurn, stored.FinishReason())
+}
+
+func TestNeedsCompaction(t *testing.T) {
+	t.Parallel()
+
+	a := &sessionAgent{
+		largeModel: Model{CatwalkCfg: catwalk.Model{ContextWindow: 1000}},
+		compaction: &CompactionStrategy{Threshold: 0.5},
+	}
+	// 260 tokens of system prompt and 240 of conversation, right at the
+	// threshold.
+	msgs := []fantasy.Message{
+		fantasy.NewSystemMessage(strings.Repeat("s", 1040)),
+		fantasy.NewUserMessage(strings.Repeat("u", 960)),
+	}
+	require.False(t, a.needsCompaction(0, msgs))
+
+	msgs = append(msgs, fantasy.NewUserMessage("more"))
+	require.True(t, a.needsCompaction(0, msgs))
+}
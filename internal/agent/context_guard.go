@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/tokencount"
+)
+
+const (
+	// charsPerToken is a rough estimate that holds reasonably well for
+	// English text and code across the tokenizers of the supported models.
+	charsPerToken = 4
+	// tokensPerImage approximates the cost of an image attachment.
+	tokensPerImage = 1500
+	// maxContextContributors is how many of the largest messages are listed
+	// in a ContextOverflowError.
+	maxContextContributors = 5
+	// exactCountThreshold is the share of the limit above which prompts are
+	// counted by the provider, when it can, the estimate being too rough to
+	// tell whether they fit.
+	exactCountThreshold = 0.8
+)
+
+// ContextContributor is a message that takes up a large part of the prompt.
+type ContextContributor struct {
+	// Index of the message in the prompt.
+	Index int
+	Role  fantasy.MessageRole
+	// Kind describes what the message holds, e.g. "tool result (view)".
+	Kind   string
+	Tokens int64
+}
+
+// ContextOverflowError is returned when the prompt about to be sent does not
+// fit in the context window of the model. It lists the largest contributors
+// so they can be trimmed.
+type ContextOverflowError struct {
+	EstimatedTokens int64
+	Limit           int64
+	Contributors    []ContextContributor
+}
+
+func (e *ContextOverflowError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "prompt is too large for the model context window: ~%d tokens, limit is %d", e.EstimatedTokens, e.Limit)
+	if len(e.Contributors) > 0 {
+		sb.WriteString("; largest contributors:")
+		for _, c := range e.Contributors {
+			fmt.Fprintf(&sb, " %s #%d (~%d tokens),", c.Kind, c.Index, c.Tokens)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), ",")
+}
+
+// checkContextWindow estimates the size of the prompt and returns a
+// ContextOverflowError if it won't fit in the context window together with
+// the requested output tokens. The messages start with the system prompt.
+// Prompts close to the limit are counted by the provider of the model when
+// it can.
+func checkContextWindow(ctx context.Context, model Model, maxOutputTokens int64, msgs []fantasy.Message, tools []fantasy.AgentTool) error {
+	limit := contextLimit(model, maxOutputTokens)
+	if limit == 0 {
+		return nil
+	}
+
+	total, contributors := estimatePromptTokens(msgs, tools)
+	if model.Tokens != nil && float64(total) > exactCountThreshold*float64(limit) {
+		prompt := tokencount.Prompt{Messages: msgs}
+		for _, tool := range tools {
+			prompt.Tools = append(prompt.Tools, tool.Info())
+		}
+		if count, err := model.Tokens.CountTokens(ctx, prompt); err != nil {
+			slog.Warn("Failed to count prompt tokens, using the estimate", "error", err)
+		} else {
+			total = count
+		}
+	}
+	if total <= limit {
+		return nil
+	}
+
+	slices.SortStableFunc(contributors, func(a, b ContextContributor) int {
+		return cmp.Compare(b.Tokens, a.Tokens)
+	})
+	return &ContextOverflowError{
+		EstimatedTokens: total,
+		Limit:           limit,
+		Contributors:    contributors[:min(maxContextContributors, len(contributors))],
+	}
+}
+
+// contextLimit returns how many tokens the prompt may take, leaving room for
+// the output, or 0 when the context window of the model is unknown.
+func contextLimit(model Model, maxOutputTokens int64) int64 {
+	contextWindow := model.CatwalkCfg.ContextWindow
+	if contextWindow <= 0 {
+		return 0
+	}
+	limit := contextWindow - maxOutputTokens
+	if limit <= 0 {
+		limit = contextWindow
+	}
+	return limit
+}
+
+// estimatePromptTokens estimates the size of the prompt, and of each of its
+// messages, the system prompt included.
+func estimatePromptTokens(msgs []fantasy.Message, tools []fantasy.AgentTool) (int64, []ContextContributor) {
+	var total int64
+	for _, tool := range tools {
+		info := tool.Info()
+		params, _ := json.Marshal(info.Parameters)
+		total += estimateTextTokens(info.Name) + estimateTextTokens(info.Description) + estimateTextTokens(string(params))
+	}
+
+	// Tool results only reference their call, keep track of the tool names
+	// to describe them.
+	toolNames := make(map[string]string)
+	contributors := make([]ContextContributor, 0, len(msgs))
+	for i, msg := range msgs {
+		tokens, kind := estimateMessageTokens(msg, toolNames)
+		total += tokens
+		contributors = append(contributors, ContextContributor{
+			Index:  i,
+			Role:   msg.Role,
+			Kind:   kind,
+			Tokens: tokens,
+		})
+	}
+	return total, contributors
+}
+
+func estimateTextTokens(text string) int64 {
+	return int64((len(text) + charsPerToken - 1) / charsPerToken)
+}
+
+func estimateMessageTokens(msg fantasy.Message, toolNames map[string]string) (int64, string) {
+	var tokens int64
+	kind := string(msg.Role) + " message"
+	for _, part := range msg.Content {
+		if text, ok := fantasy.AsMessagePart[fantasy.TextPart](part); ok {
+			tokens += estimateTextTokens(text.Text)
+		} else if reasoning, ok := fantasy.AsMessagePart[fantasy.ReasoningPart](part); ok {
+			tokens += estimateTextTokens(reasoning.Text)
+		} else if file, ok := fantasy.AsMessagePart[fantasy.FilePart](part); ok {
+			kind = "attachment (" + file.Filename + ")"
+			if strings.HasPrefix(file.MediaType, "image/") {
+				tokens += tokensPerImage
+			} else {
+				tokens += estimateTextTokens(string(file.Data))
+			}
+		} else if toolCall, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part); ok {
+			toolNames[toolCall.ToolCallID] = toolCall.ToolName
+			tokens += estimateTextTokens(toolCall.ToolName) + estimateTextTokens(toolCall.Input)
+		} else if toolResult, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part); ok {
+			kind = "tool result"
+			if name, ok := toolNames[toolResult.ToolCallID]; ok {
+				kind += " (" + name + ")"
+			}
+			tokens += estimateToolResultTokens(toolResult.Output)
+		}
+	}
+	return tokens, kind
+}
+
+func estimateToolResultTokens(output fantasy.ToolResultOutputContent) int64 {
+	switch output := output.(type) {
+	case fantasy.ToolResultOutputContentText:
+		return estimateTextTokens(output.Text)
+	case *fantasy.ToolResultOutputContentText:
+		return estimateTextTokens(output.Text)
+	case fantasy.ToolResultOutputContentError:
+		if output.Error != nil {
+			return estimateTextTokens(output.Error.Error())
+		}
+	case *fantasy.ToolResultOutputContentError:
+		if output.Error != nil {
+			return estimateTextTokens(output.Error.Error())
+		}
+	case fantasy.ToolResultOutputContentMedia, *fantasy.ToolResultOutputContentMedia:
+		return tokensPerImage
+	}
+	return 0
+}
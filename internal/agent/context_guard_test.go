@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/tokencount"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckContextWindow(t *testing.T) {
+	t.Parallel()
+
+	model := Model{CatwalkCfg: catwalk.Model{ContextWindow: 1000}}
+	msgs := []fantasy.Message{
+		fantasy.NewSystemMessage("system"),
+		fantasy.NewUserMessage("list the files"),
+		{
+			Role: fantasy.MessageRoleAssistant,
+			Content: []fantasy.MessagePart{
+				fantasy.ToolCallPart{ToolCallID: "call-1", ToolName: "ls", Input: `{}`},
+			},
+		},
+		{
+			Role: fantasy.MessageRoleTool,
+			Content: []fantasy.MessagePart{
+				fantasy.ToolResultPart{
+					ToolCallID: "call-1",
+					Output:     fantasy.ToolResultOutputContentText{Text: strings.Repeat("a", 2800)},
+				},
+			},
+		},
+		fantasy.NewUserMessage(strings.Repeat("b", 800)),
+	}
+
+	t.Run("fits", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, checkContextWindow(t.Context(), model, 0, msgs, nil))
+	})
+
+	t.Run("unknown context window", func(t *testing.T) {
+		t.Parallel()
+		require.NoError(t, checkContextWindow(t.Context(), Model{}, 500, msgs, nil))
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		t.Parallel()
+		err := checkContextWindow(t.Context(), model, 500, msgs, nil)
+		require.Error(t, err)
+
+		var overflowErr *ContextOverflowError
+		require.ErrorAs(t, err, &overflowErr)
+		require.Equal(t, int64(500), overflowErr.Limit)
+		require.Greater(t, overflowErr.EstimatedTokens, overflowErr.Limit)
+		require.Len(t, overflowErr.Contributors, len(msgs))
+
+		largest := overflowErr.Contributors[0]
+		require.Equal(t, 3, largest.Index)
+		require.Equal(t, "tool result (ls)", largest.Kind)
+		require.Equal(t, int64(700), largest.Tokens)
+		require.Equal(t, 4, overflowErr.Contributors[1].Index)
+		require.Contains(t, err.Error(), "tool result (ls) #3")
+	})
+
+	t.Run("counted by the provider", func(t *testing.T) {
+		t.Parallel()
+		// Estimated over the limit, but the provider counts fewer tokens.
+		counted := model
+		counted.Tokens = fixedCounter{tokens: 400}
+		require.NoError(t, checkContextWindow(t.Context(), counted, 500, msgs, nil))
+
+		counted.Tokens = fixedCounter{tokens: 600}
+		var overflowErr *ContextOverflowError
+		require.ErrorAs(t, checkContextWindow(t.Context(), counted, 500, msgs, nil), &overflowErr)
+		require.Equal(t, int64(600), overflowErr.EstimatedTokens)
+
+		// The estimate is used when counting fails.
+		counted.Tokens = fixedCounter{err: errors.New("offline")}
+		require.ErrorAs(t, checkContextWindow(t.Context(), counted, 500, msgs, nil), &overflowErr)
+		require.Greater(t, overflowErr.EstimatedTokens, int64(900))
+	})
+}
+
+type fixedCounter struct {
+	tokens int64
+	err    error
+}
+
+func (c fixedCounter) CountTokens(context.Context, tokencount.Prompt) (int64, error) {
+	return c.tokens, c.err
+}
@@ -9,7 +9,8 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"maps"
+	"net/http"
+	"net/url"
 	"os"
 	"slices"
 	"strings"
@@ -18,14 +19,24 @@ import (
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
 	"github.com/charmbracelet/crush/internal/agent/prompt"
 	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/audit"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/fact"
+	"github.com/charmbracelet/crush/internal/health"
 	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/inflight"
 	"github.com/charmbracelet/crush/internal/log"
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/secrets"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/spending"
+	"github.com/charmbracelet/crush/internal/terminal"
+	"github.com/charmbracelet/crush/internal/tokencount"
+	"github.com/charmbracelet/crush/internal/toolstats"
 
 	"charm.land/fantasy/providers/anthropic"
 	"charm.land/fantasy/providers/azure"
@@ -42,13 +53,29 @@ type Coordinator interface {
 	// INFO: (kujtim) this is not used yet we will use this when we have multiple agents
 	// SetMainAgent(string)
 	Run(ctx context.Context, sessionID, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error)
+	// RunApprovedPlan carries out the plan the user approved, the session
+	// staying in plan mode for its next prompts.
+	RunApprovedPlan(ctx context.Context, sessionID, plan string) (*fantasy.AgentResult, error)
 	Cancel(sessionID string)
 	CancelAll()
 	IsSessionBusy(sessionID string) bool
 	IsBusy() bool
 	QueuedPrompts(sessionID string) int
 	ClearQueue(sessionID string)
+	SetDryRun(sessionID string, dryRun bool)
+	IsDryRun(sessionID string) bool
+	SetPlanMode(sessionID string, planMode bool)
+	IsPlanMode(sessionID string) bool
+	// ConfirmStopPhrase lets the next prompt of a session stopped at a stop
+	// phrase go past it.
+	ConfirmStopPhrase(sessionID string)
 	Summarize(context.Context, string) error
+	// TopicChanged reports whether the prompt starts another topic than the
+	// previous prompts of the session, always false unless configured.
+	TopicChanged(ctx context.Context, sessionID, prompt string) (bool, error)
+	// StartNewTopic creates a session starting from a brief summary of the
+	// session, to go on with another topic in it.
+	StartNewTopic(ctx context.Context, sessionID string) (session.Session, error)
 	Model() Model
 	UpdateModels(ctx context.Context) error
 }
@@ -58,8 +85,15 @@ type coordinator struct {
 	sessions    session.Service
 	messages    message.Service
 	permissions permission.Service
-	history     history.Service
-	lspClients  *csync.Map[string, *lsp.Client]
+	artifacts   artifact.Service
+	facts       fact.Service
+	spending    spending.Service
+	health      health.Service
+	inflight    inflight.Service
+	secrets     *secrets.Vault
+	chaos       *chaos
+	transports  *transports
+	toolset     *Toolset
 
 	currentAgent SessionAgent
 	agents       map[string]SessionAgent
@@ -72,6 +106,15 @@ func NewCoordinator(
 	messages message.Service,
 	permissions permission.Service,
 	history history.Service,
+	artifacts artifact.Service,
+	facts fact.Service,
+	toolStats toolstats.Service,
+	audit audit.Service,
+	spending spending.Service,
+	health health.Service,
+	inflight inflight.Service,
+	terminal terminal.Service,
+	secrets *secrets.Vault,
 	lspClients *csync.Map[string, *lsp.Client],
 ) (Coordinator, error) {
 	c := &coordinator{
@@ -79,10 +122,31 @@ func NewCoordinator(
 		sessions:    sessions,
 		messages:    messages,
 		permissions: permissions,
-		history:     history,
-		lspClients:  lspClients,
+		artifacts:   artifacts,
+		facts:       facts,
+		spending:    spending,
+		health:      health,
+		inflight:    inflight,
+		secrets:     secrets,
+		chaos:       newChaosFromEnv(),
+		transports:  newTransports(),
 		agents:      make(map[string]SessionAgent),
 	}
+	c.toolset = &Toolset{
+		opts: ToolsetOptions{
+			Config:      cfg,
+			Permissions: permissions,
+			History:     history,
+			Artifacts:   artifacts,
+			Facts:       facts,
+			ToolStats:   toolStats,
+			Audit:       audit,
+			Secrets:     secrets,
+			Terminal:    terminal,
+			LSPClients:  lspClients,
+		},
+		chaos: c.chaos,
+	}
 
 	agentCfg, ok := cfg.Agents[config.AgentCoder]
 	if !ok {
@@ -106,11 +170,17 @@ func NewCoordinator(
 
 // Run implements Coordinator.
 func (c *coordinator) Run(ctx context.Context, sessionID string, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error) {
+	return c.run(ctx, sessionID, prompt, attachments, false)
+}
+
+// RunApprovedPlan implements Coordinator.
+func (c *coordinator) RunApprovedPlan(ctx context.Context, sessionID, plan string) (*fantasy.AgentResult, error) {
+	return c.run(ctx, sessionID, "The plan was approved, carry it out now:\n\n"+plan, nil, true)
+}
+
+func (c *coordinator) run(ctx context.Context, sessionID string, prompt string, attachments []message.Attachment, skipPlanMode bool) (*fantasy.AgentResult, error) {
 	model := c.currentAgent.Model()
-	maxTokens := model.CatwalkCfg.DefaultMaxTokens
-	if model.ModelCfg.MaxTokens != 0 {
-		maxTokens = model.ModelCfg.MaxTokens
-	}
+	maxTokens := model.ModelCfg.MaxOutputTokens(model.CatwalkCfg)
 
 	if !model.CatwalkCfg.SupportsImages && attachments != nil {
 		attachments = nil
@@ -123,9 +193,18 @@ func (c *coordinator) Run(ctx context.Context, sessionID string, prompt string,
 
 	mergedOptions, temp, topP, topK, freqPenalty, presPenalty := mergeCallOptions(model, providerCfg)
 
+	if c.cfg.Options.ReadOnly {
+		ctx = context.WithValue(ctx, tools.DryRunContextKey, true)
+	}
+
+	ctx, extraSystemPrompt, err := c.sessionEnvironment(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
 	return c.currentAgent.Run(ctx, SessionAgentCall{
 		SessionID:        sessionID,
-		Prompt:           prompt,
+		Prompt:           c.secrets.Redact(prompt),
 		Attachments:      attachments,
 		MaxOutputTokens:  maxTokens,
 		ProviderOptions:  mergedOptions,
@@ -134,9 +213,36 @@ func (c *coordinator) Run(ctx context.Context, sessionID string, prompt string,
 		TopK:             topK,
 		FrequencyPenalty: freqPenalty,
 		PresencePenalty:  presPenalty,
+
+		CompactToolSchemas: providerCfg.CompactToolSchemas && !cachesToolSchemas(providerCfg.Type),
+		PairToolResults:    providerCfg.PairToolResults,
+		ExtraSystemPrompt:  extraSystemPrompt,
+		SkipPlanMode:       skipPlanMode,
+		ParallelToolCalls:  model.ModelCfg.ParallelToolCalls,
 	})
 }
 
+// sessionEnvironment applies the working directory and environment variables
+// of the session, if it overrides them, and the secrets of the project to the
+// context the tools run with and returns the system prompt addition
+// describing them.
+func (c *coordinator) sessionEnvironment(ctx context.Context, sessionID string) (context.Context, string, error) {
+	sess, err := c.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return ctx, "", fmt.Errorf("failed to get session: %w", err)
+	}
+	secretEnv := c.secrets.Environ()
+	if sess.WorkingDir == "" && len(sess.Env) == 0 && len(secretEnv) == 0 {
+		return ctx, "", nil
+	}
+	env := tools.SessionEnv{
+		WorkingDir: sess.WorkingDir,
+		Env:        append(sess.Environ(), secretEnv...),
+	}
+	ctx = context.WithValue(ctx, tools.SessionEnvContextKey, env)
+	return ctx, prompt.SessionContext(env.WorkingDir, env.Env, *c.cfg), nil
+}
+
 func getProviderOptions(model Model, providerCfg config.ProviderConfig) fantasy.ProviderOptions {
 	options := fantasy.ProviderOptions{}
 
@@ -191,6 +297,12 @@ func getProviderOptions(model Model, providerCfg config.ProviderConfig) fantasy.
 		if !hasReasoningEffort && model.ModelCfg.ReasoningEffort != "" {
 			mergedOptions["reasoning_effort"] = model.ModelCfg.ReasoningEffort
 		}
+		_, hasServiceTier := mergedOptions["service_tier"]
+		if !hasServiceTier && model.ModelCfg.Priority != "" {
+			if tier, ok := openaiServiceTier(model.ModelCfg.Priority); ok {
+				mergedOptions["service_tier"] = tier
+			}
+		}
 		if openai.IsResponsesModel(model.CatwalkCfg.ID) {
 			if openai.IsResponsesReasoningModel(model.CatwalkCfg.ID) {
 				mergedOptions["reasoning_summary"] = "auto"
@@ -235,10 +347,14 @@ func getProviderOptions(model Model, providerCfg config.ProviderConfig) fantasy.
 		_, hasReasoning := mergedOptions["thinking_config"]
 		if !hasReasoning {
 			mergedOptions["thinking_config"] = map[string]any{
-				"thinking_budget":  2000,
+				"thinking_budget":  googleThinkingBudget(model.ModelCfg.ReasoningEffort),
 				"include_thoughts": true,
 			}
 		}
+		_, hasSafetySettings := mergedOptions["safety_settings"]
+		if !hasSafetySettings && model.ModelCfg.SafetyThreshold != "" {
+			mergedOptions["safety_settings"] = googleSafetySettings(model.ModelCfg.SafetyThreshold)
+		}
 		parsed, err := google.ParseOptions(mergedOptions)
 		if err == nil {
 			options[google.Name] = parsed
@@ -267,6 +383,78 @@ func getProviderOptions(model Model, providerCfg config.ProviderConfig) fantasy.
 	return options
 }
 
+// withServiceTier adds the service tier matching the priority of the model
+// to the extra body of Anthropic requests, as the SDK has no option for it,
+// unless the extra body sets one. OpenAI gets it in the call options. The
+// priorities the provider doesn't support are reported once, when the
+// model is set up, instead of on every call.
+func withServiceTier(providerCfg config.ProviderConfig, model config.SelectedModel, extraBody map[string]any) map[string]any {
+	if model.Priority == "" {
+		return extraBody
+	}
+	supported := false
+	switch providerCfg.Type {
+	case openai.Name:
+		_, supported = openaiServiceTier(model.Priority)
+	case anthropic.Name:
+		var tier string
+		tier, supported = anthropicServiceTier(model.Priority)
+		if _, ok := extraBody["service_tier"]; supported && !ok {
+			if extraBody == nil {
+				extraBody = make(map[string]any)
+			}
+			extraBody["service_tier"] = tier
+		}
+	}
+	if !supported {
+		slog.Warn("Call priority not supported by provider, ignoring", "priority", model.Priority, "provider", providerCfg.ID, "model", model.Model)
+	}
+	return extraBody
+}
+
+// openaiServiceTier maps a call priority to the matching OpenAI service tier.
+// Batch processing is not available for interactive calls.
+func openaiServiceTier(priority config.CallPriority) (string, bool) {
+	switch priority {
+	case config.CallPriorityStandard:
+		return "default", true
+	case config.CallPriorityFlex:
+		return string(openai.ServiceTierFlex), true
+	case config.CallPriorityPriority:
+		return string(openai.ServiceTierPriority), true
+	default:
+		return "", false
+	}
+}
+
+// anthropicServiceTier maps a call priority to the matching Anthropic
+// service tier: the priority tier is used when available with auto, and
+// standard_only keeps to the standard tier. There's no flex tier, and batch
+// processing is not available for interactive calls.
+func anthropicServiceTier(priority config.CallPriority) (string, bool) {
+	switch priority {
+	case config.CallPriorityStandard:
+		return "standard_only", true
+	case config.CallPriorityPriority:
+		return "auto", true
+	default:
+		return "", false
+	}
+}
+
+// googleThinkingBudget maps a reasoning effort to the number of tokens
+// Gemini models may think for.
+func googleThinkingBudget(effort string) int {
+	switch effort {
+	case "low":
+		return 1024
+	case "high":
+		return 8192
+	default:
+		return 2000
+	}
+}
+
 func mergeCallOptions(model Model, cfg config.ProviderConfig) (fantasy.ProviderOptions, *float64, *float64, *int64, *float64, *float64) {
 	modelOptions := getProviderOptions(model, cfg)
 	temp := cmp.Or(model.ModelCfg.Temperature, model.CatwalkCfg.Options.Temperature)
@@ -290,15 +478,30 @@ func (c *coordinator) buildAgent(ctx context.Context, prompt *prompt.Prompt, age
 
 	largeProviderCfg, _ := c.cfg.Providers.Get(large.ModelCfg.Provider)
 	result := NewSessionAgent(SessionAgentOptions{
-		large,
-		small,
-		largeProviderCfg.SystemPromptPrefix,
-		systemPrompt,
-		c.cfg.Options.DisableAutoSummarize,
-		c.permissions.SkipRequests(),
-		c.sessions,
-		c.messages,
-		nil,
+		LargeModel:           large,
+		SmallModel:           small,
+		SystemPromptPrefix:   largeProviderCfg.SystemPromptPrefix,
+		SystemPrompt:         systemPrompt,
+		DisableAutoSummarize: c.cfg.Options.DisableAutoSummarize,
+		IsYolo:               c.permissions.SkipRequests(),
+		PlanMode:             agent.PlanMode,
+		Sessions:             c.sessions,
+		Messages:             c.messages,
+		LoopDetection:        c.cfg.Options.LoopDetection,
+		Health:               c.health,
+		RunSummary:           c.cfg.Options.RunSummary,
+		ToolOutput:           c.cfg.Tools.Output,
+		StopPhrases:          c.cfg.Options.StopPhrases,
+		Spending:             c.spending,
+		Compaction:           compactionStrategy(c.cfg.Options.Compaction),
+		Summarizers:          summarizers(c.cfg.Options.Summarizers),
+		Topics:               c.topicDetection(small.ModelCfg),
+		Facts:                c.facts,
+		Artifacts:            c.artifacts,
+		PrefetchDir:          c.prefetchDir(),
+		ToolConcurrency:      c.cfg.Tools.MaxConcurrency(),
+		ToolTimeout:          c.cfg.Tools.CallTimeout(),
+		ToolMiddleware:       c.toolset.Middleware(),
 	})
 	go func() {
 		tools, err := c.buildTools(ctx, agent)
@@ -311,70 +514,25 @@ func (c *coordinator) buildAgent(ctx context.Context, prompt *prompt.Prompt, age
 	return result, nil
 }
 
+// prefetchDir returns the working directory view and grep calls are
+// prefetched in, empty unless enabled.
+func (c *coordinator) prefetchDir() string {
+	if !c.cfg.Options.Prefetch {
+		return ""
+	}
+	return c.cfg.WorkingDir()
+}
+
 func (c *coordinator) buildTools(ctx context.Context, agent config.Agent) ([]fantasy.AgentTool, error) {
-	var allTools []fantasy.AgentTool
+	var extra []fantasy.AgentTool
 	if slices.Contains(agent.AllowedTools, AgentToolName) {
 		agentTool, err := c.agentTool(ctx)
 		if err != nil {
 			return nil, err
 		}
-		allTools = append(allTools, agentTool)
-	}
-
-	allTools = append(allTools,
-		tools.NewBashTool(c.permissions, c.cfg.WorkingDir(), c.cfg.Options.Attribution),
-		tools.NewDownloadTool(c.permissions, c.cfg.WorkingDir(), nil),
-		tools.NewEditTool(c.lspClients, c.permissions, c.history, c.cfg.WorkingDir()),
-		tools.NewMultiEditTool(c.lspClients, c.permissions, c.history, c.cfg.WorkingDir()),
-		tools.NewFetchTool(c.permissions, c.cfg.WorkingDir(), nil),
-		tools.NewGlobTool(c.cfg.WorkingDir()),
-		tools.NewGrepTool(c.cfg.WorkingDir()),
-		tools.NewLsTool(c.permissions, c.cfg.WorkingDir(), c.cfg.Tools.Ls),
-		tools.NewSourcegraphTool(nil),
-		tools.NewViewTool(c.lspClients, c.permissions, c.cfg.WorkingDir()),
-		tools.NewWriteTool(c.lspClients, c.permissions, c.history, c.cfg.WorkingDir()),
-	)
-
-	if len(c.cfg.LSP) > 0 {
-		allTools = append(allTools, tools.NewDiagnosticsTool(c.lspClients), tools.NewReferencesTool(c.lspClients))
-	}
-
-	var filteredTools []fantasy.AgentTool
-	for _, tool := range allTools {
-		if slices.Contains(agent.AllowedTools, tool.Info().Name) {
-			filteredTools = append(filteredTools, tool)
-		}
+		extra = append(extra, agentTool)
 	}
-
-	mcpTools := tools.GetMCPTools(context.Background(), c.permissions, c.cfg)
-
-	for _, mcpTool := range mcpTools {
-		if agent.AllowedMCP == nil {
-			// No MCP restrictions
-			filteredTools = append(filteredTools, mcpTool)
-		} else if len(agent.AllowedMCP) == 0 {
-			// no mcps allowed
-			break
-		}
-
-		for mcp, tools := range agent.AllowedMCP {
-			if mcp == mcpTool.MCP() {
-				if len(tools) == 0 {
-					filteredTools = append(filteredTools, mcpTool)
-				}
-				for _, t := range tools {
-					if t == mcpTool.MCPToolName() {
-						filteredTools = append(filteredTools, mcpTool)
-					}
-				}
-				break
-			}
-		}
-	}
-	slices.SortFunc(filteredTools, func(a, b fantasy.AgentTool) int {
-		return strings.Compare(a.Info().Name, b.Info().Name)
-	})
-	return filteredTools, nil
+	return c.toolset.Tools(ctx, agent, extra...), nil
 }
 
 // TODO: when we support multiple agents we need to change this so that we pass in the agent specific model config
@@ -403,7 +561,7 @@ func (c *coordinator) buildAgentModels(ctx context.Context) (Model, Model, error
 		return Model{}, Model{}, errors.New("large model provider not configured")
 	}
 
-	smallProvider, err := c.buildProvider(smallProviderCfg, largeModelCfg)
+	smallProvider, err := c.buildProvider(smallProviderCfg, smallModelCfg)
 	if err != nil {
 		return Model{}, Model{}, err
 	}
@@ -441,6 +599,9 @@ func (c *coordinator) buildAgentModels(ctx context.Context) (Model, Model, error
 		smallModelID += ":exacto"
 	}
 
+	largeModelID = deploymentName(largeProviderCfg, largeModelID)
+	smallModelID = deploymentName(smallProviderCfg, smallModelID)
+
 	largeModel, err := largeProvider.LanguageModel(ctx, largeModelID)
 	if err != nil {
 		return Model{}, Model{}, err
@@ -449,19 +610,47 @@ func (c *coordinator) buildAgentModels(ctx context.Context) (Model, Model, error
 	if err != nil {
 		return Model{}, Model{}, err
 	}
+	if c.chaos != nil {
+		largeModel = c.chaos.model(largeModel)
+		smallModel = c.chaos.model(smallModel)
+	}
+	largeModel = newResumableModel(largeModel, string(largeProviderCfg.Type))
+	smallModel = newResumableModel(smallModel, string(smallProviderCfg.Type))
+	if isGoogleProvider(largeProviderCfg.Type) {
+		largeModel = newSafetyWarningModel(largeModel)
+	}
+	if isGoogleProvider(smallProviderCfg.Type) {
+		smallModel = newSafetyWarningModel(smallModel)
+	}
+	if c.inflight != nil {
+		largeModel = newLimitedModel(largeModel, c.inflight, largeProviderCfg.ID, largeProviderCfg.MaxConcurrentRequests)
+		smallModel = newLimitedModel(smallModel, c.inflight, smallProviderCfg.ID, smallProviderCfg.MaxConcurrentRequests)
+	}
 
 	return Model{
 			Model:      largeModel,
 			CatwalkCfg: *largeCatwalkModel,
 			ModelCfg:   largeModelCfg,
+			Tokens:     c.tokenCounter(largeProviderCfg, largeModelID),
 		}, Model{
 			Model:      smallModel,
 			CatwalkCfg: *smallCatwalkModel,
 			ModelCfg:   smallModelCfg,
+			Tokens:     c.tokenCounter(smallProviderCfg, smallModelID),
 		}, nil
 }
 
-func (c *coordinator) buildAnthropicProvider(baseURL, apiKey string, headers map[string]string) (fantasy.Provider, error) {
+// tokenCounter returns the token counter of the model, or nil when its
+// provider can't count tokens.
+func (c *coordinator) tokenCounter(providerCfg config.ProviderConfig, modelID string) tokencount.Counter {
+	counter, err := tokencount.New(c.cfg.Resolver(), providerCfg, modelID)
+	if err != nil {
+		return nil
+	}
+	return counter
+}
+
+func (c *coordinator) buildAnthropicProvider(baseURL, apiKey string, headers map[string]string, extraBody map[string]any, httpClient *http.Client) (fantasy.Provider, error) {
 	hasBearerAuth := false
 	for key := range headers {
 		if strings.ToLower(key) == "authorization" {
@@ -494,21 +683,27 @@ func (c *coordinator) buildAnthropicProvider(baseURL, apiKey string, headers map
 		opts = append(opts, anthropic.WithBaseURL(baseURL))
 	}
 
-	if c.cfg.Options.Debug {
-		httpClient := log.NewHTTPClient()
-		opts = append(opts, anthropic.WithHTTPClient(httpClient))
+	// The anthropic SDK options are not exposed, so the stop sequences and
+	// extra body fields are added to the requests by transports. The extra
+	// body goes first, so its stop sequences are kept.
+	httpClient.Transport = &stopSequencesTransport{transport: httpClient.Transport}
+	if len(extraBody) > 0 {
+		httpClient.Transport = &extraBodyTransport{
+			transport: httpClient.Transport,
+			body:      extraBody,
+		}
 	}
+	opts = append(opts, anthropic.WithHTTPClient(httpClient))
 
 	return anthropic.New(opts...)
 }
 
-func (c *coordinator) buildOpenaiProvider(baseURL, apiKey string, headers map[string]string) (fantasy.Provider, error) {
+func (c *coordinator) buildOpenaiProvider(baseURL, apiKey string, headers map[string]string, extraBody map[string]any, audio *config.ModelAudio, httpClient *http.Client) (fantasy.Provider, error) {
 	opts := []openai.Option{
 		openai.WithAPIKey(apiKey),
 		openai.WithUseResponsesAPI(),
 	}
-	if c.cfg.Options.Debug {
-		httpClient := log.NewHTTPClient()
+	if httpClient != nil {
 		opts = append(opts, openai.WithHTTPClient(httpClient))
 	}
 	if len(headers) > 0 {
@@ -517,16 +712,19 @@ func (c *coordinator) buildOpenaiProvider(baseURL, apiKey string, headers map[st
 	if baseURL != "" {
 		opts = append(opts, openai.WithBaseURL(baseURL))
 	}
+	if audio != nil {
+		opts = append(opts, openai.WithLanguageModelOptions(audioLanguageModelOptions(audio)...))
+	}
+	for extraKey, extraValue := range extraBody {
+		opts = append(opts, openai.WithSDKOptions(openaisdk.WithJSONSet(extraKey, extraValue)))
+	}
 	return openai.New(opts...)
 }
 
-func (c *coordinator) buildOpenrouterProvider(_, apiKey string, headers map[string]string) (fantasy.Provider, error) {
+func (c *coordinator) buildOpenrouterProvider(_, apiKey string, headers map[string]string, httpClient *http.Client) (fantasy.Provider, error) {
 	opts := []openrouter.Option{
 		openrouter.WithAPIKey(apiKey),
-	}
-	if c.cfg.Options.Debug {
-		httpClient := log.NewHTTPClient()
-		opts = append(opts, openrouter.WithHTTPClient(httpClient))
+		openrouter.WithHTTPClient(httpClient),
 	}
 	if len(headers) > 0 {
 		opts = append(opts, openrouter.WithHeaders(headers))
@@ -534,13 +732,12 @@ func (c *coordinator) buildOpenrouterProvider(_, apiKey string, headers map[stri
 	return openrouter.New(opts...)
 }
 
-func (c *coordinator) buildOpenaiCompatProvider(baseURL, apiKey string, headers map[string]string, extraBody map[string]any) (fantasy.Provider, error) {
+func (c *coordinator) buildOpenaiCompatProvider(baseURL, apiKey string, headers map[string]string, extraBody map[string]any, httpClient *http.Client) (fantasy.Provider, error) {
 	opts := []openaicompat.Option{
 		openaicompat.WithBaseURL(baseURL),
 		openaicompat.WithAPIKey(apiKey),
 	}
-	if c.cfg.Options.Debug {
-		httpClient := log.NewHTTPClient()
+	if httpClient != nil {
 		opts = append(opts, openaicompat.WithHTTPClient(httpClient))
 	}
 	if len(headers) > 0 {
@@ -554,19 +751,15 @@ func (c *coordinator) buildOpenaiCompatProvider(baseURL, apiKey string, headers
 	return openaicompat.New(opts...)
 }
 
-func (c *coordinator) buildAzureProvider(baseURL, apiKey string, headers map[string]string, options map[string]string) (fantasy.Provider, error) {
+func (c *coordinator) buildAzureProvider(baseURL, apiKey, apiVersion string, headers map[string]string, httpClient *http.Client) (fantasy.Provider, error) {
 	opts := []azure.Option{
 		azure.WithBaseURL(baseURL),
 		azure.WithAPIKey(apiKey),
 	}
-	if c.cfg.Options.Debug {
-		httpClient := log.NewHTTPClient()
+	if httpClient != nil {
 		opts = append(opts, azure.WithHTTPClient(httpClient))
 	}
-	if options == nil {
-		options = make(map[string]string)
-	}
-	if apiVersion, ok := options["apiVersion"]; ok {
+	if apiVersion != "" {
 		opts = append(opts, azure.WithAPIVersion(apiVersion))
 	}
 	if len(headers) > 0 {
@@ -576,11 +769,21 @@ func (c *coordinator) buildAzureProvider(baseURL, apiKey string, headers map[str
 	return azure.New(opts...)
 }
 
-func (c *coordinator) buildBedrockProvider(headers map[string]string) (fantasy.Provider, error) {
-	var opts []bedrock.Option
-	if c.cfg.Options.Debug {
-		httpClient := log.NewHTTPClient()
-		opts = append(opts, bedrock.WithHTTPClient(httpClient))
+// deploymentName returns the name of the Azure OpenAI deployment serving the
+// model, requests are routed to /openai/deployments/{name}.
+func deploymentName(providerCfg config.ProviderConfig, modelID string) string {
+	if providerCfg.Type != azure.Name {
+		return modelID
+	}
+	if name, ok := providerCfg.Deployments[modelID]; ok && name != "" {
+		return name
+	}
+	return modelID
+}
+
+func (c *coordinator) buildBedrockProvider(headers map[string]string, httpClient *http.Client) (fantasy.Provider, error) {
+	opts := []bedrock.Option{
+		bedrock.WithHTTPClient(httpClient),
 	}
 	if len(headers) > 0 {
 		opts = append(opts, bedrock.WithHeaders(headers))
@@ -592,14 +795,11 @@ func (c *coordinator) buildBedrockProvider(headers map[string]string) (fantasy.P
 	return bedrock.New(opts...)
 }
 
-func (c *coordinator) buildGoogleProvider(baseURL, apiKey string, headers map[string]string) (fantasy.Provider, error) {
+func (c *coordinator) buildGoogleProvider(baseURL, apiKey string, headers map[string]string, httpClient *http.Client) (fantasy.Provider, error) {
 	opts := []google.Option{
 		google.WithBaseURL(baseURL),
 		google.WithGeminiAPIKey(apiKey),
-	}
-	if c.cfg.Options.Debug {
-		httpClient := log.NewHTTPClient()
-		opts = append(opts, google.WithHTTPClient(httpClient))
+		google.WithHTTPClient(httpClient),
 	}
 	if len(headers) > 0 {
 		opts = append(opts, google.WithHeaders(headers))
@@ -607,11 +807,9 @@ func (c *coordinator) buildGoogleProvider(baseURL, apiKey string, headers map[st
 	return google.New(opts...)
 }
 
-func (c *coordinator) buildGoogleVertexProvider(headers map[string]string, options map[string]string) (fantasy.Provider, error) {
-	opts := []google.Option{}
-	if c.cfg.Options.Debug {
-		httpClient := log.NewHTTPClient()
-		opts = append(opts, google.WithHTTPClient(httpClient))
+func (c *coordinator) buildGoogleVertexProvider(headers map[string]string, options map[string]string, httpClient *http.Client) (fantasy.Provider, error) {
+	opts := []google.Option{
+		google.WithHTTPClient(httpClient),
 	}
 	if len(headers) > 0 {
 		opts = append(opts, google.WithHeaders(headers))
@@ -644,15 +842,51 @@ func (c *coordinator) isAnthropicThinking(model config.SelectedModel) bool {
 	return false
 }
 
+// addAnthropicBeta adds the beta to the anthropic-beta header, keeping the
+// ones already set.
+func addAnthropicBeta(headers map[string]string, beta string) {
+	v, ok := headers["anthropic-beta"]
+	switch {
+	case !ok || v == "":
+		headers["anthropic-beta"] = beta
+	case !slices.ContainsFunc(strings.Split(v, ","), func(b string) bool { return strings.TrimSpace(b) == beta }):
+		headers["anthropic-beta"] = v + "," + beta
+	}
+}
+
+// isAnthropicAPI reports whether the base URL of an anthropic provider is the
+// API of Anthropic, the default when not set.
+func isAnthropicAPI(baseURL string) bool {
+	if baseURL == "" {
+		return true
+	}
+	u, err := url.Parse(baseURL)
+	return err == nil && strings.EqualFold(u.Hostname(), "api.anthropic.com")
+}
+
 func (c *coordinator) buildProvider(providerCfg config.ProviderConfig, model config.SelectedModel) (fantasy.Provider, error) {
-	headers := maps.Clone(providerCfg.ExtraHeaders)
+	headers, err := providerCfg.ResolvedExtraHeaders(c.cfg.Resolver())
+	if err != nil {
+		return nil, err
+	}
+	extraBody, err := providerCfg.ResolvedExtraBody(c.cfg.Resolver())
+	if err != nil {
+		return nil, err
+	}
 
-	// handle special headers for anthropic
-	if providerCfg.Type == anthropic.Name && c.isAnthropicThinking(model) {
-		if v, ok := headers["anthropic-beta"]; ok {
-			headers["anthropic-beta"] = v + ",interleaved-thinking-2025-05-14"
-		} else {
-			headers["anthropic-beta"] = "interleaved-thinking-2025-05-14"
+	// The headers and body of the configuration win over the attribution.
+	attribution := c.usageAttribution(providerCfg.Type)
+	for k, v := range attribution.headers {
+		if _, ok := headers[k]; !ok {
+			headers[k] = v
+		}
+	}
+	for k, v := range attribution.body {
+		if extraBody == nil {
+			extraBody = make(map[string]any)
+		}
+		if _, ok := extraBody[k]; !ok {
+			extraBody[k] = v
 		}
 	}
 
@@ -660,28 +894,72 @@ func (c *coordinator) buildProvider(providerCfg config.ProviderConfig, model con
 	apiKey, _ := c.cfg.Resolve(providerCfg.APIKey)
 	baseURL, _ := c.cfg.Resolve(providerCfg.BaseURL)
 
+	// handle special headers for anthropic
+	if providerCfg.Type == anthropic.Name {
+		// Without fine-grained tool streaming the tool input is only sent
+		// once complete, instead of as input_json_delta parts. Proxies and
+		// other providers compatible with the API may reject the beta.
+		if isAnthropicAPI(baseURL) {
+			addAnthropicBeta(headers, "fine-grained-tool-streaming-2025-05-14")
+		}
+		if c.isAnthropicThinking(model) {
+			addAnthropicBeta(headers, "interleaved-thinking-2025-05-14")
+		}
+	}
+	extraBody = withServiceTier(providerCfg, model, extraBody)
+
+	if len(c.cfg.Tools.Strict) > 0 && !supportsStrictTools(providerCfg.Type) {
+		slog.Warn("Strict tool schemas not supported by provider, sending them as non-strict", "provider", providerCfg.ID)
+	}
+	if model.ParallelToolCalls != nil && !supportsStrictTools(providerCfg.Type) && providerCfg.Type != anthropic.Name {
+		slog.Warn("Parallel tool calls setting not supported by provider, ignoring", "provider", providerCfg.ID)
+	}
+
+	httpClient := c.httpClient(providerCfg)
 	switch providerCfg.Type {
 	case openai.Name:
-		return c.buildOpenaiProvider(baseURL, apiKey, headers)
+		return c.buildOpenaiProvider(baseURL, apiKey, headers, extraBody, model.Audio, c.openaiHTTPClient(httpClient))
 	case anthropic.Name:
-		return c.buildAnthropicProvider(baseURL, apiKey, headers)
+		return c.buildAnthropicProvider(baseURL, apiKey, headers, extraBody, httpClient)
 	case openrouter.Name:
-		return c.buildOpenrouterProvider(baseURL, apiKey, headers)
+		return c.buildOpenrouterProvider(baseURL, apiKey, headers, httpClient)
 	case azure.Name:
-		return c.buildAzureProvider(baseURL, apiKey, headers, providerCfg.ExtraParams)
+		// The API version of the configuration wins over the environment.
+		apiVersion := cmp.Or(providerCfg.APIVersion, providerCfg.ExtraParams["apiVersion"])
+		return c.buildAzureProvider(baseURL, apiKey, apiVersion, headers, c.openaiHTTPClient(httpClient))
 	case bedrock.Name:
-		return c.buildBedrockProvider(headers)
+		return c.buildBedrockProvider(headers, httpClient)
 	case google.Name:
-		return c.buildGoogleProvider(baseURL, apiKey, headers)
+		return c.buildGoogleProvider(baseURL, apiKey, headers, httpClient)
 	case "google-vertex":
-		return c.buildGoogleVertexProvider(headers, providerCfg.ExtraParams)
+		return c.buildGoogleVertexProvider(headers, providerCfg.ExtraParams, httpClient)
 	case openaicompat.Name:
-		return c.buildOpenaiCompatProvider(baseURL, apiKey, headers, providerCfg.ExtraBody)
+		return c.buildOpenaiCompatProvider(baseURL, apiKey, headers, extraBody, c.openaiHTTPClient(httpClient))
 	default:
 		return nil, fmt.Errorf("provider type not supported: %q", providerCfg.Type)
 	}
 }
 
+// httpClient returns a client sending requests over the transport shared by
+// the models of the provider, logging them in debug mode.
+func (c *coordinator) httpClient(providerCfg config.ProviderConfig) *http.Client {
+	var transport http.RoundTripper = c.transports.get(providerCfg)
+	if c.cfg.Options.Debug {
+		transport = &log.HTTPRoundTripLogger{Transport: transport}
+	}
+	return &http.Client{Transport: transport}
+}
+
+// openaiHTTPClient returns the client OpenAI style providers send their
+// requests with, rewriting the tool settings of the requests.
+func (c *coordinator) openaiHTTPClient(httpClient *http.Client) *http.Client {
+	httpClient.Transport = &toolCallsTransport{
+		transport: httpClient.Transport,
+		strict:    c.cfg.Tools.Strict,
+	}
+	return httpClient
+}
+
 func isExactoSupported(modelID string) bool {
 	supportedModels := []string{
 		"moonshotai/kimi-k2-0905",
@@ -742,6 +1020,34 @@ func (c *coordinator) QueuedPrompts(sessionID string) int {
 	return c.currentAgent.QueuedPrompts(sessionID)
 }
 
+func (c *coordinator) SetDryRun(sessionID string, dryRun bool) {
+	c.currentAgent.SetDryRun(sessionID, dryRun)
+}
+
+func (c *coordinator) IsDryRun(sessionID string) bool {
+	return c.cfg.Options.ReadOnly || c.currentAgent.IsDryRun(sessionID)
+}
+
+func (c *coordinator) SetPlanMode(sessionID string, planMode bool) {
+	c.currentAgent.SetPlanMode(sessionID, planMode)
+}
+
+func (c *coordinator) IsPlanMode(sessionID string) bool {
+	return c.currentAgent.IsPlanMode(sessionID)
+}
+
+func (c *coordinator) ConfirmStopPhrase(sessionID string) {
+	c.currentAgent.ConfirmStopPhrase(sessionID)
+}
+
+func (c *coordinator) TopicChanged(ctx context.Context, sessionID, prompt string) (bool, error) {
+	return c.currentAgent.TopicChanged(ctx, sessionID, prompt)
+}
+
+func (c *coordinator) StartNewTopic(ctx context.Context, sessionID string) (session.Session, error) {
+	return c.currentAgent.StartNewTopic(ctx, sessionID)
+}
+
 func (c *coordinator) Summarize(ctx context.Context, sessionID string) error {
 	providerCfg, ok := c.cfg.Providers.Get(c.currentAgent.Model().ModelCfg.Provider)
 	if !ok {
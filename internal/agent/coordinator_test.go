@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"testing"
+
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/google"
+	"charm.land/fantasy/providers/openai"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProviderOptionsPriority(t *testing.T) {
+	t.Parallel()
+
+	openaiCfg := config.ProviderConfig{ID: "openai", Type: openai.Name}
+
+	tests := []struct {
+		name     string
+		priority config.CallPriority
+		options  map[string]any
+		want     string
+	}{
+		{name: "unset", want: ""},
+		{name: "standard", priority: config.CallPriorityStandard, want: "default"},
+		{name: "flex", priority: config.CallPriorityFlex, want: "flex"},
+		{name: "priority", priority: config.CallPriorityPriority, want: "priority"},
+		{name: "batch is ignored", priority: config.CallPriorityBatch, want: ""},
+		{
+			name:     "provider options win",
+			priority: config.CallPriorityFlex,
+			options:  map[string]any{"service_tier": "priority"},
+			want:     "priority",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			model := Model{
+				CatwalkCfg: catwalk.Model{ID: "gpt-4o"},
+				ModelCfg: config.SelectedModel{
+					Model:           "gpt-4o",
+					Provider:        "openai",
+					Priority:        tt.priority,
+					ProviderOptions: tt.options,
+				},
+			}
+			options := getProviderOptions(model, openaiCfg)
+			parsed, ok := options[openai.Name].(*openai.ResponsesProviderOptions)
+			require.True(t, ok)
+			if tt.want == "" {
+				require.Nil(t, parsed.ServiceTier)
+				return
+			}
+			require.NotNil(t, parsed.ServiceTier)
+			require.Equal(t, tt.want, string(*parsed.ServiceTier))
+		})
+	}
+
+	t.Run("anthropic", func(t *testing.T) {
+		t.Parallel()
+
+		anthropicCfg := config.ProviderConfig{ID: "anthropic", Type: anthropic.Name}
+		model := config.SelectedModel{Model: "claude-sonnet-4", Provider: "anthropic", Priority: config.CallPriorityPriority}
+		require.Equal(t, map[string]any{"service_tier": "auto"}, withServiceTier(anthropicCfg, model, nil))
+
+		model.Priority = config.CallPriorityStandard
+		require.Equal(t, map[string]any{"service_tier": "standard_only"}, withServiceTier(anthropicCfg, model, nil))
+
+		extraBody := map[string]any{"service_tier": "auto"}
+		require.Equal(t, map[string]any{"service_tier": "auto"}, withServiceTier(anthropicCfg, model, extraBody), "the extra body wins")
+
+		model.Priority = config.CallPriorityFlex
+		require.Nil(t, withServiceTier(anthropicCfg, model, nil))
+	})
+
+	t.Run("unsupported provider", func(t *testing.T) {
+		t.Parallel()
+
+		model := config.SelectedModel{Model: "gemini-2.5-pro", Provider: "gemini", Priority: config.CallPriorityPriority}
+		require.Nil(t, withServiceTier(config.ProviderConfig{ID: "gemini", Type: google.Name}, model, nil))
+	})
+}
+
+func TestGetProviderOptionsGoogleThinking(t *testing.T) {
+	t.Parallel()
+
+	googleCfg := config.ProviderConfig{ID: "gemini", Type: google.Name}
+
+	tests := []struct {
+		name    string
+		effort  string
+		options map[string]any
+		want    int64
+	}{
+		{name: "unset", want: 2000},
+		{name: "low", effort: "low", want: 1024},
+		{name: "medium", effort: "medium", want: 2000},
+		{name: "high", effort: "high", want: 8192},
+		{
+			name:    "provider options win",
+			effort:  "high",
+			options: map[string]any{"thinking_config": map[string]any{"thinking_budget": 4096}},
+			want:    4096,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			model := Model{
+				CatwalkCfg: catwalk.Model{ID: "gemini-2.5-pro"},
+				ModelCfg: config.SelectedModel{
+					Model:           "gemini-2.5-pro",
+					Provider:        "gemini",
+					ReasoningEffort: tt.effort,
+					ProviderOptions: tt.options,
+				},
+			}
+			options := getProviderOptions(model, googleCfg)
+			parsed, ok := options[google.Name].(*google.ProviderOptions)
+			require.True(t, ok)
+			require.NotNil(t, parsed.ThinkingConfig)
+			require.NotNil(t, parsed.ThinkingConfig.ThinkingBudget)
+			require.Equal(t, tt.want, *parsed.ThinkingConfig.ThinkingBudget)
+		})
+	}
+}
+
+func TestAddAnthropicBeta(t *testing.T) {
+	t.Parallel()
+
+	headers := map[string]string{}
+	addAnthropicBeta(headers, "fine-grained-tool-streaming-2025-05-14")
+	require.Equal(t, "fine-grained-tool-streaming-2025-05-14", headers["anthropic-beta"])
+
+	addAnthropicBeta(headers, "interleaved-thinking-2025-05-14")
+	require.Equal(t, "fine-grained-tool-streaming-2025-05-14,interleaved-thinking-2025-05-14", headers["anthropic-beta"])
+
+	headers = map[string]string{"anthropic-beta": "context-1m-2025-08-07, fine-grained-tool-streaming-2025-05-14"}
+	addAnthropicBeta(headers, "fine-grained-tool-streaming-2025-05-14")
+	require.Equal(t, "context-1m-2025-08-07, fine-grained-tool-streaming-2025-05-14", headers["anthropic-beta"])
+}
+
+func TestIsAnthropicAPI(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, isAnthropicAPI(""))
+	require.True(t, isAnthropicAPI("https://api.anthropic.com/v1"))
+	require.False(t, isAnthropicAPI("https://proxy.example.com/anthropic"))
+	require.False(t, isAnthropicAPI("https://api.anthropic.com.example.com"))
+}
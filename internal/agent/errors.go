@@ -3,6 +3,8 @@ package agent
 import (
 	"context"
 	"errors"
+
+	"github.com/charmbracelet/crush/internal/message"
 )
 
 var (
@@ -10,8 +12,42 @@ var (
 	ErrSessionBusy      = errors.New("session is currently processing another request")
 	ErrEmptyPrompt      = errors.New("prompt is empty")
 	ErrSessionMissing   = errors.New("session id is missing")
+	ErrStepVetoed       = errors.New("step vetoed")
 )
 
 func isCancelledErr(err error) bool {
 	return errors.Is(err, context.Canceled) || errors.Is(err, ErrRequestCancelled)
 }
+
+// CancelError is the cause of the cancellation of the context of a run,
+// telling why it was cancelled. Runs cancelled without one are considered
+// cancelled by the user, or timed out past a deadline.
+//
+//	ctx, cancel := context.WithCancelCause(ctx)
+//	cancel(&agent.CancelError{Reason: message.CancelReasonBudget})
+type CancelError struct {
+	Reason message.CancelReason
+}
+
+func (e *CancelError) Error() string {
+	return "run canceled: " + string(e.Reason)
+}
+
+// cancelReason returns why the run ended by err was cancelled, or "" when
+// it failed instead. ctx is the context of the run.
+func cancelReason(ctx context.Context, err error) message.CancelReason {
+	if errors.Is(err, ErrStepVetoed) {
+		return message.CancelReasonPolicy
+	}
+	if ctx.Err() == nil && !isCancelledErr(err) && !errors.Is(err, context.DeadlineExceeded) {
+		return ""
+	}
+	cause := context.Cause(ctx)
+	if cancelErr := (*CancelError)(nil); errors.As(cause, &cancelErr) {
+		return cancelErr.Reason
+	}
+	if errors.Is(cause, context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return message.CancelReasonTimeout
+	}
+	return message.CancelReasonUser
+}
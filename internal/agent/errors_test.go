@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelReason(t *testing.T) {
+	t.Parallel()
+
+	t.Run("failed run", func(t *testing.T) {
+		t.Parallel()
+		require.Empty(t, cancelReason(t.Context(), errors.New("rate limited")))
+	})
+
+	t.Run("cancelled without a cause", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		require.Equal(t, message.CancelReasonUser, cancelReason(ctx, context.Canceled))
+	})
+
+	t.Run("cancelled with a cause", func(t *testing.T) {
+		t.Parallel()
+		parent, cancel := context.WithCancelCause(t.Context())
+		ctx, stop := context.WithCancel(parent)
+		defer stop()
+		cancel(&CancelError{Reason: message.CancelReasonBudget})
+		require.Equal(t, message.CancelReasonBudget, cancelReason(ctx, fmt.Errorf("stream: %w", context.Canceled)))
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(t.Context(), 0)
+		defer cancel()
+		<-ctx.Done()
+		require.Equal(t, message.CancelReasonTimeout, cancelReason(ctx, context.DeadlineExceeded))
+	})
+
+	t.Run("vetoed step", func(t *testing.T) {
+		t.Parallel()
+		err := fmt.Errorf("%w: %w", ErrStepVetoed, errors.New("no force pushes"))
+		require.Equal(t, message.CancelReasonPolicy, cancelReason(t.Context(), err))
+	})
+}
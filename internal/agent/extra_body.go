@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// extraBodyTransport merges the configured extra body fields into the JSON
+// body of every request, overriding the fields set by the SDK.
+type extraBodyTransport struct {
+	transport http.RoundTripper
+	body      map[string]any
+}
+
+func (t *extraBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Body == http.NoBody || !strings.Contains(req.Header.Get("Content-Type"), "json") {
+		return t.transport.RoundTrip(req)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		slog.Debug("Request body is not a JSON object, not adding extra body", "error", err)
+	} else {
+		maps.Copy(fields, t.body)
+		if merged, err := json.Marshal(fields); err == nil {
+			data = merged
+		}
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	return t.transport.RoundTrip(req)
+}
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtraBodyTransport(t *testing.T) {
+	t.Parallel()
+
+	var got []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		got, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(got)), r.ContentLength)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: &extraBodyTransport{
+		transport: http.DefaultTransport,
+		body: map[string]any{
+			"metadata": map[string]any{"user_id": "crush"},
+			"stream":   false,
+		},
+	}}
+	post := func(contentType, body string) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, server.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	post("application/json", `{"model":"claude","stream":true}`)
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(got, &fields))
+	require.Equal(t, map[string]any{
+		"model":    "claude",
+		"stream":   false,
+		"metadata": map[string]any{"user_id": "crush"},
+	}, fields)
+
+	post("text/plain", "hello")
+	require.Equal(t, "hello", string(got))
+
+	post("application/json", `[1,2]`)
+	require.Equal(t, "[1,2]", string(got))
+}
@@ -0,0 +1,33 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/fact"
+)
+
+// withFacts gives the facts of the session to the model in a system message
+// following the system prompt, so they're kept when earlier turns are
+// summarized or compacted.
+func (a *sessionAgent) withFacts(ctx context.Context, sessionID string, msgs []fantasy.Message) []fantasy.Message {
+	if a.facts == nil {
+		return msgs
+	}
+	facts, err := a.facts.List(ctx, sessionID)
+	if err != nil {
+		slog.Warn("Failed to list the facts of the session", "session_id", sessionID, "error", err)
+		return msgs
+	}
+	formatted := fact.Format(facts)
+	if formatted == "" {
+		return msgs
+	}
+	at := 0
+	for at < len(msgs) && msgs[at].Role == fantasy.MessageRoleSystem {
+		at++
+	}
+	return slices.Insert(slices.Clone(msgs), at, fantasy.NewSystemMessage(formatted))
+}
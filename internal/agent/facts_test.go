@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/fact"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithFacts(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	_, err = q.CreateSession(t.Context(), db.CreateSessionParams{ID: "session", Title: "Test"})
+	require.NoError(t, err)
+
+	msgs := []fantasy.Message{
+		fantasy.NewSystemMessage("You are a coding agent."),
+		fantasy.NewUserMessage("Fix the login"),
+	}
+	a := &sessionAgent{facts: fact.NewService(q)}
+	require.Equal(t, msgs, a.withFacts(t.Context(), "session", msgs))
+
+	_, err = a.facts.Set(t.Context(), "session", "auth", "Tokens come from /api/v2/token", fact.CategoryEndpoint)
+	require.NoError(t, err)
+	got := a.withFacts(t.Context(), "session", msgs)
+	require.Len(t, got, 3)
+	require.Equal(t, msgs[0], got[0])
+	require.Equal(t, fantasy.MessageRoleSystem, got[1].Role)
+	require.Contains(t, got[1].Content[0].(fantasy.TextPart).Text, "- auth: Tokens come from /api/v2/token")
+	require.Equal(t, msgs[1], got[2])
+	require.Len(t, msgs, 2)
+
+	a.facts = nil
+	require.Equal(t, msgs, a.withFacts(t.Context(), "session", msgs))
+}
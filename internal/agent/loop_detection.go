@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+const (
+	defaultLoopRepeatedSteps   = 3
+	defaultLoopFailedToolCalls = 3
+	defaultLoopStaleSteps      = 6
+)
+
+// LoopReason is the heuristic that detected a loop.
+type LoopReason string
+
+const (
+	// LoopRepeatedSteps means the same step, or the same pair of steps,
+	// repeated.
+	LoopRepeatedSteps LoopReason = "repeated_steps"
+	// LoopFailingToolCall means the same tool call kept failing.
+	LoopFailingToolCall LoopReason = "failing_tool_call"
+	// LoopNoProgress means the last steps brought no new content.
+	LoopNoProgress LoopReason = "no_progress"
+)
+
+// LoopDetected describes why the agent was considered stuck.
+type LoopDetected struct {
+	Reason LoopReason
+	// Steps is the number of steps run when the loop was detected.
+	Steps  int
+	Detail string
+}
+
+// Guidance is the message sent to the model for its final step.
+func (l LoopDetected) Guidance() string {
+	return fmt.Sprintf(
+		"You are stuck in a loop: %s. Stop calling tools. Briefly summarize what you did, what is blocking you and what the user could do next.",
+		l.Detail,
+	)
+}
+
+// detectLoop looks for unproductive patterns in the steps of a run.
+func detectLoop(steps []fantasy.StepResult, opts config.LoopDetection) *LoopDetected {
+	if opts.Disabled {
+		return nil
+	}
+	if loop := detectRepeatedSteps(steps, positiveOr(opts.RepeatedSteps, defaultLoopRepeatedSteps)); loop != nil {
+		return loop
+	}
+	if loop := detectFailingToolCall(steps, positiveOr(opts.FailedToolCalls, defaultLoopFailedToolCalls)); loop != nil {
+		return loop
+	}
+	return detectNoProgress(steps, positiveOr(opts.StaleSteps, defaultLoopStaleSteps))
+}
+
+func positiveOr(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
+
+// detectRepeatedSteps finds the last step, or the last two steps, repeated
+// the given number of times.
+func detectRepeatedSteps(steps []fantasy.StepResult, repeats int) *LoopDetected {
+	signatures := make([]string, len(steps))
+	for i, step := range steps {
+		signatures[i] = stepSignature(step)
+	}
+	for period := 1; period <= 2; period++ {
+		n := period * repeats
+		if len(signatures) < n {
+			continue
+		}
+		last := signatures[len(signatures)-n:]
+		repeated := true
+		for i := period; i < n; i++ {
+			if last[i] != last[i-period] {
+				repeated = false
+				break
+			}
+		}
+		if period == 2 && last[0] == last[1] {
+			// Already reported as a single repeated step.
+			repeated = false
+		}
+		if repeated {
+			detail := fmt.Sprintf("the same step was repeated %d times", repeats)
+			if period == 2 {
+				detail = fmt.Sprintf("the same two steps alternated %d times", repeats)
+			}
+			return &LoopDetected{Reason: LoopRepeatedSteps, Steps: len(steps), Detail: detail}
+		}
+	}
+	return nil
+}
+
+// detectFailingToolCall finds a tool call that failed the given number of
+// times with the same input.
+func detectFailingToolCall(steps []fantasy.StepResult, failures int) *LoopDetected {
+	counts := map[string]int{}
+	for _, step := range steps {
+		calls := map[string]string{}
+		for _, tc := range step.Content.ToolCalls() {
+			calls[tc.ToolCallID] = tc.ToolName + "\x00" + normalizeToolInput(tc.Input)
+		}
+		for _, result := range step.Content.ToolResults() {
+			if result.Result.GetType() != fantasy.ToolResultContentTypeError {
+				continue
+			}
+			key, ok := calls[result.ToolCallID]
+			if !ok {
+				continue
+			}
+			counts[key]++
+			if counts[key] >= failures {
+				return &LoopDetected{
+					Reason: LoopFailingToolCall,
+					Steps:  len(steps),
+					Detail: fmt.Sprintf("the %s tool failed %d times with the same input", result.ToolName, counts[key]),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// detectNoProgress finds the given number of steps in a row whose text and
+// tool results were all seen before.
+func detectNoProgress(steps []fantasy.StepResult, stale int) *LoopDetected {
+	seen := map[string]bool{}
+	run := 0
+	for _, step := range steps {
+		isNew := false
+		for _, content := range stepContents(step) {
+			if !seen[content] {
+				seen[content] = true
+				isNew = true
+			}
+		}
+		if isNew {
+			run = 0
+			continue
+		}
+		run++
+	}
+	if run < stale {
+		return nil
+	}
+	return &LoopDetected{
+		Reason: LoopNoProgress,
+		Steps:  len(steps),
+		Detail: fmt.Sprintf("the last %d steps brought nothing new", run),
+	}
+}
+
+// stepSignature identifies what the model did in a step.
+func stepSignature(step fantasy.StepResult) string {
+	var sb strings.Builder
+	sb.WriteString(strings.TrimSpace(step.Content.Text()))
+	for _, tc := range step.Content.ToolCalls() {
+		sb.WriteString("\x00" + tc.ToolName + "\x00" + normalizeToolInput(tc.Input))
+	}
+	return sb.String()
+}
+
+// stepContents returns the text and the tool results of a step.
+func stepContents(step fantasy.StepResult) []string {
+	var contents []string
+	if text := strings.TrimSpace(step.Content.Text()); text != "" {
+		contents = append(contents, text)
+	}
+	for _, result := range step.Content.ToolResults() {
+		var output string
+		switch result.Result.GetType() {
+		case fantasy.ToolResultContentTypeText:
+			if r, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentText](result.Result); ok {
+				output = strings.TrimPrefix(r.Text, duplicateToolCallNotice)
+			}
+		case fantasy.ToolResultContentTypeError:
+			if r, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentError](result.Result); ok && r.Error != nil {
+				output = r.Error.Error()
+			}
+		default:
+			continue
+		}
+		contents = append(contents, result.ToolName+"\x00"+output)
+	}
+	return contents
+}
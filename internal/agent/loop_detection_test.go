@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+// toolStep is a step calling a tool with the given input, the result is an
+// error when failed is set.
+func toolStep(id, input, output string, failed bool) fantasy.StepResult {
+	var result fantasy.ToolResultOutputContent = fantasy.ToolResultOutputContentText{Text: output}
+	if failed {
+		result = fantasy.ToolResultOutputContentError{Error: errors.New(output)}
+	}
+	return fantasy.StepResult{Response: fantasy.Response{Content: fantasy.ResponseContent{
+		fantasy.ToolCallContent{ToolCallID: id, ToolName: "grep", Input: input},
+		fantasy.ToolResultContent{ToolCallID: id, ToolName: "grep", Result: result},
+	}}}
+}
+
+func TestDetectLoop(t *testing.T) {
+	t.Parallel()
+
+	opts := config.LoopDetection{}
+
+	t.Run("progress", func(t *testing.T) {
+		t.Parallel()
+		var steps []fantasy.StepResult
+		for i := range 10 {
+			steps = append(steps, toolStep("call", fmt.Sprintf(`{"pattern":"%d"}`, i), fmt.Sprint(i), false))
+		}
+		require.Nil(t, detectLoop(steps, opts))
+	})
+
+	t.Run("repeated step", func(t *testing.T) {
+		t.Parallel()
+		steps := []fantasy.StepResult{
+			toolStep("1", `{"pattern":"foo"}`, "a", false),
+			toolStep("2", `{"pattern": "foo"}`, "a", false),
+		}
+		require.Nil(t, detectLoop(steps, opts))
+		steps = append(steps, toolStep("3", `{"pattern":"foo"}`, "a", false))
+		loop := detectLoop(steps, opts)
+		require.NotNil(t, loop)
+		require.Equal(t, LoopRepeatedSteps, loop.Reason)
+		require.Equal(t, 3, loop.Steps)
+	})
+
+	t.Run("alternating steps", func(t *testing.T) {
+		t.Parallel()
+		var steps []fantasy.StepResult
+		for i := range 6 {
+			input := `{"pattern":"foo"}`
+			if i%2 == 1 {
+				input = `{"pattern":"bar"}`
+			}
+			steps = append(steps, toolStep(fmt.Sprint(i), input, fmt.Sprint(i), false))
+		}
+		loop := detectLoop(steps, opts)
+		require.NotNil(t, loop)
+		require.Equal(t, LoopRepeatedSteps, loop.Reason)
+		require.Contains(t, loop.Detail, "alternated")
+	})
+
+	t.Run("failing tool call", func(t *testing.T) {
+		t.Parallel()
+		steps := []fantasy.StepResult{
+			toolStep("1", `{"pattern":"("}`, "bad pattern 1", true),
+			toolStep("2", `{"pattern":"ok"}`, "found", false),
+			toolStep("3", `{"pattern":"("}`, "bad pattern 2", true),
+			toolStep("4", `{"pattern":"other"}`, "found again", false),
+		}
+		require.Nil(t, detectLoop(steps, opts))
+		steps = append(steps, toolStep("5", `{"pattern":"("}`, "bad pattern 3", true))
+		loop := detectLoop(steps, opts)
+		require.NotNil(t, loop)
+		require.Equal(t, LoopFailingToolCall, loop.Reason)
+	})
+
+	t.Run("no progress", func(t *testing.T) {
+		t.Parallel()
+		custom := config.LoopDetection{StaleSteps: 2}
+		steps := []fantasy.StepResult{
+			toolStep("1", `{"pattern":"a"}`, "same", false),
+			toolStep("2", `{"pattern":"b"}`, "same", false),
+		}
+		require.Nil(t, detectLoop(steps, custom))
+		steps = append(steps, toolStep("3", `{"pattern":"c"}`, duplicateToolCallNotice+"same", false))
+		loop := detectLoop(steps, custom)
+		require.NotNil(t, loop)
+		require.Equal(t, LoopNoProgress, loop.Reason)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		steps := []fantasy.StepResult{
+			toolStep("1", `{}`, "a", false),
+			toolStep("2", `{}`, "a", false),
+			toolStep("3", `{}`, "a", false),
+		}
+		require.Nil(t, detectLoop(steps, config.LoopDetection{Disabled: true}))
+	})
+}
@@ -0,0 +1,172 @@
+package prompt
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// contextCache keeps the context files that were read, so building the
+// prompts of every turn doesn't read them again. The directories of the
+// context paths are watched and their entries dropped when something
+// changes in them. Paths that can't be watched are checked for changes of
+// their modification time and size instead.
+type contextCache struct {
+	mu      sync.Mutex
+	entries map[string]contextEntry
+
+	watchOnce sync.Once
+	watcher   *fsnotify.Watcher
+}
+
+type contextEntry struct {
+	files   []ContextFile
+	watched bool
+	// stamps tell unwatched files apart from their newer versions.
+	stamps map[string]fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+var contextFileCache = &contextCache{}
+
+// load returns the context files of the path, a file or a directory,
+// reading them only if they changed since the last time.
+func (c *contextCache) load(fullPath string) []ContextFile {
+	c.watchOnce.Do(c.startWatching)
+
+	c.mu.Lock()
+	entry, ok := c.entries[fullPath]
+	c.mu.Unlock()
+	if ok && (entry.watched || unchanged(entry.stamps)) {
+		return entry.files
+	}
+
+	// Watch before reading so changes made in between aren't missed.
+	watched := c.watch(fullPath)
+	entry = contextEntry{
+		files:   processContextPath(fullPath, ""),
+		watched: watched,
+	}
+	if !watched {
+		if info, err := os.Stat(fullPath); err != nil || info.IsDir() {
+			// Directories can get new files, only files can be checked.
+			return entry.files
+		}
+		entry.stamps = make(map[string]fileStamp, len(entry.files))
+		for _, f := range entry.files {
+			stamp, ok := stat(f.Path)
+			if !ok {
+				return entry.files
+			}
+			entry.stamps[f.Path] = stamp
+		}
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]contextEntry)
+	}
+	c.entries[fullPath] = entry
+	c.mu.Unlock()
+	return entry.files
+}
+
+func (c *contextCache) startWatching() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Warn("Failed to watch context files, they will be checked for changes on every turn", "error", err)
+		return
+	}
+	c.watcher = watcher
+	go c.handleEvents()
+}
+
+// watch watches the directory of the file, which sees it being created or
+// replaced, or the directory and its subdirectories. It reports whether the
+// changes to the path will be seen.
+func (c *contextCache) watch(fullPath string) bool {
+	if c.watcher == nil {
+		return false
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil || !info.IsDir() {
+		return c.watcher.Add(filepath.Dir(fullPath)) == nil
+	}
+	watched := true
+	_ = filepath.WalkDir(fullPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			watched = false
+			return nil
+		}
+		if d.IsDir() {
+			if err := c.watcher.Add(path); err != nil {
+				slog.Debug("Failed to watch context directory", "path", path, "error", err)
+				watched = false
+			}
+		}
+		return nil
+	})
+	return watched
+}
+
+func (c *contextCache) handleEvents() {
+	for {
+		select {
+		case ev, ok := <-c.watcher.Events:
+			if !ok {
+				return
+			}
+			c.invalidate(ev.Name)
+		case err, ok := <-c.watcher.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been missed, read everything again to be sure.
+			slog.Debug("Context file watcher error", "error", err)
+			c.mu.Lock()
+			clear(c.entries)
+			c.mu.Unlock()
+		}
+	}
+}
+
+// invalidate drops the entries of the path and of the directories it's in.
+func (c *contextCache) invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if key == path || strings.HasPrefix(path, key+string(filepath.Separator)) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func unchanged(stamps map[string]fileStamp) bool {
+	if stamps == nil {
+		return false
+	}
+	for path, stamp := range stamps {
+		current, ok := stat(path)
+		if !ok || current != stamp {
+			return false
+		}
+	}
+	return true
+}
+
+func stat(path string) (fileStamp, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, false
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, true
+}
@@ -0,0 +1,56 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextCache(t *testing.T) {
+	t.Parallel()
+
+	for name, watch := range map[string]bool{"watched": true, "stamped": false} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &contextCache{}
+			if !watch {
+				c.watchOnce.Do(func() {})
+			}
+			tmp := t.TempDir()
+			file := filepath.Join(tmp, "CRUSH.md")
+			rules := filepath.Join(tmp, ".cursor", "rules")
+			require.NoError(t, os.MkdirAll(rules, 0o755))
+			require.NoError(t, os.WriteFile(file, []byte("v1"), 0o644))
+			require.NoError(t, os.WriteFile(filepath.Join(rules, "go.md"), []byte("rule"), 0o644))
+
+			require.Equal(t, []ContextFile{{Path: file, Content: "v1"}}, c.load(file))
+			require.Equal(t, []ContextFile{{Path: filepath.Join(rules, "go.md"), Content: "rule"}}, c.load(rules))
+			c.mu.Lock()
+			_, cached := c.entries[file]
+			c.mu.Unlock()
+			require.True(t, cached)
+
+			require.NoError(t, os.WriteFile(file, []byte("version 2"), 0o644))
+			require.Eventually(t, func() bool {
+				files := c.load(file)
+				return len(files) == 1 && files[0].Content == "version 2"
+			}, 5*time.Second, 10*time.Millisecond)
+
+			require.NoError(t, os.WriteFile(filepath.Join(rules, "ts.md"), []byte("other rule"), 0o644))
+			require.Eventually(t, func() bool {
+				return len(c.load(rules)) == 2
+			}, 5*time.Second, 10*time.Millisecond)
+
+			missing := filepath.Join(tmp, "AGENTS.md")
+			require.Empty(t, c.load(missing))
+			require.NoError(t, os.WriteFile(missing, []byte("agents"), 0o644))
+			require.Eventually(t, func() bool {
+				return len(c.load(missing)) == 1
+			}, 5*time.Second, 10*time.Millisecond)
+		})
+	}
+}
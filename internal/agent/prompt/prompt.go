@@ -102,11 +102,11 @@ func processFile(filePath string) *ContextFile {
 	}
 }
 
-func processContextPath(p string, cfg config.Config) []ContextFile {
+func processContextPath(p, baseDir string) []ContextFile {
 	var contexts []ContextFile
 	fullPath := p
 	if !filepath.IsAbs(p) {
-		fullPath = filepath.Join(cfg.WorkingDir(), p)
+		fullPath = filepath.Join(baseDir, p)
 	}
 	info, err := os.Stat(fullPath)
 	if err != nil {
@@ -150,18 +150,6 @@ func (p *Prompt) promptData(ctx context.Context, provider, model string, cfg con
 	workingDir := cmp.Or(p.workingDir, cfg.WorkingDir())
 	platform := cmp.Or(p.platform, runtime.GOOS)
 
-	files := map[string][]ContextFile{}
-
-	for _, pth := range cfg.Options.ContextPaths {
-		expanded := expandPath(pth, cfg)
-		pathKey := strings.ToLower(expanded)
-		if _, ok := files[pathKey]; ok {
-			continue
-		}
-		content := processContextPath(expanded, cfg)
-		files[pathKey] = content
-	}
-
 	isGit := isGitRepo(cfg.WorkingDir())
 	data := PromptDat{
 		Provider:   provider,
@@ -180,12 +168,71 @@ func (p *Prompt) promptData(ctx context.Context, provider, model string, cfg con
 		}
 	}
 
-	for _, contextFiles := range files {
-		data.ContextFiles = append(data.ContextFiles, contextFiles...)
-	}
+	data.ContextFiles = loadContextFiles(cfg.WorkingDir(), cfg)
 	return data, nil
 }
 
+// loadContextFiles reads the configured context paths, relative paths are
+// resolved from baseDir. Files are only read again after they changed.
+func loadContextFiles(baseDir string, cfg config.Config) []ContextFile {
+	files := map[string][]ContextFile{}
+
+	for _, pth := range cfg.Options.ContextPaths {
+		expanded := expandPath(pth, cfg)
+		pathKey := strings.ToLower(expanded)
+		if _, ok := files[pathKey]; ok {
+			continue
+		}
+		fullPath := expanded
+		if !filepath.IsAbs(fullPath) {
+			fullPath = filepath.Join(baseDir, fullPath)
+		}
+		files[pathKey] = contextFileCache.load(fullPath)
+	}
+
+	var contextFiles []ContextFile
+	for _, f := range files {
+		contextFiles = append(contextFiles, f...)
+	}
+	return contextFiles
+}
+
+// SessionContext returns the system prompt addition for a session that
+// works in its own directory or with its own environment variables. It
+// includes the context files of the session directory.
+func SessionContext(workingDir string, env []string, cfg config.Config) string {
+	if workingDir == "" && len(env) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("<session_environment>\n")
+	if workingDir != "" {
+		fmt.Fprintf(&sb, "This session works in %s instead of %s. Commands run there and relative paths are resolved from it.\n", workingDir, cfg.WorkingDir())
+	}
+	if len(env) > 0 {
+		names := make([]string, len(env))
+		for i, kv := range env {
+			names[i], _, _ = strings.Cut(kv, "=")
+		}
+		fmt.Fprintf(&sb, "Environment variables set for the commands of this session: %s\n", strings.Join(names, ", "))
+	}
+	sb.WriteString("</session_environment>")
+
+	if workingDir == "" {
+		return sb.String()
+	}
+	files := loadContextFiles(workingDir, cfg)
+	if len(files) == 0 {
+		return sb.String()
+	}
+	sb.WriteString("\n\n<memory>\n")
+	for _, f := range files {
+		fmt.Fprintf(&sb, "<file path=\"%s\">\n%s\n</file>\n", f.Path, f.Content)
+	}
+	sb.WriteString("</memory>")
+	return sb.String()
+}
+
 func isGitRepo(dir string) bool {
 	_, err := os.Stat(filepath.Join(dir, ".git"))
 	return err == nil
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"context"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/inflight"
+)
+
+// limitedModel waits for a slot of its provider before each call, so the
+// agents sharing the provider don't send more requests at once than it's
+// configured to take.
+type limitedModel struct {
+	fantasy.LanguageModel
+	inflight inflight.Service
+	provider string
+	limit    int
+}
+
+func newLimitedModel(model fantasy.LanguageModel, service inflight.Service, provider string, limit int) fantasy.LanguageModel {
+	return &limitedModel{LanguageModel: model, inflight: service, provider: provider, limit: limit}
+}
+
+func (m *limitedModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	release, err := m.inflight.Acquire(ctx, m.provider, m.limit)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return m.LanguageModel.Generate(ctx, call)
+}
+
+// Stream holds the slot until the stream is read to the end.
+func (m *limitedModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	release, err := m.inflight.Acquire(ctx, m.provider, m.limit)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := m.LanguageModel.Stream(ctx, call)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return func(yield func(fantasy.StreamPart) bool) {
+		defer release()
+		for part := range stream {
+			if !yield(part) {
+				return
+			}
+		}
+	}, nil
+}
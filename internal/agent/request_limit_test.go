@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/inflight"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitedModelHoldsSlotWhileStreaming(t *testing.T) {
+	t.Parallel()
+
+	service := inflight.NewService()
+	model := newLimitedModel(&streamModel{parts: []fantasy.StreamPart{
+		{Type: fantasy.StreamPartTypeTextDelta, Delta: "Hello"},
+		{Type: fantasy.StreamPartTypeFinish},
+	}}, service, "openai", 1)
+
+	stream, err := model.Stream(t.Context(), fantasy.Call{})
+	require.NoError(t, err)
+	require.Equal(t, inflight.Gauge{InFlight: 1}, service.Gauge())
+
+	for part := range stream {
+		if part.Type == fantasy.StreamPartTypeTextDelta {
+			require.Equal(t, inflight.Gauge{InFlight: 1}, service.Gauge())
+			break
+		}
+	}
+	require.Equal(t, inflight.Gauge{}, service.Gauge(), "the slot is released when reading stops")
+}
@@ -0,0 +1,486 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"charm.land/fantasy"
+)
+
+// MarshalResult encodes an agent result as JSON so it can be stored, sent to
+// the clients of the HTTP API and decoded back with UnmarshalResult. The
+// content of the responses and messages, which are interfaces in fantasy, is
+// encoded as {"type": ..., "data": ...} objects. Provider metadata and
+// options are encoded as their provider encodes them, and decoded back as
+// RawProviderData since only the provider knows their type.
+func MarshalResult(result *fantasy.AgentResult) ([]byte, error) {
+	encoded := resultJSON{TotalUsage: result.TotalUsage}
+	var err error
+	if encoded.Response, err = encodeResponse(result.Response); err != nil {
+		return nil, err
+	}
+	for _, step := range result.Steps {
+		var s stepJSON
+		if s.Response, err = encodeResponse(step.Response); err != nil {
+			return nil, err
+		}
+		for _, msg := range step.Messages {
+			m, err := encodeMessage(msg)
+			if err != nil {
+				return nil, err
+			}
+			s.Messages = append(s.Messages, m)
+		}
+		encoded.Steps = append(encoded.Steps, s)
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalResult decodes an agent result encoded with MarshalResult.
+func UnmarshalResult(data []byte) (*fantasy.AgentResult, error) {
+	var encoded resultJSON
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+	result := &fantasy.AgentResult{TotalUsage: encoded.TotalUsage}
+	var err error
+	if result.Response, err = decodeResponse(encoded.Response); err != nil {
+		return nil, err
+	}
+	for _, s := range encoded.Steps {
+		var step fantasy.StepResult
+		if step.Response, err = decodeResponse(s.Response); err != nil {
+			return nil, err
+		}
+		for _, m := range s.Messages {
+			msg, err := decodeMessage(m)
+			if err != nil {
+				return nil, err
+			}
+			step.Messages = append(step.Messages, msg)
+		}
+		result.Steps = append(result.Steps, step)
+	}
+	return result, nil
+}
+
+// RawProviderData is provider metadata or options decoded by
+// UnmarshalResult, kept as the JSON they were encoded to.
+type RawProviderData json.RawMessage
+
+func (RawProviderData) Options() {}
+
+func (d RawProviderData) MarshalJSON() ([]byte, error) {
+	if len(d) == 0 {
+		return []byte("null"), nil
+	}
+	return d, nil
+}
+
+type resultJSON struct {
+	Steps      []stepJSON    `json:"steps"`
+	Response   responseJSON  `json:"response"`
+	TotalUsage fantasy.Usage `json:"total_usage"`
+}
+
+type stepJSON struct {
+	Response responseJSON  `json:"response"`
+	Messages []messageJSON `json:"messages"`
+}
+
+type responseJSON struct {
+	Content          []typedJSON          `json:"content"`
+	FinishReason     fantasy.FinishReason `json:"finish_reason"`
+	Usage            fantasy.Usage        `json:"usage"`
+	Warnings         []warningJSON        `json:"warnings,omitempty"`
+	ProviderMetadata providerDataJSON     `json:"provider_metadata,omitempty"`
+}
+
+type warningJSON struct {
+	Type    fantasy.CallWarningType `json:"type"`
+	Setting string                  `json:"setting,omitempty"`
+	Details string                  `json:"details,omitempty"`
+	Message string                  `json:"message,omitempty"`
+}
+
+type messageJSON struct {
+	Role            fantasy.MessageRole `json:"role"`
+	Content         []typedJSON         `json:"content"`
+	ProviderOptions providerDataJSON    `json:"provider_options,omitempty"`
+}
+
+// typedJSON is a value of an interface type along with its type.
+type typedJSON struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type toolCallJSON struct {
+	ToolCallID       string           `json:"tool_call_id"`
+	ToolName         string           `json:"tool_name"`
+	Input            string           `json:"input"`
+	ProviderExecuted bool             `json:"provider_executed,omitempty"`
+	Invalid          bool             `json:"invalid,omitempty"`
+	ValidationError  string           `json:"validation_error,omitempty"`
+	ProviderMetadata providerDataJSON `json:"provider_metadata,omitempty"`
+}
+
+type toolResultJSON struct {
+	ToolCallID       string           `json:"tool_call_id"`
+	ToolName         string           `json:"tool_name,omitempty"`
+	Result           typedJSON        `json:"result"`
+	ClientMetadata   string           `json:"client_metadata,omitempty"`
+	ProviderExecuted bool             `json:"provider_executed,omitempty"`
+	ProviderMetadata providerDataJSON `json:"provider_metadata,omitempty"`
+	ProviderOptions  providerDataJSON `json:"provider_options,omitempty"`
+}
+
+// providerDataJSON is provider metadata or options, by provider.
+type providerDataJSON map[string]json.RawMessage
+
+type toolErrorJSON struct {
+	Error string `json:"error"`
+}
+
+func encodeResponse(resp fantasy.Response) (responseJSON, error) {
+	encoded := responseJSON{
+		FinishReason: resp.FinishReason,
+		Usage:        resp.Usage,
+	}
+	var err error
+	if encoded.ProviderMetadata, err = encodeProviderData(resp.ProviderMetadata); err != nil {
+		return encoded, err
+	}
+	for _, w := range resp.Warnings {
+		encoded.Warnings = append(encoded.Warnings, warningJSON{
+			Type:    w.Type,
+			Setting: w.Setting,
+			Details: w.Details,
+			Message: w.Message,
+		})
+	}
+	for _, content := range resp.Content {
+		c, err := encodeContent(content)
+		if err != nil {
+			return encoded, err
+		}
+		encoded.Content = append(encoded.Content, c)
+	}
+	return encoded, nil
+}
+
+func decodeResponse(encoded responseJSON) (fantasy.Response, error) {
+	resp := fantasy.Response{
+		FinishReason:     encoded.FinishReason,
+		Usage:            encoded.Usage,
+		ProviderMetadata: decodeProviderData[fantasy.ProviderMetadata](encoded.ProviderMetadata),
+	}
+	for _, w := range encoded.Warnings {
+		resp.Warnings = append(resp.Warnings, fantasy.CallWarning{
+			Type:    w.Type,
+			Setting: w.Setting,
+			Details: w.Details,
+			Message: w.Message,
+		})
+	}
+	for _, c := range encoded.Content {
+		content, err := decodeContent(c)
+		if err != nil {
+			return resp, err
+		}
+		resp.Content = append(resp.Content, content)
+	}
+	return resp, nil
+}
+
+func encodeContent(content fantasy.Content) (typedJSON, error) {
+	var v any
+	switch c := content.(type) {
+	case fantasy.TextContent, fantasy.ReasoningContent, fantasy.FileContent, fantasy.SourceContent:
+		v = c
+	case fantasy.ToolCallContent:
+		metadata, err := encodeProviderData(c.ProviderMetadata)
+		if err != nil {
+			return typedJSON{}, err
+		}
+		tc := toolCallJSON{
+			ToolCallID:       c.ToolCallID,
+			ToolName:         c.ToolName,
+			Input:            c.Input,
+			ProviderExecuted: c.ProviderExecuted,
+			Invalid:          c.Invalid,
+			ProviderMetadata: metadata,
+		}
+		if c.ValidationError != nil {
+			tc.ValidationError = c.ValidationError.Error()
+		}
+		v = tc
+	case fantasy.ToolResultContent:
+		result, err := encodeToolOutput(c.Result)
+		if err != nil {
+			return typedJSON{}, err
+		}
+		metadata, err := encodeProviderData(c.ProviderMetadata)
+		if err != nil {
+			return typedJSON{}, err
+		}
+		v = toolResultJSON{
+			ToolCallID:       c.ToolCallID,
+			ToolName:         c.ToolName,
+			Result:           result,
+			ClientMetadata:   c.ClientMetadata,
+			ProviderExecuted: c.ProviderExecuted,
+			ProviderMetadata: metadata,
+		}
+	default:
+		return typedJSON{}, fmt.Errorf("unsupported content type %q", content.GetType())
+	}
+	return newTypedJSON(string(content.GetType()), v)
+}
+
+func decodeContent(encoded typedJSON) (fantasy.Content, error) {
+	switch fantasy.ContentType(encoded.Type) {
+	case fantasy.ContentTypeText:
+		c, metadata, err := decodeWithProviderData[fantasy.TextContent](encoded.Data, "provider_metadata")
+		c.ProviderMetadata = decodeProviderData[fantasy.ProviderMetadata](metadata)
+		return c, err
+	case fantasy.ContentTypeReasoning:
+		c, metadata, err := decodeWithProviderData[fantasy.ReasoningContent](encoded.Data, "provider_metadata")
+		c.ProviderMetadata = decodeProviderData[fantasy.ProviderMetadata](metadata)
+		return c, err
+	case fantasy.ContentTypeFile:
+		c, metadata, err := decodeWithProviderData[fantasy.FileContent](encoded.Data, "provider_metadata")
+		c.ProviderMetadata = decodeProviderData[fantasy.ProviderMetadata](metadata)
+		return c, err
+	case fantasy.ContentTypeSource:
+		c, metadata, err := decodeWithProviderData[fantasy.SourceContent](encoded.Data, "provider_metadata")
+		c.ProviderMetadata = decodeProviderData[fantasy.ProviderMetadata](metadata)
+		return c, err
+	case fantasy.ContentTypeToolCall:
+		tc, err := decodeAs[toolCallJSON](encoded.Data)
+		if err != nil {
+			return nil, err
+		}
+		content := fantasy.ToolCallContent{
+			ToolCallID:       tc.ToolCallID,
+			ToolName:         tc.ToolName,
+			Input:            tc.Input,
+			ProviderExecuted: tc.ProviderExecuted,
+			Invalid:          tc.Invalid,
+			ProviderMetadata: decodeProviderData[fantasy.ProviderMetadata](tc.ProviderMetadata),
+		}
+		if tc.ValidationError != "" {
+			content.ValidationError = errors.New(tc.ValidationError)
+		}
+		return content, nil
+	case fantasy.ContentTypeToolResult:
+		tr, err := decodeAs[toolResultJSON](encoded.Data)
+		if err != nil {
+			return nil, err
+		}
+		result, err := decodeToolOutput(tr.Result)
+		if err != nil {
+			return nil, err
+		}
+		return fantasy.ToolResultContent{
+			ToolCallID:       tr.ToolCallID,
+			ToolName:         tr.ToolName,
+			Result:           result,
+			ClientMetadata:   tr.ClientMetadata,
+			ProviderExecuted: tr.ProviderExecuted,
+			ProviderMetadata: decodeProviderData[fantasy.ProviderMetadata](tr.ProviderMetadata),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported content type %q", encoded.Type)
+	}
+}
+
+func encodeMessage(msg fantasy.Message) (messageJSON, error) {
+	encoded := messageJSON{Role: msg.Role}
+	var err error
+	if encoded.ProviderOptions, err = encodeProviderData(msg.ProviderOptions); err != nil {
+		return encoded, err
+	}
+	for _, part := range msg.Content {
+		var v any
+		switch p := part.(type) {
+		case fantasy.TextPart, fantasy.ReasoningPart, fantasy.FilePart, fantasy.ToolCallPart:
+			v = p
+		case fantasy.ToolResultPart:
+			output, err := encodeToolOutput(p.Output)
+			if err != nil {
+				return encoded, err
+			}
+			options, err := encodeProviderData(p.ProviderOptions)
+			if err != nil {
+				return encoded, err
+			}
+			v = toolResultJSON{ToolCallID: p.ToolCallID, Result: output, ProviderOptions: options}
+		default:
+			return encoded, fmt.Errorf("unsupported message part type %q", part.GetType())
+		}
+		typed, err := newTypedJSON(string(part.GetType()), v)
+		if err != nil {
+			return encoded, err
+		}
+		encoded.Content = append(encoded.Content, typed)
+	}
+	return encoded, nil
+}
+
+func decodeMessage(encoded messageJSON) (fantasy.Message, error) {
+	msg := fantasy.Message{
+		Role:            encoded.Role,
+		ProviderOptions: decodeProviderData[fantasy.ProviderOptions](encoded.ProviderOptions),
+	}
+	for _, typed := range encoded.Content {
+		var part fantasy.MessagePart
+		var err error
+		switch fantasy.ContentType(typed.Type) {
+		case fantasy.ContentTypeText:
+			p, options, decodeErr := decodeWithProviderData[fantasy.TextPart](typed.Data, "provider_options")
+			p.ProviderOptions = decodeProviderData[fantasy.ProviderOptions](options)
+			part, err = p, decodeErr
+		case fantasy.ContentTypeReasoning:
+			p, options, decodeErr := decodeWithProviderData[fantasy.ReasoningPart](typed.Data, "provider_options")
+			p.ProviderOptions = decodeProviderData[fantasy.ProviderOptions](options)
+			part, err = p, decodeErr
+		case fantasy.ContentTypeFile:
+			p, options, decodeErr := decodeWithProviderData[fantasy.FilePart](typed.Data, "provider_options")
+			p.ProviderOptions = decodeProviderData[fantasy.ProviderOptions](options)
+			part, err = p, decodeErr
+		case fantasy.ContentTypeToolCall:
+			p, options, decodeErr := decodeWithProviderData[fantasy.ToolCallPart](typed.Data, "provider_options")
+			p.ProviderOptions = decodeProviderData[fantasy.ProviderOptions](options)
+			part, err = p, decodeErr
+		case fantasy.ContentTypeToolResult:
+			var tr toolResultJSON
+			if tr, err = decodeAs[toolResultJSON](typed.Data); err != nil {
+				break
+			}
+			var output fantasy.ToolResultOutputContent
+			if output, err = decodeToolOutput(tr.Result); err != nil {
+				break
+			}
+			part = fantasy.ToolResultPart{
+				ToolCallID:      tr.ToolCallID,
+				Output:          output,
+				ProviderOptions: decodeProviderData[fantasy.ProviderOptions](tr.ProviderOptions),
+			}
+		default:
+			err = fmt.Errorf("unsupported message part type %q", typed.Type)
+		}
+		if err != nil {
+			return msg, err
+		}
+		msg.Content = append(msg.Content, part)
+	}
+	return msg, nil
+}
+
+func encodeToolOutput(output fantasy.ToolResultOutputContent) (typedJSON, error) {
+	if output == nil {
+		return typedJSON{}, nil
+	}
+	var v any
+	switch output.GetType() {
+	case fantasy.ToolResultContentTypeText:
+		v, _ = fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentText](output)
+	case fantasy.ToolResultContentTypeError:
+		r, _ := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentError](output)
+		var e toolErrorJSON
+		if r.Error != nil {
+			e.Error = r.Error.Error()
+		}
+		v = e
+	case fantasy.ToolResultContentTypeMedia:
+		v, _ = fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentMedia](output)
+	default:
+		return typedJSON{}, fmt.Errorf("unsupported tool result type %q", output.GetType())
+	}
+	return newTypedJSON(string(output.GetType()), v)
+}
+
+func decodeToolOutput(encoded typedJSON) (fantasy.ToolResultOutputContent, error) {
+	switch fantasy.ToolResultContentType(encoded.Type) {
+	case "":
+		return nil, nil
+	case fantasy.ToolResultContentTypeText:
+		return decodeAs[fantasy.ToolResultOutputContentText](encoded.Data)
+	case fantasy.ToolResultContentTypeError:
+		e, err := decodeAs[toolErrorJSON](encoded.Data)
+		if err != nil {
+			return nil, err
+		}
+		return fantasy.ToolResultOutputContentError{Error: errors.New(e.Error)}, nil
+	case fantasy.ToolResultContentTypeMedia:
+		return decodeAs[fantasy.ToolResultOutputContentMedia](encoded.Data)
+	default:
+		return nil, fmt.Errorf("unsupported tool result type %q", encoded.Type)
+	}
+}
+
+func newTypedJSON(typ string, v any) (typedJSON, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return typedJSON{}, err
+	}
+	return typedJSON{Type: typ, Data: data}, nil
+}
+
+func decodeAs[T any](data json.RawMessage) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+// decodeWithProviderData decodes the JSON object into T apart from the
+// provider data under the key, which can't be decoded into the interfaces
+// fantasy holds it in and is returned apart.
+func decodeWithProviderData[T any](data json.RawMessage, key string) (T, providerDataJSON, error) {
+	var v T
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return v, nil, err
+	}
+	var providerData providerDataJSON
+	if raw, ok := fields[key]; ok {
+		if err := json.Unmarshal(raw, &providerData); err != nil {
+			return v, nil, err
+		}
+		delete(fields, key)
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return v, nil, err
+	}
+	v, err = decodeAs[T](data)
+	return v, providerData, err
+}
+
+func encodeProviderData[M ~map[string]fantasy.ProviderOptionsData](m M) (providerDataJSON, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	encoded := make(providerDataJSON, len(m))
+	for provider, data := range m {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		encoded[provider] = raw
+	}
+	return encoded, nil
+}
+
+func decodeProviderData[M ~map[string]fantasy.ProviderOptionsData](encoded providerDataJSON) M {
+	if len(encoded) == 0 {
+		return nil
+	}
+	m := make(M, len(encoded))
+	for provider, raw := range encoded {
+		m[provider] = RawProviderData(raw)
+	}
+	return m
+}
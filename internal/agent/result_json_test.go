@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultJSON(t *testing.T) {
+	t.Parallel()
+
+	usage := fantasy.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15}
+	toolStep := fantasy.Response{
+		Content: fantasy.ResponseContent{
+			fantasy.ReasoningContent{Text: "thinking"},
+			fantasy.TextContent{Text: "let me look"},
+			fantasy.ToolCallContent{ToolCallID: "call-1", ToolName: "view", Input: `{"file_path":"go.mod"}`},
+			fantasy.ToolCallContent{ToolCallID: "call-2", ToolName: "view", Input: `{`, Invalid: true, ValidationError: errors.New("bad input")},
+			fantasy.ToolResultContent{
+				ToolCallID:     "call-1",
+				ToolName:       "view",
+				Result:         fantasy.ToolResultOutputContentText{Text: "module example"},
+				ClientMetadata: `{"lines":1}`,
+			},
+			fantasy.ToolResultContent{
+				ToolCallID: "call-2",
+				ToolName:   "view",
+				Result:     fantasy.ToolResultOutputContentError{Error: errors.New("bad input")},
+			},
+			fantasy.SourceContent{SourceType: fantasy.SourceTypeURL, ID: "s", URL: "https://charm.land"},
+			fantasy.FileContent{MediaType: "image/png", Data: []byte{1, 2, 3}},
+		},
+		FinishReason: fantasy.FinishReasonToolCalls,
+		Usage:        usage,
+		Warnings:     []fantasy.CallWarning{{Type: fantasy.CallWarningTypeOther, Message: "careful"}},
+	}
+	final := fantasy.Response{
+		Content:      fantasy.ResponseContent{fantasy.TextContent{Text: "done"}},
+		FinishReason: fantasy.FinishReasonStop,
+		Usage:        usage,
+	}
+	result := &fantasy.AgentResult{
+		Steps: []fantasy.StepResult{
+			{
+				Response: toolStep,
+				Messages: []fantasy.Message{
+					{
+						Role: fantasy.MessageRoleAssistant,
+						Content: []fantasy.MessagePart{
+							fantasy.ReasoningPart{Text: "thinking"},
+							fantasy.TextPart{Text: "let me look"},
+							fantasy.ToolCallPart{ToolCallID: "call-1", ToolName: "view", Input: `{"file_path":"go.mod"}`},
+						},
+					},
+					{
+						Role: fantasy.MessageRoleTool,
+						Content: []fantasy.MessagePart{
+							fantasy.ToolResultPart{ToolCallID: "call-1", Output: fantasy.ToolResultOutputContentMedia{Data: "AQID", MediaType: "image/png"}},
+							fantasy.FilePart{Filename: "a.png", Data: []byte{1}, MediaType: "image/png"},
+						},
+					},
+				},
+			},
+			{Response: final},
+		},
+		Response:   final,
+		TotalUsage: fantasy.Usage{InputTokens: 20, OutputTokens: 10, TotalTokens: 30},
+	}
+
+	data, err := MarshalResult(result)
+	require.NoError(t, err)
+
+	decoded, err := UnmarshalResult(data)
+	require.NoError(t, err)
+	require.Equal(t, result, decoded)
+
+	t.Run("stable encoding", func(t *testing.T) {
+		t.Parallel()
+		var raw struct {
+			Response struct {
+				Content []struct {
+					Type string `json:"type"`
+				} `json:"content"`
+			} `json:"response"`
+		}
+		require.NoError(t, json.Unmarshal(data, &raw))
+		require.Len(t, raw.Response.Content, 1)
+		require.Equal(t, "text", raw.Response.Content[0].Type)
+	})
+
+	t.Run("provider data is kept", func(t *testing.T) {
+		t.Parallel()
+		data, err := MarshalResult(&fantasy.AgentResult{
+			Response: fantasy.Response{
+				Content: fantasy.ResponseContent{fantasy.ReasoningContent{
+					Text: "thinking",
+					ProviderMetadata: fantasy.ProviderMetadata{
+						anthropic.Name: &anthropic.ReasoningOptionMetadata{Signature: "sig"},
+					},
+				}},
+			},
+			Steps: []fantasy.StepResult{{Messages: []fantasy.Message{{
+				Role: fantasy.MessageRoleAssistant,
+				ProviderOptions: fantasy.ProviderOptions{
+					anthropic.Name: &anthropic.ProviderOptions{SendReasoning: fantasy.Opt(true)},
+				},
+				Content: []fantasy.MessagePart{fantasy.TextPart{
+					Text: "hi",
+					ProviderOptions: fantasy.ProviderOptions{
+						anthropic.Name: &anthropic.ProviderCacheControlOptions{
+							CacheControl: anthropic.CacheControl{Type: "ephemeral"},
+						},
+					},
+				}},
+			}}}},
+		})
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"signature":"sig"`)
+		require.Contains(t, string(data), `"ephemeral"`)
+
+		decoded, err := UnmarshalResult(data)
+		require.NoError(t, err)
+		reasoning := decoded.Response.Content[0].(fantasy.ReasoningContent)
+		require.Equal(t, "thinking", reasoning.Text)
+		require.IsType(t, RawProviderData{}, reasoning.ProviderMetadata[anthropic.Name])
+		part := decoded.Steps[0].Messages[0].Content[0].(fantasy.TextPart)
+		require.Equal(t, "hi", part.Text)
+		require.IsType(t, RawProviderData{}, part.ProviderOptions[anthropic.Name])
+		require.IsType(t, RawProviderData{}, decoded.Steps[0].Messages[0].ProviderOptions[anthropic.Name])
+
+		reencoded, err := MarshalResult(decoded)
+		require.NoError(t, err)
+		require.JSONEq(t, string(data), string(reencoded))
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		t.Parallel()
+		_, err := UnmarshalResult([]byte(`{"response": {"content": [{"type": "hologram", "data": {}}]}}`))
+		require.ErrorContains(t, err, `unsupported content type "hologram"`)
+	})
+}
@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+)
+
+// maxResumes bounds the continuation requests sent for a single response.
+const maxResumes = 2
+
+// resumeMetadataKey is the key of the resumeMetadata of the responses that
+// were resumed.
+const resumeMetadataKey = "crush"
+
+// continuePrompt asks the model to go on with a response that was cut short,
+// for providers that can't continue a response from a partial one.
+const continuePrompt = "Your previous response was cut off by a network error. Continue it exactly where it stopped, without repeating anything already written and without mentioning the interruption."
+
+// resumeMetadata tells where the continuations of a resumed response start
+// in its text.
+type resumeMetadata struct {
+	Seams []int
+}
+
+func (*resumeMetadata) Options() {}
+
+// resumableModel resumes the responses whose stream drops midway, instead of
+// discarding what was streamed. A continuation request carrying the partial
+// text asks the model to continue from where it stopped, and its text is
+// streamed as the rest of the response.
+//
+// Only responses cut while writing text are resumed, tool calls cut short
+// are left to fail, their input can't be continued reliably.
+type resumableModel struct {
+	fantasy.LanguageModel
+	providerType string
+}
+
+func newResumableModel(model fantasy.LanguageModel, providerType string) fantasy.LanguageModel {
+	return &resumableModel{LanguageModel: model, providerType: providerType}
+}
+
+func (m *resumableModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	stream, err := m.LanguageModel.Stream(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	return func(yield func(fantasy.StreamPart) bool) {
+		var text strings.Builder
+		// textID is the ID of the text of the response, the texts of the
+		// continuations are streamed under it.
+		var textID string
+		var seams []int
+		for resumes := 0; ; resumes++ {
+			var failed *fantasy.StreamPart
+			// Set when the continuation repeats the whitespace the prefill
+			// left out.
+			trimLeading := resumes > 0 && m.prefill(call) && endsWithSpace(text.String())
+			cutText := true
+			for part := range stream {
+				switch part.Type {
+				case fantasy.StreamPartTypeError:
+					failed = &part
+				case fantasy.StreamPartTypeTextStart:
+					cutText = true
+					if textID != "" {
+						// The continuation goes on with the text started.
+						continue
+					}
+					textID = part.ID
+				case fantasy.StreamPartTypeTextDelta:
+					if textID != "" {
+						part.ID = textID
+					}
+					if trimLeading {
+						part.Delta = strings.TrimLeftFunc(part.Delta, unicode.IsSpace)
+						trimLeading = part.Delta == ""
+					}
+					text.WriteString(part.Delta)
+				case fantasy.StreamPartTypeTextEnd:
+					cutText = false
+					if textID != "" {
+						part.ID = textID
+					}
+				case fantasy.StreamPartTypeReasoningStart, fantasy.StreamPartTypeReasoningDelta,
+					fantasy.StreamPartTypeReasoningEnd:
+					if resumes > 0 {
+						// The reasoning of the continuation is about going on
+						// with the response, not part of it.
+						continue
+					}
+					cutText = false
+				case fantasy.StreamPartTypeToolInputStart, fantasy.StreamPartTypeToolCall:
+					cutText = false
+				case fantasy.StreamPartTypeFinish:
+					if len(seams) > 0 {
+						if part.ProviderMetadata == nil {
+							part.ProviderMetadata = fantasy.ProviderMetadata{}
+						}
+						part.ProviderMetadata[resumeMetadataKey] = &resumeMetadata{Seams: seams}
+					}
+				}
+				if failed != nil {
+					break
+				}
+				if !yield(part) {
+					return
+				}
+			}
+			if failed == nil {
+				return
+			}
+			if resumes == maxResumes || !cutText || text.Len() == 0 || ctx.Err() != nil {
+				yield(*failed)
+				return
+			}
+
+			slog.Warn("Stream dropped midway, resuming the response", "model", m.Model(), "error", failed.Error, "streamed", text.Len())
+			seams = append(seams, text.Len())
+			stream, err = m.LanguageModel.Stream(ctx, m.continuation(call, text.String()))
+			if err != nil {
+				yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: err})
+				return
+			}
+		}
+	}, nil
+}
+
+// continuation returns the call continuing the partial response. Anthropic
+// models continue a last assistant message, unless thinking, others are
+// asked to.
+func (m *resumableModel) continuation(call fantasy.Call, partial string) fantasy.Call {
+	prompt := append(fantasy.Prompt{}, call.Prompt...)
+	if m.prefill(call) {
+		// Anthropic rejects assistant messages ending with whitespace.
+		prompt = append(prompt, assistantText(strings.TrimRightFunc(partial, unicode.IsSpace)))
+	} else {
+		prompt = append(prompt, assistantText(partial), fantasy.NewUserMessage(continuePrompt))
+	}
+	call.Prompt = prompt
+	return call
+}
+
+func (m *resumableModel) prefill(call fantasy.Call) bool {
+	if m.providerType != anthropic.Name {
+		return false
+	}
+	opts, ok := call.ProviderOptions[anthropic.Name].(*anthropic.ProviderOptions)
+	return !ok || opts.Thinking == nil
+}
+
+func assistantText(text string) fantasy.Message {
+	return fantasy.Message{
+		Role:    fantasy.MessageRoleAssistant,
+		Content: []fantasy.MessagePart{fantasy.TextPart{Text: text}},
+	}
+}
+
+func endsWithSpace(s string) bool {
+	return strings.TrimRightFunc(s, unicode.IsSpace) != s
+}
+
+// resumeSeams returns where the continuations of the response start in its
+// text, nil when it wasn't resumed.
+func resumeSeams(metadata fantasy.ProviderMetadata) []int {
+	resumed, ok := metadata[resumeMetadataKey].(*resumeMetadata)
+	if !ok {
+		return nil
+	}
+	return resumed.Seams
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/openai"
+	"github.com/stretchr/testify/require"
+)
+
+// droppingModel streams a response per call, recording the calls.
+type droppingModel struct {
+	fantasy.LanguageModel
+	responses [][]fantasy.StreamPart
+	calls     []fantasy.Call
+}
+
+func (m *droppingModel) Model() string { return "test" }
+
+func (m *droppingModel) Stream(_ context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	parts := m.responses[len(m.calls)]
+	m.calls = append(m.calls, call)
+	return func(yield func(fantasy.StreamPart) bool) {
+		for _, part := range parts {
+			if !yield(part) {
+				return
+			}
+		}
+	}, nil
+}
+
+var errDropped = errors.New("unexpected EOF")
+
+func textParts(id string, deltas ...string) []fantasy.StreamPart {
+	parts := []fantasy.StreamPart{{Type: fantasy.StreamPartTypeTextStart, ID: id}}
+	for _, delta := range deltas {
+		parts = append(parts, fantasy.StreamPart{Type: fantasy.StreamPartTypeTextDelta, ID: id, Delta: delta})
+	}
+	return parts
+}
+
+func collectText(t *testing.T, model fantasy.LanguageModel, call fantasy.Call) (string, []fantasy.StreamPart) {
+	t.Helper()
+	stream, err := model.Stream(t.Context(), call)
+	require.NoError(t, err)
+	var text strings.Builder
+	var parts []fantasy.StreamPart
+	for part := range stream {
+		if part.Type == fantasy.StreamPartTypeTextDelta {
+			require.Equal(t, "1", part.ID)
+			text.WriteString(part.Delta)
+		}
+		parts = append(parts, part)
+	}
+	return text.String(), parts
+}
+
+func TestResumableModel(t *testing.T) {
+	t.Parallel()
+
+	inner := &droppingModel{responses: [][]fantasy.StreamPart{
+		append(textParts("1", "The fix is ", "to close "), fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: errDropped}),
+		append(textParts("2", "the file."),
+			fantasy.StreamPart{Type: fantasy.StreamPartTypeTextEnd, ID: "2"},
+			fantasy.StreamPart{Type: fantasy.StreamPartTypeFinish, FinishReason: fantasy.FinishReasonStop},
+		),
+	}}
+	call := fantasy.Call{Prompt: fantasy.Prompt{fantasy.NewUserMessage("fix it")}}
+	text, parts := collectText(t, newResumableModel(inner, openai.Name), call)
+
+	require.Equal(t, "The fix is to close the file.", text)
+	starts := 0
+	for _, part := range parts {
+		require.NotEqual(t, fantasy.StreamPartTypeError, part.Type)
+		if part.Type == fantasy.StreamPartTypeTextStart {
+			starts++
+		}
+	}
+	require.Equal(t, 1, starts)
+	finish := parts[len(parts)-1]
+	require.Equal(t, fantasy.StreamPartTypeFinish, finish.Type)
+	require.Equal(t, []int{len("The fix is to close ")}, resumeSeams(finish.ProviderMetadata))
+
+	// The model is asked to go on with the partial response.
+	require.Len(t, inner.calls, 2)
+	continuation := inner.calls[1].Prompt
+	require.Len(t, continuation, 3)
+	require.Equal(t, fantasy.MessageRoleAssistant, continuation[1].Role)
+	require.Equal(t, fantasy.TextPart{Text: "The fix is to close "}, continuation[1].Content[0])
+	require.Equal(t, fantasy.NewUserMessage(continuePrompt), continuation[2])
+}
+
+func TestResumableModelPrefill(t *testing.T) {
+	t.Parallel()
+
+	inner := &droppingModel{responses: [][]fantasy.StreamPart{
+		append(textParts("1", "The fix is "), fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: errDropped}),
+		append(textParts("2", " to close it."), fantasy.StreamPart{Type: fantasy.StreamPartTypeFinish}),
+	}}
+	call := fantasy.Call{Prompt: fantasy.Prompt{fantasy.NewUserMessage("fix it")}}
+	text, _ := collectText(t, newResumableModel(inner, anthropic.Name), call)
+
+	// Anthropic continues the response itself, without trailing whitespace.
+	require.Equal(t, "The fix is to close it.", text)
+	continuation := inner.calls[1].Prompt
+	require.Len(t, continuation, 2)
+	require.Equal(t, fantasy.TextPart{Text: "The fix is"}, continuation[1].Content[0])
+}
+
+func TestResumableModelGivesUp(t *testing.T) {
+	t.Parallel()
+
+	dropped := fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: errDropped}
+	for name, responses := range map[string][][]fantasy.StreamPart{
+		"nothing streamed": {{dropped}},
+		"tool call cut": {append(textParts("1", "Let me look."),
+			fantasy.StreamPart{Type: fantasy.StreamPartTypeTextEnd, ID: "1"},
+			fantasy.StreamPart{Type: fantasy.StreamPartTypeToolInputStart, ID: "call"},
+			dropped,
+		)},
+		"dropping again": {
+			append(textParts("1", "a"), dropped),
+			append(textParts("2", "b"), dropped),
+			append(textParts("3", "c"), dropped),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			inner := &droppingModel{responses: responses}
+			_, parts := collectText(t, newResumableModel(inner, openai.Name), fantasy.Call{})
+			require.ErrorIs(t, parts[len(parts)-1].Error, errDropped)
+			require.Len(t, inner.calls, len(responses))
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// testCommand matches the shell commands that run tests.
+var testCommand = regexp.MustCompile(`(^|[\s;&|(])(go test|gotestsum|(npm|pnpm|yarn|bun)( run)? test|npx (jest|vitest)|jest|vitest|pytest|python3? -m (pytest|unittest)|tox|cargo (test|nextest)|(make|just|task) test|mix test|rspec|dotnet test|(mvn|\./mvnw|gradle|\./gradlew) test|phpunit|ctest)\b`)
+
+// addRunSummary sums up the turn started by the prompt and stores the
+// summary on the last assistant message. It's only a nicety, so failing
+// to do it is logged and the turn goes on.
+func (a *sessionAgent) addRunSummary(ctx context.Context, prompt message.Message, last *message.Message) {
+	msgs, err := a.messages.List(ctx, prompt.SessionID)
+	if err != nil {
+		slog.Warn("Failed to list messages for the run summary", "session_id", prompt.SessionID, "error", err)
+		return
+	}
+	i := slices.IndexFunc(msgs, func(msg message.Message) bool { return msg.ID == prompt.ID })
+	if i < 0 {
+		return
+	}
+	last.SetRunSummary(summarizeRun(msgs[i+1:]))
+	if err := a.messages.Update(ctx, *last); err != nil {
+		slog.Warn("Failed to save the run summary", "session_id", prompt.SessionID, "error", err)
+	}
+}
+
+// summarizeRun sums up what the agent did in the messages of a turn: the
+// files it changed and the commands it ran, which of them were tests, and
+// what's left to look at. Calls that failed or were only described in dry
+// run mode don't count.
+func summarizeRun(msgs []message.Message) message.RunSummary {
+	results := make(map[string]message.ToolResult)
+	for _, msg := range msgs {
+		for _, result := range msg.ToolResults() {
+			results[result.ToolCallID] = result
+		}
+	}
+
+	var summary message.RunSummary
+	// Whether the last run of each command failed, to suggest following up
+	// on the ones that still fail.
+	lastFailed := make(map[string]bool)
+	var commandOrder []string
+	for _, msg := range msgs {
+		if msg.Role != message.Assistant {
+			continue
+		}
+		summary.Steps++
+		for _, call := range msg.ToolCalls() {
+			result, ok := results[call.ID]
+			if !ok || tools.IsDryRunResponse(result.Content) {
+				continue
+			}
+			if call.Name == tools.BashToolName {
+				var params tools.BashParams
+				if err := json.Unmarshal([]byte(call.Input), &params); err != nil || params.Command == "" {
+					continue
+				}
+				var metadata tools.BashResponseMetadata
+				_ = json.Unmarshal([]byte(result.Metadata), &metadata)
+				failed := result.IsError || metadata.ExitCode != 0
+				summary.Commands = append(summary.Commands, message.CommandRun{Command: params.Command, Failed: failed})
+				if testCommand.MatchString(params.Command) {
+					summary.Tests = message.TestsPassed
+					if failed {
+						summary.Tests = message.TestsFailed
+					}
+				}
+				if _, seen := lastFailed[params.Command]; !seen {
+					commandOrder = append(commandOrder, params.Command)
+				}
+				lastFailed[params.Command] = failed
+				continue
+			}
+			if result.IsError {
+				continue
+			}
+			for _, file := range touchedFiles(call, result) {
+				if !slices.Contains(summary.FilesTouched, file) {
+					summary.FilesTouched = append(summary.FilesTouched, file)
+				}
+			}
+		}
+	}
+
+	for _, command := range commandOrder {
+		if !lastFailed[command] {
+			continue
+		}
+		switch {
+		case !testCommand.MatchString(command):
+			summary.FollowUps = append(summary.FollowUps, fmt.Sprintf("Check why `%s` failed", command))
+		case summary.Tests == message.TestsFailed:
+			// Once the last test run passed, earlier failures were likely
+			// fixed along the way.
+			summary.FollowUps = append(summary.FollowUps, fmt.Sprintf("Fix the tests failing in `%s`", command))
+		}
+	}
+	return summary
+}
+
+// touchedFiles returns the files the tool call changed.
+func touchedFiles(call message.ToolCall, result message.ToolResult) []string {
+	switch call.Name {
+	case tools.EditToolName, tools.MultiEditToolName, tools.WriteToolName, tools.DownloadToolName:
+		var params struct {
+			FilePath string `json:"file_path"`
+		}
+		if err := json.Unmarshal([]byte(call.Input), &params); err != nil || params.FilePath == "" {
+			return nil
+		}
+		return []string{params.FilePath}
+	case tools.MultiFileEditToolName:
+		var metadata tools.MultiFileEditResponseMetadata
+		if err := json.Unmarshal([]byte(result.Metadata), &metadata); err != nil {
+			return nil
+		}
+		var files []string
+		for _, file := range metadata.Files {
+			if file.Skipped == "" && file.Replacements > 0 {
+				files = append(files, file.FilePath)
+			}
+		}
+		return files
+	}
+	return nil
+}
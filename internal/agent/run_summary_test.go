@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeRun(t *testing.T) {
+	t.Parallel()
+
+	call := func(id, name, input string) message.ToolCall {
+		return message.ToolCall{ID: id, Name: name, Input: input, Finished: true}
+	}
+	result := func(id, content, metadata string, isError bool) message.Message {
+		return message.Message{Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: id, Content: content, Metadata: metadata, IsError: isError},
+		}}
+	}
+	msgs := []message.Message{
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			call("1", "edit", `{"file_path":"/src/main.go","old_string":"a","new_string":"b"}`),
+			call("2", "write", `{"file_path":"/src/broken.go","content":"x"}`),
+			call("3", "bash", `{"command":"go test ./..."}`),
+		}},
+		result("1", "edited", "{}", false),
+		result("2", "permission denied", "", true),
+		result("3", "FAIL", `{"exit_code":1}`, false),
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			call("4", "multi_file_edit", `{"files":["/src/a.go","/src/b.go"]}`),
+			call("5", "bash", `{"command":"go vet ./..."}`),
+			call("6", "edit", `{"file_path":"/src/main.go"}`),
+			call("7", "write", `{"file_path":"/src/dry.go"}`),
+		}},
+		result("4", "done", `{"files":[{"file_path":"/src/a.go","replacements":2},{"file_path":"/src/b.go","skipped":"no match"}]}`, false),
+		result("5", "exit", `{"exit_code":2}`, false),
+		result("6", "edited", "{}", false),
+		result("7", "<dry_run>\nDry run, no changes were made.\nWould: write\n</dry_run>", "", false),
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			call("8", "bash", `{"command":"cd src && go test ./..."}`),
+		}},
+		result("8", "ok", `{}`, false),
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "Done."}}},
+	}
+
+	require.Equal(t, message.RunSummary{
+		Steps:        4,
+		FilesTouched: []string{"/src/main.go", "/src/a.go"},
+		Commands: []message.CommandRun{
+			{Command: "go test ./...", Failed: true},
+			{Command: "go vet ./...", Failed: true},
+			{Command: "cd src && go test ./..."},
+		},
+		Tests:     message.TestsPassed,
+		FollowUps: []string{"Check why `go vet ./...` failed"},
+	}, summarizeRun(msgs))
+
+	// Without the last test run, the tests are still failing.
+	summary := summarizeRun(msgs[:len(msgs)-3])
+	require.Equal(t, message.TestsFailed, summary.Tests)
+	require.Equal(t, []string{
+		"Fix the tests failing in `go test ./...`",
+		"Check why `go vet ./...` failed",
+	}, summary.FollowUps)
+}
+
+func TestTestCommand(t *testing.T) {
+	t.Parallel()
+
+	for _, command := range []string{"go test ./...", "npm test", "pnpm run test", "python -m pytest -x", "cargo nextest run", "make test", "cd web && yarn test"} {
+		require.True(t, testCommand.MatchString(command), command)
+	}
+	for _, command := range []string{"go build ./...", "ls tests", "git log --grep test", "echo latest"} {
+		require.False(t, testCommand.MatchString(command), command)
+	}
+}
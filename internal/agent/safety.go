@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/google"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+)
+
+// googleHarmCategories are the harm categories the safety threshold of
+// Gemini models applies to.
+var googleHarmCategories = []string{
+	"HARM_CATEGORY_HATE_SPEECH",
+	"HARM_CATEGORY_DANGEROUS_CONTENT",
+	"HARM_CATEGORY_HARASSMENT",
+	"HARM_CATEGORY_SEXUALLY_EXPLICIT",
+}
+
+// googleSafetySettings returns the safety settings applying the threshold to
+// every harm category.
+func googleSafetySettings(threshold string) []google.SafetySetting {
+	settings := make([]google.SafetySetting, 0, len(googleHarmCategories))
+	for _, category := range googleHarmCategories {
+		settings = append(settings, google.SafetySetting{Category: category, Threshold: threshold})
+	}
+	return settings
+}
+
+func isGoogleProvider(t catwalk.Type) bool {
+	return t == google.Name || t == "google-vertex"
+}
+
+// contentFilterWarning is added to the responses stopped by the safety
+// filters of the model.
+var contentFilterWarning = fantasy.CallWarning{
+	Type:    fantasy.CallWarningTypeOther,
+	Setting: "safety_threshold",
+	Message: "The response was blocked by the safety filters of the model.",
+}
+
+// safetyWarningModel surfaces the responses Gemini models stop with their
+// safety filters as warnings, fantasy only reports them with the finish
+// reason.
+type safetyWarningModel struct {
+	fantasy.LanguageModel
+}
+
+func newSafetyWarningModel(model fantasy.LanguageModel) fantasy.LanguageModel {
+	return &safetyWarningModel{LanguageModel: model}
+}
+
+func (m *safetyWarningModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	resp, err := m.LanguageModel.Generate(ctx, call)
+	if err == nil && resp.FinishReason == fantasy.FinishReasonContentFilter {
+		resp.Warnings = append(resp.Warnings, contentFilterWarning)
+	}
+	return resp, err
+}
+
+// Stream sends the warning before the finish part. The warnings of a step
+// are the last ones streamed, so it's sent along with the ones of the call.
+func (m *safetyWarningModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	stream, err := m.LanguageModel.Stream(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	return func(yield func(fantasy.StreamPart) bool) {
+		var warnings []fantasy.CallWarning
+		for part := range stream {
+			switch part.Type {
+			case fantasy.StreamPartTypeWarnings:
+				warnings = part.Warnings
+			case fantasy.StreamPartTypeFinish:
+				if part.FinishReason == fantasy.FinishReasonContentFilter {
+					if !yield(fantasy.StreamPart{
+						Type:     fantasy.StreamPartTypeWarnings,
+						Warnings: append(slices.Clone(warnings), contentFilterWarning),
+					}) {
+						return
+					}
+				}
+			}
+			if !yield(part) {
+				return
+			}
+		}
+	}, nil
+}
+
+// warningMessages joins the messages of the warnings, one per line.
+func warningMessages(warnings []fantasy.CallWarning) string {
+	messages := make([]string, 0, len(warnings))
+	for _, w := range warnings {
+		messages = append(messages, w.Message)
+	}
+	return strings.Join(messages, "\n")
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/google"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoogleSafetyThreshold(t *testing.T) {
+	t.Parallel()
+
+	googleCfg := config.ProviderConfig{ID: "gemini", Type: google.Name}
+	options := func(selected config.SelectedModel) *google.ProviderOptions {
+		t.Helper()
+		parsed, ok := getProviderOptions(Model{CatwalkCfg: catwalk.Model{ID: "gemini-2.5-pro"}, ModelCfg: selected}, googleCfg)[google.Name].(*google.ProviderOptions)
+		require.True(t, ok)
+		return parsed
+	}
+
+	require.Empty(t, options(config.SelectedModel{}).SafetySettings)
+
+	settings := options(config.SelectedModel{SafetyThreshold: "BLOCK_ONLY_HIGH"}).SafetySettings
+	require.Len(t, settings, len(googleHarmCategories))
+	for _, setting := range settings {
+		require.Equal(t, "BLOCK_ONLY_HIGH", setting.Threshold)
+	}
+
+	// The settings of the provider options win.
+	settings = options(config.SelectedModel{
+		SafetyThreshold: "BLOCK_ONLY_HIGH",
+		ProviderOptions: map[string]any{"safety_settings": []any{
+			map[string]any{"category": "HARM_CATEGORY_HARASSMENT", "threshold": "OFF"},
+		}},
+	}).SafetySettings
+	require.Equal(t, []google.SafetySetting{{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "OFF"}}, settings)
+}
+
+// cannedModel responds with the given stream parts and response.
+type cannedModel struct {
+	fantasy.LanguageModel
+	parts []fantasy.StreamPart
+	resp  fantasy.Response
+}
+
+func (m *cannedModel) Generate(context.Context, fantasy.Call) (*fantasy.Response, error) {
+	resp := m.resp
+	return &resp, nil
+}
+
+func (m *cannedModel) Stream(context.Context, fantasy.Call) (fantasy.StreamResponse, error) {
+	return slices.Values(m.parts), nil
+}
+
+func TestSafetyWarningModel(t *testing.T) {
+	t.Parallel()
+
+	toolWarning := fantasy.CallWarning{Type: fantasy.CallWarningTypeUnsupportedTool, Message: "unsupported"}
+	model := newSafetyWarningModel(&cannedModel{
+		parts: []fantasy.StreamPart{
+			{Type: fantasy.StreamPartTypeWarnings, Warnings: []fantasy.CallWarning{toolWarning}},
+			{Type: fantasy.StreamPartTypeFinish, FinishReason: fantasy.FinishReasonContentFilter},
+		},
+		resp: fantasy.Response{FinishReason: fantasy.FinishReasonContentFilter},
+	})
+
+	stream, err := model.Stream(t.Context(), fantasy.Call{})
+	require.NoError(t, err)
+	parts := slices.Collect(stream)
+	require.Len(t, parts, 3)
+	require.Equal(t, fantasy.StreamPartTypeWarnings, parts[1].Type)
+	require.Equal(t, []fantasy.CallWarning{toolWarning, contentFilterWarning}, parts[1].Warnings)
+	require.Equal(t, fantasy.StreamPartTypeFinish, parts[2].Type)
+
+	resp, err := model.Generate(t.Context(), fantasy.Call{})
+	require.NoError(t, err)
+	require.Equal(t, []fantasy.CallWarning{contentFilterWarning}, resp.Warnings)
+
+	// Responses that weren't blocked are left alone.
+	model = newSafetyWarningModel(&cannedModel{
+		parts: []fantasy.StreamPart{{Type: fantasy.StreamPartTypeFinish, FinishReason: fantasy.FinishReasonStop}},
+	})
+	stream, err = model.Stream(t.Context(), fantasy.Call{})
+	require.NoError(t, err)
+	require.Len(t, slices.Collect(stream), 1)
+}
@@ -0,0 +1,204 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StopPhraseError is returned when the model wrote one of the configured
+// stop phrases. Generation stops right away, before any tool the model was
+// about to call runs. The session is then held: the next prompt only goes
+// past the phrase once the user confirmed it with ConfirmStopPhrase, and is
+// otherwise taken as the user declining to.
+//
+// Anthropic is also given the phrases as stop sequences, so it stops before
+// writing them and tells which one it stopped at. The other providers
+// either have no stop sequences or report them as the usual end of a turn,
+// so the phrases are only matched in the text they stream.
+type StopPhraseError struct {
+	Phrase string
+}
+
+func (e *StopPhraseError) Error() string {
+	return fmt.Sprintf("stopped at stop phrase %q", e.Phrase)
+}
+
+// stopPhraseDeclined is added to the system prompt of the run following a
+// stop phrase the user didn't confirm going past.
+func stopPhraseDeclined(phrase string) string {
+	return fmt.Sprintf("Your previous response was stopped at the stop phrase %q and the user did not confirm going past it. Don't do what you were about to do after it unless the user explicitly asks for it.", phrase)
+}
+
+// matchStopPhrase returns the stop phrase the text ends up containing once
+// the delta was appended to it, if any. Only the end of the text is looked
+// at, so phrases split across deltas are found without searching the whole
+// text again. Matching ignores case.
+func matchStopPhrase(text, delta string, phrases []string) string {
+	for _, phrase := range phrases {
+		if phrase == "" {
+			continue
+		}
+		tail := text[max(0, len(text)-len(delta)-len(phrase)):]
+		if strings.Contains(strings.ToLower(tail), strings.ToLower(phrase)) {
+			return phrase
+		}
+	}
+	return ""
+}
+
+type stopSequencesContextKey struct{}
+
+// stopSequences are the stop phrases of a run sent to the provider as stop
+// sequences, along with the one it stopped at.
+type stopSequences struct {
+	phrases []string
+
+	mu      sync.Mutex
+	stopped string
+}
+
+// withStopSequences returns a context the requests of which send the stop
+// phrases as stop sequences, where the provider supports it. Blank phrases
+// are left out as providers reject them.
+func withStopSequences(ctx context.Context, phrases []string) (context.Context, *stopSequences) {
+	seqs := &stopSequences{}
+	for _, phrase := range phrases {
+		if strings.TrimSpace(phrase) != "" {
+			seqs.phrases = append(seqs.phrases, phrase)
+		}
+	}
+	if len(seqs.phrases) == 0 {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, stopSequencesContextKey{}, seqs), seqs
+}
+
+func (s *stopSequences) record(phrase string) {
+	if !slices.Contains(s.phrases, phrase) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = phrase
+}
+
+// match returns the stop phrase the provider stopped at, if any.
+func (s *stopSequences) match() string {
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+// stopSequencesTransport sends the stop phrases of the run as the stop
+// sequences of Anthropic messages requests, which the SDK doesn't expose,
+// and records the one the response stopped at.
+type stopSequencesTransport struct {
+	transport http.RoundTripper
+}
+
+func (t *stopSequencesTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	seqs, _ := req.Context().Value(stopSequencesContextKey{}).(*stopSequences)
+	if seqs == nil || !strings.HasSuffix(req.URL.Path, "/messages") ||
+		req.Body == nil || req.Body == http.NoBody || !strings.Contains(req.Header.Get("Content-Type"), "json") {
+		return t.transport.RoundTrip(req)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		slog.Debug("Request body is not a JSON object, not adding stop sequences", "error", err)
+	} else {
+		// Stop sequences set in the extra body are kept.
+		sequences, _ := fields["stop_sequences"].([]any)
+		for _, phrase := range seqs.phrases {
+			sequences = append(sequences, phrase)
+		}
+		fields["stop_sequences"] = sequences
+		if rewritten, err := json.Marshal(fields); err == nil {
+			data = rewritten
+		}
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	resp, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &stopReasonReader{body: resp.Body, seqs: seqs}
+	return resp, nil
+}
+
+// stopReasonReader records the stop sequence a response stopped at as it's
+// read, from the message_delta event of streamed responses or from the body
+// of the others.
+type stopReasonReader struct {
+	body io.ReadCloser
+	seqs *stopSequences
+	line []byte
+}
+
+func (r *stopReasonReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	r.line = append(r.line, p[:n]...)
+	if i := bytes.LastIndexByte(r.line, '\n'); i >= 0 {
+		for line := range bytes.SplitSeq(r.line[:i], []byte("\n")) {
+			r.scan(line)
+		}
+		r.line = append(r.line[:0], r.line[i+1:]...)
+	}
+	if err == io.EOF {
+		r.scan(r.line)
+		r.line = nil
+	}
+	return n, err
+}
+
+func (r *stopReasonReader) Close() error {
+	return r.body.Close()
+}
+
+func (r *stopReasonReader) scan(line []byte) {
+	line = bytes.TrimPrefix(bytes.TrimSpace(line), []byte("data:"))
+	if !bytes.Contains(line, []byte(`"stop_sequence"`)) {
+		return
+	}
+	type stop struct {
+		StopReason   string `json:"stop_reason"`
+		StopSequence string `json:"stop_sequence"`
+	}
+	var body struct {
+		stop
+		Delta stop `json:"delta"`
+	}
+	if err := json.Unmarshal(line, &body); err != nil {
+		return
+	}
+	for _, s := range []stop{body.stop, body.Delta} {
+		if s.StopReason == "stop_sequence" {
+			r.seqs.record(s.StopSequence)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchStopPhrase(t *testing.T) {
+	t.Parallel()
+
+	phrases := []string{"", "BEGIN DESTRUCTIVE", "rm -rf /"}
+	require.Empty(t, matchStopPhrase("Let me look at the tests.", " tests.", phrases))
+	require.Equal(t, "BEGIN DESTRUCTIVE", matchStopPhrase("Next: BEGIN DESTRUCTIVE", " DESTRUCTIVE", phrases), "split across deltas")
+	require.Equal(t, "BEGIN DESTRUCTIVE", matchStopPhrase("begin destructive changes", " changes", phrases), "case is ignored")
+	require.Equal(t, "rm -rf /", matchStopPhrase("I'll run rm -rf /tmp", "/tmp", phrases))
+	require.Empty(t, matchStopPhrase("BEGIN DESTRUCTIVE was mentioned long ago, nothing new here", " here", phrases), "earlier text was already checked")
+	require.Empty(t, matchStopPhrase("BEGIN DESTRUCTIVE", "", nil))
+}
+
+func TestStopSequencesTransport(t *testing.T) {
+	t.Parallel()
+
+	var got map[string]any
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(data)), r.ContentLength)
+		_ = json.Unmarshal(data, &got)
+		_, _ = io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: &stopSequencesTransport{transport: http.DefaultTransport}}
+	ctx, seqs := withStopSequences(t.Context(), []string{" ", "BEGIN DESTRUCTIVE"})
+	post := func(path string) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+path, strings.NewReader(`{"model":"claude","stop_sequences":["END"]}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		_, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	response = "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"stop_reason\":null,\"stop_sequence\":null}}\n\n" +
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\",\"stop_sequence\":null}}\n\n"
+	post("/v1/messages")
+	require.Equal(t, []any{"END", "BEGIN DESTRUCTIVE"}, got["stop_sequences"], "blank phrases are left out")
+	require.Empty(t, seqs.match())
+
+	post("/v1/messages/count_tokens")
+	require.Equal(t, []any{"END"}, got["stop_sequences"], "only messages are stopped")
+
+	response = "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"stop_sequence\",\"stop_sequence\":\"END\"}}\n\n"
+	post("/v1/messages")
+	require.Empty(t, seqs.match(), "stop sequences of the extra body aren't stop phrases")
+
+	response = "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"stop_sequence\",\"stop_sequence\":\"BEGIN DESTRUCTIVE\"}}\n\n"
+	post("/v1/messages")
+	require.Equal(t, "BEGIN DESTRUCTIVE", seqs.match())
+
+	_, seqs = withStopSequences(t.Context(), nil)
+	require.Nil(t, seqs)
+	require.Empty(t, seqs.match())
+}
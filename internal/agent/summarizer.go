@@ -0,0 +1,270 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// SummaryKind is what a summary is made for, each kind can be made by its
+// own summarizer.
+type SummaryKind string
+
+const (
+	// SummarySession is the summary of a session the next turns start from.
+	SummarySession SummaryKind = "session"
+	// SummaryCompaction is the summary a run approaching the context window
+	// goes on from.
+	SummaryCompaction SummaryKind = "compaction"
+	// SummaryToolOutput is the summary of a tool result over the budget.
+	SummaryToolOutput SummaryKind = "tool_output"
+)
+
+// extractiveMaxTokens is the size of extractive summaries of conversations
+// when the request doesn't set one.
+const extractiveMaxTokens = 2000
+
+// SummaryRequest is what to summarize, either a conversation or the output
+// of a tool.
+type SummaryRequest struct {
+	Kind SummaryKind
+	// Messages is the conversation to summarize.
+	Messages []fantasy.Message
+	// ToolName and Text are the tool and the output to summarize when there
+	// are no messages.
+	ToolName string
+	Text     string
+	// Prompt is what models are asked for, a detailed summary of the
+	// conversation when empty.
+	Prompt string
+	// MaxTokens is about the size of the summary, 0 for the default of the
+	// summarizer.
+	MaxTokens       int64
+	ProviderOptions fantasy.ProviderOptions
+	// OnTextDelta, when set, is called with the text of the summary as it's
+	// made.
+	OnTextDelta func(text string) error
+}
+
+// Summary is the summary made for a request.
+type Summary struct {
+	Text string
+	// Usage and ProviderMetadata are those of the model that made the
+	// summary, empty for extractive ones.
+	Usage            fantasy.Usage
+	ProviderMetadata fantasy.ProviderMetadata
+	// Extractive is set when the summary is made of parts of what was
+	// summarized, without a model.
+	Extractive bool
+}
+
+// Summarizer makes the summaries of conversations and tool outputs.
+type Summarizer interface {
+	Summarize(ctx context.Context, req SummaryRequest) (Summary, error)
+}
+
+// summarizers returns the summarizers configured for each kind, those left
+// out use the model falling back to an extractive summary.
+func summarizers(cfg config.Summarizers) map[SummaryKind]Summarizer {
+	configured := map[SummaryKind]config.SummarizerType{
+		SummarySession:    cfg.Session,
+		SummaryCompaction: cfg.Compaction,
+		SummaryToolOutput: cfg.ToolOutput,
+	}
+	result := make(map[SummaryKind]Summarizer)
+	for kind, typ := range configured {
+		if typ == config.SummarizerExtractive {
+			result[kind] = ExtractiveSummarizer{}
+		}
+	}
+	return result
+}
+
+// summarizer returns the summarizer of the kind, by default the large model
+// for conversations and the small one for tool outputs, with an extractive
+// summary when they fail.
+func (a *sessionAgent) summarizer(kind SummaryKind) Summarizer {
+	if s := a.summarizers[kind]; s != nil {
+		return s
+	}
+	model, prompt := a.largeModel, summaryPrompt
+	if kind == SummaryToolOutput {
+		model, prompt = a.smallModel, toolOutputPrompt
+	}
+	return fallbackSummarizer{
+		primary:  modelSummarizer{model: model, systemPrompt: string(prompt)},
+		fallback: ExtractiveSummarizer{},
+	}
+}
+
+// modelSummarizer has a model make the summaries.
+type modelSummarizer struct {
+	model        Model
+	systemPrompt string
+}
+
+func (s modelSummarizer) Summarize(ctx context.Context, req SummaryRequest) (Summary, error) {
+	maxOutput := req.MaxTokens
+	if maxOutput <= 0 {
+		maxOutput = s.model.ModelCfg.MaxOutputTokens(s.model.CatwalkCfg)
+	}
+	agent := fantasy.NewAgent(s.model.Model,
+		fantasy.WithSystemPrompt(s.systemPrompt),
+		fantasy.WithMaxOutputTokens(maxOutput),
+	)
+	prompt := cmp.Or(req.Prompt, "Provide a detailed summary of our conversation above.")
+	if len(req.Messages) == 0 {
+		prompt = fmt.Sprintf("Output of the %s tool:\n\n%s", req.ToolName, req.Text)
+	}
+
+	var resp *fantasy.AgentResult
+	var err error
+	if req.OnTextDelta != nil {
+		resp, err = agent.Stream(ctx, fantasy.AgentStreamCall{
+			Prompt:          prompt,
+			Messages:        req.Messages,
+			ProviderOptions: req.ProviderOptions,
+			OnTextDelta: func(_, text string) error {
+				return req.OnTextDelta(text)
+			},
+		})
+	} else {
+		resp, err = agent.Generate(ctx, fantasy.AgentCall{
+			Prompt:          prompt,
+			Messages:        req.Messages,
+			ProviderOptions: req.ProviderOptions,
+		})
+	}
+	if err != nil {
+		return Summary{}, err
+	}
+	text := resp.Response.Content.Text()
+	if idx := strings.Index(text, "</think>"); idx > 0 {
+		text = text[idx+len("</think>"):]
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return Summary{}, errors.New("empty summary")
+	}
+	return Summary{
+		Text:             text,
+		Usage:            resp.TotalUsage,
+		ProviderMetadata: resp.Response.ProviderMetadata,
+	}, nil
+}
+
+// fallbackSummarizer makes the summary with the fallback when the primary
+// summarizer fails.
+type fallbackSummarizer struct {
+	primary  Summarizer
+	fallback Summarizer
+}
+
+func (s fallbackSummarizer) Summarize(ctx context.Context, req SummaryRequest) (Summary, error) {
+	summary, err := s.primary.Summarize(ctx, req)
+	if err == nil || ctx.Err() != nil {
+		return summary, err
+	}
+	slog.Warn("Failed to summarize, using the fallback", "kind", req.Kind, "error", err)
+	return s.fallback.Summarize(ctx, req)
+}
+
+// ExtractiveSummarizer makes summaries without calling a model, keeping the
+// parts of what's summarized that matter the most. Conversations keep the
+// requests of the user, the files worked on and the last answer, tool
+// outputs keep their structure or their first and last lines.
+type ExtractiveSummarizer struct{}
+
+func (ExtractiveSummarizer) Summarize(_ context.Context, req SummaryRequest) (Summary, error) {
+	var text string
+	if len(req.Messages) == 0 {
+		maxTokens := req.MaxTokens
+		if maxTokens <= 0 {
+			maxTokens = extractiveMaxTokens
+		}
+		text, _ = extractOutput(req.Text, int(maxTokens)*charsPerToken)
+	} else {
+		maxTokens := req.MaxTokens
+		if maxTokens <= 0 || maxTokens > extractiveMaxTokens {
+			maxTokens = extractiveMaxTokens
+		}
+		text = extractConversation(req.Messages, int(maxTokens)*charsPerToken)
+	}
+	if text == "" {
+		return Summary{}, errors.New("nothing to summarize")
+	}
+	if req.OnTextDelta != nil {
+		if err := req.OnTextDelta(text); err != nil {
+			return Summary{}, err
+		}
+	}
+	return Summary{Text: text, Extractive: true}, nil
+}
+
+// maxExtractedRequest is the length requests of the user are cut to in
+// extractive summaries.
+const maxExtractedRequest = 500
+
+// extractConversation lists the requests of the user, the files the tools
+// were called on and the last answer of the conversation, within maxChars.
+func extractConversation(msgs []fantasy.Message, maxChars int) string {
+	var requests, files []string
+	seenFiles := make(map[string]bool)
+	var lastAnswer string
+	for _, msg := range msgs {
+		for _, part := range msg.Content {
+			switch part := part.(type) {
+			case fantasy.TextPart:
+				switch msg.Role {
+				case fantasy.MessageRoleUser:
+					request := strings.TrimSpace(part.Text)
+					if len(request) > maxExtractedRequest {
+						request = strings.ToValidUTF8(request[:maxExtractedRequest], "") + "..."
+					}
+					if request != "" {
+						requests = append(requests, "- "+strings.ReplaceAll(request, "\n", " "))
+					}
+				case fantasy.MessageRoleAssistant:
+					if text := strings.TrimSpace(part.Text); text != "" {
+						lastAnswer = text
+					}
+				}
+			case fantasy.ToolCallPart:
+				var input struct {
+					FilePath string `json:"file_path"`
+					Path     string `json:"path"`
+				}
+				_ = json.Unmarshal([]byte(part.Input), &input)
+				for _, file := range []string{input.FilePath, input.Path} {
+					if file != "" && !seenFiles[file] {
+						seenFiles[file] = true
+						files = append(files, "- "+file)
+					}
+				}
+			}
+		}
+	}
+	if len(requests) == 0 && lastAnswer == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("This summary was extracted from the conversation without a model.\n")
+	if len(requests) > 0 {
+		b.WriteString("\n## Requests\n\n" + strings.Join(requests, "\n") + "\n")
+	}
+	if len(files) > 0 {
+		b.WriteString("\n## Files worked on\n\n" + strings.Join(files, "\n") + "\n")
+	}
+	if lastAnswer != "" {
+		b.WriteString("\n## Last answer\n\n" + lastAnswer + "\n")
+	}
+	return extractLines(strings.TrimSpace(b.String()), maxChars)
+}
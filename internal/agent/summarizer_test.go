@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+var summarizedConversation = []fantasy.Message{
+	fantasy.NewUserMessage("Fix the parser"),
+	{
+		Role: fantasy.MessageRoleAssistant,
+		Content: []fantasy.MessagePart{
+			fantasy.TextPart{Text: "Looking at it."},
+			fantasy.ToolCallPart{ToolCallID: "call-1", ToolName: "edit", Input: `{"file_path":"parser.go"}`},
+		},
+	},
+	{
+		Role: fantasy.MessageRoleTool,
+		Content: []fantasy.MessagePart{
+			fantasy.ToolResultPart{ToolCallID: "call-1", Output: fantasy.ToolResultOutputContentText{Text: "edited"}},
+		},
+	},
+	fantasy.NewUserMessage("Now add a test"),
+	{
+		Role:    fantasy.MessageRoleAssistant,
+		Content: []fantasy.MessagePart{fantasy.TextPart{Text: "Added TestParse."}},
+	},
+}
+
+func TestExtractiveSummarizer(t *testing.T) {
+	t.Parallel()
+
+	summary, err := ExtractiveSummarizer{}.Summarize(t.Context(), SummaryRequest{
+		Kind:     SummarySession,
+		Messages: summarizedConversation,
+	})
+	require.NoError(t, err)
+	require.True(t, summary.Extractive)
+	require.Contains(t, summary.Text, "## Requests\n\n- Fix the parser\n- Now add a test")
+	require.Contains(t, summary.Text, "## Files worked on\n\n- parser.go")
+	require.Contains(t, summary.Text, "## Last answer\n\nAdded TestParse.")
+	require.NotContains(t, summary.Text, "Looking at it.")
+
+	summary, err = ExtractiveSummarizer{}.Summarize(t.Context(), SummaryRequest{
+		Kind:      SummaryToolOutput,
+		Text:      strings.Repeat("line\n", 1000),
+		MaxTokens: 10,
+	})
+	require.NoError(t, err)
+	require.Contains(t, summary.Text, "lines omitted")
+
+	_, err = ExtractiveSummarizer{}.Summarize(t.Context(), SummaryRequest{Kind: SummarySession, Messages: []fantasy.Message{}})
+	require.Error(t, err)
+}
+
+func TestSummarizerFallback(t *testing.T) {
+	t.Parallel()
+
+	a := &sessionAgent{largeModel: Model{Model: &summaryModel{err: errors.New("offline")}}}
+	summary, err := a.summarizer(SummaryCompaction).Summarize(t.Context(), SummaryRequest{
+		Kind:     SummaryCompaction,
+		Messages: summarizedConversation,
+	})
+	require.NoError(t, err)
+	require.True(t, summary.Extractive)
+	require.Contains(t, summary.Text, "Fix the parser")
+
+	a = &sessionAgent{largeModel: Model{Model: &summaryModel{summary: "<think>hmm</think> Parser fixed."}}}
+	summary, err = a.summarizer(SummaryCompaction).Summarize(t.Context(), SummaryRequest{
+		Kind:     SummaryCompaction,
+		Messages: summarizedConversation,
+	})
+	require.NoError(t, err)
+	require.False(t, summary.Extractive)
+	require.Equal(t, "Parser fixed.", summary.Text)
+}
+
+func TestSummarizeSessionExtractive(t *testing.T) {
+	t.Parallel()
+
+	env := testEnv(t)
+	model := &summaryModel{err: errors.New("must not be called")}
+	agent := NewSessionAgent(SessionAgentOptions{
+		LargeModel:  Model{Model: model},
+		SmallModel:  Model{Model: model},
+		Sessions:    env.sessions,
+		Messages:    env.messages,
+		Summarizers: summarizers(config.Summarizers{Session: config.SummarizerExtractive}),
+	})
+
+	sess, err := env.sessions.Create(t.Context(), "extractive")
+	require.NoError(t, err)
+	_, err = env.messages.Create(t.Context(), sess.ID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Fix the parser"}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, agent.Summarize(t.Context(), sess.ID, nil))
+	require.Empty(t, model.prompts)
+
+	sess, err = env.sessions.Get(t.Context(), sess.ID)
+	require.NoError(t, err)
+	summary, err := env.messages.Get(t.Context(), sess.SummaryMessageID)
+	require.NoError(t, err)
+	require.True(t, summary.IsSummaryMessage)
+	require.Contains(t, summary.Content().Text, "- Fix the parser")
+	require.Equal(t, message.FinishReasonEndTurn, summary.FinishReason())
+}
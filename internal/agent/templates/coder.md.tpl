@@ -337,7 +337,11 @@ Diagnostics (lint/typecheck) included in tool output.
 </lsp>
 {{end}}
 
-{{if .ContextFiles}}
+{{if .Config.Options.ResponseLanguage}}<language>
+Always respond to the user in {{.Config.Options.ResponseLanguage}}, regardless of the language of the code, files or tool output. Keep code, identifiers, commands and file paths unchanged.
+</language>
+
+{{end}}{{if .ContextFiles}}
 <memory>
 {{range .ContextFiles}}
 <file path="{{.Path}}">
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/audit"
+)
+
+// auditTools logs every execution of the tools to the audit log. Run as the
+// outermost middleware, it logs the calls served from the cache of the run
+// too, and the output the model was given once shortened to the budget.
+func auditTools(log audit.Service) ToolMiddleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			start := time.Now()
+			resp, err := next(ctx, call)
+
+			entry := audit.Entry{
+				SessionID:  tools.GetSessionFromContext(ctx),
+				MessageID:  tools.GetMessageFromContext(ctx),
+				ToolCallID: call.ID,
+				ToolName:   call.Name,
+				Input:      call.Input,
+				Duration:   time.Since(start),
+				Status:     audit.StatusSuccess,
+				ExitCode:   exitCode(call.Name, resp.Metadata),
+			}
+			output := resp.Content
+			switch {
+			case err != nil:
+				entry.Status = audit.StatusFailed
+				output = err.Error()
+			case resp.IsError:
+				entry.Status = audit.StatusError
+			}
+			// Record even if the call was cancelled, it may have done something.
+			if _, recordErr := log.Record(context.WithoutCancel(ctx), entry, output); recordErr != nil {
+				slog.Warn("Failed to record tool execution in the audit log", "tool", entry.ToolName, "error", recordErr)
+			}
+			return resp, err
+		}
+	}
+}
+
+// exitCode returns the exit code of the commands the tool ran, from the
+// metadata of its response.
+func exitCode(toolName, metadata string) *int64 {
+	if metadata == "" {
+		return nil
+	}
+	var meta struct {
+		ExitCode *int64 `json:"exit_code"`
+	}
+	if err := json.Unmarshal([]byte(metadata), &meta); err != nil {
+		return nil
+	}
+	if meta.ExitCode == nil && toolName == tools.BashToolName {
+		// The bash tool leaves out the exit code of the commands that
+		// succeeded.
+		var zero int64
+		return &zero
+	}
+	return meta.ExitCode
+}
@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/azure"
+	"charm.land/fantasy/providers/openai"
+	"charm.land/fantasy/providers/openaicompat"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+)
+
+// supportsStrictTools reports whether providers of the type accept strict
+// function schemas. The SDKs always send them as non-strict, so they're
+// rewritten by a toolCallsTransport.
+func supportsStrictTools(providerType catwalk.Type) bool {
+	switch providerType {
+	case openai.Name, azure.Name, openaicompat.Name:
+		return true
+	}
+	return false
+}
+
+type parallelToolCallsContextKey struct{}
+
+// withParallelToolCalls returns a context the OpenAI style requests of which
+// allow or forbid parallel tool calls, nil leaving it to the provider.
+func withParallelToolCalls(ctx context.Context, parallel *bool) context.Context {
+	return context.WithValue(ctx, parallelToolCallsContextKey{}, parallel)
+}
+
+// withParallelToolUse returns the provider options with the parallel tool
+// calls of Anthropic allowed or forbidden, unless the provider options
+// already set it.
+func withParallelToolUse(opts fantasy.ProviderOptions, parallel *bool) fantasy.ProviderOptions {
+	o, ok := opts[anthropic.Name].(*anthropic.ProviderOptions)
+	if parallel == nil || !ok || o.DisableParallelToolUse != nil {
+		return opts
+	}
+	copied := *o
+	copied.DisableParallelToolUse = fantasy.Opt(!*parallel)
+	result := make(fantasy.ProviderOptions, len(opts))
+	for name, data := range opts {
+		result[name] = data
+	}
+	result[anthropic.Name] = &copied
+	return result
+}
+
+// toolCallsTransport sets how the model calls tools in the JSON body of
+// OpenAI requests, which the SDK doesn't expose: the tools listed in strict
+// get strict schemas, and parallel tool calls are allowed or forbidden as
+// the context of the request says. Both the chat completions and the
+// responses formats are handled.
+type toolCallsTransport struct {
+	transport http.RoundTripper
+	strict    []string
+
+	// warned holds the strict tools whose schema can't be made strict, so
+	// the downgrade is only logged once.
+	warned sync.Map
+}
+
+func (t *toolCallsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	parallel, _ := req.Context().Value(parallelToolCallsContextKey{}).(*bool)
+	if len(t.strict) == 0 && parallel == nil ||
+		req.Body == nil || req.Body == http.NoBody || !strings.Contains(req.Header.Get("Content-Type"), "json") {
+		return t.transport.RoundTrip(req)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		slog.Debug("Request body is not a JSON object, not changing tool calls", "error", err)
+	} else if tools, ok := fields["tools"].([]any); ok && len(tools) > 0 {
+		for _, tool := range tools {
+			t.makeStrict(tool)
+		}
+		if parallel != nil {
+			fields["parallel_tool_calls"] = *parallel
+		}
+		if rewritten, err := json.Marshal(fields); err == nil {
+			data = rewritten
+		}
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	return t.transport.RoundTrip(req)
+}
+
+// makeStrict makes the function tool strict if it's listed.
+func (t *toolCallsTransport) makeStrict(tool any) {
+	function, ok := tool.(map[string]any)
+	if !ok {
+		return
+	}
+	// Chat completions nest the function, responses don't.
+	if nested, ok := function["function"].(map[string]any); ok {
+		function = nested
+	}
+	name, _ := function["name"].(string)
+	if !slices.Contains(t.strict, name) {
+		return
+	}
+	// The schema is changed on a copy, so it's sent untouched if it can't be
+	// made strict.
+	var schema map[string]any
+	data, _ := json.Marshal(function["parameters"])
+	if err := json.Unmarshal(data, &schema); err != nil || schema == nil || !strictSchema(schema) {
+		if _, warned := t.warned.LoadOrStore(name, true); !warned {
+			slog.Warn("Tool schema can't be made strict, sending it as non-strict", "tool", name)
+		}
+		return
+	}
+	function["parameters"] = schema
+	function["strict"] = true
+}
+
+// strictSchema turns the JSON schema into one valid for strict mode, where
+// every object lists all its properties as required and forbids others.
+// Optional properties become nullable instead. It reports false if the
+// schema can't be made strict.
+func strictSchema(schema map[string]any) bool {
+	if !hasType(schema, "object") {
+		return false
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]any); ok {
+		for _, name := range list {
+			if name, ok := name.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(properties))
+	for name, property := range properties {
+		property, ok := property.(map[string]any)
+		if !ok || !strictProperty(property) {
+			return false
+		}
+		if !required[name] {
+			makeNullable(property)
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	schema["required"] = names
+	schema["additionalProperties"] = false
+	return true
+}
+
+func strictProperty(property map[string]any) bool {
+	if hasType(property, "object") {
+		return strictSchema(property)
+	}
+	if items, ok := property["items"].(map[string]any); ok && hasType(items, "object") {
+		return strictSchema(items)
+	}
+	return true
+}
+
+func hasType(schema map[string]any, want string) bool {
+	switch typ := schema["type"].(type) {
+	case string:
+		return typ == want
+	case []any:
+		return slices.Contains(typ, any(want))
+	}
+	return false
+}
+
+func makeNullable(property map[string]any) {
+	switch typ := property["type"].(type) {
+	case string:
+		property["type"] = []any{typ, "null"}
+	case []any:
+		if !slices.Contains(typ, any("null")) {
+			property["type"] = append(typ, "null")
+		}
+	}
+	if enum, ok := property["enum"].([]any); ok && !slices.Contains(enum, nil) {
+		property["enum"] = append(enum, nil)
+	}
+}
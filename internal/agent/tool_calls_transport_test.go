@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolCallsTransport(t *testing.T) {
+	t.Parallel()
+
+	var got []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		got, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(got)), r.ContentLength)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &http.Client{Transport: &toolCallsTransport{
+		transport: http.DefaultTransport,
+		strict:    []string{"view", "broken"},
+	}}
+	ctx := withParallelToolCalls(t.Context(), fantasy.Opt(false))
+	post := func(body string) map[string]any {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		var fields map[string]any
+		require.NoError(t, json.Unmarshal(got, &fields))
+		return fields
+	}
+	viewSchema := `{"type":"object","properties":{"file_path":{"type":"string"},"limit":{"type":"integer"}},"required":["file_path"]}`
+
+	t.Run("chat completions", func(t *testing.T) {
+		fields := post(`{"model":"gpt-4o","tools":[
+			{"type":"function","function":{"name":"view","parameters":` + viewSchema + `,"strict":false}},
+			{"type":"function","function":{"name":"bash","parameters":{"type":"object","properties":{"command":{"type":"string"}}},"strict":false}}
+		]}`)
+		require.Equal(t, false, fields["parallel_tool_calls"])
+
+		tools := fields["tools"].([]any)
+		view := tools[0].(map[string]any)["function"].(map[string]any)
+		require.Equal(t, true, view["strict"])
+		require.Equal(t, map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"file_path": map[string]any{"type": "string"},
+				"limit":     map[string]any{"type": []any{"integer", "null"}},
+			},
+			"required":             []any{"file_path", "limit"},
+			"additionalProperties": false,
+		}, view["parameters"])
+
+		bash := tools[1].(map[string]any)["function"].(map[string]any)
+		require.Equal(t, false, bash["strict"])
+		require.NotContains(t, bash["parameters"], "additionalProperties")
+	})
+
+	t.Run("responses", func(t *testing.T) {
+		fields := post(`{"model":"gpt-5","tools":[{"type":"function","name":"view","parameters":` + viewSchema + `,"strict":false}]}`)
+		view := fields["tools"].([]any)[0].(map[string]any)
+		require.Equal(t, true, view["strict"])
+		require.Equal(t, false, view["parameters"].(map[string]any)["additionalProperties"])
+	})
+
+	t.Run("schema that can't be strict", func(t *testing.T) {
+		fields := post(`{"tools":[{"type":"function","name":"broken","parameters":{"type":"object","properties":{"options":{"type":"object","properties":{"x":{}},"additionalProperties":true},"bad":"nope"}},"strict":false}]}`)
+		broken := fields["tools"].([]any)[0].(map[string]any)
+		require.Equal(t, false, broken["strict"])
+		require.Equal(t, map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"options": map[string]any{"type": "object", "properties": map[string]any{"x": map[string]any{}}, "additionalProperties": true},
+				"bad":     "nope",
+			},
+		}, broken["parameters"])
+	})
+
+	t.Run("without tools", func(t *testing.T) {
+		fields := post(`{"model":"gpt-4o"}`)
+		require.NotContains(t, fields, "parallel_tool_calls")
+	})
+
+	t.Run("parallel tool calls left to the provider", func(t *testing.T) {
+		ctx = withParallelToolCalls(t.Context(), nil)
+		fields := post(`{"tools":[{"type":"function","name":"bash","parameters":{"type":"object"},"strict":false}]}`)
+		require.NotContains(t, fields, "parallel_tool_calls")
+	})
+}
+
+func TestWithParallelToolUse(t *testing.T) {
+	t.Parallel()
+
+	opts := fantasy.ProviderOptions{anthropic.Name: &anthropic.ProviderOptions{}}
+	require.Equal(t, opts, withParallelToolUse(opts, nil))
+
+	got := withParallelToolUse(opts, fantasy.Opt(false))
+	require.Equal(t, fantasy.Opt(true), got[anthropic.Name].(*anthropic.ProviderOptions).DisableParallelToolUse)
+	require.Nil(t, opts[anthropic.Name].(*anthropic.ProviderOptions).DisableParallelToolUse, "the options of the call are copied")
+
+	set := fantasy.ProviderOptions{anthropic.Name: &anthropic.ProviderOptions{DisableParallelToolUse: fantasy.Opt(false)}}
+	require.Equal(t, set, withParallelToolUse(set, fantasy.Opt(false)), "the provider options win")
+}
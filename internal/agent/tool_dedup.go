@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+)
+
+// dedupToolNames are the tools that only read the project, so calling them
+// again with the same input gives the same result until something changes.
+var dedupToolNames = map[string]bool{
+	tools.ViewToolName:        true,
+	tools.GlobToolName:        true,
+	tools.GrepToolName:        true,
+	tools.LSToolName:          true,
+	tools.SourcegraphToolName: true,
+	tools.DiagnosticsToolName: true,
+	tools.ReferencesToolName:  true,
+}
+
+const duplicateToolCallNotice = "Note: this call is identical to a previous one, it was not run again and the previous result is returned. Change the input if you need different results.\n\n"
+
+// toolResultCache keeps the results of the read-only tool calls of a run.
+// Any other tool call may change the project and clears it.
+type toolResultCache struct {
+	mu      sync.Mutex
+	results map[string]fantasy.ToolResponse
+}
+
+func newToolResultCache() *toolResultCache {
+	return &toolResultCache{results: map[string]fantasy.ToolResponse{}}
+}
+
+// dedupTools wraps the tools so identical read-only calls made in the same
+// run return the cached result instead of running again.
+func dedupTools(agentTools []fantasy.AgentTool) []fantasy.AgentTool {
+	cache := newToolResultCache()
+	wrapped := make([]fantasy.AgentTool, len(agentTools))
+	for i, tool := range agentTools {
+		wrapped[i] = dedupTool{AgentTool: tool, cache: cache}
+	}
+	return wrapped
+}
+
+type dedupTool struct {
+	fantasy.AgentTool
+	cache *toolResultCache
+}
+
+func (t dedupTool) Run(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	name := t.Info().Name
+	if !dedupToolNames[name] {
+		t.cache.clear()
+		return t.AgentTool.Run(ctx, call)
+	}
+
+	key := name + "\x00" + normalizeToolInput(call.Input)
+	if resp, ok := t.cache.get(key); ok {
+		slog.Debug("Skipping duplicate tool call", "tool", name, "input", call.Input)
+		resp.Content = duplicateToolCallNotice + resp.Content
+		return resp, nil
+	}
+	resp, err := t.AgentTool.Run(ctx, call)
+	if err == nil && !resp.IsError {
+		t.cache.set(key, resp)
+	}
+	return resp, err
+}
+
+func (c *toolResultCache) get(key string) (fantasy.ToolResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp, ok := c.results[key]
+	return resp, ok
+}
+
+func (c *toolResultCache) set(key string, resp fantasy.ToolResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results[key] = resp
+}
+
+func (c *toolResultCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.results)
+}
+
+// normalizeToolInput re-encodes the input so the formatting and the order of
+// the fields don't matter.
+func normalizeToolInput(input string) string {
+	var v any
+	if err := json.Unmarshal([]byte(input), &v); err != nil {
+		return input
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return input
+	}
+	return string(data)
+}
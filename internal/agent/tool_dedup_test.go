@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/stretchr/testify/require"
+)
+
+type countingParams struct {
+	Pattern string `json:"pattern"`
+	Path    string `json:"path"`
+}
+
+func newCountingTool(name string, runs *int) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		name,
+		"Counts its runs.",
+		func(context.Context, countingParams, fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			*runs++
+			return fantasy.NewTextResponse("result"), nil
+		},
+	)
+}
+
+func TestDedupTools(t *testing.T) {
+	t.Parallel()
+
+	var greps, writes int
+	wrapped := dedupTools([]fantasy.AgentTool{
+		newCountingTool(tools.GrepToolName, &greps),
+		newCountingTool(tools.WriteToolName, &writes),
+	})
+	grep, write := wrapped[0], wrapped[1]
+	run := func(tool fantasy.AgentTool, input string) fantasy.ToolResponse {
+		resp, err := tool.Run(t.Context(), fantasy.ToolCall{ID: "call", Name: tool.Info().Name, Input: input})
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := run(grep, `{"pattern": "foo", "path": "."}`)
+	require.Equal(t, "result", resp.Content)
+
+	resp = run(grep, `{"path":".","pattern":"foo"}`)
+	require.Equal(t, 1, greps, "identical calls are not run again")
+	require.Equal(t, duplicateToolCallNotice+"result", resp.Content)
+
+	run(grep, `{"pattern": "bar", "path": "."}`)
+	require.Equal(t, 2, greps)
+
+	run(write, `{"pattern": "foo", "path": "."}`)
+	run(write, `{"pattern": "foo", "path": "."}`)
+	require.Equal(t, 2, writes, "tools that change the project always run")
+
+	resp = run(grep, `{"pattern": "foo", "path": "."}`)
+	require.Equal(t, 3, greps, "changes clear the cache")
+	require.Equal(t, "result", resp.Content)
+}
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"context"
+	"slices"
+
+	"charm.land/fantasy"
+)
+
+// ToolHandler runs a tool call.
+type ToolHandler func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error)
+
+// ToolMiddleware wraps the execution of the tools, to log, rate limit, cache
+// or ask for permissions without changing each tool. It may change the call
+// before passing it to next, or return without calling next to answer in
+// place of the tool. Returning permission.ErrorPermissionDenied denies the
+// call the way the tools do.
+type ToolMiddleware func(next ToolHandler) ToolHandler
+
+// chainTools wraps the tools with the middleware, the first one being the
+// outermost.
+func chainTools(agentTools []fantasy.AgentTool, middleware []ToolMiddleware) []fantasy.AgentTool {
+	if len(middleware) == 0 {
+		return agentTools
+	}
+	wrapped := make([]fantasy.AgentTool, len(agentTools))
+	for i, tool := range agentTools {
+		handler := ToolHandler(tool.Run)
+		for _, m := range slices.Backward(middleware) {
+			handler = m(handler)
+		}
+		wrapped[i] = chainedTool{AgentTool: tool, handler: handler}
+	}
+	return wrapped
+}
+
+type chainedTool struct {
+	fantasy.AgentTool
+	handler ToolHandler
+}
+
+func (t chainedTool) Run(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	return t.handler(ctx, call)
+}
@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+type echoParams struct {
+	Text string `json:"text"`
+}
+
+func TestChainTools(t *testing.T) {
+	t.Parallel()
+
+	echo := fantasy.NewAgentTool("echo", "Echoes its text.", func(_ context.Context, params echoParams, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+		return fantasy.NewTextResponse(params.Text), nil
+	})
+
+	var order []string
+	trace := func(name string) ToolMiddleware {
+		return func(next ToolHandler) ToolHandler {
+			return func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+				order = append(order, name)
+				return next(ctx, call)
+			}
+		}
+	}
+	rewrite := func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			switch call.Input {
+			case `{"text": "secret"}`:
+				return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
+			case `{"text": "cached"}`:
+				return fantasy.NewTextResponse("from cache"), nil
+			}
+			call.Input = `{"text": "rewritten"}`
+			return next(ctx, call)
+		}
+	}
+	tool := chainTools([]fantasy.AgentTool{echo}, []ToolMiddleware{trace("outer"), trace("inner"), rewrite})[0]
+	require.Equal(t, "echo", tool.Info().Name)
+	run := func(input string) (fantasy.ToolResponse, error) {
+		return tool.Run(t.Context(), fantasy.ToolCall{ID: "call", Name: "echo", Input: input})
+	}
+
+	resp, err := run(`{"text": "hello"}`)
+	require.NoError(t, err)
+	require.Equal(t, "rewritten", resp.Content)
+	require.Equal(t, []string{"outer", "inner"}, order)
+
+	resp, err = run(`{"text": "cached"}`)
+	require.NoError(t, err)
+	require.Equal(t, "from cache", resp.Content)
+
+	_, err = run(`{"text": "secret"}`)
+	require.ErrorIs(t, err, permission.ErrorPermissionDenied)
+
+	require.Equal(t, echo, chainTools([]fantasy.AgentTool{echo}, nil)[0], "tools are left alone without middleware")
+}
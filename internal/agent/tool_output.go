@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+//go:embed templates/tool_output.md
+var toolOutputPrompt []byte
+
+// budgetTools wraps the tools so their results longer than the configured
+// budget are shortened before they go in the context.
+func (a *sessionAgent) budgetTools(agentTools []fantasy.AgentTool) []fantasy.AgentTool {
+	if a.toolOutput.MaxTokens <= 0 {
+		return agentTools
+	}
+	wrapped := make([]fantasy.AgentTool, len(agentTools))
+	for i, tool := range agentTools {
+		wrapped[i] = budgetedTool{AgentTool: tool, agent: a}
+	}
+	return wrapped
+}
+
+type budgetedTool struct {
+	fantasy.AgentTool
+	agent *sessionAgent
+}
+
+func (t budgetedTool) Run(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	resp, err := t.AgentTool.Run(ctx, call)
+	if err != nil || resp.Type != "text" || estimateTextTokens(resp.Content) <= int64(t.agent.toolOutput.MaxTokens) {
+		return resp, err
+	}
+	resp.Content = t.agent.shortenToolOutput(ctx, t.Info().Name, resp.Content)
+	return resp, nil
+}
+
+// shortenToolOutput brings the output of the tool within the budget, noting
+// that it was shortened so the model knows it's missing parts. It's
+// summarized by the small model if configured to, otherwise its structure is
+// kept and the middle dropped.
+func (a *sessionAgent) shortenToolOutput(ctx context.Context, toolName, content string) string {
+	maxTokens := a.toolOutput.MaxTokens
+	if a.toolOutput.Summarize {
+		summary, err := a.summarizeToolOutput(ctx, toolName, content)
+		if err == nil && estimateTextTokens(summary) <= int64(maxTokens) {
+			return fmt.Sprintf("[Output of about %d tokens summarized to fit the budget of %d tokens]\n\n%s",
+				estimateTextTokens(content), maxTokens, summary)
+		}
+		slog.Warn("Failed to summarize tool output, keeping its start and end instead", "tool", toolName, "error", err)
+	}
+	shortened, how := extractOutput(content, maxTokens*charsPerToken)
+	return fmt.Sprintf("[Output of about %d tokens shortened to fit the budget of %d tokens by %s]\n\n%s",
+		estimateTextTokens(content), maxTokens, how, shortened)
+}
+
+func (a *sessionAgent) summarizeToolOutput(ctx context.Context, toolName, content string) (string, error) {
+	// The output may not even fit the context of the small model.
+	if limit := a.smallModel.CatwalkCfg.ContextWindow / 2; limit > 0 && estimateTextTokens(content) > limit {
+		content, _ = extractOutput(content, int(limit)*charsPerToken)
+	}
+
+	maxOutput := int64(a.toolOutput.MaxTokens)
+	if a.smallModel.CatwalkCfg.CanReason {
+		maxOutput = max(maxOutput, a.smallModel.CatwalkCfg.DefaultMaxTokens)
+	}
+	summary, err := a.summarizer(SummaryToolOutput).Summarize(ctx, SummaryRequest{
+		Kind:      SummaryToolOutput,
+		ToolName:  toolName,
+		Text:      content,
+		MaxTokens: maxOutput,
+	})
+	if err != nil {
+		return "", err
+	}
+	if summary.Extractive {
+		// The output is shortened the same way without a summary.
+		return "", errors.New("no model summary")
+	}
+	return summary.Text, nil
+}
+
+// extractOutput shortens the content to at most about maxChars, returning
+// how it did. JSON keeps its structure with long arrays and strings cut,
+// anything else keeps its first and last lines, the end of logs being
+// where errors usually are.
+func extractOutput(content string, maxChars int) (string, string) {
+	if shortened, ok := extractJSON(content, maxChars); ok {
+		return shortened, "cutting long arrays and strings"
+	}
+	return extractLines(content, maxChars), "dropping its middle"
+}
+
+// jsonLimits are the ever smaller limits tried on JSON, as the number of
+// items kept of arrays and characters of strings.
+var jsonLimits = []struct{ items, chars int }{
+	{100, 1000},
+	{20, 200},
+	{5, 80},
+	{1, 40},
+}
+
+func extractJSON(content string, maxChars int) (string, bool) {
+	trimmed := strings.TrimSpace(content)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return "", false
+	}
+	dec := json.NewDecoder(strings.NewReader(trimmed))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return "", false
+	}
+	for _, limits := range jsonLimits {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(pruneJSON(v, limits.items, limits.chars)); err != nil {
+			return "", false
+		}
+		if buf.Len() <= maxChars {
+			return strings.TrimSpace(buf.String()), true
+		}
+	}
+	return "", false
+}
+
+func pruneJSON(v any, items, chars int) any {
+	switch v := v.(type) {
+	case []any:
+		pruned := make([]any, 0, min(len(v), items)+1)
+		for _, item := range v[:min(len(v), items)] {
+			pruned = append(pruned, pruneJSON(item, items, chars))
+		}
+		if len(v) > items {
+			pruned = append(pruned, fmt.Sprintf("... %d more items", len(v)-items))
+		}
+		return pruned
+	case map[string]any:
+		pruned := make(map[string]any, len(v))
+		for key, value := range v {
+			pruned[key] = pruneJSON(value, items, chars)
+		}
+		return pruned
+	case string:
+		if len(v) <= chars {
+			return v
+		}
+		return fmt.Sprintf("%s... %d more characters", strings.ToValidUTF8(v[:chars], ""), len(v)-chars)
+	}
+	return v
+}
+
+// extractLines keeps the first and last whole lines of the content that
+// fit, two fifths of the budget going to the start.
+func extractLines(content string, maxChars int) string {
+	if len(content) <= maxChars {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	headBudget := maxChars * 2 / 5
+	tailBudget := maxChars - headBudget
+
+	head, size := 0, 0
+	for head < len(lines) && size+len(lines[head])+1 <= headBudget {
+		size += len(lines[head]) + 1
+		head++
+	}
+	tail, size := len(lines), 0
+	for tail > head && size+len(lines[tail-1])+1 <= tailBudget {
+		size += len(lines[tail-1]) + 1
+		tail--
+	}
+	if head == 0 && tail == len(lines) {
+		// Lines too long to keep whole, cut them.
+		return fmt.Sprintf("%s\n\n... [%d characters omitted] ...\n\n%s",
+			strings.ToValidUTF8(content[:headBudget], ""),
+			len(content)-maxChars,
+			strings.ToValidUTF8(content[len(content)-tailBudget:], ""))
+	}
+	return fmt.Sprintf("%s\n\n... [%d lines omitted] ...\n\n%s",
+		strings.Join(lines[:head], "\n"),
+		tail-head,
+		strings.Join(lines[tail:], "\n"))
+}
@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+type summaryModel struct {
+	fantasy.LanguageModel
+	summary string
+	err     error
+	prompts []string
+}
+
+func (m *summaryModel) Provider() string { return "test" }
+func (m *summaryModel) Model() string    { return "test" }
+
+func (m *summaryModel) Generate(_ context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	for _, msg := range call.Prompt {
+		for _, part := range msg.Content {
+			if text, ok := part.(fantasy.TextPart); ok {
+				m.prompts = append(m.prompts, text.Text)
+			}
+		}
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &fantasy.Response{
+		Content:      fantasy.ResponseContent{fantasy.TextContent{Text: m.summary}},
+		FinishReason: fantasy.FinishReasonStop,
+	}, nil
+}
+
+func TestBudgetedTool(t *testing.T) {
+	t.Parallel()
+
+	var lines []string
+	for i := range 1000 {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	output := strings.Join(lines, "\n")
+	tool := fantasy.NewAgentTool("logs", "Print logs", func(context.Context, struct{}, fantasy.ToolCall) (fantasy.ToolResponse, error) {
+		return fantasy.NewTextResponse(output), nil
+	})
+	run := func(t *testing.T, a *sessionAgent) string {
+		tools := a.budgetTools([]fantasy.AgentTool{tool})
+		resp, err := tools[0].Run(t.Context(), fantasy.ToolCall{Input: "{}"})
+		require.NoError(t, err)
+		return resp.Content
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, output, run(t, &sessionAgent{}))
+	})
+
+	t.Run("extract", func(t *testing.T) {
+		t.Parallel()
+		content := run(t, &sessionAgent{toolOutput: config.ToolOutput{MaxTokens: 100}})
+		require.True(t, strings.HasPrefix(content, "[Output of about 2223 tokens shortened to fit the budget of 100 tokens by dropping its middle]\n\nline 0\n"))
+		require.True(t, strings.HasSuffix(content, "\nline 999"))
+		require.Contains(t, content, "lines omitted")
+		require.Less(t, estimateTextTokens(content), int64(150))
+	})
+
+	t.Run("summarize", func(t *testing.T) {
+		t.Parallel()
+		model := &summaryModel{summary: "1000 lines counting up"}
+		content := run(t, &sessionAgent{
+			smallModel: Model{Model: model},
+			toolOutput: config.ToolOutput{MaxTokens: 100, Summarize: true},
+		})
+		require.Equal(t, "[Output of about 2223 tokens summarized to fit the budget of 100 tokens]\n\n1000 lines counting up", content)
+		require.Contains(t, model.prompts[len(model.prompts)-1], "Output of the logs tool:\n\nline 0\n")
+	})
+
+	t.Run("summarize failed", func(t *testing.T) {
+		t.Parallel()
+		content := run(t, &sessionAgent{
+			smallModel: Model{Model: &summaryModel{err: errors.New("overloaded")}},
+			toolOutput: config.ToolOutput{MaxTokens: 100, Summarize: true},
+		})
+		require.Contains(t, content, "by dropping its middle")
+	})
+}
+
+func TestExtractOutput(t *testing.T) {
+	t.Parallel()
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		var items []map[string]any
+		for i := range 200 {
+			items = append(items, map[string]any{"id": i, "name": strings.Repeat("x", 300)})
+		}
+		data, err := json.Marshal(map[string]any{"total": 200, "items": items})
+		require.NoError(t, err)
+
+		shortened, how := extractOutput(string(data), 2000)
+		require.Equal(t, "cutting long arrays and strings", how)
+		require.LessOrEqual(t, len(shortened), 2000)
+		var v struct {
+			Total int   `json:"total"`
+			Items []any `json:"items"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(shortened), &v))
+		require.Equal(t, 200, v.Total)
+		require.Equal(t, "... 195 more items", v.Items[len(v.Items)-1])
+	})
+
+	t.Run("long lines", func(t *testing.T) {
+		t.Parallel()
+		shortened, how := extractOutput(strings.Repeat("é", 1000), 100)
+		require.Equal(t, "dropping its middle", how)
+		require.Contains(t, shortened, "[1900 characters omitted]")
+		require.True(t, strings.HasPrefix(shortened, strings.Repeat("é", 20)+"\n"))
+		require.True(t, strings.HasSuffix(shortened, "\n"+strings.Repeat("é", 30)))
+	})
+
+	t.Run("short", func(t *testing.T) {
+		t.Parallel()
+		shortened, _ := extractOutput("a\nb", 100)
+		require.Equal(t, "a\nb", shortened)
+	})
+}
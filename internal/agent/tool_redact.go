@@ -0,0 +1,22 @@
+package agent
+
+import (
+	"context"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/secrets"
+)
+
+// redactTools replaces the values of the secrets of the project in the
+// output of the tools, so they never reach the model, the stored messages or
+// the exports made from them.
+func redactTools(secrets *secrets.Vault) ToolMiddleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			resp, err := next(ctx, call)
+			resp.Content = secrets.Redact(resp.Content)
+			resp.Metadata = secrets.Redact(resp.Metadata)
+			return resp, err
+		}
+	}
+}
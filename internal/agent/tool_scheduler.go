@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+// toolStopGrace is how long a tool call is given to return once its context
+// is cancelled.
+const toolStopGrace = 5 * time.Second
+
+// toolScheduler runs the tool calls of the steps of a run. fantasy starts
+// all the calls of a step at once, the scheduler bounds how many run at the
+// same time, stops the calls running for too long and has their results
+// delivered in the order the model made the calls.
+type toolScheduler struct {
+	// slots holds a value for each call running, nil when unbounded.
+	slots   chan struct{}
+	timeout time.Duration
+	// grace is how long a call is waited for once cancelled.
+	grace time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*scheduledCall
+	// last is the latest call of the step.
+	last *scheduledCall
+}
+
+type scheduledCall struct {
+	prev *scheduledCall
+	// delivered is closed once the result of the call was delivered.
+	delivered chan struct{}
+}
+
+func newToolScheduler(concurrency int, timeout time.Duration) *toolScheduler {
+	s := &toolScheduler{
+		timeout: timeout,
+		grace:   toolStopGrace,
+		calls:   make(map[string]*scheduledCall),
+	}
+	if concurrency > 0 {
+		s.slots = make(chan struct{}, concurrency)
+	}
+	return s
+}
+
+// scheduleTools wraps the tools to be run by the scheduler.
+func scheduleTools(agentTools []fantasy.AgentTool, s *toolScheduler) []fantasy.AgentTool {
+	wrapped := make([]fantasy.AgentTool, len(agentTools))
+	for i, tool := range agentTools {
+		wrapped[i] = scheduledTool{AgentTool: tool, scheduler: s}
+	}
+	return wrapped
+}
+
+type scheduledTool struct {
+	fantasy.AgentTool
+	scheduler *toolScheduler
+}
+
+func (t scheduledTool) Run(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	return t.scheduler.run(ctx, t.AgentTool, call)
+}
+
+// startStep forgets the calls of the previous step.
+func (s *toolScheduler) startStep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.calls)
+	s.last = nil
+}
+
+// add adds a call to the step, its result is delivered after the ones of
+// the calls added before.
+func (s *toolScheduler) add(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	call := &scheduledCall{prev: s.last, delivered: make(chan struct{})}
+	s.calls[id] = call
+	s.last = call
+}
+
+// deliver delivers the result of the call once the results of the calls
+// made before it were delivered.
+func (s *toolScheduler) deliver(ctx context.Context, id string, deliver func() error) error {
+	s.mu.Lock()
+	call, ok := s.calls[id]
+	s.mu.Unlock()
+	if !ok {
+		return deliver()
+	}
+	defer close(call.delivered)
+	if call.prev != nil {
+		select {
+		case <-call.prev.delivered:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+	return deliver()
+}
+
+type toolRun struct {
+	resp fantasy.ToolResponse
+	err  error
+}
+
+// run runs the call once a slot is free. When the call times out or ctx is
+// done its context is cancelled and it's given the grace period to return.
+// Calls that don't stop by then are left running, so the step doesn't wait
+// for them, and keep their slot until they return.
+func (s *toolScheduler) run(ctx context.Context, tool fantasy.AgentTool, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	release := func() {}
+	if s.slots != nil {
+		select {
+		case s.slots <- struct{}{}:
+			release = func() { <-s.slots }
+		case <-ctx.Done():
+			return fantasy.ToolResponse{}, context.Cause(ctx)
+		}
+	}
+
+	runCtx := ctx
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	done := make(chan toolRun, 1)
+	go func() {
+		defer release()
+		resp, err := tool.Run(runCtx, call)
+		done <- toolRun{resp: resp, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		if ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+			return timedOut(s.timeout), nil
+		}
+		return r.resp, r.err
+	case <-runCtx.Done():
+	}
+
+	stopped := true
+	select {
+	case <-done:
+	case <-time.After(s.grace):
+		stopped = false
+	}
+	if ctx.Err() != nil {
+		if !stopped {
+			slog.Warn("Cancelled tool call didn't stop, leaving it running", "tool", call.Name, "id", call.ID)
+		}
+		return fantasy.ToolResponse{}, context.Cause(ctx)
+	}
+	if !stopped {
+		slog.Warn("Tool call timed out and didn't stop, leaving it running", "tool", call.Name, "id", call.ID, "timeout", s.timeout)
+		return stillRunning(s.timeout), nil
+	}
+	slog.Warn("Tool call timed out", "tool", call.Name, "id", call.ID, "timeout", s.timeout)
+	return timedOut(s.timeout), nil
+}
+
+func timedOut(timeout time.Duration) fantasy.ToolResponse {
+	return fantasy.NewTextErrorResponse(fmt.Sprintf("The tool call was stopped after running for %s.", timeout))
+}
+
+func stillRunning(timeout time.Duration) fantasy.ToolResponse {
+	return fantasy.NewTextErrorResponse(fmt.Sprintf("The tool call timed out after %s and didn't stop when asked, it may still be running and changing things.", timeout))
+}
@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func newBlockingTool(run func(ctx context.Context) (fantasy.ToolResponse, error)) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		"block",
+		"Blocks until told to return.",
+		func(ctx context.Context, _ struct{}, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			return run(ctx)
+		},
+	)
+}
+
+func TestToolSchedulerConcurrency(t *testing.T) {
+	t.Parallel()
+
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	tool := scheduleTools([]fantasy.AgentTool{newBlockingTool(func(context.Context) (fantasy.ToolResponse, error) {
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		<-release
+		running.Add(-1)
+		return fantasy.NewTextResponse("done"), nil
+	})}, newToolScheduler(2, 0))[0]
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			resp, err := tool.Run(t.Context(), fantasy.ToolCall{Name: "block", Input: "{}"})
+			require.NoError(t, err)
+			require.Equal(t, "done", resp.Content)
+		})
+	}
+	require.Eventually(t, func() bool { return running.Load() == 2 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	require.Equal(t, int32(2), peak.Load())
+}
+
+func TestToolSchedulerTimeout(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stopped", func(t *testing.T) {
+		t.Parallel()
+		tool := scheduleTools([]fantasy.AgentTool{newBlockingTool(func(ctx context.Context) (fantasy.ToolResponse, error) {
+			<-ctx.Done()
+			return fantasy.ToolResponse{}, ctx.Err()
+		})}, newToolScheduler(0, 10*time.Millisecond))[0]
+
+		resp, err := tool.Run(t.Context(), fantasy.ToolCall{Name: "block", Input: "{}"})
+		require.NoError(t, err)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "stopped after running for 10ms")
+	})
+
+	t.Run("still running", func(t *testing.T) {
+		t.Parallel()
+		// The tool ignores its context, it's left running after the grace
+		// period.
+		stuck := make(chan struct{})
+		t.Cleanup(func() { close(stuck) })
+		s := newToolScheduler(0, 10*time.Millisecond)
+		s.grace = 10 * time.Millisecond
+		tool := scheduleTools([]fantasy.AgentTool{newBlockingTool(func(context.Context) (fantasy.ToolResponse, error) {
+			<-stuck
+			return fantasy.NewTextResponse("done"), nil
+		})}, s)[0]
+
+		resp, err := tool.Run(t.Context(), fantasy.ToolCall{Name: "block", Input: "{}"})
+		require.NoError(t, err)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "may still be running")
+	})
+}
+
+func TestToolSchedulerCancel(t *testing.T) {
+	t.Parallel()
+
+	stuck := make(chan struct{})
+	started := make(chan struct{})
+	var once sync.Once
+	s := newToolScheduler(1, 0)
+	s.grace = 10 * time.Millisecond
+	tool := scheduleTools([]fantasy.AgentTool{newBlockingTool(func(context.Context) (fantasy.ToolResponse, error) {
+		once.Do(func() { close(started) })
+		<-stuck
+		return fantasy.NewTextResponse("done"), nil
+	})}, s)[0]
+
+	ctx, cancel := context.WithCancel(t.Context())
+	go func() {
+		<-started
+		cancel()
+	}()
+	_, err := tool.Run(ctx, fantasy.ToolCall{Name: "block", Input: "{}"})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The call left running keeps its slot until it returns.
+	ctx, cancel = context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err = tool.Run(ctx, fantasy.ToolCall{Name: "block", Input: "{}"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(stuck)
+	resp, err := tool.Run(t.Context(), fantasy.ToolCall{Name: "block", Input: "{}"})
+	require.NoError(t, err)
+	require.Equal(t, "done", resp.Content)
+}
+
+func TestToolSchedulerDeliverOrder(t *testing.T) {
+	t.Parallel()
+
+	s := newToolScheduler(0, 0)
+	s.startStep()
+	ids := []string{"a", "b", "c", "d"}
+	for _, id := range ids {
+		s.add(id)
+	}
+
+	var mu sync.Mutex
+	var delivered []string
+	var wg sync.WaitGroup
+	// Delivered in the reverse order they end in.
+	for i := len(ids) - 1; i >= 0; i-- {
+		id := ids[i]
+		wg.Go(func() {
+			require.NoError(t, s.deliver(t.Context(), id, func() error {
+				mu.Lock()
+				defer mu.Unlock()
+				delivered = append(delivered, id)
+				return nil
+			}))
+		})
+		time.Sleep(time.Millisecond)
+	}
+	wg.Wait()
+	require.Equal(t, ids, delivered)
+
+	// Calls of the previous step don't hold back the next one.
+	s.add("stale")
+	s.startStep()
+	s.add("e")
+	require.NoError(t, s.deliver(t.Context(), "e", func() error { return nil }))
+}
@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// cachesToolSchemas reports whether the provider caches the tool schemas
+// itself, in which case abbreviating them only breaks the cache.
+func cachesToolSchemas(t catwalk.Type) bool {
+	return t == catwalk.TypeAnthropic || t == catwalk.TypeBedrock
+}
+
+// toolsForCall returns the tools to send for a call. With compact schemas the
+// full schemas are only sent on the first turn of the session, when the tools
+// change or when the previous turn had a failing tool call, and abbreviated
+// ones otherwise.
+func (a *sessionAgent) toolsForCall(sessionID string, msgs []message.Message, compact bool) []fantasy.AgentTool {
+	if !compact {
+		return a.tools
+	}
+	fingerprint := toolsFingerprint(a.tools)
+	sent, ok := a.fullToolSchemas.Get(sessionID)
+	if !ok || sent != fingerprint || len(msgs) == 0 || lastTurnFailed(msgs) {
+		a.fullToolSchemas.Set(sessionID, fingerprint)
+		return a.tools
+	}
+	compacted := make([]fantasy.AgentTool, len(a.tools))
+	for i, tool := range a.tools {
+		compacted[i] = compactTool{tool}
+	}
+	return compacted
+}
+
+// lastTurnFailed reports whether a tool call of the last turn failed, the
+// model may have misused the tool and needs its full description.
+func lastTurnFailed(msgs []message.Message) bool {
+	for _, msg := range slices.Backward(msgs) {
+		if msg.Role == message.User {
+			return false
+		}
+		for _, result := range msg.ToolResults() {
+			if result.IsError {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func toolsFingerprint(tools []fantasy.AgentTool) string {
+	infos := make([]fantasy.ToolInfo, len(tools))
+	for i, tool := range tools {
+		infos[i] = tool.Info()
+	}
+	slices.SortFunc(infos, func(a, b fantasy.ToolInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	data, _ := json.Marshal(infos)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// compactTool sends the first sentence of the tool description and the
+// parameters without their descriptions. The structure of the parameters is
+// kept so the inputs are still validated.
+type compactTool struct {
+	fantasy.AgentTool
+}
+
+func (t compactTool) Info() fantasy.ToolInfo {
+	info := t.AgentTool.Info()
+	info.Description = firstSentence(info.Description)
+	info.Parameters = stripDescriptions(info.Parameters)
+	return info
+}
+
+func firstSentence(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	if i := strings.Index(s, ". "); i >= 0 {
+		s = s[:i+1]
+	}
+	return s
+}
+
+// stripDescriptions copies the schema without the description fields.
+// Parameters named "description" have a schema as value and are kept.
+func stripDescriptions(schema map[string]any) map[string]any {
+	stripped := make(map[string]any, len(schema))
+	for k, v := range schema {
+		if _, ok := v.(string); ok && k == "description" {
+			continue
+		}
+		stripped[k] = stripValue(v)
+	}
+	return stripped
+}
+
+func stripValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return stripDescriptions(v)
+	case []any:
+		values := make([]any, len(v))
+		for i, item := range v {
+			values[i] = stripValue(item)
+		}
+		return values
+	default:
+		return v
+	}
+}
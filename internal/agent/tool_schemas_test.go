@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+type describeParams struct {
+	Command     string `json:"command" description:"The command to run."`
+	Description string `json:"description" description:"What the command does."`
+}
+
+func newDescribeTool() fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		"describe",
+		"Runs a command. It can take a while.\n\nMore details about the tool.",
+		func(context.Context, describeParams, fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			return fantasy.NewTextResponse(""), nil
+		},
+	)
+}
+
+func TestCompactTool(t *testing.T) {
+	t.Parallel()
+
+	info := compactTool{newDescribeTool()}.Info()
+	require.Equal(t, "Runs a command.", info.Description)
+	require.Equal(t, map[string]any{
+		"command":     map[string]any{"type": "string"},
+		"description": map[string]any{"type": "string"},
+	}, info.Parameters)
+}
+
+func TestToolsForCall(t *testing.T) {
+	t.Parallel()
+
+	a := NewSessionAgent(SessionAgentOptions{Tools: []fantasy.AgentTool{newDescribeTool()}}).(*sessionAgent)
+	isCompact := func(tools []fantasy.AgentTool) bool {
+		_, ok := tools[0].(compactTool)
+		return ok
+	}
+	history := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "hi"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "hello"}}},
+	}
+
+	require.False(t, isCompact(a.toolsForCall("s", history, false)))
+	require.False(t, isCompact(a.toolsForCall("s", nil, true)), "first turn sends full schemas")
+	require.True(t, isCompact(a.toolsForCall("s", history, true)))
+	require.False(t, isCompact(a.toolsForCall("other", history, true)), "other sessions didn't get them yet")
+
+	failed := append(history, message.Message{
+		Role:  message.Tool,
+		Parts: []message.ContentPart{message.ToolResult{ToolCallID: "1", IsError: true}},
+	})
+	require.False(t, isCompact(a.toolsForCall("s", failed, true)), "failed tool calls resend full schemas")
+
+	a.SetTools([]fantasy.AgentTool{newDescribeTool(), newDescribeTool()})
+	require.False(t, isCompact(a.toolsForCall("s", history, true)), "changed tools resend full schemas")
+	require.True(t, isCompact(a.toolsForCall("s", history, true)))
+}
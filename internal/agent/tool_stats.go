@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/toolstats"
+)
+
+// measureTools records the duration, output size and outcome of every
+// execution of the tools.
+func measureTools(stats toolstats.Service) ToolMiddleware {
+	return func(next ToolHandler) ToolHandler {
+		return func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			start := time.Now()
+			resp, err := next(ctx, call)
+			duration := time.Since(start)
+
+			sessionID := tools.GetSessionFromContext(ctx)
+			if sessionID == "" {
+				return resp, err
+			}
+			// Record even if the call was cancelled so aborted slow tools
+			// still show up in the stats.
+			_, recordErr := stats.Record(context.WithoutCancel(ctx), toolstats.Execution{
+				SessionID:  sessionID,
+				ToolName:   call.Name,
+				Duration:   duration,
+				OutputSize: int64(len(resp.Content)),
+				IsError:    err != nil || resp.IsError,
+			})
+			if recordErr != nil {
+				slog.Warn("Failed to record tool execution", "tool", call.Name, "error", recordErr)
+			}
+			return resp, err
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/permission"
+)
+
+type ArtifactParams struct {
+	FilePath    string `json:"file_path" description:"The path of the generated file to save"`
+	Name        string `json:"name,omitempty" description:"The display name of the artifact, defaults to the file name"`
+	Kind        string `json:"kind,omitempty" description:"The kind of artifact: image, report, binary or file. Guessed when empty"`
+	Description string `json:"description,omitempty" description:"A short description of the artifact"`
+}
+
+type ArtifactPermissionsParams struct {
+	FilePath    string `json:"file_path"`
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+}
+
+type ArtifactResponseMetadata struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+const ArtifactToolName = "artifact"
+
+//go:embed artifact.md
+var artifactDescription []byte
+
+func NewArtifactTool(permissions permission.Service, artifacts artifact.Service, workingDir string) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		ArtifactToolName,
+		string(artifactDescription),
+		func(ctx context.Context, params ArtifactParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if params.FilePath == "" {
+				return fantasy.NewTextErrorResponse("file_path is required"), nil
+			}
+			switch params.Kind {
+			case "", artifact.KindImage, artifact.KindReport, artifact.KindBinary, artifact.KindFile:
+			default:
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("unknown kind %q, use image, report, binary or file", params.Kind)), nil
+			}
+
+			filePath := params.FilePath
+			if !filepath.IsAbs(filePath) {
+				filePath = filepath.Join(workingDir, filePath)
+			}
+
+			sessionID := GetSessionFromContext(ctx)
+			if sessionID == "" {
+				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for saving artifacts")
+			}
+
+			if IsDryRunFromContext(ctx) {
+				return NewDryRunResponse(fmt.Sprintf("Save %s as an artifact", filePath)), nil
+			}
+
+			// Check if file is outside working directory and request permission if needed
+			absWorkingDir, err := filepath.Abs(workingDir)
+			if err != nil {
+				return fantasy.ToolResponse{}, fmt.Errorf("error resolving working directory: %w", err)
+			}
+
+			absFilePath, err := filepath.Abs(filePath)
+			if err != nil {
+				return fantasy.ToolResponse{}, fmt.Errorf("error resolving file path: %w", err)
+			}
+
+			relPath, err := filepath.Rel(absWorkingDir, absFilePath)
+			if err != nil || strings.HasPrefix(relPath, "..") {
+				granted := permissions.Request(
+					permission.CreatePermissionRequest{
+						SessionID:   sessionID,
+						Path:        absFilePath,
+						ToolCallID:  call.ID,
+						ToolName:    ArtifactToolName,
+						Action:      "read",
+						Description: fmt.Sprintf("Save file outside working directory as an artifact: %s", absFilePath),
+						Params:      ArtifactPermissionsParams(params),
+					},
+				)
+
+				if !granted {
+					return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
+				}
+			}
+
+			saved, err := artifacts.Register(ctx, artifact.RegisterParams{
+				SessionID:   sessionID,
+				SourcePath:  filePath,
+				Name:        params.Name,
+				Kind:        params.Kind,
+				Description: params.Description,
+			})
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("file not found: %s", filePath)), nil
+			case errors.Is(err, artifact.ErrTooLarge), errors.Is(err, artifact.ErrIsDirectory):
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			case err != nil:
+				return fantasy.ToolResponse{}, fmt.Errorf("error saving artifact: %w", err)
+			}
+
+			return fantasy.WithResponseMetadata(
+				fantasy.NewTextResponse(fmt.Sprintf("Saved %s as %s artifact %q (%d bytes)", filePath, saved.Kind, saved.Name, saved.Size)),
+				ArtifactResponseMetadata{
+					ID:   saved.ID,
+					Name: saved.Name,
+					Kind: saved.Kind,
+					Path: saved.Path,
+					Size: saved.Size,
+				},
+			), nil
+		})
+}
@@ -2,17 +2,21 @@ package tools
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	_ "embed"
 	"fmt"
 	"html/template"
+	"os"
 	"strings"
 	"time"
 
 	"charm.land/fantasy"
 	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/shell"
+	"github.com/charmbracelet/crush/internal/terminal"
 )
 
 type BashParams struct {
@@ -33,6 +37,7 @@ type BashResponseMetadata struct {
 	Output           string `json:"output"`
 	Description      string `json:"description"`
 	WorkingDirectory string `json:"working_directory"`
+	ExitCode         int    `json:"exit_code,omitempty"`
 }
 
 const (
@@ -177,10 +182,38 @@ func blockFuncs() []shell.BlockFunc {
 	}
 }
 
-func NewBashTool(permissions permission.Service, workingDir string, attribution *config.Attribution) fantasy.AgentTool {
+// sessionShell is the shell of a session that overrides its environment.
+type sessionShell struct {
+	env SessionEnv
+	*shell.Shell
+}
+
+func NewBashTool(permissions permission.Service, workingDir string, attribution *config.Attribution, terminal terminal.Service) fantasy.AgentTool {
 	// Set up command blocking on the persistent shell
 	persistentShell := shell.GetPersistentShell(workingDir)
 	persistentShell.SetBlockFuncs(blockFuncs())
+	sessionShells := csync.NewMap[string, *sessionShell]()
+	// shellFor returns the shell commands of the session run in, sessions
+	// with their own working directory or environment get their own shell.
+	shellFor := func(ctx context.Context, sessionID string) *shell.Shell {
+		env, ok := GetSessionEnvFromContext(ctx)
+		if !ok {
+			return shell.GetPersistentShell(workingDir).Shell
+		}
+		if s, ok := sessionShells.Get(sessionID); ok && s.env.equal(env) {
+			return s.Shell
+		}
+		s := &sessionShell{
+			env: env,
+			Shell: shell.NewShell(&shell.Options{
+				WorkingDir: cmp.Or(env.WorkingDir, workingDir),
+				Env:        append(os.Environ(), env.Env...),
+				BlockFuncs: blockFuncs(),
+			}),
+		}
+		sessionShells.Set(sessionID, s)
+		return s.Shell
+	}
 	return fantasy.NewAgentTool(
 		BashToolName,
 		string(bashDescription(attribution)),
@@ -211,12 +244,23 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 			if sessionID == "" {
 				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for executing shell command")
 			}
+			// Read-only commands still run in dry-run mode so the plan can be
+			// based on the actual state of the project.
+			sessionShell := shellFor(ctx, sessionID)
+			if !isSafeReadOnly && IsDryRunFromContext(ctx) {
+				return fantasy.WithResponseMetadata(
+					NewDryRunResponse(fmt.Sprintf("Execute command: %s", params.Command)),
+					BashResponseMetadata{
+						Description:      params.Description,
+						WorkingDirectory: sessionShell.GetWorkingDir(),
+					},
+				), nil
+			}
 			if !isSafeReadOnly {
-				shell := shell.GetPersistentShell(workingDir)
 				p := permissions.Request(
 					permission.CreatePermissionRequest{
 						SessionID:   sessionID,
-						Path:        shell.GetWorkingDir(),
+						Path:        sessionShell.GetWorkingDir(),
 						ToolCallID:  call.ID,
 						ToolName:    BashToolName,
 						Action:      "execute",
@@ -238,11 +282,19 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 				defer cancel()
 			}
 
-			persistentShell := shell.GetPersistentShell(workingDir)
-			stdout, stderr, err := persistentShell.Exec(ctx, params.Command)
+			var stdout, stderr string
+			var err error
+			// Stream the output so long-running commands can be followed.
+			if terminal != nil {
+				out := terminal.Writer(sessionID, call.ID, params.Command)
+				stdout, stderr, err = sessionShell.ExecStream(ctx, params.Command, out)
+				out.Close()
+			} else {
+				stdout, stderr, err = sessionShell.Exec(ctx, params.Command)
+			}
 
 			// Get the current working directory after command execution
-			currentWorkingDir := persistentShell.GetWorkingDir()
+			currentWorkingDir := sessionShell.GetWorkingDir()
 			interrupted := shell.IsInterrupt(err)
 			exitCode := shell.ExitCode(err)
 			if exitCode == 0 && !interrupted && err != nil {
@@ -285,6 +337,7 @@ func NewBashTool(permissions permission.Service, workingDir string, attribution
 				Output:           stdout,
 				Description:      params.Description,
 				WorkingDirectory: currentWorkingDir,
+				ExitCode:         exitCode,
 			}
 			if stdout == "" {
 				return fantasy.WithResponseMetadata(fantasy.NewTextResponse(BashNoOutput), metadata), nil
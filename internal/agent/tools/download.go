@@ -72,6 +72,10 @@ func NewDownloadTool(permissions permission.Service, workingDir string, client *
 				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for downloading files")
 			}
 
+			if IsDryRunFromContext(ctx) {
+				return NewDryRunResponse(fmt.Sprintf("Download file from URL: %s to %s", params.URL, filePath)), nil
+			}
+
 			p := permissions.Request(
 				permission.CreatePermissionRequest{
 					SessionID:   sessionID,
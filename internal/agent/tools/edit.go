@@ -114,11 +114,6 @@ func createNewFile(edit editContext, filePath, content string, call fantasy.Tool
 		return fantasy.ToolResponse{}, fmt.Errorf("failed to access file: %w", err)
 	}
 
-	dir := filepath.Dir(filePath)
-	if err = os.MkdirAll(dir, 0o755); err != nil {
-		return fantasy.ToolResponse{}, fmt.Errorf("failed to create parent directories: %w", err)
-	}
-
 	sessionID := GetSessionFromContext(edit.ctx)
 	if sessionID == "" {
 		return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for creating a new file")
@@ -129,6 +124,17 @@ func createNewFile(edit editContext, filePath, content string, call fantasy.Tool
 		content,
 		strings.TrimPrefix(filePath, edit.workingDir),
 	)
+	if IsDryRunFromContext(edit.ctx) {
+		return fantasy.WithResponseMetadata(
+			NewDryRunResponse(fmt.Sprintf("Create file %s", filePath)),
+			EditResponseMetadata{
+				NewContent: content,
+				Additions:  additions,
+				Removals:   removals,
+			},
+		), nil
+	}
+
 	p := edit.permissions.Request(
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
@@ -148,6 +154,11 @@ func createNewFile(edit editContext, filePath, content string, call fantasy.Tool
 		return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
+	dir := filepath.Dir(filePath)
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return fantasy.ToolResponse{}, fmt.Errorf("failed to create parent directories: %w", err)
+	}
+
 	err = os.WriteFile(filePath, []byte(content), 0o644)
 	if err != nil {
 		return fantasy.ToolResponse{}, fmt.Errorf("failed to write file: %w", err)
@@ -250,6 +261,18 @@ func deleteContent(edit editContext, filePath, oldString string, replaceAll bool
 		strings.TrimPrefix(filePath, edit.workingDir),
 	)
 
+	if IsDryRunFromContext(edit.ctx) {
+		return fantasy.WithResponseMetadata(
+			NewDryRunResponse(fmt.Sprintf("Delete content from file %s", filePath)),
+			EditResponseMetadata{
+				OldContent: oldContent,
+				NewContent: newContent,
+				Additions:  additions,
+				Removals:   removals,
+			},
+		), nil
+	}
+
 	p := edit.permissions.Request(
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
@@ -385,6 +408,18 @@ func replaceContent(edit editContext, filePath, oldString, newString string, rep
 		strings.TrimPrefix(filePath, edit.workingDir),
 	)
 
+	if IsDryRunFromContext(edit.ctx) {
+		return fantasy.WithResponseMetadata(
+			NewDryRunResponse(fmt.Sprintf("Replace content in file %s", filePath)),
+			EditResponseMetadata{
+				OldContent: oldContent,
+				NewContent: newContent,
+				Additions:  additions,
+				Removals:   removals,
+			},
+		), nil
+	}
+
 	p := edit.permissions.Request(
 		permission.CreatePermissionRequest{
 			SessionID:   sessionID,
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/fact"
+)
+
+type FactsParams struct {
+	Key      string `json:"key" description:"Short unique name of the fact"`
+	Value    string `json:"value,omitempty" description:"The fact, leave empty to delete the fact with the key"`
+	Category string `json:"category,omitempty" description:"The category of the fact: decision, constraint, endpoint or note. Defaults to note"`
+}
+
+const FactsToolName = "facts"
+
+//go:embed facts.md
+var factsDescription []byte
+
+func NewFactsTool(facts fact.Service) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		FactsToolName,
+		string(factsDescription),
+		func(ctx context.Context, params FactsParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if strings.TrimSpace(params.Key) == "" {
+				return fantasy.NewTextErrorResponse("key is required"), nil
+			}
+			switch params.Category {
+			case "", fact.CategoryDecision, fact.CategoryConstraint, fact.CategoryEndpoint, fact.CategoryNote:
+			default:
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("unknown category %q, use decision, constraint, endpoint or note", params.Category)), nil
+			}
+
+			sessionID := GetSessionFromContext(ctx)
+			if sessionID == "" {
+				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for recording facts")
+			}
+
+			if IsDryRunFromContext(ctx) {
+				return NewDryRunResponse(fmt.Sprintf("Record fact %q", params.Key)), nil
+			}
+
+			if strings.TrimSpace(params.Value) == "" {
+				err := facts.Delete(ctx, sessionID, params.Key)
+				switch {
+				case errors.Is(err, fact.ErrNotFound):
+					return fantasy.NewTextErrorResponse(err.Error()), nil
+				case err != nil:
+					return fantasy.ToolResponse{}, fmt.Errorf("error deleting fact: %w", err)
+				}
+				return fantasy.NewTextResponse(fmt.Sprintf("Deleted fact %q", params.Key)), nil
+			}
+
+			saved, err := facts.Set(ctx, sessionID, params.Key, params.Value, params.Category)
+			switch {
+			case errors.Is(err, fact.ErrTooLong), errors.Is(err, fact.ErrTooMany):
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			case err != nil:
+				return fantasy.ToolResponse{}, fmt.Errorf("error recording fact: %w", err)
+			}
+			return fantasy.NewTextResponse(fmt.Sprintf("Recorded %s %q", saved.Category, saved.Key)), nil
+		})
+}
@@ -95,6 +95,8 @@ type GrepResponseMetadata struct {
 const (
 	GrepToolName        = "grep"
 	maxGrepContentWidth = 500
+	// grepLimit is the number of matches returned.
+	grepLimit = 100
 )
 
 //go:embed grep.md
@@ -121,20 +123,14 @@ func NewGrepTool(workingDir string) fantasy.AgentTool {
 				return fantasy.NewTextErrorResponse("pattern is required"), nil
 			}
 
-			// If literal_text is true, escape the pattern
-			searchPattern := params.Pattern
-			if params.LiteralText {
-				searchPattern = escapeRegexPattern(params.Pattern)
-			}
-
-			searchPath := params.Path
-			if searchPath == "" {
-				searchPath = workingDir
-			}
-
-			matches, truncated, err := searchFiles(ctx, searchPattern, searchPath, params.Include, 100)
-			if err != nil {
-				return fantasy.NewTextErrorResponse(fmt.Sprintf("error searching files: %v", err)), nil
+			matches, truncated, ok := prefetchedMatches(ctx, call.ID, params)
+			if !ok {
+				searchPattern, searchPath := grepSearch(params, workingDir)
+				var err error
+				matches, truncated, err = searchFiles(ctx, searchPattern, searchPath, params.Include, grepLimit)
+				if err != nil {
+					return fantasy.NewTextErrorResponse(fmt.Sprintf("error searching files: %v", err)), nil
+				}
 			}
 
 			var output strings.Builder
@@ -182,6 +178,20 @@ func NewGrepTool(workingDir string) fantasy.AgentTool {
 		})
 }
 
+// grepSearch returns the pattern and path to search for the parameters.
+func grepSearch(params GrepParams, workingDir string) (pattern, path string) {
+	// If literal_text is true, escape the pattern
+	pattern = params.Pattern
+	if params.LiteralText {
+		pattern = escapeRegexPattern(params.Pattern)
+	}
+	path = params.Path
+	if path == "" {
+		path = workingDir
+	}
+	return pattern, path
+}
+
 func searchFiles(ctx context.Context, pattern, rootPath, include string, limit int) ([]grepMatch, bool, error) {
 	matches, err := searchWithRipgrep(ctx, pattern, rootPath, include)
 	if err != nil {
@@ -88,12 +88,22 @@ var (
 	mcpBroker       = pubsub.NewBroker[MCPEvent]()
 )
 
+// mcpReadResourceToolName names the tool reading the resources of a server.
+const mcpReadResourceToolName = "read_resource"
+
+// mcpMaxListedResources bounds the resources listed in the description of the
+// tool reading them.
+const mcpMaxListedResources = 50
+
 type McpTool struct {
 	mcpName         string
 	tool            *mcp.Tool
 	permissions     permission.Service
 	workingDir      string
+	timeout         time.Duration
 	providerOptions fantasy.ProviderOptions
+	// resources is set for the tool reading the resources of the server.
+	resources bool
 }
 
 func (m *McpTool) SetProviderOptions(opts fantasy.ProviderOptions) {
@@ -174,6 +184,37 @@ func runTool(ctx context.Context, name, toolName string, input string) (fantasy.
 	return fantasy.NewTextResponse(strings.Join(output, "\n")), nil
 }
 
+func readResource(ctx context.Context, name, input string) (fantasy.ToolResponse, error) {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.URI == "" {
+		return fantasy.NewTextErrorResponse("uri is required"), nil
+	}
+
+	c, err := getOrRenewClient(ctx, name)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	result, err := c.ReadResource(ctx, &mcp.ReadResourceParams{URI: params.URI})
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+
+	output := make([]string, 0, len(result.Contents))
+	for _, v := range result.Contents {
+		if v.Text != "" || len(v.Blob) == 0 {
+			output = append(output, v.Text)
+		} else {
+			output = append(output, fmt.Sprintf("[%s: %d bytes of %s]", v.URI, len(v.Blob), cmp.Or(v.MIMEType, "binary data")))
+		}
+	}
+	return fantasy.NewTextResponse(strings.Join(output, "\n")), nil
+}
+
 func getOrRenewClient(ctx context.Context, name string) (*mcp.ClientSession, error) {
 	sess, ok := mcpClients.Get(name)
 	if !ok {
@@ -224,26 +265,108 @@ func (m *McpTool) Run(ctx context.Context, params fantasy.ToolCall) (fantasy.Too
 		return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
-	return runTool(ctx, m.mcpName, m.tool.Name, params.Input)
+	if m.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+	}
+	var resp fantasy.ToolResponse
+	var err error
+	if m.resources {
+		resp, err = readResource(ctx, m.mcpName, params.Input)
+	} else {
+		resp, err = runTool(ctx, m.mcpName, m.tool.Name, params.Input)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("%s timed out after %s", m.Info().Name, m.timeout)), nil
+	}
+	return resp, err
 }
 
-func getTools(ctx context.Context, name string, permissions permission.Service, c *mcp.ClientSession, workingDir string) ([]*McpTool, error) {
+func getTools(ctx context.Context, name string, m config.MCPConfig, permissions permission.Service, c *mcp.ClientSession, workingDir string) ([]*McpTool, error) {
 	result, err := c.ListTools(ctx, &mcp.ListToolsParams{})
 	if err != nil {
 		return nil, err
 	}
-	mcpTools := make([]*McpTool, 0, len(result.Tools))
+	timeout := time.Duration(max(m.ToolTimeout, 0)) * time.Second
+	mcpTools := make([]*McpTool, 0, len(result.Tools)+1)
 	for _, tool := range result.Tools {
+		if len(m.Tools) > 0 && !slices.Contains(m.Tools, tool.Name) {
+			continue
+		}
+		mcpTools = append(mcpTools, &McpTool{
+			mcpName:     name,
+			tool:        tool,
+			permissions: permissions,
+			workingDir:  workingDir,
+			timeout:     timeout,
+		})
+	}
+
+	if len(m.Tools) > 0 && !slices.Contains(m.Tools, mcpReadResourceToolName) {
+		return mcpTools, nil
+	}
+	tool, err := getResourceTool(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if tool != nil {
 		mcpTools = append(mcpTools, &McpTool{
 			mcpName:     name,
 			tool:        tool,
 			permissions: permissions,
 			workingDir:  workingDir,
+			timeout:     timeout,
+			resources:   true,
 		})
 	}
 	return mcpTools, nil
 }
 
+// getResourceTool returns the tool reading the resources of the server,
+// listing them in its description, or nil when it has none.
+func getResourceTool(ctx context.Context, c *mcp.ClientSession) (*mcp.Tool, error) {
+	init := c.InitializeResult()
+	if init == nil || init.Capabilities == nil || init.Capabilities.Resources == nil {
+		return nil, nil
+	}
+	var b strings.Builder
+	b.WriteString("Reads a resource of the MCP server by its URI. The resources include:\n")
+	n := 0
+	for resource, err := range c.Resources(ctx, nil) {
+		if err != nil {
+			return nil, err
+		}
+		if n == mcpMaxListedResources {
+			b.WriteString("- ...\n")
+			break
+		}
+		fmt.Fprintf(&b, "- %s (%s)", resource.URI, cmp.Or(resource.Title, resource.Name))
+		if resource.Description != "" {
+			fmt.Fprintf(&b, ": %s", resource.Description)
+		}
+		b.WriteString("\n")
+		n++
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return &mcp.Tool{
+		Name:        mcpReadResourceToolName,
+		Description: b.String(),
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"uri": map[string]any{
+					"type":        "string",
+					"description": "The URI of the resource to read",
+				},
+			},
+			"required": []any{"uri"},
+		},
+	}, nil
+}
+
 // SubscribeMCPEvents returns a channel for MCP events
 func SubscribeMCPEvents(ctx context.Context) <-chan pubsub.Event[MCPEvent] {
 	return mcpBroker.Subscribe(ctx)
@@ -345,7 +468,7 @@ func GetMCPTools(ctx context.Context, permissions permission.Service, cfg *confi
 
 				mcpClients.Set(name, c)
 
-				tools, err := getTools(ctx, name, permissions, c, cfg.WorkingDir())
+				tools, err := getTools(ctx, name, m, permissions, c, cfg.WorkingDir())
 				if err != nil {
 					slog.Error("error listing tools", "error", err)
 					updateMCPState(name, MCPStateError, err, nil, 0)
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+// connectMCP connects a client to the server in memory.
+func connectMCP(t *testing.T, server *mcp.Server) *mcp.ClientSession {
+	t.Helper()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(t.Context(), serverTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { serverSession.Close() })
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "crush"}, nil)
+	session, err := client.Connect(t.Context(), clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func TestGetMCPTools(t *testing.T) {
+	t.Parallel()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "docs"}, nil)
+	handler := func(context.Context, *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{}, nil
+	}
+	schema := map[string]any{"type": "object", "properties": map[string]any{"query": map[string]any{"type": "string"}}}
+	server.AddTool(&mcp.Tool{Name: "search", InputSchema: schema}, handler)
+	server.AddTool(&mcp.Tool{Name: "delete", InputSchema: schema}, handler)
+	server.AddResource(&mcp.Resource{URI: "docs://guide", Name: "guide", Description: "The user guide"}, func(context.Context, *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: "docs://guide", Text: "Welcome"}}}, nil
+	})
+	session := connectMCP(t, server)
+
+	names := func(tools []*McpTool) []string {
+		var names []string
+		for _, tool := range tools {
+			names = append(names, tool.Info().Name)
+		}
+		return names
+	}
+
+	tools, err := getTools(t.Context(), "docs", config.MCPConfig{ToolTimeout: 30}, nil, session, t.TempDir())
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"mcp_docs_search", "mcp_docs_delete", "mcp_docs_read_resource"}, names(tools))
+
+	resources := tools[len(tools)-1]
+	require.True(t, resources.resources)
+	require.Equal(t, 30*time.Second, resources.timeout)
+	info := resources.Info()
+	require.Contains(t, info.Description, "- docs://guide (guide): The user guide")
+	require.Equal(t, []string{"uri"}, info.Required)
+
+	// Only the tools allowed.
+	tools, err = getTools(t.Context(), "docs", config.MCPConfig{Tools: []string{"search"}}, nil, session, t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, []string{"mcp_docs_search"}, names(tools))
+	require.Zero(t, tools[0].timeout)
+}
+
+func TestGetResourceToolWithoutResources(t *testing.T) {
+	t.Parallel()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "empty"}, nil)
+	tool, err := getResourceTool(t.Context(), connectMCP(t, server))
+	require.NoError(t, err)
+	require.Nil(t, tool)
+}
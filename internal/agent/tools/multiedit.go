@@ -128,12 +128,6 @@ func processMultiEditWithCreation(edit editContext, params MultiEditParams, call
 		return fantasy.ToolResponse{}, fmt.Errorf("failed to access file: %w", err)
 	}
 
-	// Create parent directories
-	dir := filepath.Dir(params.FilePath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return fantasy.ToolResponse{}, fmt.Errorf("failed to create parent directories: %w", err)
-	}
-
 	// Start with the content from the first edit
 	currentContent := firstEdit.NewString
 
@@ -156,6 +150,18 @@ func processMultiEditWithCreation(edit editContext, params MultiEditParams, call
 	// Check permissions
 	_, additions, removals := diff.GenerateDiff("", currentContent, strings.TrimPrefix(params.FilePath, edit.workingDir))
 
+	if IsDryRunFromContext(edit.ctx) {
+		return fantasy.WithResponseMetadata(
+			NewDryRunResponse(fmt.Sprintf("Create file %s with %d edits", params.FilePath, len(params.Edits))),
+			MultiEditResponseMetadata{
+				NewContent:   currentContent,
+				Additions:    additions,
+				Removals:     removals,
+				EditsApplied: len(params.Edits),
+			},
+		), nil
+	}
+
 	p := edit.permissions.Request(permission.CreatePermissionRequest{
 		SessionID:   sessionID,
 		Path:        fsext.PathOrPrefix(params.FilePath, edit.workingDir),
@@ -173,6 +179,12 @@ func processMultiEditWithCreation(edit editContext, params MultiEditParams, call
 		return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
 	}
 
+	// Create parent directories
+	dir := filepath.Dir(params.FilePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fantasy.ToolResponse{}, fmt.Errorf("failed to create parent directories: %w", err)
+	}
+
 	// Write the file
 	err := os.WriteFile(params.FilePath, []byte(currentContent), 0o644)
 	if err != nil {
@@ -265,6 +277,18 @@ func processMultiEditExistingFile(edit editContext, params MultiEditParams, call
 
 	// Generate diff and check permissions
 	_, additions, removals := diff.GenerateDiff(oldContent, currentContent, strings.TrimPrefix(params.FilePath, edit.workingDir))
+	if IsDryRunFromContext(edit.ctx) {
+		return fantasy.WithResponseMetadata(
+			NewDryRunResponse(fmt.Sprintf("Apply %d edits to file %s", len(params.Edits), params.FilePath)),
+			MultiEditResponseMetadata{
+				OldContent:   oldContent,
+				NewContent:   currentContent,
+				Additions:    additions,
+				Removals:     removals,
+				EditsApplied: len(params.Edits),
+			},
+		), nil
+	}
 	p := edit.permissions.Request(permission.CreatePermissionRequest{
 		SessionID:   sessionID,
 		Path:        fsext.PathOrPrefix(params.FilePath, edit.workingDir),
@@ -0,0 +1,380 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/permission"
+)
+
+type MultiFileEditOperation struct {
+	OldString string `json:"old_string" description:"The text to replace, or a regular expression when regex is true"`
+	NewString string `json:"new_string" description:"The text to replace it with. With regex, $1 and ${name} expand to capture groups"`
+	Regex     bool   `json:"regex,omitempty" description:"Treat old_string as a Go regular expression (default false)"`
+}
+
+type MultiFileEditParams struct {
+	Files   []string                 `json:"files,omitempty" description:"The paths of the files to modify"`
+	Pattern string                   `json:"pattern,omitempty" description:"A glob pattern selecting the files to modify, used when files is empty"`
+	Path    string                   `json:"path,omitempty" description:"The directory the pattern is matched in. Defaults to the current working directory."`
+	Edits   []MultiFileEditOperation `json:"edits" description:"Array of replacements applied in order to every file"`
+}
+
+type MultiFileEditPermissionsParams struct {
+	Files []string `json:"files"`
+	Diff  string   `json:"diff"`
+}
+
+type MultiFileEditFileResult struct {
+	FilePath     string `json:"file_path"`
+	Replacements int    `json:"replacements"`
+	Additions    int    `json:"additions"`
+	Removals     int    `json:"removals"`
+	Skipped      string `json:"skipped,omitempty"`
+}
+
+type MultiFileEditResponseMetadata struct {
+	Files        []MultiFileEditFileResult `json:"files"`
+	Replacements int                       `json:"replacements"`
+	Additions    int                       `json:"additions"`
+	Removals     int                       `json:"removals"`
+}
+
+const (
+	MultiFileEditToolName = "multi_file_edit"
+
+	// maxMultiFileEditFiles caps how many files a single call may touch.
+	maxMultiFileEditFiles = 200
+)
+
+//go:embed multifileedit.md
+var multiFileEditDescription []byte
+
+// fileChange is a pending change to a single file.
+type fileChange struct {
+	path       string
+	oldContent string
+	newContent string
+	isCrlf     bool
+	mode       os.FileMode
+	result     MultiFileEditFileResult
+}
+
+func NewMultiFileEditTool(lspClients *csync.Map[string, *lsp.Client], permissions permission.Service, files history.Service, workingDir string) fantasy.AgentTool {
+	return fantasy.NewAgentTool(
+		MultiFileEditToolName,
+		string(multiFileEditDescription),
+		func(ctx context.Context, params MultiFileEditParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if len(params.Files) == 0 && params.Pattern == "" {
+				return fantasy.NewTextErrorResponse("either files or pattern is required"), nil
+			}
+			if len(params.Edits) == 0 {
+				return fantasy.NewTextErrorResponse("at least one edit operation is required"), nil
+			}
+
+			replacers, err := compileMultiFileEdits(params.Edits)
+			if err != nil {
+				return fantasy.NewTextErrorResponse(err.Error()), nil
+			}
+
+			paths, errResp, err := multiFileEditPaths(ctx, params, workingDir)
+			if err != nil {
+				return fantasy.ToolResponse{}, err
+			}
+			if errResp != nil {
+				return *errResp, nil
+			}
+
+			// Compute every change up front so nothing is written unless all
+			// files can be edited.
+			var changes []*fileChange
+			var skipped []MultiFileEditFileResult
+			for _, path := range paths {
+				change, errResp, err := prepareFileChange(path, replacers)
+				if err != nil {
+					return fantasy.ToolResponse{}, err
+				}
+				if errResp != nil {
+					return *errResp, nil
+				}
+				if change.result.Skipped != "" {
+					skipped = append(skipped, change.result)
+					continue
+				}
+				changes = append(changes, change)
+			}
+
+			if len(changes) == 0 {
+				return fantasy.WithResponseMetadata(
+					fantasy.NewTextErrorResponse("no matches found in any of the files"),
+					MultiFileEditResponseMetadata{Files: skipped},
+				), nil
+			}
+
+			var combinedDiff strings.Builder
+			changedPaths := make([]string, 0, len(changes))
+			for _, change := range changes {
+				d, additions, removals := diff.GenerateDiff(change.oldContent, change.newContent, strings.TrimPrefix(change.path, workingDir))
+				change.result.Additions = additions
+				change.result.Removals = removals
+				combinedDiff.WriteString(d)
+				changedPaths = append(changedPaths, change.path)
+			}
+			metadata := multiFileEditMetadata(changes, skipped)
+			description := fmt.Sprintf("Apply %d edits to %d files", len(params.Edits), len(changes))
+
+			if IsDryRunFromContext(ctx) {
+				return fantasy.WithResponseMetadata(NewDryRunResponse(description), metadata), nil
+			}
+
+			sessionID := GetSessionFromContext(ctx)
+			if sessionID == "" {
+				return fantasy.ToolResponse{}, fmt.Errorf("session ID is required for editing files")
+			}
+
+			p := permissions.Request(permission.CreatePermissionRequest{
+				SessionID:   sessionID,
+				Path:        fsext.PathOrPrefix(commonDir(changedPaths), workingDir),
+				ToolCallID:  call.ID,
+				ToolName:    MultiFileEditToolName,
+				Action:      "write",
+				Description: description,
+				Params: MultiFileEditPermissionsParams{
+					Files: changedPaths,
+					Diff:  combinedDiff.String(),
+				},
+			})
+			if !p {
+				return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
+			}
+
+			if err := writeFileChanges(changes); err != nil {
+				return fantasy.ToolResponse{}, err
+			}
+
+			for _, change := range changes {
+				recordFileChangeHistory(ctx, files, sessionID, change)
+				recordFileWrite(change.path)
+				recordFileRead(change.path)
+				notifyLSPs(ctx, lspClients, change.path)
+			}
+
+			var text strings.Builder
+			text.WriteString("<result>\n")
+			fmt.Fprintf(&text, "%s:\n", description)
+			for _, result := range metadata.Files {
+				if result.Skipped != "" {
+					fmt.Fprintf(&text, "- %s: skipped (%s)\n", result.FilePath, result.Skipped)
+					continue
+				}
+				fmt.Fprintf(&text, "- %s: %d replacements\n", result.FilePath, result.Replacements)
+			}
+			text.WriteString("</result>\n")
+			for _, change := range changes {
+				text.WriteString(getDiagnostics(change.path, lspClients))
+			}
+
+			return fantasy.WithResponseMetadata(fantasy.NewTextResponse(text.String()), metadata), nil
+		})
+}
+
+// replacer applies a single edit operation to a file's content.
+type replacer func(content string) (string, int)
+
+func compileMultiFileEdits(edits []MultiFileEditOperation) ([]replacer, error) {
+	replacers := make([]replacer, 0, len(edits))
+	for i, edit := range edits {
+		if edit.OldString == "" {
+			return nil, fmt.Errorf("edit %d: old_string is required", i+1)
+		}
+		if edit.OldString == edit.NewString {
+			return nil, fmt.Errorf("edit %d: old_string and new_string are identical", i+1)
+		}
+		if !edit.Regex {
+			replacers = append(replacers, func(content string) (string, int) {
+				n := strings.Count(content, edit.OldString)
+				return strings.ReplaceAll(content, edit.OldString, edit.NewString), n
+			})
+			continue
+		}
+		re, err := regexp.Compile(edit.OldString)
+		if err != nil {
+			return nil, fmt.Errorf("edit %d: invalid regular expression: %w", i+1, err)
+		}
+		replacers = append(replacers, func(content string) (string, int) {
+			n := len(re.FindAllStringIndex(content, -1))
+			return re.ReplaceAllString(content, edit.NewString), n
+		})
+	}
+	return replacers, nil
+}
+
+func multiFileEditPaths(ctx context.Context, params MultiFileEditParams, workingDir string) ([]string, *fantasy.ToolResponse, error) {
+	var paths []string
+	if len(params.Files) > 0 {
+		paths = make([]string, 0, len(params.Files))
+		for _, path := range params.Files {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(workingDir, path)
+			}
+			paths = append(paths, filepath.Clean(path))
+		}
+	} else {
+		searchPath := params.Path
+		if searchPath == "" {
+			searchPath = workingDir
+		} else if !filepath.IsAbs(searchPath) {
+			searchPath = filepath.Join(workingDir, searchPath)
+		}
+		matches, truncated, err := globFiles(ctx, params.Pattern, searchPath, maxMultiFileEditFiles+1)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error finding files: %w", err)
+		}
+		if truncated || len(matches) > maxMultiFileEditFiles {
+			resp := fantasy.NewTextErrorResponse(fmt.Sprintf("pattern matches more than %d files, use a more specific pattern or path", maxMultiFileEditFiles))
+			return nil, &resp, nil
+		}
+		paths = matches
+	}
+
+	if len(paths) > maxMultiFileEditFiles {
+		resp := fantasy.NewTextErrorResponse(fmt.Sprintf("at most %d files can be edited at once", maxMultiFileEditFiles))
+		return nil, &resp, nil
+	}
+	if len(paths) == 0 {
+		resp := fantasy.NewTextErrorResponse("no files matched the pattern")
+		return nil, &resp, nil
+	}
+	return paths, nil, nil
+}
+
+func prepareFileChange(path string, replacers []replacer) (*fileChange, *fantasy.ToolResponse, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			resp := fantasy.NewTextErrorResponse(fmt.Sprintf("file not found: %s", path))
+			return nil, &resp, nil
+		}
+		return nil, nil, fmt.Errorf("failed to access file: %w", err)
+	}
+	if fileInfo.IsDir() {
+		resp := fantasy.NewTextErrorResponse(fmt.Sprintf("path is a directory, not a file: %s", path))
+		return nil, &resp, nil
+	}
+
+	// Files don't need to be read first, but if they were, they must not
+	// have changed since.
+	if lastRead := getLastReadTime(path); !lastRead.IsZero() && fileInfo.ModTime().After(lastRead) {
+		resp := fantasy.NewTextErrorResponse(
+			fmt.Sprintf("file %s has been modified since it was last read (mod time: %s, last read: %s)",
+				path, fileInfo.ModTime().Format(time.RFC3339), lastRead.Format(time.RFC3339),
+			))
+		return nil, &resp, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	oldContent, isCrlf := fsext.ToUnixLineEndings(string(content))
+	change := &fileChange{
+		path:       path,
+		oldContent: oldContent,
+		newContent: oldContent,
+		isCrlf:     isCrlf,
+		mode:       fileInfo.Mode().Perm(),
+		result:     MultiFileEditFileResult{FilePath: path},
+	}
+	for _, replace := range replacers {
+		var n int
+		change.newContent, n = replace(change.newContent)
+		change.result.Replacements += n
+	}
+
+	switch {
+	case change.result.Replacements == 0:
+		change.result.Skipped = "no matches"
+	case change.newContent == change.oldContent:
+		change.result.Skipped = "no changes"
+	}
+	return change, nil, nil
+}
+
+// writeFileChanges writes all changes, restoring the files already written if
+// any write fails.
+func writeFileChanges(changes []*fileChange) error {
+	for i, change := range changes {
+		content := change.newContent
+		if change.isCrlf {
+			content, _ = fsext.ToWindowsLineEndings(content)
+		}
+		if err := os.WriteFile(change.path, []byte(content), change.mode); err != nil {
+			for _, written := range changes[:i] {
+				original := written.oldContent
+				if written.isCrlf {
+					original, _ = fsext.ToWindowsLineEndings(original)
+				}
+				if restoreErr := os.WriteFile(written.path, []byte(original), written.mode); restoreErr != nil {
+					slog.Error("Failed to restore file after failed multi-file edit", "file", written.path, "error", restoreErr)
+				}
+			}
+			return fmt.Errorf("failed to write file %s: %w", change.path, err)
+		}
+	}
+	return nil
+}
+
+func recordFileChangeHistory(ctx context.Context, files history.Service, sessionID string, change *fileChange) {
+	file, err := files.GetByPathAndSession(ctx, change.path, sessionID)
+	if err != nil {
+		if _, err := files.Create(ctx, sessionID, change.path, change.oldContent); err != nil {
+			slog.Debug("Error creating file history", "error", err)
+			return
+		}
+	} else if file.Content != change.oldContent {
+		// User manually changed the content, store an intermediate version.
+		if _, err := files.CreateVersion(ctx, sessionID, change.path, change.oldContent); err != nil {
+			slog.Debug("Error creating file history version", "error", err)
+		}
+	}
+	if _, err := files.CreateVersion(ctx, sessionID, change.path, change.newContent); err != nil {
+		slog.Debug("Error creating file history version", "error", err)
+	}
+}
+
+func multiFileEditMetadata(changes []*fileChange, skipped []MultiFileEditFileResult) MultiFileEditResponseMetadata {
+	var metadata MultiFileEditResponseMetadata
+	for _, change := range changes {
+		metadata.Files = append(metadata.Files, change.result)
+		metadata.Replacements += change.result.Replacements
+		metadata.Additions += change.result.Additions
+		metadata.Removals += change.result.Removals
+	}
+	metadata.Files = append(metadata.Files, skipped...)
+	return metadata
+}
+
+// commonDir returns the deepest directory containing all the given paths.
+func commonDir(paths []string) string {
+	dir := filepath.Dir(paths[0])
+	for _, path := range paths[1:] {
+		for dir != filepath.Dir(dir) && !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			dir = filepath.Dir(dir)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// OutlineTokenBudget is the estimated size above which a full read of a
+	// file returns its outline instead of its content.
+	OutlineTokenBudget = 20000
+
+	// charsPerToken is a rough estimate of the size of a token.
+	charsPerToken = 4
+
+	maxOutlineImports = 50
+	maxOutlineSymbols = 400
+)
+
+var (
+	importLineRe = regexp.MustCompile(`^\s*(import\b|from\s+\S+\s+import\b|use\s|#include\b|require\b|using\s|package\s)`)
+	symbolLineRe = regexp.MustCompile(`^(\s{0,4}|\t?)(export\s+)?(default\s+)?(pub(\(\w+\))?\s+)?` +
+		`(public\s+|private\s+|protected\s+|internal\s+|static\s+|abstract\s+|final\s+|async\s+|unsafe\s+)*` +
+		`(func|function|def|class|interface|struct|enum|trait|impl|fn|type|module|namespace|object|record)\b`)
+	topLevelLineRe = regexp.MustCompile(`^(export\s+)?(const|var|let|val)\b`)
+	headingLineRe  = regexp.MustCompile(`^#{1,6}\s+\S`)
+)
+
+// estimateTokens returns a rough estimate of the number of tokens in a text
+// of the given size.
+func estimateTokens(size int64) int64 {
+	return (size + charsPerToken - 1) / charsPerToken
+}
+
+// outlineLine is a line of a file kept in its outline.
+type outlineLine struct {
+	number int
+	text   string
+}
+
+// buildOutline returns the imports and the top level declarations of a file
+// with their line numbers, so the relevant ranges can be read on their own.
+func buildOutline(content string, markdown bool) (imports, symbols []outlineLine) {
+	inImportBlock, inCodeFence := false, false
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		entry := outlineLine{number: i + 1, text: line}
+		if len(entry.text) > MaxLineLength {
+			entry.text = entry.text[:MaxLineLength] + "..."
+		}
+
+		switch {
+		case markdown:
+			if strings.HasPrefix(line, "```") {
+				inCodeFence = !inCodeFence
+			}
+			if !inCodeFence && headingLineRe.MatchString(line) && len(symbols) < maxOutlineSymbols {
+				symbols = append(symbols, entry)
+			}
+			continue
+		case inImportBlock:
+			if len(imports) < maxOutlineImports {
+				imports = append(imports, entry)
+			}
+			if strings.HasPrefix(strings.TrimSpace(line), ")") {
+				inImportBlock = false
+			}
+			continue
+		case importLineRe.MatchString(line):
+			if len(imports) < maxOutlineImports {
+				imports = append(imports, entry)
+			}
+			inImportBlock = strings.HasSuffix(strings.TrimSpace(line), "(")
+			continue
+		}
+
+		isSymbol := symbolLineRe.MatchString(line) || topLevelLineRe.MatchString(line)
+		if isSymbol && len(symbols) < maxOutlineSymbols {
+			symbols = append(symbols, entry)
+		}
+	}
+	return imports, symbols
+}
+
+// formatOutline renders the outline of a file that is too large to be read
+// at once, with a hint on how to read specific ranges.
+func formatOutline(filePath, content string, lineCount int, size int64) string {
+	markdown := strings.HasSuffix(strings.ToLower(filePath), ".md")
+	imports, symbols := buildOutline(content, markdown)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "File is too large to read at once (%d lines, ~%d tokens). Showing its outline instead.\n",
+		lineCount, estimateTokens(size))
+	if len(imports) > 0 {
+		sb.WriteString("\nImports:\n")
+		writeOutlineLines(&sb, imports)
+	}
+	if len(symbols) > 0 {
+		sb.WriteString("\nOutline:\n")
+		writeOutlineLines(&sb, symbols)
+		if len(symbols) == maxOutlineSymbols {
+			sb.WriteString("\n(Outline truncated)\n")
+		}
+	} else {
+		sb.WriteString("\nNo declarations found.\n")
+	}
+
+	example := 1
+	if len(symbols) > 0 {
+		example = symbols[0].number
+	}
+	fmt.Fprintf(&sb, "\nUse the 'offset' and 'limit' parameters to read the ranges you need, e.g. offset=%d limit=100 to read from line %d.",
+		example-1, example)
+	return sb.String()
+}
+
+func writeOutlineLines(sb *strings.Builder, lines []outlineLine) {
+	for _, line := range lines {
+		sb.WriteString(addLineNumbers(line.text, line.number))
+		sb.WriteString("\n")
+	}
+}
@@ -0,0 +1,289 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type prefetcherContextKey string
+
+// PrefetcherContextKey carries the prefetcher of the run to the tools.
+const PrefetcherContextKey prefetcherContextKey = "prefetcher"
+
+// prefetchTimeout bounds the work started for a call that may never come.
+const prefetchTimeout = 30 * time.Second
+
+// Prefetcher starts the read-only work of view and grep calls while the
+// model is still streaming their input, reading the file or searching as
+// soon as the parameters needed are complete, so the results are ready once
+// the calls are. Only these idempotent tools are prefetched, and their
+// results are only used when they still hold.
+type Prefetcher struct {
+	workingDir string
+
+	mu    sync.Mutex
+	calls map[string]*prefetchCall
+}
+
+type prefetchCall struct {
+	toolName string
+	input    strings.Builder
+	// params is the JSON of the parameters the work was started with.
+	params string
+	work   *prefetchWork
+}
+
+type prefetchWork struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	// stale is set when a tool that may change the files ran since the
+	// work started.
+	stale bool
+
+	file     prefetchedFile
+	matches  []grepMatch
+	truncate bool
+	err      error
+}
+
+type prefetchedFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+	data    []byte
+}
+
+func NewPrefetcher(workingDir string) *Prefetcher {
+	return &Prefetcher{
+		workingDir: workingDir,
+		calls:      make(map[string]*prefetchCall),
+	}
+}
+
+// GetPrefetcherFromContext returns the prefetcher of the run, nil when
+// prefetching is disabled.
+func GetPrefetcherFromContext(ctx context.Context) *Prefetcher {
+	p, _ := ctx.Value(PrefetcherContextKey).(*Prefetcher)
+	return p
+}
+
+// Start records a call the model started streaming.
+func (p *Prefetcher) Start(callID, toolName string) {
+	if toolName != ViewToolName && toolName != GrepToolName {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls[callID] = &prefetchCall{toolName: toolName}
+}
+
+// Input appends streamed input to the call, starting the work again when the
+// parameters it needs changed.
+func (p *Prefetcher) Input(callID, delta string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	call, ok := p.calls[callID]
+	if !ok {
+		return
+	}
+	call.input.WriteString(delta)
+	params := completedMembers(call.input.String())
+
+	switch call.toolName {
+	case ViewToolName:
+		var view ViewParams
+		if json.Unmarshal([]byte(params), &view) != nil || view.FilePath == "" {
+			return
+		}
+		// Only the path matters, the whole file is read.
+		key := view.FilePath
+		if key == call.params {
+			return
+		}
+		call.params = key
+		call.restart(func(ctx context.Context, w *prefetchWork) {
+			w.file, w.err = p.readFile(view.FilePath)
+		})
+	case GrepToolName:
+		var grep GrepParams
+		if json.Unmarshal([]byte(params), &grep) != nil || grep.Pattern == "" {
+			return
+		}
+		key, err := json.Marshal(grep)
+		if err != nil || string(key) == call.params {
+			return
+		}
+		call.params = string(key)
+		call.restart(func(ctx context.Context, w *prefetchWork) {
+			pattern, path := grepSearch(grep, p.workingDir)
+			w.matches, w.truncate, w.err = searchFiles(ctx, pattern, path, grep.Include, grepLimit)
+		})
+	}
+}
+
+// Done forgets the call once its tool ran. Other tools than the prefetched
+// ones may change the files, the searches done before are left unused.
+func (p *Prefetcher) Done(callID, toolName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if call, ok := p.calls[callID]; ok {
+		call.stop()
+		delete(p.calls, callID)
+	}
+	if toolName == ViewToolName || toolName == GrepToolName {
+		return
+	}
+	for _, call := range p.calls {
+		if call.toolName == GrepToolName && call.work != nil {
+			call.work.stale = true
+		}
+	}
+}
+
+// Reset forgets all calls, stopping the work still going on.
+func (p *Prefetcher) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, call := range p.calls {
+		call.stop()
+		delete(p.calls, id)
+	}
+}
+
+func (c *prefetchCall) restart(fn func(ctx context.Context, w *prefetchWork)) {
+	c.stop()
+	ctx, cancel := context.WithTimeout(context.Background(), prefetchTimeout)
+	w := &prefetchWork{done: make(chan struct{}), cancel: cancel}
+	c.work = w
+	go func() {
+		defer close(w.done)
+		defer cancel()
+		fn(ctx, w)
+	}()
+}
+
+func (c *prefetchCall) stop() {
+	if c.work != nil {
+		c.work.cancel()
+	}
+}
+
+// readFile reads the file at the path, the way the view tool would. Files
+// outside of the working directory need a permission and are never read
+// ahead.
+func (p *Prefetcher) readFile(path string) (prefetchedFile, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.workingDir, path)
+	}
+	rel, err := filepath.Rel(p.workingDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return prefetchedFile{}, os.ErrPermission
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return prefetchedFile{}, err
+	}
+	if info.IsDir() || info.Size() > MaxReadSize {
+		return prefetchedFile{}, os.ErrInvalid
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return prefetchedFile{}, err
+	}
+	return prefetchedFile{path: path, size: info.Size(), modTime: info.ModTime(), data: data}, nil
+}
+
+// work returns the work of the call started with the parameters, waiting
+// for it to finish.
+func (p *Prefetcher) work(ctx context.Context, callID, params string) (*prefetchWork, bool) {
+	if p == nil {
+		return nil, false
+	}
+	p.mu.Lock()
+	call, ok := p.calls[callID]
+	if !ok || call.work == nil || call.params != params {
+		p.mu.Unlock()
+		return nil, false
+	}
+	w := call.work
+	p.mu.Unlock()
+
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		return nil, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if w.stale || w.err != nil {
+		return nil, false
+	}
+	return w, true
+}
+
+// prefetchedContent returns the content of the file read ahead for the call,
+// if it didn't change since.
+func prefetchedContent(ctx context.Context, callID, path, filePath string, info os.FileInfo) ([]byte, bool) {
+	w, ok := GetPrefetcherFromContext(ctx).work(ctx, callID, path)
+	if !ok {
+		return nil, false
+	}
+	f := w.file
+	if f.path != filePath || f.size != info.Size() || !f.modTime.Equal(info.ModTime()) {
+		return nil, false
+	}
+	return f.data, true
+}
+
+// prefetchedMatches returns the results of the search done ahead for the
+// call, if it was done with the same parameters.
+func prefetchedMatches(ctx context.Context, callID string, params GrepParams) ([]grepMatch, bool, bool) {
+	key, err := json.Marshal(params)
+	if err != nil {
+		return nil, false, false
+	}
+	w, ok := GetPrefetcherFromContext(ctx).work(ctx, callID, string(key))
+	if !ok {
+		return nil, false, false
+	}
+	return w.matches, w.truncate, true
+}
+
+// completedMembers returns the JSON object made of the members of the
+// partial object that are complete.
+func completedMembers(partial string) string {
+	if json.Valid([]byte(partial + "}")) {
+		return partial + "}"
+	}
+	// Up to the last comma between members.
+	depth := 0
+	inString := false
+	escaped := false
+	last := -1
+	for i, r := range partial {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case inString:
+		case r == '{' || r == '[':
+			depth++
+		case r == '}' || r == ']':
+			depth--
+		case r == ',' && depth == 1:
+			last = i
+		}
+	}
+	if last < 0 {
+		return "{}"
+	}
+	return partial[:last] + "}"
+}
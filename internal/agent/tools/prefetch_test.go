@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompletedMembers(t *testing.T) {
+	t.Parallel()
+
+	for partial, want := range map[string]string{
+		``:                                   `{}`,
+		`{`:                                  `{}`,
+		`{"file_path": "main.g`:              `{}`,
+		`{"file_path": "main.go"`:            `{"file_path": "main.go"}`,
+		`{"file_path": "main.go", "off`:      `{"file_path": "main.go"}`,
+		`{"pattern": "a\", b", "literal`:     `{"pattern": "a\", b"}`,
+		`{"pattern": "x", "literal_text": t`: `{"pattern": "x"}`,
+	} {
+		require.Equal(t, want, completedMembers(partial), partial)
+	}
+}
+
+func TestPrefetchView(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	path := filepath.Join(workingDir, "main.go")
+	require.NoError(t, os.WriteFile(path, []byte("package main\n"), 0o644))
+
+	p := NewPrefetcher(workingDir)
+	p.Start("call", ViewToolName)
+	for _, delta := range []string{`{"file_`, `path": "mai`, `n.go"`, `, "limit": 10}`} {
+		p.Input("call", delta)
+	}
+	ctx := context.WithValue(t.Context(), PrefetcherContextKey, p)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	data, ok := prefetchedContent(ctx, "call", "main.go", path, info)
+	require.True(t, ok)
+	require.Equal(t, "package main\n", string(data))
+
+	// The file read ahead is left unused once the file changed.
+	require.NoError(t, os.WriteFile(path, []byte("package other\n"), 0o644))
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	_, ok = prefetchedContent(ctx, "call", "main.go", path, info)
+	require.False(t, ok)
+
+	tool := NewViewTool(csync.NewMap[string, *lsp.Client](), nil, workingDir)
+	resp, err := tool.Run(ctx, fantasy.ToolCall{ID: "call", Name: ViewToolName, Input: `{"file_path": "main.go", "limit": 10}`})
+	require.NoError(t, err)
+	require.Contains(t, resp.Content, "package other")
+
+	// Nothing is read ahead outside of the working directory.
+	p.Start("outside", ViewToolName)
+	p.Input("outside", `{"file_path": "`+filepath.Join(t.TempDir(), "secret")+`"}`)
+	_, ok = p.work(ctx, "outside", filepath.Join(workingDir, "secret"))
+	require.False(t, ok)
+}
+
+func TestPrefetchGrep(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "a.go"), []byte("func parse() {}\n"), 0o644))
+
+	p := NewPrefetcher(workingDir)
+	ctx := context.WithValue(t.Context(), PrefetcherContextKey, p)
+	p.Start("call", GrepToolName)
+	p.Input("call", `{"pattern": "parse"`)
+
+	matches, _, ok := prefetchedMatches(ctx, "call", GrepParams{Pattern: "parse"})
+	require.True(t, ok)
+	require.Len(t, matches, 1)
+	_, _, ok = prefetchedMatches(ctx, "call", GrepParams{Pattern: "parse", Include: "*.go"})
+	require.False(t, ok)
+
+	// Another tool may have changed the files.
+	p.Done("other", "bash")
+	_, _, ok = prefetchedMatches(ctx, "call", GrepParams{Pattern: "parse"})
+	require.False(t, ok)
+
+	p.Done("call", GrepToolName)
+	_, _, ok = prefetchedMatches(ctx, "call", GrepParams{Pattern: "parse"})
+	require.False(t, ok)
+}
@@ -0,0 +1,339 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+type SearchParams struct {
+	Query   string   `json:"query" description:"The text to search for"`
+	Sources []string `json:"sources,omitempty" description:"The names of the sources to search. Defaults to all of them."`
+	Limit   int      `json:"limit,omitempty" description:"The maximum number of results to return. Defaults to 30."`
+}
+
+type SearchResponseMetadata struct {
+	NumberOfHits int               `json:"number_of_hits"`
+	Sources      []string          `json:"sources"`
+	Failed       map[string]string `json:"failed,omitempty"`
+}
+
+// SearchHit is a result of a search source.
+type SearchHit struct {
+	// Location is where the hit is, a path with a line number or a URL. It
+	// may be empty.
+	Location string
+	Text     string
+}
+
+// SearchSource is a source of results for the search tool.
+type SearchSource struct {
+	// Name labels the results of the source.
+	Name string
+	// Search returns the hits of the query, best first. The call is the one
+	// of the search tool, to ask for permissions on its behalf.
+	Search func(ctx context.Context, call fantasy.ToolCall, query string) ([]SearchHit, error)
+}
+
+const (
+	SearchToolName = "search"
+
+	defaultSearchLimit = 30
+	searchTimeout      = 30 * time.Second
+	// rrfK damps the advantage of the first hits of each source when
+	// merging, as in reciprocal rank fusion.
+	rrfK = 60
+	// maxSearchHitWidth is how much of the text of each hit is shown.
+	maxSearchHitWidth = 500
+)
+
+//go:embed search.md
+var searchDescription []byte
+
+// NewSearchTool creates a tool searching all the sources at once.
+func NewSearchTool(sources []SearchSource) fantasy.AgentTool {
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.Name
+	}
+	description := string(searchDescription) + "\nAvailable sources: " + strings.Join(names, ", ")
+
+	return fantasy.NewAgentTool(
+		SearchToolName,
+		description,
+		func(ctx context.Context, params SearchParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			if strings.TrimSpace(params.Query) == "" {
+				return fantasy.NewTextErrorResponse("query is required"), nil
+			}
+			selected := sources
+			if len(params.Sources) > 0 {
+				selected = nil
+				for _, name := range params.Sources {
+					i := slices.Index(names, name)
+					if i < 0 {
+						return fantasy.NewTextErrorResponse(fmt.Sprintf("unknown source %q, available sources: %s", name, strings.Join(names, ", "))), nil
+					}
+					selected = append(selected, sources[i])
+				}
+			}
+			limit := params.Limit
+			if limit <= 0 {
+				limit = defaultSearchLimit
+			}
+
+			hits, failed := searchAll(ctx, call, selected, params.Query)
+			if err := ctx.Err(); err != nil {
+				return fantasy.ToolResponse{}, err
+			}
+			ranked := rankHits(hits, params.Query)
+			metadata := SearchResponseMetadata{
+				NumberOfHits: len(ranked),
+				Failed:       failed,
+			}
+			for _, source := range selected {
+				metadata.Sources = append(metadata.Sources, source.Name)
+			}
+			response := fantasy.NewTextResponse(formatHits(ranked[:min(len(ranked), limit)], len(ranked), params.Query, failed))
+			if len(failed) == len(selected) {
+				response.IsError = true
+			}
+			return fantasy.WithResponseMetadata(response, metadata), nil
+		})
+}
+
+// sourceHits are the hits of a source.
+type sourceHits struct {
+	source string
+	hits   []SearchHit
+}
+
+// searchAll queries the sources concurrently, returning the hits of those
+// that answered and the errors of the others.
+func searchAll(ctx context.Context, call fantasy.ToolCall, sources []SearchSource, query string) ([]sourceHits, map[string]string) {
+	results := make([]sourceHits, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, searchTimeout)
+			defer cancel()
+			hits, err := source.Search(ctx, call, query)
+			results[i] = sourceHits{source: source.Name, hits: hits}
+			errs[i] = err
+		})
+	}
+	wg.Wait()
+
+	var failed map[string]string
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if failed == nil {
+			failed = make(map[string]string)
+		}
+		failed[sources[i].Name] = err.Error()
+	}
+	return results, failed
+}
+
+// rankedHit is a hit merged from the sources that found it.
+type rankedHit struct {
+	SearchHit
+	sources []string
+	score   float64
+}
+
+// rankHits merges the hits of the sources. Each source contributes to the
+// score of a hit according to its rank there, so sources are interleaved
+// and hits found by several of them come first. Hits matching more words
+// of the query get a boost.
+func rankHits(results []sourceHits, query string) []rankedHit {
+	words := strings.Fields(strings.ToLower(query))
+	var ranked []rankedHit
+	byLocation := make(map[string]int)
+	for _, result := range results {
+		for rank, hit := range result.hits {
+			score := 1 / float64(rrfK+rank+1)
+			if hit.Location != "" {
+				if i, ok := byLocation[hit.Location]; ok {
+					ranked[i].score += score
+					if !slices.Contains(ranked[i].sources, result.source) {
+						ranked[i].sources = append(ranked[i].sources, result.source)
+					}
+					continue
+				}
+				byLocation[hit.Location] = len(ranked)
+			}
+			ranked = append(ranked, rankedHit{SearchHit: hit, sources: []string{result.source}, score: score})
+		}
+	}
+
+	for i, hit := range ranked {
+		text := strings.ToLower(hit.Location + " " + hit.Text)
+		matched := 0
+		for _, word := range words {
+			if strings.Contains(text, word) {
+				matched++
+			}
+		}
+		if len(words) > 0 {
+			ranked[i].score *= 1 + float64(matched)/float64(len(words))
+		}
+	}
+	slices.SortStableFunc(ranked, func(a, b rankedHit) int {
+		return cmp.Compare(b.score, a.score)
+	})
+	return ranked
+}
+
+func formatHits(hits []rankedHit, total int, query string, failed map[string]string) string {
+	var sb strings.Builder
+	if total == 0 {
+		fmt.Fprintf(&sb, "No results found for %q", query)
+	} else {
+		fmt.Fprintf(&sb, "Found %d results for %q", total, query)
+		if len(hits) < total {
+			fmt.Fprintf(&sb, ", showing the first %d", len(hits))
+		}
+	}
+	sb.WriteString("\n")
+	for i, hit := range hits {
+		fmt.Fprintf(&sb, "\n%d. [%s]", i+1, strings.Join(hit.sources, ", "))
+		if hit.Location != "" {
+			fmt.Fprintf(&sb, " %s", hit.Location)
+		}
+		text := strings.TrimSpace(hit.Text)
+		if len(text) > maxSearchHitWidth {
+			text = strings.ToValidUTF8(text[:maxSearchHitWidth], "") + "..."
+		}
+		if text != "" {
+			sb.WriteString("\n   " + strings.ReplaceAll(text, "\n", "\n   "))
+		}
+		sb.WriteString("\n")
+	}
+	if len(failed) > 0 {
+		sb.WriteString("\nSources that failed:\n")
+		for _, name := range slices.Sorted(maps.Keys(failed)) {
+			fmt.Fprintf(&sb, "- %s: %s\n", name, failed[name])
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// NewWorkspaceSearchSource searches the files of the working directory for
+// the query as literal text.
+func NewWorkspaceSearchSource(workingDir string) SearchSource {
+	return SearchSource{
+		Name: "workspace",
+		Search: func(ctx context.Context, _ fantasy.ToolCall, query string) ([]SearchHit, error) {
+			matches, _, err := searchFiles(ctx, escapeRegexPattern(query), workingDir, "", 100)
+			if err != nil {
+				return nil, err
+			}
+			hits := make([]SearchHit, 0, len(matches))
+			for _, match := range matches {
+				location := match.path
+				if rel, err := filepath.Rel(workingDir, match.path); err == nil {
+					location = filepath.ToSlash(rel)
+				}
+				if match.lineNum > 0 {
+					location = fmt.Sprintf("%s:%d", location, match.lineNum)
+				}
+				hits = append(hits, SearchHit{Location: location, Text: match.lineText})
+			}
+			return hits, nil
+		},
+	}
+}
+
+// NewMCPSearchSource makes a source of the MCP tool if it's a search tool:
+// its name mentions search and it only needs a query.
+func NewMCPSearchSource(tool *McpTool) (SearchSource, bool) {
+	if !isSearchTool(tool.Info()) {
+		return SearchSource{}, false
+	}
+	return SearchSource{
+		Name: tool.MCP() + "/" + tool.MCPToolName(),
+		Search: func(ctx context.Context, call fantasy.ToolCall, query string) ([]SearchHit, error) {
+			input, err := json.Marshal(map[string]string{"query": query})
+			if err != nil {
+				return nil, err
+			}
+			call.Input = string(input)
+			resp, err := tool.Run(ctx, call)
+			if err != nil {
+				return nil, err
+			}
+			if resp.IsError {
+				return nil, errors.New(resp.Content)
+			}
+			return splitHits(resp.Content), nil
+		},
+	}, true
+}
+
+func isSearchTool(info fantasy.ToolInfo) bool {
+	if !strings.Contains(strings.ToLower(info.Name), "search") {
+		return false
+	}
+	query, ok := info.Parameters["query"].(map[string]any)
+	if !ok || query["type"] != "string" {
+		return false
+	}
+	for _, required := range info.Required {
+		if required != "query" {
+			return false
+		}
+	}
+	return true
+}
+
+// locationKeys are the fields of JSON results telling where they are.
+var locationKeys = []string{"url", "uri", "link", "path", "file", "location"}
+
+// splitHits splits the output of a search tool into hits: the items of a
+// JSON array, or the paragraphs, or lines, of text.
+func splitHits(content string) []SearchHit {
+	var items []json.RawMessage
+	if err := json.Unmarshal([]byte(content), &items); err == nil {
+		hits := make([]SearchHit, 0, len(items))
+		for _, item := range items {
+			hit := SearchHit{Text: string(item)}
+			var fields map[string]any
+			if json.Unmarshal(item, &fields) == nil {
+				for _, key := range locationKeys {
+					if location, ok := fields[key].(string); ok && location != "" {
+						hit.Location = location
+						break
+					}
+				}
+			}
+			hits = append(hits, hit)
+		}
+		return hits
+	}
+
+	var hits []SearchHit
+	blocks := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n")
+	if len(blocks) == 1 {
+		blocks = strings.Split(blocks[0], "\n")
+	}
+	for _, block := range blocks {
+		if block = strings.TrimSpace(block); block != "" {
+			hits = append(hits, SearchHit{Text: block})
+		}
+	}
+	return hits
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchTool(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "auth.go"), []byte("package auth\n\n// login checks the password\nfunc login() {}\n"), 0o644))
+
+	docs := SearchSource{
+		Name: "docs/search",
+		Search: func(_ context.Context, call fantasy.ToolCall, query string) ([]SearchHit, error) {
+			require.Equal(t, "call-1", call.ID)
+			return []SearchHit{
+				{Location: "https://docs.example.com/sso", Text: "Single sign-on"},
+				{Location: "auth.go:3", Text: "// login checks the password"},
+				{Location: "https://docs.example.com/login", Text: "How login works"},
+			}, nil
+		},
+	}
+	broken := SearchSource{
+		Name: "issues/search",
+		Search: func(context.Context, fantasy.ToolCall, string) ([]SearchHit, error) {
+			return nil, errors.New("unauthorized")
+		},
+	}
+	tool := NewSearchTool([]SearchSource{NewWorkspaceSearchSource(dir), docs, broken})
+	require.Contains(t, tool.Info().Description, "Available sources: workspace, docs/search, issues/search")
+
+	run := func(t *testing.T, params SearchParams) fantasy.ToolResponse {
+		input, err := json.Marshal(params)
+		require.NoError(t, err)
+		resp, err := tool.Run(t.Context(), fantasy.ToolCall{ID: "call-1", Name: SearchToolName, Input: string(input)})
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := run(t, SearchParams{Query: "login"})
+	require.False(t, resp.IsError)
+	require.Equal(t, `Found 3 results for "login"
+
+1. [workspace, docs/search] auth.go:3
+   // login checks the password
+
+2. [docs/search] https://docs.example.com/login
+   How login works
+
+3. [docs/search] https://docs.example.com/sso
+   Single sign-on
+
+Sources that failed:
+- issues/search: unauthorized`, resp.Content)
+	var metadata SearchResponseMetadata
+	require.NoError(t, json.Unmarshal([]byte(resp.Metadata), &metadata))
+	require.Equal(t, 3, metadata.NumberOfHits)
+	require.Equal(t, map[string]string{"issues/search": "unauthorized"}, metadata.Failed)
+
+	resp = run(t, SearchParams{Query: "login", Sources: []string{"workspace"}, Limit: 1})
+	require.Equal(t, "Found 1 results for \"login\"\n\n1. [workspace] auth.go:3\n   // login checks the password", resp.Content)
+
+	resp = run(t, SearchParams{Query: "login", Sources: []string{"issues/search"}})
+	require.True(t, resp.IsError)
+
+	resp = run(t, SearchParams{Query: "login", Sources: []string{"wiki"}})
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, `unknown source "wiki"`)
+}
+
+func TestIsSearchTool(t *testing.T) {
+	t.Parallel()
+
+	query := map[string]any{"query": map[string]any{"type": "string"}}
+	require.True(t, isSearchTool(fantasy.ToolInfo{Name: "mcp_docs_search_docs", Parameters: query, Required: []string{"query"}}))
+	require.False(t, isSearchTool(fantasy.ToolInfo{Name: "mcp_docs_fetch", Parameters: query}))
+	require.False(t, isSearchTool(fantasy.ToolInfo{Name: "mcp_docs_search", Parameters: map[string]any{"q": map[string]any{"type": "string"}}}))
+	require.False(t, isSearchTool(fantasy.ToolInfo{Name: "mcp_docs_search", Parameters: query, Required: []string{"query", "repo"}}))
+}
+
+func TestSplitHits(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []SearchHit{
+		{Location: "https://a.example.com", Text: `{"title":"A","url":"https://a.example.com"}`},
+		{Text: `{"title":"B"}`},
+	}, splitHits(`[{"title":"A","url":"https://a.example.com"},{"title":"B"}]`))
+	require.Equal(t, []SearchHit{{Text: "first\nresult"}, {Text: "second"}}, splitHits("first\nresult\n\n\nsecond\n"))
+	require.Equal(t, []SearchHit{{Text: "a"}, {Text: "b"}}, splitHits("a\nb"))
+}
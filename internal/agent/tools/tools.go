@@ -2,18 +2,38 @@ package tools
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"charm.land/fantasy"
 )
 
 type (
-	sessionIDContextKey string
-	messageIDContextKey string
+	sessionIDContextKey  string
+	messageIDContextKey  string
+	dryRunContextKey     string
+	sessionEnvContextKey string
 )
 
 const (
-	SessionIDContextKey sessionIDContextKey = "session_id"
-	MessageIDContextKey messageIDContextKey = "message_id"
+	SessionIDContextKey  sessionIDContextKey  = "session_id"
+	MessageIDContextKey  messageIDContextKey  = "message_id"
+	DryRunContextKey     dryRunContextKey     = "dry_run"
+	SessionEnvContextKey sessionEnvContextKey = "session_env"
 )
 
+// SessionEnv is the working directory and environment variables a session
+// overrides for the commands it runs.
+type SessionEnv struct {
+	WorkingDir string
+	Env        []string
+}
+
+func (e SessionEnv) equal(other SessionEnv) bool {
+	return e.WorkingDir == other.WorkingDir && slices.Equal(e.Env, other.Env)
+}
+
 func GetSessionFromContext(ctx context.Context) string {
 	sessionID := ctx.Value(SessionIDContextKey)
 	if sessionID == nil {
@@ -37,3 +57,29 @@ func GetMessageFromContext(ctx context.Context) string {
 	}
 	return s
 }
+
+// GetSessionEnvFromContext returns the environment overrides of the session,
+// if it has any.
+func GetSessionEnvFromContext(ctx context.Context) (SessionEnv, bool) {
+	env, ok := ctx.Value(SessionEnvContextKey).(SessionEnv)
+	return env, ok
+}
+
+// IsDryRunFromContext reports whether mutation tools should describe what
+// they would do instead of doing it.
+func IsDryRunFromContext(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(DryRunContextKey).(bool)
+	return dryRun
+}
+
+// NewDryRunResponse returns the response of a mutation tool that was not
+// executed because the session is in dry-run mode.
+func NewDryRunResponse(action string) fantasy.ToolResponse {
+	return fantasy.NewTextResponse(fmt.Sprintf("<dry_run>\nDry run, no changes were made.\nWould: %s\n</dry_run>", action))
+}
+
+// IsDryRunResponse reports whether the content of a tool result is the one
+// of a NewDryRunResponse, where nothing was done.
+func IsDryRunResponse(content string) bool {
+	return strings.HasPrefix(content, "<dry_run>\n")
+}
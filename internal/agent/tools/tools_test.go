@@ -0,0 +1,373 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"encoding/json"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/fact"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	ctx := context.WithValue(t.Context(), SessionIDContextKey, "session")
+	ctx = context.WithValue(ctx, DryRunContextKey, true)
+	require.True(t, IsDryRunFromContext(ctx))
+	require.False(t, IsDryRunFromContext(t.Context()))
+
+	t.Run("write", func(t *testing.T) {
+		t.Parallel()
+
+		// Permissions and history are never used in dry-run mode.
+		tool := NewWriteTool(csync.NewMap[string, *lsp.Client](), nil, nil, workingDir)
+		resp, err := tool.Run(ctx, fantasy.ToolCall{
+			ID:    "call",
+			Name:  WriteToolName,
+			Input: `{"file_path": "nested/new.txt", "content": "hello"}`,
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError)
+		require.Contains(t, resp.Content, "Dry run")
+		require.Contains(t, resp.Metadata, "hello")
+
+		_, err = os.Stat(filepath.Join(workingDir, "nested"))
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("bash", func(t *testing.T) {
+		t.Parallel()
+
+		tool := NewBashTool(nil, workingDir, &config.Attribution{}, nil)
+		resp, err := tool.Run(ctx, fantasy.ToolCall{
+			ID:    "call",
+			Name:  BashToolName,
+			Input: `{"command": "touch created.txt"}`,
+		})
+		require.NoError(t, err)
+		require.Contains(t, resp.Content, "Execute command: touch created.txt")
+
+		_, err = os.Stat(filepath.Join(workingDir, "created.txt"))
+		require.True(t, os.IsNotExist(err))
+	})
+}
+
+func TestBashSessionEnv(t *testing.T) {
+	t.Parallel()
+
+	sessionDir := t.TempDir()
+	tool := NewBashTool(nil, t.TempDir(), &config.Attribution{}, nil)
+	ctx := context.WithValue(t.Context(), SessionIDContextKey, "session-env")
+	ctx = context.WithValue(ctx, SessionEnvContextKey, SessionEnv{
+		WorkingDir: sessionDir,
+		Env:        []string{"CRUSH_SESSION_VAR=from-session"},
+	})
+
+	resp, err := tool.Run(ctx, fantasy.ToolCall{
+		ID:    "call",
+		Name:  BashToolName,
+		Input: `{"command": "echo $CRUSH_SESSION_VAR"}`,
+	})
+	require.NoError(t, err)
+	require.Contains(t, resp.Content, "from-session")
+	require.Contains(t, resp.Metadata, sessionDir)
+}
+
+func TestMultiFileEdit(t *testing.T) {
+	t.Parallel()
+
+	workingDir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.go":     "package a\n\nfunc OldName() {}\n",
+		"b.go":     "package b\n\nvar _ = a.OldName()\nvar _ = a.OldName\n",
+		"c.go":     "package c\n",
+		"notes.md": "OldName\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(workingDir, name), []byte(content), 0o644))
+	}
+
+	ctx := context.WithValue(t.Context(), SessionIDContextKey, "session")
+	ctx = context.WithValue(ctx, DryRunContextKey, true)
+	// Permissions and history are never used in dry-run mode or when the
+	// edit is rejected.
+	tool := NewMultiFileEditTool(csync.NewMap[string, *lsp.Client](), nil, nil, workingDir)
+
+	t.Run("pattern", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := tool.Run(ctx, fantasy.ToolCall{
+			ID:    "call",
+			Name:  MultiFileEditToolName,
+			Input: `{"pattern": "*.go", "edits": [{"old_string": "OldName", "new_string": "NewName"}]}`,
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError, resp.Content)
+		require.Contains(t, resp.Content, "Apply 1 edits to 2 files")
+
+		var meta MultiFileEditResponseMetadata
+		require.NoError(t, json.Unmarshal([]byte(resp.Metadata), &meta))
+		require.Equal(t, 3, meta.Replacements)
+		require.Len(t, meta.Files, 3)
+		require.Equal(t, "no matches", meta.Files[2].Skipped)
+		require.Equal(t, filepath.Join(workingDir, "c.go"), meta.Files[2].FilePath)
+
+		content, err := os.ReadFile(filepath.Join(workingDir, "a.go"))
+		require.NoError(t, err)
+		require.Contains(t, string(content), "OldName")
+	})
+
+	t.Run("regex", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := tool.Run(ctx, fantasy.ToolCall{
+			ID:    "call",
+			Name:  MultiFileEditToolName,
+			Input: `{"files": ["b.go"], "edits": [{"old_string": "a\\.OldName\\(\\)", "new_string": "a.NewName()", "regex": true}]}`,
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError, resp.Content)
+
+		var meta MultiFileEditResponseMetadata
+		require.NoError(t, json.Unmarshal([]byte(resp.Metadata), &meta))
+		require.Equal(t, 1, meta.Replacements)
+	})
+
+	t.Run("missing file rejects all", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := tool.Run(ctx, fantasy.ToolCall{
+			ID:    "call",
+			Name:  MultiFileEditToolName,
+			Input: `{"files": ["a.go", "missing.go"], "edits": [{"old_string": "OldName", "new_string": "NewName"}]}`,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "file not found")
+	})
+
+	t.Run("invalid regex", func(t *testing.T) {
+		t.Parallel()
+
+		resp, err := tool.Run(ctx, fantasy.ToolCall{
+			ID:    "call",
+			Name:  MultiFileEditToolName,
+			Input: `{"files": ["a.go"], "edits": [{"old_string": "(", "new_string": "x", "regex": true}]}`,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "invalid regular expression")
+	})
+}
+
+func TestWriteFileChangesRestoresOnFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	written := filepath.Join(dir, "written.txt")
+	require.NoError(t, os.WriteFile(written, []byte("old"), 0o644))
+
+	err := writeFileChanges([]*fileChange{
+		{path: written, oldContent: "old", newContent: "new", mode: 0o644},
+		{path: filepath.Join(dir, "missing", "file.txt"), oldContent: "old", newContent: "new", mode: 0o644},
+	})
+	require.Error(t, err)
+
+	content, err := os.ReadFile(written)
+	require.NoError(t, err)
+	require.Equal(t, "old", string(content))
+}
+
+func TestArtifactTool(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	conn, err := db.Connect(t.Context(), dataDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	_, err = q.CreateSession(t.Context(), db.CreateSessionParams{ID: "session", Title: "Test"})
+	require.NoError(t, err)
+
+	workingDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "plot.png"), []byte("png"), 0o644))
+
+	artifacts := artifact.NewService(q, dataDir)
+	permissions := permission.NewPermissionService(workingDir, false, nil)
+	requests := permissions.Subscribe(t.Context())
+	go func() {
+		for event := range requests {
+			permissions.Deny(event.Payload)
+		}
+	}()
+	tool := NewArtifactTool(permissions, artifacts, workingDir)
+	ctx := context.WithValue(t.Context(), SessionIDContextKey, "session")
+
+	resp, err := tool.Run(ctx, fantasy.ToolCall{
+		ID:    "call",
+		Name:  ArtifactToolName,
+		Input: `{"file_path": "plot.png", "description": "Latency plot"}`,
+	})
+	require.NoError(t, err)
+	require.False(t, resp.IsError, resp.Content)
+
+	var meta ArtifactResponseMetadata
+	require.NoError(t, json.Unmarshal([]byte(resp.Metadata), &meta))
+	require.Equal(t, "plot.png", meta.Name)
+	require.Equal(t, artifact.KindImage, meta.Kind)
+
+	saved, err := artifacts.ListBySession(t.Context(), "session")
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	require.Equal(t, "Latency plot", saved[0].Description)
+
+	resp, err = tool.Run(ctx, fantasy.ToolCall{
+		ID:    "call",
+		Name:  ArtifactToolName,
+		Input: `{"file_path": "missing.png"}`,
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError)
+	require.Contains(t, resp.Content, "file not found")
+
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("secret"), 0o644))
+	_, err = tool.Run(ctx, fantasy.ToolCall{
+		ID:    "call",
+		Name:  ArtifactToolName,
+		Input: `{"file_path": "` + filepath.ToSlash(outside) + `"}`,
+	})
+	require.ErrorIs(t, err, permission.ErrorPermissionDenied, "files outside the working directory need a permission")
+
+	resp, err = tool.Run(ctx, fantasy.ToolCall{
+		ID:    "call",
+		Name:  ArtifactToolName,
+		Input: `{"file_path": "plot.png", "kind": "video"}`,
+	})
+	require.NoError(t, err)
+	require.True(t, resp.IsError)
+}
+
+func TestFactsTool(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	_, err = q.CreateSession(t.Context(), db.CreateSessionParams{ID: "session", Title: "Test"})
+	require.NoError(t, err)
+
+	facts := fact.NewService(q)
+	tool := NewFactsTool(facts)
+	ctx := context.WithValue(t.Context(), SessionIDContextKey, "session")
+	run := func(input string) fantasy.ToolResponse {
+		resp, err := tool.Run(ctx, fantasy.ToolCall{ID: "call", Name: FactsToolName, Input: input})
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := run(`{"key": "db", "value": "Stay on SQLite", "category": "decision"}`)
+	require.False(t, resp.IsError, resp.Content)
+	saved, err := facts.List(t.Context(), "session")
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	require.Equal(t, fact.CategoryDecision, saved[0].Category)
+
+	require.True(t, run(`{"key": "db", "value": "Postgres", "category": "opinion"}`).IsError)
+	require.True(t, run(`{"value": "Postgres"}`).IsError)
+
+	resp = run(`{"key": "db"}`)
+	require.False(t, resp.IsError, resp.Content)
+	saved, err = facts.List(t.Context(), "session")
+	require.NoError(t, err)
+	require.Empty(t, saved)
+	require.True(t, run(`{"key": "db"}`).IsError)
+}
+
+func TestViewOutline(t *testing.T) {
+	t.Parallel()
+
+	var sb strings.Builder
+	sb.WriteString("package big\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n")
+	for i := range 200 {
+		fmt.Fprintf(&sb, "\n// Func%d does things.\nfunc Func%d() {\n", i, i)
+		for range 20 {
+			sb.WriteString("\tfmt.Println(strings.Repeat(\"padding\", 2))\n")
+		}
+		sb.WriteString("}\n")
+	}
+	workingDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "big.go"), []byte(sb.String()), 0o644))
+
+	tool := NewViewTool(csync.NewMap[string, *lsp.Client](), nil, workingDir)
+	resp, err := tool.Run(t.Context(), fantasy.ToolCall{
+		ID:    "call",
+		Name:  ViewToolName,
+		Input: `{"file_path": "big.go"}`,
+	})
+	require.NoError(t, err)
+	require.False(t, resp.IsError, resp.Content)
+	require.Contains(t, resp.Content, "Showing its outline instead")
+	require.Contains(t, resp.Content, "     1|package big")
+	require.Contains(t, resp.Content, "     5|\t\"strings\"")
+	require.Contains(t, resp.Content, "     9|func Func0() {")
+	require.Contains(t, resp.Content, "func Func199() {")
+	require.NotContains(t, resp.Content, "padding")
+	require.Contains(t, resp.Content, "offset=8 limit=100")
+
+	var meta ViewResponseMetadata
+	require.NoError(t, json.Unmarshal([]byte(resp.Metadata), &meta))
+	require.True(t, meta.Outline)
+
+	// Reading a range returns the content as usual.
+	resp, err = tool.Run(t.Context(), fantasy.ToolCall{
+		ID:    "call",
+		Name:  ViewToolName,
+		Input: `{"file_path": "big.go", "offset": 8, "limit": 5}`,
+	})
+	require.NoError(t, err)
+	require.Contains(t, resp.Content, "     9|func Func0() {")
+	require.Contains(t, resp.Content, "padding")
+	require.NotContains(t, resp.Content, "outline")
+}
+
+func TestBuildOutline(t *testing.T) {
+	t.Parallel()
+
+	content := strings.Join([]string{
+		"from typing import List",
+		"import os",
+		"",
+		"class Server:",
+		"    def start(self):",
+		"        def nested():",
+		"            pass",
+		"",
+		"async def main():",
+		"    value = 1",
+	}, "\n")
+	imports, symbols := buildOutline(content, false)
+	require.Equal(t, []outlineLine{{1, "from typing import List"}, {2, "import os"}}, imports)
+	require.Equal(t, []outlineLine{
+		{4, "class Server:"},
+		{5, "    def start(self):"},
+		{9, "async def main():"},
+	}, symbols)
+
+	_, symbols = buildOutline("# Title\ntext\n## Usage\n```\n# comment\n```", true)
+	require.Equal(t, []outlineLine{{1, "# Title"}, {3, "## Usage"}}, symbols)
+}
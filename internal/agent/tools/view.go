@@ -2,6 +2,7 @@ package tools
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	_ "embed"
 	"fmt"
@@ -41,6 +42,9 @@ type viewTool struct {
 type ViewResponseMetadata struct {
 	FilePath string `json:"file_path"`
 	Content  string `json:"content"`
+	// Outline is set when the file was too large and its outline was
+	// returned instead of its content.
+	Outline bool `json:"outline,omitempty"`
 }
 
 const (
@@ -144,11 +148,6 @@ func NewViewTool(lspClients *csync.Map[string, *lsp.Client], permissions permiss
 					fileInfo.Size(), MaxReadSize)), nil
 			}
 
-			// Set default limit if not provided
-			if params.Limit <= 0 {
-				params.Limit = DefaultReadLimit
-			}
-
 			// Check if it's an image file
 			isImage, imageType := isImageFile(filePath)
 			// TODO: handle images
@@ -156,8 +155,32 @@ func NewViewTool(lspClients *csync.Map[string, *lsp.Client], permissions permiss
 				return fantasy.NewTextErrorResponse(fmt.Sprintf("This is an image file of type: %s\n", imageType)), nil
 			}
 
+			// Reading a whole file that would take a large part of the
+			// context returns its outline, so only the relevant ranges get
+			// read.
+			prefetched, ok := prefetchedContent(ctx, call.ID, params.FilePath, filePath, fileInfo)
+			if params.Offset == 0 && params.Limit <= 0 && estimateTokens(fileInfo.Size()) > OutlineTokenBudget {
+				if !ok {
+					if prefetched, err = os.ReadFile(filePath); err != nil {
+						return fantasy.ToolResponse{}, fmt.Errorf("error reading file: %w", err)
+					}
+				}
+				return viewOutline(ctx, lspClients, filePath, prefetched, fileInfo.Size())
+			}
+
+			// Set default limit if not provided
+			if params.Limit <= 0 {
+				params.Limit = DefaultReadLimit
+			}
+
 			// Read the file content
-			content, lineCount, err := readTextFile(filePath, params.Offset, params.Limit)
+			var content string
+			var lineCount int
+			if ok {
+				content, lineCount, err = readText(bytes.NewReader(prefetched), params.Offset, params.Limit)
+			} else {
+				content, lineCount, err = readTextFile(filePath, params.Offset, params.Limit)
+			}
 			isValidUt8 := utf8.ValidString(content)
 			if !isValidUt8 {
 				return fantasy.NewTextErrorResponse("File content is not valid UTF-8"), nil
@@ -189,6 +212,27 @@ func NewViewTool(lspClients *csync.Map[string, *lsp.Client], permissions permiss
 		})
 }
 
+func viewOutline(ctx context.Context, lspClients *csync.Map[string, *lsp.Client], filePath string, data []byte, size int64) (fantasy.ToolResponse, error) {
+	content := string(data)
+	if !utf8.ValidString(content) {
+		return fantasy.NewTextErrorResponse("File content is not valid UTF-8"), nil
+	}
+
+	notifyLSPs(ctx, lspClients, filePath)
+	outline := formatOutline(filePath, content, strings.Count(content, "\n")+1, size)
+	output := "<file>\n" + outline + "\n</file>\n"
+	output += getDiagnostics(filePath, lspClients)
+	recordFileRead(filePath)
+	return fantasy.WithResponseMetadata(
+		fantasy.NewTextResponse(output),
+		ViewResponseMetadata{
+			FilePath: filePath,
+			Content:  outline,
+			Outline:  true,
+		},
+	), nil
+}
+
 func addLineNumbers(content string, startLine int) string {
 	if content == "" {
 		return ""
@@ -220,7 +264,11 @@ func readTextFile(filePath string, offset, limit int) (string, int, error) {
 		return "", 0, err
 	}
 	defer file.Close()
+	return readText(file, offset, limit)
+}
 
+func readText(file io.ReadSeeker, offset, limit int) (string, int, error) {
+	var err error
 	lineCount := 0
 
 	scanner := NewLineScanner(file)
@@ -88,11 +88,6 @@ func NewWriteTool(lspClients *csync.Map[string, *lsp.Client], permissions permis
 				return fantasy.ToolResponse{}, fmt.Errorf("error checking file: %w", err)
 			}
 
-			dir := filepath.Dir(filePath)
-			if err = os.MkdirAll(dir, 0o755); err != nil {
-				return fantasy.ToolResponse{}, fmt.Errorf("error creating directory: %w", err)
-			}
-
 			oldContent := ""
 			if fileInfo != nil && !fileInfo.IsDir() {
 				oldBytes, readErr := os.ReadFile(filePath)
@@ -112,6 +107,17 @@ func NewWriteTool(lspClients *csync.Map[string, *lsp.Client], permissions permis
 				strings.TrimPrefix(filePath, workingDir),
 			)
 
+			if IsDryRunFromContext(ctx) {
+				return fantasy.WithResponseMetadata(
+					NewDryRunResponse(fmt.Sprintf("Write %d bytes to file %s", len(params.Content), filePath)),
+					WriteResponseMetadata{
+						Diff:      diff,
+						Additions: additions,
+						Removals:  removals,
+					},
+				), nil
+			}
+
 			p := permissions.Request(
 				permission.CreatePermissionRequest{
 					SessionID:   sessionID,
@@ -131,6 +137,11 @@ func NewWriteTool(lspClients *csync.Map[string, *lsp.Client], permissions permis
 				return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
 			}
 
+			dir := filepath.Dir(filePath)
+			if err = os.MkdirAll(dir, 0o755); err != nil {
+				return fantasy.ToolResponse{}, fmt.Errorf("error creating directory: %w", err)
+			}
+
 			err = os.WriteFile(filePath, []byte(params.Content), 0o644)
 			if err != nil {
 				return fantasy.ToolResponse{}, fmt.Errorf("error writing file: %w", err)
@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/audit"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/fact"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/secrets"
+	"github.com/charmbracelet/crush/internal/terminal"
+	"github.com/charmbracelet/crush/internal/toolstats"
+)
+
+// ToolsetOptions are the configuration and services the tools run with.
+type ToolsetOptions struct {
+	Config      *config.Config
+	Permissions permission.Service
+	History     history.Service
+	Artifacts   artifact.Service
+	Facts       fact.Service
+	// ToolStats records the executions of the tools, nil to not record
+	// them.
+	ToolStats toolstats.Service
+	// Audit logs the executions of the tools, nil to not log them.
+	Audit audit.Service
+	// Secrets are given to the commands and redacted from the output of
+	// the tools, nil for none.
+	Secrets    *secrets.Vault
+	Terminal   terminal.Service
+	LSPClients *csync.Map[string, *lsp.Client]
+}
+
+// Toolset builds the tools of the agents, so the agents and the clients of
+// `crush mcp serve` get the same tools, filtered and wrapped the same way.
+type Toolset struct {
+	opts  ToolsetOptions
+	chaos *chaos
+}
+
+// NewToolset creates a toolset.
+func NewToolset(opts ToolsetOptions) *Toolset {
+	return &Toolset{opts: opts, chaos: newChaosFromEnv()}
+}
+
+// Tools returns the built-in and MCP tools the agent is allowed, along with
+// the extra tools it is allowed, sorted by name.
+func (t *Toolset) Tools(ctx context.Context, agent config.Agent, extra ...fantasy.AgentTool) []fantasy.AgentTool {
+	cfg := t.opts.Config
+	allTools := append(slices.Clone(extra),
+		tools.NewBashTool(t.opts.Permissions, cfg.WorkingDir(), cfg.Options.Attribution, t.opts.Terminal),
+		tools.NewDownloadTool(t.opts.Permissions, cfg.WorkingDir(), nil),
+		tools.NewEditTool(t.opts.LSPClients, t.opts.Permissions, t.opts.History, cfg.WorkingDir()),
+		tools.NewMultiEditTool(t.opts.LSPClients, t.opts.Permissions, t.opts.History, cfg.WorkingDir()),
+		tools.NewMultiFileEditTool(t.opts.LSPClients, t.opts.Permissions, t.opts.History, cfg.WorkingDir()),
+		tools.NewFetchTool(t.opts.Permissions, cfg.WorkingDir(), nil),
+		tools.NewGlobTool(cfg.WorkingDir()),
+		tools.NewGrepTool(cfg.WorkingDir()),
+		tools.NewLsTool(t.opts.Permissions, cfg.WorkingDir(), cfg.Tools.Ls),
+		tools.NewSourcegraphTool(nil),
+		tools.NewViewTool(t.opts.LSPClients, t.opts.Permissions, cfg.WorkingDir()),
+		tools.NewWriteTool(t.opts.LSPClients, t.opts.Permissions, t.opts.History, cfg.WorkingDir()),
+		tools.NewArtifactTool(t.opts.Permissions, t.opts.Artifacts, cfg.WorkingDir()),
+		tools.NewFactsTool(t.opts.Facts),
+	)
+
+	if len(cfg.LSP) > 0 {
+		allTools = append(allTools, tools.NewDiagnosticsTool(t.opts.LSPClients), tools.NewReferencesTool(t.opts.LSPClients))
+	}
+
+	var filteredTools []fantasy.AgentTool
+	for _, tool := range allTools {
+		if slices.Contains(agent.AllowedTools, tool.Info().Name) {
+			filteredTools = append(filteredTools, tool)
+		}
+	}
+
+	mcpTools := tools.GetMCPTools(context.Background(), t.opts.Permissions, cfg)
+
+	for _, mcpTool := range mcpTools {
+		if agent.AllowedMCP == nil {
+			// No MCP restrictions
+			filteredTools = append(filteredTools, mcpTool)
+		} else if len(agent.AllowedMCP) == 0 {
+			// no mcps allowed
+			break
+		}
+
+		for mcp, tools := range agent.AllowedMCP {
+			if mcp == mcpTool.MCP() {
+				if len(tools) == 0 {
+					filteredTools = append(filteredTools, mcpTool)
+				}
+				for _, t := range tools {
+					if t == mcpTool.MCPToolName() {
+						filteredTools = append(filteredTools, mcpTool)
+					}
+				}
+				break
+			}
+		}
+	}
+	if slices.Contains(agent.AllowedTools, tools.SearchToolName) {
+		// Searching all sources at once only helps with several of them.
+		var searchSources []tools.SearchSource
+		for _, tool := range filteredTools {
+			if mcpTool, ok := tool.(*tools.McpTool); ok {
+				if source, ok := tools.NewMCPSearchSource(mcpTool); ok {
+					searchSources = append(searchSources, source)
+				}
+			}
+		}
+		if len(searchSources) > 0 {
+			searchSources = append([]tools.SearchSource{tools.NewWorkspaceSearchSource(cfg.WorkingDir())}, searchSources...)
+			filteredTools = append(filteredTools, tools.NewSearchTool(searchSources))
+		}
+	}
+	slices.SortFunc(filteredTools, func(a, b fantasy.AgentTool) int {
+		return strings.Compare(a.Info().Name, b.Info().Name)
+	})
+	return chainTools(filteredTools, t.toolMiddleware())
+}
+
+// Middleware returns the middleware the runs wrap the tools with, the first
+// one being the outermost. The runs apply it outside of the output budget
+// and the cache of the run, so the audit log records the output the model
+// was given, the calls served from the cache included.
+func (t *Toolset) Middleware() []ToolMiddleware {
+	var middleware []ToolMiddleware
+	if t.opts.Audit != nil {
+		middleware = append(middleware, auditTools(t.opts.Audit))
+	}
+	return middleware
+}
+
+// toolMiddleware returns the middleware wrapping each tool, the first one
+// being the outermost: the chaos fails the calls before they reach the
+// tool, the secrets are redacted before the output is shortened to the
+// budget, and the stats measure the tool alone.
+func (t *Toolset) toolMiddleware() []ToolMiddleware {
+	var middleware []ToolMiddleware
+	if t.chaos != nil {
+		middleware = append(middleware, t.chaos.tools)
+	}
+	if t.opts.Secrets != nil {
+		middleware = append(middleware, redactTools(t.opts.Secrets))
+	}
+	if t.opts.ToolStats != nil {
+		middleware = append(middleware, measureTools(t.opts.ToolStats))
+	}
+	return middleware
+}
+
+// Serve returns the tools the agent is allowed for running them outside of
+// its runs, wrapped with the middleware of the runs and run in the
+// environment and read-only mode the runs set.
+func (t *Toolset) Serve(ctx context.Context, agent config.Agent) []fantasy.AgentTool {
+	middleware := append([]ToolMiddleware{t.runContext}, t.Middleware()...)
+	return chainTools(t.Tools(ctx, agent), middleware)
+}
+
+// runContext runs the calls with the secrets in their environment, and
+// describing the changes instead of applying them in read-only mode.
+func (t *Toolset) runContext(next ToolHandler) ToolHandler {
+	return func(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+		if t.opts.Config.Options.ReadOnly {
+			ctx = context.WithValue(ctx, tools.DryRunContextKey, true)
+		}
+		if env := t.opts.Secrets.Environ(); len(env) > 0 {
+			ctx = context.WithValue(ctx, tools.SessionEnvContextKey, tools.SessionEnv{Env: env})
+		}
+		return next(ctx, call)
+	}
+}
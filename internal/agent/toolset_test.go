@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolsetServe(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cfg, err := config.Init(dir, t.TempDir(), false)
+	require.NoError(t, err)
+	cfg.Options.DisabledTools = []string{tools.BashToolName}
+	cfg.Options.ReadOnly = true
+	cfg.SetupAgents()
+
+	toolset := NewToolset(ToolsetOptions{
+		Config:      cfg,
+		Permissions: permission.NewPermissionService(dir, true, nil),
+	})
+	served := toolset.Serve(t.Context(), cfg.Agents[config.AgentCoder])
+	var names []string
+	for _, tool := range served {
+		names = append(names, tool.Info().Name)
+	}
+	require.NotContains(t, names, tools.BashToolName, "disabled tools are left out")
+	require.True(t, slices.IsSorted(names))
+
+	i := slices.Index(names, tools.WriteToolName)
+	require.NotEqual(t, -1, i)
+	path := filepath.Join(dir, "notes.txt")
+	ctx := context.WithValue(t.Context(), tools.SessionIDContextKey, "session")
+	resp, err := served[i].Run(ctx, fantasy.ToolCall{
+		ID:    "call",
+		Name:  tools.WriteToolName,
+		Input: `{"file_path": "` + filepath.ToSlash(path) + `", "content": "hello"}`,
+	})
+	require.NoError(t, err)
+	require.False(t, resp.IsError, resp.Content)
+	require.Contains(t, resp.Content, "Write 5 bytes")
+	require.NoFileExists(t, path, "read-only mode describes the changes")
+}
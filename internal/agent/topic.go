@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/embedding"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+//go:embed templates/topic.md
+var topicPrompt []byte
+
+const (
+	// DefaultTopicThreshold is the similarity with each of the previous
+	// prompts under which a prompt starts another topic.
+	DefaultTopicThreshold = 0.3
+	// DefaultTopicMinMessages is the number of prompts of a session before
+	// changes of topic are detected.
+	DefaultTopicMinMessages = 4
+
+	// topicHistory is the number of previous prompts a new one is compared
+	// with.
+	topicHistory = 5
+	// briefSummaryTokens is about the size of the summary a new topic starts
+	// from.
+	briefSummaryTokens = 500
+)
+
+const briefSummaryPrompt = "Provide a brief summary of our conversation above, in a few short paragraphs: what was worked on, what was decided and what is left to do."
+
+// TopicDetection configures the detection of prompts starting another topic
+// than the one of their session.
+type TopicDetection struct {
+	// Embeddings compares the prompts with an embedding model when set, the
+	// small model is asked otherwise.
+	Embeddings  embedding.Model
+	Threshold   float64
+	MinMessages int
+}
+
+// topicDetection returns the topic detection of the configuration, nil when
+// it's disabled or its embedding model can't be used.
+func (c *coordinator) topicDetection(small config.SelectedModel) *TopicDetection {
+	opts := c.cfg.Options.TopicChange
+	detection := &TopicDetection{
+		Threshold:   cmp.Or(opts.Threshold, DefaultTopicThreshold),
+		MinMessages: cmp.Or(opts.MinMessages, DefaultTopicMinMessages),
+	}
+	switch opts.Detector {
+	case config.TopicDetectorModel:
+		return detection
+	case config.TopicDetectorEmbedding:
+		providerID := cmp.Or(opts.EmbeddingProvider, small.Provider)
+		providerCfg, ok := c.cfg.Providers.Get(providerID)
+		if !ok {
+			slog.Warn("Embedding provider of topic detection not configured, not detecting changes of topic", "provider", providerID)
+			return nil
+		}
+		model, err := embedding.EmbeddingModel(c.cfg.Resolver(), providerCfg, opts.EmbeddingModel)
+		if err != nil {
+			slog.Warn("Failed to get the embedding model of topic detection, not detecting changes of topic", "error", err)
+			return nil
+		}
+		detection.Embeddings = model
+		return detection
+	default:
+		return nil
+	}
+}
+
+func (a *sessionAgent) TopicChanged(ctx context.Context, sessionID, prompt string) (bool, error) {
+	if a.topics == nil || strings.TrimSpace(prompt) == "" {
+		return false, nil
+	}
+	currentSession, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get session: %w", err)
+	}
+	msgs, err := a.getSessionMessages(ctx, currentSession)
+	if err != nil {
+		return false, err
+	}
+	var previous []string
+	for _, msg := range msgs {
+		if msg.Role != message.User || msg.IsSummaryMessage {
+			continue
+		}
+		if text := strings.TrimSpace(msg.Content().Text); text != "" {
+			previous = append(previous, text)
+		}
+	}
+	if len(previous) < a.topics.MinMessages {
+		return false, nil
+	}
+	previous = previous[max(0, len(previous)-topicHistory):]
+
+	if a.topics.Embeddings != nil {
+		return embeddingsTopicChanged(ctx, a.topics.Embeddings, a.topics.Threshold, previous, prompt)
+	}
+	return a.modelTopicChanged(ctx, previous, prompt)
+}
+
+// embeddingsTopicChanged reports whether the prompt is less similar than
+// the threshold to each of the previous prompts.
+func embeddingsTopicChanged(ctx context.Context, model embedding.Model, threshold float64, previous []string, prompt string) (bool, error) {
+	result, err := model.Embed(ctx, append(slices.Clone(previous), prompt))
+	if err != nil {
+		return false, fmt.Errorf("failed to embed prompts: %w", err)
+	}
+	last := len(result.Embeddings) - 1
+	for _, e := range result.Embeddings[:last] {
+		if embedding.CosineSimilarity(e, result.Embeddings[last]) >= threshold {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// modelTopicChanged asks the small model whether the prompt starts another
+// topic than the previous ones.
+func (a *sessionAgent) modelTopicChanged(ctx context.Context, previous []string, prompt string) (bool, error) {
+	var maxOutput int64 = 10
+	if a.smallModel.CatwalkCfg.CanReason {
+		maxOutput = a.smallModel.CatwalkCfg.DefaultMaxTokens
+	}
+	agent := fantasy.NewAgent(a.smallModel.Model,
+		fantasy.WithSystemPrompt(string(topicPrompt)+"\n /no_think"),
+		fantasy.WithMaxOutputTokens(maxOutput),
+	)
+
+	var b strings.Builder
+	b.WriteString("Previous messages:\n")
+	for _, p := range previous {
+		fmt.Fprintf(&b, "\n<message>\n%s\n</message>\n", p)
+	}
+	fmt.Fprintf(&b, "\nNew message:\n\n<message>\n%s\n</message>\n\nDoes the new message start an unrelated topic?", prompt)
+	resp, err := agent.Generate(ctx, fantasy.AgentCall{Prompt: b.String()})
+	if err != nil {
+		return false, fmt.Errorf("failed to detect a change of topic: %w", err)
+	}
+	answer := resp.Response.Content.Text()
+	if idx := strings.Index(answer, "</think>"); idx > 0 {
+		answer = answer[idx+len("</think>"):]
+	}
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "yes"), nil
+}
+
+func (a *sessionAgent) StartNewTopic(ctx context.Context, sessionID string) (session.Session, error) {
+	currentSession, err := a.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to get session: %w", err)
+	}
+	msgs, err := a.getSessionMessages(ctx, currentSession)
+	if err != nil {
+		return session.Session{}, err
+	}
+	aiMsgs := buildHistory(msgs, true)
+	summary, err := a.summarizer(SummarySession).Summarize(ctx, SummaryRequest{
+		Kind:      SummarySession,
+		Messages:  aiMsgs,
+		Prompt:    briefSummaryPrompt,
+		MaxTokens: briefSummaryTokens,
+	})
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to summarize session: %w", err)
+	}
+
+	newSession, err := a.sessions.Create(ctx, "New Session")
+	if err != nil {
+		return session.Session{}, err
+	}
+	text := fmt.Sprintf("Summary of the previous session %q, the conversation goes on with another topic:\n\n%s", currentSession.Title, summary.Text)
+	summaryMessage, err := a.messages.Create(ctx, newSession.ID, message.CreateMessageParams{
+		Role: message.Assistant,
+		Parts: []message.ContentPart{
+			message.TextContent{Text: text},
+			message.Finish{Reason: message.FinishReasonEndTurn, Time: time.Now().Unix()},
+		},
+		Model:            a.largeModel.Model.Model(),
+		Provider:         a.largeModel.Model.Provider(),
+		IsSummaryMessage: true,
+	})
+	if err != nil {
+		return session.Session{}, err
+	}
+
+	a.updateSessionUsage(ctx, a.largeModel, &newSession, summary.Usage, a.openrouterCost(summary.ProviderMetadata))
+	newSession.SummaryMessageID = summaryMessage.ID
+	newSession.PromptTokens = 0
+	newSession.CompletionTokens = summary.Usage.OutputTokens
+	return a.sessions.Save(ctx, newSession)
+}
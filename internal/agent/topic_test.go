@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/embedding"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+// topicEmbeddings embeds texts to fixed vectors.
+type topicEmbeddings map[string][]float32
+
+func (topicEmbeddings) Provider() string { return "test" }
+func (topicEmbeddings) Model() string    { return "test" }
+
+func (e topicEmbeddings) Embed(_ context.Context, texts []string) (embedding.Result, error) {
+	result := embedding.Result{}
+	for _, text := range texts {
+		result.Embeddings = append(result.Embeddings, e[text])
+	}
+	return result, nil
+}
+
+func TestTopicChanged(t *testing.T) {
+	t.Parallel()
+
+	env := testEnv(t)
+	sess, err := env.sessions.Create(t.Context(), "Parser")
+	require.NoError(t, err)
+	for _, prompt := range []string{"fix the parser", "add a parser test"} {
+		_, err := env.messages.Create(t.Context(), sess.ID, message.CreateMessageParams{
+			Role:  message.User,
+			Parts: []message.ContentPart{message.TextContent{Text: prompt}},
+		})
+		require.NoError(t, err)
+	}
+
+	embeddings := topicEmbeddings{
+		"fix the parser":    {1, 0, 0},
+		"add a parser test": {0.9, 0.1, 0},
+		"parser docs":       {0.8, 0.2, 0.1},
+		"deploy to prod":    {0, 0, 1},
+	}
+	a := &sessionAgent{
+		sessions: env.sessions,
+		messages: env.messages,
+		topics:   &TopicDetection{Embeddings: embeddings, Threshold: DefaultTopicThreshold, MinMessages: 2},
+	}
+	changed, err := a.TopicChanged(t.Context(), sess.ID, "parser docs")
+	require.NoError(t, err)
+	require.False(t, changed)
+	changed, err = a.TopicChanged(t.Context(), sess.ID, "deploy to prod")
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	// Not before enough prompts were sent.
+	a.topics.MinMessages = 3
+	changed, err = a.TopicChanged(t.Context(), sess.ID, "deploy to prod")
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// Asking the small model.
+	model := &summaryModel{summary: "Yes."}
+	a.smallModel = Model{Model: model}
+	a.topics = &TopicDetection{MinMessages: 2}
+	changed, err = a.TopicChanged(t.Context(), sess.ID, "deploy to prod")
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Contains(t, model.prompts[len(model.prompts)-1], "<message>\nfix the parser\n</message>")
+	model.summary = "no"
+	changed, err = a.TopicChanged(t.Context(), sess.ID, "parser docs")
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// Without topic detection.
+	a.topics = nil
+	changed, err = a.TopicChanged(t.Context(), sess.ID, "deploy to prod")
+	require.NoError(t, err)
+	require.False(t, changed)
+}
+
+func TestStartNewTopic(t *testing.T) {
+	t.Parallel()
+
+	env := testEnv(t)
+	model := &summaryModel{summary: "Fixed the parser."}
+	agent := NewSessionAgent(SessionAgentOptions{
+		LargeModel: Model{Model: model},
+		SmallModel: Model{Model: model},
+		Sessions:   env.sessions,
+		Messages:   env.messages,
+	})
+	sess, err := env.sessions.Create(t.Context(), "Parser")
+	require.NoError(t, err)
+	_, err = env.messages.Create(t.Context(), sess.ID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "fix the parser"}},
+	})
+	require.NoError(t, err)
+
+	newSession, err := agent.StartNewTopic(t.Context(), sess.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, sess.ID, newSession.ID)
+	require.Equal(t, int64(1), newSession.MessageCount)
+	require.Contains(t, model.prompts, briefSummaryPrompt)
+
+	summary, err := env.messages.Get(t.Context(), newSession.SummaryMessageID)
+	require.NoError(t, err)
+	require.True(t, summary.IsSummaryMessage)
+	require.Equal(t, newSession.ID, summary.SessionID)
+	require.Equal(t, "Summary of the previous session \"Parser\", the conversation goes on with another topic:\n\nFixed the parser.", summary.Content().Text)
+
+	// The session it started from is left as it was.
+	sess, err = env.sessions.Get(t.Context(), sess.ID)
+	require.NoError(t, err)
+	require.Empty(t, sess.SummaryMessageID)
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// transports holds the HTTP transport of each provider, shared by all its
+// models so the large and small models and the sub-agents reuse the same
+// connections. The default transport keeps 2 idle connections per host,
+// which has steps running at once open new connections, each with its own
+// TLS handshake.
+type transports struct {
+	mu         sync.Mutex
+	byProvider map[string]*providerTransport
+}
+
+type providerTransport struct {
+	settings  config.ProviderHTTP
+	transport *http.Transport
+}
+
+func newTransports() *transports {
+	return &transports{byProvider: make(map[string]*providerTransport)}
+}
+
+// get returns the transport of the provider, replacing it when its settings
+// changed.
+func (t *transports) get(providerCfg config.ProviderConfig) *http.Transport {
+	var settings config.ProviderHTTP
+	if providerCfg.HTTP != nil {
+		settings = *providerCfg.HTTP
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if pt, ok := t.byProvider[providerCfg.ID]; ok {
+		if pt.settings == settings {
+			return pt.transport
+		}
+		pt.transport.CloseIdleConnections()
+	}
+	transport := newTransport(providerCfg.HTTP)
+	t.byProvider[providerCfg.ID] = &providerTransport{settings: settings, transport: transport}
+	return transport
+}
+
+// newTransport returns a transport with the settings, pinging idle HTTP/2
+// connections so the ones dropped along the way are noticed before a
+// request is sent on them.
+func newTransport(settings *config.ProviderHTTP) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = max(transport.MaxIdleConns, settings.MaxIdleConnections())
+	transport.MaxIdleConnsPerHost = settings.MaxIdleConnections()
+	transport.IdleConnTimeout = settings.IdleConnTimeout()
+	transport.ForceAttemptHTTP2 = true
+	transport.HTTP2 = &http.HTTP2Config{
+		SendPingTimeout: settings.HTTP2PingInterval(),
+		PingTimeout:     settings.HTTP2PingTimeout(),
+	}
+	return transport
+}
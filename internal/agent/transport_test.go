@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransports(t *testing.T) {
+	t.Parallel()
+
+	ts := newTransports()
+	openai := config.ProviderConfig{ID: "openai"}
+	transport := ts.get(openai)
+	require.Same(t, transport, ts.get(openai), "models of a provider share its transport")
+	require.NotSame(t, transport, ts.get(config.ProviderConfig{ID: "anthropic"}))
+
+	require.Equal(t, 16, transport.MaxIdleConnsPerHost)
+	require.Equal(t, 90*time.Second, transport.IdleConnTimeout)
+	require.Equal(t, 30*time.Second, transport.HTTP2.SendPingTimeout)
+	require.Equal(t, 15*time.Second, transport.HTTP2.PingTimeout)
+
+	// Changed settings get a new transport.
+	openai.HTTP = &config.ProviderHTTP{MaxIdleConns: 4, IdleTimeout: 300, PingInterval: 10, PingTimeout: 5}
+	tuned := ts.get(openai)
+	require.NotSame(t, transport, tuned)
+	require.Same(t, tuned, ts.get(openai))
+	require.Equal(t, 4, tuned.MaxIdleConnsPerHost)
+	require.Equal(t, 300*time.Second, tuned.IdleConnTimeout)
+	require.Equal(t, 10*time.Second, tuned.HTTP2.SendPingTimeout)
+	require.Equal(t, 5*time.Second, tuned.HTTP2.PingTimeout)
+}
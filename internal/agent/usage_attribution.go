@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os/exec"
+	"os/user"
+	"strings"
+	"time"
+
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/openai"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+)
+
+// attribution is what attributes the requests to a provider to the
+// developer sending them.
+type attribution struct {
+	body    map[string]any
+	headers map[string]string
+}
+
+// usageAttribution returns what attributes the requests to providers of the
+// type, nothing unless enabled in the options.
+func (c *coordinator) usageAttribution(providerType catwalk.Type) attribution {
+	opts := c.cfg.Options.UsageAttribution
+	if !opts.Enabled {
+		return attribution{}
+	}
+	userID := developerID(c.cfg.WorkingDir())
+	if opts.User != "" {
+		resolved, err := c.cfg.Resolve(opts.User)
+		if err != nil {
+			slog.Warn("Failed to resolve the user of usage attribution, using the git identity", "error", err)
+		} else {
+			userID = resolved
+		}
+	}
+	return attributionFor(providerType, userID, opts.Organization, opts.Project)
+}
+
+// attributionFor returns the fields OpenAI and Anthropic attribute the
+// requests with: the user and metadata of OpenAI requests, along with the
+// organization and project headers, and the user ID in the metadata of
+// Anthropic requests.
+func attributionFor(providerType catwalk.Type, userID, organization, project string) attribution {
+	switch providerType {
+	case openai.Name:
+		a := attribution{body: map[string]any{}, headers: map[string]string{}}
+		metadata := map[string]any{}
+		if userID != "" {
+			a.body["user"] = userID
+			metadata["user"] = userID
+		}
+		if organization != "" {
+			a.headers["OpenAI-Organization"] = organization
+			metadata["organization"] = organization
+		}
+		if project != "" {
+			a.headers["OpenAI-Project"] = project
+			metadata["project"] = project
+		}
+		if len(metadata) > 0 {
+			a.body["metadata"] = metadata
+		}
+		return a
+	case anthropic.Name:
+		if userID == "" {
+			return attribution{}
+		}
+		return attribution{body: map[string]any{
+			"metadata": map[string]any{"user_id": userID},
+		}}
+	default:
+		return attribution{}
+	}
+}
+
+// developerID identifies the developer without giving away who they are: a
+// hash of the git user.email of the project, or of the login name without
+// one.
+func developerID(workingDir string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", "config", "--get", "user.email")
+	cmd.Dir = workingDir
+	identity := ""
+	if out, err := cmd.Output(); err == nil {
+		identity = strings.ToLower(strings.TrimSpace(string(out)))
+	}
+	if identity == "" {
+		if u, err := user.Current(); err == nil {
+			identity = u.Username
+		}
+	}
+	if identity == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(sum[:])
+}
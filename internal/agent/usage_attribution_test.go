@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os/exec"
+	"testing"
+
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/google"
+	"charm.land/fantasy/providers/openai"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributionFor(t *testing.T) {
+	t.Parallel()
+
+	a := attributionFor(openai.Name, "dev", "org-1", "proj_1")
+	require.Equal(t, map[string]string{"OpenAI-Organization": "org-1", "OpenAI-Project": "proj_1"}, a.headers)
+	require.Equal(t, map[string]any{
+		"user":     "dev",
+		"metadata": map[string]any{"user": "dev", "organization": "org-1", "project": "proj_1"},
+	}, a.body)
+
+	a = attributionFor(anthropic.Name, "dev", "org-1", "proj_1")
+	require.Empty(t, a.headers)
+	require.Equal(t, map[string]any{"metadata": map[string]any{"user_id": "dev"}}, a.body)
+
+	require.Empty(t, attributionFor(anthropic.Name, "", "org-1", ""))
+	require.Empty(t, attributionFor(google.Name, "dev", "org-1", "proj_1"))
+}
+
+func TestDeveloperID(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"config", "user.email", "Dev@Example.com"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		require.NoError(t, cmd.Run())
+	}
+
+	sum := sha256.Sum256([]byte("dev@example.com"))
+	require.Equal(t, hex.EncodeToString(sum[:]), developerID(dir))
+}
@@ -3,27 +3,48 @@ package app
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"charm.land/fantasy"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/agent"
 	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/audit"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/draft"
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/fact"
 	"github.com/charmbracelet/crush/internal/format"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/health"
 	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/inflight"
+	"github.com/charmbracelet/crush/internal/instance"
 	"github.com/charmbracelet/crush/internal/log"
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/secrets"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/spending"
+	"github.com/charmbracelet/crush/internal/symbols"
+	"github.com/charmbracelet/crush/internal/terminal"
+	"github.com/charmbracelet/crush/internal/toolstats"
+	"github.com/charmbracelet/crush/internal/tui/actions"
+	"github.com/charmbracelet/crush/internal/tui/components/anim"
+	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/x/ansi"
 )
 
@@ -31,14 +52,45 @@ type App struct {
 	Sessions    session.Service
 	Messages    message.Service
 	History     history.Service
+	Artifacts   artifact.Service
+	Facts       fact.Service
 	Permissions permission.Service
+	ToolStats   toolstats.Service
+	Audit       audit.Service
+	Spending    spending.Service
+	Health      health.Service
+	Inflight    inflight.Service
+	Terminal    terminal.Service
+
+	// Secrets are given to the shell commands of the agent and redacted
+	// from what the model sees. It's nil if the vault couldn't be opened.
+	Secrets *secrets.Vault
+
+	// Drafts are the prompts written in the editor and not sent yet.
+	Drafts *draft.Store
+
+	// Bus carries the events of all the services, for the TUI and anyone
+	// embedding the app to observe.
+	Bus *pubsub.Bus
+
+	// ConcurrentWriters are the other crush processes that may be editing
+	// the project at the same time.
+	ConcurrentWriters []instance.Info
 
 	AgentCoordinator agent.Coordinator
 
 	LSPClients *csync.Map[string, *lsp.Client]
 
+	// Files is the cached list of the files of the project, for mentions.
+	Files *fsext.FileIndex
+	// Symbols indexes the functions and types declared in Files.
+	Symbols *symbols.Index
+
 	config *config.Config
 
+	// activeSessionID is the session shown in the TUI.
+	activeSessionID atomic.Value
+
 	serviceEventsWG *sync.WaitGroup
 	eventsCtx       context.Context
 	events          chan tea.Msg
@@ -53,7 +105,8 @@ type App struct {
 func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 	q := db.New(conn)
 	sessions := session.NewService(q)
-	messages := message.NewService(q)
+	retention := retentionPolicy(cfg.Options.Retention)
+	messages := message.NewService(q, conn, message.WithRetention(retention))
 	files := history.NewService(q, conn)
 	skipPermissionsRequests := cfg.Permissions != nil && cfg.Permissions.SkipRequests
 	allowedTools := []string{}
@@ -61,12 +114,37 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 		allowedTools = cfg.Permissions.AllowedTools
 	}
 
+	animation := cfg.Options.TUI.Animation
+	anim.SetProfile(anim.Profile{
+		FPS:           animation.FPS,
+		ReducedMotion: animation.ReducedMotion,
+		StaticColors:  animation.CycleColors != nil && !*animation.CycleColors,
+	})
+
+	styles.SetHyperlinks(cfg.Options.TUI.HyperlinksEnabled())
+
+	if err := actions.SetKeybindings(cfg.Options.TUI.Keybindings); err != nil {
+		slog.Warn("Some keybindings were ignored", "error", err)
+	}
+
+	depth, limit := cfg.Options.TUI.Completions.Limits()
 	app := &App{
 		Sessions:    sessions,
 		Messages:    messages,
 		History:     files,
+		Artifacts:   artifact.NewService(q, cfg.Options.DataDirectory),
+		Facts:       fact.NewService(q),
 		Permissions: permission.NewPermissionService(cfg.WorkingDir(), skipPermissionsRequests, allowedTools),
+		ToolStats:   toolstats.NewService(q),
+		Audit:       audit.NewService(q),
+		Spending:    spending.NewService(q),
+		Health:      health.NewDisabledService(),
+		Inflight:    inflight.NewService(),
+		Terminal:    terminal.NewService(),
+		Drafts:      draft.NewStore(cfg.Options.DataDirectory),
 		LSPClients:  csync.NewMap[string, *lsp.Client](),
+		Files:       fsext.NewFileIndex(cfg.WorkingDir(), depth, limit),
+		Bus:         pubsub.NewBus(),
 
 		globalCtx: ctx,
 
@@ -77,8 +155,35 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 		tuiWG:           &sync.WaitGroup{},
 	}
 
+	// Health checks are disabled offline, where status pages are out of reach.
+	if !cfg.Options.DisableHealthChecks {
+		app.Health = health.NewService(http.DefaultClient)
+	}
+
+	if vault, err := secrets.Open(cfg.Options.DataDirectory); err != nil {
+		slog.Warn("Failed to open secrets", "error", err)
+	} else {
+		app.Secrets = vault
+	}
+
+	// Coordinate with other crush processes running on the same project.
+	registration, err := instance.Register(cfg.Options.DataDirectory, cfg.Options.ReadOnly)
+	if err != nil {
+		slog.Warn("Failed to register instance", "error", err)
+	} else {
+		app.ConcurrentWriters = registration.Writers()
+		app.cleanupFuncs = append(app.cleanupFuncs, registration.Unregister)
+	}
+
+	app.Symbols = symbols.NewIndex(cfg.WorkingDir(), app.Files.Files)
+	app.cleanupFuncs = append(app.cleanupFuncs, app.Files.Close)
+
 	app.setupEvents()
 
+	if retention.Enabled() {
+		go app.enforceRetention(ctx)
+	}
+
 	// Initialize LSP clients in the background.
 	app.initLSPClients(ctx)
 
@@ -102,8 +207,8 @@ func (app *App) Config() *config.Config {
 }
 
 // RunNonInteractive handles the execution flow when a prompt is provided via
-// CLI flag.
-func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool) error {
+// CLI flag. The text of the responses is written to out as it streams.
+func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet, jsonOutput bool, out io.Writer) error {
 	slog.Info("Running in non-interactive mode")
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -156,6 +261,7 @@ func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool
 			done <- response{
 				err: fmt.Errorf("failed to start agent processing stream: %w", err),
 			}
+			return
 		}
 		done <- response{
 			result: result,
@@ -164,6 +270,7 @@ func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool
 
 	messageEvents := app.Messages.Subscribe(ctx)
 	messageReadBytes := make(map[string]int)
+	ctxDone := ctx.Done()
 
 	defer fmt.Printf(ansi.ResetProgressBar)
 	for {
@@ -173,18 +280,27 @@ func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool
 		select {
 		case result := <-done:
 			stopSpinner()
+			if jsonOutput {
+				if err := app.writeRunJSON(ctx, sess.ID, out); err != nil {
+					return err
+				}
+			}
 			if result.err != nil {
-				if errors.Is(result.err, context.Canceled) || errors.Is(result.err, agent.ErrRequestCancelled) {
-					slog.Info("Non-interactive: agent processing cancelled", "session_id", sess.ID)
+				if errors.Is(result.err, context.Canceled) || errors.Is(result.err, context.DeadlineExceeded) || errors.Is(result.err, agent.ErrRequestCancelled) {
+					slog.Info("Non-interactive: agent processing cancelled", "session_id", sess.ID, "cause", context.Cause(ctx))
 					return nil
 				}
 				return fmt.Errorf("agent processing failed: %w", result.err)
 			}
 			return nil
 
-		case event := <-messageEvents:
+		case event, ok := <-messageEvents:
+			if !ok {
+				messageEvents = nil
+				continue
+			}
 			msg := event.Payload
-			if msg.SessionID == sess.ID && msg.Role == message.Assistant && len(msg.Parts) > 0 {
+			if !jsonOutput && msg.SessionID == sess.ID && msg.Role == message.Assistant && len(msg.Parts) > 0 {
 				stopSpinner()
 
 				content := msg.Content().String()
@@ -196,31 +312,107 @@ func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool
 				}
 
 				part := content[readBytes:]
-				fmt.Print(part)
+				fmt.Fprint(out, part)
 				messageReadBytes[msg.ID] = len(content)
 			}
 
-		case <-ctx.Done():
+		case <-ctxDone:
+			if jsonOutput {
+				// Wait for the run to record how it ended.
+				ctxDone = nil
+				continue
+			}
 			stopSpinner()
 			return ctx.Err()
 		}
 	}
 }
 
+// writeRunJSON writes the session of a non-interactive run as JSON, telling
+// how each turn finished and why when it was cancelled.
+func (app *App) writeRunJSON(ctx context.Context, sessionID string, out io.Writer) error {
+	// The run may have been cancelled.
+	ctx = context.WithoutCancel(ctx)
+	sess, err := app.Sessions.Get(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	msgs, err := app.Messages.List(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(export.JSON(sess, msgs))
+}
+
 func (app *App) UpdateAgentModel(ctx context.Context) error {
 	return app.AgentCoordinator.UpdateModels(ctx)
 }
 
+// Topics the components of the app publish their events on.
+const (
+	TopicSessions                pubsub.Topic = "sessions"
+	TopicMessages                pubsub.Topic = "messages"
+	TopicPermissions             pubsub.Topic = "permissions"
+	TopicPermissionNotifications pubsub.Topic = "permissions-notifications"
+	TopicHistory                 pubsub.Topic = "history"
+	TopicArtifacts               pubsub.Topic = "artifacts"
+	TopicFacts                   pubsub.Topic = "facts"
+	TopicToolStats               pubsub.Topic = "tool-stats"
+	TopicAudit                   pubsub.Topic = "audit"
+	TopicSpending                pubsub.Topic = "spending"
+	TopicHealth                  pubsub.Topic = "health"
+	TopicInflight                pubsub.Topic = "inflight"
+	TopicTerminal                pubsub.Topic = "terminal"
+	TopicMCP                     pubsub.Topic = "mcp"
+	TopicLSP                     pubsub.Topic = "lsp"
+)
+
 func (app *App) setupEvents() {
 	ctx, cancel := context.WithCancel(app.globalCtx)
 	app.eventsCtx = ctx
-	setupSubscriber(ctx, app.serviceEventsWG, "sessions", app.Sessions.Subscribe, app.events)
-	setupSubscriber(ctx, app.serviceEventsWG, "messages", app.Messages.Subscribe, app.events)
-	setupSubscriber(ctx, app.serviceEventsWG, "permissions", app.Permissions.Subscribe, app.events)
-	setupSubscriber(ctx, app.serviceEventsWG, "permissions-notifications", app.Permissions.SubscribeNotifications, app.events)
-	setupSubscriber(ctx, app.serviceEventsWG, "history", app.History.Subscribe, app.events)
-	setupSubscriber(ctx, app.serviceEventsWG, "mcp", tools.SubscribeMCPEvents, app.events)
-	setupSubscriber(ctx, app.serviceEventsWG, "lsp", SubscribeLSPEvents, app.events)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicSessions, app.Sessions.Subscribe)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicMessages, app.Messages.Subscribe)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicPermissions, app.Permissions.Subscribe)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicPermissionNotifications, app.Permissions.SubscribeNotifications)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicHistory, app.History.Subscribe)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicArtifacts, app.Artifacts.Subscribe)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicFacts, app.Facts.Subscribe)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicToolStats, app.ToolStats.Subscribe)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicAudit, app.Audit.Subscribe)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicSpending, app.Spending.Subscribe)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicHealth, app.Health.Subscribe)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicInflight, app.Inflight.Subscribe)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicTerminal, app.Terminal.Subscribe)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicMCP, tools.SubscribeMCPEvents)
+	forward(ctx, app.serviceEventsWG, app.Bus, TopicLSP, SubscribeLSPEvents)
+
+	// The TUI receives every event as a tea.Msg.
+	tuiEvents := app.Bus.Subscribe(ctx)
+	app.serviceEventsWG.Go(func() {
+		for e := range tuiEvents {
+			select {
+			case app.events <- e.Event:
+			case <-time.After(2 * time.Second):
+				slog.Warn("message dropped due to slow consumer", "topic", e.Topic)
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+
+	// Log the lifecycle of resources, leaving out streamed message and
+	// command output updates.
+	logEvents := app.Bus.Subscribe(ctx, pubsub.Not(pubsub.Filter(func(e pubsub.Envelope) bool {
+		return (e.Topic == TopicMessages || e.Topic == TopicTerminal) && e.Type == pubsub.UpdatedEvent
+	})))
+	app.serviceEventsWG.Go(func() {
+		for e := range logEvents {
+			slog.Debug("Event", "topic", e.Topic, "type", e.Type)
+		}
+	})
+
 	cleanupFunc := func() error {
 		cancel()
 		app.serviceEventsWG.Wait()
@@ -229,36 +421,15 @@ func (app *App) setupEvents() {
 	app.cleanupFuncs = append(app.cleanupFuncs, cleanupFunc)
 }
 
-func setupSubscriber[T any](
+func forward[T any](
 	ctx context.Context,
 	wg *sync.WaitGroup,
-	name string,
-	subscriber func(context.Context) <-chan pubsub.Event[T],
-	outputCh chan<- tea.Msg,
+	bus *pubsub.Bus,
+	topic pubsub.Topic,
+	subscribe func(context.Context) <-chan pubsub.Event[T],
 ) {
 	wg.Go(func() {
-		subCh := subscriber(ctx)
-		for {
-			select {
-			case event, ok := <-subCh:
-				if !ok {
-					slog.Debug("subscription channel closed", "name", name)
-					return
-				}
-				var msg tea.Msg = event
-				select {
-				case outputCh <- msg:
-				case <-time.After(2 * time.Second):
-					slog.Warn("message dropped due to slow consumer", "name", name)
-				case <-ctx.Done():
-					slog.Debug("subscription cancelled", "name", name)
-					return
-				}
-			case <-ctx.Done():
-				slog.Debug("subscription cancelled", "name", name)
-				return
-			}
-		}
+		pubsub.Forward(ctx, bus, topic, subscribe)
 	})
 }
 
@@ -275,6 +446,15 @@ func (app *App) InitCoderAgent(ctx context.Context) error {
 		app.Messages,
 		app.Permissions,
 		app.History,
+		app.Artifacts,
+		app.Facts,
+		app.ToolStats,
+		app.Audit,
+		app.Spending,
+		app.Health,
+		app.Inflight,
+		app.Terminal,
+		app.Secrets,
 		app.LSPClients,
 	)
 	if err != nil {
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/control"
+)
+
+// ServeControl starts the control socket, so other processes can send
+// prompts to the TUI and query its state. The socket is closed on shutdown.
+func (app *App) ServeControl() error {
+	server, err := control.Listen(control.SocketPath(app.config.Options.DataDirectory, os.Getpid()), app)
+	if err != nil {
+		return err
+	}
+	app.cleanupFuncs = append(app.cleanupFuncs, server.Close)
+	return nil
+}
+
+// SetActiveSession records the session shown in the TUI, the one prompts sent
+// through the control socket go to.
+func (app *App) SetActiveSession(sessionID string) {
+	app.activeSessionID.Store(sessionID)
+}
+
+func (app *App) activeSession() string {
+	id, _ := app.activeSessionID.Load().(string)
+	return id
+}
+
+// Send implements control.Handler.
+func (app *App) Send(ctx context.Context, text string) error {
+	slog.Debug("Received prompt through the control socket")
+	select {
+	case app.events <- control.SendMsg{Text: text}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Status implements control.Handler.
+func (app *App) Status(ctx context.Context) (control.Status, error) {
+	status := control.Status{
+		PID:        os.Getpid(),
+		WorkingDir: app.config.WorkingDir(),
+	}
+	sessionID := app.activeSession()
+	if sessionID != "" {
+		sess, err := app.Sessions.Get(ctx, sessionID)
+		if err != nil {
+			return control.Status{}, err
+		}
+		status.SessionID = sess.ID
+		status.SessionTitle = sess.Title
+	}
+	if app.AgentCoordinator != nil {
+		if sessionID != "" {
+			status.Busy = app.AgentCoordinator.IsSessionBusy(sessionID)
+			status.QueuedPrompts = app.AgentCoordinator.QueuedPrompts(sessionID)
+		} else {
+			status.Busy = app.AgentCoordinator.IsBusy()
+		}
+		status.Model = app.AgentCoordinator.Model().CatwalkCfg.Name
+	}
+	return status, nil
+}
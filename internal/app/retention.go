@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// retentionInterval is how often the retention policy is enforced on the
+// stored messages.
+const retentionInterval = time.Hour
+
+func retentionPolicy(cfg config.Retention) message.RetentionPolicy {
+	return message.RetentionPolicy{
+		BodyMaxAge:      cfg.MessageBodyMaxAge(),
+		DropReasoning:   cfg.DropReasoning,
+		DropAttachments: cfg.DropAttachments,
+	}
+}
+
+// enforceRetention applies the retention policy to the stored messages, when
+// the app starts and then every retentionInterval until ctx is done.
+func (app *App) enforceRetention(ctx context.Context) {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+	for {
+		enforced, err := app.Messages.EnforceRetention(ctx)
+		if err != nil {
+			slog.Warn("Failed to enforce the retention policy", "error", err)
+		} else if enforced > 0 {
+			slog.Info("Enforced the retention policy", "messages", enforced)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Package archive moves sessions between projects or machines in a SQLite
+// database of the same schema as the one of crush, holding the sessions
+// with their messages, tool outputs, file history and facts, so nothing is
+// lost on the way.
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/db"
+)
+
+// ErrExists is returned when exporting to a file that already exists.
+var ErrExists = errors.New("archive already exists")
+
+// tables are the tables holding the rows of the sessions moved along with
+// them. The artifacts are left out, their files are kept outside of the
+// database, and so are the spending and sync state of the machine.
+var tables = []string{
+	"messages",
+	"files",
+	"session_facts",
+	"session_forks",
+	"session_merges",
+	"tool_executions",
+}
+
+// Result describes an export or an import.
+type Result struct {
+	Sessions int
+	Messages int
+	// Skipped is the number of sessions imported already.
+	Skipped int
+}
+
+// Export writes the sessions, along with the sessions spawned from them, to
+// a new archive at the path. All sessions are exported without IDs.
+func Export(ctx context.Context, conn *sql.DB, path string, sessionIDs []string) (Result, error) {
+	if _, err := os.Stat(path); err == nil {
+		return Result{}, fmt.Errorf("%w: %s", ErrExists, path)
+	}
+	ids, err := selectSessions(ctx, conn, sessionIDs)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := create(ctx, path); err != nil {
+		return Result{}, err
+	}
+	c, detach, err := attach(ctx, conn, path)
+	if err != nil {
+		_ = os.Remove(path)
+		return Result{}, err
+	}
+	result, err := copySessions(ctx, c, "main", "archive", ids)
+	detach()
+	if err != nil {
+		_ = os.Remove(path)
+		return Result{}, err
+	}
+	return result, nil
+}
+
+// Import adds the sessions of the archive at the path the database doesn't
+// have yet, keeping their IDs. The archive is left as it is, even when made
+// by an older version.
+func Import(ctx context.Context, conn *sql.DB, path string) (Result, error) {
+	if _, err := os.Stat(path); err != nil {
+		return Result{}, err
+	}
+	// Work on a copy migrated to the current schema.
+	tmp, err := os.MkdirTemp("", "crush-archive-*")
+	if err != nil {
+		return Result{}, err
+	}
+	defer os.RemoveAll(tmp)
+	migrated := filepath.Join(tmp, "archive.db")
+	if err := copyFile(path, migrated); err != nil {
+		return Result{}, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if err := create(ctx, migrated); err != nil {
+		return Result{}, err
+	}
+
+	c, detach, err := attach(ctx, conn, migrated)
+	if err != nil {
+		return Result{}, err
+	}
+	defer detach()
+	rows, err := c.QueryContext(ctx, "SELECT id, id IN (SELECT id FROM main.sessions) FROM archive.sessions")
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to read archive: %w", err)
+	}
+	var ids []string
+	skipped := 0
+	for rows.Next() {
+		var id string
+		var exists bool
+		if err := rows.Scan(&id, &exists); err != nil {
+			rows.Close()
+			return Result{}, err
+		}
+		if exists {
+			skipped++
+			continue
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Result{}, err
+	}
+
+	result, err := copySessions(ctx, c, "archive", "main", ids)
+	if err != nil {
+		return Result{}, err
+	}
+	result.Skipped = skipped
+	return result, nil
+}
+
+// create creates the archive at the path, or migrates it, as a single file
+// database.
+func create(ctx context.Context, path string) error {
+	conn, err := db.Open(ctx, path)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, "PRAGMA journal_mode = DELETE")
+	return err
+}
+
+// attach attaches the archive at the path to a connection of the database
+// as the archive schema. The connection is released by detaching it.
+func attach(ctx context.Context, conn *sql.DB, path string) (*sql.Conn, func(), error) {
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := c.ExecContext(ctx, "ATTACH DATABASE ? AS archive", path); err != nil {
+		c.Close()
+		return nil, nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	return c, func() {
+		_, _ = c.ExecContext(context.Background(), "DETACH DATABASE archive")
+		c.Close()
+	}, nil
+}
+
+// selectSessions returns the IDs of the sessions along with the ones spawned
+// from them, or of all sessions without IDs.
+func selectSessions(ctx context.Context, conn *sql.DB, ids []string) ([]string, error) {
+	query := "SELECT id FROM sessions"
+	var args []any
+	if len(ids) > 0 {
+		selected, err := json.Marshal(ids)
+		if err != nil {
+			return nil, err
+		}
+		query = `
+WITH RECURSIVE selected(id) AS (
+    SELECT id FROM sessions WHERE id IN (SELECT value FROM json_each(?))
+    UNION
+    SELECT sessions.id FROM sessions JOIN selected ON sessions.parent_session_id = selected.id
+)
+SELECT id FROM selected`
+		args = append(args, string(selected))
+	}
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var result []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		result = append(result, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if !slices.Contains(result, id) {
+			return nil, fmt.Errorf("session %s not found", id)
+		}
+	}
+	return result, nil
+}
+
+// copySessions copies the sessions and their rows from one schema of the
+// connection to the other.
+func copySessions(ctx context.Context, c *sql.Conn, from, to string, ids []string) (Result, error) {
+	selected, err := json.Marshal(ids)
+	if err != nil {
+		return Result{}, err
+	}
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// The rows of the sessions are copied before the sessions themselves,
+	// so the triggers counting messages leave the counts copied alone.
+	if _, err := tx.ExecContext(ctx, "PRAGMA defer_foreign_keys = ON"); err != nil {
+		return Result{}, err
+	}
+	var result Result
+	for _, table := range append(slices.Clone(tables), "sessions") {
+		key := "session_id"
+		if table == "sessions" {
+			key = "id"
+		}
+		copied, err := copyRows(ctx, tx, table, key, from, to, string(selected))
+		if err != nil {
+			return Result{}, fmt.Errorf("failed to copy %s: %w", table, err)
+		}
+		switch table {
+		case "sessions":
+			result.Sessions = int(copied)
+		case "messages":
+			result.Messages = int(copied)
+		}
+	}
+	return result, tx.Commit()
+}
+
+// copyRows copies the rows of the table whose key is one of the selected
+// JSON array, with the columns both schemas have.
+func copyRows(ctx context.Context, tx *sql.Tx, table, key, from, to, selected string) (int64, error) {
+	rows, err := tx.QueryContext(ctx, `
+SELECT name FROM pragma_table_info(?1, ?2)
+WHERE name IN (SELECT name FROM pragma_table_info(?1, ?3))
+ORDER BY cid`, table, from, to)
+	if err != nil {
+		return 0, err
+	}
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		columns = append(columns, `"`+name+`"`)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(columns) == 0 {
+		return 0, nil
+	}
+	list := strings.Join(columns, ", ")
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(
+		`INSERT INTO %s.%s (%s) SELECT %s FROM %s.%s WHERE %s IN (SELECT value FROM json_each(?))`,
+		to, table, list, list, from, table, key,
+	), selected)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
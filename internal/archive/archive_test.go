@@ -0,0 +1,88 @@
+package archive
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImport(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+
+	for _, s := range []db.CreateSessionParams{
+		{ID: "parser", Title: "Parser", Cost: 1.5},
+		{ID: "task", ParentSessionID: sql.NullString{String: "parser", Valid: true}, Title: "Task"},
+		{ID: "other", Title: "Other"},
+	} {
+		_, err := q.CreateSession(t.Context(), s)
+		require.NoError(t, err)
+	}
+	for _, m := range []db.CreateMessageParams{
+		{ID: "m1", SessionID: "parser", Role: "user", Parts: `[{"type":"text","data":{"text":"fix the tokenizer"}}]`},
+		{ID: "m2", SessionID: "parser", Role: "tool", Parts: `[{"type":"tool_result","data":{"content":"ok"}}]`},
+		{ID: "m3", SessionID: "task", Role: "user", Parts: `[]`},
+		{ID: "m4", SessionID: "other", Role: "user", Parts: `[]`},
+	} {
+		_, err := q.CreateMessage(t.Context(), m)
+		require.NoError(t, err)
+	}
+	_, err = q.SetSessionFact(t.Context(), db.SetSessionFactParams{SessionID: "parser", Key: "lexer", Value: "Hand written", Category: "decision"})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "archive.db")
+	result, err := Export(t.Context(), conn, path, []string{"parser"})
+	require.NoError(t, err)
+	require.Equal(t, Result{Sessions: 2, Messages: 3}, result)
+	require.NoFileExists(t, path+"-wal")
+
+	_, err = Export(t.Context(), conn, path, nil)
+	require.ErrorIs(t, err, ErrExists)
+	_, err = Export(t.Context(), conn, filepath.Join(t.TempDir(), "missing.db"), []string{"missing"})
+	require.Error(t, err)
+
+	// Into another project.
+	other, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { other.Close() })
+	result, err = Import(t.Context(), other, path)
+	require.NoError(t, err)
+	require.Equal(t, Result{Sessions: 2, Messages: 3}, result)
+
+	oq := db.New(other)
+	imported, err := oq.GetSessionByID(t.Context(), "parser")
+	require.NoError(t, err)
+	original, err := q.GetSessionByID(t.Context(), "parser")
+	require.NoError(t, err)
+	require.Equal(t, original, imported)
+	task, err := oq.GetSessionByID(t.Context(), "task")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), task.MessageCount)
+	_, err = oq.GetSessionByID(t.Context(), "other")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	msgs, err := oq.ListMessagesBySession(t.Context(), "parser")
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	facts, err := oq.ListSessionFacts(t.Context(), "parser")
+	require.NoError(t, err)
+	require.Len(t, facts, 1)
+	require.Equal(t, "Hand written", facts[0].Value)
+
+	// The imported messages are searchable.
+	var found int
+	require.NoError(t, other.QueryRowContext(t.Context(), "SELECT count(*) FROM messages_fts WHERE messages_fts MATCH 'tokenizer'").Scan(&found))
+	require.Equal(t, 1, found)
+
+	// Importing again skips the sessions already there.
+	result, err = Import(t.Context(), other, path)
+	require.NoError(t, err)
+	require.Equal(t, Result{Skipped: 2}, result)
+}
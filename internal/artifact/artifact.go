@@ -0,0 +1,256 @@
+// Package artifact stores the outputs generated during a session, such as
+// coverage reports, build binaries or rendered images, so they can be found
+// again after the working tree has moved on.
+package artifact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+// MaxSize is the largest file that can be stored as an artifact.
+const MaxSize = 100 * 1024 * 1024
+
+const (
+	KindImage  = "image"
+	KindReport = "report"
+	KindBinary = "binary"
+	KindFile   = "file"
+	KindAudio  = "audio"
+)
+
+var (
+	ErrTooLarge    = errors.New("artifact is too large")
+	ErrIsDirectory = errors.New("artifact is a directory")
+)
+
+// Artifact is a stored copy of a file generated during a session.
+type Artifact struct {
+	ID          string
+	SessionID   string
+	Name        string
+	Kind        string
+	Description string
+	// Path is where the stored copy lives, under the data directory.
+	Path string
+	// SourcePath is where the file was generated.
+	SourcePath string
+	Size       int64
+	CreatedAt  int64
+}
+
+// RegisterParams describes a file to store as an artifact. Name defaults to
+// the base name of the source and Kind is guessed from the file when empty.
+type RegisterParams struct {
+	SessionID   string
+	SourcePath  string
+	Name        string
+	Kind        string
+	Description string
+}
+
+type Service interface {
+	pubsub.Suscriber[Artifact]
+	Register(ctx context.Context, params RegisterParams) (Artifact, error)
+	Get(ctx context.Context, id string) (Artifact, error)
+	List(ctx context.Context) ([]Artifact, error)
+	ListBySession(ctx context.Context, sessionID string) ([]Artifact, error)
+}
+
+type service struct {
+	*pubsub.Broker[Artifact]
+	q   db.Querier
+	dir string
+}
+
+// NewService returns a service that stores artifacts in the artifacts
+// directory under dataDir.
+func NewService(q db.Querier, dataDir string) Service {
+	return &service{
+		Broker: pubsub.NewBroker[Artifact](),
+		q:      q,
+		dir:    filepath.Join(dataDir, "artifacts"),
+	}
+}
+
+func (s *service) Register(ctx context.Context, params RegisterParams) (Artifact, error) {
+	info, err := os.Stat(params.SourcePath)
+	if err != nil {
+		return Artifact{}, err
+	}
+	if info.IsDir() {
+		return Artifact{}, fmt.Errorf("%w: %s", ErrIsDirectory, params.SourcePath)
+	}
+	if info.Size() > MaxSize {
+		return Artifact{}, fmt.Errorf("%w: %d bytes, the limit is %d", ErrTooLarge, info.Size(), MaxSize)
+	}
+
+	name := filepath.Base(params.Name)
+	if params.Name == "" || name == "." || name == string(filepath.Separator) {
+		name = filepath.Base(params.SourcePath)
+	}
+	kind := params.Kind
+	if kind == "" {
+		kind = DetectKind(params.SourcePath, info.Mode())
+	}
+
+	id := uuid.New().String()
+	path := filepath.Join(s.dir, id, name)
+	if err := copyFile(params.SourcePath, path, info.Mode().Perm()); err != nil {
+		return Artifact{}, fmt.Errorf("failed to store artifact: %w", err)
+	}
+
+	dbArtifact, err := s.q.CreateArtifact(ctx, db.CreateArtifactParams{
+		ID:          id,
+		SessionID:   params.SessionID,
+		Name:        name,
+		Kind:        kind,
+		Description: params.Description,
+		Path:        path,
+		SourcePath:  params.SourcePath,
+		Size:        info.Size(),
+	})
+	if err != nil {
+		_ = os.RemoveAll(filepath.Dir(path))
+		return Artifact{}, err
+	}
+	artifact := fromDBItem(dbArtifact)
+	s.Publish(pubsub.CreatedEvent, artifact)
+	return artifact, nil
+}
+
+func (s *service) Get(ctx context.Context, id string) (Artifact, error) {
+	dbArtifact, err := s.q.GetArtifact(ctx, id)
+	if err != nil {
+		return Artifact{}, err
+	}
+	return fromDBItem(dbArtifact), nil
+}
+
+func (s *service) List(ctx context.Context) ([]Artifact, error) {
+	dbArtifacts, err := s.q.ListArtifacts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fromDBItems(dbArtifacts), nil
+}
+
+func (s *service) ListBySession(ctx context.Context, sessionID string) ([]Artifact, error) {
+	dbArtifacts, err := s.q.ListArtifactsBySession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return fromDBItems(dbArtifacts), nil
+}
+
+// DetectKind guesses the kind of artifact from the file name and mode.
+func DetectKind(path string, mode os.FileMode) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".bmp":
+		return KindImage
+	case ".html", ".htm", ".pdf", ".txt", ".md", ".json", ".xml", ".csv", ".out", ".log", ".lcov":
+		return KindReport
+	case ".exe", ".wasm", ".so", ".dylib", ".dll", ".a":
+		return KindBinary
+	}
+	if mode&0o111 != 0 {
+		return KindBinary
+	}
+	return KindFile
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func fromDBItems(items []db.Artifact) []Artifact {
+	artifacts := make([]Artifact, len(items))
+	for i, item := range items {
+		artifacts[i] = fromDBItem(item)
+	}
+	return artifacts
+}
+
+func fromDBItem(item db.Artifact) Artifact {
+	return Artifact{
+		ID:          item.ID,
+		SessionID:   item.SessionID,
+		Name:        item.Name,
+		Kind:        item.Kind,
+		Description: item.Description,
+		Path:        item.Path,
+		SourcePath:  item.SourcePath,
+		Size:        item.Size,
+		CreatedAt:   item.CreatedAt,
+	}
+}
+
+// Export copies the artifacts of a session to dir and returns how
+// many were copied.
+func Export(ctx context.Context, artifacts Service, sessionID, dir string) (int, error) {
+	list, err := artifacts.ListBySession(ctx, sessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list session artifacts: %w", err)
+	}
+	if len(list) == 0 {
+		return 0, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create artifacts directory: %w", err)
+	}
+	used := make(map[string]bool, len(list))
+	for _, a := range list {
+		name := a.Name
+		if used[name] {
+			// Keep artifacts saved under the same name apart.
+			name = shortID(a.ID) + "-" + name
+		}
+		used[name] = true
+
+		content, err := os.ReadFile(a.Path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read artifact %q: %w", a.Name, err)
+		}
+		info, err := os.Stat(a.Path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read artifact %q: %w", a.Name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, info.Mode().Perm()); err != nil {
+			return 0, fmt.Errorf("failed to write artifact %q: %w", a.Name, err)
+		}
+	}
+	return len(list), nil
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,149 @@
+package artifact
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	conn, err := db.Connect(t.Context(), dataDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	q := db.New(conn)
+	for _, id := range []string{"session", "other"} {
+		_, err = q.CreateSession(t.Context(), db.CreateSessionParams{ID: id, Title: "Test"})
+		require.NoError(t, err)
+	}
+
+	workDir := t.TempDir()
+	report := filepath.Join(workDir, "coverage.html")
+	require.NoError(t, os.WriteFile(report, []byte("<html></html>"), 0o644))
+	binary := filepath.Join(workDir, "app")
+	require.NoError(t, os.WriteFile(binary, []byte("\x7fELF"), 0o755))
+
+	svc := NewService(q, dataDir)
+	events := svc.Subscribe(t.Context())
+
+	created, err := svc.Register(t.Context(), RegisterParams{
+		SessionID:   "session",
+		SourcePath:  report,
+		Description: "Test coverage",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "coverage.html", created.Name)
+	require.Equal(t, KindReport, created.Kind)
+	require.Equal(t, int64(13), created.Size)
+	require.Equal(t, report, created.SourcePath)
+	require.True(t, filepath.IsAbs(created.Path))
+	require.Contains(t, created.Path, filepath.Join(dataDir, "artifacts"))
+
+	event := <-events
+	require.Equal(t, created, event.Payload)
+
+	// The stored copy outlives the generated file.
+	require.NoError(t, os.Remove(report))
+	content, err := os.ReadFile(created.Path)
+	require.NoError(t, err)
+	require.Equal(t, "<html></html>", string(content))
+
+	_, err = svc.Register(t.Context(), RegisterParams{
+		SessionID:  "other",
+		SourcePath: binary,
+		Name:       "../server",
+	})
+	require.NoError(t, err)
+
+	got, err := svc.Get(t.Context(), created.ID)
+	require.NoError(t, err)
+	require.Equal(t, created, got)
+
+	all, err := svc.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	bySession, err := svc.ListBySession(t.Context(), "other")
+	require.NoError(t, err)
+	require.Len(t, bySession, 1)
+	require.Equal(t, "server", bySession[0].Name)
+	require.Equal(t, KindBinary, bySession[0].Kind)
+
+	_, err = svc.Register(t.Context(), RegisterParams{SessionID: "session", SourcePath: workDir})
+	require.ErrorIs(t, err, ErrIsDirectory)
+}
+
+func TestDetectKind(t *testing.T) {
+	t.Parallel()
+
+	for path, want := range map[string]string{
+		"plot.PNG":    KindImage,
+		"cover.out":   KindReport,
+		"report.html": KindReport,
+		"lib.so":      KindBinary,
+		"archive.zip": KindFile,
+		"bin/unknown": KindFile,
+	} {
+		require.Equal(t, want, DetectKind(path, 0o644), path)
+	}
+	require.Equal(t, KindBinary, DetectKind("bin/server", 0o755))
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	conn, err := db.Connect(t.Context(), dataDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	q := db.New(conn)
+	for _, id := range []string{"session", "other"} {
+		_, err = q.CreateSession(t.Context(), db.CreateSessionParams{ID: id, Title: "Test"})
+		require.NoError(t, err)
+	}
+	artifacts := NewService(q, dataDir)
+
+	workDir := t.TempDir()
+	for name, content := range map[string]string{
+		"coverage.html":        "first",
+		"nested/coverage.html": "second",
+		"other.txt":            "other",
+	} {
+		path := filepath.Join(workDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	_, err = artifacts.Register(t.Context(), RegisterParams{SessionID: "session", SourcePath: filepath.Join(workDir, "coverage.html")})
+	require.NoError(t, err)
+	second, err := artifacts.Register(t.Context(), RegisterParams{SessionID: "session", SourcePath: filepath.Join(workDir, "nested", "coverage.html")})
+	require.NoError(t, err)
+	_, err = artifacts.Register(t.Context(), RegisterParams{SessionID: "other", SourcePath: filepath.Join(workDir, "other.txt")})
+	require.NoError(t, err)
+
+	outDir := filepath.Join(t.TempDir(), "out")
+	n, err := Export(t.Context(), artifacts, "session", outDir)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	entries, err := os.ReadDir(outDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	content, err := os.ReadFile(filepath.Join(outDir, "coverage.html"))
+	require.NoError(t, err)
+	require.Equal(t, "first", string(content))
+	content, err = os.ReadFile(filepath.Join(outDir, shortID(second.ID)+"-coverage.html"))
+	require.NoError(t, err)
+	require.Equal(t, "second", string(content))
+
+	n, err = Export(t.Context(), artifacts, "missing", filepath.Join(t.TempDir(), "none"))
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
@@ -0,0 +1,166 @@
+// Package audit keeps a log of every tool the agent ran, with its input and
+// a hash of its output, for compliance reviews of what the agent did. The
+// log is kept when the sessions are deleted.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+// DefaultLimit is the number of entries listed unless asked otherwise.
+const DefaultLimit = 100
+
+// Status is how a tool execution ended.
+type Status string
+
+const (
+	// StatusSuccess is a tool that ran and succeeded.
+	StatusSuccess Status = "success"
+	// StatusError is a tool that ran and reported an error to the model.
+	StatusError Status = "error"
+	// StatusFailed is a tool that failed to run.
+	StatusFailed Status = "failed"
+)
+
+// Entry is the record of a tool execution.
+type Entry struct {
+	ID         string
+	SessionID  string
+	MessageID  string
+	ToolCallID string
+	ToolName   string
+	Input      string
+	// OutputHash is the SHA-256 of the output given to the model.
+	OutputHash string
+	OutputSize int64
+	Duration   time.Duration
+	Status     Status
+	// ExitCode is the exit code of the commands run, nil for the tools that
+	// don't run any.
+	ExitCode  *int64
+	CreatedAt int64
+}
+
+// Filter selects the entries to list, the zero value selecting the latest
+// entries of all tools.
+type Filter struct {
+	ToolName  string
+	SessionID string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+type Service interface {
+	pubsub.Suscriber[Entry]
+	// Record logs an execution, hashing its output.
+	Record(ctx context.Context, entry Entry, output string) (Entry, error)
+	Get(ctx context.Context, id string) (Entry, error)
+	// List returns the entries of the filter, latest first.
+	List(ctx context.Context, filter Filter) ([]Entry, error)
+}
+
+type service struct {
+	*pubsub.Broker[Entry]
+	q db.Querier
+}
+
+func NewService(q db.Querier) Service {
+	return &service{
+		Broker: pubsub.NewBroker[Entry](),
+		q:      q,
+	}
+}
+
+func (s *service) Record(ctx context.Context, entry Entry, output string) (Entry, error) {
+	sum := sha256.Sum256([]byte(output))
+	entry.OutputHash = hex.EncodeToString(sum[:])
+	entry.OutputSize = int64(len(output))
+	var exitCode sql.NullInt64
+	if entry.ExitCode != nil {
+		exitCode = sql.NullInt64{Int64: *entry.ExitCode, Valid: true}
+	}
+	dbEntry, err := s.q.CreateToolAudit(ctx, db.CreateToolAuditParams{
+		ID:         uuid.New().String(),
+		SessionID:  entry.SessionID,
+		MessageID:  entry.MessageID,
+		ToolCallID: entry.ToolCallID,
+		ToolName:   entry.ToolName,
+		Input:      entry.Input,
+		OutputHash: entry.OutputHash,
+		OutputSize: entry.OutputSize,
+		DurationMs: entry.Duration.Milliseconds(),
+		Status:     string(entry.Status),
+		ExitCode:   exitCode,
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+	// Keep the full precision duration for subscribers.
+	entry.ID = dbEntry.ID
+	entry.CreatedAt = dbEntry.CreatedAt
+	s.Publish(pubsub.CreatedEvent, entry)
+	return entry, nil
+}
+
+func (s *service) Get(ctx context.Context, id string) (Entry, error) {
+	dbEntry, err := s.q.GetToolAudit(ctx, id)
+	if err != nil {
+		return Entry{}, err
+	}
+	return fromDBItem(dbEntry), nil
+}
+
+func (s *service) List(ctx context.Context, filter Filter) ([]Entry, error) {
+	params := db.ListToolAuditParams{
+		ToolName:  sql.NullString{String: filter.ToolName, Valid: filter.ToolName != ""},
+		SessionID: sql.NullString{String: filter.SessionID, Valid: filter.SessionID != ""},
+		Limit:     int64(filter.Limit),
+	}
+	if params.Limit <= 0 {
+		params.Limit = DefaultLimit
+	}
+	if !filter.Since.IsZero() {
+		params.CreatedAfter = sql.NullInt64{Int64: filter.Since.Unix(), Valid: true}
+	}
+	if !filter.Until.IsZero() {
+		params.CreatedBefore = sql.NullInt64{Int64: filter.Until.Unix(), Valid: true}
+	}
+	dbEntries, err := s.q.ListToolAudit(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, len(dbEntries))
+	for i, item := range dbEntries {
+		entries[i] = fromDBItem(item)
+	}
+	return entries, nil
+}
+
+func fromDBItem(item db.ToolAudit) Entry {
+	entry := Entry{
+		ID:         item.ID,
+		SessionID:  item.SessionID,
+		MessageID:  item.MessageID,
+		ToolCallID: item.ToolCallID,
+		ToolName:   item.ToolName,
+		Input:      item.Input,
+		OutputHash: item.OutputHash,
+		OutputSize: item.OutputSize,
+		Duration:   time.Duration(item.DurationMs) * time.Millisecond,
+		Status:     Status(item.Status),
+		CreatedAt:  item.CreatedAt,
+	}
+	if item.ExitCode.Valid {
+		entry.ExitCode = &item.ExitCode.Int64
+	}
+	return entry
+}
@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	svc := NewService(db.New(conn))
+	events := svc.Subscribe(t.Context())
+
+	exitCode := int64(2)
+	for _, record := range []struct {
+		entry  Entry
+		output string
+	}{
+		{Entry{SessionID: "first", ToolName: "bash", Input: `{"command":"make"}`, Duration: 1500 * time.Millisecond, Status: StatusError, ExitCode: &exitCode}, "make: *** Error 2"},
+		{Entry{SessionID: "first", ToolName: "view", Input: `{"file_path":"main.go"}`, Status: StatusSuccess}, "package main"},
+		{Entry{SessionID: "second", ToolName: "bash", Input: `{"command":"ls"}`, Status: StatusSuccess}, "main.go"},
+	} {
+		_, err := svc.Record(t.Context(), record.entry, record.output)
+		require.NoError(t, err)
+	}
+
+	event := <-events
+	require.Equal(t, "bash", event.Payload.ToolName)
+	require.NotEmpty(t, event.Payload.ID)
+	// The SHA-256 of the output.
+	require.Equal(t, "9010bf4af11ffde4c43a98b63ee557ac5cfe8aa358c6badeb7e32274b85ab82a", event.Payload.OutputHash)
+	require.Equal(t, int64(len("make: *** Error 2")), event.Payload.OutputSize)
+
+	entry, err := svc.Get(t.Context(), event.Payload.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusError, entry.Status)
+	require.Equal(t, 1500*time.Millisecond, entry.Duration)
+	require.Equal(t, &exitCode, entry.ExitCode)
+	require.Equal(t, `{"command":"make"}`, entry.Input)
+
+	entries, err := svc.List(t.Context(), Filter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	entries, err = svc.List(t.Context(), Filter{ToolName: "bash"})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	entries, err = svc.List(t.Context(), Filter{ToolName: "bash", SessionID: "second"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Nil(t, entries[0].ExitCode)
+
+	entries, err = svc.List(t.Context(), Filter{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	entries, err = svc.List(t.Context(), Filter{Since: time.Now().Add(-time.Hour), Until: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	entries, err = svc.List(t.Context(), Filter{Until: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aymanbagabas/go-udiff"
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply [session-id]",
+	Short: "Export the file changes of a session as a git patch",
+	Long: `Collect all file modifications made during a session and print them as a
+unified git patch, or commit them to a new branch. The artifacts saved during
+the session can be copied along with the changes.
+If no session ID is given, the most recent session is used.`,
+	Example: `
+# Print the changes of the latest session as a patch
+crush apply
+
+# Write the changes of a specific session to a file
+crush apply 4f6c1b2e -o changes.patch
+
+# Apply the changes elsewhere
+crush apply | git -C /path/to/other/clone apply
+
+# Commit the changes of the latest session to a new branch
+crush apply --branch crush/my-feature
+
+# Also copy the artifacts of the session, like reports or binaries
+crush apply -o changes.patch --artifacts ./artifacts
+  `,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		branch, _ := cmd.Flags().GetString("branch")
+		artifactsDir, _ := cmd.Flags().GetString("artifacts")
+		ctx := cmd.Context()
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+		cfg := st.cfg
+
+		sess, err := resolveApplySession(ctx, st.sessions, args)
+		if err != nil {
+			return err
+		}
+
+		sessionFiles, err := st.files.ListBySession(ctx, sess.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list session files: %w", err)
+		}
+
+		if artifactsDir != "" {
+			n, err := artifact.Export(ctx, st.artifacts, sess.ID, artifactsDir)
+			if err != nil {
+				return err
+			}
+			cmd.PrintErrf("Copied %d artifact(s) to %s\n", n, artifactsDir)
+		}
+
+		changes, outside := sessionChanges(sessionFiles, cfg.WorkingDir())
+		for _, path := range outside {
+			cmd.PrintErrf("Skipping %s, outside of the working directory\n", path)
+		}
+		if len(changes) == 0 {
+			if artifactsDir != "" {
+				return nil
+			}
+			return fmt.Errorf("session %q has no file changes", sess.ID)
+		}
+
+		if branch != "" {
+			return commitChanges(ctx, cfg.WorkingDir(), branch, sess.Title, changes)
+		}
+
+		patch := generatePatch(changes)
+		if output == "" {
+			_, err = fmt.Fprint(cmd.OutOrStdout(), patch)
+			return err
+		}
+		if err := os.WriteFile(output, []byte(patch), 0o644); err != nil {
+			return fmt.Errorf("failed to write patch file: %w", err)
+		}
+		cmd.Printf("Wrote changes to %d file(s) to %s\n", len(changes), output)
+		return nil
+	},
+}
+
+func init() {
+	applyCmd.Flags().StringP("output", "o", "", "Write the patch to a file instead of stdout")
+	applyCmd.Flags().StringP("branch", "b", "", "Create a branch with the given name and commit the changes to it")
+	applyCmd.Flags().String("artifacts", "", "Copy the artifacts of the session to the given directory")
+}
+
+// fileChange holds the state of a file before and after a session.
+type fileChange struct {
+	Path   string
+	Before string
+	After  string
+	// Created is set for the files the session created, which the tools
+	// record with an empty first version.
+	Created bool
+	// Deleted is set for the files that no longer exist, as the history
+	// doesn't record deletions.
+	Deleted bool
+}
+
+func resolveApplySession(ctx context.Context, sessions session.Service, args []string) (session.Session, error) {
+	if len(args) > 0 {
+		sess, err := sessions.Get(ctx, args[0])
+		if err != nil {
+			return session.Session{}, fmt.Errorf("session %q not found: %w", args[0], err)
+		}
+		return sess, nil
+	}
+	all, err := sessions.List(ctx)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	if len(all) == 0 {
+		return session.Session{}, fmt.Errorf("no sessions found")
+	}
+	return all[0], nil
+}
+
+// sessionChanges reduces the file history of a session to one change per
+// path, comparing the first recorded version with the last one, or with no
+// content for the files that no longer exist. The paths of the changes are
+// relative to the working directory, the changed files outside of it are
+// returned apart as a patch can't hold them.
+func sessionChanges(files []history.File, workingDir string) (changes []fileChange, outside []string) {
+	byPath := make(map[string]*fileChange)
+	var paths []string
+	for _, f := range files {
+		change, ok := byPath[f.Path]
+		if !ok {
+			change = &fileChange{Path: f.Path, Before: f.Content, Created: f.Content == ""}
+			byPath[f.Path] = change
+			paths = append(paths, f.Path)
+		}
+		change.After = f.Content
+	}
+	slices.Sort(paths)
+
+	for _, path := range paths {
+		change := byPath[path]
+		abs := change.Path
+		if !filepath.IsAbs(abs) {
+			abs = filepath.Join(workingDir, abs)
+		}
+		if _, err := os.Stat(abs); errors.Is(err, fs.ErrNotExist) {
+			change.Deleted = true
+			change.After = ""
+		}
+		if change.Created && change.Deleted || !change.Deleted && change.Before == change.After {
+			continue
+		}
+		rel, err := filepath.Rel(workingDir, change.Path)
+		if err != nil || !filepath.IsLocal(rel) {
+			outside = append(outside, change.Path)
+			continue
+		}
+		change.Path = filepath.ToSlash(rel)
+		changes = append(changes, *change)
+	}
+	return changes, outside
+}
+
+// generatePatch renders the changes as a patch that can be consumed by
+// `git apply`.
+func generatePatch(changes []fileChange) string {
+	var sb strings.Builder
+	for _, change := range changes {
+		name := change.Path
+		oldName, newName := "a/"+name, "b/"+name
+		fmt.Fprintf(&sb, "diff --git a/%s b/%s\n", name, name)
+		switch {
+		case change.Created:
+			sb.WriteString("new file mode 100644\n")
+			oldName = "/dev/null"
+		case change.Deleted:
+			sb.WriteString("deleted file mode 100644\n")
+			newName = "/dev/null"
+		}
+		sb.WriteString(udiff.Unified(oldName, newName, change.Before, change.After))
+	}
+	return sb.String()
+}
+
+// commitChanges commits the content of the files after the session to a new
+// branch, leaving the working tree as it is. The changes are applied to the
+// index, so it must not hold staged changes.
+func commitChanges(ctx context.Context, workingDir, branch, title string, changes []fileChange) error {
+	if _, err := runGit(ctx, workingDir, "", "diff", "--cached", "--quiet"); err != nil {
+		return fmt.Errorf("the index has staged changes, commit or unstage them first")
+	}
+	patch := generatePatch(changes)
+	steps := [][]string{
+		{"apply", "--cached", "--check"},
+		{"switch", "-c", branch},
+		{"apply", "--cached"},
+		{"commit", "-m", "crush: " + title},
+	}
+	for _, args := range steps {
+		var stdin string
+		if args[0] == "apply" {
+			stdin = patch
+		}
+		if out, err := runGit(ctx, workingDir, stdin, args...); err != nil {
+			return fmt.Errorf("git %s failed: %w\n%s", args[0], err, out)
+		}
+	}
+	return nil
+}
+
+func runGit(ctx context.Context, workingDir, stdin string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = workingDir
+	cmd.Stdin = strings.NewReader(stdin)
+	return cmd.CombinedOutput()
+}
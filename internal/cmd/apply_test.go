@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	writeFile(t, path("main.go"), "package main\n\nfunc main() {}\n")
+	writeFile(t, path("new.go"), "package new\n")
+	writeFile(t, path("same.go"), "package same\n")
+	writeFile(t, path("..notes"), "todo\n")
+	writeFile(t, path("emptied.go"), "")
+	files := []history.File{
+		{Path: path("main.go"), Content: "package main\n", Version: 0},
+		{Path: path("new.go"), Content: "", Version: 0},
+		{Path: path("main.go"), Content: "package main\n\nfunc main() {}\n", Version: 1},
+		{Path: path("new.go"), Content: "package new\n", Version: 1},
+		{Path: path("same.go"), Content: "package same\n", Version: 0},
+		{Path: path("same.go"), Content: "package same\n", Version: 1},
+		{Path: path("..notes"), Content: "", Version: 0},
+		{Path: path("..notes"), Content: "todo\n", Version: 1},
+		{Path: path("emptied.go"), Content: "package emptied\n", Version: 0},
+		{Path: path("emptied.go"), Content: "", Version: 1},
+		{Path: path("removed.go"), Content: "package removed\n", Version: 0},
+		{Path: path("scratch.go"), Content: "", Version: 0},
+		{Path: path("scratch.go"), Content: "package scratch\n", Version: 1},
+		{Path: "/etc/hosts", Content: "", Version: 0},
+		{Path: "/etc/hosts", Content: "127.0.0.1 work\n", Version: 1},
+	}
+
+	changes, outside := sessionChanges(files, dir)
+	require.Equal(t, []fileChange{
+		{Path: "..notes", Before: "", After: "todo\n", Created: true},
+		{Path: "emptied.go", Before: "package emptied\n", After: ""},
+		{Path: "main.go", Before: "package main\n", After: "package main\n\nfunc main() {}\n"},
+		{Path: "new.go", Before: "", After: "package new\n", Created: true},
+		{Path: "removed.go", Before: "package removed\n", After: "", Deleted: true},
+	}, changes, "files created and removed during the session are left out")
+	require.Equal(t, []string{"/etc/hosts"}, outside)
+}
+
+func TestGeneratePatch(t *testing.T) {
+	patch := generatePatch([]fileChange{
+		{Path: "main.go", Before: "package main\n", After: "package main\n\nfunc main() {}\n"},
+		{Path: "new.go", Before: "", After: "package new\n", Created: true},
+		{Path: "old.go", Before: "package old\n", After: "", Deleted: true},
+		{Path: "emptied.go", Before: "package emptied\n", After: ""},
+	})
+
+	require.Contains(t, patch, "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n")
+	require.Contains(t, patch, "+func main() {}\n")
+	require.Contains(t, patch, "diff --git a/new.go b/new.go\nnew file mode 100644\n--- /dev/null\n+++ b/new.go\n")
+	require.Contains(t, patch, "+package new\n")
+	require.Contains(t, patch, "diff --git a/old.go b/old.go\ndeleted file mode 100644\n--- a/old.go\n+++ /dev/null\n")
+	require.Contains(t, patch, "-package old\n")
+	require.Contains(t, patch, "diff --git a/emptied.go b/emptied.go\n--- a/emptied.go\n+++ b/emptied.go\n", "emptied files are kept")
+}
+
+func TestCommitChanges(t *testing.T) {
+	dir := t.TempDir()
+	gitRun := func(args ...string) string {
+		t.Helper()
+		out, err := runGit(t.Context(), dir, "", args...)
+		require.NoError(t, err, string(out))
+		return string(out)
+	}
+	gitRun("init", "--quiet", "--initial-branch", "main")
+	gitRun("config", "user.email", "crush@example.com")
+	gitRun("config", "user.name", "Crush")
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, "old.go"), "package old\n")
+	gitRun("add", ".")
+	gitRun("commit", "--quiet", "-m", "initial")
+
+	// The working tree has changed since the session.
+	writeFile(t, filepath.Join(dir, "main.go"), "package main\n\n// Edited by hand.\n")
+	require.NoError(t, commitChanges(t.Context(), dir, "crush/feature", "Feature", []fileChange{
+		{Path: "main.go", Before: "package main\n", After: "package main\n\nfunc main() {}\n"},
+		{Path: "new.go", Before: "", After: "package new\n", Created: true},
+		{Path: "old.go", Before: "package old\n", After: "", Deleted: true},
+	}))
+
+	require.Equal(t, "crush/feature\n", gitRun("branch", "--show-current"))
+	require.Equal(t, "crush: Feature\n", gitRun("log", "-1", "--format=%s"))
+	require.Equal(t, "main.go\nnew.go\n", gitRun("ls-tree", "--name-only", "HEAD"))
+	require.Equal(t, "package main\n\nfunc main() {}\n", gitRun("show", "HEAD:main.go"))
+	requireFile(t, filepath.Join(dir, "main.go"), "package main\n\n// Edited by hand.\n")
+
+	// Staged changes would end up in the commit.
+	writeFile(t, filepath.Join(dir, "staged.go"), "package staged\n")
+	gitRun("add", "staged.go")
+	require.Error(t, commitChanges(t.Context(), dir, "crush/other", "Other", []fileChange{
+		{Path: "other.go", Before: "", After: "package other\n", Created: true},
+	}))
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/spf13/cobra"
+)
+
+var artifactsCmd = &cobra.Command{
+	Use:   "artifacts",
+	Short: "Manage the files generated during sessions",
+	Long: `Artifacts are files such as coverage reports, build binaries or rendered
+images that were saved during a session. A copy of each artifact is kept in
+the data directory.`,
+}
+
+var artifactsListCmd = &cobra.Command{
+	Use:   "list [session-id]",
+	Short: "List the saved artifacts",
+	Long:  `List the artifacts of all sessions, newest first, or of a single session.`,
+	Example: `
+# List all artifacts
+crush artifacts list
+
+# List the artifacts of a session
+crush artifacts list 4f6c1b2e
+  `,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		var artifacts []artifact.Artifact
+		if len(args) > 0 {
+			artifacts, err = st.artifacts.ListBySession(ctx, args[0])
+		} else {
+			artifacts, err = st.artifacts.List(ctx)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list artifacts: %w", err)
+		}
+		if len(artifacts) == 0 {
+			cmd.Println("No artifacts saved yet.")
+			return nil
+		}
+
+		headers := []string{"ID", "Session", "Name", "Kind", "Size", "Created"}
+		return printTable(cmd, headers, artifactRows(artifacts))
+	},
+}
+
+var artifactsOpenCmd = &cobra.Command{
+	Use:   "open <artifact-id>",
+	Short: "Open an artifact with the default application",
+	Long: `Open an artifact with the default application of the system. The ID can be
+shortened to any prefix that matches a single artifact.`,
+	Example: `
+# Open an artifact
+crush artifacts open 9b2d
+
+# Print where an artifact is stored
+crush artifacts open 9b2d --path
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printPath, _ := cmd.Flags().GetBool("path")
+		ctx := cmd.Context()
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		a, err := findArtifact(ctx, st.artifacts, args[0])
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(a.Path); err != nil {
+			return fmt.Errorf("artifact %q is missing from the data directory: %w", a.Name, err)
+		}
+		if printPath {
+			cmd.Println(a.Path)
+			return nil
+		}
+		return openFile(a.Path)
+	},
+}
+
+func init() {
+	artifactsOpenCmd.Flags().Bool("path", false, "Print the path of the stored artifact instead of opening it")
+	artifactsCmd.AddCommand(artifactsListCmd, artifactsOpenCmd)
+}
+
+func artifactRows(artifacts []artifact.Artifact) [][]string {
+	rows := make([][]string, len(artifacts))
+	for i, a := range artifacts {
+		rows[i] = []string{
+			shortID(a.ID),
+			shortID(a.SessionID),
+			a.Name,
+			a.Kind,
+			formatBytes(a.Size),
+			time.Unix(a.CreatedAt, 0).Format(time.DateTime),
+		}
+	}
+	return rows
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// findArtifact returns the artifact with the given ID, or the only one whose
+// ID starts with it.
+func findArtifact(ctx context.Context, artifacts artifact.Service, id string) (artifact.Artifact, error) {
+	if a, err := artifacts.Get(ctx, id); err == nil {
+		return a, nil
+	}
+	all, err := artifacts.List(ctx)
+	if err != nil {
+		return artifact.Artifact{}, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	var matches []artifact.Artifact
+	for _, a := range all {
+		if strings.HasPrefix(a.ID, id) {
+			matches = append(matches, a)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return artifact.Artifact{}, fmt.Errorf("artifact %q not found", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return artifact.Artifact{}, fmt.Errorf("artifact ID %q is ambiguous, it matches %d artifacts", id, len(matches))
+	}
+}
+
+// openFile opens the file with the default application, without waiting for
+// it to exit.
+func openFile(path string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", path)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+	default:
+		cmd = exec.Command("xdg-open", path)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return cmd.Process.Release()
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestArtifacts(t *testing.T) artifact.Service {
+	dataDir := t.TempDir()
+	conn, err := db.Connect(t.Context(), dataDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	q := db.New(conn)
+	for _, id := range []string{"session", "other"} {
+		_, err := q.CreateSession(t.Context(), db.CreateSessionParams{ID: id, Title: "Test"})
+		require.NoError(t, err)
+	}
+	return artifact.NewService(q, dataDir)
+}
+
+func TestFindArtifact(t *testing.T) {
+	artifacts := newTestArtifacts(t)
+
+	source := filepath.Join(t.TempDir(), "report.txt")
+	require.NoError(t, os.WriteFile(source, []byte("report"), 0o644))
+	saved, err := artifacts.Register(t.Context(), artifact.RegisterParams{SessionID: "session", SourcePath: source})
+	require.NoError(t, err)
+
+	found, err := findArtifact(t.Context(), artifacts, saved.ID)
+	require.NoError(t, err)
+	require.Equal(t, saved, found)
+
+	found, err = findArtifact(t.Context(), artifacts, saved.ID[:4])
+	require.NoError(t, err)
+	require.Equal(t, saved, found)
+
+	_, err = findArtifact(t.Context(), artifacts, "not-an-id")
+	require.ErrorContains(t, err, "not found")
+}
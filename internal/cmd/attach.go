@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/charmbracelet/crush/internal/daemon"
+	"github.com/spf13/cobra"
+)
+
+var attachCmd = &cobra.Command{
+	Use:   "attach <session>",
+	Short: "Follow a session the daemon is working on",
+	Long: `Print the messages of a session run by the daemon and, while the agent
+works in it, what the agent does as it does it.
+Interrupting detaches: the agent keeps running in the daemon, and the
+session can be attached to again later.`,
+	Example: `
+# Start a task in the background
+crush run --detach "Migrate the tests to testify"
+
+# Follow it, press ctrl+c to detach
+crush attach <session>
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessionID := args[0]
+		socket, err := resolveDaemonSocket(cmd)
+		if err != nil {
+			return err
+		}
+		client := daemon.NewClient(socket)
+		if err := client.Ping(cmd.Context()); err != nil {
+			return errors.New("no crush daemon is running for this project, start one with crush run --detach")
+		}
+
+		err = daemon.Attach(cmd.Context(), client, sessionID, cmd.OutOrStdout())
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "\nDetached, the agent keeps running. Attach again with: crush attach %s\n", sessionID)
+			return nil
+		}
+		return err
+	},
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/audit"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Review the tools the agent ran",
+	Long: `Every tool the agent runs is recorded in the audit log with its input, a
+SHA-256 hash of its output, how long it took and how it ended. The log is kept
+when sessions are deleted.`,
+}
+
+var auditListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the recorded tool executions",
+	Long:  `List the recorded tool executions, newest first.`,
+	Example: `
+# List the latest tool executions
+crush audit list
+
+# List the bash commands run during the last day
+crush audit list --tool bash --since 24h
+
+# List the tool executions of a session in a time range
+crush audit list --session 4f6c1b2e-... --since 2025-10-01 --until 2025-10-02
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		toolName, _ := cmd.Flags().GetString("tool")
+		sessionID, _ := cmd.Flags().GetString("session")
+		since, _ := cmd.Flags().GetString("since")
+		until, _ := cmd.Flags().GetString("until")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		filter := audit.Filter{ToolName: toolName, SessionID: sessionID, Limit: limit}
+		var err error
+		if filter.Since, err = parseTime(since, time.Now()); err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		if filter.Until, err = parseTime(until, time.Now()); err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		entries, err := st.audit.List(cmd.Context(), filter)
+		if err != nil {
+			return fmt.Errorf("failed to list tool executions: %w", err)
+		}
+		if len(entries) == 0 {
+			cmd.Println("No tool executions recorded.")
+			return nil
+		}
+
+		headers := []string{"ID", "Session", "Tool", "Status", "Exit", "Duration", "Created"}
+		return printTable(cmd, headers, auditRows(entries))
+	},
+}
+
+var auditShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a recorded tool execution",
+	Long:  `Show a recorded tool execution along with the input the tool was given.`,
+	Example: `
+# Show a tool execution
+crush audit show 9b2d4c1a-...
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		entry, err := st.audit.Get(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("tool execution %q not found: %w", args[0], err)
+		}
+
+		cmd.Printf("ID:          %s\n", entry.ID)
+		cmd.Printf("Session:     %s\n", entry.SessionID)
+		cmd.Printf("Message:     %s\n", entry.MessageID)
+		cmd.Printf("Tool call:   %s\n", entry.ToolCallID)
+		cmd.Printf("Tool:        %s\n", entry.ToolName)
+		cmd.Printf("Status:      %s\n", entry.Status)
+		cmd.Printf("Exit code:   %s\n", formatExitCode(entry.ExitCode))
+		cmd.Printf("Duration:    %s\n", entry.Duration)
+		cmd.Printf("Created:     %s\n", time.Unix(entry.CreatedAt, 0).Format(time.DateTime))
+		cmd.Printf("Output:      %s (%s)\n", entry.OutputHash, formatBytes(entry.OutputSize))
+		cmd.Printf("Input:\n%s\n", entry.Input)
+		return nil
+	},
+}
+
+func init() {
+	auditListCmd.Flags().String("tool", "", "Only list the executions of a tool")
+	auditListCmd.Flags().String("session", "", "Only list the executions of a session")
+	auditListCmd.Flags().String("since", "", "Only list the executions after a time, a date or a duration such as 24h")
+	auditListCmd.Flags().String("until", "", "Only list the executions before a time, a date or a duration such as 24h")
+	auditListCmd.Flags().IntP("limit", "n", audit.DefaultLimit, "Number of executions to list")
+	auditCmd.AddCommand(auditListCmd, auditShowCmd)
+}
+
+func auditRows(entries []audit.Entry) [][]string {
+	rows := make([][]string, len(entries))
+	for i, e := range entries {
+		rows[i] = []string{
+			e.ID,
+			shortID(e.SessionID),
+			e.ToolName,
+			string(e.Status),
+			formatExitCode(e.ExitCode),
+			e.Duration.String(),
+			time.Unix(e.CreatedAt, 0).Format(time.DateTime),
+		}
+	}
+	return rows
+}
+
+func formatExitCode(code *int64) string {
+	if code == nil {
+		return "-"
+	}
+	return strconv.FormatInt(*code, 10)
+}
+
+// parseTime parses a point in time given as an RFC 3339 time, a date or a
+// duration before now. The empty string is the zero time.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateTime, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a time, a date nor a duration", s)
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTime(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 10, 26, 12, 0, 0, 0, time.Local)
+	for input, want := range map[string]time.Time{
+		"":                     {},
+		"24h":                  now.Add(-24 * time.Hour),
+		"2025-10-01":           time.Date(2025, 10, 1, 0, 0, 0, 0, time.Local),
+		"2025-10-01 08:30:00":  time.Date(2025, 10, 1, 8, 30, 0, 0, time.Local),
+		"2025-10-01T08:30:00Z": time.Date(2025, 10, 1, 8, 30, 0, 0, time.UTC),
+	} {
+		got, err := parseTime(input, now)
+		require.NoError(t, err, input)
+		require.True(t, want.Equal(got), "%s: got %s", input, got)
+	}
+
+	_, err := parseTime("yesterday", now)
+	require.Error(t, err)
+}
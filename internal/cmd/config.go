@@ -0,0 +1,425 @@
+package cmd
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/home"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
+	"github.com/charmbracelet/crush/internal/version"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// configBundleVersion is the version of the layout of the config
+	// bundles, bumped when bundles stop being readable by older versions.
+	configBundleVersion = 1
+
+	configManifestName = "manifest.json"
+	configFileName     = "crush.json"
+	configDataName     = "data/crush.json"
+	configCommandsDir  = "commands/"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Share and inspect the configuration",
+	Long: `Export the global configuration, keybindings and custom commands into a
+single archive, and import it on another machine. Use it to set up a new
+machine or to share a team-standard setup.
+Show the configuration in effect and the file each value comes from.`,
+}
+
+var configEffectiveCmd = &cobra.Command{
+	Use:   "effective",
+	Short: "Show the configuration in effect and where each value comes from",
+	Long: `Show the values of the configuration files merged for the working directory
+and the file each value comes from. The files are merged in this order, later
+ones overriding earlier ones:
+
+  1. team:    .crush/team.json of the repository, shared by the team
+  2. global:  the configuration of the user
+  3. data:    the preferences saved by the app, like the selected models
+  4. project: crush.json and .crush.json, from the outermost directory
+
+Objects are merged field by field and arrays are appended to, so a list like
+allowed_models may come from several files. Credentials are redacted.`,
+	Example: `
+# Show the configuration of the current project
+crush config effective
+
+# Show the configuration of another project
+crush config effective --cwd /path/to/project
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		layers := config.Layers(cwd)
+		values, err := config.Effective(layers)
+		if err != nil {
+			return err
+		}
+
+		cmd.Println("Configuration files, from lowest to highest priority:")
+		for _, layer := range layers {
+			var missing string
+			if _, err := os.Stat(layer.Path); err != nil {
+				missing = " (not found)"
+			}
+			cmd.Printf("  %-8s %s%s\n", layer.Kind, home.Short(layer.Path), missing)
+		}
+		cmd.Println()
+		if len(values) == 0 {
+			cmd.Println("No values set.")
+			return nil
+		}
+		return printTable(cmd, []string{"Key", "Value", "Source"}, effectiveRows(values))
+	},
+}
+
+var configExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the global configuration into a zip file",
+	Long: `Export the global configuration, the preferences saved by the app, such as the
+selected models, and the user commands into a zip file.
+API keys, tokens, headers and environment variables are left out, unless they
+reference an environment variable, like $OPENAI_API_KEY. Project
+configurations and the secrets of the projects aren't exported.`,
+	Example: `
+# Export the configuration in the current directory
+crush config export
+
+# Export the configuration into a given file
+crush config export --output team.zip
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+
+		files, err := collectConfigBundle(config.GlobalConfig(), config.GlobalConfigData(), commands.UserCommandDirs())
+		if err != nil {
+			return err
+		}
+		if len(files) == 1 {
+			return errors.New("nothing to export, no global configuration or user commands found")
+		}
+
+		if output == "" {
+			output = "crush-config-" + time.Now().Format("20060102-150405") + ".zip"
+		}
+		if err := writeBundle(output, files); err != nil {
+			return err
+		}
+		for _, f := range files[1:] {
+			cmd.Printf("Exported %s\n", f.name)
+		}
+		cmd.Printf("Configuration written to %s, credentials were left out.\n", output)
+		return nil
+	},
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a configuration exported with crush config export",
+	Long: `Import the global configuration, the preferences and the user commands of a
+zip file written by crush config export.
+Existing files are kept unless --force is given. Credentials aren't part of
+the archive, set them again after importing.`,
+	Example: `
+# Import a configuration, keeping the existing files
+crush config import team.zip
+
+# Import a configuration, replacing the existing files
+crush config import team.zip --force
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+
+		dests := configDests{
+			config: config.GlobalConfig(),
+			data:   config.GlobalConfigData(),
+		}
+		if dirs := commands.UserCommandDirs(); len(dirs) > 0 {
+			dests.commands = dirs[0]
+		}
+		imported, err := importConfigBundle(args[0], dests, force)
+		for _, f := range imported {
+			if f.skipped {
+				cmd.Printf("Kept existing %s, use --force to replace it\n", f.path)
+			} else {
+				cmd.Printf("Imported %s\n", f.path)
+			}
+		}
+		return err
+	},
+}
+
+func init() {
+	configExportCmd.Flags().StringP("output", "o", "", "Path of the zip file to write")
+	configImportCmd.Flags().BoolP("force", "f", false, "Replace the existing files")
+	configCmd.AddCommand(configExportCmd, configImportCmd, configEffectiveCmd)
+}
+
+func effectiveRows(values []config.EffectiveValue) [][]string {
+	rows := make([][]string, len(values))
+	for i, v := range values {
+		value, err := json.Marshal(redactTree(v.Value, isSensitivePath(v.Path)))
+		if err != nil {
+			value = fmt.Appendf(nil, "%v", v.Value)
+		}
+		sources := make([]string, len(v.Sources))
+		for j, source := range v.Sources {
+			sources[j] = home.Short(source.Path)
+		}
+		rows[i] = []string{v.Path, string(value), strings.Join(sources, ", ")}
+	}
+	return rows
+}
+
+// isSensitivePath reports whether the value at the dotted path of the
+// configuration holds credentials.
+func isSensitivePath(path string) bool {
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		if sensitiveConfigKey.MatchString(key) || (i < len(keys)-1 && slices.Contains(sensitiveConfigMaps, key)) {
+			return true
+		}
+	}
+	return false
+}
+
+type configManifest struct {
+	Version      int       `json:"version"`
+	CrushVersion string    `json:"crush_version"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// collectConfigBundle gathers the files of a config bundle, its manifest
+// first. Missing files are left out.
+func collectConfigBundle(configPath, dataPath string, commandDirs []string) ([]bundleFile, error) {
+	manifest, err := json.MarshalIndent(configManifest{
+		Version:      configBundleVersion,
+		CrushVersion: version.Version,
+		CreatedAt:    time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	files := []bundleFile{{name: configManifestName, data: manifest}}
+
+	for _, f := range []struct{ name, path string }{
+		{configFileName, configPath},
+		{configDataName, dataPath},
+	} {
+		data, err := stripConfigFile(f.path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, bundleFile{name: f.name, data: data})
+	}
+
+	seen := make(map[string]bool)
+	for _, dir := range commandDirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(strings.ToLower(d.Name()), ".md") {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			name := configCommandsDir + filepath.ToSlash(rel)
+			// The first directories take precedence, like when loading the
+			// commands.
+			if seen[name] {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			seen[name] = true
+			files = append(files, bundleFile{name: name, data: data})
+			return nil
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read commands: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// stripConfigFile returns the configuration file without credentials.
+func stripConfigFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	stripSecrets(tree, false)
+	return json.MarshalIndent(tree, "", "  ")
+}
+
+// stripSecrets removes the credentials from the configuration, the way
+// redactTree redacts them. References to environment variables are kept,
+// they let each machine provide its own credentials.
+func stripSecrets(node map[string]any, sensitive bool) {
+	for key, value := range node {
+		sensitive := sensitive || sensitiveConfigKey.MatchString(key)
+		switch value := value.(type) {
+		case map[string]any:
+			stripSecrets(value, sensitive || slices.Contains(sensitiveConfigMaps, key))
+		case []any:
+			kept := value[:0]
+			for _, item := range value {
+				switch item := item.(type) {
+				case map[string]any:
+					stripSecrets(item, sensitive)
+				case string:
+					if sensitive && isSecretValue(item) {
+						continue
+					}
+				}
+				kept = append(kept, item)
+			}
+			node[key] = kept
+		case string:
+			if sensitive && isSecretValue(value) {
+				delete(node, key)
+			}
+		}
+	}
+}
+
+func isSecretValue(s string) bool {
+	return s != "" && !strings.HasPrefix(s, "$")
+}
+
+// configDests are where the files of a config bundle are imported.
+type configDests struct {
+	config   string
+	data     string
+	commands string
+}
+
+type importedFile struct {
+	path    string
+	skipped bool
+}
+
+// importConfigBundle writes the files of the bundle to their destinations,
+// keeping the existing ones unless force is set.
+func importConfigBundle(bundlePath string, dests configDests, force bool) ([]importedFile, error) {
+	zr, err := zip.OpenReader(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle: %w", err)
+	}
+	defer zr.Close()
+
+	if err := checkConfigManifest(&zr.Reader); err != nil {
+		return nil, err
+	}
+
+	var imported []importedFile
+	for _, f := range zr.File {
+		dest, err := configBundleDest(f.Name, dests)
+		if err != nil {
+			return imported, err
+		}
+		if dest == "" {
+			continue
+		}
+		if !force {
+			if _, err := os.Stat(dest); err == nil {
+				imported = append(imported, importedFile{path: dest, skipped: true})
+				continue
+			}
+		}
+		if err := extractBundleFile(f, dest); err != nil {
+			return imported, err
+		}
+		imported = append(imported, importedFile{path: dest})
+	}
+	return imported, nil
+}
+
+func checkConfigManifest(zr *zip.Reader) error {
+	f, err := zr.Open(configManifestName)
+	if err != nil {
+		return errors.New("not a configuration bundle, it has no manifest")
+	}
+	defer f.Close()
+	var manifest configManifest
+	if err := json.NewDecoder(f).Decode(&manifest); err != nil {
+		return fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if manifest.Version > configBundleVersion {
+		return fmt.Errorf("the bundle was exported by a newer version of crush (%s), update crush to import it", manifest.CrushVersion)
+	}
+	return nil
+}
+
+// configBundleDest returns where the file of the bundle goes, the empty
+// string for the files that aren't imported.
+func configBundleDest(name string, dests configDests) (string, error) {
+	switch {
+	case name == configFileName:
+		return dests.config, nil
+	case name == configDataName:
+		return dests.data, nil
+	case strings.HasPrefix(name, configCommandsDir) && !strings.HasSuffix(name, "/"):
+		rel := strings.TrimPrefix(name, configCommandsDir)
+		if !filepath.IsLocal(filepath.FromSlash(rel)) || path.Clean(rel) != rel {
+			return "", fmt.Errorf("invalid command path %q in bundle", name)
+		}
+		if dests.commands == "" {
+			return "", nil
+		}
+		return filepath.Join(dests.commands, filepath.FromSlash(rel)), nil
+	}
+	return "", nil
+}
+
+func extractBundleFile(f *zip.File, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	defer r.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dest, err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripSecrets(t *testing.T) {
+	t.Parallel()
+
+	tree := map[string]any{
+		"providers": map[string]any{
+			"openai": map[string]any{
+				"api_key":       "sk-secret",
+				"extra_headers": map[string]any{"X-Team": "team-secret", "X-Org": "$ORG"},
+				"base_url":      "https://api.openai.com/v1",
+			},
+			"anthropic": map[string]any{"api_key": "$ANTHROPIC_API_KEY"},
+		},
+		"mcp": map[string]any{
+			"github": map[string]any{
+				"env":  map[string]any{"GITHUB_TOKEN": "ghp_secret"},
+				"args": []any{"serve"},
+			},
+		},
+		"options": map[string]any{"tui": map[string]any{"compact_mode": true}},
+	}
+
+	stripSecrets(tree, false)
+	providers := tree["providers"].(map[string]any)
+	require.Equal(t, map[string]any{
+		"extra_headers": map[string]any{"X-Org": "$ORG"},
+		"base_url":      "https://api.openai.com/v1",
+	}, providers["openai"])
+	require.Equal(t, map[string]any{"api_key": "$ANTHROPIC_API_KEY"}, providers["anthropic"])
+
+	github := tree["mcp"].(map[string]any)["github"].(map[string]any)
+	require.Equal(t, map[string]any{}, github["env"])
+	require.Equal(t, []any{"serve"}, github["args"])
+	require.Equal(t, map[string]any{"tui": map[string]any{"compact_mode": true}}, tree["options"])
+}
+
+func TestConfigBundle(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	configPath := filepath.Join(src, "config", "crush.json")
+	commandsDir := filepath.Join(src, "commands")
+	homeCommandsDir := filepath.Join(src, "home-commands")
+	writeFile(t, configPath, `{"providers": {"openai": {"api_key": "sk-secret"}}, "options": {"debug": true}}`)
+	writeFile(t, filepath.Join(commandsDir, "review.md"), "Review $FILE")
+	writeFile(t, filepath.Join(commandsDir, "git", "commit.md"), "Commit the changes")
+	writeFile(t, filepath.Join(homeCommandsDir, "review.md"), "Shadowed")
+	writeFile(t, filepath.Join(homeCommandsDir, "notes.txt"), "Not a command")
+
+	files, err := collectConfigBundle(configPath, filepath.Join(src, "missing.json"), []string{commandsDir, homeCommandsDir, filepath.Join(src, "missing")})
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.name)
+	}
+	require.Equal(t, []string{configManifestName, configFileName, "commands/git/commit.md", "commands/review.md"}, names)
+
+	bundle := filepath.Join(src, "bundle.zip")
+	require.NoError(t, writeBundle(bundle, files))
+
+	dst := t.TempDir()
+	dests := configDests{
+		config:   filepath.Join(dst, "config", "crush.json"),
+		data:     filepath.Join(dst, "data", "crush.json"),
+		commands: filepath.Join(dst, "commands"),
+	}
+	writeFile(t, filepath.Join(dests.commands, "review.md"), "Local review")
+
+	imported, err := importConfigBundle(bundle, dests, false)
+	require.NoError(t, err)
+	require.Equal(t, []importedFile{
+		{path: dests.config},
+		{path: filepath.Join(dests.commands, "git", "commit.md")},
+		{path: filepath.Join(dests.commands, "review.md"), skipped: true},
+	}, imported)
+
+	var cfg map[string]any
+	data, err := os.ReadFile(dests.config)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &cfg))
+	require.Equal(t, map[string]any{
+		"providers": map[string]any{"openai": map[string]any{}},
+		"options":   map[string]any{"debug": true},
+	}, cfg)
+	requireFile(t, filepath.Join(dests.commands, "review.md"), "Local review")
+
+	_, err = importConfigBundle(bundle, dests, true)
+	require.NoError(t, err)
+	requireFile(t, filepath.Join(dests.commands, "review.md"), "Review $FILE")
+}
+
+func TestConfigBundleDest(t *testing.T) {
+	t.Parallel()
+
+	dests := configDests{config: "/c/crush.json", data: "/d/crush.json", commands: "/commands"}
+	for name, want := range map[string]string{
+		configFileName:           dests.config,
+		configDataName:           dests.data,
+		"commands/a/b.md":        filepath.Join("/commands", "a", "b.md"),
+		"commands/":              "",
+		"logs/crush.log":         "",
+		configManifestName:       "",
+		"commands/sub/../x.md":   "error",
+		"commands/../../evil.md": "error",
+	} {
+		dest, err := configBundleDest(name, dests)
+		if want == "error" {
+			require.Error(t, err, name)
+			continue
+		}
+		require.NoError(t, err, name)
+		require.Equal(t, want, dest, name)
+	}
+}
+
+func TestIsSensitivePath(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, isSensitivePath("providers.openai.api_key"))
+	require.True(t, isSensitivePath("mcp.github.env.GITHUB_TOKEN"))
+	require.True(t, isSensitivePath("providers.openai.extra_headers.X-Team"))
+	require.False(t, isSensitivePath("options.tui.compact_mode"))
+	require.False(t, isSensitivePath("mcp.github.env"))
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func requireFile(t *testing.T, path, content string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, content, string(data))
+}
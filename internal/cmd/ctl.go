@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/control"
+	"github.com/charmbracelet/crush/internal/instance"
+	"github.com/spf13/cobra"
+)
+
+var ctlCmd = &cobra.Command{
+	Use:   "ctl",
+	Short: "Control a running Crush instance",
+	Long: `Send commands to the interactive Crush instance running on the project,
+e.g. from editor keybindings or scripts. When several instances are running,
+choose one with --pid.`,
+}
+
+var ctlSendCmd = &cobra.Command{
+	Use:   "send [prompt...]",
+	Short: "Send a prompt to the current session of a running instance",
+	Long: `Send a prompt to the session open in a running Crush instance, as if it
+was typed in the editor. A new session is started when none is open.
+The prompt can be provided as arguments or piped from stdin.`,
+	Example: `
+# Ask the running instance to run the tests
+crush ctl send "run tests"
+
+# Send the output of a command along with the prompt
+go vet ./... 2>&1 | crush ctl send "fix these issues"
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prompt, err := MaybePrependStdin(strings.Join(args, " "))
+		if err != nil {
+			return fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		prompt = strings.TrimSpace(prompt)
+		if prompt == "" {
+			return fmt.Errorf("no prompt provided")
+		}
+
+		socket, err := resolveControlSocket(cmd)
+		if err != nil {
+			return err
+		}
+		_, err = control.Do(cmd.Context(), socket, control.Request{
+			Command: control.CommandSend,
+			Text:    prompt,
+		})
+		return err
+	},
+}
+
+var ctlStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Print the state of a running instance",
+	Example: `
+# Print the state of the running instance
+crush ctl status
+
+# Print it as JSON
+crush ctl status --json
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		socket, err := resolveControlSocket(cmd)
+		if err != nil {
+			return err
+		}
+		resp, err := control.Do(cmd.Context(), socket, control.Request{Command: control.CommandStatus})
+		if err != nil {
+			return err
+		}
+		if resp.Status == nil {
+			return errors.New("crush did not report its status")
+		}
+		if asJSON {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(resp.Status)
+		}
+		printControlStatus(cmd, *resp.Status)
+		return nil
+	},
+}
+
+func init() {
+	ctlCmd.PersistentFlags().Int("pid", 0, "Process ID of the instance to control")
+	ctlStatusCmd.Flags().Bool("json", false, "Print the status as JSON")
+	ctlCmd.AddCommand(ctlSendCmd, ctlStatusCmd)
+}
+
+func printControlStatus(cmd *cobra.Command, status control.Status) {
+	state := "idle"
+	if status.Busy {
+		state = "busy"
+	}
+	if status.QueuedPrompts > 0 {
+		state += fmt.Sprintf(" (%d queued)", status.QueuedPrompts)
+	}
+	session := "none"
+	if status.SessionID != "" {
+		session = fmt.Sprintf("%s (%s)", status.SessionTitle, status.SessionID)
+	}
+	cmd.Printf("PID:       %d\n", status.PID)
+	cmd.Printf("Directory: %s\n", status.WorkingDir)
+	cmd.Printf("Session:   %s\n", session)
+	if status.Model != "" {
+		cmd.Printf("Model:     %s\n", status.Model)
+	}
+	cmd.Printf("State:     %s\n", state)
+}
+
+// resolveControlSocket returns the control socket of the instance chosen with
+// --pid, or of the only instance running on the project.
+func resolveControlSocket(cmd *cobra.Command) (string, error) {
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return "", err
+	}
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	cfg, err := config.Load(cwd, dataDir, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to load configuration: %v", err)
+	}
+	dataDir = cfg.Options.DataDirectory
+
+	if pid, _ := cmd.Flags().GetInt("pid"); pid != 0 {
+		return control.SocketPath(dataDir, pid), nil
+	}
+
+	running, err := instance.Running(dataDir)
+	if err != nil {
+		return "", err
+	}
+	var sockets []string
+	var pids []string
+	for _, info := range running {
+		socket := control.SocketPath(dataDir, info.PID)
+		if _, err := os.Stat(socket); err != nil {
+			continue
+		}
+		sockets = append(sockets, socket)
+		pids = append(pids, strconv.Itoa(info.PID))
+	}
+	switch len(sockets) {
+	case 0:
+		return "", fmt.Errorf("no running crush instance found in %s", cfg.WorkingDir())
+	case 1:
+		return sockets[0], nil
+	default:
+		return "", fmt.Errorf("several crush instances are running (PIDs %s), choose one with --pid", strings.Join(pids, ", "))
+	}
+}
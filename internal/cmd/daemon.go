@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/daemon"
+	"github.com/charmbracelet/crush/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the agent in the background",
+	Long: `Run the agent in a process serving the sessions of the project on a socket
+in the data directory, so long tasks keep running once the terminal is closed.
+It's started in the background by crush run --detach, and followed with
+crush attach. Tools run without asking for permissions, as with crush run.`,
+	Example: `
+# Start a task in the background and follow it
+crush run --detach "Migrate the tests to testify"
+crush attach <session>
+
+# Stop the daemon and what it's doing
+crush daemon stop
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		if !app.Config().IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+		handler := server.New(ctx, server.Options{
+			Sessions:    app.Sessions,
+			Messages:    app.Messages,
+			Permissions: app.Permissions,
+			Agent:       app.AgentCoordinator,
+		})
+		return daemon.Serve(ctx, daemon.SocketPath(app.Config().Options.DataDirectory), handler)
+	},
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the daemon, cancelling what the agent is doing",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		socket, err := resolveDaemonSocket(cmd)
+		if err != nil {
+			return err
+		}
+		client := daemon.NewClient(socket)
+		if err := client.Ping(cmd.Context()); err != nil {
+			return errors.New("no crush daemon is running for this project")
+		}
+		return client.Stop(cmd.Context())
+	},
+}
+
+func init() {
+	daemonCmd.AddCommand(daemonStopCmd)
+}
+
+// resolveDaemonSocket returns the socket of the daemon of the project.
+func resolveDaemonSocket(cmd *cobra.Command) (string, error) {
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return "", err
+	}
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	cfg, err := config.Load(cwd, dataDir, false)
+	if err != nil {
+		return "", fmt.Errorf("failed to load configuration: %v", err)
+	}
+	return daemon.SocketPath(cfg.Options.DataDirectory), nil
+}
+
+// ensureDaemon starts the daemon of the project in the background unless it
+// is running, and returns a client of it.
+func ensureDaemon(cmd *cobra.Command) (*daemon.Client, error) {
+	socket, err := resolveDaemonSocket(cmd)
+	if err != nil {
+		return nil, err
+	}
+	client := daemon.NewClient(socket)
+	if client.Ping(cmd.Context()) == nil {
+		return client, nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the crush executable: %w", err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"daemon", "--cwd", cwd}
+	if dataDir, _ := cmd.Flags().GetString("data-dir"); dataDir != "" {
+		args = append(args, "--data-dir", dataDir)
+	}
+	if debug, _ := cmd.Flags().GetBool("debug"); debug {
+		args = append(args, "--debug")
+	}
+	if err := daemon.Start(cmd.Context(), socket, exe, args...); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
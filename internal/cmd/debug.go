@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/colorprofile"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/log"
+	"github.com/charmbracelet/crush/internal/secrets"
+	"github.com/charmbracelet/crush/internal/version"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// defaultBundleLogLines is how many of the latest log lines are bundled.
+	defaultBundleLogLines = 2000
+	// maxBundlePanics is how many of the latest panic logs are bundled.
+	maxBundlePanics = 3
+
+	redactedValue = "[REDACTED]"
+)
+
+// sensitiveConfigKey matches the configuration fields holding credentials.
+var sensitiveConfigKey = regexp.MustCompile(`(?i)(api_?key|(^|_)token$|secret|password)`)
+
+// sensitiveConfigMaps are the configuration maps whose values are all
+// redacted, their keys are kept.
+var sensitiveConfigMaps = []string{"env", "headers", "extra_headers"}
+
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Help with debugging Crush",
+}
+
+var debugBundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Collect what's needed to report a bug in a zip file",
+	Long: `Collect the latest logs, the configuration, the metadata of the latest
+provider requests, the version of the database and information about the
+terminal into a zip file to attach to a bug report.
+API keys, headers, environment variables and the secrets of the project are
+redacted. Each file is shown for review before it's added, unless --yes is
+given or the input isn't a terminal.`,
+	Example: `
+# Review the files and write the bundle in the current directory
+crush debug bundle
+
+# Write the bundle without review
+crush debug bundle --yes --output bug.zip
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		yes, _ := cmd.Flags().GetBool("yes")
+		logLines, _ := cmd.Flags().GetInt("log-lines")
+
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+		cfg, err := config.Load(cwd, dataDir, false)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %v", err)
+		}
+		vault, err := secrets.Open(cfg.Options.DataDirectory)
+		if err != nil {
+			// Without the secrets, they can't be redacted.
+			return fmt.Errorf("failed to open secrets: %w", err)
+		}
+
+		files := collectBundle(cmd, cfg, cwd, logLines)
+		for i := range files {
+			files[i].data = []byte(vault.Redact(string(files[i].data)))
+		}
+		if !yes && term.IsTerminal(os.Stdin.Fd()) {
+			if files, err = reviewBundle(cmd.InOrStdin(), cmd.OutOrStdout(), files); err != nil {
+				return err
+			}
+		}
+		if len(files) == 0 {
+			return errors.New("nothing to bundle")
+		}
+
+		if output == "" {
+			output = "crush-debug-" + time.Now().Format("20060102-150405") + ".zip"
+		}
+		if err := writeBundle(output, files); err != nil {
+			return err
+		}
+		cmd.Printf("Bundle written to %s, review it before attaching it to an issue.\n", output)
+		return nil
+	},
+}
+
+func init() {
+	debugBundleCmd.Flags().StringP("output", "o", "", "Path of the zip file to write")
+	debugBundleCmd.Flags().BoolP("yes", "y", false, "Include every file without review")
+	debugBundleCmd.Flags().Int("log-lines", defaultBundleLogLines, "Number of the latest log lines to include")
+	debugCmd.AddCommand(debugBundleCmd)
+}
+
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// collectBundle gathers the files of the bundle. What can't be read is
+// noted in the file instead, so the bundle shows what went wrong.
+func collectBundle(cmd *cobra.Command, cfg *config.Config, cwd string, logLines int) []bundleFile {
+	files := []bundleFile{
+		{name: "environment.txt", data: environmentInfo()},
+	}
+	add := func(name string, data []byte, err error) {
+		if err != nil {
+			data = fmt.Appendf(data, "error: %v\n", err)
+		}
+		files = append(files, bundleFile{name: name, data: data})
+	}
+
+	data, err := redactConfig(cfg)
+	add("config.json", data, err)
+
+	current, latest, err := db.SchemaVersion(cmd.Context(), cfg.Options.DataDirectory)
+	add("database.txt", fmt.Appendf(nil, "schema version: %d\nlatest version: %d\n", current, latest), err)
+
+	logsDir := filepath.Join(cfg.Options.DataDirectory, "logs")
+	data, err = tailFile(filepath.Join(logsDir, "crush.log"), logLines)
+	add("logs/crush.log", data, err)
+
+	if data, err := providerRequests(filepath.Join(logsDir, log.HTTPExchangesFile)); !errors.Is(err, os.ErrNotExist) {
+		add("provider-requests.json", data, err)
+	}
+
+	panics, _ := filepath.Glob(filepath.Join(cwd, "crush-panic-*.log"))
+	// The names end with a timestamp, so the latest sort last.
+	slices.Sort(panics)
+	for _, path := range panics[max(0, len(panics)-maxBundlePanics):] {
+		data, err := os.ReadFile(path)
+		add("panics/"+filepath.Base(path), data, err)
+	}
+	return files
+}
+
+// environmentInfo describes Crush, the system and the terminal.
+func environmentInfo() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "version: %s\n", version.Version)
+	fmt.Fprintf(&b, "os: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "go: %s\n", runtime.Version())
+	for _, name := range []string{"TERM", "COLORTERM", "TERM_PROGRAM", "TERM_PROGRAM_VERSION", "TMUX", "LANG", "LC_ALL", "SHELL"} {
+		if value, ok := os.LookupEnv(name); ok {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	fmt.Fprintf(&b, "color profile: %s\n", colorprofile.Detect(os.Stdout, os.Environ()))
+	if width, height, err := term.GetSize(os.Stdout.Fd()); err == nil {
+		fmt.Fprintf(&b, "terminal size: %dx%d\n", width, height)
+	}
+	fmt.Fprintf(&b, "stdin is a terminal: %t\n", term.IsTerminal(os.Stdin.Fd()))
+	fmt.Fprintf(&b, "stdout is a terminal: %t\n", term.IsTerminal(os.Stdout.Fd()))
+	return b.Bytes()
+}
+
+// redactConfig returns the configuration as JSON, without credentials.
+// References to environment variables, like $OPENAI_API_KEY, are kept as
+// they help finding out where a value comes from.
+func redactConfig(cfg *config.Config) ([]byte, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to decode configuration: %w", err)
+	}
+	return json.MarshalIndent(redactTree(tree, false), "", "  ")
+}
+
+func redactTree(node any, sensitive bool) any {
+	switch node := node.(type) {
+	case map[string]any:
+		for key, value := range node {
+			if _, ok := value.(map[string]any); ok && slices.Contains(sensitiveConfigMaps, key) {
+				node[key] = redactTree(value, true)
+				continue
+			}
+			node[key] = redactTree(value, sensitive || sensitiveConfigKey.MatchString(key))
+		}
+	case []any:
+		for i, value := range node {
+			node[i] = redactTree(value, sensitive)
+		}
+	case string:
+		if sensitive && node != "" && !strings.HasPrefix(node, "$") {
+			return redactedValue
+		}
+	}
+	return node
+}
+
+// tailFile returns the last lines of the file.
+func tailFile(path string, lines int) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	all := bytes.SplitAfter(data, []byte("\n"))
+	if len(all) > 0 && len(all[len(all)-1]) == 0 {
+		all = all[:len(all)-1]
+	}
+	return bytes.Join(all[max(0, len(all)-lines):], nil), nil
+}
+
+// providerRequests returns the metadata of the provider requests dumped by
+// the inspector, without their headers and bodies.
+func providerRequests(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var exchanges []log.HTTPExchange
+	if err := json.Unmarshal(data, &exchanges); err != nil {
+		return nil, fmt.Errorf("failed to decode provider requests: %w", err)
+	}
+	for i := range exchanges {
+		exchanges[i].RequestHeaders = nil
+		exchanges[i].ResponseHeaders = nil
+		exchanges[i].RequestBody = ""
+		exchanges[i].ResponseBody = ""
+	}
+	return json.MarshalIndent(exchanges, "", "  ")
+}
+
+// reviewBundle asks which files to include, showing them on request.
+func reviewBundle(in io.Reader, out io.Writer, files []bundleFile) ([]bundleFile, error) {
+	r := bufio.NewReader(in)
+	var kept []bundleFile
+	for _, f := range files {
+		for {
+			fmt.Fprintf(out, "Include %s (%d bytes)? [Y/n/v(iew)] ", f.name, len(f.data))
+			answer, err := r.ReadString('\n')
+			if err != nil && answer == "" {
+				return nil, fmt.Errorf("failed to read answer: %w", err)
+			}
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "", "y", "yes":
+				kept = append(kept, f)
+			case "n", "no":
+			case "v", "view":
+				fmt.Fprintf(out, "--- %s ---\n%s\n--- end of %s ---\n", f.name, f.data, f.name)
+				continue
+			default:
+				continue
+			}
+			break
+		}
+	}
+	return kept, nil
+}
+
+func writeBundle(path string, files []bundleFile) error {
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	zw := zip.NewWriter(out)
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err == nil {
+			_, err = w.Write(f.data)
+		}
+		if err != nil {
+			out.Close()
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
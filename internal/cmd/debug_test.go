@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactTree(t *testing.T) {
+	t.Parallel()
+
+	tree := map[string]any{
+		"providers": map[string]any{
+			"openai": map[string]any{
+				"api_key":       "sk-secret",
+				"extra_headers": map[string]any{"X-Team": "team-secret"},
+				"base_url":      "https://api.openai.com/v1",
+			},
+			"anthropic": map[string]any{"api_key": "$ANTHROPIC_API_KEY"},
+		},
+		"mcp": map[string]any{
+			"github": map[string]any{
+				"env":  map[string]any{"GITHUB_TOKEN": "ghp_secret"},
+				"args": []any{"serve"},
+			},
+		},
+		"models": map[string]any{"large": map[string]any{"max_tokens": float64(4096)}},
+	}
+
+	redactTree(tree, false)
+	providers := tree["providers"].(map[string]any)
+	openai := providers["openai"].(map[string]any)
+	require.Equal(t, redactedValue, openai["api_key"])
+	require.Equal(t, map[string]any{"X-Team": redactedValue}, openai["extra_headers"])
+	require.Equal(t, "https://api.openai.com/v1", openai["base_url"])
+	require.Equal(t, "$ANTHROPIC_API_KEY", providers["anthropic"].(map[string]any)["api_key"])
+
+	github := tree["mcp"].(map[string]any)["github"].(map[string]any)
+	require.Equal(t, map[string]any{"GITHUB_TOKEN": redactedValue}, github["env"])
+	require.Equal(t, []any{"serve"}, github["args"])
+	require.Equal(t, float64(4096), tree["models"].(map[string]any)["large"].(map[string]any)["max_tokens"])
+}
+
+func TestTailFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "crush.log")
+	require.NoError(t, os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0o600))
+
+	data, err := tailFile(path, 2)
+	require.NoError(t, err)
+	require.Equal(t, "two\nthree\n", string(data))
+
+	data, err = tailFile(path, 10)
+	require.NoError(t, err)
+	require.Equal(t, "one\ntwo\nthree\n", string(data))
+}
+
+func TestReviewBundle(t *testing.T) {
+	t.Parallel()
+
+	files := []bundleFile{
+		{name: "environment.txt", data: []byte("os: linux")},
+		{name: "config.json", data: []byte("{}")},
+		{name: "logs/crush.log", data: []byte("started")},
+	}
+	var out bytes.Buffer
+	kept, err := reviewBundle(strings.NewReader("\nv\nn\nmaybe\ny\n"), &out, files)
+	require.NoError(t, err)
+	require.Len(t, kept, 2)
+	require.Equal(t, "environment.txt", kept[0].name)
+	require.Equal(t, "logs/crush.log", kept[1].name)
+	require.Contains(t, out.String(), "--- config.json ---\n{}\n")
+
+	_, err = reviewBundle(strings.NewReader(""), &out, files)
+	require.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/tui/actions"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/termcap"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check what the terminal supports",
+	Long: `Check what the terminal supports and how Crush adapts to it: how many
+colors it shows and the theme used for them, and whether the mouse and
+hyperlinks are used.`,
+	Example: `
+# Check the terminal
+crush doctor
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		// A broken configuration is reported rather than hiding the
+		// terminal checks, which then use the default options.
+		var opts config.TUIOptions
+		var rows [][]string
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+		if cfg, err := config.Load(cwd, dataDir, false); err != nil {
+			rows = append(rows, []string{"Configuration", "failed to load", err.Error()})
+		} else {
+			opts = *cfg.Options.TUI
+		}
+
+		caps := termcap.Detect(os.Stdout, os.Environ())
+		headers := []string{"Check", "Status", "Notes"}
+		return printTable(cmd, headers, append(rows, doctorRows(caps, opts)...))
+	},
+}
+
+func doctorRows(caps termcap.Capabilities, opts config.TUIOptions) [][]string {
+	terminal := caps.Term
+	if terminal == "" {
+		terminal = "unknown"
+	}
+	if caps.Program != "" {
+		terminal += " (" + caps.Program + ")"
+	}
+
+	var colors string
+	switch n := caps.Colors(); n {
+	case 0:
+		colors = "none"
+	case 1 << 24:
+		colors = "true color"
+	default:
+		colors = fmt.Sprintf("%d colors", n)
+	}
+
+	mouse, mouseNotes := enabledStatus(opts.MouseEnabled(caps.Mouse), opts.Mouse != nil), ""
+	if !opts.MouseEnabled(caps.Mouse) {
+		mouseNotes = fmt.Sprintf("%s and %s resize the sidebar, c or y copies the focused message",
+			actions.Shortcut(actions.GrowSidebar), actions.Shortcut(actions.ShrinkSidebar))
+	} else if !caps.Mouse {
+		mouseNotes = "the terminal may not report mouse events"
+	}
+
+	return [][]string{
+		{"Terminal", terminal, ""},
+		{"Colors", colors, "theme " + styles.ThemeForProfile(caps.Profile)},
+		{"Mouse", mouse, mouseNotes},
+		{"Hyperlinks", enabledStatus(caps.Hyperlinks && opts.HyperlinksEnabled(), !opts.HyperlinksEnabled()), ""},
+	}
+}
+
+// enabledStatus describes whether a feature is enabled, and whether that's
+// decided by the configuration rather than detected from the terminal.
+func enabledStatus(enabled, configured bool) string {
+	status := "disabled"
+	if enabled {
+		status = "enabled"
+	}
+	if configured {
+		status += " in config"
+	}
+	return status
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/colorprofile"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/tui/termcap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoctorRows(t *testing.T) {
+	t.Parallel()
+
+	full := termcap.Capabilities{Term: "xterm-ghostty", Program: "ghostty", Profile: colorprofile.TrueColor, Mouse: true, Hyperlinks: true}
+	require.Equal(t, [][]string{
+		{"Terminal", "xterm-ghostty (ghostty)", ""},
+		{"Colors", "true color", "theme charmtone"},
+		{"Mouse", "enabled", ""},
+		{"Hyperlinks", "enabled", ""},
+	}, doctorRows(full, config.TUIOptions{}))
+
+	off := false
+	rows := doctorRows(full, config.TUIOptions{Mouse: &off, Hyperlinks: &off})
+	require.Equal(t, "disabled in config", rows[2][1])
+	require.Contains(t, rows[2][2], "resize the sidebar")
+	require.Equal(t, "disabled in config", rows[3][1])
+
+	basic := termcap.Capabilities{Term: "linux", Profile: colorprofile.ANSI}
+	rows = doctorRows(basic, config.TUIOptions{})
+	require.Equal(t, []string{"Colors", "16 colors", "theme ansi"}, rows[1])
+	require.Equal(t, "disabled", rows[2][1])
+	require.Equal(t, "disabled", rows[3][1])
+
+	on := true
+	rows = doctorRows(basic, config.TUIOptions{Mouse: &on})
+	require.Equal(t, []string{"Mouse", "enabled in config", "the terminal may not report mouse events"}, rows[2])
+}
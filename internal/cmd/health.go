@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/health"
+	"github.com/spf13/cobra"
+)
+
+var healthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Check the status pages of providers",
+	Long: `Check the status pages of the providers that publish one, to find out
+whether failing requests are caused by an incident on their side.`,
+	Example: `
+# Check the status of the providers
+crush health
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := ResolveCwd(cmd)
+		if err != nil {
+			return err
+		}
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+		cfg, err := config.Load(cwd, dataDir, false)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %v", err)
+		}
+
+		if cfg.Options.Offline {
+			return fmt.Errorf("offline mode: not checking the status pages of providers")
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+		defer cancel()
+
+		checker := health.NewService(http.DefaultClient)
+		headers := []string{"Provider", "Configured", "Status", "Page"}
+		return printTable(cmd, headers, healthRows(ctx, cfg, checker))
+	},
+}
+
+func healthRows(ctx context.Context, cfg *config.Config, checker health.Service) [][]string {
+	var rows [][]string
+	for _, id := range slices.Sorted(maps.Keys(health.StatusPages)) {
+		name, configured := id, "no"
+		if providerCfg, ok := cfg.Providers.Get(id); ok && !providerCfg.Disable {
+			configured = "yes"
+			if providerCfg.Name != "" {
+				name = providerCfg.Name
+			}
+		}
+
+		status, err := checker.Check(ctx, id)
+		if err != nil {
+			rows = append(rows, []string{name, configured, "Unknown: " + err.Error(), health.StatusPages[id]})
+			continue
+		}
+		rows = append(rows, []string{name, configured, status.Description, status.URL})
+	}
+	return rows
+}
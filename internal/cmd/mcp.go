@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/charmbracelet/crush/internal/agent"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/mcpserver"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/secrets"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Use crush over the Model Context Protocol",
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the built-in tools as an MCP server over stdio",
+	Long: `Serve the built-in tools of crush, the shell, file editing and search tools,
+as a Model Context Protocol server over stdio, so other clients such as IDEs
+or desktop assistants can use them.
+The tools are the ones the configuration gives the agent, disabled and
+offline tools left out, and run the way they do for the agent: in a session
+of their own, with the secrets of the project, logged to the audit log and
+under the permissions of the configuration. The other permissions are asked
+of the user through the client when it supports it, and denied otherwise.`,
+	Example: `
+# Serve the tools of the current project
+crush mcp serve
+
+# Serve the tools of another project, without asking for permissions
+crush mcp serve --cwd /path/to/project --yolo
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		yolo, _ := cmd.Flags().GetBool("yolo")
+		readOnly, _ := cmd.Flags().GetBool("read-only")
+		ctx := cmd.Context()
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		cfg := st.cfg
+		if cfg.Permissions == nil {
+			cfg.Permissions = &config.Permissions{}
+		}
+		cfg.Permissions.SkipRequests = yolo
+		cfg.Options.ReadOnly = readOnly
+		if cfg.Agents == nil {
+			// The agents are only set up along with the providers, which
+			// the tools don't need.
+			cfg.SetupAgents()
+		}
+		agentCfg := cfg.Agents[config.AgentCoder]
+		// Serve the built-in tools only, the clients reach the MCP servers
+		// on their own.
+		agentCfg.AllowedMCP = map[string][]string{}
+
+		permissions := permission.NewPermissionService(cfg.WorkingDir(), cfg.Permissions.SkipRequests, cfg.Permissions.AllowedTools)
+		vault, err := secrets.Open(cfg.Options.DataDirectory)
+		if err != nil {
+			slog.Warn("Failed to open secrets", "error", err)
+		}
+
+		sess, err := st.sessions.Create(ctx, "MCP tools")
+		if err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+
+		toolset := agent.NewToolset(agent.ToolsetOptions{
+			Config:      cfg,
+			Permissions: permissions,
+			History:     st.files,
+			Artifacts:   st.artifacts,
+			Facts:       st.facts,
+			ToolStats:   st.toolStats,
+			Audit:       st.audit,
+			Secrets:     vault,
+			LSPClients:  csync.NewMap[string, *lsp.Client](),
+		})
+		server := mcpserver.New(mcpserver.Options{
+			Tools:       toolset.Serve(ctx, agentCfg),
+			Permissions: permissions,
+			SessionID:   sess.ID,
+		})
+		return server.Run(ctx, &mcp.StdioTransport{})
+	},
+}
+
+func init() {
+	mcpServeCmd.Flags().BoolP("yolo", "y", false, "Run the tools without asking for permissions (dangerous mode)")
+	mcpServeCmd.Flags().BoolP("read-only", "r", false, "Describe changes instead of applying them")
+	mcpCmd.AddCommand(mcpServeCmd)
+}
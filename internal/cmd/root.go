@@ -18,6 +18,8 @@ import (
 	"github.com/charmbracelet/crush/internal/db"
 	"github.com/charmbracelet/crush/internal/event"
 	"github.com/charmbracelet/crush/internal/tui"
+	"github.com/charmbracelet/crush/internal/tui/termcap"
+	"github.com/charmbracelet/crush/internal/tui/width"
 	"github.com/charmbracelet/crush/internal/version"
 	"github.com/charmbracelet/fang"
 	"github.com/charmbracelet/lipgloss/v2"
@@ -30,16 +32,36 @@ func init() {
 	rootCmd.PersistentFlags().StringP("cwd", "c", "", "Current working directory")
 	rootCmd.PersistentFlags().StringP("data-dir", "D", "", "Custom crush data directory")
 	rootCmd.PersistentFlags().BoolP("debug", "d", false, "Debug")
+	rootCmd.PersistentFlags().Bool("offline", false, "Only use local providers and never reach the internet")
 
 	rootCmd.Flags().BoolP("help", "h", false, "Help")
 	rootCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+	rootCmd.Flags().BoolP("read-only", "r", false, "Describe changes instead of applying them, e.g. while another instance edits the project")
 
 	rootCmd.AddCommand(
 		runCmd,
 		dirsCmd,
+		configCmd,
 		updateProvidersCmd,
 		logsCmd,
 		schemaCmd,
+		applyCmd,
+		toolsCmd,
+		artifactsCmd,
+		auditCmd,
+		mcpCmd,
+		ctlCmd,
+		healthCmd,
+		syncCmd,
+		transcriptCmd,
+		secretsCmd,
+		debugCmd,
+		doctorCmd,
+		sessionsCmd,
+		serveCmd,
+		spendingCmd,
+		daemonCmd,
+		attachCmd,
 	)
 }
 
@@ -70,7 +92,20 @@ crush run "Explain the use of context in Go"
 
 # Run in dangerous mode (auto-accept all permissions)
 crush -y
+
+# Follow along without changing files while another instance works
+crush -r
+
+# Run in an air-gapped environment with local providers only
+crush --offline
   `,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Through the environment so that every command loading the
+		// configuration, and the processes they start, are offline.
+		if offline, _ := cmd.Flags().GetBool("offline"); offline {
+			os.Setenv("CRUSH_OFFLINE", "1")
+		}
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		app, err := setupApp(cmd)
 		if err != nil {
@@ -80,14 +115,24 @@ crush -y
 
 		event.AppInitialized()
 
+		// Measure ambiguous characters before the TUI takes over the
+		// terminal.
+		width.Apply(width.Mode(app.Config().Options.TUI.AmbiguousWidth))
+		caps := termcap.Detect(os.Stdout, os.Environ())
+
 		// Set up the TUI.
 		program := tea.NewProgram(
-			tui.New(app),
+			tui.New(app, caps),
 			tea.WithContext(cmd.Context()),
+			tea.WithFPS(app.Config().Options.TUI.Rendering.MaxFPS),
 			tea.WithFilter(tui.MouseEventFilter)) // Filter mouse events based on focus state
 
 		go app.Subscribe(program)
 
+		if err := app.ServeControl(); err != nil {
+			slog.Warn("Failed to start control socket", "error", err)
+		}
+
 		if _, err := program.Run(); err != nil {
 			event.Error(err)
 			slog.Error("TUI run error", "error", err)
@@ -148,6 +193,7 @@ func Execute() {
 func setupApp(cmd *cobra.Command) (*app.App, error) {
 	debug, _ := cmd.Flags().GetBool("debug")
 	yolo, _ := cmd.Flags().GetBool("yolo")
+	readOnly, _ := cmd.Flags().GetBool("read-only")
 	dataDir, _ := cmd.Flags().GetString("data-dir")
 	ctx := cmd.Context()
 
@@ -165,6 +211,7 @@ func setupApp(cmd *cobra.Command) (*app.App, error) {
 		cfg.Permissions = &config.Permissions{}
 	}
 	cfg.Permissions.SkipRequests = yolo
+	cfg.Options.ReadOnly = readOnly
 
 	if err := createDotCrushDir(cfg.Options.DataDirectory); err != nil {
 		return nil, err
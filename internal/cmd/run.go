@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"strings"
 
+	"github.com/charmbracelet/crush/internal/shell"
 	"github.com/spf13/cobra"
 )
 
@@ -12,7 +15,12 @@ var runCmd = &cobra.Command{
 	Use:   "run [prompt...]",
 	Short: "Run a single non-interactive prompt",
 	Long: `Run a single prompt in non-interactive mode and exit.
-The prompt can be provided as arguments or piped from stdin.`,
+The prompt can be provided as arguments or piped from stdin.
+With --pipe, the response is streamed into the standard input of a shell
+command as it's generated, for live post-processing.
+With --detach, the prompt runs in the background daemon of the project,
+which is started if needed, and the session can be followed with
+crush attach.`,
 	Example: `
 # Run a simple prompt
 crush run Explain the use of context in Go
@@ -22,9 +30,28 @@ echo "What is this code doing?" | crush run
 
 # Run with quiet mode (no spinner)
 crush run -q "Generate a README for this project"
+
+# Render the response as it streams
+crush run --pipe "glow -" "Summarize the architecture of this project"
+
+# Give up after five minutes and print how the run ended as JSON
+crush run --timeout 5m --json "Fix the failing tests"
+
+# Run in the background and follow it later
+crush run --detach "Migrate the tests to testify"
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		quiet, _ := cmd.Flags().GetBool("quiet")
+		pipe, _ := cmd.Flags().GetString("pipe")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+
+		if detach, _ := cmd.Flags().GetBool("detach"); detach {
+			if pipe != "" || jsonOutput || timeout > 0 {
+				return fmt.Errorf("--detach can't be used with --pipe, --json or --timeout")
+			}
+			return runDetached(cmd, strings.Join(args, " "))
+		}
 
 		app, err := setupApp(cmd)
 		if err != nil {
@@ -48,11 +75,85 @@ crush run -q "Generate a README for this project"
 			return fmt.Errorf("no prompt provided")
 		}
 
-		// Run non-interactive flow using the App method
-		return app.RunNonInteractive(cmd.Context(), prompt, quiet)
+		ctx := cmd.Context()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		if pipe == "" {
+			// Run non-interactive flow using the App method
+			return app.RunNonInteractive(ctx, prompt, quiet, jsonOutput, cmd.OutOrStdout())
+		}
+		return runPiped(cmd, pipe, app.Config().WorkingDir(), func(out io.Writer) error {
+			return app.RunNonInteractive(ctx, prompt, quiet, jsonOutput, out)
+		})
 	},
 }
 
 func init() {
 	runCmd.Flags().BoolP("quiet", "q", false, "Hide spinner")
+	runCmd.Flags().String("pipe", "", "Stream the response into the standard input of a shell command")
+	runCmd.Flags().Bool("json", false, "Print the session as JSON once the run ends, with why it was cancelled if it was")
+	runCmd.Flags().Duration("timeout", 0, "Cancel the run after this long, 0 for no limit")
+	runCmd.Flags().Bool("detach", false, "Run the prompt in the background daemon and print its session")
+}
+
+// runDetached sends the prompt to the daemon of the project in a new
+// session, and prints how to follow it.
+func runDetached(cmd *cobra.Command, prompt string) error {
+	prompt, err := MaybePrependStdin(prompt)
+	if err != nil {
+		return fmt.Errorf("failed to read from stdin: %w", err)
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return fmt.Errorf("no prompt provided")
+	}
+
+	client, err := ensureDaemon(cmd)
+	if err != nil {
+		return err
+	}
+	sess, err := client.CreateSession(cmd.Context(), detachedTitle(prompt))
+	if err != nil {
+		return err
+	}
+	if err := client.Prompt(cmd.Context(), sess.ID, prompt); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), sess.ID)
+	fmt.Fprintf(cmd.ErrOrStderr(), "Running in the background, follow it with: crush attach %s\n", sess.ID)
+	return nil
+}
+
+// detachedTitle returns the title of the session of a detached run, made of
+// the start of its prompt.
+func detachedTitle(prompt string) string {
+	const maxTitleLength = 100
+	if runes := []rune(prompt); len(runes) > maxTitleLength {
+		prompt = string(runes[:maxTitleLength]) + "..."
+	}
+	return "Background: " + prompt
+}
+
+// runPiped runs the shell command with what run writes as its standard
+// input, and waits for it to finish once run is done.
+func runPiped(cmd *cobra.Command, command, workingDir string, run func(out io.Writer) error) error {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		sh := shell.NewShell(&shell.Options{WorkingDir: workingDir})
+		err := sh.Run(cmd.Context(), command, pr, cmd.OutOrStdout(), cmd.ErrOrStderr())
+		// Unblock run if the command exits before reading everything.
+		pr.Close()
+		done <- err
+	}()
+
+	err := run(pw)
+	pw.Close()
+	if pipeErr := <-done; pipeErr != nil && err == nil {
+		return fmt.Errorf("pipe command failed: %w", pipeErr)
+	}
+	return err
 }
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetachedTitle(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "Background: fix it", detachedTitle("fix it"))
+	got := detachedTitle(strings.Repeat("日", 101))
+	require.True(t, utf8.ValidString(got))
+	require.Equal(t, "Background: "+strings.Repeat("日", 100)+"...", got)
+}
+
+func TestRunPiped(t *testing.T) {
+	t.Parallel()
+
+	newCmd := func() (*cobra.Command, *bytes.Buffer) {
+		cmd := &cobra.Command{}
+		cmd.SetContext(context.Background())
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		return cmd, &out
+	}
+
+	t.Run("streams into the command", func(t *testing.T) {
+		t.Parallel()
+		cmd, out := newCmd()
+		err := runPiped(cmd, "tr a-z A-Z", t.TempDir(), func(w io.Writer) error {
+			fmt.Fprint(w, "hello ")
+			fmt.Fprint(w, "world")
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, "HELLO WORLD", out.String())
+	})
+
+	t.Run("command exits early", func(t *testing.T) {
+		t.Parallel()
+		cmd, _ := newCmd()
+		err := runPiped(cmd, "true", t.TempDir(), func(w io.Writer) error {
+			_, err := io.Copy(w, strings.NewReader(strings.Repeat("x", 1<<20)))
+			require.Error(t, err)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("command fails", func(t *testing.T) {
+		t.Parallel()
+		cmd, _ := newCmd()
+		err := runPiped(cmd, "exit 3", t.TempDir(), func(w io.Writer) error {
+			return nil
+		})
+		require.ErrorContains(t, err, "pipe command failed")
+	})
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/secrets"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage the secrets of the project",
+	Long: `Secrets are credentials such as database URLs or API tokens that the
+commands run by the agent need. They're kept encrypted in the data directory
+of the project and given to shell commands as environment variables. Their
+values are redacted from tool output, so the model never sees them.`,
+}
+
+var secretsSetCmd = &cobra.Command{
+	Use:   "set <name> [value]",
+	Short: "Set a secret",
+	Long: `Set a secret. Without a value, it's read from the standard input, or asked
+for without echoing it when the input is a terminal.`,
+	Example: `
+# Ask for the value of a secret
+crush secrets set DB_URL
+
+# Read the value from a file
+crush secrets set API_TOKEN < token.txt
+  `,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vault, err := openVault(cmd)
+		if err != nil {
+			return err
+		}
+		var value string
+		if len(args) > 1 {
+			value = args[1]
+		} else if value, err = readSecret(cmd, args[0]); err != nil {
+			return err
+		}
+		if err := vault.Set(args[0], value); err != nil {
+			return err
+		}
+		cmd.PrintErrf("Secret %s set.\n", args[0])
+		return nil
+	},
+}
+
+var secretsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the names of the secrets",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vault, err := openVault(cmd)
+		if err != nil {
+			return err
+		}
+		names := vault.Names()
+		if len(names) == 0 {
+			cmd.Println("No secrets set yet.")
+			return nil
+		}
+		for _, name := range names {
+			cmd.Println(name)
+		}
+		return nil
+	},
+}
+
+var secretsUnsetCmd = &cobra.Command{
+	Use:   "unset <name>",
+	Short: "Remove a secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vault, err := openVault(cmd)
+		if err != nil {
+			return err
+		}
+		if err := vault.Delete(args[0]); errors.Is(err, secrets.ErrNotFound) {
+			return fmt.Errorf("secret %q not found", args[0])
+		} else if err != nil {
+			return err
+		}
+		cmd.PrintErrf("Secret %s removed.\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsSetCmd, secretsListCmd, secretsUnsetCmd)
+}
+
+// openVault opens the secrets of the project the command runs in.
+func openVault(cmd *cobra.Command) (*secrets.Vault, error) {
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return nil, err
+	}
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	cfg, err := config.Load(cwd, dataDir, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %v", err)
+	}
+	return secrets.Open(cfg.Options.DataDirectory)
+}
+
+// readSecret reads the value of a secret from the standard input.
+func readSecret(cmd *cobra.Command, name string) (string, error) {
+	if term.IsTerminal(os.Stdin.Fd()) {
+		cmd.PrintErrf("Value of %s: ", name)
+		value, err := term.ReadPassword(os.Stdin.Fd())
+		cmd.PrintErrln()
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret: %w", err)
+		}
+		return string(value), nil
+	}
+	value, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return strings.TrimRight(string(value), "\r\n"), nil
+}
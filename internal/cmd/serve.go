@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve sessions and agent streams over HTTP",
+	Long: `Serve the sessions of the project over an HTTP API, so other programs can
+list them, send prompts to the agent and follow its responses as they stream
+with server-sent events.
+Tools run without asking for permissions, as with crush run, so clients must
+send a token as a bearer token. Without --token or CRUSH_SERVE_TOKEN a random
+token is generated and printed. POST requests must be JSON.`,
+	Example: `
+# Serve on the default address
+crush serve
+
+# Follow the agent and send it a prompt
+curl -N -H "Authorization: Bearer $TOKEN" localhost:7600/sessions/<id>/events
+curl -H "Authorization: Bearer $TOKEN" -H "Content-Type: application/json" \
+  -d '{"prompt": "run the tests"}' localhost:7600/sessions/<id>/prompt
+
+# Serve on every interface
+CRUSH_SERVE_TOKEN=secret crush serve --addr :7600
+  `,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		token, _ := cmd.Flags().GetString("token")
+		if token == "" {
+			token = os.Getenv("CRUSH_SERVE_TOKEN")
+		}
+		generated := token == ""
+		if generated {
+			var err error
+			if token, err = randomToken(); err != nil {
+				return err
+			}
+		}
+
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		if !app.Config().IsConfigured() {
+			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
+		}
+
+		ctx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+		srv := &http.Server{
+			Addr: addr,
+			Handler: server.New(ctx, server.Options{
+				Sessions:    app.Sessions,
+				Messages:    app.Messages,
+				Permissions: app.Permissions,
+				Agent:       app.AgentCoordinator,
+				Token:       token,
+				Remote:      !isLoopback(addr),
+			}),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Serving on http://%s\n", listener.Addr())
+		if generated {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Token: %s\n", token)
+		}
+
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				slog.Warn("Failed to shut down the server", "error", err)
+			}
+		}()
+		if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("addr", "127.0.0.1:7600", "Address to listen on")
+	serveCmd.Flags().String("token", "", "Token clients must send, defaults to $CRUSH_SERVE_TOKEN or a random one")
+}
+
+// randomToken returns a token for clients to authenticate with.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate a token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isLoopback reports whether the address only listens on the local machine.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
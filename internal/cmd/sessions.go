@@ -0,0 +1,358 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/archive"
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/importer"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/sessionfork"
+	"github.com/charmbracelet/crush/internal/sessionmerge"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Work with the sessions of the project",
+}
+
+var sessionsExportAllCmd = &cobra.Command{
+	Use:   "export-all",
+	Short: "Export every session to a directory",
+	Long: `Export every session of the project to a directory, one file per
+session, for backups or to feed a knowledge base. Each session gets its own
+directory, nested in the one of the session it was spawned from, with the
+artifacts of the session under artifacts/, and the directory gets an index of
+them in index.md and index.json.
+With --incremental, only the sessions updated since the last export to the
+directory are written again.`,
+	Example: `
+# Export all sessions as Markdown
+crush sessions export-all --dir out/
+
+# Keep a JSON backup up to date
+crush sessions export-all --dir backup/ --format json --incremental
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		format, _ := cmd.Flags().GetString("format")
+		incremental, _ := cmd.Flags().GetBool("incremental")
+		switch export.Format(format) {
+		case export.FormatMarkdown, export.FormatJSON:
+		default:
+			return fmt.Errorf("unknown format %q, expected %s or %s", format, export.FormatMarkdown, export.FormatJSON)
+		}
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		result, err := export.All(cmd.Context(), st.sessions, st.messages, dir, export.Options{
+			Format:      export.Format(format),
+			Incremental: incremental,
+			Artifacts:   st.artifacts,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Exported %d sessions to %s", result.Exported, dir)
+		if result.Artifacts > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), " with %d artifacts", result.Artifacts)
+		}
+		if result.Unchanged > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), ", %d unchanged", result.Unchanged)
+		}
+		fmt.Fprintln(cmd.OutOrStdout())
+		return nil
+	},
+}
+
+// formatSQLite is the format of the archives of sessions, a SQLite database
+// of the same schema as the one of the project.
+const formatSQLite = "sqlite"
+
+var sessionsExportCmd = &cobra.Command{
+	Use:   "export [session-id...]",
+	Short: "Export sessions to an archive",
+	Long: `Export sessions to a SQLite archive, a database of the same schema holding
+the sessions with their messages, tool outputs, file history and facts, to move
+them to another machine without losing anything. The sessions spawned from the
+ones exported come along, and all sessions are exported without IDs. Import the
+archive with crush sessions import --from sqlite.`,
+	Example: `
+# Archive the whole history of the project
+crush sessions export --format sqlite --out archive.db
+
+# Archive two sessions
+crush sessions export 3f2a... 9c1b... --format sqlite --out archive.db
+  `,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		out, _ := cmd.Flags().GetString("out")
+		if format != formatSQLite {
+			return fmt.Errorf("unknown format %q, expected %s, use export-all for markdown and json", format, formatSQLite)
+		}
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		result, err := archive.Export(cmd.Context(), st.conn, out, args)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Exported %d sessions with %d messages to %s\n", result.Sessions, result.Messages, out)
+		return nil
+	},
+}
+
+var sessionsImportCmd = &cobra.Command{
+	Use:   "import <path>",
+	Short: "Import the conversations of another coding assistant",
+	Long: `Import the conversations another coding assistant keeps on disk as new
+sessions of the project, keeping the roles, tool calls and timestamps where the
+format has them, or the sessions of an archive made by crush sessions export.
+  sqlite       an archive, the sessions the project has already are skipped
+  claude-code  a transcript, or a project directory of them, under ~/.claude/projects
+  aider        the .aider.chat.history.md file, or the project directory holding it
+  cursor       a state.vscdb database, or the directory holding it`,
+	Example: `
+# Import a Claude Code project
+crush sessions import --from claude-code ~/.claude/projects/-home-me-project
+
+# Import the aider history of the project
+crush sessions import --from aider .
+
+# Import an archive made on another machine
+crush sessions import --from sqlite archive.db
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, _ := cmd.Flags().GetString("from")
+		if from == formatSQLite {
+			return importArchive(cmd, args[0])
+		}
+		source := importer.Source(from)
+		if !slices.Contains(importer.Sources, source) {
+			return fmt.Errorf("unknown source %q, expected one of %s", from, sourceNames())
+		}
+
+		conversations, err := importer.Read(source, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read %s conversations: %w", source, err)
+		}
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		ids, err := importer.Import(cmd.Context(), st.conn, source, conversations)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Imported %d sessions from %s\n", len(ids), args[0])
+		return nil
+	},
+}
+
+var sessionsMergeCmd = &cobra.Command{
+	Use:   "merge <session-id> <session-id>",
+	Short: "Merge two sessions into a new one",
+	Long: `Merge two sessions into a new one, for work that was split across sessions
+by accident. The messages of the second session come after the ones of the
+first, or with --interleave in the order they were sent. Messages of the second
+session the first one has too are left out, and the token counts and costs of
+both add up. The sessions merged are kept, and the new session records where
+its messages came from.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		interleave, _ := cmd.Flags().GetBool("interleave")
+		title, _ := cmd.Flags().GetString("title")
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		result, err := sessionmerge.Merge(cmd.Context(), st.conn, args[0], args[1], sessionmerge.Options{
+			Interleave: interleave,
+			Title:      title,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Merged into session %s with %d messages", result.SessionID, result.Messages)
+		if result.Duplicates > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), ", %d duplicates left out", result.Duplicates)
+		}
+		fmt.Fprintln(cmd.OutOrStdout())
+		return nil
+	},
+}
+
+var sessionsForkCmd = &cobra.Command{
+	Use:   "fork <session-id>",
+	Short: "Copy a session into a new one to continue it another way",
+	Long: `Copy a session and its messages into a new session, to explore another
+continuation without changing the conversation. With --at-message, only the
+messages up to that one are copied. The new session keeps the environment of the
+session and records the session and message it was forked at.`,
+	Example: `
+# Fork a whole session
+crush sessions fork 3f2a...
+
+# Fork a session before it went the wrong way
+crush sessions fork 3f2a... --at-message 9c1b...
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		atMessage, _ := cmd.Flags().GetString("at-message")
+		title, _ := cmd.Flags().GetString("title")
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		result, err := sessionfork.Fork(cmd.Context(), st.conn, args[0], sessionfork.Options{
+			AtMessageID: atMessage,
+			Title:       title,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Forked into session %s with %d messages\n", result.SessionID, result.Messages)
+		return nil
+	},
+}
+
+var sessionsSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the messages of the sessions",
+	Long: `Search the messages of the sessions of the project, most relevant first,
+showing the part of each message around the matches. Words must all match,
+"quoted phrases" match as they are, a trailing * matches the words starting
+with it, and AND, OR, NOT and parentheses combine them.`,
+	Example: `
+# Messages about the parser
+crush sessions search parser
+
+# Messages about the parser or the lexer, but not about tests
+crush sessions search '(parser OR lexer) NOT test*'
+
+# An exact phrase in one session
+crush sessions search '"context deadline exceeded"' --session 3f2a...
+  `,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sessionID, _ := cmd.Flags().GetString("session")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		// The matches are marked with control characters, highlighted when
+		// printing to a terminal.
+		const start, end = "\x02", "\x03"
+		results, err := st.messages.Search(cmd.Context(), strings.Join(args, " "), message.SearchOptions{
+			SessionID:      sessionID,
+			Limit:          limit,
+			HighlightStart: start,
+			HighlightEnd:   end,
+		})
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No messages found")
+			return nil
+		}
+
+		highlight := strings.NewReplacer(start, "", end, "")
+		if term.IsTerminal(os.Stdout.Fd()) {
+			// Bold and underlined, back to normal.
+			highlight = strings.NewReplacer(start, "\x1b[1;4m", end, "\x1b[22;24m")
+		}
+		for _, result := range results {
+			created := time.Unix(result.CreatedAt, 0).Format("2006-01-02 15:04")
+			fmt.Fprintf(cmd.OutOrStdout(), "%s  %s  %s  %s\n", result.SessionTitle, result.SessionID, result.Role, created)
+			snippet := strings.Join(strings.Fields(result.Snippet), " ")
+			fmt.Fprintf(cmd.OutOrStdout(), "    %s\n\n", highlight.Replace(snippet))
+		}
+		return nil
+	},
+}
+
+func importArchive(cmd *cobra.Command, path string) error {
+	st, err := openStore(cmd)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	result, err := archive.Import(cmd.Context(), st.conn, path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Imported %d sessions with %d messages from %s", result.Sessions, result.Messages, path)
+	if result.Skipped > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), ", %d already there", result.Skipped)
+	}
+	fmt.Fprintln(cmd.OutOrStdout())
+	return nil
+}
+
+func sourceNames() string {
+	names := make([]string, len(importer.Sources))
+	for i, source := range importer.Sources {
+		names[i] = string(source)
+	}
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	sessionsExportAllCmd.Flags().String("dir", "", "Directory to export the sessions to")
+	sessionsExportAllCmd.Flags().String("format", string(export.FormatMarkdown), "Format of the session files: markdown or json")
+	sessionsExportAllCmd.Flags().Bool("incremental", false, "Only export the sessions updated since the last export to the directory")
+	_ = sessionsExportAllCmd.MarkFlagRequired("dir")
+	sessionsCmd.AddCommand(sessionsExportAllCmd)
+
+	sessionsExportCmd.Flags().String("format", formatSQLite, "Format of the archive: sqlite")
+	sessionsExportCmd.Flags().String("out", "", "Path of the archive, which must not exist")
+	_ = sessionsExportCmd.MarkFlagRequired("out")
+	sessionsCmd.AddCommand(sessionsExportCmd)
+
+	sessionsImportCmd.Flags().String("from", "", "Assistant the conversations come from: "+sourceNames()+", or sqlite for an archive")
+	_ = sessionsImportCmd.MarkFlagRequired("from")
+	sessionsCmd.AddCommand(sessionsImportCmd)
+
+	sessionsMergeCmd.Flags().Bool("interleave", false, "Order the messages of both sessions by the time they were sent")
+	sessionsMergeCmd.Flags().String("title", "", "Title of the merged session, the one of the first session by default")
+	sessionsCmd.AddCommand(sessionsMergeCmd)
+
+	sessionsForkCmd.Flags().String("at-message", "", "Last message copied to the fork, all of them by default")
+	sessionsForkCmd.Flags().String("title", "", "Title of the fork, the one of the session followed by (fork) by default")
+	sessionsCmd.AddCommand(sessionsForkCmd)
+
+	sessionsSearchCmd.Flags().String("session", "", "Only search the messages of this session")
+	sessionsSearchCmd.Flags().Int("limit", 20, "Maximum number of messages found")
+	sessionsCmd.AddCommand(sessionsSearchCmd)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/spending"
+	"github.com/spf13/cobra"
+)
+
+var spendingCmd = &cobra.Command{
+	Use:   "spending",
+	Short: "Print how much was spent on models",
+	Long: `Print how much was spent on models today, this week and this month,
+against the limits set in spending_limits, and the spending of the month by
+day and by model. Costs are computed from the prices of the models, or
+reported by the provider when it does.`,
+	Example: `
+# Print the spending report
+crush spending
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		now := time.Now()
+		records, err := st.spending.Since(ctx, spending.Since(now))
+		if err != nil {
+			return fmt.Errorf("failed to list spending: %w", err)
+		}
+		report := spending.NewReport(records, now)
+
+		limits := st.cfg.Options.SpendingLimits
+		if err := printTable(cmd, []string{"Period", "Spent", "Limit", "Used"}, spendingPeriodRows(report, limits)); err != nil {
+			return err
+		}
+		if len(report.Days) == 0 {
+			return nil
+		}
+		cmd.Println()
+		if err := printTable(cmd, []string{"Day", "Spent"}, spendingTotalRows(report.Days)); err != nil {
+			return err
+		}
+		cmd.Println()
+		return printTable(cmd, []string{"Model", "Spent this month"}, spendingTotalRows(report.Models))
+	},
+}
+
+func spendingPeriodRows(report spending.Report, limits config.SpendingLimits) [][]string {
+	names := map[spending.Period]string{
+		spending.Day:   "Today",
+		spending.Week:  "This week",
+		spending.Month: "This month",
+	}
+	rows := make([][]string, len(spending.Periods))
+	for i, period := range spending.Periods {
+		spent := report.Totals[period]
+		limit, used := "-", "-"
+		if l := period.Limit(limits); l > 0 {
+			limit = formatDollars(l)
+			used = fmt.Sprintf("%.0f%%", spent/l*100)
+		}
+		rows[i] = []string{names[period], formatDollars(spent), limit, used}
+	}
+	return rows
+}
+
+func spendingTotalRows(totals []spending.Total) [][]string {
+	rows := make([][]string, len(totals))
+	for i, total := range totals {
+		rows[i] = []string{total.Name, formatDollars(total.Cost)}
+	}
+	return rows
+}
+
+func formatDollars(amount float64) string {
+	return fmt.Sprintf("$%.2f", amount)
+}
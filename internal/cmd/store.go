@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/audit"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/fact"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/spending"
+	"github.com/charmbracelet/crush/internal/toolstats"
+	"github.com/spf13/cobra"
+)
+
+// store gives the commands that work on the stored data, without starting
+// the app, a single database connection and the services built on top of it.
+type store struct {
+	cfg  *config.Config
+	conn *sql.DB
+
+	sessions  session.Service
+	messages  message.Service
+	files     history.Service
+	artifacts artifact.Service
+	facts     fact.Service
+	toolStats toolstats.Service
+	audit     audit.Service
+	spending  spending.Service
+}
+
+// openStore loads the configuration and connects to the database of the
+// project the command runs in. The store must be closed when done.
+func openStore(cmd *cobra.Command) (*store, error) {
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return nil, err
+	}
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+	cfg, err := config.Load(cwd, dataDir, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %v", err)
+	}
+
+	conn, err := db.Connect(cmd.Context(), cfg.Options.DataDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	q := db.New(conn)
+	return &store{
+		cfg:       cfg,
+		conn:      conn,
+		sessions:  session.NewService(q),
+		messages:  message.NewService(q, conn),
+		files:     history.NewService(q, conn),
+		artifacts: artifact.NewService(q, cfg.Options.DataDirectory),
+		facts:     fact.NewService(q),
+		toolStats: toolstats.NewService(q),
+		audit:     audit.NewService(q),
+		spending:  spending.NewService(q),
+	}, nil
+}
+
+func (s *store) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/charmbracelet/crush/internal/sessionsync"
+	"github.com/spf13/cobra"
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync sessions with a remote",
+	Long: `Sync the sessions of the project with the remote configured under "sync",
+so the same conversation history is available on every machine.
+
+Sessions changed on this machine are pushed, the ones changed elsewhere are
+pulled, and the ones changed on both sides are merged message by message,
+keeping the most recently updated version of each. Sessions deleted on one
+side are deleted on the other unless they changed there since the last sync.`,
+	Example: `
+# Sync the sessions of the project
+crush sync
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+
+		if s.cfg.Sync == nil || s.cfg.Sync.Remote == "" {
+			return errors.New("no sync remote configured: set sync.remote in crush.json")
+		}
+		syncCfg := *s.cfg.Sync
+		project := syncCfg.Project
+		if project == "" {
+			project = filepath.Base(s.cfg.WorkingDir())
+		}
+
+		remote, err := sessionsync.Open(cmd.Context(), syncCfg, s.cfg.Resolver(), s.cfg.Options.DataDirectory)
+		if err != nil {
+			return err
+		}
+		syncer := sessionsync.New(s.conn, remote, syncCfg.Remote+"#"+project, project)
+		changes, err := syncer.Sync(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			cmd.Println("Sessions are up to date.")
+			return nil
+		}
+
+		rows := make([][]string, 0, len(changes))
+		for _, change := range changes {
+			rows = append(rows, []string{change.SessionID, change.Title, string(change.Action)})
+		}
+		return printTable(cmd, []string{"Session", "Title", "Change"}, rows)
+	},
+}
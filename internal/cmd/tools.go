@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/toolstats"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/lipgloss/v2/table"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var toolsCmd = &cobra.Command{
+	Use:   "tools",
+	Short: "Inspect the tools used by Crush",
+}
+
+var toolsStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Print execution metrics for each tool",
+	Long: `Print how often each tool ran across all sessions, how long it took,
+how much output it produced and how often it failed.`,
+	Example: `
+# Print tool execution metrics
+crush tools stats
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		stats, err := st.toolStats.List(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list tool stats: %w", err)
+		}
+		if len(stats) == 0 {
+			cmd.Println("No tool executions recorded yet.")
+			return nil
+		}
+
+		headers := []string{"Tool", "Runs", "Errors", "Avg time", "Max time", "Avg output"}
+		return printTable(cmd, headers, toolStatsRows(stats))
+	},
+}
+
+// printTable prints the rows as a table when attached to a terminal and as
+// tab-aligned columns otherwise.
+func printTable(cmd *cobra.Command, headers []string, rows [][]string) error {
+	if term.IsTerminal(os.Stdout.Fd()) {
+		// We're in a TTY: make it fancy.
+		t := table.New().
+			Border(lipgloss.RoundedBorder()).
+			StyleFunc(func(row, col int) lipgloss.Style {
+				return lipgloss.NewStyle().Padding(0, 1)
+			}).
+			Headers(headers...).
+			Rows(rows...)
+		lipgloss.Println(t)
+		return nil
+	}
+	// Not a TTY.
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	for _, row := range append([][]string{headers}, rows...) {
+		for i, cell := range row {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, cell)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+func toolStatsRows(stats []toolstats.Stat) [][]string {
+	rows := make([][]string, len(stats))
+	for i, stat := range stats {
+		rows[i] = []string{
+			stat.ToolName,
+			strconv.FormatInt(stat.Executions, 10),
+			fmt.Sprintf("%d (%.0f%%)", stat.Errors, stat.ErrorRate()*100),
+			stat.AvgDuration.Round(time.Millisecond).String(),
+			stat.MaxDuration.Round(time.Millisecond).String(),
+			formatBytes(stat.AvgOutputSize),
+		}
+	}
+	return rows
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func init() {
+	toolsCmd.AddCommand(toolsStatsCmd)
+}
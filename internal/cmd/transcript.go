@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/spf13/cobra"
+)
+
+// transcriptPollInterval is how often a followed session is checked for new
+// messages.
+const transcriptPollInterval = 500 * time.Millisecond
+
+var transcriptCmd = &cobra.Command{
+	Use:   "transcript [session-id]",
+	Short: "Print a plain text transcript of a session",
+	Long: `Print the conversation of a session as plain text, without colors or
+other terminal sequences, with each message prefixed by who wrote it.
+With --follow, the transcript keeps growing while the session is used in
+the TUI, so screen readers and log pipelines can follow along.
+If no session ID is given, the most recent session is used.`,
+	Example: `
+# Print the transcript of the latest session
+crush transcript
+
+# Follow a session while it's used in another terminal
+crush transcript 4f6c1b2e --follow
+  `,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		follow, _ := cmd.Flags().GetBool("follow")
+		ctx := cmd.Context()
+
+		st, err := openStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+
+		sess, err := resolveApplySession(ctx, st.sessions, args)
+		if err != nil {
+			return err
+		}
+
+		t := &transcript{out: cmd.OutOrStdout()}
+		list := func(ctx context.Context) ([]message.Message, error) {
+			return st.messages.List(ctx, sess.ID)
+		}
+		if follow {
+			return t.follow(ctx, list, transcriptPollInterval)
+		}
+		msgs, err := list(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+		t.write(msgs)
+		t.flush(msgs)
+		return nil
+	},
+}
+
+func init() {
+	transcriptCmd.Flags().BoolP("follow", "f", false, "Keep printing new messages as they arrive")
+}
+
+// transcript prints messages as plain text. Messages are written as they
+// grow, so the text of a streaming response is printed once, a piece at a
+// time, and never repeated.
+type transcript struct {
+	out io.Writer
+
+	// next is the index of the first message not completely printed.
+	next int
+	// id is the ID of that message, and text and calls how much of its text
+	// and tool calls were printed.
+	id    string
+	text  int
+	calls int
+	// started reports whether the role prefix of the message was printed.
+	started bool
+	// midLine reports whether the last line printed isn't terminated yet.
+	midLine bool
+}
+
+// follow prints the messages listed by list until ctx is done, checking for
+// new ones every interval.
+func (t *transcript) follow(ctx context.Context, list func(context.Context) ([]message.Message, error), interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		msgs, err := list(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+		t.write(msgs)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// write prints what's new in msgs since the last call. A message is only
+// considered complete when it's finished or followed by another one, until
+// then it can still grow.
+func (t *transcript) write(msgs []message.Message) {
+	for t.next < len(msgs) {
+		msg := msgs[t.next]
+		if msg.ID != t.id {
+			t.id, t.text, t.calls, t.started = msg.ID, 0, 0, false
+		}
+		complete := t.next < len(msgs)-1 || msg.Role != message.Assistant || msg.IsFinished()
+		t.writeMessage(msg, complete)
+		if !complete {
+			return
+		}
+		if t.started {
+			t.endLine()
+			fmt.Fprintln(t.out)
+		}
+		t.next++
+	}
+}
+
+// flush completes the last message, for when nothing more is coming.
+func (t *transcript) flush(msgs []message.Message) {
+	if t.next >= len(msgs) {
+		return
+	}
+	t.writeMessage(msgs[t.next], true)
+	if t.started {
+		t.endLine()
+	}
+	t.next++
+}
+
+func (t *transcript) writeMessage(msg message.Message, complete bool) {
+	switch msg.Role {
+	case message.User, message.Assistant:
+		prefix := "You"
+		if msg.Role == message.Assistant {
+			prefix = "Crush"
+		}
+		if text := ansi.Strip(msg.Content().Text); len(text) > t.text {
+			if !t.started {
+				t.print(prefix + ": ")
+				t.started = true
+			}
+			t.print(text[t.text:])
+			t.text = len(text)
+		}
+		for _, call := range msg.ToolCalls()[t.calls:] {
+			if !call.Finished && !complete {
+				break
+			}
+			t.endLine()
+			t.print("Tool: " + call.Name + "\n")
+			t.started = true
+			t.calls++
+		}
+	case message.Tool:
+		for _, result := range msg.ToolResults() {
+			t.started = true
+			if !result.IsError {
+				t.print("Tool: " + result.Name + " finished\n")
+				continue
+			}
+			reason, _, _ := strings.Cut(strings.TrimSpace(ansi.Strip(result.Content)), "\n")
+			t.print("Tool: " + result.Name + " failed: " + reason + "\n")
+		}
+	}
+}
+
+func (t *transcript) print(s string) {
+	if s == "" {
+		return
+	}
+	fmt.Fprint(t.out, s)
+	t.midLine = !strings.HasSuffix(s, "\n")
+}
+
+func (t *transcript) endLine() {
+	if t.midLine {
+		t.print("\n")
+	}
+}
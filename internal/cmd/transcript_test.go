@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscript(t *testing.T) {
+	t.Parallel()
+
+	user := message.Message{ID: "1", Role: message.User, Parts: []message.ContentPart{
+		message.TextContent{Text: "What's in main.go?"},
+	}}
+	call := message.Message{ID: "2", Role: message.Assistant, Parts: []message.ContentPart{
+		message.TextContent{Text: "Let me \x1b[1mlook\x1b[0m."},
+		message.ToolCall{ID: "a", Name: "view", Finished: true},
+	}}
+	result := message.Message{ID: "3", Role: message.Tool, Parts: []message.ContentPart{
+		message.ToolResult{ToolCallID: "a", Name: "view", Content: "package main"},
+	}}
+	streaming := message.Message{ID: "4", Role: message.Assistant, Parts: []message.ContentPart{
+		message.TextContent{Text: "The main"},
+	}}
+
+	var out bytes.Buffer
+	tr := &transcript{out: &out}
+	tr.write([]message.Message{user, call, result, streaming})
+	require.Equal(t, "You: What's in main.go?\n\nCrush: Let me look.\nTool: view\n\nTool: view finished\n\nCrush: The main", out.String())
+
+	streaming.Parts = []message.ContentPart{
+		message.TextContent{Text: "The main package."},
+		message.Finish{Reason: message.FinishReasonEndTurn},
+	}
+	tr.write([]message.Message{user, call, result, streaming})
+	require.Equal(t, "You: What's in main.go?\n\nCrush: Let me look.\nTool: view\n\nTool: view finished\n\nCrush: The main package.\n\n", out.String())
+
+	// Nothing is printed twice.
+	tr.write([]message.Message{user, call, result, streaming})
+	tr.flush([]message.Message{user, call, result, streaming})
+	require.Equal(t, "You: What's in main.go?\n\nCrush: Let me look.\nTool: view\n\nTool: view finished\n\nCrush: The main package.\n\n", out.String())
+}
+
+func TestTranscriptFlush(t *testing.T) {
+	t.Parallel()
+
+	msgs := []message.Message{{ID: "1", Role: message.Assistant, Parts: []message.ContentPart{
+		message.TextContent{Text: "Cancelled halfway"},
+		message.ToolCall{ID: "a", Name: "bash"},
+	}}}
+
+	var out bytes.Buffer
+	tr := &transcript{out: &out}
+	tr.write(msgs)
+	tr.flush(msgs)
+	require.Equal(t, "Crush: Cancelled halfway\nTool: bash\n", out.String())
+}
@@ -48,6 +48,17 @@ const (
 	SelectedModelTypeSmall SelectedModelType = "small"
 )
 
+// CallPriority trades latency for cost on providers that offer different
+// service tiers.
+type CallPriority string
+
+const (
+	CallPriorityStandard CallPriority = "standard"
+	CallPriorityFlex     CallPriority = "flex"
+	CallPriorityPriority CallPriority = "priority"
+	CallPriorityBatch    CallPriority = "batch"
+)
+
 const (
 	AgentCoder string = "coder"
 	AgentTask  string = "task"
@@ -62,7 +73,7 @@ type SelectedModel struct {
 	Provider string `json:"provider" jsonschema:"required,description=The model provider ID that matches a key in the providers config,example=openai"`
 
 	// Only used by models that use the openai provider and need this set.
-	ReasoningEffort string `json:"reasoning_effort,omitempty" jsonschema:"description=Reasoning effort level for OpenAI models that support it,enum=low,enum=medium,enum=high"`
+	ReasoningEffort string `json:"reasoning_effort,omitempty" jsonschema:"description=Reasoning effort level for OpenAI and Gemini models that support it,enum=low,enum=medium,enum=high"`
 
 	// Used by anthropic models that can reason to indicate if the model should think.
 	Think bool `json:"think,omitempty" jsonschema:"description=Enable thinking mode for Anthropic models that support reasoning"`
@@ -75,8 +86,51 @@ type SelectedModel struct {
 	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty" jsonschema:"description=Frequency penalty to reduce repetition"`
 	PresencePenalty  *float64 `json:"presence_penalty,omitempty" jsonschema:"description=Presence penalty to increase topic diversity"`
 
+	// Service tier to request, ignored by providers that don't support it.
+	Priority CallPriority `json:"priority,omitempty" jsonschema:"description=Service tier used to trade latency for cost on providers that support it,enum=standard,enum=flex,enum=priority,enum=batch"`
+
+	// Whether the model may call several tools in one step, the provider
+	// decides when unset.
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty" jsonschema:"description=Allow the model to call several tools in one step (the provider decides when unset)"`
+
 	// Override provider specific options.
 	ProviderOptions map[string]any `json:"provider_options,omitempty" jsonschema:"description=Additional provider-specific options for the model"`
+
+	// Only used by Gemini models, the threshold applies to every harm
+	// category unless provider_options has safety_settings.
+	SafetyThreshold string `json:"safety_threshold,omitempty" jsonschema:"description=Blocking threshold of the safety filters of Gemini models for every harm category,enum=BLOCK_LOW_AND_ABOVE,enum=BLOCK_MEDIUM_AND_ABOVE,enum=BLOCK_ONLY_HIGH,enum=BLOCK_NONE,enum=OFF"`
+
+	// Only used by openai models that can respond with audio.
+	Audio *ModelAudio `json:"audio,omitempty" jsonschema:"description=Request spoken audio output for OpenAI models that support it"`
+}
+
+type ModelAudio struct {
+	// The voice the model uses to respond.
+	Voice string `json:"voice,omitempty" jsonschema:"description=Voice used for audio responses,enum=alloy,enum=ash,enum=ballad,enum=coral,enum=echo,enum=sage,enum=shimmer,enum=verse,default=alloy"`
+	// The audio format, streaming responses only support pcm16.
+	Format string `json:"format,omitempty" jsonschema:"description=Audio output format,enum=pcm16,default=pcm16"`
+}
+
+// defaultMaxOutputTokens is used when neither the user configuration nor the
+// known model metadata say how many tokens a model may generate.
+const defaultMaxOutputTokens int64 = 4096
+
+// MaxOutputTokens returns the maximum number of tokens to request from the
+// given model. A value set in the configuration always wins; otherwise it is
+// derived from the model metadata and capped to half of the context window so
+// a single response can't exhaust it.
+func (m SelectedModel) MaxOutputTokens(model catwalk.Model) int64 {
+	if m.MaxTokens > 0 {
+		return m.MaxTokens
+	}
+	maxTokens := model.DefaultMaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxOutputTokens
+	}
+	if limit := model.ContextWindow / 2; limit > 0 && maxTokens > limit {
+		maxTokens = limit
+	}
+	return maxTokens
 }
 
 type ProviderConfig struct {
@@ -96,10 +150,27 @@ type ProviderConfig struct {
 	// Custom system prompt prefix.
 	SystemPromptPrefix string `json:"system_prompt_prefix,omitempty" jsonschema:"description=Custom prefix to add to system prompts for this provider"`
 
+	// Send abbreviated tool schemas after the first turn of a session.
+	CompactToolSchemas bool `json:"compact_tool_schemas,omitempty" jsonschema:"description=Send abbreviated tool schemas after the first turn of a session to reduce the prompt size. Ignored for providers that cache prompts,default=false"`
+
+	// Azure OpenAI API version and deployment names by model ID.
+	APIVersion  string            `json:"api_version,omitempty" jsonschema:"description=API version of Azure OpenAI providers,example=2025-01-01-preview"`
+	Deployments map[string]string `json:"deployments,omitempty" jsonschema:"description=Deployment names of the models of Azure OpenAI providers by model ID. Models without one are deployed under their ID"`
+
+	// Repair the conversation so tool calls are immediately followed by
+	// their results.
+	PairToolResults bool `json:"pair_tool_results,omitempty" jsonschema:"description=Repair the conversation before sending it so each tool call is immediately followed by its result. For providers that reject it otherwise,default=false"`
+
+	// Most requests sent to the provider at once, 0 for no limit.
+	MaxConcurrentRequests int `json:"max_concurrent_requests,omitempty" jsonschema:"description=Most generate and stream requests sent to the provider at once across sessions and sub-agents. Others wait for their turn. 0 for no limit,minimum=0,example=4"`
+
+	// Settings of the connections to the provider.
+	HTTP *ProviderHTTP `json:"http,omitempty" jsonschema:"description=Settings of the HTTP connections to the provider shared by all its models"`
+
 	// Extra headers to send with each request to the provider.
-	ExtraHeaders map[string]string `json:"extra_headers,omitempty" jsonschema:"description=Additional HTTP headers to send with requests"`
-	// Extra body
-	ExtraBody map[string]any `json:"extra_body,omitempty" jsonschema:"description=Additional fields to include in request bodies, only works with openai-compatible providers"`
+	ExtraHeaders map[string]string `json:"extra_headers,omitempty" jsonschema:"description=Additional HTTP headers to send with requests. Values support variables like $VAR and $(command)"`
+	// Extra fields to merge into the body of each request to the provider.
+	ExtraBody map[string]any `json:"extra_body,omitempty" jsonschema:"description=Additional fields to include in request bodies of openai and anthropic providers and openai-compatible ones. String values support variables like $VAR and $(command)"`
 
 	ProviderOptions map[string]any `json:"provider_options,omitempty" jsonschema:"description=Additional provider-specific options for this provider"`
 
@@ -110,6 +181,60 @@ type ProviderConfig struct {
 	Models []catwalk.Model `json:"models,omitempty" jsonschema:"description=List of models available from this provider"`
 }
 
+// ProviderHTTP tunes the HTTP connections to a provider. Connections are
+// kept open between the steps of the agent so the following requests skip
+// the TLS handshake.
+type ProviderHTTP struct {
+	// Most idle connections kept open to the provider.
+	MaxIdleConns int `json:"max_idle_conns,omitempty" jsonschema:"description=Most idle connections kept open to the provider for the following requests,default=16,minimum=1,example=32"`
+	// Seconds an idle connection is kept open.
+	IdleTimeout int `json:"idle_timeout,omitempty" jsonschema:"description=Seconds an idle connection is kept open before being closed,default=90,minimum=1,example=300"`
+	// Seconds without data on an HTTP/2 connection before it is pinged.
+	PingInterval int `json:"ping_interval,omitempty" jsonschema:"description=Seconds without data received on an HTTP/2 connection before it is pinged to check it is still alive. Catches connections dropped by proxies and NAT gateways,default=30,minimum=1,example=15"`
+	// Seconds to wait for the answer of a ping before closing the connection.
+	PingTimeout int `json:"ping_timeout,omitempty" jsonschema:"description=Seconds to wait for the answer to a ping before closing the HTTP/2 connection,default=15,minimum=1,example=5"`
+}
+
+const (
+	defaultMaxIdleConns = 16
+	defaultIdleTimeout  = 90 * time.Second
+	defaultPingInterval = 30 * time.Second
+	defaultPingTimeout  = 15 * time.Second
+)
+
+// MaxIdleConnections returns the most idle connections kept open.
+func (h *ProviderHTTP) MaxIdleConnections() int {
+	if h == nil || h.MaxIdleConns <= 0 {
+		return defaultMaxIdleConns
+	}
+	return h.MaxIdleConns
+}
+
+// IdleConnTimeout returns how long an idle connection is kept open.
+func (h *ProviderHTTP) IdleConnTimeout() time.Duration {
+	if h == nil || h.IdleTimeout <= 0 {
+		return defaultIdleTimeout
+	}
+	return time.Duration(h.IdleTimeout) * time.Second
+}
+
+// HTTP2PingInterval returns how long an HTTP/2 connection goes without data
+// before it is pinged.
+func (h *ProviderHTTP) HTTP2PingInterval() time.Duration {
+	if h == nil || h.PingInterval <= 0 {
+		return defaultPingInterval
+	}
+	return time.Duration(h.PingInterval) * time.Second
+}
+
+// HTTP2PingTimeout returns how long to wait for the answer to a ping.
+func (h *ProviderHTTP) HTTP2PingTimeout() time.Duration {
+	if h == nil || h.PingTimeout <= 0 {
+		return defaultPingTimeout
+	}
+	return time.Duration(h.PingTimeout) * time.Second
+}
+
 type MCPType string
 
 const (
@@ -129,6 +254,11 @@ type MCPConfig struct {
 
 	// TODO: maybe make it possible to get the value from the env
 	Headers map[string]string `json:"headers,omitempty" jsonschema:"description=HTTP headers for HTTP/SSE MCP servers"`
+
+	// Tools of the server given to the agents, all of them when empty.
+	Tools []string `json:"tools,omitempty" jsonschema:"description=Names of the tools of the server given to the agents. All tools are given when empty. read_resource names the tool reading the resources of the server,example=search,example=read_resource"`
+	// Seconds a tool call may take, no limit when 0.
+	ToolTimeout int `json:"tool_timeout,omitempty" jsonschema:"description=Seconds a call to a tool of the server may take before it is cancelled. No limit when 0,minimum=0,example=120"`
 }
 
 type LSPConfig struct {
@@ -145,10 +275,102 @@ type LSPConfig struct {
 type TUIOptions struct {
 	CompactMode bool   `json:"compact_mode,omitempty" jsonschema:"description=Enable compact mode for the TUI interface,default=false"`
 	DiffMode    string `json:"diff_mode,omitempty" jsonschema:"description=Diff mode for the TUI interface,enum=unified,enum=split"`
+	Language    string `json:"language,omitempty" jsonschema:"description=Language of the TUI interface (detected from the locale if not set),enum=en,enum=es,enum=pt,enum=fr,enum=de"`
+	// AmbiguousWidth is how wide East Asian ambiguous characters are drawn:
+	// "narrow", "wide" or "auto" to ask the terminal.
+	AmbiguousWidth string `json:"ambiguous_width,omitempty" jsonschema:"description=Width of East Asian ambiguous characters (auto asks the terminal),enum=auto,enum=narrow,enum=wide,default=auto"`
+	// Hyperlinks makes file paths and URLs clickable in terminals that
+	// support OSC 8 hyperlinks.
+	Hyperlinks *bool `json:"hyperlinks,omitempty" jsonschema:"description=Make file paths and URLs clickable in terminals that support OSC 8 hyperlinks,default=true"`
+	// CopyOnSelect copies selected text to the clipboard as soon as the
+	// mouse button is released.
+	CopyOnSelect *bool `json:"copy_on_select,omitempty" jsonschema:"description=Copy selected text to the clipboard when the mouse button is released,default=true"`
+	// Mouse enables mouse support. When not set it's enabled unless the
+	// terminal doesn't report mouse events.
+	Mouse *bool `json:"mouse,omitempty" jsonschema:"description=Enable mouse support (detected from the terminal if not set)"`
 	// Here we can add themes later or any TUI related options
 	//
 
 	Completions Completions `json:"completions,omitzero" jsonschema:"description=Completions UI options"`
+	Animation   Animation   `json:"animation,omitzero" jsonschema:"description=How spinners and other animations move"`
+	Rendering   Rendering   `json:"rendering,omitzero" jsonschema:"description=How often the TUI renders while responses stream"`
+	// Keybindings maps action IDs to the keys bound to them, replacing the
+	// default keys.
+	Keybindings map[string][]string `json:"keybindings,omitempty" jsonschema:"description=Keys bound to TUI actions by action ID replacing the defaults,example={\"new_session\":[\"ctrl+t\"]}"`
+	StatusBar   StatusBar           `json:"status_bar,omitzero" jsonschema:"description=What the status bar shows next to the key help"`
+}
+
+// StatusBar defines the segments shown on the right of the status bar.
+type StatusBar struct {
+	Segments []StatusSegment `json:"segments,omitempty" jsonschema:"description=Segments shown on the right of the status bar from left to right"`
+}
+
+type StatusSegmentType string
+
+const (
+	StatusSegmentModel     StatusSegmentType = "model"
+	StatusSegmentTokens    StatusSegmentType = "tokens"
+	StatusSegmentCost      StatusSegmentType = "cost"
+	StatusSegmentGitBranch StatusSegmentType = "git_branch"
+	StatusSegmentHealth    StatusSegmentType = "health"
+	StatusSegmentRequests  StatusSegmentType = "requests"
+	StatusSegmentCommand   StatusSegmentType = "command"
+)
+
+// StatusSegment is a piece of information shown in the status bar. When
+// the segments don't fit, the last ones are left out first.
+type StatusSegment struct {
+	Type     StatusSegmentType `json:"type" jsonschema:"description=What the segment shows,enum=model,enum=tokens,enum=cost,enum=git_branch,enum=health,enum=requests,enum=command"`
+	Command  string            `json:"command,omitempty" jsonschema:"description=Shell command whose first line of output the segment shows (command segments only),example=date +%H:%M"`
+	Interval int               `json:"interval,omitempty" jsonschema:"description=Seconds between two runs of the command,default=10,minimum=1"`
+	MinWidth int               `json:"min_width,omitempty" jsonschema:"description=Columns the segment takes at least,minimum=0"`
+	MaxWidth int               `json:"max_width,omitempty" jsonschema:"description=Columns after which the segment is truncated (0 for no limit),minimum=0"`
+}
+
+// RefreshInterval returns how often the command of the segment runs.
+func (s StatusSegment) RefreshInterval() time.Duration {
+	if s.Interval < 1 {
+		return 10 * time.Second
+	}
+	return time.Duration(s.Interval) * time.Second
+}
+
+// Animation defines how the animations of the TUI move.
+type Animation struct {
+	ReducedMotion bool  `json:"reduced_motion,omitempty" jsonschema:"description=Replace animations with a static indicator,default=false"`
+	FPS           int   `json:"fps,omitempty" jsonschema:"description=Frame rate of animations,default=20,minimum=1,maximum=60,example=10"`
+	CycleColors   *bool `json:"cycle_colors,omitempty" jsonschema:"description=Cycle the theme colors through spinners,default=true"`
+}
+
+// Rendering defines how often the TUI renders.
+type Rendering struct {
+	MaxFPS int `json:"max_fps,omitempty" jsonschema:"description=Most frames rendered per second,default=60,minimum=1,maximum=120,example=30"`
+	// Adaptive spaces the renders of streaming responses further when frames
+	// are expensive to render, like on low-powered machines or over SSH.
+	Adaptive *bool `json:"adaptive,omitempty" jsonschema:"description=Render streaming responses less often when frames are slow to render,default=true"`
+}
+
+// CopyOnSelectEnabled reports whether selected text is copied when the
+// mouse button is released.
+func (t TUIOptions) CopyOnSelectEnabled() bool {
+	return ptrValOr(t.CopyOnSelect, true)
+}
+
+// MouseEnabled reports whether the TUI uses the mouse, with detected
+// whether the terminal supports it.
+func (t TUIOptions) MouseEnabled(detected bool) bool {
+	return ptrValOr(t.Mouse, detected)
+}
+
+// HyperlinksEnabled reports whether file paths and URLs are rendered as
+// hyperlinks.
+func (t TUIOptions) HyperlinksEnabled() bool {
+	return ptrValOr(t.Hyperlinks, true)
+}
+
+// IsAdaptive reports whether rendering adapts to the cost of frames.
+func (r Rendering) IsAdaptive() bool {
+	return ptrValOr(r.Adaptive, true)
 }
 
 // Completions defines options for the completions UI.
@@ -172,16 +394,129 @@ type Attribution struct {
 }
 
 type Options struct {
-	ContextPaths              []string     `json:"context_paths,omitempty" jsonschema:"description=Paths to files containing context information for the AI,example=.cursorrules,example=CRUSH.md"`
-	TUI                       *TUIOptions  `json:"tui,omitempty" jsonschema:"description=Terminal user interface options"`
-	Debug                     bool         `json:"debug,omitempty" jsonschema:"description=Enable debug logging,default=false"`
-	DebugLSP                  bool         `json:"debug_lsp,omitempty" jsonschema:"description=Enable debug logging for LSP servers,default=false"`
-	DisableAutoSummarize      bool         `json:"disable_auto_summarize,omitempty" jsonschema:"description=Disable automatic conversation summarization,default=false"`
-	DataDirectory             string       `json:"data_directory,omitempty" jsonschema:"description=Directory for storing application data (relative to working directory),default=.crush,example=.crush"` // Relative to the cwd
-	DisabledTools             []string     `json:"disabled_tools" jsonschema:"description=Tools to disable"`
-	DisableProviderAutoUpdate bool         `json:"disable_provider_auto_update,omitempty" jsonschema:"description=Disable providers auto-update,default=false"`
-	Attribution               *Attribution `json:"attribution,omitempty" jsonschema:"description=Attribution settings for generated content"`
-	DisableMetrics            bool         `json:"disable_metrics,omitempty" jsonschema:"description=Disable sending metrics,default=false"`
+	ContextPaths              []string         `json:"context_paths,omitempty" jsonschema:"description=Paths to files containing context information for the AI,example=.cursorrules,example=CRUSH.md"`
+	TUI                       *TUIOptions      `json:"tui,omitempty" jsonschema:"description=Terminal user interface options"`
+	Debug                     bool             `json:"debug,omitempty" jsonschema:"description=Enable debug logging,default=false"`
+	DebugLSP                  bool             `json:"debug_lsp,omitempty" jsonschema:"description=Enable debug logging for LSP servers,default=false"`
+	DisableAutoSummarize      bool             `json:"disable_auto_summarize,omitempty" jsonschema:"description=Disable automatic conversation summarization,default=false"`
+	DataDirectory             string           `json:"data_directory,omitempty" jsonschema:"description=Directory for storing application data (relative to working directory),default=.crush,example=.crush"` // Relative to the cwd
+	DisabledTools             []string         `json:"disabled_tools" jsonschema:"description=Tools to disable"`
+	DisableProviderAutoUpdate bool             `json:"disable_provider_auto_update,omitempty" jsonschema:"description=Disable providers auto-update,default=false"`
+	Attribution               *Attribution     `json:"attribution,omitempty" jsonschema:"description=Attribution settings for generated content"`
+	DisableMetrics            bool             `json:"disable_metrics,omitempty" jsonschema:"description=Disable sending metrics,default=false"`
+	DisableHealthChecks       bool             `json:"disable_health_checks,omitempty" jsonschema:"description=Disable checking the status pages of providers when requests to them keep failing,default=false"`
+	PlanMode                  bool             `json:"plan_mode,omitempty" jsonschema:"description=Have the coder agent propose a plan for approval before making changes,default=false"`
+	ResponseLanguage          string           `json:"response_language,omitempty" jsonschema:"description=Language the model should respond in,example=Spanish,example=Brazilian Portuguese"`
+	LoopDetection             LoopDetection    `json:"loop_detection,omitzero" jsonschema:"description=Thresholds used to stop the agent when it is stuck in a loop"`
+	ContextSharing            ContextSharing   `json:"context_sharing,omitzero" jsonschema:"description=Context of the parent session shared with the sub-agents it spawns"`
+	RunSummary                bool             `json:"run_summary,omitempty" jsonschema:"description=Sum up the files changed and commands run at the end of turns with several steps,default=false"`
+	StopPhrases               []string         `json:"stop_phrases,omitempty" jsonschema:"description=Phrases that stop the agent as soon as it writes them until you confirm going past them,example=BEGIN DESTRUCTIVE"`
+	SpendingLimits            SpendingLimits   `json:"spending_limits,omitzero" jsonschema:"description=Spending in US dollars over which you get warned"`
+	Compaction                Compaction       `json:"compaction,omitzero" jsonschema:"description=Compact the conversation as it approaches the context window instead of stopping to summarize the session"`
+	Retention                 Retention        `json:"retention,omitzero" jsonschema:"description=What of the conversations is kept in storage"`
+	Summarizers               Summarizers      `json:"summarizers,omitzero" jsonschema:"description=How each kind of summary is made"`
+	UsageAttribution          UsageAttribution `json:"usage_attribution,omitzero" jsonschema:"description=Attribute the requests to OpenAI and Anthropic to the developer for spending reports"`
+	TopicChange               TopicChange      `json:"topic_change,omitzero" jsonschema:"description=Offer to start a new session when a message changes the topic"`
+	Prefetch                  bool             `json:"prefetch,omitempty" jsonschema:"description=Read files and search while the model is still writing view and grep calls so their results come back sooner,default=false"`
+	Offline                   bool             `json:"offline,omitempty" jsonschema:"description=Only use local providers and never reach the internet for air-gapped environments. Also set with CRUSH_OFFLINE or --offline,default=false"`
+	AllowedModels             []string         `json:"allowed_models,omitempty" jsonschema:"description=Only offer the models matching these patterns: a model ID or a provider/model ID where * matches any characters but /,example=anthropic/claude-*,example=gpt-4o"`
+	ReadOnly                  bool             `json:"-"` // Describe changes instead of applying them
+}
+
+// ContextSharing configures what the sub-agents spawned by the agent get to
+// know about the session that spawned them. Nothing is shared by default.
+type ContextSharing struct {
+	Handoff        bool `json:"handoff,omitempty" jsonschema:"description=Share the handoff document of the parent session or its summary when it has none,default=false"`
+	PinnedMessages bool `json:"pinned_messages,omitempty" jsonschema:"description=Share the messages pinned in the parent session,default=false"`
+}
+
+// SpendingLimits are the spending in US dollars, computed from the prices
+// of the models, over which a warning is shown. Zero disables the warning.
+type SpendingLimits struct {
+	Daily   float64 `json:"daily,omitempty" jsonschema:"description=Warn when spending more than this in a day,example=10"`
+	Weekly  float64 `json:"weekly,omitempty" jsonschema:"description=Warn when spending more than this in a week starting on Monday,example=50"`
+	Monthly float64 `json:"monthly,omitempty" jsonschema:"description=Warn when spending more than this in a calendar month,example=200"`
+}
+
+// LoopDetection configures when the agent is considered stuck and asked to
+// wrap up. Zero values use the defaults.
+type LoopDetection struct {
+	Disabled        bool `json:"disabled,omitempty" jsonschema:"description=Disable loop detection,default=false"`
+	RepeatedSteps   int  `json:"repeated_steps,omitempty" jsonschema:"description=Stop when the same step or the same two steps repeat this many times,default=3,minimum=1"`
+	FailedToolCalls int  `json:"failed_tool_calls,omitempty" jsonschema:"description=Stop when the same tool call fails this many times,default=3,minimum=1"`
+	StaleSteps      int  `json:"stale_steps,omitempty" jsonschema:"description=Stop after this many steps in a row without new content,default=6,minimum=1"`
+}
+
+// Compaction configures the compaction of runs approaching the context
+// window of the model.
+type Compaction struct {
+	Enabled   bool    `json:"enabled,omitempty" jsonschema:"description=Summarize older steps and continue when the conversation approaches the context window,default=false"`
+	Threshold float64 `json:"threshold,omitempty" jsonschema:"description=Share of the context window filled before compacting,default=0.8,minimum=0,maximum=1"`
+}
+
+// SummarizerType is how summaries are made.
+type SummarizerType string
+
+const (
+	// SummarizerModel has a model make the summaries, falling back to
+	// extractive ones when it fails.
+	SummarizerModel SummarizerType = "model"
+	// SummarizerExtractive keeps parts of what's summarized without calling
+	// a model, for offline and local-only use.
+	SummarizerExtractive SummarizerType = "extractive"
+)
+
+// Summarizers selects how each kind of summary is made, SummarizerModel when
+// empty.
+type Summarizers struct {
+	Session    SummarizerType `json:"session,omitempty" jsonschema:"description=How the summaries of sessions are made,enum=model,enum=extractive,default=model"`
+	Compaction SummarizerType `json:"compaction,omitempty" jsonschema:"description=How the summaries of compacted runs are made,enum=model,enum=extractive,default=model"`
+	ToolOutput SummarizerType `json:"tool_output,omitempty" jsonschema:"description=How long tool results are summarized when tools.output.summarize is set,enum=model,enum=extractive,default=model"`
+}
+
+// TopicDetector is how the start of another topic is detected.
+type TopicDetector string
+
+const (
+	// TopicDetectorEmbedding compares the embeddings of the messages.
+	TopicDetectorEmbedding TopicDetector = "embedding"
+	// TopicDetectorModel asks the small model.
+	TopicDetectorModel TopicDetector = "model"
+)
+
+// TopicChange configures the detection of messages starting another topic
+// than the one of their session, to offer to go on in a new session that
+// carries a brief summary. Disabled without a detector.
+type TopicChange struct {
+	Detector          TopicDetector `json:"detector,omitempty" jsonschema:"description=How the start of another topic is detected: comparing the embeddings of the messages or asking the small model. Disabled when empty,enum=embedding,enum=model"`
+	EmbeddingProvider string        `json:"embedding_provider,omitempty" jsonschema:"description=Provider of the embedding model. Defaults to the provider of the small model"`
+	EmbeddingModel    string        `json:"embedding_model,omitempty" jsonschema:"description=Embedding model comparing the messages,example=text-embedding-3-small,example=nomic-embed-text"`
+	Threshold         float64       `json:"threshold,omitempty" jsonschema:"description=Similarity with each of the previous messages under which a message starts another topic,default=0.3,minimum=0,maximum=1"`
+	MinMessages       int           `json:"min_messages,omitempty" jsonschema:"description=Messages sent in a session before changes of topic are detected,default=4,minimum=1"`
+}
+
+// UsageAttribution attributes the requests to OpenAI and Anthropic to the
+// developer sending them, and to an OpenAI organization and project, for
+// the spending to be reported per developer.
+type UsageAttribution struct {
+	Enabled      bool   `json:"enabled,omitempty" jsonschema:"description=Attribute the requests to the developer by sending a hash of the git user.email or else of the login name,default=false"`
+	User         string `json:"user,omitempty" jsonschema:"description=Developer the requests are attributed to instead of the hash of the git identity. Supports variables like $VAR and $(command),example=$USER"`
+	Organization string `json:"organization,omitempty" jsonschema:"description=OpenAI organization ID the requests are billed to,example=org-123"`
+	Project      string `json:"project,omitempty" jsonschema:"description=OpenAI project ID the requests are billed to,example=proj_123"`
+}
+
+// Retention configures what of the conversations is kept in storage. It's
+// enforced when messages are written and periodically on the stored ones.
+type Retention struct {
+	MessageBodyDays int  `json:"message_body_days,omitempty" jsonschema:"description=Delete the content of messages older than this many days keeping the session stats,example=90,minimum=0"`
+	DropReasoning   bool `json:"drop_reasoning,omitempty" jsonschema:"description=Never store the reasoning of models,default=false"`
+	DropAttachments bool `json:"drop_attachments,omitempty" jsonschema:"description=Never store the files attached to messages,default=false"`
+}
+
+// MessageBodyMaxAge returns the age past which the content of messages is
+// deleted, 0 to keep it.
+func (r Retention) MessageBodyMaxAge() time.Duration {
+	return time.Duration(max(r.MessageBodyDays, 0)) * 24 * time.Hour
 }
 
 type MCPs map[string]MCPConfig
@@ -268,10 +603,71 @@ type Agent struct {
 
 	// Overrides the context paths for this agent
 	ContextPaths []string `json:"context_paths,omitempty"`
+
+	// Makes the agent propose a plan for approval before changing anything.
+	PlanMode bool `json:"plan_mode,omitempty"`
+
+	// What the agent gets to know about the parent session when it runs as
+	// a sub-agent.
+	ContextSharing ContextSharing `json:"context_sharing,omitzero"`
+}
+
+// Sync configures the remote sessions are synced with by crush sync.
+type Sync struct {
+	Remote   string `json:"remote" jsonschema:"required,description=Where sessions are synced: an S3 bucket or a WebDAV folder or a git repository or a local directory,example=s3://my-bucket/crush,example=https://dav.example.com/crush,example=git+ssh://git@github.com/me/crush-sessions.git,example=/mnt/shared/crush"`
+	Project  string `json:"project,omitempty" jsonschema:"description=Folder of the remote holding the sessions of this project. Defaults to the name of the project directory"`
+	Username string `json:"username,omitempty" jsonschema:"description=WebDAV username"`
+	Password string `json:"password,omitempty" jsonschema:"description=WebDAV password. Supports variables like $VAR and $(command)"`
+	Region   string `json:"region,omitempty" jsonschema:"description=S3 region. Defaults to the one of the AWS configuration"`
+	Endpoint string `json:"endpoint,omitempty" jsonschema:"description=Endpoint of an S3 compatible storage,example=https://minio.example.com"`
 }
 
 type Tools struct {
 	Ls ToolLs `json:"ls,omitzero"`
+
+	SlowWarning *int `json:"slow_warning,omitempty" jsonschema:"description=Warn when a tool runs for longer than this many seconds (0 disables the warning),default=30,example=60"`
+
+	// Strict lists the tools whose arguments must match their schema exactly,
+	// on providers supporting strict function calling.
+	Strict []string `json:"strict,omitempty" jsonschema:"description=Tools whose arguments are enforced to match their schema by providers supporting strict function calling,example=edit"`
+
+	Output ToolOutput `json:"output,omitzero"`
+
+	Concurrency int `json:"concurrency,omitempty" jsonschema:"description=Maximum number of tool calls of a step running at once (0 uses the default),default=8,example=4"`
+	Timeout     int `json:"timeout,omitempty" jsonschema:"description=Stop tool calls running for longer than this many seconds (0 disables the timeout),example=600"`
+}
+
+// ToolOutput limits how much of the results of tools goes in the context.
+type ToolOutput struct {
+	MaxTokens int  `json:"max_tokens,omitempty" jsonschema:"description=Shorten tool results longer than this many tokens (0 keeps them whole),example=8000"`
+	Summarize bool `json:"summarize,omitempty" jsonschema:"description=Summarize long tool results with the small model instead of keeping their start and end"`
+}
+
+const defaultSlowToolWarning = 30 * time.Second
+
+// SlowWarningThreshold returns how long a tool may run before the user is
+// warned about it, or zero if the warning is disabled.
+func (t Tools) SlowWarningThreshold() time.Duration {
+	if t.SlowWarning == nil {
+		return defaultSlowToolWarning
+	}
+	return time.Duration(max(*t.SlowWarning, 0)) * time.Second
+}
+
+const defaultToolConcurrency = 8
+
+// MaxConcurrency returns how many tool calls of a step may run at once.
+func (t Tools) MaxConcurrency() int {
+	if t.Concurrency <= 0 {
+		return defaultToolConcurrency
+	}
+	return t.Concurrency
+}
+
+// CallTimeout returns how long a tool call may run, or zero if it may run
+// for as long as it takes.
+func (t Tools) CallTimeout() time.Duration {
+	return time.Duration(max(t.Timeout, 0)) * time.Second
 }
 
 type ToolLs struct {
@@ -303,6 +699,8 @@ type Config struct {
 
 	Tools Tools `json:"tools,omitzero" jsonschema:"description=Tool configurations"`
 
+	Sync *Sync `json:"sync,omitempty" jsonschema:"description=Remote used by crush sync to share sessions between machines"`
+
 	Agents map[string]Agent `json:"-"`
 
 	// Internal
@@ -472,15 +870,19 @@ func allToolNames() []string {
 		"download",
 		"edit",
 		"multiedit",
+		"multi_file_edit",
 		"lsp_diagnostics",
 		"lsp_references",
 		"fetch",
 		"glob",
 		"grep",
 		"ls",
+		"search",
 		"sourcegraph",
 		"view",
 		"write",
+		"artifact",
+		"facts",
 	}
 }
 
@@ -511,7 +913,11 @@ func filterSlice(data []string, mask []string, include bool) []string {
 }
 
 func (c *Config) SetupAgents() {
-	allowedTools := resolveAllowedTools(allToolNames(), c.Options.DisabledTools)
+	disabledTools := c.Options.DisabledTools
+	if c.Options.Offline {
+		disabledTools = append(slices.Clone(disabledTools), onlineTools...)
+	}
+	allowedTools := resolveAllowedTools(allToolNames(), disabledTools)
 
 	agents := map[string]Agent{
 		AgentCoder: {
@@ -521,6 +927,7 @@ func (c *Config) SetupAgents() {
 			Model:        SelectedModelTypeLarge,
 			ContextPaths: c.Options.ContextPaths,
 			AllowedTools: allowedTools,
+			PlanMode:     c.Options.PlanMode,
 		},
 
 		AgentTask: {
@@ -531,7 +938,8 @@ func (c *Config) SetupAgents() {
 			ContextPaths: c.Options.ContextPaths,
 			AllowedTools: resolveReadOnlyTools(allowedTools),
 			// NO MCPs or LSPs by default
-			AllowedMCP: map[string][]string{},
+			AllowedMCP:     map[string][]string{},
+			ContextSharing: c.Options.ContextSharing,
 		},
 	}
 	c.Agents = agents
@@ -582,7 +990,11 @@ func (c *ProviderConfig) TestConnection(resolver VariableResolver) error {
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	for k, v := range c.ExtraHeaders {
+	extraHeaders, err := c.ResolvedExtraHeaders(resolver)
+	if err != nil {
+		return err
+	}
+	for k, v := range extraHeaders {
 		req.Header.Set(k, v)
 	}
 	b, err := client.Do(req)
@@ -603,6 +1015,74 @@ func (c *ProviderConfig) TestConnection(resolver VariableResolver) error {
 	return nil
 }
 
+// ResolvedExtraHeaders returns the extra headers of the provider with the
+// variables in their values resolved.
+func (c *ProviderConfig) ResolvedExtraHeaders(resolver VariableResolver) (map[string]string, error) {
+	headers := make(map[string]string, len(c.ExtraHeaders))
+	for k, v := range c.ExtraHeaders {
+		resolved, err := resolveTemplate(resolver, v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve extra header %q of provider %s: %w", k, c.ID, err)
+		}
+		headers[k] = resolved
+	}
+	return headers, nil
+}
+
+// ResolvedExtraBody returns the extra body of the provider with the
+// variables in its string values resolved, at any depth.
+func (c *ProviderConfig) ResolvedExtraBody(resolver VariableResolver) (map[string]any, error) {
+	if len(c.ExtraBody) == 0 {
+		return nil, nil
+	}
+	body, err := resolveBodyValue(resolver, c.ExtraBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve extra body of provider %s: %w", c.ID, err)
+	}
+	return body.(map[string]any), nil
+}
+
+func resolveBodyValue(resolver VariableResolver, value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return resolveTemplate(resolver, v)
+	case map[string]any:
+		resolved := make(map[string]any, len(v))
+		for key, item := range v {
+			r, err := resolveBodyValue(resolver, item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+	case []any:
+		resolved := make([]any, len(v))
+		for i, item := range v {
+			r, err := resolveBodyValue(resolver, item)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", i, err)
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	default:
+		return value, nil
+	}
+}
+
+// resolveTemplate resolves the variables in value, leaving values without
+// any untouched.
+func resolveTemplate(resolver VariableResolver, value string) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+	if resolver == nil {
+		return "", fmt.Errorf("no variable resolver configured")
+	}
+	return resolver.ResolveValue(value)
+}
+
 func resolveEnvs(envs map[string]string) []string {
 	resolver := NewShellVariableResolver(env.New())
 	for e, v := range envs {
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectedModel_MaxOutputTokens(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		selected SelectedModel
+		model    catwalk.Model
+		expected int64
+	}{
+		{
+			name:     "configured value wins",
+			selected: SelectedModel{MaxTokens: 1000},
+			model:    catwalk.Model{DefaultMaxTokens: 8000, ContextWindow: 200_000},
+			expected: 1000,
+		},
+		{
+			name:     "derived from model metadata",
+			model:    catwalk.Model{DefaultMaxTokens: 8000, ContextWindow: 200_000},
+			expected: 8000,
+		},
+		{
+			name:     "fallback when metadata is missing",
+			model:    catwalk.Model{},
+			expected: defaultMaxOutputTokens,
+		},
+		{
+			name:     "capped to half of the context window",
+			model:    catwalk.Model{DefaultMaxTokens: 8000, ContextWindow: 4000},
+			expected: 2000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.expected, tt.selected.MaxOutputTokens(tt.model))
+		})
+	}
+}
+
+func TestProviderConfig_ResolvedExtras(t *testing.T) {
+	t.Parallel()
+
+	resolver := NewEnvironmentVariableResolver(env.NewFromMap(map[string]string{
+		"TEAM":  "platform",
+		"TOKEN": "secret",
+	}))
+	providerCfg := ProviderConfig{
+		ID: "test",
+		ExtraHeaders: map[string]string{
+			"X-Team":  "$TEAM",
+			"X-Plain": "plain",
+		},
+		ExtraBody: map[string]any{
+			"metadata": map[string]any{"user": "$TOKEN", "tags": []any{"$TEAM", 1.0}},
+			"store":    true,
+		},
+	}
+
+	headers, err := providerCfg.ResolvedExtraHeaders(resolver)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"X-Team": "platform", "X-Plain": "plain"}, headers)
+
+	body, err := providerCfg.ResolvedExtraBody(resolver)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"metadata": map[string]any{"user": "secret", "tags": []any{"platform", 1.0}},
+		"store":    true,
+	}, body)
+	// The configured values are left untouched.
+	require.Equal(t, "$TEAM", providerCfg.ExtraHeaders["X-Team"])
+
+	providerCfg.ExtraHeaders["X-Missing"] = "$MISSING"
+	_, err = providerCfg.ResolvedExtraHeaders(resolver)
+	require.ErrorContains(t, err, "X-Missing")
+
+	empty := ProviderConfig{}
+	headers, err = empty.ResolvedExtraHeaders(nil)
+	require.NoError(t, err)
+	require.NotNil(t, headers)
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/fsext"
+)
+
+// teamConfigName is the team configuration, committed to the repository
+// so its members share the same setup.
+var teamConfigName = filepath.Join(defaultDataDirectory, "team.json")
+
+// LayerKind is where a configuration file comes from.
+type LayerKind string
+
+const (
+	// LayerTeam is the team configuration of the repository, .crush/team.json.
+	LayerTeam LayerKind = "team"
+	// LayerGlobal is the configuration of the user.
+	LayerGlobal LayerKind = "global"
+	// LayerData holds the preferences saved by the app, like the selected
+	// models.
+	LayerData LayerKind = "data"
+	// LayerProject is a crush.json or .crush.json of the project or of one
+	// of its parent directories.
+	LayerProject LayerKind = "project"
+)
+
+// Layer is a configuration file.
+type Layer struct {
+	Kind LayerKind
+	Path string
+}
+
+// Layers returns the configuration files merged for the working directory,
+// the ones with more priority last. The team configuration comes first so
+// the configurations of the user override it, then the global
+// configuration, the preferences saved by the app and the configurations of
+// the project, from the outermost directory to the working directory. The
+// files may not exist.
+func Layers(cwd string) []Layer {
+	var layers []Layer
+	if path, ok := fsext.LookupClosest(cwd, teamConfigName); ok {
+		layers = append(layers, Layer{Kind: LayerTeam, Path: path})
+	}
+	layers = append(layers,
+		Layer{Kind: LayerGlobal, Path: GlobalConfig()},
+		Layer{Kind: LayerData, Path: GlobalConfigData()},
+	)
+
+	configNames := []string{appName + ".json", "." + appName + ".json"}
+	foundConfigs, err := fsext.Lookup(cwd, configNames...)
+	if err != nil {
+		return layers
+	}
+	// reverse order so last config has more priority
+	slices.Reverse(foundConfigs)
+	for _, path := range foundConfigs {
+		layers = append(layers, Layer{Kind: LayerProject, Path: path})
+	}
+	return layers
+}
+
+// EffectiveValue is a value of the merged configuration and the files it
+// comes from. Arrays are merged by appending, so they may come from several
+// files, other values come from the last file setting them.
+type EffectiveValue struct {
+	// Path is the dotted path of the value, like options.tui.compact_mode.
+	Path    string
+	Value   any
+	Sources []Layer
+}
+
+// Effective returns the values of the configuration files merged, sorted by
+// path, along with the files they come from.
+func Effective(layers []Layer) ([]EffectiveValue, error) {
+	values := make(map[string]*EffectiveValue)
+	for _, layer := range layers {
+		data, err := os.ReadFile(layer.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", layer.Path, err)
+		}
+		var tree map[string]any
+		if err := json.Unmarshal(data, &tree); err != nil {
+			return nil, fmt.Errorf("failed to decode config file %s: %w", layer.Path, err)
+		}
+		mergeEffective(values, "", tree, layer)
+	}
+
+	effective := make([]EffectiveValue, 0, len(values))
+	for _, v := range values {
+		effective = append(effective, *v)
+	}
+	slices.SortFunc(effective, func(a, b EffectiveValue) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return effective, nil
+}
+
+func mergeEffective(values map[string]*EffectiveValue, prefix string, tree map[string]any, layer Layer) {
+	for key, value := range tree {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		switch value := value.(type) {
+		case map[string]any:
+			// Objects are merged, the values of their fields are tracked
+			// instead.
+			if len(value) > 0 || hasFields(values, path) {
+				delete(values, path)
+				mergeEffective(values, path, value, layer)
+				continue
+			}
+		case []any:
+			if existing, ok := values[path]; ok {
+				if items, ok := existing.Value.([]any); ok {
+					existing.Value = append(items, value...)
+					existing.Sources = append(existing.Sources, layer)
+					continue
+				}
+			}
+		}
+		for field := range values {
+			if strings.HasPrefix(field, path+".") {
+				delete(values, field)
+			}
+		}
+		values[path] = &EffectiveValue{Path: path, Value: value, Sources: []Layer{layer}}
+	}
+}
+
+func hasFields(values map[string]*EffectiveValue, path string) bool {
+	for field := range values {
+		if strings.HasPrefix(field, path+".") {
+			return true
+		}
+	}
+	return false
+}
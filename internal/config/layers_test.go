@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayers(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(t.TempDir(), "data"))
+
+	repo := t.TempDir()
+	cwd := filepath.Join(repo, "service")
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, ".crush"), 0o755))
+	require.NoError(t, os.MkdirAll(cwd, 0o755))
+	for _, path := range []string{
+		filepath.Join(repo, ".crush", "team.json"),
+		filepath.Join(repo, "crush.json"),
+		filepath.Join(cwd, ".crush.json"),
+	} {
+		require.NoError(t, os.WriteFile(path, []byte("{}"), 0o644))
+	}
+
+	require.Equal(t, []Layer{
+		{Kind: LayerTeam, Path: filepath.Join(repo, ".crush", "team.json")},
+		{Kind: LayerGlobal, Path: GlobalConfig()},
+		{Kind: LayerData, Path: GlobalConfigData()},
+		{Kind: LayerProject, Path: filepath.Join(repo, "crush.json")},
+		{Kind: LayerProject, Path: filepath.Join(cwd, ".crush.json")},
+	}, Layers(cwd))
+}
+
+func TestEffective(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, content string) Layer {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return Layer{Kind: LayerProject, Path: path}
+	}
+	team := write("team.json", `{
+		"options": {"allowed_models": ["anthropic/*"], "tui": {"compact_mode": true}, "debug": true},
+		"permissions": {"allowed_tools": ["view"]},
+		"lsp": {"go": {"command": "gopls"}}
+	}`)
+	global := write("global.json", `{
+		"options": {"allowed_models": ["gpt-4o"], "tui": {}, "debug": false},
+		"lsp": "none"
+	}`)
+	project := write("project.json", `{"permissions": {"allowed_tools": ["ls"]}, "lsp": {"rust": {}}}`)
+	missing := Layer{Kind: LayerData, Path: filepath.Join(dir, "missing.json")}
+
+	values, err := Effective([]Layer{team, global, missing, project})
+	require.NoError(t, err)
+	require.Equal(t, []EffectiveValue{
+		{Path: "lsp.rust", Value: map[string]any{}, Sources: []Layer{project}},
+		{Path: "options.allowed_models", Value: []any{"anthropic/*", "gpt-4o"}, Sources: []Layer{team, global}},
+		{Path: "options.debug", Value: false, Sources: []Layer{global}},
+		{Path: "options.tui.compact_mode", Value: true, Sources: []Layer{team}},
+		{Path: "permissions.allowed_tools", Value: []any{"view", "ls"}, Sources: []Layer{team, project}},
+	}, values)
+
+	_, err = Effective([]Layer{write("broken.json", "{")})
+	require.ErrorContains(t, err, "broken.json")
+}
@@ -85,9 +85,21 @@ func Load(workingDir, dataDir string, debug bool) (*Config, error) {
 	// Configure providers
 	valueResolver := NewShellVariableResolver(env)
 	cfg.resolver = valueResolver
+	configured := make(map[string]bool)
+	for id := range cfg.Providers.Seq2() {
+		configured[id] = true
+	}
 	if err := cfg.configureProviders(env, valueResolver, cfg.knownProviders); err != nil {
 		return nil, fmt.Errorf("failed to configure providers: %w", err)
 	}
+	if cfg.Options.Offline {
+		if err := cfg.restrictToLocal(configured); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.Options.AllowedModels) > 0 {
+		cfg.restrictModels()
+	}
 
 	if !cfg.IsConfigured() {
 		slog.Warn("No providers configured")
@@ -190,6 +202,9 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			SystemPromptPrefix: config.SystemPromptPrefix,
 			ExtraHeaders:       headers,
 			ExtraBody:          config.ExtraBody,
+			PairToolResults:    config.PairToolResults,
+			APIVersion:         config.APIVersion,
+			Deployments:        config.Deployments,
 			ExtraParams:        make(map[string]string),
 			Models:             p.Models,
 		}
@@ -348,6 +363,15 @@ func (c *Config) setDefaults(workingDir, dataDir string) {
 		c.Options.DisableProviderAutoUpdate, _ = strconv.ParseBool(str)
 	}
 
+	if OfflineFromEnv() {
+		c.Options.Offline = true
+	}
+	if c.Options.Offline {
+		c.Options.DisableProviderAutoUpdate = true
+		c.Options.DisableMetrics = true
+		c.Options.DisableHealthChecks = true
+	}
+
 	if c.Options.Attribution == nil {
 		c.Options.Attribution = &Attribution{
 			CoAuthoredBy:  true,
@@ -411,7 +435,7 @@ func (c *Config) defaultModelSelection(knownProviders []catwalk.Provider) (large
 		if !ok || providerConfig.Disable {
 			continue
 		}
-		defaultLargeModel := c.GetModel(string(p.ID), p.DefaultLargeModelID)
+		defaultLargeModel := c.defaultModel(string(p.ID), p.DefaultLargeModelID)
 		if defaultLargeModel == nil {
 			err = fmt.Errorf("default large model %s not found for provider %s", p.DefaultLargeModelID, p.ID)
 			return largeModel, smallModel, err
@@ -423,7 +447,7 @@ func (c *Config) defaultModelSelection(knownProviders []catwalk.Provider) (large
 			ReasoningEffort: defaultLargeModel.DefaultReasoningEffort,
 		}
 
-		defaultSmallModel := c.GetModel(string(p.ID), p.DefaultSmallModelID)
+		defaultSmallModel := c.defaultModel(string(p.ID), p.DefaultSmallModelID)
 		if defaultSmallModel == nil {
 			err = fmt.Errorf("default small model %s not found for provider %s", p.DefaultSmallModelID, p.ID)
 			return largeModel, smallModel, err
@@ -566,26 +590,14 @@ func (c *Config) configureSelectedModels(knownProviders []catwalk.Provider) erro
 	return nil
 }
 
-// lookupConfigs searches config files recursively from CWD up to FS root
+// lookupConfigs returns the paths of the config files, the ones with more
+// priority last.
 func lookupConfigs(cwd string) []string {
-	// prepend default config paths
-	configPaths := []string{
-		GlobalConfig(),
-		GlobalConfigData(),
+	var configPaths []string
+	for _, layer := range Layers(cwd) {
+		configPaths = append(configPaths, layer.Path)
 	}
-
-	configNames := []string{appName + ".json", "." + appName + ".json"}
-
-	foundConfigs, err := fsext.Lookup(cwd, configNames...)
-	if err != nil {
-		// returns at least default configs
-		return configPaths
-	}
-
-	// reverse order so last config has more priority
-	slices.Reverse(foundConfigs)
-
-	return append(configPaths, foundConfigs...)
+	return configPaths
 }
 
 func loadFromConfigPaths(configPaths []string) (*Config, error) {
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+)
+
+// modelsOverrideFile is the file in the config directory that adds or
+// overrides models of the known providers.
+func modelsOverrideFile() string {
+	return filepath.Join(filepath.Dir(GlobalConfig()), "models.json")
+}
+
+// loadModelOverrides merges the models of the override file at path into
+// the providers. The file maps provider IDs to lists of models: models with
+// a known ID only change the fields they set, the others are added. It's
+// fine for the file not to exist, but an invalid one is an error so typos
+// don't go unnoticed.
+func loadModelOverrides(path string, providers []catwalk.Provider) ([]catwalk.Provider, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return providers, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model overrides: %w", err)
+	}
+	providers, err = mergeModelOverrides(data, providers)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model overrides in %s: %w", path, err)
+	}
+	return providers, nil
+}
+
+func mergeModelOverrides(data []byte, providers []catwalk.Provider) ([]catwalk.Provider, error) {
+	var overrides map[string][]json.RawMessage
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, err
+	}
+
+	// The providers are shared, so changed ones are copied.
+	providers = slices.Clone(providers)
+	for _, providerID := range slices.Sorted(maps.Keys(overrides)) {
+		i := slices.IndexFunc(providers, func(p catwalk.Provider) bool { return string(p.ID) == providerID })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown provider %q", providerID)
+		}
+		p := providers[i]
+		p.Models = slices.Clone(p.Models)
+		seen := make(map[string]bool)
+		for _, raw := range overrides[providerID] {
+			var override struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(raw, &override); err != nil {
+				return nil, fmt.Errorf("provider %q: %w", providerID, err)
+			}
+			if override.ID == "" {
+				return nil, fmt.Errorf("provider %q: model without an id", providerID)
+			}
+			if seen[override.ID] {
+				return nil, fmt.Errorf("provider %q: model %q is listed twice", providerID, override.ID)
+			}
+			seen[override.ID] = true
+
+			// Decoding over the known model only changes the fields set.
+			j := slices.IndexFunc(p.Models, func(m catwalk.Model) bool { return m.ID == override.ID })
+			var model catwalk.Model
+			if j >= 0 {
+				model = p.Models[j]
+			}
+			if err := json.Unmarshal(raw, &model); err != nil {
+				return nil, fmt.Errorf("provider %q: model %q: %w", providerID, override.ID, err)
+			}
+			if model.Name == "" {
+				model.Name = model.ID
+			}
+			if err := validateModel(model); err != nil {
+				return nil, fmt.Errorf("provider %q: model %q: %w", providerID, override.ID, err)
+			}
+			if j >= 0 {
+				p.Models[j] = model
+			} else {
+				p.Models = append(p.Models, model)
+			}
+		}
+		providers[i] = p
+	}
+	return providers, nil
+}
+
+func validateModel(model catwalk.Model) error {
+	switch {
+	case model.ContextWindow <= 0:
+		return errors.New("context_window must be positive")
+	case model.DefaultMaxTokens < 0:
+		return errors.New("default_max_tokens can't be negative")
+	case model.DefaultMaxTokens > model.ContextWindow:
+		return errors.New("default_max_tokens can't be larger than context_window")
+	case model.CostPer1MIn < 0, model.CostPer1MOut < 0, model.CostPer1MInCached < 0, model.CostPer1MOutCached < 0:
+		return errors.New("costs can't be negative")
+	case model.DefaultReasoningEffort != "" && len(model.ReasoningLevels) > 0 && !slices.Contains(model.ReasoningLevels, model.DefaultReasoningEffort):
+		return fmt.Errorf("default_reasoning_effort %q isn't one of reasoning_levels", model.DefaultReasoningEffort)
+	}
+	return nil
+}
+
+// restrictModels keeps the models of the providers to the ones allowed by
+// the allowed_models option, leaving out the providers with none left.
+func (c *Config) restrictModels() {
+	for _, id := range slices.Sorted(maps.Keys(maps.Collect(c.Providers.Seq2()))) {
+		p, _ := c.Providers.Get(id)
+		p.Models = slices.DeleteFunc(slices.Clone(p.Models), func(m catwalk.Model) bool {
+			return !modelAllowed(c.Options.AllowedModels, id, m.ID)
+		})
+		if len(p.Models) == 0 {
+			slog.Debug("Skipping provider without allowed models", "provider", id)
+			c.Providers.Del(id)
+			continue
+		}
+		c.Providers.Set(id, p)
+	}
+}
+
+// modelAllowed reports whether a pattern matches the ID of the model, alone
+// or prefixed by the ID of its provider, like openai/gpt-4o.
+func modelAllowed(patterns []string, providerID, modelID string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, modelID); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, providerID+"/"+modelID); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultModel returns the model of the provider, or its first model when
+// the allowed models leave it out.
+func (c *Config) defaultModel(providerID, modelID string) *catwalk.Model {
+	if model := c.GetModel(providerID, modelID); model != nil || len(c.Options.AllowedModels) == 0 {
+		return model
+	}
+	if p, ok := c.Providers.Get(providerID); ok && len(p.Models) > 0 {
+		return &p.Models[0]
+	}
+	return nil
+}
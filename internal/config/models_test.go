@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadModelOverrides(t *testing.T) {
+	t.Parallel()
+
+	known := []catwalk.Provider{{
+		ID: catwalk.InferenceProviderAnthropic,
+		Models: []catwalk.Model{{
+			ID:               "claude",
+			Name:             "Claude",
+			CostPer1MIn:      3,
+			ContextWindow:    200_000,
+			DefaultMaxTokens: 50_000,
+			SupportsImages:   true,
+		}},
+	}}
+
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "models.json")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Parallel()
+		providers, err := loadModelOverrides(filepath.Join(t.TempDir(), "models.json"), known)
+		require.NoError(t, err)
+		require.Equal(t, known, providers)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		t.Parallel()
+		path := write(t, `{
+			"anthropic": [
+				{"id": "claude", "context_window": 1000000, "cost_per_1m_in": 6},
+				{"id": "claude-next", "context_window": 400000, "default_max_tokens": 64000, "can_reason": true}
+			]
+		}`)
+		providers, err := loadModelOverrides(path, known)
+		require.NoError(t, err)
+		require.Equal(t, []catwalk.Model{
+			{
+				ID:               "claude",
+				Name:             "Claude",
+				CostPer1MIn:      6,
+				ContextWindow:    1_000_000,
+				DefaultMaxTokens: 50_000,
+				SupportsImages:   true,
+			},
+			{
+				ID:               "claude-next",
+				Name:             "claude-next",
+				ContextWindow:    400_000,
+				DefaultMaxTokens: 64_000,
+				CanReason:        true,
+			},
+		}, providers[0].Models)
+		require.Len(t, known[0].Models, 1, "known providers are left alone")
+		require.Equal(t, int64(200_000), known[0].Models[0].ContextWindow)
+	})
+
+	for name, tt := range map[string]struct {
+		content string
+		err     string
+	}{
+		"malformed":        {`{"anthropic": {}}`, "cannot unmarshal"},
+		"unknown provider": {`{"nope": []}`, `unknown provider "nope"`},
+		"missing id":       {`{"anthropic": [{"context_window": 1}]}`, "model without an id"},
+		"duplicate":        {`{"anthropic": [{"id": "claude"}, {"id": "claude"}]}`, `model "claude" is listed twice`},
+		"no context":       {`{"anthropic": [{"id": "new"}]}`, "context_window must be positive"},
+		"max tokens":       {`{"anthropic": [{"id": "claude", "default_max_tokens": 300000}]}`, "default_max_tokens can't be larger than context_window"},
+		"negative cost":    {`{"anthropic": [{"id": "claude", "cost_per_1m_out": -1}]}`, "costs can't be negative"},
+		"reasoning effort": {`{"anthropic": [{"id": "claude", "reasoning_levels": ["low"], "default_reasoning_effort": "high"}]}`, `default_reasoning_effort "high"`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			path := write(t, tt.content)
+			_, err := loadModelOverrides(path, known)
+			require.ErrorContains(t, err, path)
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestRestrictModels(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		Options: &Options{AllowedModels: []string{"anthropic/claude-*", "gpt-4o"}},
+		Providers: csync.NewMapFrom(map[string]ProviderConfig{
+			"anthropic": {ID: "anthropic", Models: []catwalk.Model{{ID: "claude-sonnet-4"}, {ID: "claude-opus-4"}, {ID: "other"}}},
+			"openai":    {ID: "openai", Models: []catwalk.Model{{ID: "gpt-4o"}, {ID: "gpt-4o-mini"}}},
+			"ollama":    {ID: "ollama", Models: []catwalk.Model{{ID: "claude-local"}}},
+		}),
+	}
+	cfg.restrictModels()
+
+	anthropic, _ := cfg.Providers.Get("anthropic")
+	require.Equal(t, []catwalk.Model{{ID: "claude-sonnet-4"}, {ID: "claude-opus-4"}}, anthropic.Models)
+	openai, _ := cfg.Providers.Get("openai")
+	require.Equal(t, []catwalk.Model{{ID: "gpt-4o"}}, openai.Models)
+	_, ok := cfg.Providers.Get("ollama")
+	require.False(t, ok, "providers without allowed models are left out")
+
+	// Defaults that aren't allowed fall back to the first allowed model.
+	require.Equal(t, "claude-sonnet-4", cfg.defaultModel("anthropic", "other").ID)
+	require.Equal(t, "claude-opus-4", cfg.defaultModel("anthropic", "claude-opus-4").ID)
+}
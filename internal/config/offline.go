@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// onlineTools are the tools reaching the internet, disabled offline.
+var onlineTools = []string{"download", "fetch", "sourcegraph"}
+
+// OfflineFromEnv reports whether CRUSH_OFFLINE asks for the offline mode.
+func OfflineFromEnv() bool {
+	offline, _ := strconv.ParseBool(os.Getenv("CRUSH_OFFLINE"))
+	return offline
+}
+
+// IsLocalURL reports whether the URL is served by this machine or the local
+// network: localhost, hosts without a domain or with a local one, and
+// loopback, private or link-local addresses.
+func IsLocalURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast()
+	}
+	if host == "localhost" || !strings.Contains(host, ".") {
+		return true
+	}
+	for _, suffix := range []string{".localhost", ".local", ".internal", ".lan", ".home.arpa"} {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalRemote reports whether a sync remote is a directory or is served
+// locally.
+func isLocalRemote(sync *Sync) bool {
+	remote := strings.TrimPrefix(sync.Remote, "git+")
+	u, err := url.Parse(remote)
+	if err != nil || u.Scheme == "" || u.Scheme == "file" {
+		// Paths and scp-like git remotes.
+		if host, _, ok := strings.Cut(remote, ":"); ok && strings.Contains(host, "@") {
+			_, host, _ = strings.Cut(host, "@")
+			return IsLocalURL("ssh://" + host)
+		}
+		return true
+	}
+	if u.Scheme == "s3" {
+		return sync.Endpoint != "" && IsLocalURL(sync.Endpoint)
+	}
+	return IsLocalURL(remote)
+}
+
+// restrictToLocal keeps the configuration from reaching outside in offline
+// mode. Providers detected from the environment that aren't local are left
+// out, the ones in the configuration files, as MCP servers and sync remotes,
+// are errors to fix.
+func (c *Config) restrictToLocal(configured map[string]bool) error {
+	var errs []error
+	for _, id := range slices.Sorted(maps.Keys(maps.Collect(c.Providers.Seq2()))) {
+		p, _ := c.Providers.Get(id)
+		if p.Disable {
+			continue
+		}
+		baseURL, err := c.resolver.ResolveValue(p.BaseURL)
+		if err == nil && IsLocalURL(baseURL) {
+			continue
+		}
+		if !configured[id] {
+			slog.Info("Skipping provider reaching outside in offline mode", "provider", id)
+			c.Providers.Del(id)
+			continue
+		}
+		if baseURL == "" {
+			baseURL = string(p.Type)
+		}
+		errs = append(errs, fmt.Errorf("provider %q reaches %s: disable it or use a local endpoint", id, baseURL))
+	}
+	for _, m := range c.MCP.Sorted() {
+		if m.MCP.Disabled || m.MCP.Type == MCPStdio || m.MCP.Type == "" {
+			continue
+		}
+		if !IsLocalURL(m.MCP.URL) {
+			errs = append(errs, fmt.Errorf("mcp server %q reaches %s: disable it or use a local server", m.Name, m.MCP.URL))
+		}
+	}
+	if c.Sync != nil && !isLocalRemote(c.Sync) {
+		errs = append(errs, fmt.Errorf("sync remote %s is not local", c.Sync.Remote))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("offline mode: %w", err)
+	}
+	return nil
+}
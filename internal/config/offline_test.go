@@ -0,0 +1,116 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsLocalURL(t *testing.T) {
+	t.Parallel()
+
+	for url, local := range map[string]bool{
+		"http://localhost:11434/v1":       true,
+		"http://127.0.0.1:1234/v1":        true,
+		"http://[::1]:8080":               true,
+		"http://192.168.1.20:11434":       true,
+		"http://ollama:11434":             true,
+		"http://gpu-box.local/v1":         true,
+		"https://llm.corp.internal/v1":    true,
+		"https://api.openai.com/v1":       false,
+		"https://8.8.8.8/v1":              false,
+		"localhost:11434":                 false,
+		"":                                false,
+		"https://models.example.com/v1/x": false,
+	} {
+		require.Equal(t, local, IsLocalURL(url), url)
+	}
+}
+
+func TestRestrictToLocal(t *testing.T) {
+	t.Parallel()
+
+	knownProviders := []catwalk.Provider{{
+		ID:          "openai",
+		APIKey:      "$OPENAI_API_KEY",
+		APIEndpoint: "https://api.openai.com/v1",
+		Models:      []catwalk.Model{{ID: "gpt"}},
+	}}
+	ollama := ProviderConfig{
+		BaseURL: "http://localhost:11434/v1",
+		Models:  []catwalk.Model{{ID: "llama"}},
+	}
+
+	newConfig := func(providers map[string]ProviderConfig) (*Config, map[string]bool) {
+		cfg := &Config{Providers: csync.NewMapFrom(providers)}
+		cfg.setDefaults(t.TempDir(), "")
+		env := env.NewFromMap(map[string]string{"OPENAI_API_KEY": "key"})
+		cfg.resolver = NewEnvironmentVariableResolver(env)
+		configured := make(map[string]bool)
+		for id := range providers {
+			configured[id] = true
+		}
+		require.NoError(t, cfg.configureProviders(env, cfg.resolver, knownProviders))
+		return cfg, configured
+	}
+
+	// Providers detected from the environment are left out.
+	cfg, configured := newConfig(map[string]ProviderConfig{"ollama": ollama})
+	require.NoError(t, cfg.restrictToLocal(configured))
+	require.Equal(t, 1, cfg.Providers.Len())
+	_, ok := cfg.Providers.Get("ollama")
+	require.True(t, ok)
+
+	// Configured ones are errors.
+	cfg, configured = newConfig(map[string]ProviderConfig{
+		"ollama": ollama,
+		"openai": {APIKey: "$OPENAI_API_KEY"},
+	})
+	cfg.MCP = MCPs{
+		"docs":  {Type: MCPHttp, URL: "https://mcp.example.com"},
+		"local": {Type: MCPSSE, URL: "http://localhost:3000/sse"},
+		"off":   {Type: MCPHttp, URL: "https://off.example.com", Disabled: true},
+	}
+	cfg.Sync = &Sync{Remote: "s3://bucket/crush"}
+	err := cfg.restrictToLocal(configured)
+	require.ErrorContains(t, err, `provider "openai" reaches https://api.openai.com/v1`)
+	require.ErrorContains(t, err, `mcp server "docs" reaches https://mcp.example.com`)
+	require.NotContains(t, err.Error(), `"local"`)
+	require.NotContains(t, err.Error(), `"off"`)
+	require.ErrorContains(t, err, "sync remote s3://bucket/crush is not local")
+
+	// Disabled ones are fine.
+	cfg, configured = newConfig(map[string]ProviderConfig{
+		"ollama": ollama,
+		"openai": {Disable: true},
+	})
+	cfg.Sync = &Sync{Remote: "/mnt/shared/crush"}
+	require.NoError(t, cfg.restrictToLocal(configured))
+}
+
+func TestOfflineDisablesOnlineTools(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{Options: &Options{Offline: true, DisabledTools: []string{"bash"}}}
+	cfg.SetupAgents()
+	tools := cfg.Agents[AgentCoder].AllowedTools
+	require.NotContains(t, tools, "fetch")
+	require.NotContains(t, tools, "download")
+	require.NotContains(t, tools, "sourcegraph")
+	require.NotContains(t, tools, "bash")
+	require.Contains(t, tools, "view")
+	require.Equal(t, []string{"bash"}, cfg.Options.DisabledTools)
+}
+
+func TestOfflineDisablesOutsideRequests(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{Options: &Options{Offline: true}}
+	cfg.setDefaults(t.TempDir(), "")
+	require.True(t, cfg.Options.DisableProviderAutoUpdate)
+	require.True(t, cfg.Options.DisableMetrics)
+	require.True(t, cfg.Options.DisableHealthChecks)
+}
@@ -86,6 +86,9 @@ func UpdateProviders(pathOrUrl string) error {
 	case pathOrUrl == "embedded":
 		providers = embedded.GetAll()
 	case strings.HasPrefix(pathOrUrl, "http://") || strings.HasPrefix(pathOrUrl, "https://"):
+		if OfflineFromEnv() && !IsLocalURL(pathOrUrl) {
+			return fmt.Errorf("offline mode: not fetching providers from %s, update them from a local file or embedded", pathOrUrl)
+		}
 		var err error
 		providers, err = catwalk.NewWithURL(pathOrUrl).GetProviders()
 		if err != nil {
@@ -121,6 +124,9 @@ func Providers(cfg *Config) ([]catwalk.Provider, error) {
 
 		autoUpdateDisabled := cfg.Options.DisableProviderAutoUpdate
 		providerList, providerErr = loadProviders(autoUpdateDisabled, client, path)
+		if providerErr == nil {
+			providerList, providerErr = loadModelOverrides(modelsOverrideFile(), providerList)
+		}
 	})
 	return providerList, providerErr
 }
@@ -0,0 +1,206 @@
+// Package control lets other processes drive a running crush instance through
+// a UNIX socket, e.g. to send a prompt from an editor keybinding.
+//
+// The protocol is a single JSON request per connection, answered with a single
+// JSON response.
+package control
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	CommandSend   = "send"
+	CommandStatus = "status"
+
+	// maxSocketPath is the longest socket path that works on every platform,
+	// macOS limits them to 104 bytes.
+	maxSocketPath = 100
+
+	requestTimeout = 10 * time.Second
+)
+
+// Request is a command sent to a running instance.
+type Request struct {
+	Command string `json:"command"`
+	// Text is the prompt of a send command.
+	Text string `json:"text,omitempty"`
+}
+
+// Response is the answer of a running instance to a request.
+type Response struct {
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// Status describes the state of a running instance.
+type Status struct {
+	PID           int    `json:"pid"`
+	WorkingDir    string `json:"working_dir"`
+	SessionID     string `json:"session_id,omitempty"`
+	SessionTitle  string `json:"session_title,omitempty"`
+	Busy          bool   `json:"busy"`
+	QueuedPrompts int    `json:"queued_prompts,omitempty"`
+	Model         string `json:"model,omitempty"`
+}
+
+// SendMsg asks the TUI to submit a prompt received through the control
+// socket, as if it was typed in the editor.
+type SendMsg struct {
+	Text string
+}
+
+// Handler executes the commands received by a server.
+type Handler interface {
+	Send(ctx context.Context, text string) error
+	Status(ctx context.Context) (Status, error)
+}
+
+// SocketPath returns the path of the control socket of the process with the
+// given PID. Sockets live next to the instance registry in the data
+// directory, unless that path is too long for a socket.
+func SocketPath(dataDir string, pid int) string {
+	name := strconv.Itoa(pid) + ".sock"
+	path := filepath.Join(dataDir, "instances", name)
+	if len(path) <= maxSocketPath {
+		return path
+	}
+	abs, err := filepath.Abs(dataDir)
+	if err != nil {
+		abs = dataDir
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(os.TempDir(), "crush-"+hex.EncodeToString(sum[:6])+"-"+name)
+}
+
+// Server accepts control requests on a UNIX socket.
+type Server struct {
+	listener net.Listener
+	handler  Handler
+	path     string
+	wg       sync.WaitGroup
+}
+
+// Listen starts serving control requests on the socket at path.
+func Listen(path string, handler Handler) (*Server, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create control socket directory: %w", err)
+	}
+	// A socket left behind by a crashed process with the same PID.
+	_ = os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on control socket: %w", err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict control socket: %w", err)
+	}
+
+	s := &Server{listener: listener, handler: handler, path: path}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Close stops the server and removes the socket.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	if rmErr := os.Remove(s.path); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+		return rmErr
+	}
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Error("Control socket stopped accepting connections", "error", err)
+			}
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		_ = json.NewEncoder(conn).Encode(Response{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	var resp Response
+	switch req.Command {
+	case CommandSend:
+		if req.Text == "" {
+			resp.Error = "text is required"
+		} else if err := s.handler.Send(ctx, req.Text); err != nil {
+			resp.Error = err.Error()
+		}
+	case CommandStatus:
+		status, err := s.handler.Status(ctx)
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Status = &status
+		}
+	default:
+		resp.Error = fmt.Sprintf("unknown command %q", req.Command)
+	}
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		slog.Debug("Failed to write control response", "error", err)
+	}
+}
+
+// Do sends a request to the instance listening on the socket at path and
+// returns its response. Errors reported by the instance are returned as
+// errors.
+func Do(ctx context.Context, path string, req Request) (Response, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to connect to crush: %w", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
@@ -0,0 +1,96 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHandler struct {
+	mu    sync.Mutex
+	sent  []string
+	err   error
+	state Status
+}
+
+func (h *fakeHandler) Send(_ context.Context, text string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.err != nil {
+		return h.err
+	}
+	h.sent = append(h.sent, text)
+	return nil
+}
+
+func (h *fakeHandler) Status(context.Context) (Status, error) {
+	return h.state, nil
+}
+
+func shortTempDir(t *testing.T) string {
+	t.Helper()
+	// t.TempDir() can exceed the maximum length of a socket path.
+	dir, err := os.MkdirTemp("", "crush-ctl")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(shortTempDir(t), "instances", "1.sock")
+	handler := &fakeHandler{state: Status{PID: 1, SessionID: "session", Busy: true}}
+	server, err := Listen(path, handler)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	_, err = Do(t.Context(), path, Request{Command: CommandSend, Text: "run tests"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"run tests"}, handler.sent)
+
+	resp, err := Do(t.Context(), path, Request{Command: CommandStatus})
+	require.NoError(t, err)
+	require.Equal(t, &handler.state, resp.Status)
+
+	_, err = Do(t.Context(), path, Request{Command: CommandSend})
+	require.EqualError(t, err, "text is required")
+
+	_, err = Do(t.Context(), path, Request{Command: "restart"})
+	require.EqualError(t, err, `unknown command "restart"`)
+
+	handler.mu.Lock()
+	handler.err = errors.New("no session")
+	handler.mu.Unlock()
+	_, err = Do(t.Context(), path, Request{Command: CommandSend, Text: "again"})
+	require.EqualError(t, err, "no session")
+
+	require.NoError(t, server.Close())
+	require.NoFileExists(t, path)
+
+	_, err = Do(t.Context(), path, Request{Command: CommandStatus})
+	require.Error(t, err)
+}
+
+func TestSocketPath(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, filepath.Join("/project/.crush", "instances", "42.sock"), SocketPath("/project/.crush", 42))
+
+	long := "/" + strings.Repeat("nested/", 20) + ".crush"
+	path := SocketPath(long, 42)
+	require.LessOrEqual(t, len(path), maxSocketPath+len(os.TempDir()))
+	require.Equal(t, os.TempDir(), filepath.Dir(path))
+	require.True(t, strings.HasSuffix(path, "-42.sock"))
+	require.Equal(t, path, SocketPath(long, 42), "the path must be stable")
+	require.NotEqual(t, path, SocketPath(long+"2", 42))
+}
@@ -0,0 +1,178 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/server"
+)
+
+// ErrDaemonStopped is returned by Attach when the daemon stops while the
+// agent is still working.
+var ErrDaemonStopped = errors.New("the daemon stopped")
+
+// Attach writes the messages of the session to w and, while the agent works
+// in it, what the agent does as it does it. It returns once the agent is
+// done or ctx is done, which detaches without stopping the agent.
+func Attach(ctx context.Context, c *Client, sessionID string, w io.Writer) error {
+	// Subscribe before reading the session, so nothing happens in between.
+	stream, err := c.Events(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	busy, err := c.Busy(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	sess, err := c.Session(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	p := newPrinter(w)
+	for _, msg := range sess.Messages {
+		p.message(msg)
+	}
+	if !busy {
+		return nil
+	}
+
+	for {
+		event, err := stream.Next()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, io.EOF) {
+			return ErrDaemonStopped
+		}
+		if err != nil {
+			return err
+		}
+		p.event(event)
+		if event.Type == server.EventRunFinished {
+			if event.Error != "" {
+				return errors.New(event.Error)
+			}
+			return nil
+		}
+	}
+}
+
+// printer writes messages and their changes as plain text, skipping what
+// was already written.
+type printer struct {
+	w io.Writer
+	// text is the length of the text written for each message.
+	text     map[string]int
+	calls    map[string]bool
+	results  map[string]bool
+	finished map[string]bool
+	// seen are the messages written, which events may announce again.
+	seen map[string]bool
+}
+
+func newPrinter(w io.Writer) *printer {
+	return &printer{
+		w:        w,
+		text:     make(map[string]int),
+		calls:    make(map[string]bool),
+		results:  make(map[string]bool),
+		finished: make(map[string]bool),
+		seen:     make(map[string]bool),
+	}
+}
+
+func (p *printer) message(msg export.Message) {
+	p.seen[msg.ID] = true
+	switch msg.Role {
+	case message.User:
+		fmt.Fprintf(p.w, "> %s\n\n", strings.ReplaceAll(msg.Text, "\n", "\n> "))
+		p.text[msg.ID] = len(msg.Text)
+	case message.Assistant:
+		p.delta(msg.ID, msg.Text, 0)
+		for _, call := range msg.ToolCalls {
+			if call.Finished {
+				p.toolCall(call)
+			}
+		}
+		if msg.FinishReason != "" {
+			p.finish(msg.ID, msg.FinishReason, msg.CancelReason)
+		}
+	case message.Tool:
+		for _, result := range msg.ToolResults {
+			p.toolResult(result)
+		}
+	}
+}
+
+func (p *printer) event(event server.Event) {
+	switch event.Type {
+	case server.EventMessage:
+		if !p.seen[event.MessageID] {
+			p.message(*event.Message)
+		}
+	case server.EventTextDelta:
+		p.delta(event.MessageID, event.Delta, event.Offset)
+	case server.EventToolCall:
+		p.toolCall(*event.ToolCall)
+	case server.EventToolResult:
+		p.toolResult(*event.ToolResult)
+	case server.EventFinish:
+		p.finish(event.MessageID, event.Finish.Reason, event.Finish.CancelReason)
+	}
+}
+
+// delta writes the part of the text starting at offset that wasn't written
+// yet.
+func (p *printer) delta(messageID, text string, offset int) {
+	written, end := p.text[messageID], offset+len(text)
+	if end <= written {
+		return
+	}
+	if offset < written {
+		text = text[written-offset:]
+	}
+	fmt.Fprint(p.w, text)
+	p.text[messageID] = end
+}
+
+func (p *printer) toolCall(call message.ToolCall) {
+	if p.calls[call.ID] {
+		return
+	}
+	p.calls[call.ID] = true
+	fmt.Fprintf(p.w, "\n→ %s\n", call.Name)
+}
+
+func (p *printer) toolResult(result export.ToolResult) {
+	if p.results[result.ToolCallID] || !result.IsError {
+		return
+	}
+	p.results[result.ToolCallID] = true
+	line, _, _ := strings.Cut(strings.TrimSpace(result.Content), "\n")
+	fmt.Fprintf(p.w, "  %s failed: %s\n", result.Name, line)
+}
+
+func (p *printer) finish(messageID string, reason message.FinishReason, cancel message.CancelReason) {
+	if p.finished[messageID] {
+		return
+	}
+	p.finished[messageID] = true
+	if p.text[messageID] > 0 {
+		fmt.Fprintln(p.w)
+	}
+	if reason != message.FinishReasonCanceled {
+		return
+	}
+	if cancel != "" {
+		fmt.Fprintf(p.w, "(canceled: %s)\n", cancel.Description())
+	} else {
+		fmt.Fprintln(p.w, "(canceled)")
+	}
+}
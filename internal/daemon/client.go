@@ -0,0 +1,178 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/server"
+)
+
+// baseURL is the URL of the daemon, whose host is ignored as requests are
+// sent to the socket.
+const baseURL = "http://crush"
+
+// maxEventSize is the size of the largest event read, which may carry the
+// whole output of a tool.
+const maxEventSize = 16 << 20
+
+// Client sends requests to a daemon.
+type Client struct {
+	http *http.Client
+}
+
+// NewClient returns a client of the daemon listening on the socket at path.
+func NewClient(path string) *Client {
+	return &Client{http: &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}}
+}
+
+// Ping returns an error if no daemon answers.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/ping", nil, nil)
+}
+
+// Stop asks the daemon to cancel what the agent is doing and exit.
+func (c *Client) Stop(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/stop", nil, nil)
+}
+
+// CreateSession creates a session with the title.
+func (c *Client) CreateSession(ctx context.Context, title string) (export.Session, error) {
+	var sess export.Session
+	err := c.do(ctx, http.MethodPost, "/sessions", map[string]string{"title": title}, &sess)
+	return sess, err
+}
+
+// Session returns the session with its messages.
+func (c *Client) Session(ctx context.Context, sessionID string) (export.Session, error) {
+	var sess export.Session
+	err := c.do(ctx, http.MethodGet, "/sessions/"+sessionID, nil, &sess)
+	return sess, err
+}
+
+// Busy reports whether the agent is working in the session.
+func (c *Client) Busy(ctx context.Context, sessionID string) (bool, error) {
+	var status server.SessionStatus
+	err := c.do(ctx, http.MethodGet, "/sessions/"+sessionID+"/status", nil, &status)
+	return status.Busy, err
+}
+
+// Prompt sends a prompt to the agent, which runs it in the background.
+func (c *Client) Prompt(ctx context.Context, sessionID, prompt string) error {
+	return c.do(ctx, http.MethodPost, "/sessions/"+sessionID+"/prompt", map[string]string{"prompt": prompt}, nil)
+}
+
+// Cancel cancels what the agent is doing in the session.
+func (c *Client) Cancel(ctx context.Context, sessionID string) error {
+	return c.do(ctx, http.MethodPost, "/sessions/"+sessionID+"/cancel", nil, nil)
+}
+
+// Events subscribes to the events of the session. Events sent once it
+// returns are read from the stream until ctx is done.
+func (c *Client) Events(ctx context.Context, sessionID string) (*Stream, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/sessions/"+sessionID+"/events", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the daemon: %w", err)
+	}
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, maxEventSize)
+	return &Stream{body: resp.Body, scanner: scanner}, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the daemon: %w", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to read the response of the daemon: %w", err)
+	}
+	return nil
+}
+
+// checkResponse returns the error the daemon answered with, if any.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		return fmt.Errorf("the daemon answered %s", resp.Status)
+	}
+	return errors.New(body.Error)
+}
+
+// Stream reads the events sent by the daemon.
+type Stream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+// Next returns the next event, or io.EOF once the daemon closed the stream.
+func (s *Stream) Next() (server.Event, error) {
+	for s.scanner.Scan() {
+		data, ok := strings.CutPrefix(s.scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event server.Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return server.Event{}, fmt.Errorf("invalid event: %w", err)
+		}
+		return event, nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return server.Event{}, err
+	}
+	return server.Event{}, io.EOF
+}
+
+// Close stops reading the events.
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,134 @@
+// Package daemon runs the agent in a background process that outlives the
+// terminal it was started from, so long tasks can be left running and
+// followed again later with crush attach.
+//
+// The daemon serves the HTTP API of the server package on a UNIX socket in
+// the data directory. Sessions are stored in the database shared by every
+// process, so what the agent did while nobody was attached is read from
+// there, and what it does next is streamed as events.
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+const (
+	socketName = "daemon.sock"
+
+	// maxSocketPath is the longest socket path that works on every platform,
+	// macOS limits them to 104 bytes.
+	maxSocketPath = 100
+
+	startTimeout = 10 * time.Second
+	pingInterval = 100 * time.Millisecond
+)
+
+// ErrRunning is returned by Serve when a daemon already serves the socket.
+var ErrRunning = errors.New("a crush daemon is already running for this project")
+
+// SocketPath returns the path of the socket of the daemon of the project
+// using the data directory, unless that path is too long for a socket.
+func SocketPath(dataDir string) string {
+	path := filepath.Join(dataDir, socketName)
+	if len(path) <= maxSocketPath {
+		return path
+	}
+	abs, err := filepath.Abs(dataDir)
+	if err != nil {
+		abs = dataDir
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(os.TempDir(), "crush-"+hex.EncodeToString(sum[:6])+"-"+socketName)
+}
+
+// Serve serves handler on the socket at path until ctx is done or a client
+// asks the daemon to stop.
+func Serve(ctx context.Context, path string, handler http.Handler) error {
+	if err := NewClient(path).Ping(ctx); err == nil {
+		return ErrRunning
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create daemon socket directory: %w", err)
+	}
+	// A socket left behind by a daemon that crashed.
+	_ = os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("failed to listen on daemon socket: %w", err)
+	}
+	defer os.Remove(path)
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict daemon socket: %w", err)
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	mux := http.NewServeMux()
+	mux.Handle("/", handler)
+	mux.HandleFunc("GET /ping", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /stop", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+		_ = http.NewResponseController(w).Flush()
+		stop()
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		// Event streams never go idle, so they are closed rather than
+		// waited for.
+		if err := srv.Close(); err != nil {
+			slog.Warn("Failed to close the daemon server", "error", err)
+		}
+	}()
+	if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Start runs exe with args in the background, detached from the terminal,
+// and waits until the daemon it starts answers on the socket at path.
+func Start(ctx context.Context, path, exe string, args ...string) error {
+	cmd := exec.Command(exe, args...)
+	cmd.SysProcAttr = detached()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start the daemon: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+	client := NewClient(path)
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-exited:
+			return fmt.Errorf("the daemon exited while starting, see crush logs: %v", err)
+		case <-ctx.Done():
+			return fmt.Errorf("the daemon did not start: %w", ctx.Err())
+		case <-ticker.C:
+			if client.Ping(ctx) == nil {
+				return nil
+			}
+		}
+	}
+}
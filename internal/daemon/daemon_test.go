@@ -0,0 +1,125 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/server"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+// slowAgent starts a response and finishes it once the attached client
+// asked whether it's busy.
+type slowAgent struct {
+	messages message.Service
+	started  chan struct{}
+	checked  chan struct{}
+	once     sync.Once
+	busy     atomic.Bool
+}
+
+func (a *slowAgent) Run(ctx context.Context, sessionID, prompt string, _ ...message.Attachment) (*fantasy.AgentResult, error) {
+	a.busy.Store(true)
+	defer a.busy.Store(false)
+	if _, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: prompt}},
+	}); err != nil {
+		return nil, err
+	}
+	msg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{Role: message.Assistant})
+	if err != nil {
+		return nil, err
+	}
+	update := func(step func()) error {
+		step()
+		update := msg
+		update.Parts = slices.Clone(msg.Parts)
+		return a.messages.Update(ctx, update)
+	}
+	if err := update(func() { msg.AppendContent("Hello ") }); err != nil {
+		return nil, err
+	}
+	close(a.started)
+	<-a.checked
+	if err := update(func() { msg.AppendContent("world") }); err != nil {
+		return nil, err
+	}
+	if err := update(func() { msg.AddFinish(message.FinishReasonEndTurn, "", "") }); err != nil {
+		return nil, err
+	}
+	return &fantasy.AgentResult{}, nil
+}
+
+func (a *slowAgent) Cancel(string) {}
+
+func (a *slowAgent) IsSessionBusy(string) bool {
+	busy := a.busy.Load()
+	a.once.Do(func() { close(a.checked) })
+	return busy
+}
+
+func TestDaemonAttach(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	messages := message.NewService(q, conn)
+	agent := &slowAgent{
+		messages: messages,
+		started:  make(chan struct{}),
+		checked:  make(chan struct{}),
+	}
+	handler := server.New(t.Context(), server.Options{
+		Sessions:    session.NewService(q),
+		Messages:    messages,
+		Permissions: permission.NewPermissionService(t.TempDir(), false, nil),
+		Agent:       agent,
+	})
+
+	path := SocketPath(t.TempDir())
+	served := make(chan error, 1)
+	go func() { served <- Serve(t.Context(), path, handler) }()
+	client := NewClient(path)
+	require.Eventually(t, func() bool { return client.Ping(t.Context()) == nil }, startTimeout, pingInterval)
+	require.ErrorIs(t, Serve(t.Context(), path, handler), ErrRunning)
+
+	sess, err := client.CreateSession(t.Context(), "Greeting")
+	require.NoError(t, err)
+	require.NoError(t, client.Prompt(t.Context(), sess.ID, "Say hello"))
+	<-agent.started
+
+	var out bytes.Buffer
+	require.NoError(t, Attach(t.Context(), client, sess.ID, &out))
+	require.Equal(t, "> Say hello\n\nHello world\n", out.String())
+
+	require.NoError(t, client.Stop(t.Context()))
+	require.NoError(t, <-served)
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestPrinterSkipsWhatWasWritten(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	p := newPrinter(&out)
+	p.message(export.Message{ID: "msg-1", Role: message.Assistant, Text: "Hello wor"})
+	p.event(server.Event{Type: server.EventTextDelta, MessageID: "msg-1", Delta: "Hello ", Offset: 0})
+	p.event(server.Event{Type: server.EventTextDelta, MessageID: "msg-1", Delta: "world", Offset: 6})
+	p.event(server.Event{Type: server.EventTextDelta, MessageID: "msg-1", Delta: "!", Offset: 11})
+	require.Equal(t, "Hello world!", out.String())
+}
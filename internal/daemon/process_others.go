@@ -0,0 +1,11 @@
+//go:build !windows
+
+package daemon
+
+import "syscall"
+
+// detached returns the attributes of a process started in its own session,
+// so it keeps running once the terminal that started it is closed.
+func detached() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package daemon
+
+import "syscall"
+
+// detachedProcess starts the process without a console.
+const detachedProcess = 0x00000008
+
+// detached returns the attributes of a process started without a console,
+// so it keeps running once the terminal that started it is closed.
+func detached() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess}
+}
@@ -18,8 +18,12 @@ func Connect(ctx context.Context, dataDir string) (*sql.DB, error) {
 	if dataDir == "" {
 		return nil, fmt.Errorf("data.dir is not set")
 	}
-	dbPath := filepath.Join(dataDir, "crush.db")
+	return Open(ctx, filepath.Join(dataDir, "crush.db"))
+}
 
+// Open opens the database at the path, creating it when missing, and
+// migrates it to the current schema.
+func Open(ctx context.Context, dbPath string) (*sql.DB, error) {
 	// Set pragmas for better performance
 	pragmas := []string{
 		"PRAGMA foreign_keys = ON;",
@@ -28,6 +32,9 @@ func Connect(ctx context.Context, dataDir string) (*sql.DB, error) {
 		"PRAGMA cache_size = -8000;",
 		"PRAGMA synchronous = NORMAL;",
 		"PRAGMA secure_delete = ON;",
+		// Wait for other crush processes writing to the database instead of
+		// failing right away.
+		"PRAGMA busy_timeout = 5000;",
 	}
 
 	db, err := driver.Open(dbPath, func(c *sqlite3.Conn) error {
@@ -24,6 +24,12 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.countMessagesBySessionStmt, err = db.PrepareContext(ctx, countMessagesBySession); err != nil {
+		return nil, fmt.Errorf("error preparing query CountMessagesBySession: %w", err)
+	}
+	if q.createArtifactStmt, err = db.PrepareContext(ctx, createArtifact); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateArtifact: %w", err)
+	}
 	if q.createFileStmt, err = db.PrepareContext(ctx, createFile); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateFile: %w", err)
 	}
@@ -33,21 +39,48 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.createSessionStmt, err = db.PrepareContext(ctx, createSession); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateSession: %w", err)
 	}
+	if q.createSessionForkStmt, err = db.PrepareContext(ctx, createSessionFork); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateSessionFork: %w", err)
+	}
+	if q.createSessionMergeStmt, err = db.PrepareContext(ctx, createSessionMerge); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateSessionMerge: %w", err)
+	}
+	if q.createSpendingStmt, err = db.PrepareContext(ctx, createSpending); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateSpending: %w", err)
+	}
+	if q.createToolAuditStmt, err = db.PrepareContext(ctx, createToolAudit); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateToolAudit: %w", err)
+	}
+	if q.createToolExecutionStmt, err = db.PrepareContext(ctx, createToolExecution); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateToolExecution: %w", err)
+	}
 	if q.deleteFileStmt, err = db.PrepareContext(ctx, deleteFile); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteFile: %w", err)
 	}
 	if q.deleteMessageStmt, err = db.PrepareContext(ctx, deleteMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteMessage: %w", err)
 	}
+	if q.deleteMessagesBySessionStmt, err = db.PrepareContext(ctx, deleteMessagesBySession); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMessagesBySession: %w", err)
+	}
 	if q.deleteSessionStmt, err = db.PrepareContext(ctx, deleteSession); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteSession: %w", err)
 	}
+	if q.deleteSessionFactStmt, err = db.PrepareContext(ctx, deleteSessionFact); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteSessionFact: %w", err)
+	}
 	if q.deleteSessionFilesStmt, err = db.PrepareContext(ctx, deleteSessionFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteSessionFiles: %w", err)
 	}
 	if q.deleteSessionMessagesStmt, err = db.PrepareContext(ctx, deleteSessionMessages); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteSessionMessages: %w", err)
 	}
+	if q.deleteSyncStateStmt, err = db.PrepareContext(ctx, deleteSyncState); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteSyncState: %w", err)
+	}
+	if q.getArtifactStmt, err = db.PrepareContext(ctx, getArtifact); err != nil {
+		return nil, fmt.Errorf("error preparing query GetArtifact: %w", err)
+	}
 	if q.getFileStmt, err = db.PrepareContext(ctx, getFile); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFile: %w", err)
 	}
@@ -60,35 +93,111 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getSessionByIDStmt, err = db.PrepareContext(ctx, getSessionByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetSessionByID: %w", err)
 	}
+	if q.getSessionForkStmt, err = db.PrepareContext(ctx, getSessionFork); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSessionFork: %w", err)
+	}
+	if q.getToolAuditStmt, err = db.PrepareContext(ctx, getToolAudit); err != nil {
+		return nil, fmt.Errorf("error preparing query GetToolAudit: %w", err)
+	}
+	if q.importMessageStmt, err = db.PrepareContext(ctx, importMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query ImportMessage: %w", err)
+	}
+	if q.importSessionStmt, err = db.PrepareContext(ctx, importSession); err != nil {
+		return nil, fmt.Errorf("error preparing query ImportSession: %w", err)
+	}
+	if q.listArtifactsStmt, err = db.PrepareContext(ctx, listArtifacts); err != nil {
+		return nil, fmt.Errorf("error preparing query ListArtifacts: %w", err)
+	}
+	if q.listArtifactsBySessionStmt, err = db.PrepareContext(ctx, listArtifactsBySession); err != nil {
+		return nil, fmt.Errorf("error preparing query ListArtifactsBySession: %w", err)
+	}
+	if q.listChildSessionsStmt, err = db.PrepareContext(ctx, listChildSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListChildSessions: %w", err)
+	}
 	if q.listFilesByPathStmt, err = db.PrepareContext(ctx, listFilesByPath); err != nil {
 		return nil, fmt.Errorf("error preparing query ListFilesByPath: %w", err)
 	}
 	if q.listFilesBySessionStmt, err = db.PrepareContext(ctx, listFilesBySession); err != nil {
 		return nil, fmt.Errorf("error preparing query ListFilesBySession: %w", err)
 	}
+	if q.listLatestMessagesStmt, err = db.PrepareContext(ctx, listLatestMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query ListLatestMessages: %w", err)
+	}
 	if q.listLatestSessionFilesStmt, err = db.PrepareContext(ctx, listLatestSessionFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListLatestSessionFiles: %w", err)
 	}
 	if q.listMessagesBySessionStmt, err = db.PrepareContext(ctx, listMessagesBySession); err != nil {
 		return nil, fmt.Errorf("error preparing query ListMessagesBySession: %w", err)
 	}
+	if q.listMessagesForRetentionStmt, err = db.PrepareContext(ctx, listMessagesForRetention); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMessagesForRetention: %w", err)
+	}
 	if q.listNewFilesStmt, err = db.PrepareContext(ctx, listNewFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListNewFiles: %w", err)
 	}
+	if q.listSessionFactsStmt, err = db.PrepareContext(ctx, listSessionFacts); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSessionFacts: %w", err)
+	}
+	if q.listSessionForksStmt, err = db.PrepareContext(ctx, listSessionForks); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSessionForks: %w", err)
+	}
+	if q.listSessionMergesStmt, err = db.PrepareContext(ctx, listSessionMerges); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSessionMerges: %w", err)
+	}
 	if q.listSessionsStmt, err = db.PrepareContext(ctx, listSessions); err != nil {
 		return nil, fmt.Errorf("error preparing query ListSessions: %w", err)
 	}
+	if q.listSpendingSinceStmt, err = db.PrepareContext(ctx, listSpendingSince); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSpendingSince: %w", err)
+	}
+	if q.listSyncStatesStmt, err = db.PrepareContext(ctx, listSyncStates); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSyncStates: %w", err)
+	}
+	if q.listToolAuditStmt, err = db.PrepareContext(ctx, listToolAudit); err != nil {
+		return nil, fmt.Errorf("error preparing query ListToolAudit: %w", err)
+	}
+	if q.listToolStatsStmt, err = db.PrepareContext(ctx, listToolStats); err != nil {
+		return nil, fmt.Errorf("error preparing query ListToolStats: %w", err)
+	}
+	if q.searchMessagesStmt, err = db.PrepareContext(ctx, searchMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchMessages: %w", err)
+	}
+	if q.setSessionFactStmt, err = db.PrepareContext(ctx, setSessionFact); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSessionFact: %w", err)
+	}
+	if q.setSyncStateStmt, err = db.PrepareContext(ctx, setSyncState); err != nil {
+		return nil, fmt.Errorf("error preparing query SetSyncState: %w", err)
+	}
 	if q.updateMessageStmt, err = db.PrepareContext(ctx, updateMessage); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateMessage: %w", err)
 	}
 	if q.updateSessionStmt, err = db.PrepareContext(ctx, updateSession); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateSession: %w", err)
 	}
+	if q.updateSessionEnvironmentStmt, err = db.PrepareContext(ctx, updateSessionEnvironment); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateSessionEnvironment: %w", err)
+	}
+	if q.updateSessionHandoffStmt, err = db.PrepareContext(ctx, updateSessionHandoff); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateSessionHandoff: %w", err)
+	}
+	if q.updateSessionPinnedMessagesStmt, err = db.PrepareContext(ctx, updateSessionPinnedMessages); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateSessionPinnedMessages: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.countMessagesBySessionStmt != nil {
+		if cerr := q.countMessagesBySessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countMessagesBySessionStmt: %w", cerr)
+		}
+	}
+	if q.createArtifactStmt != nil {
+		if cerr := q.createArtifactStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createArtifactStmt: %w", cerr)
+		}
+	}
 	if q.createFileStmt != nil {
 		if cerr := q.createFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createFileStmt: %w", cerr)
@@ -104,6 +213,31 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing createSessionStmt: %w", cerr)
 		}
 	}
+	if q.createSessionForkStmt != nil {
+		if cerr := q.createSessionForkStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createSessionForkStmt: %w", cerr)
+		}
+	}
+	if q.createSessionMergeStmt != nil {
+		if cerr := q.createSessionMergeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createSessionMergeStmt: %w", cerr)
+		}
+	}
+	if q.createSpendingStmt != nil {
+		if cerr := q.createSpendingStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createSpendingStmt: %w", cerr)
+		}
+	}
+	if q.createToolAuditStmt != nil {
+		if cerr := q.createToolAuditStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createToolAuditStmt: %w", cerr)
+		}
+	}
+	if q.createToolExecutionStmt != nil {
+		if cerr := q.createToolExecutionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createToolExecutionStmt: %w", cerr)
+		}
+	}
 	if q.deleteFileStmt != nil {
 		if cerr := q.deleteFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteFileStmt: %w", cerr)
@@ -114,11 +248,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteMessageStmt: %w", cerr)
 		}
 	}
+	if q.deleteMessagesBySessionStmt != nil {
+		if cerr := q.deleteMessagesBySessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMessagesBySessionStmt: %w", cerr)
+		}
+	}
 	if q.deleteSessionStmt != nil {
 		if cerr := q.deleteSessionStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteSessionStmt: %w", cerr)
 		}
 	}
+	if q.deleteSessionFactStmt != nil {
+		if cerr := q.deleteSessionFactStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteSessionFactStmt: %w", cerr)
+		}
+	}
 	if q.deleteSessionFilesStmt != nil {
 		if cerr := q.deleteSessionFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteSessionFilesStmt: %w", cerr)
@@ -129,6 +273,16 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteSessionMessagesStmt: %w", cerr)
 		}
 	}
+	if q.deleteSyncStateStmt != nil {
+		if cerr := q.deleteSyncStateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteSyncStateStmt: %w", cerr)
+		}
+	}
+	if q.getArtifactStmt != nil {
+		if cerr := q.getArtifactStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getArtifactStmt: %w", cerr)
+		}
+	}
 	if q.getFileStmt != nil {
 		if cerr := q.getFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getFileStmt: %w", cerr)
@@ -149,6 +303,41 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getSessionByIDStmt: %w", cerr)
 		}
 	}
+	if q.getSessionForkStmt != nil {
+		if cerr := q.getSessionForkStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSessionForkStmt: %w", cerr)
+		}
+	}
+	if q.getToolAuditStmt != nil {
+		if cerr := q.getToolAuditStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getToolAuditStmt: %w", cerr)
+		}
+	}
+	if q.importMessageStmt != nil {
+		if cerr := q.importMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing importMessageStmt: %w", cerr)
+		}
+	}
+	if q.importSessionStmt != nil {
+		if cerr := q.importSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing importSessionStmt: %w", cerr)
+		}
+	}
+	if q.listArtifactsStmt != nil {
+		if cerr := q.listArtifactsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listArtifactsStmt: %w", cerr)
+		}
+	}
+	if q.listArtifactsBySessionStmt != nil {
+		if cerr := q.listArtifactsBySessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listArtifactsBySessionStmt: %w", cerr)
+		}
+	}
+	if q.listChildSessionsStmt != nil {
+		if cerr := q.listChildSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listChildSessionsStmt: %w", cerr)
+		}
+	}
 	if q.listFilesByPathStmt != nil {
 		if cerr := q.listFilesByPathStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listFilesByPathStmt: %w", cerr)
@@ -159,6 +348,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listFilesBySessionStmt: %w", cerr)
 		}
 	}
+	if q.listLatestMessagesStmt != nil {
+		if cerr := q.listLatestMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listLatestMessagesStmt: %w", cerr)
+		}
+	}
 	if q.listLatestSessionFilesStmt != nil {
 		if cerr := q.listLatestSessionFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listLatestSessionFilesStmt: %w", cerr)
@@ -169,16 +363,71 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listMessagesBySessionStmt: %w", cerr)
 		}
 	}
+	if q.listMessagesForRetentionStmt != nil {
+		if cerr := q.listMessagesForRetentionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMessagesForRetentionStmt: %w", cerr)
+		}
+	}
 	if q.listNewFilesStmt != nil {
 		if cerr := q.listNewFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listNewFilesStmt: %w", cerr)
 		}
 	}
+	if q.listSessionFactsStmt != nil {
+		if cerr := q.listSessionFactsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSessionFactsStmt: %w", cerr)
+		}
+	}
+	if q.listSessionForksStmt != nil {
+		if cerr := q.listSessionForksStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSessionForksStmt: %w", cerr)
+		}
+	}
+	if q.listSessionMergesStmt != nil {
+		if cerr := q.listSessionMergesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSessionMergesStmt: %w", cerr)
+		}
+	}
 	if q.listSessionsStmt != nil {
 		if cerr := q.listSessionsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listSessionsStmt: %w", cerr)
 		}
 	}
+	if q.listSpendingSinceStmt != nil {
+		if cerr := q.listSpendingSinceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSpendingSinceStmt: %w", cerr)
+		}
+	}
+	if q.listSyncStatesStmt != nil {
+		if cerr := q.listSyncStatesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSyncStatesStmt: %w", cerr)
+		}
+	}
+	if q.listToolAuditStmt != nil {
+		if cerr := q.listToolAuditStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listToolAuditStmt: %w", cerr)
+		}
+	}
+	if q.listToolStatsStmt != nil {
+		if cerr := q.listToolStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listToolStatsStmt: %w", cerr)
+		}
+	}
+	if q.searchMessagesStmt != nil {
+		if cerr := q.searchMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchMessagesStmt: %w", cerr)
+		}
+	}
+	if q.setSessionFactStmt != nil {
+		if cerr := q.setSessionFactStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSessionFactStmt: %w", cerr)
+		}
+	}
+	if q.setSyncStateStmt != nil {
+		if cerr := q.setSyncStateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setSyncStateStmt: %w", cerr)
+		}
+	}
 	if q.updateMessageStmt != nil {
 		if cerr := q.updateMessageStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateMessageStmt: %w", cerr)
@@ -189,6 +438,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing updateSessionStmt: %w", cerr)
 		}
 	}
+	if q.updateSessionEnvironmentStmt != nil {
+		if cerr := q.updateSessionEnvironmentStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateSessionEnvironmentStmt: %w", cerr)
+		}
+	}
+	if q.updateSessionHandoffStmt != nil {
+		if cerr := q.updateSessionHandoffStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateSessionHandoffStmt: %w", cerr)
+		}
+	}
+	if q.updateSessionPinnedMessagesStmt != nil {
+		if cerr := q.updateSessionPinnedMessagesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateSessionPinnedMessagesStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -226,53 +490,119 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                          DBTX
-	tx                          *sql.Tx
-	createFileStmt              *sql.Stmt
-	createMessageStmt           *sql.Stmt
-	createSessionStmt           *sql.Stmt
-	deleteFileStmt              *sql.Stmt
-	deleteMessageStmt           *sql.Stmt
-	deleteSessionStmt           *sql.Stmt
-	deleteSessionFilesStmt      *sql.Stmt
-	deleteSessionMessagesStmt   *sql.Stmt
-	getFileStmt                 *sql.Stmt
-	getFileByPathAndSessionStmt *sql.Stmt
-	getMessageStmt              *sql.Stmt
-	getSessionByIDStmt          *sql.Stmt
-	listFilesByPathStmt         *sql.Stmt
-	listFilesBySessionStmt      *sql.Stmt
-	listLatestSessionFilesStmt  *sql.Stmt
-	listMessagesBySessionStmt   *sql.Stmt
-	listNewFilesStmt            *sql.Stmt
-	listSessionsStmt            *sql.Stmt
-	updateMessageStmt           *sql.Stmt
-	updateSessionStmt           *sql.Stmt
+	db                              DBTX
+	tx                              *sql.Tx
+	countMessagesBySessionStmt      *sql.Stmt
+	createArtifactStmt              *sql.Stmt
+	createFileStmt                  *sql.Stmt
+	createMessageStmt               *sql.Stmt
+	createSessionStmt               *sql.Stmt
+	createSessionForkStmt           *sql.Stmt
+	createSessionMergeStmt          *sql.Stmt
+	createSpendingStmt              *sql.Stmt
+	createToolAuditStmt             *sql.Stmt
+	createToolExecutionStmt         *sql.Stmt
+	deleteFileStmt                  *sql.Stmt
+	deleteMessageStmt               *sql.Stmt
+	deleteMessagesBySessionStmt     *sql.Stmt
+	deleteSessionStmt               *sql.Stmt
+	deleteSessionFactStmt           *sql.Stmt
+	deleteSessionFilesStmt          *sql.Stmt
+	deleteSessionMessagesStmt       *sql.Stmt
+	deleteSyncStateStmt             *sql.Stmt
+	getArtifactStmt                 *sql.Stmt
+	getFileStmt                     *sql.Stmt
+	getFileByPathAndSessionStmt     *sql.Stmt
+	getMessageStmt                  *sql.Stmt
+	getSessionByIDStmt              *sql.Stmt
+	getSessionForkStmt              *sql.Stmt
+	getToolAuditStmt                *sql.Stmt
+	importMessageStmt               *sql.Stmt
+	importSessionStmt               *sql.Stmt
+	listArtifactsStmt               *sql.Stmt
+	listArtifactsBySessionStmt      *sql.Stmt
+	listChildSessionsStmt           *sql.Stmt
+	listFilesByPathStmt             *sql.Stmt
+	listFilesBySessionStmt          *sql.Stmt
+	listLatestMessagesStmt          *sql.Stmt
+	listLatestSessionFilesStmt      *sql.Stmt
+	listMessagesBySessionStmt       *sql.Stmt
+	listMessagesForRetentionStmt    *sql.Stmt
+	listNewFilesStmt                *sql.Stmt
+	listSessionFactsStmt            *sql.Stmt
+	listSessionForksStmt            *sql.Stmt
+	listSessionMergesStmt           *sql.Stmt
+	listSessionsStmt                *sql.Stmt
+	listSpendingSinceStmt           *sql.Stmt
+	listSyncStatesStmt              *sql.Stmt
+	listToolAuditStmt               *sql.Stmt
+	listToolStatsStmt               *sql.Stmt
+	searchMessagesStmt              *sql.Stmt
+	setSessionFactStmt              *sql.Stmt
+	setSyncStateStmt                *sql.Stmt
+	updateMessageStmt               *sql.Stmt
+	updateSessionStmt               *sql.Stmt
+	updateSessionEnvironmentStmt    *sql.Stmt
+	updateSessionHandoffStmt        *sql.Stmt
+	updateSessionPinnedMessagesStmt *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                          tx,
-		tx:                          tx,
-		createFileStmt:              q.createFileStmt,
-		createMessageStmt:           q.createMessageStmt,
-		createSessionStmt:           q.createSessionStmt,
-		deleteFileStmt:              q.deleteFileStmt,
-		deleteMessageStmt:           q.deleteMessageStmt,
-		deleteSessionStmt:           q.deleteSessionStmt,
-		deleteSessionFilesStmt:      q.deleteSessionFilesStmt,
-		deleteSessionMessagesStmt:   q.deleteSessionMessagesStmt,
-		getFileStmt:                 q.getFileStmt,
-		getFileByPathAndSessionStmt: q.getFileByPathAndSessionStmt,
-		getMessageStmt:              q.getMessageStmt,
-		getSessionByIDStmt:          q.getSessionByIDStmt,
-		listFilesByPathStmt:         q.listFilesByPathStmt,
-		listFilesBySessionStmt:      q.listFilesBySessionStmt,
-		listLatestSessionFilesStmt:  q.listLatestSessionFilesStmt,
-		listMessagesBySessionStmt:   q.listMessagesBySessionStmt,
-		listNewFilesStmt:            q.listNewFilesStmt,
-		listSessionsStmt:            q.listSessionsStmt,
-		updateMessageStmt:           q.updateMessageStmt,
-		updateSessionStmt:           q.updateSessionStmt,
+		db:                              tx,
+		tx:                              tx,
+		countMessagesBySessionStmt:      q.countMessagesBySessionStmt,
+		createArtifactStmt:              q.createArtifactStmt,
+		createFileStmt:                  q.createFileStmt,
+		createMessageStmt:               q.createMessageStmt,
+		createSessionStmt:               q.createSessionStmt,
+		createSessionForkStmt:           q.createSessionForkStmt,
+		createSessionMergeStmt:          q.createSessionMergeStmt,
+		createSpendingStmt:              q.createSpendingStmt,
+		createToolAuditStmt:             q.createToolAuditStmt,
+		createToolExecutionStmt:         q.createToolExecutionStmt,
+		deleteFileStmt:                  q.deleteFileStmt,
+		deleteMessageStmt:               q.deleteMessageStmt,
+		deleteMessagesBySessionStmt:     q.deleteMessagesBySessionStmt,
+		deleteSessionStmt:               q.deleteSessionStmt,
+		deleteSessionFactStmt:           q.deleteSessionFactStmt,
+		deleteSessionFilesStmt:          q.deleteSessionFilesStmt,
+		deleteSessionMessagesStmt:       q.deleteSessionMessagesStmt,
+		deleteSyncStateStmt:             q.deleteSyncStateStmt,
+		getArtifactStmt:                 q.getArtifactStmt,
+		getFileStmt:                     q.getFileStmt,
+		getFileByPathAndSessionStmt:     q.getFileByPathAndSessionStmt,
+		getMessageStmt:                  q.getMessageStmt,
+		getSessionByIDStmt:              q.getSessionByIDStmt,
+		getSessionForkStmt:              q.getSessionForkStmt,
+		getToolAuditStmt:                q.getToolAuditStmt,
+		importMessageStmt:               q.importMessageStmt,
+		importSessionStmt:               q.importSessionStmt,
+		listArtifactsStmt:               q.listArtifactsStmt,
+		listArtifactsBySessionStmt:      q.listArtifactsBySessionStmt,
+		listChildSessionsStmt:           q.listChildSessionsStmt,
+		listFilesByPathStmt:             q.listFilesByPathStmt,
+		listFilesBySessionStmt:          q.listFilesBySessionStmt,
+		listLatestMessagesStmt:          q.listLatestMessagesStmt,
+		listLatestSessionFilesStmt:      q.listLatestSessionFilesStmt,
+		listMessagesBySessionStmt:       q.listMessagesBySessionStmt,
+		listMessagesForRetentionStmt:    q.listMessagesForRetentionStmt,
+		listNewFilesStmt:                q.listNewFilesStmt,
+		listSessionFactsStmt:            q.listSessionFactsStmt,
+		listSessionForksStmt:            q.listSessionForksStmt,
+		listSessionMergesStmt:           q.listSessionMergesStmt,
+		listSessionsStmt:                q.listSessionsStmt,
+		listSpendingSinceStmt:           q.listSpendingSinceStmt,
+		listSyncStatesStmt:              q.listSyncStatesStmt,
+		listToolAuditStmt:               q.listToolAuditStmt,
+		listToolStatsStmt:               q.listToolStatsStmt,
+		searchMessagesStmt:              q.searchMessagesStmt,
+		setSessionFactStmt:              q.setSessionFactStmt,
+		setSyncStateStmt:                q.setSyncStateStmt,
+		updateMessageStmt:               q.updateMessageStmt,
+		updateSessionStmt:               q.updateSessionStmt,
+		updateSessionEnvironmentStmt:    q.updateSessionEnvironmentStmt,
+		updateSessionHandoffStmt:        q.updateSessionHandoffStmt,
+		updateSessionPinnedMessagesStmt: q.updateSessionPinnedMessagesStmt,
 	}
 }
@@ -10,6 +10,27 @@ import (
 	"database/sql"
 )
 
+const countMessagesBySession = `-- name: CountMessagesBySession :one
+SELECT COUNT(*)
+FROM messages
+WHERE session_id = ?1
+    AND (?2 IS NULL OR role = ?2)
+    AND (?3 IS NULL OR created_at < ?3)
+`
+
+type CountMessagesBySessionParams struct {
+	SessionID     string         `json:"session_id"`
+	Role          sql.NullString `json:"role"`
+	CreatedBefore sql.NullInt64  `json:"created_before"`
+}
+
+func (q *Queries) CountMessagesBySession(ctx context.Context, arg CountMessagesBySessionParams) (int64, error) {
+	row := q.queryRow(ctx, q.countMessagesBySessionStmt, countMessagesBySession, arg.SessionID, arg.Role, arg.CreatedBefore)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createMessage = `-- name: CreateMessage :one
 INSERT INTO messages (
     id,
@@ -73,6 +94,54 @@ func (q *Queries) DeleteMessage(ctx context.Context, id string) error {
 	return err
 }
 
+const deleteMessagesBySession = `-- name: DeleteMessagesBySession :many
+DELETE FROM messages
+WHERE session_id = ?1
+    AND (?2 IS NULL OR role = ?2)
+    AND (?3 IS NULL OR created_at < ?3)
+RETURNING id, session_id, role, parts, model, created_at, updated_at, finished_at, provider, is_summary_message
+`
+
+type DeleteMessagesBySessionParams struct {
+	SessionID     string         `json:"session_id"`
+	Role          sql.NullString `json:"role"`
+	CreatedBefore sql.NullInt64  `json:"created_before"`
+}
+
+func (q *Queries) DeleteMessagesBySession(ctx context.Context, arg DeleteMessagesBySessionParams) ([]Message, error) {
+	rows, err := q.query(ctx, q.deleteMessagesBySessionStmt, deleteMessagesBySession, arg.SessionID, arg.Role, arg.CreatedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Message{}
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.SessionID,
+			&i.Role,
+			&i.Parts,
+			&i.Model,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FinishedAt,
+			&i.Provider,
+			&i.IsSummaryMessage,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const deleteSessionMessages = `-- name: DeleteSessionMessages :exec
 DELETE FROM messages
 WHERE session_id = ?
@@ -107,6 +176,52 @@ func (q *Queries) GetMessage(ctx context.Context, id string) (Message, error) {
 	return i, err
 }
 
+const listLatestMessages = `-- name: ListLatestMessages :many
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, provider, is_summary_message
+FROM (
+    SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, provider, is_summary_message, ROW_NUMBER() OVER (
+        PARTITION BY session_id ORDER BY created_at DESC, rowid DESC
+    ) AS position
+    FROM messages
+)
+WHERE position <= ?
+ORDER BY session_id, position DESC
+`
+
+func (q *Queries) ListLatestMessages(ctx context.Context, limit int64) ([]Message, error) {
+	rows, err := q.query(ctx, q.listLatestMessagesStmt, listLatestMessages, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Message{}
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.SessionID,
+			&i.Role,
+			&i.Parts,
+			&i.Model,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FinishedAt,
+			&i.Provider,
+			&i.IsSummaryMessage,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listMessagesBySession = `-- name: ListMessagesBySession :many
 SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, provider, is_summary_message
 FROM messages
@@ -148,6 +263,132 @@ func (q *Queries) ListMessagesBySession(ctx context.Context, sessionID string) (
 	return items, nil
 }
 
+const listMessagesForRetention = `-- name: ListMessagesForRetention :many
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, provider, is_summary_message
+FROM messages
+WHERE EXISTS (
+    SELECT 1
+    FROM json_each(messages.parts)
+    WHERE (messages.created_at < ?1 AND json_extract(value, '$.type') NOT IN ('finish', 'run_summary'))
+        OR (?2 = 1 AND json_extract(value, '$.type') = 'reasoning')
+        OR (?3 = 1 AND json_extract(value, '$.type') IN ('binary', 'image_url'))
+)
+ORDER BY created_at ASC
+`
+
+type ListMessagesForRetentionParams struct {
+	CreatedBefore   sql.NullInt64 `json:"created_before"`
+	DropReasoning   int64         `json:"drop_reasoning"`
+	DropAttachments int64         `json:"drop_attachments"`
+}
+
+func (q *Queries) ListMessagesForRetention(ctx context.Context, arg ListMessagesForRetentionParams) ([]Message, error) {
+	rows, err := q.query(ctx, q.listMessagesForRetentionStmt, listMessagesForRetention, arg.CreatedBefore, arg.DropReasoning, arg.DropAttachments)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Message{}
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.SessionID,
+			&i.Role,
+			&i.Parts,
+			&i.Model,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FinishedAt,
+			&i.Provider,
+			&i.IsSummaryMessage,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchMessages = `-- name: SearchMessages :many
+SELECT
+    m.id,
+    m.session_id,
+    m.role,
+    m.created_at,
+    s.title AS session_title,
+    CAST(snippet(messages_fts, 0, ?1, ?2, '...', 16) AS TEXT) AS snippet,
+    CAST(bm25(messages_fts) AS REAL) AS rank
+FROM messages_fts
+JOIN messages m ON m.rowid = messages_fts.rowid
+JOIN sessions s ON s.id = m.session_id
+WHERE messages_fts MATCH ?3
+    AND (?4 IS NULL OR m.session_id = ?4)
+ORDER BY rank ASC, m.created_at DESC
+LIMIT ?5
+`
+
+type SearchMessagesParams struct {
+	HighlightStart interface{}    `json:"highlight_start"`
+	HighlightEnd   interface{}    `json:"highlight_end"`
+	Query          string         `json:"query"`
+	SessionID      sql.NullString `json:"session_id"`
+	Limit          int64          `json:"limit"`
+}
+
+type SearchMessagesRow struct {
+	ID           string  `json:"id"`
+	SessionID    string  `json:"session_id"`
+	Role         string  `json:"role"`
+	CreatedAt    int64   `json:"created_at"`
+	SessionTitle string  `json:"session_title"`
+	Snippet      string  `json:"snippet"`
+	Rank         float64 `json:"rank"`
+}
+
+func (q *Queries) SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]SearchMessagesRow, error) {
+	rows, err := q.query(ctx, q.searchMessagesStmt, searchMessages,
+		arg.HighlightStart,
+		arg.HighlightEnd,
+		arg.Query,
+		arg.SessionID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchMessagesRow{}
+	for rows.Next() {
+		var i SearchMessagesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.SessionID,
+			&i.Role,
+			&i.CreatedAt,
+			&i.SessionTitle,
+			&i.Snippet,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateMessage = `-- name: UpdateMessage :exec
 UPDATE messages
 SET
@@ -167,3 +408,49 @@ func (q *Queries) UpdateMessage(ctx context.Context, arg UpdateMessageParams) er
 	_, err := q.exec(ctx, q.updateMessageStmt, updateMessage, arg.Parts, arg.FinishedAt, arg.ID)
 	return err
 }
+
+const importMessage = `-- name: ImportMessage :exec
+INSERT INTO messages (
+    id,
+    session_id,
+    role,
+    parts,
+    model,
+    provider,
+    is_summary_message,
+    created_at,
+    updated_at,
+    finished_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+`
+
+type ImportMessageParams struct {
+	ID               string         `json:"id"`
+	SessionID        string         `json:"session_id"`
+	Role             string         `json:"role"`
+	Parts            string         `json:"parts"`
+	Model            sql.NullString `json:"model"`
+	Provider         sql.NullString `json:"provider"`
+	IsSummaryMessage int64          `json:"is_summary_message"`
+	CreatedAt        int64          `json:"created_at"`
+	UpdatedAt        int64          `json:"updated_at"`
+	FinishedAt       sql.NullInt64  `json:"finished_at"`
+}
+
+func (q *Queries) ImportMessage(ctx context.Context, arg ImportMessageParams) error {
+	_, err := q.exec(ctx, q.importMessageStmt, importMessage,
+		arg.ID,
+		arg.SessionID,
+		arg.Role,
+		arg.Parts,
+		arg.Model,
+		arg.Provider,
+		arg.IsSummaryMessage,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.FinishedAt,
+	)
+	return err
+}
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Listing, counting and deleting the messages of a session filter by session
+CREATE INDEX IF NOT EXISTS idx_messages_session_id_created_at ON messages (session_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_messages_session_id_created_at;
+-- +goose StatementEnd
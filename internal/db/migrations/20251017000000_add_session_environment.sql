@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Per session working directory and environment variables (JSON object)
+ALTER TABLE sessions ADD COLUMN working_dir TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN env TEXT NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN env;
+ALTER TABLE sessions DROP COLUMN working_dir;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Handoff document shared with child sessions and the messages pinned for them (JSON array of IDs)
+ALTER TABLE sessions ADD COLUMN handoff TEXT NOT NULL DEFAULT '';
+ALTER TABLE sessions ADD COLUMN pinned_message_ids TEXT NOT NULL DEFAULT '[]';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN pinned_message_ids;
+ALTER TABLE sessions DROP COLUMN handoff;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Content hash of each session the last time it was synced with a remote
+CREATE TABLE IF NOT EXISTS sync_state (
+    remote TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    synced_at INTEGER NOT NULL,  -- Unix timestamp in milliseconds
+    PRIMARY KEY (remote, session_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS sync_state;
+-- +goose StatementEnd
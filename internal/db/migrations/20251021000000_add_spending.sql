@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Cost of each model call, kept when sessions are deleted to report the
+-- spending over time
+CREATE TABLE IF NOT EXISTS spending (
+    id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    model TEXT NOT NULL,
+    cost REAL NOT NULL,
+    created_at INTEGER NOT NULL  -- Unix timestamp in seconds
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS idx_spending_created_at ON spending (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_spending_created_at;
+DROP TABLE IF EXISTS spending;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Sessions merged into another one, kept after they're deleted to tell where
+-- the messages of the merged session came from
+CREATE TABLE IF NOT EXISTS session_merges (
+    session_id TEXT NOT NULL,
+    source_session_id TEXT NOT NULL,
+    source_title TEXT NOT NULL,
+    message_count INTEGER NOT NULL,
+    duplicate_count INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    PRIMARY KEY (session_id, source_session_id),
+    FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS session_merges;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Sessions forked from another one, at one of its messages, kept after the
+-- session forked is deleted
+CREATE TABLE IF NOT EXISTS session_forks (
+    session_id TEXT PRIMARY KEY,
+    source_session_id TEXT NOT NULL,
+    source_message_id TEXT,
+    message_count INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+    FOREIGN KEY (session_id) REFERENCES sessions (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_forks_source_session_id ON session_forks (source_session_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_session_forks_source_session_id;
+DROP TABLE IF EXISTS session_forks;
+-- +goose StatementEnd
@@ -0,0 +1,75 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Full-text index of the text, tool calls and tool results of messages,
+-- keyed by the rowid of the message.
+CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+    text,
+    tokenize = 'porter unicode61'
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+INSERT INTO messages_fts (rowid, text)
+SELECT messages.rowid, (
+    SELECT group_concat(coalesce(
+        json_extract(value, '$.data.text'),
+        json_extract(value, '$.data.input'),
+        json_extract(value, '$.data.content')
+    ), char(10))
+    FROM json_each(messages.parts)
+    WHERE json_extract(value, '$.type') IN ('text', 'tool_call', 'tool_result')
+)
+FROM messages;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS index_messages_fts_on_insert
+AFTER INSERT ON messages
+BEGIN
+INSERT INTO messages_fts (rowid, text)
+VALUES (new.rowid, (
+    SELECT group_concat(coalesce(
+        json_extract(value, '$.data.text'),
+        json_extract(value, '$.data.input'),
+        json_extract(value, '$.data.content')
+    ), char(10))
+    FROM json_each(new.parts)
+    WHERE json_extract(value, '$.type') IN ('text', 'tool_call', 'tool_result')
+));
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS index_messages_fts_on_update
+AFTER UPDATE OF parts ON messages
+WHEN new.parts != old.parts
+BEGIN
+DELETE FROM messages_fts WHERE rowid = old.rowid;
+INSERT INTO messages_fts (rowid, text)
+VALUES (new.rowid, (
+    SELECT group_concat(coalesce(
+        json_extract(value, '$.data.text'),
+        json_extract(value, '$.data.input'),
+        json_extract(value, '$.data.content')
+    ), char(10))
+    FROM json_each(new.parts)
+    WHERE json_extract(value, '$.type') IN ('text', 'tool_call', 'tool_result')
+));
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS index_messages_fts_on_delete
+AFTER DELETE ON messages
+BEGIN
+DELETE FROM messages_fts WHERE rowid = old.rowid;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS index_messages_fts_on_delete;
+DROP TRIGGER IF EXISTS index_messages_fts_on_update;
+DROP TRIGGER IF EXISTS index_messages_fts_on_insert;
+DROP TABLE IF EXISTS messages_fts;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Kept when the session is deleted, the audit log outlives the sessions.
+CREATE TABLE IF NOT EXISTS tool_audit (
+    id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    message_id TEXT NOT NULL DEFAULT '',
+    tool_call_id TEXT NOT NULL DEFAULT '',
+    tool_name TEXT NOT NULL,
+    input TEXT NOT NULL,
+    output_hash TEXT NOT NULL,
+    output_size INTEGER NOT NULL DEFAULT 0,
+    duration_ms INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    exit_code INTEGER,
+    created_at INTEGER NOT NULL  -- Unix timestamp in seconds
+);
+
+CREATE INDEX IF NOT EXISTS idx_tool_audit_created_at ON tool_audit (created_at);
+CREATE INDEX IF NOT EXISTS idx_tool_audit_session_id ON tool_audit (session_id);
+CREATE INDEX IF NOT EXISTS idx_tool_audit_tool_name ON tool_audit (tool_name);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_tool_audit_tool_name;
+DROP INDEX IF EXISTS idx_tool_audit_session_id;
+DROP INDEX IF EXISTS idx_tool_audit_created_at;
+DROP TABLE IF EXISTS tool_audit;
+-- +goose StatementEnd
@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Messages are updated with each delta while they stream, they are only
+-- indexed again once finished.
+DROP TRIGGER IF EXISTS index_messages_fts_on_update;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS index_messages_fts_on_update
+AFTER UPDATE OF parts ON messages
+WHEN new.parts != old.parts
+AND EXISTS (
+    SELECT 1 FROM json_each(new.parts)
+    WHERE json_extract(value, '$.type') = 'finish'
+)
+BEGIN
+DELETE FROM messages_fts WHERE rowid = old.rowid;
+INSERT INTO messages_fts (rowid, text)
+VALUES (new.rowid, (
+    SELECT group_concat(coalesce(
+        json_extract(value, '$.data.text'),
+        json_extract(value, '$.data.input'),
+        json_extract(value, '$.data.content')
+    ), char(10))
+    FROM json_each(new.parts)
+    WHERE json_extract(value, '$.type') IN ('text', 'tool_call', 'tool_result')
+));
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS index_messages_fts_on_update;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER IF NOT EXISTS index_messages_fts_on_update
+AFTER UPDATE OF parts ON messages
+WHEN new.parts != old.parts
+BEGIN
+DELETE FROM messages_fts WHERE rowid = old.rowid;
+INSERT INTO messages_fts (rowid, text)
+VALUES (new.rowid, (
+    SELECT group_concat(coalesce(
+        json_extract(value, '$.data.text'),
+        json_extract(value, '$.data.input'),
+        json_extract(value, '$.data.content')
+    ), char(10))
+    FROM json_each(new.parts)
+    WHERE json_extract(value, '$.type') IN ('text', 'tool_call', 'tool_result')
+));
+END;
+-- +goose StatementEnd
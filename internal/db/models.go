@@ -8,6 +8,18 @@ import (
 	"database/sql"
 )
 
+type Artifact struct {
+	ID          string `json:"id"`
+	SessionID   string `json:"session_id"`
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	Path        string `json:"path"`
+	SourcePath  string `json:"source_path"`
+	Size        int64  `json:"size"`
+	CreatedAt   int64  `json:"created_at"`
+}
+
 type File struct {
 	ID        string `json:"id"`
 	SessionID string `json:"session_id"`
@@ -42,4 +54,75 @@ type Session struct {
 	UpdatedAt        int64          `json:"updated_at"`
 	CreatedAt        int64          `json:"created_at"`
 	SummaryMessageID sql.NullString `json:"summary_message_id"`
+	WorkingDir       string         `json:"working_dir"`
+	Env              string         `json:"env"`
+	Handoff          string         `json:"handoff"`
+	PinnedMessageIds string         `json:"pinned_message_ids"`
+}
+
+type SessionFact struct {
+	SessionID string `json:"session_id"`
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	Category  string `json:"category"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+type SessionFork struct {
+	SessionID       string         `json:"session_id"`
+	SourceSessionID string         `json:"source_session_id"`
+	SourceMessageID sql.NullString `json:"source_message_id"`
+	MessageCount    int64          `json:"message_count"`
+	CreatedAt       int64          `json:"created_at"`
+}
+
+type SessionMerge struct {
+	SessionID       string `json:"session_id"`
+	SourceSessionID string `json:"source_session_id"`
+	SourceTitle     string `json:"source_title"`
+	MessageCount    int64  `json:"message_count"`
+	DuplicateCount  int64  `json:"duplicate_count"`
+	CreatedAt       int64  `json:"created_at"`
+}
+
+type Spending struct {
+	ID        string  `json:"id"`
+	SessionID string  `json:"session_id"`
+	Provider  string  `json:"provider"`
+	Model     string  `json:"model"`
+	Cost      float64 `json:"cost"`
+	CreatedAt int64   `json:"created_at"`
+}
+
+type SyncState struct {
+	Remote    string `json:"remote"`
+	SessionID string `json:"session_id"`
+	Hash      string `json:"hash"`
+	SyncedAt  int64  `json:"synced_at"`
+}
+
+type ToolAudit struct {
+	ID         string        `json:"id"`
+	SessionID  string        `json:"session_id"`
+	MessageID  string        `json:"message_id"`
+	ToolCallID string        `json:"tool_call_id"`
+	ToolName   string        `json:"tool_name"`
+	Input      string        `json:"input"`
+	OutputHash string        `json:"output_hash"`
+	OutputSize int64         `json:"output_size"`
+	DurationMs int64         `json:"duration_ms"`
+	Status     string        `json:"status"`
+	ExitCode   sql.NullInt64 `json:"exit_code"`
+	CreatedAt  int64         `json:"created_at"`
+}
+
+type ToolExecution struct {
+	ID         string `json:"id"`
+	SessionID  string `json:"session_id"`
+	ToolName   string `json:"tool_name"`
+	DurationMs int64  `json:"duration_ms"`
+	OutputSize int64  `json:"output_size"`
+	IsError    int64  `json:"is_error"`
+	CreatedAt  int64  `json:"created_at"`
 }
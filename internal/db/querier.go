@@ -6,29 +6,63 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 type Querier interface {
+	CountMessagesBySession(ctx context.Context, arg CountMessagesBySessionParams) (int64, error)
+	CreateArtifact(ctx context.Context, arg CreateArtifactParams) (Artifact, error)
 	CreateFile(ctx context.Context, arg CreateFileParams) (File, error)
 	CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateSessionFork(ctx context.Context, arg CreateSessionForkParams) error
+	CreateSessionMerge(ctx context.Context, arg CreateSessionMergeParams) error
+	CreateSpending(ctx context.Context, arg CreateSpendingParams) (Spending, error)
+	CreateToolAudit(ctx context.Context, arg CreateToolAuditParams) (ToolAudit, error)
+	CreateToolExecution(ctx context.Context, arg CreateToolExecutionParams) (ToolExecution, error)
 	DeleteFile(ctx context.Context, id string) error
 	DeleteMessage(ctx context.Context, id string) error
+	DeleteMessagesBySession(ctx context.Context, arg DeleteMessagesBySessionParams) ([]Message, error)
 	DeleteSession(ctx context.Context, id string) error
+	DeleteSessionFact(ctx context.Context, arg DeleteSessionFactParams) (int64, error)
 	DeleteSessionFiles(ctx context.Context, sessionID string) error
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
+	DeleteSyncState(ctx context.Context, arg DeleteSyncStateParams) error
+	GetArtifact(ctx context.Context, id string) (Artifact, error)
 	GetFile(ctx context.Context, id string) (File, error)
 	GetFileByPathAndSession(ctx context.Context, arg GetFileByPathAndSessionParams) (File, error)
 	GetMessage(ctx context.Context, id string) (Message, error)
 	GetSessionByID(ctx context.Context, id string) (Session, error)
+	GetSessionFork(ctx context.Context, sessionID string) (SessionFork, error)
+	GetToolAudit(ctx context.Context, id string) (ToolAudit, error)
+	ImportMessage(ctx context.Context, arg ImportMessageParams) error
+	ImportSession(ctx context.Context, arg ImportSessionParams) error
+	ListArtifacts(ctx context.Context) ([]Artifact, error)
+	ListArtifactsBySession(ctx context.Context, sessionID string) ([]Artifact, error)
+	ListChildSessions(ctx context.Context, parentSessionID sql.NullString) ([]Session, error)
 	ListFilesByPath(ctx context.Context, path string) ([]File, error)
 	ListFilesBySession(ctx context.Context, sessionID string) ([]File, error)
 	ListLatestSessionFiles(ctx context.Context, sessionID string) ([]File, error)
+	ListLatestMessages(ctx context.Context, limit int64) ([]Message, error)
 	ListMessagesBySession(ctx context.Context, sessionID string) ([]Message, error)
+	ListMessagesForRetention(ctx context.Context, arg ListMessagesForRetentionParams) ([]Message, error)
 	ListNewFiles(ctx context.Context) ([]File, error)
+	ListSessionFacts(ctx context.Context, sessionID string) ([]SessionFact, error)
+	ListSessionForks(ctx context.Context, sourceSessionID string) ([]SessionFork, error)
+	ListSessionMerges(ctx context.Context, sessionID string) ([]SessionMerge, error)
 	ListSessions(ctx context.Context) ([]Session, error)
+	ListSpendingSince(ctx context.Context, createdAt int64) ([]Spending, error)
+	ListSyncStates(ctx context.Context, remote string) ([]SyncState, error)
+	ListToolAudit(ctx context.Context, arg ListToolAuditParams) ([]ToolAudit, error)
+	ListToolStats(ctx context.Context) ([]ListToolStatsRow, error)
+	SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]SearchMessagesRow, error)
+	SetSessionFact(ctx context.Context, arg SetSessionFactParams) (SessionFact, error)
+	SetSyncState(ctx context.Context, arg SetSyncStateParams) error
 	UpdateMessage(ctx context.Context, arg UpdateMessageParams) error
 	UpdateSession(ctx context.Context, arg UpdateSessionParams) (Session, error)
+	UpdateSessionEnvironment(ctx context.Context, arg UpdateSessionEnvironmentParams) (Session, error)
+	UpdateSessionHandoff(ctx context.Context, arg UpdateSessionHandoffParams) (Session, error)
+	UpdateSessionPinnedMessages(ctx context.Context, arg UpdateSessionPinnedMessagesParams) (Session, error)
 }
 
 var _ Querier = (*Queries)(nil)
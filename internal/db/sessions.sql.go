@@ -33,7 +33,7 @@ INSERT INTO sessions (
     null,
     strftime('%s', 'now'),
     strftime('%s', 'now')
-) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+) RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, working_dir, env, handoff, pinned_message_ids
 `
 
 type CreateSessionParams struct {
@@ -68,6 +68,10 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.WorkingDir,
+		&i.Env,
+		&i.Handoff,
+		&i.PinnedMessageIds,
 	)
 	return i, err
 }
@@ -83,7 +87,7 @@ func (q *Queries) DeleteSession(ctx context.Context, id string) error {
 }
 
 const getSessionByID = `-- name: GetSessionByID :one
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, working_dir, env, handoff, pinned_message_ids
 FROM sessions
 WHERE id = ? LIMIT 1
 `
@@ -102,12 +106,16 @@ func (q *Queries) GetSessionByID(ctx context.Context, id string) (Session, error
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.WorkingDir,
+		&i.Env,
+		&i.Handoff,
+		&i.PinnedMessageIds,
 	)
 	return i, err
 }
 
 const listSessions = `-- name: ListSessions :many
-SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, working_dir, env, handoff, pinned_message_ids
 FROM sessions
 WHERE parent_session_id is NULL
 ORDER BY created_at DESC
@@ -133,6 +141,10 @@ func (q *Queries) ListSessions(ctx context.Context) ([]Session, error) {
 			&i.UpdatedAt,
 			&i.CreatedAt,
 			&i.SummaryMessageID,
+			&i.WorkingDir,
+			&i.Env,
+			&i.Handoff,
+			&i.PinnedMessageIds,
 		); err != nil {
 			return nil, err
 		}
@@ -156,7 +168,7 @@ SET
     summary_message_id = ?,
     cost = ?
 WHERE id = ?
-RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, working_dir, env, handoff, pinned_message_ids
 `
 
 type UpdateSessionParams struct {
@@ -189,6 +201,219 @@ func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (S
 		&i.UpdatedAt,
 		&i.CreatedAt,
 		&i.SummaryMessageID,
+		&i.WorkingDir,
+		&i.Env,
+		&i.Handoff,
+		&i.PinnedMessageIds,
 	)
 	return i, err
 }
+
+const updateSessionEnvironment = `-- name: UpdateSessionEnvironment :one
+UPDATE sessions
+SET
+    working_dir = ?,
+    env = ?
+WHERE id = ?
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, working_dir, env, handoff, pinned_message_ids
+`
+
+type UpdateSessionEnvironmentParams struct {
+	WorkingDir string `json:"working_dir"`
+	Env        string `json:"env"`
+	ID         string `json:"id"`
+}
+
+func (q *Queries) UpdateSessionEnvironment(ctx context.Context, arg UpdateSessionEnvironmentParams) (Session, error) {
+	row := q.queryRow(ctx, q.updateSessionEnvironmentStmt, updateSessionEnvironment, arg.WorkingDir, arg.Env, arg.ID)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.ParentSessionID,
+		&i.Title,
+		&i.MessageCount,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.Cost,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.SummaryMessageID,
+		&i.WorkingDir,
+		&i.Env,
+		&i.Handoff,
+		&i.PinnedMessageIds,
+	)
+	return i, err
+}
+
+const updateSessionHandoff = `-- name: UpdateSessionHandoff :one
+UPDATE sessions
+SET
+    handoff = ?
+WHERE id = ?
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, working_dir, env, handoff, pinned_message_ids
+`
+
+type UpdateSessionHandoffParams struct {
+	Handoff string `json:"handoff"`
+	ID      string `json:"id"`
+}
+
+func (q *Queries) UpdateSessionHandoff(ctx context.Context, arg UpdateSessionHandoffParams) (Session, error) {
+	row := q.queryRow(ctx, q.updateSessionHandoffStmt, updateSessionHandoff, arg.Handoff, arg.ID)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.ParentSessionID,
+		&i.Title,
+		&i.MessageCount,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.Cost,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.SummaryMessageID,
+		&i.WorkingDir,
+		&i.Env,
+		&i.Handoff,
+		&i.PinnedMessageIds,
+	)
+	return i, err
+}
+
+const updateSessionPinnedMessages = `-- name: UpdateSessionPinnedMessages :one
+UPDATE sessions
+SET
+    pinned_message_ids = ?
+WHERE id = ?
+RETURNING id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, working_dir, env, handoff, pinned_message_ids
+`
+
+type UpdateSessionPinnedMessagesParams struct {
+	PinnedMessageIds string `json:"pinned_message_ids"`
+	ID               string `json:"id"`
+}
+
+func (q *Queries) UpdateSessionPinnedMessages(ctx context.Context, arg UpdateSessionPinnedMessagesParams) (Session, error) {
+	row := q.queryRow(ctx, q.updateSessionPinnedMessagesStmt, updateSessionPinnedMessages, arg.PinnedMessageIds, arg.ID)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.ParentSessionID,
+		&i.Title,
+		&i.MessageCount,
+		&i.PromptTokens,
+		&i.CompletionTokens,
+		&i.Cost,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+		&i.SummaryMessageID,
+		&i.WorkingDir,
+		&i.Env,
+		&i.Handoff,
+		&i.PinnedMessageIds,
+	)
+	return i, err
+}
+
+const listChildSessions = `-- name: ListChildSessions :many
+SELECT id, parent_session_id, title, message_count, prompt_tokens, completion_tokens, cost, updated_at, created_at, summary_message_id, working_dir, env, handoff, pinned_message_ids
+FROM sessions
+WHERE parent_session_id = ?
+ORDER BY created_at ASC
+`
+
+func (q *Queries) ListChildSessions(ctx context.Context, parentSessionID sql.NullString) ([]Session, error) {
+	rows, err := q.query(ctx, q.listChildSessionsStmt, listChildSessions, parentSessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Session{}
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentSessionID,
+			&i.Title,
+			&i.MessageCount,
+			&i.PromptTokens,
+			&i.CompletionTokens,
+			&i.Cost,
+			&i.UpdatedAt,
+			&i.CreatedAt,
+			&i.SummaryMessageID,
+			&i.WorkingDir,
+			&i.Env,
+			&i.Handoff,
+			&i.PinnedMessageIds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const importSession = `-- name: ImportSession :exec
+INSERT INTO sessions (
+    id,
+    parent_session_id,
+    title,
+    prompt_tokens,
+    completion_tokens,
+    cost,
+    summary_message_id,
+    handoff,
+    pinned_message_ids,
+    updated_at,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+ON CONFLICT (id) DO UPDATE SET
+    title = excluded.title,
+    prompt_tokens = excluded.prompt_tokens,
+    completion_tokens = excluded.completion_tokens,
+    cost = excluded.cost,
+    summary_message_id = excluded.summary_message_id,
+    handoff = excluded.handoff,
+    pinned_message_ids = excluded.pinned_message_ids
+`
+
+type ImportSessionParams struct {
+	ID               string         `json:"id"`
+	ParentSessionID  sql.NullString `json:"parent_session_id"`
+	Title            string         `json:"title"`
+	PromptTokens     int64          `json:"prompt_tokens"`
+	CompletionTokens int64          `json:"completion_tokens"`
+	Cost             float64        `json:"cost"`
+	SummaryMessageID sql.NullString `json:"summary_message_id"`
+	Handoff          string         `json:"handoff"`
+	PinnedMessageIds string         `json:"pinned_message_ids"`
+	UpdatedAt        int64          `json:"updated_at"`
+	CreatedAt        int64          `json:"created_at"`
+}
+
+func (q *Queries) ImportSession(ctx context.Context, arg ImportSessionParams) error {
+	_, err := q.exec(ctx, q.importSessionStmt, importSession,
+		arg.ID,
+		arg.ParentSessionID,
+		arg.Title,
+		arg.PromptTokens,
+		arg.CompletionTokens,
+		arg.Cost,
+		arg.SummaryMessageID,
+		arg.Handoff,
+		arg.PinnedMessageIds,
+		arg.UpdatedAt,
+		arg.CreatedAt,
+	)
+	return err
+}
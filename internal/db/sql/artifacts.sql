@@ -0,0 +1,31 @@
+-- name: CreateArtifact :one
+INSERT INTO artifacts (
+    id,
+    session_id,
+    name,
+    kind,
+    description,
+    path,
+    source_path,
+    size,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, strftime('%s', 'now')
+)
+RETURNING *;
+
+-- name: GetArtifact :one
+SELECT *
+FROM artifacts
+WHERE id = ? LIMIT 1;
+
+-- name: ListArtifacts :many
+SELECT *
+FROM artifacts
+ORDER BY created_at DESC, rowid DESC;
+
+-- name: ListArtifactsBySession :many
+SELECT *
+FROM artifacts
+WHERE session_id = ?
+ORDER BY created_at ASC, rowid ASC;
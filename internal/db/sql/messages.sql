@@ -9,6 +9,17 @@ FROM messages
 WHERE session_id = ?
 ORDER BY created_at ASC;
 
+-- name: ListLatestMessages :many
+SELECT id, session_id, role, parts, model, created_at, updated_at, finished_at, provider, is_summary_message
+FROM (
+    SELECT *, ROW_NUMBER() OVER (
+        PARTITION BY session_id ORDER BY created_at DESC, rowid DESC
+    ) AS position
+    FROM messages
+)
+WHERE position <= ?
+ORDER BY session_id, position DESC;
+
 -- name: CreateMessage :one
 INSERT INTO messages (
     id,
@@ -41,3 +52,62 @@ WHERE id = ?;
 -- name: DeleteSessionMessages :exec
 DELETE FROM messages
 WHERE session_id = ?;
+
+-- name: CountMessagesBySession :one
+SELECT COUNT(*)
+FROM messages
+WHERE session_id = @session_id
+    AND (sqlc.narg('role') IS NULL OR role = sqlc.narg('role'))
+    AND (sqlc.narg('created_before') IS NULL OR created_at < sqlc.narg('created_before'));
+
+-- name: DeleteMessagesBySession :many
+DELETE FROM messages
+WHERE session_id = @session_id
+    AND (sqlc.narg('role') IS NULL OR role = sqlc.narg('role'))
+    AND (sqlc.narg('created_before') IS NULL OR created_at < sqlc.narg('created_before'))
+RETURNING *;
+
+-- name: ImportMessage :exec
+INSERT INTO messages (
+    id,
+    session_id,
+    role,
+    parts,
+    model,
+    provider,
+    is_summary_message,
+    created_at,
+    updated_at,
+    finished_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+);
+
+-- name: ListMessagesForRetention :many
+SELECT *
+FROM messages
+WHERE EXISTS (
+    SELECT 1
+    FROM json_each(messages.parts)
+    WHERE (messages.created_at < sqlc.narg('created_before') AND json_extract(value, '$.type') NOT IN ('finish', 'run_summary'))
+        OR (sqlc.arg('drop_reasoning') = 1 AND json_extract(value, '$.type') = 'reasoning')
+        OR (sqlc.arg('drop_attachments') = 1 AND json_extract(value, '$.type') IN ('binary', 'image_url'))
+)
+ORDER BY created_at ASC;
+
+-- name: SearchMessages :many
+SELECT
+    m.id,
+    m.session_id,
+    m.role,
+    m.created_at,
+    s.title AS session_title,
+    CAST(snippet(messages_fts, 0, sqlc.arg('highlight_start'), sqlc.arg('highlight_end'), '...', 16) AS TEXT) AS snippet,
+    CAST(bm25(messages_fts) AS REAL) AS rank
+FROM messages_fts
+JOIN messages m ON m.rowid = messages_fts.rowid
+JOIN sessions s ON s.id = m.session_id
+WHERE messages_fts MATCH sqlc.arg('query')
+    AND (sqlc.narg('session_id') IS NULL OR m.session_id = sqlc.narg('session_id'))
+ORDER BY rank ASC, m.created_at DESC
+LIMIT sqlc.arg('limit');
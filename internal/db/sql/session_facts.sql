@@ -0,0 +1,26 @@
+-- name: SetSessionFact :one
+INSERT INTO session_facts (
+    session_id,
+    key,
+    value,
+    category,
+    created_at,
+    updated_at
+) VALUES (
+    ?, ?, ?, ?, strftime('%s', 'now'), strftime('%s', 'now')
+)
+ON CONFLICT (session_id, key) DO UPDATE SET
+    value = excluded.value,
+    category = excluded.category,
+    updated_at = excluded.updated_at
+RETURNING *;
+
+-- name: DeleteSessionFact :execrows
+DELETE FROM session_facts
+WHERE session_id = ? AND key = ?;
+
+-- name: ListSessionFacts :many
+SELECT *
+FROM session_facts
+WHERE session_id = ?
+ORDER BY category ASC, created_at ASC, key ASC;
@@ -0,0 +1,21 @@
+-- name: CreateSessionFork :exec
+INSERT INTO session_forks (
+    session_id,
+    source_session_id,
+    source_message_id,
+    message_count,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, strftime('%s', 'now')
+);
+
+-- name: GetSessionFork :one
+SELECT *
+FROM session_forks
+WHERE session_id = ? LIMIT 1;
+
+-- name: ListSessionForks :many
+SELECT *
+FROM session_forks
+WHERE source_session_id = ?
+ORDER BY created_at ASC, rowid ASC;
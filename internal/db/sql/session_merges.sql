@@ -0,0 +1,17 @@
+-- name: CreateSessionMerge :exec
+INSERT INTO session_merges (
+    session_id,
+    source_session_id,
+    source_title,
+    message_count,
+    duplicate_count,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, strftime('%s', 'now')
+);
+
+-- name: ListSessionMerges :many
+SELECT *
+FROM session_merges
+WHERE session_id = ?
+ORDER BY created_at ASC, rowid ASC;
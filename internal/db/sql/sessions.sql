@@ -49,3 +49,56 @@ RETURNING *;
 -- name: DeleteSession :exec
 DELETE FROM sessions
 WHERE id = ?;
+
+-- name: UpdateSessionEnvironment :one
+UPDATE sessions
+SET
+    working_dir = ?,
+    env = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: UpdateSessionHandoff :one
+UPDATE sessions
+SET
+    handoff = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: UpdateSessionPinnedMessages :one
+UPDATE sessions
+SET
+    pinned_message_ids = ?
+WHERE id = ?
+RETURNING *;
+
+-- name: ListChildSessions :many
+SELECT *
+FROM sessions
+WHERE parent_session_id = ?
+ORDER BY created_at ASC;
+
+-- name: ImportSession :exec
+INSERT INTO sessions (
+    id,
+    parent_session_id,
+    title,
+    prompt_tokens,
+    completion_tokens,
+    cost,
+    summary_message_id,
+    handoff,
+    pinned_message_ids,
+    updated_at,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+ON CONFLICT (id) DO UPDATE SET
+    title = excluded.title,
+    prompt_tokens = excluded.prompt_tokens,
+    completion_tokens = excluded.completion_tokens,
+    cost = excluded.cost,
+    summary_message_id = excluded.summary_message_id,
+    handoff = excluded.handoff,
+    pinned_message_ids = excluded.pinned_message_ids;
@@ -0,0 +1,18 @@
+-- name: CreateSpending :one
+INSERT INTO spending (
+    id,
+    session_id,
+    provider,
+    model,
+    cost,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, strftime('%s', 'now')
+)
+RETURNING *;
+
+-- name: ListSpendingSince :many
+SELECT *
+FROM spending
+WHERE created_at >= ?
+ORDER BY created_at ASC;
@@ -0,0 +1,21 @@
+-- name: ListSyncStates :many
+SELECT *
+FROM sync_state
+WHERE remote = ?;
+
+-- name: SetSyncState :exec
+INSERT INTO sync_state (
+    remote,
+    session_id,
+    hash,
+    synced_at
+) VALUES (
+    ?, ?, ?, strftime('%s', 'now')
+)
+ON CONFLICT (remote, session_id) DO UPDATE SET
+    hash = excluded.hash,
+    synced_at = excluded.synced_at;
+
+-- name: DeleteSyncState :exec
+DELETE FROM sync_state
+WHERE remote = ? AND session_id = ?;
@@ -0,0 +1,33 @@
+-- name: CreateToolAudit :one
+INSERT INTO tool_audit (
+    id,
+    session_id,
+    message_id,
+    tool_call_id,
+    tool_name,
+    input,
+    output_hash,
+    output_size,
+    duration_ms,
+    status,
+    exit_code,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, strftime('%s', 'now')
+)
+RETURNING *;
+
+-- name: GetToolAudit :one
+SELECT *
+FROM tool_audit
+WHERE id = ? LIMIT 1;
+
+-- name: ListToolAudit :many
+SELECT *
+FROM tool_audit
+WHERE (sqlc.narg('tool_name') IS NULL OR tool_name = sqlc.narg('tool_name'))
+    AND (sqlc.narg('session_id') IS NULL OR session_id = sqlc.narg('session_id'))
+    AND (sqlc.narg('created_after') IS NULL OR created_at >= sqlc.narg('created_after'))
+    AND (sqlc.narg('created_before') IS NULL OR created_at < sqlc.narg('created_before'))
+ORDER BY created_at DESC, rowid DESC
+LIMIT @limit;
@@ -0,0 +1,25 @@
+-- name: CreateToolExecution :one
+INSERT INTO tool_executions (
+    id,
+    session_id,
+    tool_name,
+    duration_ms,
+    output_size,
+    is_error,
+    created_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?, strftime('%s', 'now')
+)
+RETURNING *;
+
+-- name: ListToolStats :many
+SELECT
+    tool_name,
+    COUNT(*) AS executions,
+    CAST(SUM(is_error) AS INTEGER) AS errors,
+    CAST(AVG(duration_ms) AS INTEGER) AS avg_duration_ms,
+    CAST(MAX(duration_ms) AS INTEGER) AS max_duration_ms,
+    CAST(AVG(output_size) AS INTEGER) AS avg_output_size
+FROM tool_executions
+GROUP BY tool_name
+ORDER BY tool_name;
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/ncruces/go-sqlite3/driver"
+	"github.com/pressly/goose/v3"
+)
+
+// SchemaVersion returns the version of the schema of the database in the
+// data directory and the latest version this build migrates to. Unlike
+// [Connect], it opens the database read-only and doesn't migrate it.
+func SchemaVersion(ctx context.Context, dataDir string) (current, latest int64, err error) {
+	latest, err = latestMigration()
+	if err != nil {
+		return 0, 0, err
+	}
+	dbPath := filepath.Join(dataDir, "crush.db")
+	if _, err := os.Stat(dbPath); err != nil {
+		return 0, latest, fmt.Errorf("failed to open database: %w", err)
+	}
+	db, err := driver.Open("file:" + filepath.ToSlash(dbPath) + "?mode=ro")
+	if err != nil {
+		return 0, latest, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	var version sql.NullInt64
+	err = db.QueryRowContext(ctx, "SELECT MAX(version_id) FROM goose_db_version WHERE is_applied").Scan(&version)
+	if err != nil {
+		return 0, latest, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version.Int64, latest, nil
+}
+
+func latestMigration() (int64, error) {
+	entries, err := fs.ReadDir(FS, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	var latest int64
+	for _, entry := range entries {
+		version, err := goose.NumericComponent(entry.Name())
+		if err != nil {
+			return 0, err
+		}
+		latest = max(latest, version)
+	}
+	if latest == 0 {
+		return 0, errors.New("no migrations found")
+	}
+	return latest, nil
+}
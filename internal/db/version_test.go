@@ -0,0 +1,24 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	_, _, err := SchemaVersion(t.Context(), dir)
+	require.Error(t, err)
+
+	conn, err := Connect(t.Context(), dir)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	current, latest, err := SchemaVersion(t.Context(), dir)
+	require.NoError(t, err)
+	require.Positive(t, latest)
+	require.Equal(t, latest, current)
+}
@@ -0,0 +1,141 @@
+// Package draft saves the prompts being written in the editor, so a long
+// prompt isn't lost when Crush crashes or is restarted before it's sent.
+package draft
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	draftsFile = "drafts.json"
+
+	// maxDrafts is how many drafts are kept, the oldest are dropped first.
+	maxDrafts = 50
+)
+
+// Draft is a prompt that wasn't sent.
+type Draft struct {
+	ID string `json:"id"`
+	// SessionID is the session the prompt is written for, empty for a new
+	// session.
+	SessionID string    `json:"session_id,omitempty"`
+	Text      string    `json:"text"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store keeps the drafts of a project in its data directory.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewStore returns the store of the drafts of the project with the given
+// data directory.
+func NewStore(dataDir string) *Store {
+	return &Store{path: filepath.Join(dataDir, draftsFile)}
+}
+
+// Save stores the draft, replacing the one with the same ID. A draft
+// without text is deleted.
+func (s *Store) Save(d Draft) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	drafts, err := s.load()
+	if err != nil {
+		return err
+	}
+	drafts = slices.DeleteFunc(drafts, func(other Draft) bool {
+		return other.ID == d.ID
+	})
+	if d.Text != "" {
+		if d.UpdatedAt.IsZero() {
+			d.UpdatedAt = time.Now()
+		}
+		drafts = append(drafts, d)
+	}
+	return s.save(drafts)
+}
+
+// Delete removes the draft, once it's sent.
+func (s *Store) Delete(id string) error {
+	return s.Save(Draft{ID: id})
+}
+
+// Latest returns the most recent draft of the session.
+func (s *Store) Latest(sessionID string) (Draft, bool, error) {
+	drafts, err := s.List()
+	if err != nil {
+		return Draft{}, false, err
+	}
+	for _, d := range drafts {
+		if d.SessionID == sessionID {
+			return d, true, nil
+		}
+	}
+	return Draft{}, false, nil
+}
+
+// List returns the drafts of all the sessions, most recent first.
+func (s *Store) List() ([]Draft, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	drafts, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(drafts)
+	return drafts, nil
+}
+
+// load returns the drafts, oldest first.
+func (s *Store) load() ([]Draft, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read drafts: %w", err)
+	}
+	var drafts []Draft
+	if err := json.Unmarshal(data, &drafts); err != nil {
+		return nil, fmt.Errorf("failed to decode drafts: %w", err)
+	}
+	slices.SortStableFunc(drafts, func(a, b Draft) int {
+		return cmp.Compare(a.UpdatedAt.UnixNano(), b.UpdatedAt.UnixNano())
+	})
+	return drafts, nil
+}
+
+func (s *Store) save(drafts []Draft) error {
+	slices.SortStableFunc(drafts, func(a, b Draft) int {
+		return cmp.Compare(a.UpdatedAt.UnixNano(), b.UpdatedAt.UnixNano())
+	})
+	if len(drafts) > maxDrafts {
+		drafts = drafts[len(drafts)-maxDrafts:]
+	}
+	data, err := json.MarshalIndent(drafts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode drafts: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to save drafts: %w", err)
+	}
+	// Write through a temporary file so a crash never leaves a partial file.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save drafts: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to save drafts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package draft
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	s := NewStore(dir)
+	start := time.Now()
+
+	_, ok, err := s.Latest("")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, s.Save(Draft{ID: "a", Text: "first", UpdatedAt: start}))
+	require.NoError(t, s.Save(Draft{ID: "b", SessionID: "s1", Text: "second", UpdatedAt: start.Add(time.Second)}))
+	require.NoError(t, s.Save(Draft{ID: "a", Text: "first, edited", UpdatedAt: start.Add(2 * time.Second)}))
+
+	// Drafts survive restarts.
+	s = NewStore(dir)
+	drafts, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, drafts, 2)
+	require.Equal(t, "first, edited", drafts[0].Text)
+	require.Equal(t, "second", drafts[1].Text)
+
+	d, ok, err := s.Latest("s1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "b", d.ID)
+
+	// Sent and emptied drafts are dropped.
+	require.NoError(t, s.Delete("b"))
+	require.NoError(t, s.Save(Draft{ID: "a"}))
+	drafts, err = s.List()
+	require.NoError(t, err)
+	require.Empty(t, drafts)
+}
+
+func TestStoreLimit(t *testing.T) {
+	t.Parallel()
+
+	s := NewStore(t.TempDir())
+	start := time.Now()
+	for i := range maxDrafts + 5 {
+		require.NoError(t, s.Save(Draft{
+			ID:        fmt.Sprint(i),
+			Text:      "draft",
+			UpdatedAt: start.Add(time.Duration(i) * time.Second),
+		}))
+	}
+
+	drafts, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, drafts, maxDrafts)
+	require.Equal(t, fmt.Sprint(maxDrafts+4), drafts[0].ID)
+	require.Equal(t, "5", drafts[len(drafts)-1].ID)
+}
@@ -0,0 +1,117 @@
+// Package embedding turns text into vectors with the embedding models of the
+// configured providers, to build retrieval features on.
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+
+	"charm.land/fantasy/providers/openai"
+	"charm.land/fantasy/providers/openaicompat"
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// ollamaPort is the port Ollama listens on by default, used to recognize it
+// behind openai-compat providers.
+const ollamaPort = "11434"
+
+// ErrUnsupported is returned for providers without an embeddings API.
+var ErrUnsupported = errors.New("provider does not support embeddings")
+
+// Usage is what a call to an embedding model consumed.
+type Usage struct {
+	InputTokens int64 `json:"input_tokens"`
+}
+
+// Result holds the embeddings of texts, in the order of the texts.
+type Result struct {
+	Embeddings [][]float32
+	Usage      Usage
+}
+
+// Model computes embeddings.
+type Model interface {
+	Provider() string
+	Model() string
+	// Embed returns the embeddings of the texts, splitting them in as many
+	// calls as the provider needs.
+	Embed(ctx context.Context, texts []string) (Result, error)
+}
+
+// EmbeddingModel returns the embedding model with the given ID of the
+// provider, whose variables are resolved with resolver. OpenAI and OpenAI
+// compatible providers are supported, with Ollama served by its own API.
+func EmbeddingModel(resolver config.VariableResolver, provider config.ProviderConfig, modelID string) (Model, error) {
+	headers, err := provider.ResolvedExtraHeaders(resolver)
+	if err != nil {
+		return nil, err
+	}
+	apiKey, _ := resolver.ResolveValue(provider.APIKey)
+	baseURL, _ := resolver.ResolveValue(provider.BaseURL)
+
+	switch {
+	case provider.Type == openaicompat.Name && isOllama(provider.ID, baseURL):
+		return newOllama(provider.ID, modelID, baseURL, headers), nil
+	case provider.Type == openai.Name, provider.Type == openaicompat.Name:
+		return newOpenAI(provider.ID, modelID, baseURL, apiKey, headers), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, provider.ID)
+	}
+}
+
+func isOllama(providerID, baseURL string) bool {
+	if providerID == "ollama" {
+		return true
+	}
+	u, err := url.Parse(baseURL)
+	return err == nil && u.Port() == ollamaPort
+}
+
+// batched embeds the texts in batches of at most size texts with embed,
+// summing up the usage of the calls.
+func batched(ctx context.Context, texts []string, size int, embed func(context.Context, []string) (Result, error)) (Result, error) {
+	result := Result{Embeddings: make([][]float32, 0, len(texts))}
+	for start := 0; start < len(texts); start += size {
+		batch := texts[start:min(start+size, len(texts))]
+		r, err := embed(ctx, batch)
+		if err != nil {
+			return Result{}, err
+		}
+		if len(r.Embeddings) != len(batch) {
+			return Result{}, fmt.Errorf("expected %d embeddings, got %d", len(batch), len(r.Embeddings))
+		}
+		result.Embeddings = append(result.Embeddings, r.Embeddings...)
+		result.Usage.InputTokens += r.Usage.InputTokens
+	}
+	return result, nil
+}
+
+// trimV1 returns the root of an API whose OpenAI compatible endpoints are
+// under /v1.
+func trimV1(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return strings.TrimSuffix(baseURL, "/v1")
+}
+
+// CosineSimilarity returns the cosine of the angle between two embeddings,
+// from -1 for opposite ones to 1 for identical ones, 0 when either is empty
+// or they have different lengths.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,138 @@
+package embedding
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/openai"
+	"charm.land/fantasy/providers/openaicompat"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/stretchr/testify/require"
+)
+
+func newResolver() config.VariableResolver {
+	return config.NewEnvironmentVariableResolver(env.NewFromMap(map[string]string{"KEY": "secret"}))
+}
+
+func TestOpenAIEmbeddings(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		require.Equal(t, "/embeddings", r.URL.Path)
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "text-embedding-3-small", req.Model)
+		// Out of order, as the API doesn't guarantee it.
+		var data []map[string]any
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, map[string]any{"object": "embedding", "index": i, "embedding": []float64{float64(len(req.Input[i]))}})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"object": "list",
+			"model":  req.Model,
+			"data":   data,
+			"usage":  map[string]any{"prompt_tokens": 3 * len(req.Input), "total_tokens": 3 * len(req.Input)},
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	model, err := EmbeddingModel(newResolver(), config.ProviderConfig{
+		ID:      "openai",
+		Type:    openai.Name,
+		BaseURL: srv.URL,
+		APIKey:  "$KEY",
+	}, "text-embedding-3-small")
+	require.NoError(t, err)
+	require.IsType(t, &openAIModel{}, model)
+	require.Equal(t, "openai", model.Provider())
+
+	result, err := model.Embed(t.Context(), []string{"a", "bb", "ccc"})
+	require.NoError(t, err)
+	require.Equal(t, [][]float32{{1}, {2}, {3}}, result.Embeddings)
+	require.Equal(t, int64(9), result.Usage.InputTokens)
+	require.Equal(t, 1, calls)
+}
+
+func TestOllamaEmbeddings(t *testing.T) {
+	t.Parallel()
+
+	var batches []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/embed", r.URL.Path)
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "nomic-embed-text", req.Model)
+		batches = append(batches, len(req.Input))
+		embeddings := make([][]float32, len(req.Input))
+		for i := range req.Input {
+			embeddings[i] = []float32{0.5}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"embeddings":        embeddings,
+			"prompt_eval_count": len(req.Input),
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	model, err := EmbeddingModel(newResolver(), config.ProviderConfig{
+		ID:      "ollama",
+		Type:    openaicompat.Name,
+		BaseURL: srv.URL + "/v1/",
+	}, "nomic-embed-text")
+	require.NoError(t, err)
+	require.IsType(t, &ollamaModel{}, model)
+
+	texts := make([]string, ollamaMaxBatch+1)
+	result, err := model.Embed(t.Context(), texts)
+	require.NoError(t, err)
+	require.Len(t, result.Embeddings, len(texts))
+	require.Equal(t, int64(len(texts)), result.Usage.InputTokens)
+	require.Equal(t, []int{ollamaMaxBatch, 1}, batches)
+}
+
+func TestEmbeddingModelProviders(t *testing.T) {
+	t.Parallel()
+
+	model, err := EmbeddingModel(newResolver(), config.ProviderConfig{
+		ID:      "local",
+		Type:    openaicompat.Name,
+		BaseURL: "http://localhost:11434/v1/",
+	}, "nomic-embed-text")
+	require.NoError(t, err)
+	require.IsType(t, &ollamaModel{}, model)
+
+	model, err = EmbeddingModel(newResolver(), config.ProviderConfig{
+		ID:      "lmstudio",
+		Type:    openaicompat.Name,
+		BaseURL: "http://localhost:1234/v1/",
+	}, "nomic-embed-text")
+	require.NoError(t, err)
+	require.IsType(t, &openAIModel{}, model)
+
+	_, err = EmbeddingModel(newResolver(), config.ProviderConfig{ID: "anthropic", Type: anthropic.Name}, "claude")
+	require.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	t.Parallel()
+
+	require.InDelta(t, 1, CosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	require.InDelta(t, 0, CosineSimilarity([]float32{1, 0}, []float32{0, 3}), 1e-9)
+	require.InDelta(t, -1, CosineSimilarity([]float32{1, 1}, []float32{-1, -1}), 1e-9)
+	require.Zero(t, CosineSimilarity([]float32{1}, []float32{1, 2}))
+	require.Zero(t, CosineSimilarity([]float32{0, 0}, []float32{1, 2}))
+}
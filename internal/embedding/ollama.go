@@ -0,0 +1,77 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ollamaMaxBatch bounds the inputs sent to Ollama at once, which embeds them
+// one after the other.
+const ollamaMaxBatch = 64
+
+// ollamaModel uses the native embed API of Ollama, which reports the tokens
+// it read, unlike its OpenAI compatible one.
+type ollamaModel struct {
+	provider string
+	model    string
+	baseURL  string
+	headers  map[string]string
+	client   *http.Client
+}
+
+func newOllama(provider, model, baseURL string, headers map[string]string) *ollamaModel {
+	return &ollamaModel{
+		provider: provider,
+		model:    model,
+		baseURL:  trimV1(baseURL),
+		headers:  headers,
+		client:   http.DefaultClient,
+	}
+}
+
+func (m *ollamaModel) Provider() string { return m.provider }
+
+func (m *ollamaModel) Model() string { return m.model }
+
+func (m *ollamaModel) Embed(ctx context.Context, texts []string) (Result, error) {
+	return batched(ctx, texts, ollamaMaxBatch, m.embed)
+}
+
+func (m *ollamaModel) embed(ctx context.Context, texts []string) (Result, error) {
+	body, err := json.Marshal(map[string]any{"model": m.model, "input": texts})
+	if err != nil {
+		return Result{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.baseURL+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range m.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to compute embeddings: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Result{}, fmt.Errorf("failed to compute embeddings: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	var out struct {
+		Embeddings      [][]float32 `json:"embeddings"`
+		PromptEvalCount int64       `json:"prompt_eval_count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Result{}, fmt.Errorf("failed to read embeddings: %w", err)
+	}
+	return Result{
+		Embeddings: out.Embeddings,
+		Usage:      Usage{InputTokens: out.PromptEvalCount},
+	}, nil
+}
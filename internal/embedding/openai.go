@@ -0,0 +1,71 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+
+	openaisdk "github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/option"
+)
+
+// openAIMaxBatch is the most inputs the OpenAI embeddings API takes at once.
+const openAIMaxBatch = 2048
+
+type openAIModel struct {
+	provider string
+	model    string
+	client   openaisdk.Client
+}
+
+func newOpenAI(provider, model, baseURL, apiKey string, headers map[string]string) *openAIModel {
+	opts := []option.RequestOption{option.WithAPIKey(apiKey)}
+	if baseURL != "" {
+		opts = append(opts, option.WithBaseURL(baseURL))
+	}
+	for k, v := range headers {
+		opts = append(opts, option.WithHeader(k, v))
+	}
+	return &openAIModel{
+		provider: provider,
+		model:    model,
+		client:   openaisdk.NewClient(opts...),
+	}
+}
+
+func (m *openAIModel) Provider() string { return m.provider }
+
+func (m *openAIModel) Model() string { return m.model }
+
+func (m *openAIModel) Embed(ctx context.Context, texts []string) (Result, error) {
+	return batched(ctx, texts, openAIMaxBatch, m.embed)
+}
+
+func (m *openAIModel) embed(ctx context.Context, texts []string) (Result, error) {
+	resp, err := m.client.Embeddings.New(ctx, openaisdk.EmbeddingNewParams{
+		Model:          m.model,
+		Input:          openaisdk.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+		EncodingFormat: openaisdk.EmbeddingNewParamsEncodingFormatFloat,
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to compute embeddings: %w", err)
+	}
+	embeddings := make([][]float32, len(resp.Data))
+	for _, data := range resp.Data {
+		if data.Index < 0 || int(data.Index) >= len(embeddings) {
+			return Result{}, fmt.Errorf("embedding index out of range: %d", data.Index)
+		}
+		embeddings[data.Index] = toFloat32(data.Embedding)
+	}
+	return Result{
+		Embeddings: embeddings,
+		Usage:      Usage{InputTokens: resp.Usage.PromptTokens},
+	}, nil
+}
+
+func toFloat32(v []float64) []float32 {
+	out := make([]float32, len(v))
+	for i, f := range v {
+		out[i] = float32(f)
+	}
+	return out
+}
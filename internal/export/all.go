@@ -0,0 +1,319 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// Format is the format sessions are exported in.
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+)
+
+// IndexFile is the file listing the exported sessions, read back by
+// incremental exports. IndexMarkdownFile lists them for people.
+const (
+	IndexFile         = "index.json"
+	IndexMarkdownFile = "index.md"
+)
+
+// artifactsDir is the directory of the artifacts in the directory of their
+// session.
+const artifactsDir = "artifacts"
+
+// maxSlugLength is how much of the title goes in directory names.
+const maxSlugLength = 40
+
+// Options configure an export of all sessions.
+type Options struct {
+	Format Format
+	// Incremental skips the sessions that didn't change since the export
+	// recorded in the index of the directory.
+	Incremental bool
+	// Artifacts are copied to the artifacts directory of their session
+	// along with it, nil to leave them out.
+	Artifacts artifact.Service
+}
+
+// Result counts the sessions of an export.
+type Result struct {
+	Exported  int
+	Unchanged int
+	Artifacts int
+}
+
+// Index lists the sessions of an export.
+type Index struct {
+	ExportedAt int64        `json:"exported_at"`
+	Format     Format       `json:"format"`
+	Sessions   []IndexEntry `json:"sessions"`
+}
+
+// IndexEntry is an exported session.
+type IndexEntry struct {
+	ID              string `json:"id"`
+	ParentSessionID string `json:"parent_session_id,omitempty"`
+	Title           string `json:"title"`
+	// Path is the file of the session, relative to the export directory
+	// and slash separated.
+	Path         string `json:"path"`
+	MessageCount int64  `json:"message_count"`
+	CreatedAt    int64  `json:"created_at"`
+	UpdatedAt    int64  `json:"updated_at"`
+}
+
+// All exports every session to dir, one file per session. Each session
+// gets a directory, nested in the one of the session it was spawned from,
+// holding its artifacts too, and dir gets an index of them. Sessions keep the path of the previous
+// export, so renaming them doesn't move their files, and the files of
+// deleted sessions are left alone.
+func All(ctx context.Context, sessions session.Service, messages message.Service, dir string, opts Options) (Result, error) {
+	if opts.Format == "" {
+		opts.Format = FormatMarkdown
+	}
+	previous, err := ReadIndex(dir)
+	if err != nil {
+		return Result{}, err
+	}
+	known := make(map[string]IndexEntry, len(previous.Sessions))
+	for _, entry := range previous.Sessions {
+		known[entry.ID] = entry
+	}
+
+	e := &exporter{
+		messages: messages,
+		sessions: sessions,
+		dir:      dir,
+		opts:     opts,
+		known:    known,
+		// Files of another format can't be reused.
+		reuse: opts.Incremental && previous.Format == opts.Format,
+	}
+	roots, err := sessions.List(ctx)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for _, root := range roots {
+		if err := e.export(ctx, root, ""); err != nil {
+			return e.result, err
+		}
+	}
+
+	index := Index{
+		ExportedAt: time.Now().Unix(),
+		Format:     opts.Format,
+		Sessions:   e.entries,
+	}
+	if err := writeIndex(dir, index); err != nil {
+		return e.result, err
+	}
+	return e.result, nil
+}
+
+// ReadIndex reads the index of the export in dir. It's empty if there's
+// none yet.
+func ReadIndex(dir string) (Index, error) {
+	var index Index
+	data, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return index, fmt.Errorf("failed to read export index: %w", err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return index, fmt.Errorf("failed to parse export index: %w", err)
+	}
+	return index, nil
+}
+
+type exporter struct {
+	sessions session.Service
+	messages message.Service
+	dir      string
+	opts     Options
+	known    map[string]IndexEntry
+	reuse    bool
+
+	entries []IndexEntry
+	result  Result
+}
+
+// export exports the session and, depth first, the sessions spawned from it
+// into the directory parentDir.
+func (e *exporter) export(ctx context.Context, sess session.Session, parentDir string) error {
+	entry := IndexEntry{
+		ID:              sess.ID,
+		ParentSessionID: sess.ParentSessionID,
+		Title:           sess.Title,
+		MessageCount:    sess.MessageCount,
+		CreatedAt:       sess.CreatedAt,
+		UpdatedAt:       sess.UpdatedAt,
+	}
+	prev, known := e.known[sess.ID]
+	dir := path.Join(parentDir, sessionDirName(sess))
+	if known {
+		dir = path.Dir(prev.Path)
+	}
+	entry.Path = path.Join(dir, sessionFileName(e.opts.Format))
+	file := filepath.Join(e.dir, filepath.FromSlash(entry.Path))
+
+	if e.reuse && known && prev.UpdatedAt >= sess.UpdatedAt && prev.Path == entry.Path && fileExists(file) {
+		e.result.Unchanged++
+	} else {
+		if err := e.write(ctx, sess, file); err != nil {
+			return err
+		}
+		e.result.Exported++
+		if e.opts.Artifacts != nil {
+			n, err := artifact.Export(ctx, e.opts.Artifacts, sess.ID, filepath.Join(filepath.Dir(file), artifactsDir))
+			if err != nil {
+				return err
+			}
+			e.result.Artifacts += n
+		}
+	}
+	e.entries = append(e.entries, entry)
+
+	children, err := e.sessions.ListChildren(ctx, sess.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list child sessions: %w", err)
+	}
+	for _, child := range children {
+		if err := e.export(ctx, child, path.Dir(entry.Path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *exporter) write(ctx context.Context, sess session.Session, file string) error {
+	msgs, err := e.messages.List(ctx, sess.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list messages: %w", err)
+	}
+	var data []byte
+	switch e.opts.Format {
+	case FormatJSON:
+		if data, err = json.MarshalIndent(JSON(sess, msgs), "", "  "); err != nil {
+			return fmt.Errorf("failed to encode session: %w", err)
+		}
+	default:
+		data = []byte(Markdown(sess, msgs))
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return nil
+}
+
+func writeIndex(dir string, index Index) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode export index: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, IndexFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write export index: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, IndexMarkdownFile), []byte(indexMarkdown(index)), 0o644); err != nil {
+		return fmt.Errorf("failed to write export index: %w", err)
+	}
+	return nil
+}
+
+// indexMarkdown lists the sessions of the index with links to their files,
+// the ones spawned from a session nested under it.
+func indexMarkdown(index Index) string {
+	var sb strings.Builder
+	sb.WriteString("# Crush Sessions\n\n")
+	// Sessions are listed after the one they were spawned from.
+	depths := make(map[string]int, len(index.Sessions))
+	for _, entry := range index.Sessions {
+		depth := 0
+		if parent, ok := depths[entry.ParentSessionID]; ok {
+			depth = parent + 1
+		}
+		depths[entry.ID] = depth
+
+		title := entry.Title
+		if title == "" {
+			title = "Untitled Session"
+		}
+		fmt.Fprintf(&sb, "%s- [%s](<%s>) (%s, %d messages)\n",
+			strings.Repeat("  ", depth),
+			strings.NewReplacer("[", `\[`, "]", `\]`).Replace(title),
+			entry.Path,
+			time.Unix(entry.UpdatedAt, 0).Format(time.DateOnly),
+			entry.MessageCount,
+		)
+	}
+	return sb.String()
+}
+
+// sessionDirName names the directory of the session after its title, with
+// its short ID to tell apart sessions with the same title.
+func sessionDirName(sess session.Session) string {
+	id := sess.ID
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	if slug := slugify(sess.Title); slug != "" {
+		return slug + "-" + id
+	}
+	return id
+}
+
+func sessionFileName(format Format) string {
+	if format == FormatJSON {
+		return "session.json"
+	}
+	return "session.md"
+}
+
+// slugify lowercases the letters and digits of s, joining the words with
+// dashes.
+func slugify(s string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			dash = false
+			sb.WriteRune(r)
+			if sb.Len() >= maxSlugLength {
+				break
+			}
+			continue
+		}
+		dash = true
+	}
+	return sb.String()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
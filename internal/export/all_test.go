@@ -0,0 +1,118 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/artifact"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAll(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sessions := session.NewService(q)
+	messages := message.NewService(q, conn)
+
+	parent, err := sessions.Create(t.Context(), "Fix the login bug!")
+	require.NoError(t, err)
+	child, err := sessions.CreateTaskSession(t.Context(), "toolu_01ABCDEF", parent.ID, "Search for [auth]")
+	require.NoError(t, err)
+	_, err = messages.Create(t.Context(), parent.ID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Why can't I log in?"}},
+	})
+	require.NoError(t, err)
+	_, err = messages.Create(t.Context(), child.ID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.TextContent{Text: "Found it in auth.go."}},
+	})
+	require.NoError(t, err)
+
+	dataDir := t.TempDir()
+	artifacts := artifact.NewService(q, dataDir)
+	report := filepath.Join(t.TempDir(), "coverage.html")
+	require.NoError(t, os.WriteFile(report, []byte("<html></html>"), 0o644))
+	_, err = artifacts.Register(t.Context(), artifact.RegisterParams{SessionID: child.ID, SourcePath: report})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	result, err := All(t.Context(), sessions, messages, dir, Options{Format: FormatMarkdown, Artifacts: artifacts})
+	require.NoError(t, err)
+	require.Equal(t, Result{Exported: 2, Artifacts: 1}, result)
+
+	index, err := ReadIndex(dir)
+	require.NoError(t, err)
+	require.Equal(t, FormatMarkdown, index.Format)
+	require.Len(t, index.Sessions, 2)
+	parentPath := "fix-the-login-bug-" + parent.ID[:8] + "/session.md"
+	childPath := "fix-the-login-bug-" + parent.ID[:8] + "/search-for-auth-" + child.ID[:8] + "/session.md"
+	require.Equal(t, parentPath, index.Sessions[0].Path)
+	require.Equal(t, childPath, index.Sessions[1].Path)
+	require.Equal(t, parent.ID, index.Sessions[1].ParentSessionID)
+
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(childPath)))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "Found it in auth.go.")
+	data, err = os.ReadFile(filepath.Join(dir, filepath.FromSlash(path.Dir(childPath)), "artifacts", "coverage.html"))
+	require.NoError(t, err)
+	require.Equal(t, "<html></html>", string(data))
+
+	data, err = os.ReadFile(filepath.Join(dir, IndexMarkdownFile))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "\n- [Fix the login bug!](<"+parentPath+">)")
+	require.Contains(t, string(data), "\n  - [Search for \\[auth\\]](<"+childPath+">)")
+
+	t.Run("incremental", func(t *testing.T) {
+		// Pretend the child changed since the last export.
+		index.Sessions[1].UpdatedAt--
+		data, err := json.Marshal(index)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, IndexFile), data, 0o644))
+
+		_, err = sessions.Save(t.Context(), session.Session{ID: parent.ID, Title: "Renamed"})
+		require.NoError(t, err)
+
+		result, err := All(t.Context(), sessions, messages, dir, Options{Format: FormatMarkdown, Incremental: true})
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Exported)
+		require.Equal(t, 1, result.Unchanged)
+
+		index, err := ReadIndex(dir)
+		require.NoError(t, err)
+		require.Equal(t, "Renamed", index.Sessions[0].Title)
+		require.Equal(t, parentPath, index.Sessions[0].Path, "exported sessions keep their path")
+
+		// Switching formats exports everything again.
+		result, err = All(t.Context(), sessions, messages, dir, Options{Format: FormatJSON, Incremental: true})
+		require.NoError(t, err)
+		require.Equal(t, Result{Exported: 2}, result)
+
+		data, err = os.ReadFile(filepath.Join(dir, "fix-the-login-bug-"+parent.ID[:8], "session.json"))
+		require.NoError(t, err)
+		var exported Session
+		require.NoError(t, json.Unmarshal(data, &exported))
+		require.Equal(t, parent.ID, exported.ID)
+		require.Len(t, exported.Messages, 1)
+		require.Equal(t, "Why can't I log in?", exported.Messages[0].Text)
+	})
+}
+
+func TestSlugify(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "fix-the-login-bug", slugify("  Fix the *login* bug!"))
+	require.Equal(t, "café-ü", slugify("Café / Ü"))
+	require.Empty(t, slugify("?!"))
+	require.Len(t, slugify("a very long title that goes on and on and on and on"), maxSlugLength)
+}
@@ -0,0 +1,89 @@
+package export
+
+import (
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// Session is the JSON export of a session.
+type Session struct {
+	ID               string    `json:"id"`
+	ParentSessionID  string    `json:"parent_session_id,omitempty"`
+	Title            string    `json:"title"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	Cost             float64   `json:"cost"`
+	CreatedAt        int64     `json:"created_at"`
+	UpdatedAt        int64     `json:"updated_at"`
+	Messages         []Message `json:"messages"`
+}
+
+// Message is the JSON export of a message. Attachments and the data of
+// tool results, like images, are left out.
+type Message struct {
+	ID          string              `json:"id"`
+	Role        message.MessageRole `json:"role"`
+	Model       string              `json:"model,omitempty"`
+	Provider    string              `json:"provider,omitempty"`
+	CreatedAt   int64               `json:"created_at"`
+	Text        string              `json:"text,omitempty"`
+	Reasoning   string              `json:"reasoning,omitempty"`
+	ToolCalls   []message.ToolCall  `json:"tool_calls,omitempty"`
+	ToolResults []ToolResult        `json:"tool_results,omitempty"`
+	// FinishReason and CancelReason tell how the turn of assistant messages
+	// ended.
+	FinishReason message.FinishReason `json:"finish_reason,omitempty"`
+	CancelReason message.CancelReason `json:"cancel_reason,omitempty"`
+	// Summary sums up the turn the message ended, if it was summed up.
+	Summary *message.RunSummary `json:"summary,omitempty"`
+}
+
+// ToolResult is the JSON export of the result of a tool call.
+type ToolResult struct {
+	ToolCallID string `json:"tool_call_id"`
+	Name       string `json:"name"`
+	Content    string `json:"content"`
+	IsError    bool   `json:"is_error,omitempty"`
+}
+
+// JSON exports the session with its messages.
+func JSON(sess session.Session, msgs []message.Message) Session {
+	out := Session{
+		ID:               sess.ID,
+		ParentSessionID:  sess.ParentSessionID,
+		Title:            sess.Title,
+		PromptTokens:     sess.PromptTokens,
+		CompletionTokens: sess.CompletionTokens,
+		Cost:             sess.Cost,
+		CreatedAt:        sess.CreatedAt,
+		UpdatedAt:        sess.UpdatedAt,
+		Messages:         make([]Message, 0, len(msgs)),
+	}
+	for _, msg := range msgs {
+		m := Message{
+			ID:        msg.ID,
+			Role:      msg.Role,
+			Model:     msg.Model,
+			Provider:  msg.Provider,
+			CreatedAt: msg.CreatedAt,
+			Text:      msg.Content().Text,
+			Reasoning: msg.ReasoningContent().Thinking,
+			ToolCalls: msg.ToolCalls(),
+			Summary:   msg.RunSummary(),
+		}
+		if finish := msg.FinishPart(); finish != nil && msg.Role == message.Assistant {
+			m.FinishReason = finish.Reason
+			m.CancelReason = finish.CancelReason
+		}
+		for _, result := range msg.ToolResults() {
+			m.ToolResults = append(m.ToolResults, ToolResult{
+				ToolCallID: result.ToolCallID,
+				Name:       result.Name,
+				Content:    result.Content,
+				IsError:    result.IsError,
+			})
+		}
+		out.Messages = append(out.Messages, m)
+	}
+	return out
+}
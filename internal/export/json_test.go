@@ -0,0 +1,28 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONFinishReason(t *testing.T) {
+	t.Parallel()
+
+	msgs := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{
+			message.TextContent{Text: "Refactor everything"},
+			message.Finish{Reason: "stop"},
+		}},
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			message.TextContent{Text: "Starting with"},
+			message.Finish{Reason: message.FinishReasonCanceled, CancelReason: message.CancelReasonUser},
+		}},
+	}
+	out := JSON(session.Session{}, msgs)
+	require.Empty(t, out.Messages[0].FinishReason)
+	require.Equal(t, message.FinishReasonCanceled, out.Messages[1].FinishReason)
+	require.Equal(t, message.CancelReasonUser, out.Messages[1].CancelReason)
+}
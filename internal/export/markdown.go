@@ -0,0 +1,87 @@
+// Package export writes sessions out of the database, as Markdown for people
+// and JSON for other programs.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+)
+
+// markdownLink links the code span of text to target, or returns the bare
+// code span without a target.
+func markdownLink(text, target string) string {
+	if target == "" {
+		return "`" + text + "`"
+	}
+	return fmt.Sprintf("[`%s`](<%s>)", text, target)
+}
+
+// callTarget returns the URL or file the tool call worked on, if any.
+func callTarget(call message.ToolCall) (text, target string) {
+	var input struct {
+		URL      string `json:"url"`
+		FilePath string `json:"file_path"`
+		Path     string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(call.Input), &input); err != nil {
+		return "", ""
+	}
+	switch {
+	case input.URL != "":
+		return input.URL, input.URL
+	case input.FilePath != "":
+		return filepath.Base(input.FilePath), styles.FileURL(input.FilePath)
+	case input.Path != "":
+		return filepath.Base(input.Path), styles.FileURL(input.Path)
+	}
+	return "", ""
+}
+
+// Markdown renders the conversation of the session, leaving out tool
+// results and reasoning. Attachments and the files and URLs tools worked on
+// link to their targets.
+func Markdown(sess session.Session, msgs []message.Message) string {
+	var sb strings.Builder
+	title := sess.Title
+	if title == "" {
+		title = "Untitled Session"
+	}
+	fmt.Fprintf(&sb, "# %s\n", title)
+
+	for _, msg := range msgs {
+		var parts []string
+		if text := strings.TrimSpace(msg.Content().Text); text != "" {
+			parts = append(parts, text)
+		}
+		switch msg.Role {
+		case message.User:
+			for _, attachment := range msg.BinaryContent() {
+				parts = append(parts, fmt.Sprintf("_Attached %s_", markdownLink(filepath.Base(attachment.Path), styles.FileURL(attachment.Path))))
+			}
+			if len(parts) > 0 {
+				fmt.Fprintf(&sb, "\n## You\n\n%s\n", strings.Join(parts, "\n\n"))
+			}
+		case message.Assistant:
+			for _, call := range msg.ToolCalls() {
+				used := fmt.Sprintf("_Used `%s`", call.Name)
+				if text, target := callTarget(call); target != "" {
+					used += " on " + markdownLink(text, target)
+				}
+				parts = append(parts, used+"_")
+			}
+			if finish := msg.FinishPart(); finish != nil && finish.CancelReason != "" {
+				parts = append(parts, fmt.Sprintf("_Canceled: %s_", strings.ToLower(finish.CancelReason.Description())))
+			}
+			if len(parts) > 0 {
+				fmt.Fprintf(&sb, "\n## Crush\n\n%s\n", strings.Join(parts, "\n\n"))
+			}
+		}
+	}
+	return sb.String()
+}
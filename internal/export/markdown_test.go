@@ -0,0 +1,56 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdown(t *testing.T) {
+	t.Parallel()
+
+	msgs := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{
+			message.TextContent{Text: "What's in main.go?"},
+			message.BinaryContent{Path: "/tmp/shot.png", MIMEType: "image/png"},
+		}},
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			message.ReasoningContent{Thinking: "let me look"},
+			message.ToolCall{ID: "1", Name: "view", Input: `{"file_path":"/tmp/main.go"}`, Finished: true},
+			message.ToolCall{ID: "2", Name: "fetch", Input: `{"url":"https://go.dev"}`, Finished: true},
+			message.ToolCall{ID: "3", Name: "bash", Input: `{"command":"ls"}`, Finished: true},
+		}},
+		{Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "1", Content: "package main"},
+		}},
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			message.TextContent{Text: "The main package."},
+			message.Finish{Reason: message.FinishReasonCanceled, CancelReason: message.CancelReasonTimeout},
+		}},
+	}
+
+	require.Equal(t, `# Reading main
+
+## You
+
+What's in main.go?
+
+_Attached [`+"`shot.png`"+`](<file:///tmp/shot.png>)_
+
+## Crush
+
+_Used `+"`view`"+` on [`+"`main.go`"+`](<file:///tmp/main.go>)_
+
+_Used `+"`fetch`"+` on [`+"`https://go.dev`"+`](<https://go.dev>)_
+
+_Used `+"`bash`"+`_
+
+## Crush
+
+The main package.
+
+_Canceled: timed out_
+`, Markdown(session.Session{Title: "Reading main"}, msgs))
+}
@@ -0,0 +1,158 @@
+// Package fact stores the facts the agent learned during a session, such as
+// the decisions taken, the constraints to respect or the API endpoints it
+// discovered, so they stay in its context after the session is summarized.
+package fact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+const (
+	// MaxFacts is the number of facts a session can hold.
+	MaxFacts = 50
+	// MaxValueLength is the length of the longest fact, they're meant to be
+	// short.
+	MaxValueLength = 500
+)
+
+const (
+	CategoryDecision   = "decision"
+	CategoryConstraint = "constraint"
+	CategoryEndpoint   = "endpoint"
+	CategoryNote       = "note"
+)
+
+var (
+	ErrTooMany  = errors.New("too many facts")
+	ErrTooLong  = errors.New("fact is too long")
+	ErrNotFound = errors.New("fact not found")
+)
+
+// Fact is something to remember for the rest of a session.
+type Fact struct {
+	SessionID string
+	Key       string
+	Value     string
+	Category  string
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+type Service interface {
+	pubsub.Suscriber[Fact]
+	// Set records the fact under its key, replacing the one already there.
+	// The category defaults to note.
+	Set(ctx context.Context, sessionID, key, value, category string) (Fact, error)
+	Delete(ctx context.Context, sessionID, key string) error
+	List(ctx context.Context, sessionID string) ([]Fact, error)
+}
+
+type service struct {
+	*pubsub.Broker[Fact]
+	q db.Querier
+}
+
+func NewService(q db.Querier) Service {
+	return &service{
+		Broker: pubsub.NewBroker[Fact](),
+		q:      q,
+	}
+}
+
+func (s *service) Set(ctx context.Context, sessionID, key, value, category string) (Fact, error) {
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	if len(value) > MaxValueLength {
+		return Fact{}, fmt.Errorf("%w: %d characters, the limit is %d", ErrTooLong, len(value), MaxValueLength)
+	}
+	if category == "" {
+		category = CategoryNote
+	}
+
+	facts, err := s.List(ctx, sessionID)
+	if err != nil {
+		return Fact{}, err
+	}
+	if len(facts) >= MaxFacts && !slices.ContainsFunc(facts, func(f Fact) bool { return f.Key == key }) {
+		return Fact{}, fmt.Errorf("%w: the limit is %d, delete the ones no longer needed", ErrTooMany, MaxFacts)
+	}
+
+	dbFact, err := s.q.SetSessionFact(ctx, db.SetSessionFactParams{
+		SessionID: sessionID,
+		Key:       key,
+		Value:     value,
+		Category:  category,
+	})
+	if err != nil {
+		return Fact{}, err
+	}
+	fact := fromDBItem(dbFact)
+	s.Publish(pubsub.UpdatedEvent, fact)
+	return fact, nil
+}
+
+func (s *service) Delete(ctx context.Context, sessionID, key string) error {
+	key = strings.TrimSpace(key)
+	deleted, err := s.q.DeleteSessionFact(ctx, db.DeleteSessionFactParams{
+		SessionID: sessionID,
+		Key:       key,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	s.Publish(pubsub.DeletedEvent, Fact{SessionID: sessionID, Key: key})
+	return nil
+}
+
+func (s *service) List(ctx context.Context, sessionID string) ([]Fact, error) {
+	dbFacts, err := s.q.ListSessionFacts(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	facts := make([]Fact, len(dbFacts))
+	for i, item := range dbFacts {
+		facts[i] = fromDBItem(item)
+	}
+	return facts, nil
+}
+
+// Format renders the facts compactly to be given to the model, one line per
+// fact grouped by category, or nothing without facts.
+func Format(facts []Fact) string {
+	if len(facts) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("<session_facts>\n")
+	category := ""
+	for _, f := range facts {
+		if f.Category != category {
+			category = f.Category
+			fmt.Fprintf(&b, "%s:\n", category)
+		}
+		fmt.Fprintf(&b, "- %s: %s\n", f.Key, f.Value)
+	}
+	b.WriteString("</session_facts>")
+	return b.String()
+}
+
+func fromDBItem(item db.SessionFact) Fact {
+	return Fact{
+		SessionID: item.SessionID,
+		Key:       item.Key,
+		Value:     item.Value,
+		Category:  item.Category,
+		CreatedAt: item.CreatedAt,
+		UpdatedAt: item.UpdatedAt,
+	}
+}
@@ -0,0 +1,89 @@
+package fact
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	q := db.New(conn)
+	for _, id := range []string{"session", "other"} {
+		_, err = q.CreateSession(t.Context(), db.CreateSessionParams{ID: id, Title: "Test"})
+		require.NoError(t, err)
+	}
+
+	svc := NewService(q)
+	events := svc.Subscribe(t.Context())
+
+	set, err := svc.Set(t.Context(), "session", "auth", "Tokens come from /api/v2/token", CategoryEndpoint)
+	require.NoError(t, err)
+	require.Equal(t, "auth", set.Key)
+	event := <-events
+	require.Equal(t, pubsub.UpdatedEvent, event.Type)
+	require.Equal(t, set, event.Payload)
+
+	_, err = svc.Set(t.Context(), "session", " db ", "Stay on SQLite", "")
+	require.NoError(t, err)
+	<-events
+	_, err = svc.Set(t.Context(), "session", "auth", "Tokens come from /api/v3/token", CategoryEndpoint)
+	require.NoError(t, err)
+	<-events
+	_, err = svc.Set(t.Context(), "other", "db", "Postgres", CategoryDecision)
+	require.NoError(t, err)
+	<-events
+
+	facts, err := svc.List(t.Context(), "session")
+	require.NoError(t, err)
+	require.Len(t, facts, 2)
+	require.Equal(t, "auth", facts[0].Key)
+	require.Equal(t, "Tokens come from /api/v3/token", facts[0].Value)
+	require.Equal(t, "db", facts[1].Key)
+	require.Equal(t, CategoryNote, facts[1].Category)
+
+	_, err = svc.Set(t.Context(), "session", "long", strings.Repeat("a", MaxValueLength+1), "")
+	require.ErrorIs(t, err, ErrTooLong)
+
+	require.NoError(t, svc.Delete(t.Context(), "session", "db"))
+	event = <-events
+	require.Equal(t, pubsub.DeletedEvent, event.Type)
+	require.ErrorIs(t, svc.Delete(t.Context(), "session", "db"), ErrNotFound)
+
+	for i := range MaxFacts - 1 {
+		_, err = svc.Set(t.Context(), "session", fmt.Sprintf("fact %d", i), "value", "")
+		require.NoError(t, err)
+		<-events
+	}
+	_, err = svc.Set(t.Context(), "session", "one more", "value", "")
+	require.ErrorIs(t, err, ErrTooMany)
+	// Facts already there can still be replaced.
+	_, err = svc.Set(t.Context(), "session", "auth", "Tokens come from /token", CategoryEndpoint)
+	require.NoError(t, err)
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, Format(nil))
+	require.Equal(t, `<session_facts>
+decision:
+- db: Stay on SQLite
+- orm: No ORM
+endpoint:
+- auth: Tokens come from /api/v2/token
+</session_facts>`, Format([]Fact{
+		{Key: "db", Value: "Stay on SQLite", Category: CategoryDecision},
+		{Key: "orm", Value: "No ORM", Category: CategoryDecision},
+		{Key: "auth", Value: "Tokens come from /api/v2/token", Category: CategoryEndpoint},
+	}))
+}
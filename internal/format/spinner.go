@@ -8,7 +8,6 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/tui/components/anim"
-	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/x/ansi"
 )
 
@@ -43,14 +42,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // NewSpinner creates a new spinner with the given message
 func NewSpinner(ctx context.Context, cancel context.CancelFunc, message string) *Spinner {
-	t := styles.CurrentTheme()
 	model := model{
 		anim: anim.New(anim.Settings{
 			Size:        10,
 			Label:       message,
-			LabelColor:  t.FgBase,
-			GradColorA:  t.Primary,
-			GradColorB:  t.Secondary,
 			CycleColors: true,
 		}),
 		cancel: cancel,
@@ -0,0 +1,123 @@
+package fsext
+
+import (
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// FileIndex is a cached list of the files of a directory, honoring the
+// ignore files. The directories are watched, the list is only walked again
+// after files were created, removed or renamed.
+type FileIndex struct {
+	root  string
+	depth int
+	limit int
+
+	mu      sync.Mutex
+	files   []string
+	stale   bool
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+// NewFileIndex creates an index of the files under root, walking at most
+// depth levels and keeping at most limit entries. When the directories
+// can't be watched the files are listed again on every call to Files.
+func NewFileIndex(root string, depth, limit int) *FileIndex {
+	idx := &FileIndex{
+		root:  root,
+		depth: depth,
+		limit: limit,
+		stale: true,
+		done:  make(chan struct{}),
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Warn("Failed to watch files, the file index won't be cached", "error", err)
+		return idx
+	}
+	idx.watcher = watcher
+	go idx.watch()
+	return idx
+}
+
+// Files returns the paths of the files in the index, relative to its root,
+// slash separated and sorted.
+func (idx *FileIndex) Files() ([]string, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if !idx.stale && idx.watcher != nil {
+		return slices.Clone(idx.files), nil
+	}
+
+	entries, _, err := ListDirectory(idx.root, nil, idx.depth, idx.limit)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(entries))
+	dirs := []string{idx.root}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, string(filepath.Separator)) {
+			dirs = append(dirs, entry)
+			continue
+		}
+		rel, err := filepath.Rel(idx.root, entry)
+		if err != nil {
+			continue
+		}
+		files = append(files, filepath.ToSlash(rel))
+	}
+	slices.Sort(files)
+
+	if idx.watcher != nil {
+		for _, dir := range dirs {
+			if err := idx.watcher.Add(dir); err != nil {
+				slog.Debug("Failed to watch directory", "path", dir, "error", err)
+			}
+		}
+	}
+	idx.files = files
+	idx.stale = false
+	return slices.Clone(files), nil
+}
+
+func (idx *FileIndex) watch() {
+	for {
+		select {
+		case <-idx.done:
+			return
+		case ev, ok := <-idx.watcher.Events:
+			if !ok {
+				return
+			}
+			if ev.Has(fsnotify.Create) || ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+				idx.mu.Lock()
+				idx.stale = true
+				idx.mu.Unlock()
+			}
+		case err, ok := <-idx.watcher.Errors:
+			if !ok {
+				return
+			}
+			// Events may have been missed, list the files again to be sure.
+			slog.Debug("File watcher error", "error", err)
+			idx.mu.Lock()
+			idx.stale = true
+			idx.mu.Unlock()
+		}
+	}
+}
+
+// Close stops watching the directories.
+func (idx *FileIndex) Close() error {
+	if idx.watcher == nil {
+		return nil
+	}
+	close(idx.done)
+	return idx.watcher.Close()
+}
@@ -0,0 +1,45 @@
+package fsext
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileIndex(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":     "*.log\n",
+		"main.go":        "package main",
+		"build.log":      "build output",
+		"cmd/root.go":    "package cmd",
+		"cmd/testdata/x": "x",
+	} {
+		fp := filepath.Join(tmp, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fp), 0o755))
+		require.NoError(t, os.WriteFile(fp, []byte(content), 0o644))
+	}
+
+	idx := NewFileIndex(tmp, 0, 0)
+	t.Cleanup(func() { require.NoError(t, idx.Close()) })
+
+	files, err := idx.Files()
+	require.NoError(t, err)
+	require.Equal(t, []string{".gitignore", "cmd/root.go", "cmd/testdata/x", "main.go"}, files)
+
+	t.Run("picks up new files", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(tmp, "cmd", "sync.go"), []byte("package cmd"), 0o644))
+		require.NoError(t, os.Remove(filepath.Join(tmp, "main.go")))
+		require.Eventually(t, func() bool {
+			files, err := idx.Files()
+			require.NoError(t, err)
+			return slices.Equal(files, []string{".gitignore", "cmd/root.go", "cmd/sync.go", "cmd/testdata/x"})
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
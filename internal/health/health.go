@@ -0,0 +1,211 @@
+// Package health checks the status pages of providers when requests to them
+// keep failing, to tell a provider incident apart from a local problem.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+const (
+	// FailureThreshold is the number of consecutive failed requests to a
+	// provider after which its status page is checked.
+	FailureThreshold = 3
+
+	// checkInterval is the minimum time between two checks of the status
+	// page of a provider.
+	checkInterval = time.Minute
+	checkTimeout  = 10 * time.Second
+)
+
+// Indicators reported by status pages, from the least to the most severe.
+const (
+	IndicatorNone     = "none"
+	IndicatorMinor    = "minor"
+	IndicatorMajor    = "major"
+	IndicatorCritical = "critical"
+)
+
+// StatusPages maps the providers with a public status page to the endpoint
+// returning their overall status.
+var StatusPages = map[string]string{
+	string(catwalk.InferenceProviderAnthropic): "https://status.anthropic.com/api/v2/status.json",
+	string(catwalk.InferenceProviderOpenAI):    "https://status.openai.com/api/v2/status.json",
+}
+
+// ErrNoStatusPage is returned when checking a provider without a known
+// status page.
+var ErrNoStatusPage = errors.New("provider has no known status page")
+
+// Status is the overall status of a provider as reported by its status page.
+type Status struct {
+	Provider    string
+	Indicator   string
+	Description string
+	// URL is the page to visit for the details of an incident.
+	URL       string
+	CheckedAt time.Time
+}
+
+// Incident reports whether the status page reports any degradation.
+func (s Status) Incident() bool {
+	return s.Indicator != "" && s.Indicator != IndicatorNone
+}
+
+type Service interface {
+	pubsub.Suscriber[Status]
+	// RecordFailure counts a failed request to the provider, checking its
+	// status page in the background once requests keep failing. Incidents
+	// found this way are published.
+	RecordFailure(providerID string)
+	// RecordSuccess resets the failures counted for the provider.
+	RecordSuccess(providerID string)
+	// Check fetches the current status of the provider.
+	Check(ctx context.Context, providerID string) (Status, error)
+}
+
+type service struct {
+	*pubsub.Broker[Status]
+	client *http.Client
+	pages  map[string]string
+
+	mu        sync.Mutex
+	failures  map[string]int
+	lastCheck map[string]time.Time
+	// Incidents already published, so they are only reported once.
+	reported map[string]string
+}
+
+func NewService(client *http.Client) Service {
+	return newService(client, StatusPages)
+}
+
+// NewDisabledService returns a service that never checks the status pages,
+// for when health checks are disabled.
+func NewDisabledService() Service {
+	return newService(nil, nil)
+}
+
+func newService(client *http.Client, pages map[string]string) *service {
+	return &service{
+		Broker:    pubsub.NewBroker[Status](),
+		client:    client,
+		pages:     pages,
+		failures:  make(map[string]int),
+		lastCheck: make(map[string]time.Time),
+		reported:  make(map[string]string),
+	}
+}
+
+func (s *service) RecordFailure(providerID string) {
+	if _, ok := s.pages[providerID]; !ok {
+		return
+	}
+
+	s.mu.Lock()
+	s.failures[providerID]++
+	due := s.failures[providerID] >= FailureThreshold && time.Since(s.lastCheck[providerID]) >= checkInterval
+	if due {
+		s.lastCheck[providerID] = time.Now()
+	}
+	s.mu.Unlock()
+
+	if due {
+		go s.checkIncident(providerID)
+	}
+}
+
+func (s *service) RecordSuccess(providerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, providerID)
+	delete(s.reported, providerID)
+}
+
+func (s *service) checkIncident(providerID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	status, err := s.Check(ctx, providerID)
+	if err != nil {
+		slog.Debug("Failed to check provider status page", "provider", providerID, "error", err)
+		return
+	}
+	if !status.Incident() {
+		return
+	}
+
+	s.mu.Lock()
+	seen := s.reported[providerID] == status.Description
+	s.reported[providerID] = status.Description
+	s.mu.Unlock()
+	if !seen {
+		slog.Warn("Provider incident likely", "provider", providerID, "status", status.Description)
+		s.Publish(pubsub.CreatedEvent, status)
+	}
+}
+
+func (s *service) Check(ctx context.Context, providerID string) (Status, error) {
+	url, ok := s.pages[providerID]
+	if !ok {
+		return Status{}, fmt.Errorf("%w: %s", ErrNoStatusPage, providerID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Status{}, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to fetch status page: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Status{}, fmt.Errorf("status page returned %s", resp.Status)
+	}
+
+	// The summary format of Atlassian Statuspage.
+	var summary struct {
+		Page struct {
+			URL string `json:"url"`
+		} `json:"page"`
+		Status struct {
+			Indicator   string `json:"indicator"`
+			Description string `json:"description"`
+		} `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&summary); err != nil {
+		return Status{}, fmt.Errorf("failed to parse status page: %w", err)
+	}
+	status := Status{
+		Provider:    providerID,
+		Indicator:   summary.Status.Indicator,
+		Description: summary.Status.Description,
+		URL:         summary.Page.URL,
+		CheckedAt:   time.Now(),
+	}
+	if status.URL == "" {
+		status.URL = url
+	}
+	return status, nil
+}
+
+// IsProviderFailure reports whether the error of a request suggests the
+// provider itself is having trouble, rather than the request being wrong.
+func IsProviderFailure(err error) bool {
+	var apiErr *fantasy.APICallError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.IsRetryable || apiErr.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,100 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordFailure(t *testing.T) {
+	t.Parallel()
+
+	var checks atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks.Add(1)
+		_, _ = w.Write([]byte(`{
+			"page": {"url": "https://status.example.com"},
+			"status": {"indicator": "major", "description": "Partial System Outage"}
+		}`))
+	}))
+	t.Cleanup(server.Close)
+
+	svc := newService(server.Client(), map[string]string{"anthropic": server.URL})
+	events := svc.Subscribe(t.Context())
+
+	// Providers without a status page are ignored.
+	for range FailureThreshold {
+		svc.RecordFailure("custom")
+	}
+	for range FailureThreshold - 1 {
+		svc.RecordFailure("anthropic")
+	}
+	require.Zero(t, checks.Load())
+
+	svc.RecordFailure("anthropic")
+	select {
+	case event := <-events:
+		require.Equal(t, "anthropic", event.Payload.Provider)
+		require.True(t, event.Payload.Incident())
+		require.Equal(t, "Partial System Outage", event.Payload.Description)
+		require.Equal(t, "https://status.example.com", event.Payload.URL)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no incident reported")
+	}
+
+	// The status page is not checked again right away.
+	svc.RecordFailure("anthropic")
+	require.Equal(t, int32(1), checks.Load())
+
+	svc.RecordSuccess("anthropic")
+	svc.mu.Lock()
+	require.Zero(t, svc.failures["anthropic"])
+	svc.mu.Unlock()
+}
+
+func TestDisabledService(t *testing.T) {
+	t.Parallel()
+
+	svc := NewDisabledService()
+	for range FailureThreshold {
+		svc.RecordFailure("anthropic")
+	}
+	_, err := svc.Check(t.Context(), "anthropic")
+	require.ErrorIs(t, err, ErrNoStatusPage)
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status": {"indicator": "none", "description": "All Systems Operational"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	svc := newService(server.Client(), map[string]string{"openai": server.URL})
+	status, err := svc.Check(t.Context(), "openai")
+	require.NoError(t, err)
+	require.False(t, status.Incident())
+	require.Equal(t, "All Systems Operational", status.Description)
+
+	_, err = svc.Check(t.Context(), "custom")
+	require.ErrorIs(t, err, ErrNoStatusPage)
+}
+
+func TestIsProviderFailure(t *testing.T) {
+	t.Parallel()
+
+	apiErr := func(status int, retryable bool) error {
+		return &fantasy.APICallError{AIError: &fantasy.AIError{Message: "error"}, StatusCode: status, IsRetryable: retryable}
+	}
+	require.True(t, IsProviderFailure(apiErr(http.StatusServiceUnavailable, false)))
+	require.True(t, IsProviderFailure(apiErr(http.StatusTooManyRequests, true)))
+	require.False(t, IsProviderFailure(apiErr(http.StatusUnauthorized, false)))
+	require.False(t, IsProviderFailure(errors.New("boom")))
+}
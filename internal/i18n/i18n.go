@@ -0,0 +1,123 @@
+// Package i18n provides translations for the strings shown in the TUI.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultLanguage is used when no translation is available for the detected
+// or configured language.
+const DefaultLanguage = "en"
+
+//go:embed locales/*.json
+var localesFS embed.FS
+
+var (
+	catalogs = loadCatalogs()
+	current  atomic.Value // string
+)
+
+func init() {
+	current.Store(DefaultLanguage)
+}
+
+func loadCatalogs() map[string]map[string]string {
+	catalogs := make(map[string]map[string]string)
+	entries, err := localesFS.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: reading locales: %v", err))
+	}
+	for _, entry := range entries {
+		data, err := localesFS.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: reading %s: %v", entry.Name(), err))
+		}
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("i18n: parsing %s: %v", entry.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+	return catalogs
+}
+
+// Languages returns the languages there are translations for.
+func Languages() []string {
+	languages := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		languages = append(languages, lang)
+	}
+	return languages
+}
+
+// Init sets the language of the TUI. If lang is empty, the language is
+// detected from the environment.
+func Init(lang string) {
+	if lang == "" {
+		lang = DetectLanguage()
+	}
+	if !SetLanguage(lang) {
+		slog.Debug("No translations for language, falling back to default", "language", lang)
+		SetLanguage(DefaultLanguage)
+	}
+}
+
+// SetLanguage changes the current language. It returns false if there are no
+// translations for the given language.
+func SetLanguage(lang string) bool {
+	lang = normalize(lang)
+	if _, ok := catalogs[lang]; !ok {
+		return false
+	}
+	current.Store(lang)
+	return true
+}
+
+// Language returns the current language.
+func Language() string {
+	return current.Load().(string)
+}
+
+// DetectLanguage returns the language from the locale environment variables,
+// following the POSIX precedence.
+func DetectLanguage() string {
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := normalize(os.Getenv(env)); value != "" && value != "c" && value != "posix" {
+			return value
+		}
+	}
+	return DefaultLanguage
+}
+
+// normalize turns a locale such as "pt_BR.UTF-8" into a language code ("pt").
+func normalize(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "_-.@"); i >= 0 {
+		locale = locale[:i]
+	}
+	return locale
+}
+
+// T returns the translation of key in the current language. It falls back to
+// the default language and then to the key itself.
+func T(key string) string {
+	if s, ok := catalogs[Language()][key]; ok {
+		return s
+	}
+	if s, ok := catalogs[DefaultLanguage][key]; ok {
+		return s
+	}
+	return key
+}
+
+// Tf formats the translation of key with the given arguments.
+func Tf(key string, args ...any) string {
+	return fmt.Sprintf(T(key), args...)
+}
@@ -0,0 +1,72 @@
+package i18n
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogsAreComplete(t *testing.T) {
+	t.Parallel()
+
+	keys := slices.Sorted(maps.Keys(catalogs[DefaultLanguage]))
+	require.NotEmpty(t, keys)
+	for lang, catalog := range catalogs {
+		require.Equal(t, keys, slices.Sorted(maps.Keys(catalog)), "language %q", lang)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"":                "",
+		"en":              "en",
+		"pt_BR.UTF-8":     "pt",
+		"de_DE@euro":      "de",
+		"fr-CA":           "fr",
+		"C":               "c",
+		" ES_es.utf8 ":    "es",
+		"sr_RS.UTF-8@lat": "sr",
+	}
+	for locale, want := range tests {
+		require.Equal(t, want, normalize(locale), "locale %q", locale)
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "fr_FR.UTF-8")
+	t.Setenv("LANG", "de_DE.UTF-8")
+	require.Equal(t, "fr", DetectLanguage())
+
+	t.Setenv("LC_ALL", "C")
+	require.Equal(t, "fr", DetectLanguage())
+
+	t.Setenv("LC_ALL", "es_ES.UTF-8")
+	require.Equal(t, "es", DetectLanguage())
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "")
+	require.Equal(t, DefaultLanguage, DetectLanguage())
+}
+
+func TestTranslate(t *testing.T) {
+	t.Cleanup(func() { SetLanguage(DefaultLanguage) })
+
+	require.False(t, SetLanguage("xx"))
+	require.Equal(t, DefaultLanguage, Language())
+
+	Init("pt_BR.UTF-8")
+	require.Equal(t, "pt", Language())
+	require.Equal(t, "Negar", T("permissions.deny"))
+	require.Equal(t, "A ferramenta bash levou 1m0s para executar", Tf("status.slow_tool", "bash", "1m0s"))
+	require.Equal(t, "missing.key", T("missing.key"))
+
+	Init("xx")
+	require.Equal(t, DefaultLanguage, Language())
+	require.Equal(t, "Deny", T("permissions.deny"))
+}
@@ -0,0 +1,100 @@
+package importer
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+const (
+	aiderHistoryFile  = ".aider.chat.history.md"
+	aiderStartPrefix  = "# aider chat started at "
+	aiderTimeLayout   = "2006-01-02 15:04:05"
+	aiderPromptPrefix = "#### "
+	aiderOutputPrefix = "> "
+)
+
+// readAider reads the markdown chat history aider appends to in the project,
+// path being either the file or the project directory. Every time aider was
+// started makes a conversation. The history only has the time each was
+// started, and the tool output aider quotes is left out.
+func readAider(path string) ([]Conversation, error) {
+	if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		path = filepath.Join(path, aiderHistoryFile)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		convs     []Conversation
+		started   time.Time
+		role      message.MessageRole
+		lines     []string
+		hasChat   bool
+		flushText = func() {
+			text := strings.TrimSpace(strings.Join(lines, "\n"))
+			lines = nil
+			if text == "" {
+				return
+			}
+			if !hasChat {
+				convs = append(convs, Conversation{})
+				hasChat = true
+			}
+			conv := &convs[len(convs)-1]
+			// Consecutive prompts are sent together.
+			if n := len(conv.Messages); n > 0 && conv.Messages[n-1].Role == role && role == message.User {
+				prev := conv.Messages[n-1].Parts[0].(message.TextContent)
+				conv.Messages[n-1].Parts[0] = message.TextContent{Text: prev.Text + "\n" + text}
+				return
+			}
+			conv.Messages = append(conv.Messages, Message{
+				Role:      role,
+				Parts:     []message.ContentPart{message.TextContent{Text: text}},
+				CreatedAt: started,
+			})
+		}
+	)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, aiderStartPrefix):
+			flushText()
+			started, _ = time.ParseInLocation(aiderTimeLayout, strings.TrimPrefix(line, aiderStartPrefix), time.Local)
+			role, hasChat = "", false
+		case strings.HasPrefix(line, aiderPromptPrefix):
+			if role != message.User {
+				flushText()
+				role = message.User
+			}
+			lines = append(lines, strings.TrimPrefix(line, aiderPromptPrefix))
+		case strings.HasPrefix(line, aiderOutputPrefix) || line == ">":
+			continue
+		default:
+			if role == message.User {
+				flushText()
+				role = message.Assistant
+			}
+			if role == "" {
+				continue
+			}
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flushText()
+	return convs, nil
+}
@@ -0,0 +1,245 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// claudeCodeEntry is a line of the JSONL transcripts Claude Code keeps under
+// ~/.claude/projects/<project>/<session>.jsonl.
+type claudeCodeEntry struct {
+	Type        string            `json:"type"`
+	IsMeta      bool              `json:"isMeta"`
+	IsSidechain bool              `json:"isSidechain"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Summary     string            `json:"summary"`
+	Message     claudeCodeMessage `json:"message"`
+}
+
+type claudeCodeMessage struct {
+	ID      string          `json:"id"`
+	Role    string          `json:"role"`
+	Model   string          `json:"model"`
+	Content json.RawMessage `json:"content"`
+}
+
+type claudeCodeBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	Thinking  string          `json:"thinking"`
+	Signature string          `json:"signature"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+}
+
+// readClaudeCode reads a Claude Code transcript, or every transcript in a
+// project directory.
+func readClaudeCode(path string) ([]Conversation, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		conv, err := readClaudeCodeFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return []Conversation{conv}, nil
+	}
+	files, err := filepath.Glob(filepath.Join(path, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var convs []Conversation
+	for _, file := range files {
+		conv, err := readClaudeCodeFile(file)
+		if err != nil {
+			return nil, err
+		}
+		convs = append(convs, conv)
+	}
+	return convs, nil
+}
+
+func readClaudeCodeFile(path string) (Conversation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Conversation{}, err
+	}
+	defer f.Close()
+
+	var (
+		conv Conversation
+		// names maps the IDs of the tool calls to the tools, as results only
+		// refer to the calls.
+		names  = map[string]string{}
+		lastID string
+	)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry claudeCodeEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return Conversation{}, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if entry.IsMeta || entry.IsSidechain {
+			continue
+		}
+		switch entry.Type {
+		case "summary":
+			if conv.Title == "" {
+				conv.Title = entry.Summary
+			}
+		case "assistant":
+			parts, err := claudeCodeAssistantParts(entry.Message.Content, names)
+			if err != nil {
+				return Conversation{}, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			// Claude Code writes a line per content block, all with the ID of
+			// the message they belong to.
+			if n := len(conv.Messages); n > 0 && entry.Message.ID != "" && entry.Message.ID == lastID {
+				conv.Messages[n-1].Parts = append(conv.Messages[n-1].Parts, parts...)
+				continue
+			}
+			model := entry.Message.Model
+			if model == "<synthetic>" {
+				model = ""
+			}
+			lastID = entry.Message.ID
+			conv.Messages = append(conv.Messages, Message{
+				Role:      message.Assistant,
+				Parts:     parts,
+				Model:     model,
+				CreatedAt: entry.Timestamp,
+			})
+		case "user":
+			lastID = ""
+			msgs, err := claudeCodeUserMessages(entry, names)
+			if err != nil {
+				return Conversation{}, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			conv.Messages = append(conv.Messages, msgs...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Conversation{}, err
+	}
+	return conv, nil
+}
+
+func claudeCodeAssistantParts(content json.RawMessage, names map[string]string) ([]message.ContentPart, error) {
+	blocks, text, err := claudeCodeBlocks(content)
+	if err != nil {
+		return nil, err
+	}
+	if text != "" {
+		return []message.ContentPart{message.TextContent{Text: text}}, nil
+	}
+	var parts []message.ContentPart
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			parts = append(parts, message.TextContent{Text: block.Text})
+		case "thinking":
+			parts = append(parts, message.ReasoningContent{Thinking: block.Thinking, Signature: block.Signature})
+		case "tool_use":
+			names[block.ID] = block.Name
+			parts = append(parts, message.ToolCall{
+				ID:       block.ID,
+				Name:     block.Name,
+				Input:    string(block.Input),
+				Finished: true,
+			})
+		}
+	}
+	return parts, nil
+}
+
+// claudeCodeUserMessages returns the prompt of the entry, and the results of
+// the tools in a message of their own.
+func claudeCodeUserMessages(entry claudeCodeEntry, names map[string]string) ([]Message, error) {
+	blocks, text, err := claudeCodeBlocks(entry.Message.Content)
+	if err != nil {
+		return nil, err
+	}
+	if text != "" {
+		return []Message{{
+			Role:      message.User,
+			Parts:     []message.ContentPart{message.TextContent{Text: text}},
+			CreatedAt: entry.Timestamp,
+		}}, nil
+	}
+	var prompt, results []message.ContentPart
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			prompt = append(prompt, message.TextContent{Text: block.Text})
+		case "tool_result":
+			content, err := claudeCodeResultText(block.Content)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, message.ToolResult{
+				ToolCallID: block.ToolUseID,
+				Name:       names[block.ToolUseID],
+				Content:    content,
+				IsError:    block.IsError,
+			})
+		}
+	}
+	var msgs []Message
+	if len(results) > 0 {
+		msgs = append(msgs, Message{Role: message.Tool, Parts: results, CreatedAt: entry.Timestamp})
+	}
+	if len(prompt) > 0 {
+		msgs = append(msgs, Message{Role: message.User, Parts: prompt, CreatedAt: entry.Timestamp})
+	}
+	return msgs, nil
+}
+
+// claudeCodeBlocks decodes content that's either a string or a list of
+// blocks.
+func claudeCodeBlocks(content json.RawMessage) ([]claudeCodeBlock, string, error) {
+	if len(content) == 0 {
+		return nil, "", nil
+	}
+	if content[0] == '"' {
+		var text string
+		err := json.Unmarshal(content, &text)
+		return nil, text, err
+	}
+	var blocks []claudeCodeBlock
+	err := json.Unmarshal(content, &blocks)
+	return blocks, "", err
+}
+
+// claudeCodeResultText returns the text of a tool result, dropping images.
+func claudeCodeResultText(content json.RawMessage) (string, error) {
+	blocks, text, err := claudeCodeBlocks(content)
+	if err != nil || text != "" {
+		return text, err
+	}
+	var texts []string
+	for _, block := range blocks {
+		if block.Type == "text" {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
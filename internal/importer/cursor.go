@@ -0,0 +1,261 @@
+package importer
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/ncruces/go-sqlite3/driver"
+)
+
+const (
+	cursorDatabaseFile = "state.vscdb"
+	// cursorChatKey holds the chats of the panel in the workspace databases
+	// of older versions.
+	cursorChatKey = "workbench.panel.aichat.view.aichat.chatdata"
+)
+
+// Types of the bubbles of composer conversations.
+const (
+	cursorBubbleUser      = 1
+	cursorBubbleAssistant = 2
+)
+
+type cursorChatData struct {
+	Tabs []struct {
+		ChatTitle    string `json:"chatTitle"`
+		LastSendTime int64  `json:"lastSendTime"`
+		Bubbles      []struct {
+			Type      string `json:"type"`
+			Text      string `json:"text"`
+			RawText   string `json:"rawText"`
+			ModelType string `json:"modelType"`
+		} `json:"bubbles"`
+	} `json:"tabs"`
+}
+
+type cursorComposer struct {
+	ComposerID   string         `json:"composerId"`
+	Name         string         `json:"name"`
+	CreatedAt    int64          `json:"createdAt"`
+	Conversation []cursorBubble `json:"conversation"`
+	// Newer versions only keep the headers in the composer, the bubbles
+	// having rows of their own.
+	Headers []struct {
+		BubbleID string `json:"bubbleId"`
+	} `json:"fullConversationHeadersOnly"`
+}
+
+type cursorBubble struct {
+	BubbleID  string `json:"bubbleId"`
+	Type      int    `json:"type"`
+	Text      string `json:"text"`
+	CreatedAt string `json:"createdAt"`
+	ModelInfo struct {
+		ModelName string `json:"modelName"`
+	} `json:"modelInfo"`
+	ToolFormerData *struct {
+		ToolCallID string `json:"toolCallId"`
+		Name       string `json:"name"`
+		RawArgs    string `json:"rawArgs"`
+		Result     string `json:"result"`
+	} `json:"toolFormerData"`
+}
+
+// readCursor reads the chats and composer conversations from a Cursor
+// state.vscdb database, path being either the file or the directory holding
+// it. The database is opened read-only, so Cursor can keep running.
+func readCursor(path string) ([]Conversation, error) {
+	if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		path = filepath.Join(path, cursorDatabaseFile)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	conn, err := driver.Open("file:" + filepath.ToSlash(path) + "?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer conn.Close()
+
+	chats, err := readCursorChats(conn)
+	if err != nil {
+		return nil, err
+	}
+	composers, err := readCursorComposers(conn)
+	if err != nil {
+		return nil, err
+	}
+	return append(chats, composers...), nil
+}
+
+func readCursorChats(conn *sql.DB) ([]Conversation, error) {
+	if ok, err := hasTable(conn, "ItemTable"); err != nil || !ok {
+		return nil, err
+	}
+	var value []byte
+	err := conn.QueryRow("SELECT value FROM ItemTable WHERE key = ?", cursorChatKey).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var data cursorChatData
+	if err := json.Unmarshal(value, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode chats: %w", err)
+	}
+
+	var convs []Conversation
+	for _, tab := range data.Tabs {
+		// Only the time of the last message is kept.
+		var at time.Time
+		if tab.LastSendTime > 0 {
+			at = time.UnixMilli(tab.LastSendTime)
+		}
+		conv := Conversation{Title: tab.ChatTitle}
+		for _, bubble := range tab.Bubbles {
+			text := bubble.Text
+			if text == "" {
+				text = bubble.RawText
+			}
+			if text == "" {
+				continue
+			}
+			msg := Message{
+				Parts:     []message.ContentPart{message.TextContent{Text: text}},
+				CreatedAt: at,
+			}
+			switch bubble.Type {
+			case "user":
+				msg.Role = message.User
+			case "ai":
+				msg.Role = message.Assistant
+				msg.Model = bubble.ModelType
+			default:
+				continue
+			}
+			conv.Messages = append(conv.Messages, msg)
+		}
+		convs = append(convs, conv)
+	}
+	return convs, nil
+}
+
+func readCursorComposers(conn *sql.DB) ([]Conversation, error) {
+	if ok, err := hasTable(conn, "cursorDiskKV"); err != nil || !ok {
+		return nil, err
+	}
+	rows, err := conn.Query("SELECT value FROM cursorDiskKV WHERE key LIKE 'composerData:%' ORDER BY key")
+	if err != nil {
+		return nil, err
+	}
+	var composers []cursorComposer
+	for rows.Next() {
+		var value []byte
+		if err := rows.Scan(&value); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		var composer cursorComposer
+		if len(value) == 0 || json.Unmarshal(value, &composer) != nil {
+			continue
+		}
+		composers = append(composers, composer)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var convs []Conversation
+	for _, composer := range composers {
+		bubbles := composer.Conversation
+		for _, header := range composer.Headers {
+			var value []byte
+			err := conn.QueryRow("SELECT value FROM cursorDiskKV WHERE key = ?", "bubbleId:"+composer.ComposerID+":"+header.BubbleID).Scan(&value)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			var bubble cursorBubble
+			if json.Unmarshal(value, &bubble) == nil {
+				bubbles = append(bubbles, bubble)
+			}
+		}
+		convs = append(convs, cursorComposerConversation(composer, bubbles))
+	}
+	return convs, nil
+}
+
+func cursorComposerConversation(composer cursorComposer, bubbles []cursorBubble) Conversation {
+	conv := Conversation{Title: composer.Name}
+	var at time.Time
+	if composer.CreatedAt > 0 {
+		at = time.UnixMilli(composer.CreatedAt)
+	}
+	for _, bubble := range bubbles {
+		if t, err := time.Parse(time.RFC3339, bubble.CreatedAt); err == nil {
+			at = t
+		}
+		switch bubble.Type {
+		case cursorBubbleUser:
+			if bubble.Text == "" {
+				continue
+			}
+			conv.Messages = append(conv.Messages, Message{
+				Role:      message.User,
+				Parts:     []message.ContentPart{message.TextContent{Text: bubble.Text}},
+				CreatedAt: at,
+			})
+		case cursorBubbleAssistant:
+			var parts []message.ContentPart
+			if bubble.Text != "" {
+				parts = append(parts, message.TextContent{Text: bubble.Text})
+			}
+			tool := bubble.ToolFormerData
+			if tool != nil && tool.ToolCallID != "" {
+				parts = append(parts, message.ToolCall{
+					ID:       tool.ToolCallID,
+					Name:     tool.Name,
+					Input:    tool.RawArgs,
+					Finished: true,
+				})
+			}
+			if len(parts) == 0 {
+				continue
+			}
+			conv.Messages = append(conv.Messages, Message{
+				Role:      message.Assistant,
+				Parts:     parts,
+				Model:     bubble.ModelInfo.ModelName,
+				CreatedAt: at,
+			})
+			if tool != nil && tool.ToolCallID != "" {
+				conv.Messages = append(conv.Messages, Message{
+					Role: message.Tool,
+					Parts: []message.ContentPart{message.ToolResult{
+						ToolCallID: tool.ToolCallID,
+						Name:       tool.Name,
+						Content:    tool.Result,
+					}},
+					CreatedAt: at,
+				})
+			}
+		}
+	}
+	return conv
+}
+
+func hasTable(conn *sql.DB, name string) (bool, error) {
+	var n int
+	err := conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n)
+	return n > 0, err
+}
@@ -0,0 +1,180 @@
+// Package importer converts the conversations other coding assistants keep
+// on disk into crush sessions, so their history comes along when switching.
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/google/uuid"
+)
+
+// Source is the tool conversations are imported from.
+type Source string
+
+const (
+	SourceClaudeCode Source = "claude-code"
+	SourceAider      Source = "aider"
+	SourceCursor     Source = "cursor"
+)
+
+// Sources lists the tools conversations can be imported from.
+var Sources = []Source{SourceClaudeCode, SourceAider, SourceCursor}
+
+// maxTitleLength is how much of the first prompt makes the title of
+// conversations without one.
+const maxTitleLength = 100
+
+// Conversation is a conversation read from another tool.
+type Conversation struct {
+	Title    string
+	Messages []Message
+}
+
+// Message is a message of a conversation. Tool results go in messages of
+// their own, after the assistant message calling the tools.
+type Message struct {
+	Role      message.MessageRole
+	Parts     []message.ContentPart
+	Model     string
+	CreatedAt time.Time
+}
+
+// Read reads the conversations stored by the tool at path.
+func Read(source Source, path string) ([]Conversation, error) {
+	switch source {
+	case SourceClaudeCode:
+		return readClaudeCode(path)
+	case SourceAider:
+		return readAider(path)
+	case SourceCursor:
+		return readCursor(path)
+	default:
+		return nil, fmt.Errorf("unknown source %q", source)
+	}
+}
+
+// Import stores the conversations as new sessions, and returns their IDs.
+// Conversations without messages are skipped.
+func Import(ctx context.Context, conn *sql.DB, source Source, conversations []Conversation) ([]string, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	q := db.New(conn).WithTx(tx)
+
+	var ids []string
+	for _, conv := range conversations {
+		if len(conv.Messages) == 0 {
+			continue
+		}
+		id, err := importConversation(ctx, q, source, conv)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func importConversation(ctx context.Context, q *db.Queries, source Source, conv Conversation) (string, error) {
+	now := time.Now()
+	created := timestamp(conv.Messages[0].CreatedAt, now)
+	updated := timestamp(conv.Messages[len(conv.Messages)-1].CreatedAt, now)
+	sessionID := uuid.New().String()
+	if err := q.ImportSession(ctx, db.ImportSessionParams{
+		ID:               sessionID,
+		Title:            title(source, conv),
+		PinnedMessageIds: "[]",
+		CreatedAt:        created,
+		UpdatedAt:        max(created, updated),
+	}); err != nil {
+		return "", fmt.Errorf("failed to import session: %w", err)
+	}
+
+	for _, msg := range conv.Messages {
+		at := timestamp(msg.CreatedAt, time.Unix(created, 0))
+		parts := finish(msg, at)
+		data, err := message.MarshalParts(parts)
+		if err != nil {
+			return "", err
+		}
+		if err := q.ImportMessage(ctx, db.ImportMessageParams{
+			ID:         uuid.New().String(),
+			SessionID:  sessionID,
+			Role:       string(msg.Role),
+			Parts:      data,
+			Model:      sql.NullString{String: msg.Model, Valid: msg.Model != ""},
+			CreatedAt:  at,
+			UpdatedAt:  at,
+			FinishedAt: sql.NullInt64{Int64: at, Valid: true},
+		}); err != nil {
+			return "", fmt.Errorf("failed to import message: %w", err)
+		}
+	}
+	return sessionID, nil
+}
+
+// finish returns the parts of the message ending with a finish part, as
+// those of the messages crush writes.
+func finish(msg Message, at int64) []message.ContentPart {
+	parts := msg.Parts
+	for _, part := range parts {
+		if _, ok := part.(message.Finish); ok {
+			return parts
+		}
+	}
+	reason := message.FinishReason("stop")
+	if msg.Role == message.Assistant {
+		reason = message.FinishReasonEndTurn
+		for _, part := range parts {
+			if _, ok := part.(message.ToolCall); ok {
+				reason = message.FinishReasonToolUse
+				break
+			}
+		}
+	}
+	return append(parts, message.Finish{Reason: reason, Time: at})
+}
+
+// title returns the title of the conversation, or the beginning of its first
+// prompt.
+func title(source Source, conv Conversation) string {
+	if t := strings.TrimSpace(conv.Title); t != "" {
+		return t
+	}
+	for _, msg := range conv.Messages {
+		if msg.Role != message.User {
+			continue
+		}
+		for _, part := range msg.Parts {
+			text, ok := part.(message.TextContent)
+			if !ok || strings.TrimSpace(text.Text) == "" {
+				continue
+			}
+			line, _, _ := strings.Cut(strings.TrimSpace(text.Text), "\n")
+			if runes := []rune(line); len(runes) > maxTitleLength {
+				line = string(runes[:maxTitleLength]) + "..."
+			}
+			return line
+		}
+	}
+	return "Imported from " + string(source)
+}
+
+// timestamp returns the unix time of t, or of fallback when t is unknown.
+func timestamp(t, fallback time.Time) int64 {
+	if t.IsZero() {
+		return fallback.Unix()
+	}
+	return t.Unix()
+}
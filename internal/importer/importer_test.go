@@ -0,0 +1,202 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/ncruces/go-sqlite3/driver"
+	"github.com/stretchr/testify/require"
+)
+
+const claudeCodeTranscript = `{"type":"summary","summary":"Fix the parser"}
+{"type":"user","isMeta":true,"timestamp":"2025-01-02T10:00:00Z","message":{"role":"user","content":"<command-name>/init</command-name>"}}
+{"type":"user","timestamp":"2025-01-02T10:00:01Z","message":{"role":"user","content":"Fix the parser"}}
+{"type":"assistant","timestamp":"2025-01-02T10:00:02Z","message":{"id":"msg_1","role":"assistant","model":"claude-sonnet-4","content":[{"type":"thinking","thinking":"Let me look.","signature":"sig"}]}}
+{"type":"assistant","timestamp":"2025-01-02T10:00:03Z","message":{"id":"msg_1","role":"assistant","model":"claude-sonnet-4","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"parser.go"}}]}}
+{"type":"user","timestamp":"2025-01-02T10:00:04Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"package parser"}]}]}}
+{"type":"assistant","isSidechain":true,"timestamp":"2025-01-02T10:00:05Z","message":{"id":"msg_x","role":"assistant","content":[{"type":"text","text":"sub-agent"}]}}
+{"type":"assistant","timestamp":"2025-01-02T10:00:06Z","message":{"id":"msg_2","role":"assistant","model":"claude-sonnet-4","content":[{"type":"text","text":"Fixed."}]}}
+`
+
+func TestReadClaudeCode(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "session.jsonl"), []byte(claudeCodeTranscript), 0o644))
+
+	convs, err := Read(SourceClaudeCode, dir)
+	require.NoError(t, err)
+	require.Len(t, convs, 1)
+	conv := convs[0]
+	require.Equal(t, "Fix the parser", conv.Title)
+	require.Len(t, conv.Messages, 4)
+
+	require.Equal(t, message.User, conv.Messages[0].Role)
+	require.Equal(t, []message.ContentPart{message.TextContent{Text: "Fix the parser"}}, conv.Messages[0].Parts)
+	require.Equal(t, time.Date(2025, 1, 2, 10, 0, 1, 0, time.UTC), conv.Messages[0].CreatedAt)
+
+	require.Equal(t, message.Assistant, conv.Messages[1].Role)
+	require.Equal(t, "claude-sonnet-4", conv.Messages[1].Model)
+	require.Equal(t, []message.ContentPart{
+		message.ReasoningContent{Thinking: "Let me look.", Signature: "sig"},
+		message.ToolCall{ID: "toolu_1", Name: "Read", Input: `{"file_path":"parser.go"}`, Finished: true},
+	}, conv.Messages[1].Parts)
+
+	require.Equal(t, message.Tool, conv.Messages[2].Role)
+	require.Equal(t, []message.ContentPart{
+		message.ToolResult{ToolCallID: "toolu_1", Name: "Read", Content: "package parser"},
+	}, conv.Messages[2].Parts)
+
+	require.Equal(t, []message.ContentPart{message.TextContent{Text: "Fixed."}}, conv.Messages[3].Parts)
+}
+
+func TestReadAider(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	history := `
+# aider chat started at 2025-01-02 10:00:00
+
+> Aider v0.50.0
+> Main model: gpt-4o
+
+#### Add a test
+#### for the parser
+
+Here's the test.
+
+> Applied edit to parser_test.go
+
+# aider chat started at 2025-01-03 09:00:00
+
+> Aider v0.50.0
+
+#### Thanks
+
+You're welcome.
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, aiderHistoryFile), []byte(history), 0o644))
+
+	convs, err := Read(SourceAider, dir)
+	require.NoError(t, err)
+	require.Len(t, convs, 2)
+
+	require.Len(t, convs[0].Messages, 2)
+	require.Equal(t, message.User, convs[0].Messages[0].Role)
+	require.Equal(t, []message.ContentPart{message.TextContent{Text: "Add a test\nfor the parser"}}, convs[0].Messages[0].Parts)
+	require.Equal(t, message.Assistant, convs[0].Messages[1].Role)
+	require.Equal(t, []message.ContentPart{message.TextContent{Text: "Here's the test."}}, convs[0].Messages[1].Parts)
+	require.Equal(t, time.Date(2025, 1, 2, 10, 0, 0, 0, time.Local), convs[0].Messages[0].CreatedAt)
+
+	require.Len(t, convs[1].Messages, 2)
+	require.Equal(t, []message.ContentPart{message.TextContent{Text: "You're welcome."}}, convs[1].Messages[1].Parts)
+}
+
+func TestReadCursor(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), cursorDatabaseFile)
+	conn, err := driver.Open(path)
+	require.NoError(t, err)
+	for _, stmt := range []string{
+		"CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB)",
+		"CREATE TABLE cursorDiskKV (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB)",
+	} {
+		_, err := conn.Exec(stmt)
+		require.NoError(t, err)
+	}
+	rows := map[string]map[string]string{
+		"ItemTable": {
+			cursorChatKey: `{"tabs":[{"chatTitle":"Old chat","lastSendTime":1735812000000,"bubbles":[{"type":"user","text":"Hi"},{"type":"ai","text":"Hello","modelType":"gpt-4"}]}]}`,
+		},
+		"cursorDiskKV": {
+			"composerData:c1":  `{"composerId":"c1","name":"Composer","createdAt":1735812000000,"fullConversationHeadersOnly":[{"bubbleId":"b1","type":1},{"bubbleId":"b2","type":2}]}`,
+			"bubbleId:c1:b1":   `{"bubbleId":"b1","type":1,"text":"List the files","createdAt":"2025-01-02T10:00:00Z"}`,
+			"bubbleId:c1:b2":   `{"bubbleId":"b2","type":2,"text":"","createdAt":"2025-01-02T10:00:05Z","toolFormerData":{"toolCallId":"t1","name":"list_dir","rawArgs":"{}","result":"main.go"}}`,
+			"composerData:c2":  `{"composerId":"c2","conversation":[]}`,
+			"unrelated:thing":  `{}`,
+			"composerData:bad": ``,
+		},
+	}
+	for table, values := range rows {
+		for key, value := range values {
+			_, err := conn.Exec("INSERT INTO "+table+" (key, value) VALUES (?, ?)", key, value)
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, conn.Close())
+
+	convs, err := Read(SourceCursor, path)
+	require.NoError(t, err)
+	require.Len(t, convs, 3)
+
+	require.Equal(t, "Old chat", convs[0].Title)
+	require.Len(t, convs[0].Messages, 2)
+	require.Equal(t, "gpt-4", convs[0].Messages[1].Model)
+
+	require.Equal(t, "Composer", convs[1].Title)
+	require.Len(t, convs[1].Messages, 3)
+	require.Equal(t, []message.ContentPart{
+		message.ToolCall{ID: "t1", Name: "list_dir", Input: "{}", Finished: true},
+	}, convs[1].Messages[1].Parts)
+	require.Equal(t, message.Tool, convs[1].Messages[2].Role)
+	require.Equal(t, time.Date(2025, 1, 2, 10, 0, 5, 0, time.UTC), convs[1].Messages[2].CreatedAt)
+
+	require.Empty(t, convs[2].Messages)
+}
+
+func TestTitle(t *testing.T) {
+	t.Parallel()
+
+	prompt := strings.Repeat("é", maxTitleLength+1)
+	got := title(SourceClaudeCode, Conversation{Messages: []Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: prompt}}},
+	}})
+	require.True(t, utf8.ValidString(got))
+	require.Equal(t, strings.Repeat("é", maxTitleLength)+"...", got)
+}
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	at := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	ids, err := Import(t.Context(), conn, SourceClaudeCode, []Conversation{
+		{},
+		{Messages: []Message{
+			{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Fix the parser\nIt panics."}}, CreatedAt: at},
+			{Role: message.Assistant, Model: "claude-sonnet-4", Parts: []message.ContentPart{
+				message.ToolCall{ID: "toolu_1", Name: "Read", Input: "{}", Finished: true},
+			}, CreatedAt: at.Add(time.Second)},
+			{Role: message.Tool, Parts: []message.ContentPart{
+				message.ToolResult{ToolCallID: "toolu_1", Name: "Read", Content: "package parser"},
+			}, CreatedAt: at.Add(2 * time.Second)},
+		}},
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+
+	sess, err := session.NewService(db.New(conn)).Get(t.Context(), ids[0])
+	require.NoError(t, err)
+	require.Equal(t, "Fix the parser", sess.Title)
+	require.Equal(t, at.Unix(), sess.CreatedAt)
+
+	msgs, err := message.NewService(db.New(conn), conn).List(t.Context(), ids[0])
+	require.NoError(t, err)
+	require.Len(t, msgs, 3)
+	require.Equal(t, "claude-sonnet-4", msgs[1].Model)
+	require.Equal(t, message.FinishReasonToolUse, msgs[1].FinishReason())
+	require.Equal(t, []message.ToolCall{{ID: "toolu_1", Name: "Read", Input: "{}", Finished: true}}, msgs[1].ToolCalls())
+	require.Equal(t, "package parser", msgs[2].ToolResults()[0].Content)
+	require.Equal(t, at.Unix()+2, msgs[2].CreatedAt)
+}
@@ -0,0 +1,126 @@
+// Package inflight limits how many requests are sent to each provider at
+// once, across sessions, parallel tasks and sub-agents, so crush doesn't rate
+// limit itself, and keeps count of the requests in flight and waiting.
+package inflight
+
+import (
+	"context"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// Gauge counts the requests to every provider.
+type Gauge struct {
+	// InFlight is the number of requests being sent or streamed.
+	InFlight int
+	// Queued is the number of requests waiting for the limit of their
+	// provider.
+	Queued int
+}
+
+type Service interface {
+	pubsub.Suscriber[Gauge]
+	// Acquire waits until fewer than limit requests are in flight to the
+	// provider, 0 for no limit, and returns the function to call once the
+	// request is done. Changes to the gauge are published.
+	Acquire(ctx context.Context, providerID string, limit int) (release func(), err error)
+	// Gauge returns the current counts.
+	Gauge() Gauge
+}
+
+type service struct {
+	*pubsub.Broker[Gauge]
+
+	mu        sync.Mutex
+	gauge     Gauge
+	providers map[string]*provider
+}
+
+// provider holds the requests in flight to a provider and those waiting,
+// first come first served.
+type provider struct {
+	active  int
+	waiting []chan struct{}
+}
+
+func NewService() Service {
+	return &service{
+		Broker:    pubsub.NewBroker[Gauge](),
+		providers: make(map[string]*provider),
+	}
+}
+
+func (s *service) Acquire(ctx context.Context, providerID string, limit int) (func(), error) {
+	s.mu.Lock()
+	p, ok := s.providers[providerID]
+	if !ok {
+		p = &provider{}
+		s.providers[providerID] = p
+	}
+	if limit <= 0 || p.active < limit {
+		p.active++
+		s.gauge.InFlight++
+		s.publishLocked()
+		s.mu.Unlock()
+		return s.releaseFunc(p), nil
+	}
+	granted := make(chan struct{})
+	p.waiting = append(p.waiting, granted)
+	s.gauge.Queued++
+	s.publishLocked()
+	s.mu.Unlock()
+
+	select {
+	case <-granted:
+		return s.releaseFunc(p), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for i, ch := range p.waiting {
+			if ch == granted {
+				p.waiting = append(p.waiting[:i], p.waiting[i+1:]...)
+				s.gauge.Queued--
+				s.publishLocked()
+				s.mu.Unlock()
+				return nil, ctx.Err()
+			}
+		}
+		s.mu.Unlock()
+		// The slot was handed over while giving up, pass it on.
+		s.release(p)
+		return nil, ctx.Err()
+	}
+}
+
+func (s *service) releaseFunc(p *provider) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { s.release(p) })
+	}
+}
+
+// release hands the slot over to the first request waiting, or frees it.
+func (s *service) release(p *provider) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(p.waiting) > 0 {
+		next := p.waiting[0]
+		p.waiting = p.waiting[1:]
+		s.gauge.Queued--
+		close(next)
+	} else {
+		p.active--
+		s.gauge.InFlight--
+	}
+	s.publishLocked()
+}
+
+func (s *service) publishLocked() {
+	s.Publish(pubsub.UpdatedEvent, s.gauge)
+}
+
+func (s *service) Gauge() Gauge {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gauge
+}
@@ -0,0 +1,69 @@
+package inflight
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireLimitsProvider(t *testing.T) {
+	t.Parallel()
+
+	s := NewService()
+	release1, err := s.Acquire(t.Context(), "openai", 1)
+	require.NoError(t, err)
+	// Other providers and unlimited ones aren't held up.
+	release2, err := s.Acquire(t.Context(), "anthropic", 1)
+	require.NoError(t, err)
+	release3, err := s.Acquire(t.Context(), "ollama", 0)
+	require.NoError(t, err)
+	require.Equal(t, Gauge{InFlight: 3}, s.Gauge())
+
+	acquired := make(chan func())
+	go func() {
+		release, _ := s.Acquire(t.Context(), "openai", 1)
+		acquired <- release
+	}()
+	require.Eventually(t, func() bool { return s.Gauge().Queued == 1 }, time.Second, time.Millisecond)
+	select {
+	case <-acquired:
+		t.Fatal("acquired over the limit")
+	default:
+	}
+
+	release1()
+	release4 := <-acquired
+	require.NotNil(t, release4)
+	require.Equal(t, Gauge{InFlight: 3}, s.Gauge())
+
+	// Releasing twice has no effect.
+	release1()
+	require.Equal(t, Gauge{InFlight: 3}, s.Gauge())
+
+	release2()
+	release3()
+	release4()
+	require.Equal(t, Gauge{}, s.Gauge())
+}
+
+func TestAcquireGivesUp(t *testing.T) {
+	t.Parallel()
+
+	s := NewService()
+	release, err := s.Acquire(t.Context(), "openai", 1)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err = s.Acquire(ctx, "openai", 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, Gauge{InFlight: 1}, s.Gauge())
+
+	release()
+	require.Equal(t, Gauge{}, s.Gauge())
+	release, err = s.Acquire(t.Context(), "openai", 1)
+	require.NoError(t, err)
+	release()
+}
@@ -0,0 +1,122 @@
+// Package instance keeps track of the crush processes running on the same
+// project, so they can warn about each other.
+package instance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dirName = "instances"
+
+// Info describes a running crush process.
+type Info struct {
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	ReadOnly  bool      `json:"read_only"`
+}
+
+// Registration is the entry of the current process in the registry.
+type Registration struct {
+	// Others are the other processes running on the same data directory.
+	Others []Info
+
+	path string
+}
+
+// Register adds the current process to the registry in the data directory,
+// removing the entries of processes that are no longer running.
+func Register(dataDir string, readOnly bool) (*Registration, error) {
+	dir := filepath.Join(dataDir, dirName)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create instances directory: %w", err)
+	}
+
+	others, err := list(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	info := Info{
+		PID:       os.Getpid(),
+		StartedAt: time.Now(),
+		ReadOnly:  readOnly,
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, strconv.Itoa(info.PID)+".json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to register instance: %w", err)
+	}
+	return &Registration{Others: others, path: path}, nil
+}
+
+// Unregister removes the current process from the registry.
+func (r *Registration) Unregister() error {
+	if err := os.Remove(r.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Writers returns the other processes that may edit the project.
+func (r *Registration) Writers() []Info {
+	var writers []Info
+	for _, other := range r.Others {
+		if !other.ReadOnly {
+			writers = append(writers, other)
+		}
+	}
+	return writers
+}
+
+// Running returns the crush processes running on the data directory, other
+// than the current one.
+func Running(dataDir string) ([]Info, error) {
+	dir := filepath.Join(dataDir, dirName)
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return list(dir)
+}
+
+func list(dir string) ([]Info, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read instances directory: %w", err)
+	}
+	var infos []Info
+	for _, entry := range entries {
+		name := entry.Name()
+		pid, err := strconv.Atoi(strings.TrimSuffix(name, ".json"))
+		if err != nil || !strings.HasSuffix(name, ".json") || pid == os.Getpid() {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if !processAlive(pid) {
+			slog.Debug("Removing stale instance", "pid", pid)
+			_ = os.Remove(path)
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var info Info
+		if err := json.Unmarshal(data, &info); err != nil {
+			slog.Warn("Failed to parse instance", "path", path, "error", err)
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
@@ -0,0 +1,66 @@
+package instance
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeInfo(t *testing.T, dataDir string, info Info) string {
+	t.Helper()
+	dir := filepath.Join(dataDir, dirName)
+	require.NoError(t, os.MkdirAll(dir, 0o700))
+	data, err := json.Marshal(info)
+	require.NoError(t, err)
+	path := filepath.Join(dir, strconv.Itoa(info.PID)+".json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	// The parent process (go test) is alive, the other one is not.
+	writeInfo(t, dataDir, Info{PID: os.Getppid(), StartedAt: time.Now(), ReadOnly: true})
+	stale := writeInfo(t, dataDir, Info{PID: 1 << 22, StartedAt: time.Now()})
+
+	reg, err := Register(dataDir, false)
+	require.NoError(t, err)
+	require.Len(t, reg.Others, 1)
+	require.Equal(t, os.Getppid(), reg.Others[0].PID)
+	require.Empty(t, reg.Writers(), "read-only instances don't edit the project")
+	require.NoFileExists(t, stale)
+
+	own := filepath.Join(dataDir, dirName, strconv.Itoa(os.Getpid())+".json")
+	require.FileExists(t, own)
+
+	// Registering again doesn't list the current process.
+	again, err := Register(dataDir, false)
+	require.NoError(t, err)
+	require.Len(t, again.Others, 1)
+
+	require.NoError(t, reg.Unregister())
+	require.NoFileExists(t, own)
+	require.NoError(t, again.Unregister())
+}
+
+func TestRunning(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	running, err := Running(dataDir)
+	require.NoError(t, err)
+	require.Empty(t, running)
+
+	writeInfo(t, dataDir, Info{PID: os.Getppid(), StartedAt: time.Now()})
+	running, err = Running(dataDir)
+	require.NoError(t, err)
+	require.Len(t, running, 1)
+	require.Equal(t, os.Getppid(), running[0].PID)
+}
@@ -0,0 +1,29 @@
+//go:build windows
+
+package instance
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// stillActive is the exit code of processes that haven't exited yet.
+const stillActive = 259
+
+// processAlive reports whether a process with the given PID is running.
+func processAlive(pid int) bool {
+	// Opening a process succeeds as long as something holds a handle to it,
+	// even once it exited, so its exit code tells whether it still runs.
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		// Access is denied to processes of other users, which exist.
+		return errors.Is(err, windows.ERROR_ACCESS_DENIED)
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
@@ -39,12 +39,13 @@ func (h *HTTPRoundTripLogger) RoundTrip(req *http.Request) (*http.Response, erro
 		return nil, err
 	}
 
+	reqBody := bodyToString(save)
 	if slog.Default().Enabled(req.Context(), slog.LevelDebug) {
 		slog.Debug(
 			"HTTP Request",
 			"method", req.Method,
 			"url", req.URL,
-			"body", bodyToString(save),
+			"body", reqBody,
 		)
 	}
 
@@ -52,6 +53,7 @@ func (h *HTTPRoundTripLogger) RoundTrip(req *http.Request) (*http.Response, erro
 	resp, err := h.Transport.RoundTrip(req)
 	duration := time.Since(start)
 	if err != nil {
+		recordHTTPExchange(req, nil, reqBody, "", duration, err)
 		slog.Error(
 			"HTTP request failed",
 			"method", req.Method,
@@ -63,13 +65,15 @@ func (h *HTTPRoundTripLogger) RoundTrip(req *http.Request) (*http.Response, erro
 	}
 
 	save, resp.Body, err = drainBody(resp.Body)
+	respBody := bodyToString(save)
+	recordHTTPExchange(req, resp, reqBody, respBody, duration, err)
 	if slog.Default().Enabled(req.Context(), slog.LevelDebug) {
 		slog.Debug(
 			"HTTP Response",
 			"status_code", resp.StatusCode,
 			"status", resp.Status,
 			"headers", formatHeaders(resp.Header),
-			"body", bodyToString(save),
+			"body", respBody,
 			"content_length", resp.ContentLength,
 			"duration_ms", duration.Milliseconds(),
 			"error", err,
@@ -71,3 +71,51 @@ func TestFormatHeaders(t *testing.T) {
 		t.Error("User-Agent header should be preserved")
 	}
 }
+
+func TestHTTPExchanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req_123")
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "42")
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient()
+	req, err := http.NewRequestWithContext(
+		t.Context(),
+		http.MethodPost,
+		server.URL+"?key=secret",
+		strings.NewReader(`{"test": "data"}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret-token")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	exchanges := HTTPExchanges()
+	if len(exchanges) == 0 {
+		t.Fatal("Expected the request to be recorded")
+	}
+	exchange := exchanges[0]
+	if exchange.RequestID != "req_123" {
+		t.Errorf("Expected request ID req_123, got %q", exchange.RequestID)
+	}
+	if exchange.RateLimitRemaining != "42" {
+		t.Errorf("Expected rate limit remaining 42, got %q", exchange.RateLimitRemaining)
+	}
+	if strings.Contains(exchange.URL, "secret") {
+		t.Errorf("Expected URL to be redacted, got %q", exchange.URL)
+	}
+	if exchange.RequestHeaders["Authorization"][0] != "[REDACTED]" {
+		t.Error("Authorization header should be redacted")
+	}
+	if !strings.Contains(exchange.ResponseBody, `"ok": true`) {
+		t.Errorf("Expected response body to be recorded, got %q", exchange.ResponseBody)
+	}
+}
@@ -0,0 +1,139 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxHTTPExchanges    = 10
+	maxHTTPExchangeBody = 64 * 1024
+)
+
+// HTTPExchangesFile is the name of the file [WriteHTTPExchanges] writes in
+// the log directory.
+const HTTPExchangesFile = "provider-requests.json"
+
+// HTTPExchange is a redacted snapshot of a single provider request and its
+// response, as captured by [HTTPRoundTripLogger].
+type HTTPExchange struct {
+	Time               time.Time           `json:"time"`
+	Method             string              `json:"method"`
+	URL                string              `json:"url"`
+	StatusCode         int                 `json:"status_code,omitempty"`
+	Duration           time.Duration       `json:"duration"`
+	RequestID          string              `json:"request_id,omitempty"`
+	RateLimitRemaining string              `json:"rate_limit_remaining,omitempty"`
+	RequestHeaders     map[string][]string `json:"request_headers,omitempty"`
+	ResponseHeaders    map[string][]string `json:"response_headers,omitempty"`
+	RequestBody        string              `json:"request_body,omitempty"`
+	ResponseBody       string              `json:"response_body,omitempty"`
+	Error              string              `json:"error,omitempty"`
+}
+
+var (
+	httpExchangesMu sync.Mutex
+	httpExchanges   []HTTPExchange
+)
+
+// HTTPExchanges returns the most recent provider calls, newest first.
+func HTTPExchanges() []HTTPExchange {
+	httpExchangesMu.Lock()
+	defer httpExchangesMu.Unlock()
+	exchanges := slices.Clone(httpExchanges)
+	slices.Reverse(exchanges)
+	return exchanges
+}
+
+// WriteHTTPExchanges dumps the most recent provider calls to the log
+// directory and returns the path of the written file.
+func WriteHTTPExchanges() (string, error) {
+	dir := logDir()
+	if dir == "" {
+		return "", fmt.Errorf("logging is not initialized")
+	}
+	data, err := json.MarshalIndent(HTTPExchanges(), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal provider requests: %w", err)
+	}
+	path := filepath.Join(dir, HTTPExchangesFile)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write provider requests: %w", err)
+	}
+	return path, nil
+}
+
+func recordHTTPExchange(req *http.Request, resp *http.Response, reqBody, respBody string, duration time.Duration, err error) {
+	exchange := HTTPExchange{
+		Time:           time.Now(),
+		Method:         req.Method,
+		URL:            redactURL(req.URL),
+		Duration:       duration,
+		RequestHeaders: formatHeaders(req.Header),
+		RequestBody:    truncateBody(reqBody),
+	}
+	if resp != nil {
+		exchange.StatusCode = resp.StatusCode
+		exchange.ResponseHeaders = formatHeaders(resp.Header)
+		exchange.ResponseBody = truncateBody(respBody)
+		exchange.RequestID = firstHeader(resp.Header, "x-request-id", "request-id", "cf-ray")
+		exchange.RateLimitRemaining = firstHeader(resp.Header,
+			"x-ratelimit-remaining-requests",
+			"anthropic-ratelimit-requests-remaining",
+			"x-ratelimit-remaining",
+		)
+	}
+	if err != nil {
+		exchange.Error = err.Error()
+	}
+
+	httpExchangesMu.Lock()
+	defer httpExchangesMu.Unlock()
+	httpExchanges = append(httpExchanges, exchange)
+	if len(httpExchanges) > maxHTTPExchanges {
+		httpExchanges = httpExchanges[len(httpExchanges)-maxHTTPExchanges:]
+	}
+}
+
+func firstHeader(headers http.Header, keys ...string) string {
+	for _, key := range keys {
+		if v := headers.Get(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// redactURL hides credentials passed as query parameters, e.g. Gemini's
+// "key" parameter.
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	redacted := *u
+	query := redacted.Query()
+	for key := range query {
+		lowerKey := strings.ToLower(key)
+		if lowerKey == "key" || strings.Contains(lowerKey, "token") || strings.Contains(lowerKey, "secret") {
+			query.Set(key, "[REDACTED]")
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	redacted.User = nil
+	return redacted.String()
+}
+
+func truncateBody(body string) string {
+	if len(body) <= maxHTTPExchangeBody {
+		return body
+	}
+	return body[:maxHTTPExchangeBody] + "\n[truncated]"
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"runtime/debug"
 	"sync"
 	"sync/atomic"
@@ -16,6 +17,7 @@ import (
 var (
 	initOnce    sync.Once
 	initialized atomic.Bool
+	logFilePath atomic.Value
 )
 
 func Setup(logFile string, debug bool) {
@@ -39,6 +41,7 @@ func Setup(logFile string, debug bool) {
 		})
 
 		slog.SetDefault(slog.New(logger))
+		logFilePath.Store(logFile)
 		initialized.Store(true)
 	})
 }
@@ -47,6 +50,14 @@ func Initialized() bool {
 	return initialized.Load()
 }
 
+func logDir() string {
+	logFile, _ := logFilePath.Load().(string)
+	if logFile == "" {
+		return ""
+	}
+	return filepath.Dir(logFile)
+}
+
 func RecoverPanic(name string, cleanup func()) {
 	if r := recover(); r != nil {
 		event.Error(r, "panic", true, "name", name)
@@ -0,0 +1,168 @@
+// Package mcpserver exposes the built-in tools of crush as a Model Context
+// Protocol server, so other clients, like IDEs or desktop assistants, can
+// use crush as their tool backend.
+//
+// The tools run as they do for the agent, in a session of their own and
+// under the same permissions. Permissions the configuration doesn't grant
+// are asked of the user through the client, for the clients that support
+// elicitation, and denied otherwise.
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/version"
+	"github.com/google/uuid"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Options configure a server.
+type Options struct {
+	Tools       []fantasy.AgentTool
+	Permissions permission.Service
+	// SessionID is the session the tools run in.
+	SessionID string
+}
+
+// Server serves the tools.
+type Server struct {
+	opts   Options
+	server *mcp.Server
+	// calls holds the client session of the calls running, to ask it for
+	// the permissions they request.
+	calls *csync.Map[string, *mcp.ServerSession]
+}
+
+// New creates a server serving the tools.
+func New(opts Options) *Server {
+	s := &Server{
+		opts: opts,
+		server: mcp.NewServer(&mcp.Implementation{
+			Name:    "crush",
+			Title:   "Crush",
+			Version: version.Version,
+		}, nil),
+		calls: csync.NewMap[string, *mcp.ServerSession](),
+	}
+	for _, tool := range opts.Tools {
+		s.server.AddTool(mcpTool(tool.Info()), s.handler(tool))
+	}
+	return s
+}
+
+// Run serves the tools over the transport until the client disconnects or
+// ctx is done.
+func (s *Server) Run(ctx context.Context, transport mcp.Transport) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.askPermissions(ctx)
+	return s.server.Run(ctx, transport)
+}
+
+func (s *Server) handler(tool fantasy.AgentTool) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		call := fantasy.ToolCall{
+			ID:    uuid.New().String(),
+			Name:  req.Params.Name,
+			Input: string(req.Params.Arguments),
+		}
+		if call.Input == "" {
+			call.Input = "{}"
+		}
+		s.calls.Set(call.ID, req.Session)
+		defer s.calls.Del(call.ID)
+
+		ctx = context.WithValue(ctx, tools.SessionIDContextKey, s.opts.SessionID)
+		resp, err := tool.Run(ctx, call)
+		if err != nil {
+			resp = fantasy.NewTextErrorResponse(err.Error())
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: resp.Content}},
+			IsError: resp.IsError,
+		}, nil
+	}
+}
+
+// askPermissions asks the clients for the permissions the tools request.
+func (s *Server) askPermissions(ctx context.Context) {
+	for event := range s.opts.Permissions.Subscribe(ctx) {
+		req := event.Payload
+		session, ok := s.calls.Get(req.ToolCallID)
+		if !ok {
+			s.opts.Permissions.Deny(req)
+			continue
+		}
+		go s.askPermission(ctx, session, req)
+	}
+}
+
+func (s *Server) askPermission(ctx context.Context, session *mcp.ServerSession, req permission.PermissionRequest) {
+	params := session.InitializeParams()
+	if params == nil || params.Capabilities == nil || params.Capabilities.Elicitation == nil {
+		slog.Warn("MCP client can't be asked for permissions, denying", "tool", req.ToolName, "action", req.Action)
+		s.opts.Permissions.Deny(req)
+		return
+	}
+	result, err := session.Elicit(ctx, &mcp.ElicitParams{
+		Message: permissionMessage(req),
+		RequestedSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"remember": map[string]any{
+					"type":        "boolean",
+					"title":       "Allow for the session",
+					"description": "Allow the tool to do this again without asking",
+				},
+			},
+		},
+	})
+	switch {
+	case err != nil:
+		slog.Error("Failed to ask the MCP client for a permission", "tool", req.ToolName, "error", err)
+		s.opts.Permissions.Deny(req)
+	case result.Action != "accept":
+		s.opts.Permissions.Deny(req)
+	case result.Content["remember"] == true:
+		s.opts.Permissions.GrantPersistent(req)
+	default:
+		s.opts.Permissions.Grant(req)
+	}
+}
+
+func permissionMessage(req permission.PermissionRequest) string {
+	msg := fmt.Sprintf("Allow %s to %s in %s?\n\n%s", req.ToolName, req.Action, req.Path, req.Description)
+	if params, err := json.MarshalIndent(req.Params, "", "  "); err == nil && req.Params != nil {
+		msg += "\n\n" + string(params)
+	}
+	return msg
+}
+
+// mcpTool describes the tool the way MCP clients expect it, its parameters
+// as the properties of an object schema.
+func mcpTool(info fantasy.ToolInfo) *mcp.Tool {
+	required := info.Required
+	if required == nil {
+		required = []string{}
+	}
+	parameters := info.Parameters
+	if parameters == nil {
+		parameters = map[string]any{}
+	}
+	return &mcp.Tool{
+		Name:        info.Name,
+		Description: info.Description,
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": parameters,
+			"required":   required,
+		},
+	}
+}
@@ -0,0 +1,108 @@
+package mcpserver
+
+import (
+	"context"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent/tools"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+type touchParams struct {
+	Path string `json:"path" description:"The file to touch"`
+}
+
+// newTouchTool returns a tool asking for permission to touch a file.
+func newTouchTool(permissions permission.Service) fantasy.AgentTool {
+	return fantasy.NewAgentTool("touch", "Touches a file", func(ctx context.Context, params touchParams, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+		if !permissions.Request(permission.CreatePermissionRequest{
+			SessionID:  tools.GetSessionFromContext(ctx),
+			ToolCallID: call.ID,
+			ToolName:   "touch",
+			Action:     "write",
+			Path:       params.Path,
+		}) {
+			return fantasy.ToolResponse{}, permission.ErrorPermissionDenied
+		}
+		return fantasy.NewTextResponse("touched " + params.Path + " in " + tools.GetSessionFromContext(ctx)), nil
+	})
+}
+
+// connect serves the tools to a client in memory.
+func connect(t *testing.T, permissions permission.Service, elicit func(context.Context, *mcp.ElicitRequest) (*mcp.ElicitResult, error)) *mcp.ClientSession {
+	t.Helper()
+	server := New(Options{
+		Tools:       []fantasy.AgentTool{newTouchTool(permissions)},
+		Permissions: permissions,
+		SessionID:   "session",
+	})
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	go server.Run(t.Context(), serverTransport) //nolint:errcheck
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test"}, &mcp.ClientOptions{ElicitationHandler: elicit})
+	session, err := client.Connect(t.Context(), clientTransport, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func callTouch(t *testing.T, session *mcp.ClientSession) *mcp.CallToolResult {
+	t.Helper()
+	result, err := session.CallTool(t.Context(), &mcp.CallToolParams{
+		Name:      "touch",
+		Arguments: map[string]any{"path": "main.go"},
+	})
+	require.NoError(t, err)
+	return result
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	asked := 0
+	session := connect(t, permission.NewPermissionService(t.TempDir(), false, nil), func(_ context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+		asked++
+		require.Contains(t, req.Params.Message, "Allow touch to write")
+		return &mcp.ElicitResult{Action: "accept", Content: map[string]any{"remember": true}}, nil
+	})
+
+	tools, err := session.ListTools(t.Context(), nil)
+	require.NoError(t, err)
+	require.Len(t, tools.Tools, 1)
+	require.Equal(t, "touch", tools.Tools[0].Name)
+	require.Equal(t, "object", tools.Tools[0].InputSchema.(map[string]any)["type"])
+
+	result := callTouch(t, session)
+	require.False(t, result.IsError)
+	require.Equal(t, "touched main.go in session", result.Content[0].(*mcp.TextContent).Text)
+	require.Equal(t, 1, asked)
+
+	// Remembered for the session.
+	result = callTouch(t, session)
+	require.False(t, result.IsError)
+	require.Equal(t, 1, asked)
+}
+
+func TestServerPermissionDenied(t *testing.T) {
+	t.Parallel()
+
+	session := connect(t, permission.NewPermissionService(t.TempDir(), false, nil), func(context.Context, *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+		return &mcp.ElicitResult{Action: "decline"}, nil
+	})
+	result := callTouch(t, session)
+	require.True(t, result.IsError)
+	require.Equal(t, permission.ErrorPermissionDenied.Error(), result.Content[0].(*mcp.TextContent).Text)
+
+	// Clients that can't be asked are denied.
+	session = connect(t, permission.NewPermissionService(t.TempDir(), false, nil), nil)
+	result = callTouch(t, session)
+	require.True(t, result.IsError)
+
+	// Unless the configuration allows the tool.
+	session = connect(t, permission.NewPermissionService(t.TempDir(), false, []string{"touch"}), nil)
+	result = callTouch(t, session)
+	require.False(t, result.IsError)
+}
@@ -1,8 +1,20 @@
 package message
 
+import "strings"
+
 type Attachment struct {
 	FilePath string
 	FileName string
 	MimeType string
 	Content  []byte
 }
+
+// IsText reports whether the attachment is text added to the prompt, like
+// the content of a mentioned file, rather than a file sent to the model.
+func (a Attachment) IsText() bool {
+	return isTextMIMEType(a.MimeType)
+}
+
+func isTextMIMEType(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "text/")
+}
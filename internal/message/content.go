@@ -3,6 +3,7 @@ package message
 import (
 	"encoding/base64"
 	"errors"
+	"math"
 	"slices"
 	"strings"
 	"time"
@@ -32,11 +33,49 @@ const (
 	FinishReasonCanceled         FinishReason = "canceled"
 	FinishReasonError            FinishReason = "error"
 	FinishReasonPermissionDenied FinishReason = "permission_denied"
+	// The agent was stopped because it was stuck in a loop.
+	FinishReasonLoopDetected FinishReason = "loop_detected"
+	// The agent was stopped because it wrote one of the stop phrases.
+	FinishReasonStopPhrase FinishReason = "stop_phrase"
 
 	// Should never happen
 	FinishReasonUnknown FinishReason = "unknown"
 )
 
+// CancelReason is why a run finished with FinishReasonCanceled.
+type CancelReason string
+
+const (
+	// The user cancelled the run.
+	CancelReasonUser CancelReason = "user"
+	// The run took longer than it was allowed to.
+	CancelReasonTimeout CancelReason = "timeout"
+	// The run was stopped for going over a budget.
+	CancelReasonBudget CancelReason = "budget"
+	// A policy vetoed a step of the run.
+	CancelReasonPolicy CancelReason = "policy"
+	// Crush was shutting down.
+	CancelReasonShutdown CancelReason = "shutdown"
+)
+
+// Description describes the reason for people.
+func (r CancelReason) Description() string {
+	switch r {
+	case CancelReasonUser:
+		return "Cancelled by the user"
+	case CancelReasonTimeout:
+		return "Timed out"
+	case CancelReasonBudget:
+		return "Over budget"
+	case CancelReasonPolicy:
+		return "Stopped by a policy"
+	case CancelReasonShutdown:
+		return "Interrupted by shutdown"
+	default:
+		return "Cancelled"
+	}
+}
+
 type ContentPart interface {
 	isPart()
 }
@@ -115,14 +154,81 @@ type ToolResult struct {
 func (ToolResult) isPart() {}
 
 type Finish struct {
-	Reason  FinishReason `json:"reason"`
-	Time    int64        `json:"time"`
-	Message string       `json:"message,omitempty"`
-	Details string       `json:"details,omitempty"`
+	Reason FinishReason `json:"reason"`
+	// CancelReason is set when Reason is FinishReasonCanceled.
+	CancelReason CancelReason `json:"cancel_reason,omitempty"`
+	Time         int64        `json:"time"`
+	Message      string       `json:"message,omitempty"`
+	Details      string       `json:"details,omitempty"`
 }
 
 func (Finish) isPart() {}
 
+// TestStatus is whether the tests run during a turn passed.
+type TestStatus string
+
+const (
+	TestsPassed TestStatus = "passed"
+	TestsFailed TestStatus = "failed"
+)
+
+// RunSummary sums up what the agent did during a turn. It's stored on the
+// last assistant message of the turn and never sent to the model.
+type RunSummary struct {
+	Steps        int          `json:"steps"`
+	FilesTouched []string     `json:"files_touched,omitempty"`
+	Commands     []CommandRun `json:"commands,omitempty"`
+	// Tests is the status of the last test command, empty when no tests
+	// were run.
+	Tests     TestStatus `json:"tests,omitempty"`
+	FollowUps []string   `json:"follow_ups,omitempty"`
+}
+
+func (RunSummary) isPart() {}
+
+// Logprobs are the log probabilities of the tokens of a response, when the
+// provider was asked for them. They're stored to inspect how confident the
+// model was and never sent back to it.
+type Logprobs struct {
+	Tokens []TokenLogprob `json:"tokens"`
+}
+
+func (Logprobs) isPart() {}
+
+// TokenLogprob is the log probability of a token, with the most likely
+// tokens that could have been generated in its place.
+type TokenLogprob struct {
+	Token       string       `json:"token"`
+	Logprob     float64      `json:"logprob"`
+	TopLogprobs []TopLogprob `json:"top_logprobs,omitempty"`
+}
+
+// Probability returns the probability of the token, between 0 and 1.
+func (t TokenLogprob) Probability() float64 {
+	return math.Exp(t.Logprob)
+}
+
+// TopLogprob is an alternative to a generated token.
+type TopLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+}
+
+// Resumed marks a response whose stream was cut short and resumed with a
+// continuation request. Seams are the offsets in the text of the message
+// where each continuation starts.
+type Resumed struct {
+	Seams []int `json:"seams"`
+}
+
+func (Resumed) isPart() {}
+
+// CommandRun is a shell command run by the agent.
+type CommandRun struct {
+	Command string `json:"command"`
+	Failed  bool   `json:"failed,omitempty"`
+}
+
 type Message struct {
 	ID               string
 	Role             MessageRole
@@ -220,6 +326,27 @@ func (m *Message) FinishReason() FinishReason {
 	return ""
 }
 
+// RunSummary returns the summary of the turn the message ended, if any.
+func (m *Message) RunSummary() *RunSummary {
+	for _, part := range m.Parts {
+		if c, ok := part.(RunSummary); ok {
+			return &c
+		}
+	}
+	return nil
+}
+
+// Logprobs returns the log probabilities of the tokens of the message, if
+// they were requested.
+func (m *Message) Logprobs() []TokenLogprob {
+	for _, part := range m.Parts {
+		if c, ok := part.(Logprobs); ok {
+			return c.Tokens
+		}
+	}
+	return nil
+}
+
 func (m *Message) IsThinking() bool {
 	if m.ReasoningContent().Thinking != "" && m.Content().Text == "" && !m.IsFinished() {
 		return true
@@ -356,12 +483,8 @@ func (m *Message) AppendToolCallInput(toolCallID string, inputDelta string) {
 	for i, part := range m.Parts {
 		if c, ok := part.(ToolCall); ok {
 			if c.ID == toolCallID {
-				m.Parts[i] = ToolCall{
-					ID:       c.ID,
-					Name:     c.Name,
-					Input:    c.Input + inputDelta,
-					Finished: c.Finished,
-				}
+				c.Input += inputDelta
+				m.Parts[i] = c
 				return
 			}
 		}
@@ -416,6 +539,59 @@ func (m *Message) AddFinish(reason FinishReason, message, details string) {
 	m.Parts = append(m.Parts, Finish{Reason: reason, Time: time.Now().Unix(), Message: message, Details: details})
 }
 
+// AddCancelFinish finishes the message as cancelled for the reason.
+func (m *Message) AddCancelFinish(reason CancelReason, details string) {
+	m.AddFinish(FinishReasonCanceled, reason.Description(), details)
+	finish := m.Parts[len(m.Parts)-1].(Finish)
+	finish.CancelReason = reason
+	m.Parts[len(m.Parts)-1] = finish
+}
+
+// SetRunSummary sets the summary of the turn the message ended, replacing
+// any previous one.
+func (m *Message) SetRunSummary(summary RunSummary) {
+	m.Parts = slices.DeleteFunc(m.Parts, func(part ContentPart) bool {
+		_, ok := part.(RunSummary)
+		return ok
+	})
+	m.Parts = append(m.Parts, summary)
+}
+
+// AddLogprobs appends the log probabilities of tokens to those of the
+// message.
+func (m *Message) AddLogprobs(tokens []TokenLogprob) {
+	for i, part := range m.Parts {
+		if c, ok := part.(Logprobs); ok {
+			m.Parts[i] = Logprobs{Tokens: append(slices.Clip(c.Tokens), tokens...)}
+			return
+		}
+	}
+	m.Parts = append(m.Parts, Logprobs{Tokens: tokens})
+}
+
+// AddResumeSeams records where continuations of the response start in its
+// text.
+func (m *Message) AddResumeSeams(seams []int) {
+	for i, part := range m.Parts {
+		if c, ok := part.(Resumed); ok {
+			m.Parts[i] = Resumed{Seams: append(slices.Clip(c.Seams), seams...)}
+			return
+		}
+	}
+	m.Parts = append(m.Parts, Resumed{Seams: seams})
+}
+
+// ResumeSeams returns where continuations of the response start in its
+// text, nil when the response streamed in one go.
+func (m *Message) ResumeSeams() []int {
+	for _, part := range m.Parts {
+		if c, ok := part.(Resumed); ok {
+			return c.Seams
+		}
+	}
+	return nil
+}
+
 func (m *Message) AddImageURL(url, detail string) {
 	m.Parts = append(m.Parts, ImageURLContent{URL: url, Detail: detail})
 }
@@ -434,6 +610,10 @@ func (m *Message) ToAIMessage() []fantasy.Message {
 			parts = append(parts, fantasy.TextPart{Text: text})
 		}
 		for _, content := range m.BinaryContent() {
+			if isTextMIMEType(content.MIMEType) {
+				parts = append(parts, fantasy.TextPart{Text: string(content.Data)})
+				continue
+			}
 			parts = append(parts, fantasy.FilePart{
 				Filename:  content.Path,
 				Data:      content.Data,
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/charmbracelet/crush/internal/db"
@@ -20,26 +21,62 @@ type CreateMessageParams struct {
 	IsSummaryMessage bool
 }
 
+// Filter narrows down the messages of a session bulk operations apply to. The
+// zero value matches all of them.
+type Filter struct {
+	Role MessageRole
+	// CreatedBefore matches the messages created before the unix timestamp.
+	CreatedBefore int64
+}
+
+func (f Filter) params() (sql.NullString, sql.NullInt64) {
+	return sql.NullString{String: string(f.Role), Valid: f.Role != ""},
+		sql.NullInt64{Int64: f.CreatedBefore, Valid: f.CreatedBefore != 0}
+}
+
 type Service interface {
 	pubsub.Suscriber[Message]
 	Create(ctx context.Context, sessionID string, params CreateMessageParams) (Message, error)
 	Update(ctx context.Context, message Message) error
 	Get(ctx context.Context, id string) (Message, error)
 	List(ctx context.Context, sessionID string) ([]Message, error)
+	// ListLatest returns up to limit of the latest messages of every
+	// session, oldest first, keyed by session ID.
+	ListLatest(ctx context.Context, limit int) (map[string][]Message, error)
 	Delete(ctx context.Context, id string) error
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
+	// CreateMany creates the messages in a single transaction.
+	CreateMany(ctx context.Context, sessionID string, params []CreateMessageParams) ([]Message, error)
+	// DeleteBySession deletes the messages of a session matching the filter
+	// and returns how many were deleted.
+	DeleteBySession(ctx context.Context, sessionID string, filter Filter) (int64, error)
+	CountBySession(ctx context.Context, sessionID string, filter Filter) (int64, error)
+	// EnforceRetention applies the retention policy of the service to the
+	// stored messages and returns how many were changed.
+	EnforceRetention(ctx context.Context) (int64, error)
+	// Search returns the messages matching the query from the full-text
+	// index, most relevant first.
+	Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error)
 }
 
 type service struct {
 	*pubsub.Broker[Message]
-	q db.Querier
+	db *sql.DB
+	q  *db.Queries
+
+	retention RetentionPolicy
 }
 
-func NewService(q db.Querier) Service {
-	return &service{
+func NewService(q *db.Queries, db *sql.DB, opts ...ServiceOption) Service {
+	s := &service{
 		Broker: pubsub.NewBroker[Message](),
 		q:      q,
+		db:     db,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *service) Delete(ctx context.Context, id string) error {
@@ -56,12 +93,48 @@ func (s *service) Delete(ctx context.Context, id string) error {
 }
 
 func (s *service) Create(ctx context.Context, sessionID string, params CreateMessageParams) (Message, error) {
+	message, err := s.create(ctx, s.q, sessionID, params)
+	if err != nil {
+		return Message{}, err
+	}
+	s.Publish(pubsub.CreatedEvent, message)
+	return message, nil
+}
+
+func (s *service) CreateMany(ctx context.Context, sessionID string, params []CreateMessageParams) ([]Message, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	qtx := s.q.WithTx(tx)
+	messages := make([]Message, len(params))
+	for i, p := range params {
+		messages[i], err = s.create(ctx, qtx, sessionID, p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	for _, message := range messages {
+		s.Publish(pubsub.CreatedEvent, message)
+	}
+	return messages, nil
+}
+
+func (s *service) create(ctx context.Context, q db.Querier, sessionID string, params CreateMessageParams) (Message, error) {
 	if params.Role != Assistant {
 		params.Parts = append(params.Parts, Finish{
 			Reason: "stop",
 		})
 	}
-	partsJSON, err := marshallParts(params.Parts)
+	// What the policy leaves out of storage is still in the message returned.
+	now := time.Now()
+	retained, dropped := s.retention.retain(params.Parts, now.Unix(), now)
+	partsJSON, err := marshallParts(retained)
 	if err != nil {
 		return Message{}, err
 	}
@@ -69,7 +142,7 @@ func (s *service) Create(ctx context.Context, sessionID string, params CreateMes
 	if params.IsSummaryMessage {
 		isSummary = 1
 	}
-	dbMessage, err := s.q.CreateMessage(ctx, db.CreateMessageParams{
+	dbMessage, err := q.CreateMessage(ctx, db.CreateMessageParams{
 		ID:               uuid.New().String(),
 		SessionID:        sessionID,
 		Role:             string(params.Role),
@@ -85,28 +158,51 @@ func (s *service) Create(ctx context.Context, sessionID string, params CreateMes
 	if err != nil {
 		return Message{}, err
 	}
-	s.Publish(pubsub.CreatedEvent, message)
+	if dropped {
+		message.Parts = params.Parts
+	}
 	return message, nil
 }
 
 func (s *service) DeleteSessionMessages(ctx context.Context, sessionID string) error {
-	messages, err := s.List(ctx, sessionID)
+	_, err := s.DeleteBySession(ctx, sessionID, Filter{})
+	return err
+}
+
+func (s *service) DeleteBySession(ctx context.Context, sessionID string, filter Filter) (int64, error) {
+	role, createdBefore := filter.params()
+	dbMessages, err := s.q.DeleteMessagesBySession(ctx, db.DeleteMessagesBySessionParams{
+		SessionID:     sessionID,
+		Role:          role,
+		CreatedBefore: createdBefore,
+	})
 	if err != nil {
-		return err
+		return 0, err
 	}
-	for _, message := range messages {
-		if message.SessionID == sessionID {
-			err = s.Delete(ctx, message.ID)
-			if err != nil {
-				return err
-			}
+	for _, dbMessage := range dbMessages {
+		message, err := s.fromDBItem(dbMessage)
+		if err != nil {
+			// Already deleted, only the event is lost.
+			slog.Warn("Failed to decode deleted message", "id", dbMessage.ID, "error", err)
+			continue
 		}
+		s.Publish(pubsub.DeletedEvent, message)
 	}
-	return nil
+	return int64(len(dbMessages)), nil
+}
+
+func (s *service) CountBySession(ctx context.Context, sessionID string, filter Filter) (int64, error) {
+	role, createdBefore := filter.params()
+	return s.q.CountMessagesBySession(ctx, db.CountMessagesBySessionParams{
+		SessionID:     sessionID,
+		Role:          role,
+		CreatedBefore: createdBefore,
+	})
 }
 
 func (s *service) Update(ctx context.Context, message Message) error {
-	parts, err := marshallParts(message.Parts)
+	retained, _ := s.retention.retain(message.Parts, message.CreatedAt, time.Now())
+	parts, err := marshallParts(retained)
 	if err != nil {
 		return err
 	}
@@ -151,6 +247,22 @@ func (s *service) List(ctx context.Context, sessionID string) ([]Message, error)
 	return messages, nil
 }
 
+func (s *service) ListLatest(ctx context.Context, limit int) (map[string][]Message, error) {
+	dbMessages, err := s.q.ListLatestMessages(ctx, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+	messages := make(map[string][]Message)
+	for _, dbMessage := range dbMessages {
+		msg, err := s.fromDBItem(dbMessage)
+		if err != nil {
+			return nil, err
+		}
+		messages[msg.SessionID] = append(messages[msg.SessionID], msg)
+	}
+	return messages, nil
+}
+
 func (s *service) fromDBItem(item db.Message) (Message, error) {
 	parts, err := unmarshallParts([]byte(item.Parts))
 	if err != nil {
@@ -179,11 +291,29 @@ const (
 	toolCallType   partType = "tool_call"
 	toolResultType partType = "tool_result"
 	finishType     partType = "finish"
+	runSummaryType partType = "run_summary"
+	logprobsType   partType = "logprobs"
+	resumedType    partType = "resumed"
 )
 
+// partsVersion is the version of the format parts are stored in. When the
+// format of a part changes, bump it and add an upgrade to partUpgrades so
+// older sessions keep loading.
+const partsVersion = 1
+
+// partUpgrades holds, for each version, the function that upgrades a stored
+// part from that version to the next one.
+var partUpgrades = []func(typ partType, data json.RawMessage) (json.RawMessage, error){
+	// 0 -> 1: parts were not versioned, the data did not change.
+	func(_ partType, data json.RawMessage) (json.RawMessage, error) {
+		return data, nil
+	},
+}
+
 type partWrapper struct {
-	Type partType    `json:"type"`
-	Data ContentPart `json:"data"`
+	Type    partType    `json:"type"`
+	Version int         `json:"version"`
+	Data    ContentPart `json:"data"`
 }
 
 func marshallParts(parts []ContentPart) ([]byte, error) {
@@ -207,18 +337,32 @@ func marshallParts(parts []ContentPart) ([]byte, error) {
 			typ = toolResultType
 		case Finish:
 			typ = finishType
+		case RunSummary:
+			typ = runSummaryType
+		case Logprobs:
+			typ = logprobsType
+		case Resumed:
+			typ = resumedType
 		default:
 			return nil, fmt.Errorf("unknown part type: %T", part)
 		}
 
 		wrappedParts[i] = partWrapper{
-			Type: typ,
-			Data: part,
+			Type:    typ,
+			Version: partsVersion,
+			Data:    part,
 		}
 	}
 	return json.Marshal(wrappedParts)
 }
 
+// MarshalParts encodes the parts the way they are stored, for the messages
+// written to the database without the service, like imported ones.
+func MarshalParts(parts []ContentPart) (string, error) {
+	data, err := marshallParts(parts)
+	return string(data), err
+}
+
 func unmarshallParts(data []byte) ([]ContentPart, error) {
 	temp := []json.RawMessage{}
 
@@ -230,13 +374,24 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 
 	for _, rawPart := range temp {
 		var wrapper struct {
-			Type partType        `json:"type"`
-			Data json.RawMessage `json:"data"`
+			Type    partType        `json:"type"`
+			Version int             `json:"version"`
+			Data    json.RawMessage `json:"data"`
 		}
 
 		if err := json.Unmarshal(rawPart, &wrapper); err != nil {
 			return nil, err
 		}
+		if wrapper.Version > partsVersion {
+			return nil, fmt.Errorf("unsupported %s part version %d, the message was stored by a newer version of crush", wrapper.Type, wrapper.Version)
+		}
+		for version := wrapper.Version; version < partsVersion; version++ {
+			data, err := partUpgrades[version](wrapper.Type, wrapper.Data)
+			if err != nil {
+				return nil, fmt.Errorf("upgrading %s part from version %d: %w", wrapper.Type, version, err)
+			}
+			wrapper.Data = data
+		}
 
 		switch wrapper.Type {
 		case reasoningType:
@@ -256,6 +411,7 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
 				return nil, err
 			}
+			parts = append(parts, part)
 		case binaryType:
 			part := BinaryContent{}
 			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
@@ -280,6 +436,24 @@ func unmarshallParts(data []byte) ([]ContentPart, error) {
 				return nil, err
 			}
 			parts = append(parts, part)
+		case runSummaryType:
+			part := RunSummary{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case logprobsType:
+			part := Logprobs{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case resumedType:
+			part := Resumed{}
+			if err := json.Unmarshal(wrapper.Data, &part); err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
 		default:
 			return nil, fmt.Errorf("unknown part type: %s", wrapper.Type)
 		}
@@ -0,0 +1,203 @@
+package message
+
+import (
+	"testing"
+
+	"charm.land/fantasy/providers/openai"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartsRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	parts := []ContentPart{
+		ReasoningContent{
+			Thinking:      "thinking",
+			Signature:     "signature",
+			ResponsesData: &openai.ResponsesReasoningMetadata{ItemID: "item"},
+			StartedAt:     1,
+			FinishedAt:    2,
+		},
+		TextContent{Text: "hello"},
+		ImageURLContent{URL: "https://example.com/image.png", Detail: "high"},
+		BinaryContent{Path: "image.png", MIMEType: "image/png", Data: []byte{0x89, 0x50}},
+		ToolCall{ID: "1", Name: "bash", Input: `{"command":"ls"}`, Finished: true},
+		ToolResult{ToolCallID: "1", Name: "bash", Content: "a.txt", Metadata: "{}"},
+		Finish{Reason: FinishReasonEndTurn, Time: 3, Message: "message", Details: "details"},
+		RunSummary{
+			Steps:        2,
+			FilesTouched: []string{"main.go"},
+			Commands:     []CommandRun{{Command: "go test ./...", Failed: true}},
+			Tests:        TestsFailed,
+			FollowUps:    []string{"Fix the failing tests"},
+		},
+		Logprobs{Tokens: []TokenLogprob{
+			{Token: "hello", Logprob: -0.1, TopLogprobs: []TopLogprob{{Token: "hello", Logprob: -0.1}, {Token: "hi", Logprob: -2.5}}},
+		}},
+		Resumed{Seams: []int{12, 40}},
+	}
+
+	data, err := marshallParts(parts)
+	require.NoError(t, err)
+
+	got, err := unmarshallParts(data)
+	require.NoError(t, err)
+	require.Equal(t, parts, got)
+}
+
+func TestUnmarshallLegacyParts(t *testing.T) {
+	t.Parallel()
+
+	// Parts as stored before they were versioned.
+	data := `[
+		{"type":"reasoning","data":{"thinking":"thinking","signature":"","thought_signature":"","responses_data":null}},
+		{"type":"text","data":{"text":"hello"}},
+		{"type":"binary","data":{"Path":"image.png","MIMEType":"image/png","Data":"iVA="}},
+		{"type":"tool_call","data":{"id":"1","name":"bash","input":"{}","provider_executed":false,"finished":true}},
+		{"type":"tool_result","data":{"tool_call_id":"1","name":"bash","content":"a.txt","data":"","mime_type":"","metadata":"","is_error":false}},
+		{"type":"finish","data":{"reason":"end_turn","time":3}}
+	]`
+
+	parts, err := unmarshallParts([]byte(data))
+	require.NoError(t, err)
+	require.Equal(t, []ContentPart{
+		ReasoningContent{Thinking: "thinking"},
+		TextContent{Text: "hello"},
+		BinaryContent{Path: "image.png", MIMEType: "image/png", Data: []byte{0x89, 0x50}},
+		ToolCall{ID: "1", Name: "bash", Input: "{}", Finished: true},
+		ToolResult{ToolCallID: "1", Name: "bash", Content: "a.txt"},
+		Finish{Reason: FinishReasonEndTurn, Time: 3},
+	}, parts)
+}
+
+func TestUnmarshallNewerParts(t *testing.T) {
+	t.Parallel()
+
+	_, err := unmarshallParts([]byte(`[{"type":"text","version":999,"data":{"text":"hello"}}]`))
+	require.ErrorContains(t, err, "newer version")
+}
+
+func TestPartUpgrades(t *testing.T) {
+	t.Parallel()
+
+	require.Len(t, partUpgrades, partsVersion, "every version needs an upgrade to the next one")
+}
+
+func TestBulkOperations(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sessions := session.NewService(q)
+	messages := NewService(q, conn)
+
+	sess, err := sessions.Create(t.Context(), "bulk")
+	require.NoError(t, err)
+	other, err := sessions.Create(t.Context(), "other")
+	require.NoError(t, err)
+
+	params := []CreateMessageParams{
+		{Role: User, Parts: []ContentPart{TextContent{Text: "hi"}}},
+		{Role: Assistant, Parts: []ContentPart{TextContent{Text: "hello"}}},
+		{Role: User, Parts: []ContentPart{TextContent{Text: "bye"}}},
+	}
+	created, err := messages.CreateMany(t.Context(), sess.ID, params)
+	require.NoError(t, err)
+	require.Len(t, created, 3)
+	_, err = messages.CreateMany(t.Context(), other.ID, params[:1])
+	require.NoError(t, err)
+
+	count, err := messages.CountBySession(t.Context(), sess.ID, Filter{})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+
+	count, err = messages.CountBySession(t.Context(), sess.ID, Filter{Role: User})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	count, err = messages.CountBySession(t.Context(), sess.ID, Filter{CreatedBefore: created[0].CreatedAt})
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	deleted, err := messages.DeleteBySession(t.Context(), sess.ID, Filter{Role: User})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), deleted)
+
+	remaining, err := messages.List(t.Context(), sess.ID)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	require.Equal(t, Assistant, remaining[0].Role)
+
+	require.NoError(t, messages.DeleteSessionMessages(t.Context(), sess.ID))
+	count, err = messages.CountBySession(t.Context(), sess.ID, Filter{})
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	count, err = messages.CountBySession(t.Context(), other.ID, Filter{})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+func TestCreateManyIsAtomic(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sessions := session.NewService(q)
+	messages := NewService(q, conn)
+
+	sess, err := sessions.Create(t.Context(), "atomic")
+	require.NoError(t, err)
+
+	_, err = messages.CreateMany(t.Context(), sess.ID, []CreateMessageParams{
+		{Role: User, Parts: []ContentPart{TextContent{Text: "hi"}}},
+		{Role: User, Parts: []ContentPart{nil}},
+	})
+	require.Error(t, err)
+
+	count, err := messages.CountBySession(t.Context(), sess.ID, Filter{})
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestListLatest(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sessions := session.NewService(q)
+	messages := NewService(q, conn)
+
+	sess, err := sessions.Create(t.Context(), "latest")
+	require.NoError(t, err)
+	other, err := sessions.Create(t.Context(), "other")
+	require.NoError(t, err)
+
+	_, err = messages.CreateMany(t.Context(), sess.ID, []CreateMessageParams{
+		{Role: User, Parts: []ContentPart{TextContent{Text: "one"}}},
+		{Role: Assistant, Parts: []ContentPart{TextContent{Text: "two"}}},
+		{Role: User, Parts: []ContentPart{TextContent{Text: "three"}}},
+	})
+	require.NoError(t, err)
+	_, err = messages.Create(t.Context(), other.ID, CreateMessageParams{Role: User, Parts: []ContentPart{TextContent{Text: "only"}}})
+	require.NoError(t, err)
+
+	latest, err := messages.ListLatest(t.Context(), 2)
+	require.NoError(t, err)
+	require.Len(t, latest, 2)
+
+	var texts []string
+	for _, msg := range latest[sess.ID] {
+		texts = append(texts, msg.Content().Text)
+	}
+	require.Equal(t, []string{"two", "three"}, texts)
+	require.Len(t, latest[other.ID], 1)
+}
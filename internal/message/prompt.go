@@ -0,0 +1,285 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+var (
+	// ErrMisplacedSystemMessage is returned when a system message follows
+	// the conversation instead of preceding it.
+	ErrMisplacedSystemMessage = errors.New("system messages must come before the conversation")
+	// ErrOrphanedToolResult is returned when a tool result doesn't answer a
+	// tool call of the preceding assistant message.
+	ErrOrphanedToolResult = errors.New("tool result without a matching tool call")
+	// ErrDuplicateToolResult is returned when a tool call is answered twice.
+	ErrDuplicateToolResult = errors.New("tool call answered more than once")
+)
+
+// PromptBuilder builds the messages sent to the model, validating that they
+// form a well ordered conversation.
+//
+//	msgs, err := message.NewPromptBuilder().
+//		System("You are a helpful assistant.").
+//		History(sessionMessages...).
+//		User("Summarize the conversation above.").
+//		Build()
+type PromptBuilder struct {
+	messages []fantasy.Message
+}
+
+// NewPromptBuilder returns an empty PromptBuilder.
+func NewPromptBuilder() *PromptBuilder {
+	return &PromptBuilder{}
+}
+
+// System adds a system message.
+func (b *PromptBuilder) System(text string) *PromptBuilder {
+	b.messages = append(b.messages, fantasy.NewSystemMessage(text))
+	return b
+}
+
+// User adds a user message with optional attachments.
+func (b *PromptBuilder) User(text string, files ...fantasy.FilePart) *PromptBuilder {
+	var parts []fantasy.MessagePart
+	if text != "" {
+		parts = append(parts, fantasy.TextPart{Text: text})
+	}
+	for _, file := range files {
+		parts = append(parts, file)
+	}
+	b.messages = append(b.messages, fantasy.Message{
+		Role:    fantasy.MessageRoleUser,
+		Content: parts,
+	})
+	return b
+}
+
+// Assistant adds an assistant message with the tool calls it made.
+func (b *PromptBuilder) Assistant(text string, toolCalls ...fantasy.ToolCallPart) *PromptBuilder {
+	var parts []fantasy.MessagePart
+	if text != "" {
+		parts = append(parts, fantasy.TextPart{Text: text})
+	}
+	for _, call := range toolCalls {
+		parts = append(parts, call)
+	}
+	b.messages = append(b.messages, fantasy.Message{
+		Role:    fantasy.MessageRoleAssistant,
+		Content: parts,
+	})
+	return b
+}
+
+// ToolResult adds the result of a tool call. Consecutive results are grouped
+// in the same tool message.
+func (b *PromptBuilder) ToolResult(toolCallID string, output fantasy.ToolResultOutputContent) *PromptBuilder {
+	part := fantasy.ToolResultPart{ToolCallID: toolCallID, Output: output}
+	if n := len(b.messages); n > 0 && b.messages[n-1].Role == fantasy.MessageRoleTool {
+		b.messages[n-1].Content = append(b.messages[n-1].Content, part)
+		return b
+	}
+	b.messages = append(b.messages, fantasy.Message{
+		Role:    fantasy.MessageRoleTool,
+		Content: []fantasy.MessagePart{part},
+	})
+	return b
+}
+
+// History adds stored session messages, skipping the ones that have nothing
+// to send.
+func (b *PromptBuilder) History(msgs ...Message) *PromptBuilder {
+	for _, m := range msgs {
+		if len(m.Parts) == 0 {
+			continue
+		}
+		// Assistant message without content or tool calls (cancelled before it returned anything)
+		if m.Role == Assistant && len(m.ToolCalls()) == 0 && m.Content().Text == "" && m.ReasoningContent().String() == "" {
+			continue
+		}
+		b.messages = append(b.messages, m.ToAIMessage()...)
+	}
+	return b
+}
+
+// Messages returns the messages added so far without validating them.
+func (b *PromptBuilder) Messages() []fantasy.Message {
+	return b.messages
+}
+
+// Build validates the messages and returns them.
+func (b *PromptBuilder) Build() ([]fantasy.Message, error) {
+	if err := ValidatePrompt(b.messages); err != nil {
+		return nil, err
+	}
+	return b.messages, nil
+}
+
+// ValidatePrompt checks that system messages precede the conversation and
+// that every tool result answers a tool call of the preceding assistant
+// message.
+func ValidatePrompt(msgs []fantasy.Message) error {
+	conversationStarted := false
+	// Tool calls of the last assistant message, and whether they were answered.
+	pending := map[string]bool{}
+	for i, msg := range msgs {
+		switch msg.Role {
+		case fantasy.MessageRoleSystem:
+			if conversationStarted {
+				return fmt.Errorf("message %d: %w", i, ErrMisplacedSystemMessage)
+			}
+			continue
+		case fantasy.MessageRoleTool:
+			for _, part := range msg.Content {
+				result, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part)
+				if !ok {
+					continue
+				}
+				answered, ok := pending[result.ToolCallID]
+				if !ok {
+					return fmt.Errorf("message %d: %w: %q", i, ErrOrphanedToolResult, result.ToolCallID)
+				}
+				if answered {
+					return fmt.Errorf("message %d: %w: %q", i, ErrDuplicateToolResult, result.ToolCallID)
+				}
+				pending[result.ToolCallID] = true
+			}
+		case fantasy.MessageRoleAssistant:
+			clear(pending)
+			for _, part := range msg.Content {
+				if call, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part); ok {
+					pending[call.ToolCallID] = false
+				}
+			}
+		default:
+			clear(pending)
+		}
+		conversationStarted = true
+	}
+	return nil
+}
+
+// RepairToolResults makes every tool call of the messages immediately
+// followed by its result, as some providers require. Results found later
+// in the conversation are moved after their call, calls without a result
+// get an error result, and results that answer no call or a call already
+// answered are dropped. It returns the repaired messages, the same ones
+// when nothing needed repairing, and a description of each repair.
+func RepairToolResults(msgs []fantasy.Message) ([]fantasy.Message, []string) {
+	calls := map[string]bool{}
+	for _, msg := range msgs {
+		if msg.Role != fantasy.MessageRoleAssistant {
+			continue
+		}
+		for _, part := range msg.Content {
+			if call, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part); ok {
+				calls[call.ToolCallID] = true
+			}
+		}
+	}
+
+	var warnings []string
+	// The first result of each call and the index of its message.
+	results := map[string]fantasy.ToolResultPart{}
+	resultAt := map[string]int{}
+	for i, msg := range msgs {
+		if msg.Role != fantasy.MessageRoleTool {
+			continue
+		}
+		for _, part := range msg.Content {
+			result, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part)
+			if !ok {
+				continue
+			}
+			if !calls[result.ToolCallID] {
+				warnings = append(warnings, fmt.Sprintf("message %d: dropped the result of unknown tool call %q", i, result.ToolCallID))
+				continue
+			}
+			if _, seen := results[result.ToolCallID]; seen {
+				warnings = append(warnings, fmt.Sprintf("message %d: dropped a duplicate result of tool call %q", i, result.ToolCallID))
+				continue
+			}
+			results[result.ToolCallID] = result
+			resultAt[result.ToolCallID] = i
+		}
+	}
+
+	repaired := make([]fantasy.Message, 0, len(msgs))
+	answered := map[string]bool{}
+	for i, msg := range msgs {
+		if msg.Role == fantasy.MessageRoleTool {
+			// Results are added after their call.
+			continue
+		}
+		repaired = append(repaired, msg)
+		if msg.Role != fantasy.MessageRoleAssistant {
+			continue
+		}
+		// Tool messages right after the call are where results belong.
+		end := i + 1
+		for end < len(msgs) && msgs[end].Role == fantasy.MessageRoleTool {
+			end++
+		}
+		var parts []fantasy.MessagePart
+		for _, part := range msg.Content {
+			call, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part)
+			if !ok {
+				continue
+			}
+			if answered[call.ToolCallID] {
+				continue
+			}
+			answered[call.ToolCallID] = true
+			result, ok := results[call.ToolCallID]
+			switch {
+			case !ok:
+				warnings = append(warnings, fmt.Sprintf("message %d: added a result to tool call %q which had none", i, call.ToolCallID))
+				result = fantasy.ToolResultPart{
+					ToolCallID: call.ToolCallID,
+					Output:     fantasy.ToolResultOutputContentError{Error: errors.New("tool call has no result")},
+				}
+			case resultAt[call.ToolCallID] < i || resultAt[call.ToolCallID] >= end:
+				warnings = append(warnings, fmt.Sprintf("message %d: moved the result of tool call %q after it", resultAt[call.ToolCallID], call.ToolCallID))
+			}
+			parts = append(parts, result)
+		}
+		if len(parts) > 0 {
+			repaired = append(repaired, fantasy.Message{Role: fantasy.MessageRoleTool, Content: parts})
+		}
+	}
+	if len(warnings) == 0 {
+		return msgs, nil
+	}
+	return repaired, warnings
+}
+
+// FileParts converts attachments to the file parts sent to the model.
+// Text attachments are part of the prompt instead, see PromptText.
+func FileParts(attachments []Attachment) []fantasy.FilePart {
+	var files []fantasy.FilePart
+	for _, attachment := range attachments {
+		if attachment.IsText() {
+			continue
+		}
+		files = append(files, fantasy.FilePart{
+			Filename:  attachment.FileName,
+			Data:      attachment.Content,
+			MediaType: attachment.MimeType,
+		})
+	}
+	return files
+}
+
+// PromptText returns the prompt followed by the text attachments.
+func PromptText(prompt string, attachments []Attachment) string {
+	texts := []string{prompt}
+	for _, attachment := range attachments {
+		if attachment.IsText() {
+			texts = append(texts, string(attachment.Content))
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
@@ -0,0 +1,192 @@
+package message
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptBuilder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("builds a conversation", func(t *testing.T) {
+		t.Parallel()
+
+		msgs, err := NewPromptBuilder().
+			System("system").
+			User("list the files", fantasy.FilePart{Filename: "a.txt", Data: []byte("a"), MediaType: "text/plain"}).
+			Assistant("", fantasy.ToolCallPart{ToolCallID: "1", ToolName: "ls"}, fantasy.ToolCallPart{ToolCallID: "2", ToolName: "glob"}).
+			ToolResult("1", fantasy.ToolResultOutputContentText{Text: "a.txt"}).
+			ToolResult("2", fantasy.ToolResultOutputContentText{Text: "a.txt"}).
+			Assistant("There is one file.").
+			Build()
+		require.NoError(t, err)
+		require.Len(t, msgs, 5)
+		require.Equal(t, fantasy.MessageRoleSystem, msgs[0].Role)
+		require.Len(t, msgs[1].Content, 2)
+		require.Equal(t, fantasy.MessageRoleTool, msgs[3].Role)
+		require.Len(t, msgs[3].Content, 2, "consecutive tool results are grouped")
+	})
+
+	t.Run("system message after the conversation", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewPromptBuilder().System("a").User("hi").System("b").Build()
+		require.ErrorIs(t, err, ErrMisplacedSystemMessage)
+	})
+
+	t.Run("orphaned tool result", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewPromptBuilder().
+			User("hi").
+			ToolResult("1", fantasy.ToolResultOutputContentText{Text: "a"}).
+			Build()
+		require.ErrorIs(t, err, ErrOrphanedToolResult)
+
+		_, err = NewPromptBuilder().
+			Assistant("", fantasy.ToolCallPart{ToolCallID: "1", ToolName: "ls"}).
+			User("never mind").
+			ToolResult("1", fantasy.ToolResultOutputContentText{Text: "a"}).
+			Build()
+		require.ErrorIs(t, err, ErrOrphanedToolResult)
+	})
+
+	t.Run("duplicate tool result", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewPromptBuilder().
+			Assistant("", fantasy.ToolCallPart{ToolCallID: "1", ToolName: "ls"}).
+			ToolResult("1", fantasy.ToolResultOutputContentText{Text: "a"}).
+			ToolResult("1", fantasy.ToolResultOutputContentText{Text: "a"}).
+			Build()
+		require.ErrorIs(t, err, ErrDuplicateToolResult)
+	})
+
+	t.Run("history", func(t *testing.T) {
+		t.Parallel()
+
+		msgs, err := NewPromptBuilder().History(
+			Message{Role: User, Parts: []ContentPart{TextContent{Text: "hi"}}},
+			// Cancelled before anything was returned.
+			Message{Role: Assistant, Parts: []ContentPart{Finish{Reason: FinishReasonCanceled}}},
+			Message{Role: Assistant},
+			Message{Role: Assistant, Parts: []ContentPart{ToolCall{ID: "1", Name: "ls", Input: "{}"}}},
+			Message{Role: Tool, Parts: []ContentPart{ToolResult{ToolCallID: "1", Content: "a.txt"}}},
+		).Build()
+		require.NoError(t, err)
+		require.Len(t, msgs, 3)
+		require.Equal(t, fantasy.MessageRoleUser, msgs[0].Role)
+		require.Equal(t, fantasy.MessageRoleAssistant, msgs[1].Role)
+		require.Equal(t, fantasy.MessageRoleTool, msgs[2].Role)
+	})
+}
+
+func TestTextAttachments(t *testing.T) {
+	t.Parallel()
+
+	attachments := []Attachment{
+		{FileName: "shot.png", MimeType: "image/png", Content: []byte("png")},
+		{FileName: "main.go", MimeType: "text/plain", Content: []byte("<file path=\"main.go\">\npackage main\n</file>")},
+	}
+
+	files := FileParts(attachments)
+	require.Len(t, files, 1)
+	require.Equal(t, "shot.png", files[0].Filename)
+	require.Equal(t, "explain\n\n<file path=\"main.go\">\npackage main\n</file>", PromptText("explain", attachments))
+
+	msg := Message{Role: User, Parts: []ContentPart{
+		TextContent{Text: "explain"},
+		BinaryContent{Path: "shot.png", MIMEType: "image/png", Data: []byte("png")},
+		BinaryContent{Path: "main.go", MIMEType: "text/plain", Data: []byte("package main")},
+	}}
+	parts := msg.ToAIMessage()[0].Content
+	require.Len(t, parts, 3)
+	require.IsType(t, fantasy.FilePart{}, parts[1])
+	require.Equal(t, fantasy.TextPart{Text: "package main"}, parts[2])
+}
+
+func TestRepairToolResults(t *testing.T) {
+	t.Parallel()
+
+	ls := fantasy.ToolCallPart{ToolCallID: "1", ToolName: "ls"}
+	glob := fantasy.ToolCallPart{ToolCallID: "2", ToolName: "glob"}
+	result := fantasy.ToolResultOutputContentText{Text: "a.txt"}
+
+	t.Run("well paired", func(t *testing.T) {
+		t.Parallel()
+
+		msgs := NewPromptBuilder().
+			User("list the files").
+			Assistant("", ls).
+			ToolResult("1", result).
+			Messages()
+		repaired, repairs := RepairToolResults(msgs)
+		require.Empty(t, repairs)
+		require.Equal(t, msgs, repaired)
+	})
+
+	t.Run("missing result", func(t *testing.T) {
+		t.Parallel()
+
+		repaired, repairs := RepairToolResults(NewPromptBuilder().
+			Assistant("", ls, glob).
+			ToolResult("1", result).
+			User("go on").
+			Messages())
+		require.Len(t, repairs, 1)
+		require.NoError(t, ValidatePrompt(repaired))
+		require.Len(t, repaired, 3)
+		require.Len(t, repaired[1].Content, 2)
+		missing, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](repaired[1].Content[1])
+		require.True(t, ok)
+		require.Equal(t, "2", missing.ToolCallID)
+		require.IsType(t, fantasy.ToolResultOutputContentError{}, missing.Output)
+	})
+
+	t.Run("result after another message", func(t *testing.T) {
+		t.Parallel()
+
+		repaired, repairs := RepairToolResults(NewPromptBuilder().
+			Assistant("", ls).
+			User("hurry").
+			ToolResult("1", result).
+			Messages())
+		require.Len(t, repairs, 1)
+		require.NoError(t, ValidatePrompt(repaired))
+		require.Equal(t, []fantasy.MessageRole{
+			fantasy.MessageRoleAssistant,
+			fantasy.MessageRoleTool,
+			fantasy.MessageRoleUser,
+		}, roles(repaired))
+	})
+
+	t.Run("orphaned and duplicate results", func(t *testing.T) {
+		t.Parallel()
+
+		repaired, repairs := RepairToolResults(NewPromptBuilder().
+			User("hi").
+			ToolResult("3", result).
+			Assistant("", ls).
+			ToolResult("1", result).
+			ToolResult("1", result).
+			Messages())
+		require.Len(t, repairs, 2)
+		require.NoError(t, ValidatePrompt(repaired))
+		require.Equal(t, []fantasy.MessageRole{
+			fantasy.MessageRoleUser,
+			fantasy.MessageRoleAssistant,
+			fantasy.MessageRoleTool,
+		}, roles(repaired))
+		require.Len(t, repaired[2].Content, 1)
+	})
+}
+
+func roles(msgs []fantasy.Message) []fantasy.MessageRole {
+	roles := make([]fantasy.MessageRole, len(msgs))
+	for i, msg := range msgs {
+		roles[i] = msg.Role
+	}
+	return roles
+}
@@ -0,0 +1,115 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// RetentionPolicy decides what of the messages is kept in storage. The zero
+// value keeps everything.
+type RetentionPolicy struct {
+	// BodyMaxAge is the age past which the content of messages is deleted.
+	// How they finished and the summaries of the turns are kept for the
+	// stats. Zero keeps the content.
+	BodyMaxAge time.Duration
+	// DropReasoning never stores the reasoning of models.
+	DropReasoning bool
+	// DropAttachments never stores the files attached to messages.
+	DropAttachments bool
+}
+
+// Enabled reports whether the policy leaves anything out of storage.
+func (p RetentionPolicy) Enabled() bool {
+	return p.BodyMaxAge > 0 || p.DropReasoning || p.DropAttachments
+}
+
+// retain returns the parts of a message created at the given unix time that
+// are stored, and whether any was left out.
+func (p RetentionPolicy) retain(parts []ContentPart, createdAt int64, now time.Time) ([]ContentPart, bool) {
+	expired := p.BodyMaxAge > 0 && createdAt < now.Add(-p.BodyMaxAge).Unix()
+	retained := make([]ContentPart, 0, len(parts))
+	for _, part := range parts {
+		switch part.(type) {
+		case Finish, RunSummary:
+		case ReasoningContent:
+			if p.DropReasoning || expired {
+				continue
+			}
+		case BinaryContent, ImageURLContent:
+			if p.DropAttachments || expired {
+				continue
+			}
+		default:
+			if expired {
+				continue
+			}
+		}
+		retained = append(retained, part)
+	}
+	return retained, len(retained) != len(parts)
+}
+
+// ServiceOption configures the message service.
+type ServiceOption func(*service)
+
+// WithRetention has the service enforce the policy on the messages it
+// writes, and on the stored ones with EnforceRetention.
+func WithRetention(policy RetentionPolicy) ServiceOption {
+	return func(s *service) {
+		s.retention = policy
+	}
+}
+
+func (s *service) EnforceRetention(ctx context.Context) (int64, error) {
+	if !s.retention.Enabled() {
+		return 0, nil
+	}
+	now := time.Now()
+	params := db.ListMessagesForRetentionParams{}
+	if s.retention.BodyMaxAge > 0 {
+		params.CreatedBefore = sql.NullInt64{Int64: now.Add(-s.retention.BodyMaxAge).Unix(), Valid: true}
+	}
+	if s.retention.DropReasoning {
+		params.DropReasoning = 1
+	}
+	if s.retention.DropAttachments {
+		params.DropAttachments = 1
+	}
+	dbMessages, err := s.q.ListMessagesForRetention(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+
+	var enforced int64
+	for _, dbMessage := range dbMessages {
+		message, err := s.fromDBItem(dbMessage)
+		if err != nil {
+			slog.Warn("Failed to decode message for retention", "id", dbMessage.ID, "error", err)
+			continue
+		}
+		parts, changed := s.retention.retain(message.Parts, message.CreatedAt, now)
+		if !changed {
+			continue
+		}
+		message.Parts = parts
+		data, err := marshallParts(parts)
+		if err != nil {
+			return enforced, err
+		}
+		if err := s.q.UpdateMessage(ctx, db.UpdateMessageParams{
+			ID:         message.ID,
+			Parts:      string(data),
+			FinishedAt: dbMessage.FinishedAt,
+		}); err != nil {
+			return enforced, err
+		}
+		enforced++
+		s.Publish(pubsub.UpdatedEvent, message)
+	}
+	return enforced, nil
+}
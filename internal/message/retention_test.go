@@ -0,0 +1,100 @@
+package message
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetentionOnWrite(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sess, err := session.NewService(q).Create(t.Context(), "retention")
+	require.NoError(t, err)
+	messages := NewService(q, conn, WithRetention(RetentionPolicy{DropReasoning: true, DropAttachments: true}))
+
+	attachment := BinaryContent{Path: "image.png", MIMEType: "image/png", Data: []byte{0x89, 0x50}}
+	user, err := messages.Create(t.Context(), sess.ID, CreateMessageParams{
+		Role:  User,
+		Parts: []ContentPart{TextContent{Text: "look"}, attachment},
+	})
+	require.NoError(t, err)
+	// The message created still has it, to be sent to the model.
+	require.Equal(t, []BinaryContent{attachment}, user.BinaryContent())
+	stored, err := messages.Get(t.Context(), user.ID)
+	require.NoError(t, err)
+	require.Empty(t, stored.BinaryContent())
+	require.Equal(t, "look", stored.Content().Text)
+
+	assistant, err := messages.Create(t.Context(), sess.ID, CreateMessageParams{Role: Assistant})
+	require.NoError(t, err)
+	assistant.AppendReasoningContent("thinking")
+	assistant.AppendContent("a cat")
+	require.NoError(t, messages.Update(t.Context(), assistant))
+	stored, err = messages.Get(t.Context(), assistant.ID)
+	require.NoError(t, err)
+	require.Empty(t, stored.ReasoningContent().Thinking)
+	require.Equal(t, "a cat", stored.Content().Text)
+}
+
+func TestEnforceRetention(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sess, err := session.NewService(q).Create(t.Context(), "retention")
+	require.NoError(t, err)
+
+	// Stored before the policy was set.
+	old := time.Now().Add(-100 * 24 * time.Hour).Unix()
+	oldParts, err := MarshalParts([]ContentPart{
+		TextContent{Text: "secret"},
+		ToolCall{ID: "1", Name: "bash", Input: `{"command":"ls"}`, Finished: true},
+		Finish{Reason: FinishReasonToolUse, Time: old},
+		RunSummary{Steps: 2},
+	})
+	require.NoError(t, err)
+	require.NoError(t, q.ImportMessage(t.Context(), db.ImportMessageParams{
+		ID:        "old",
+		SessionID: sess.ID,
+		Role:      string(Assistant),
+		Parts:     oldParts,
+		CreatedAt: old,
+		UpdatedAt: old,
+	}))
+	recent, err := NewService(q, conn).Create(t.Context(), sess.ID, CreateMessageParams{
+		Role:  Assistant,
+		Parts: []ContentPart{ReasoningContent{Thinking: "thinking"}, TextContent{Text: "hello"}},
+	})
+	require.NoError(t, err)
+
+	messages := NewService(q, conn, WithRetention(RetentionPolicy{BodyMaxAge: 90 * 24 * time.Hour, DropReasoning: true}))
+	enforced, err := messages.EnforceRetention(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), enforced)
+
+	stored, err := messages.Get(t.Context(), "old")
+	require.NoError(t, err)
+	require.Equal(t, []ContentPart{Finish{Reason: FinishReasonToolUse, Time: old}, RunSummary{Steps: 2}}, stored.Parts)
+	stored, err = messages.Get(t.Context(), recent.ID)
+	require.NoError(t, err)
+	require.Equal(t, []ContentPart{TextContent{Text: "hello"}}, stored.Parts)
+
+	enforced, err = messages.EnforceRetention(t.Context())
+	require.NoError(t, err)
+	require.Zero(t, enforced)
+
+	// Without a policy nothing changes.
+	enforced, err = NewService(q, conn).EnforceRetention(t.Context())
+	require.NoError(t, err)
+	require.Zero(t, enforced)
+}
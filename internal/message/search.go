@@ -0,0 +1,178 @@
+package message
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/crush/internal/db"
+)
+
+// defaultSearchLimit is the number of results of a search without a limit.
+const defaultSearchLimit = 20
+
+// SearchOptions narrows a search of the messages.
+type SearchOptions struct {
+	// SessionID limits the search to a session, all of them when empty.
+	SessionID string
+	// Limit is the maximum number of results, 20 when zero.
+	Limit int
+	// HighlightStart and HighlightEnd surround the matches in the snippets.
+	HighlightStart string
+	HighlightEnd   string
+}
+
+// SearchResult is a message matching a search.
+type SearchResult struct {
+	MessageID    string
+	SessionID    string
+	SessionTitle string
+	Role         MessageRole
+	CreatedAt    int64
+	// Snippet is the part of the message around the matches.
+	Snippet string
+	// Rank is the relevance of the message, lower is better.
+	Rank float64
+}
+
+func (s *service) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+	params := db.SearchMessagesParams{
+		HighlightStart: opts.HighlightStart,
+		HighlightEnd:   opts.HighlightEnd,
+		Query:          match,
+		Limit:          int64(opts.Limit),
+	}
+	if params.Limit <= 0 {
+		params.Limit = defaultSearchLimit
+	}
+	if opts.SessionID != "" {
+		params.SessionID = sql.NullString{String: opts.SessionID, Valid: true}
+	}
+	rows, err := s.q.SearchMessages(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages for %q: %w", query, err)
+	}
+	results := make([]SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = SearchResult{
+			MessageID:    row.ID,
+			SessionID:    row.SessionID,
+			SessionTitle: row.SessionTitle,
+			Role:         MessageRole(row.Role),
+			CreatedAt:    row.CreatedAt,
+			Snippet:      row.Snippet,
+			Rank:         row.Rank,
+		}
+	}
+	return results, nil
+}
+
+// ftsQuery turns a search into an FTS5 query. Words and "quoted phrases"
+// match as they are, a trailing * matches the words starting with them, and
+// AND, OR, NOT and parentheses combine them. Words next to each other must
+// all match. Everything else is quoted so that punctuation in the search
+// isn't taken for the FTS5 syntax.
+func ftsQuery(query string) string {
+	var terms []string
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			terms = append(terms, string(r))
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if phrase := strings.TrimSpace(string(runes[i+1 : end])); phrase != "" {
+				terms = append(terms, quoteFTS(phrase))
+			}
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()"`, runes[end]) {
+				end++
+			}
+			word := string(runes[i:end])
+			i = end
+			switch {
+			case word == "AND" || word == "OR" || word == "NOT":
+				terms = append(terms, word)
+			case strings.HasSuffix(word, "*") && strings.Trim(word, "*") != "":
+				terms = append(terms, quoteFTS(strings.TrimRight(word, "*"))+"*")
+			case strings.Trim(word, "*") != "":
+				terms = append(terms, quoteFTS(word))
+			}
+		}
+	}
+	return strings.Join(balanceFTS(terms), " ")
+}
+
+func quoteFTS(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// balanceFTS drops the operators and parentheses that would make the query
+// invalid: unmatched parentheses, and operators without terms on both sides.
+func balanceFTS(terms []string) []string {
+	isOperator := func(term string) bool {
+		return term == "AND" || term == "OR" || term == "NOT"
+	}
+
+	var result []string
+	var open []int
+	for _, term := range terms {
+		switch {
+		case term == "(":
+			open = append(open, len(result))
+			result = append(result, term)
+		case term == ")":
+			for len(result) > 0 && isOperator(result[len(result)-1]) {
+				result = result[:len(result)-1]
+			}
+			if len(open) == 0 {
+				continue
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			if len(result) == start+1 {
+				// Nothing in the parentheses.
+				result = result[:start]
+				continue
+			}
+			result = append(result, term)
+		case isOperator(term):
+			if len(result) == 0 || isOperator(result[len(result)-1]) || result[len(result)-1] == "(" {
+				continue
+			}
+			result = append(result, term)
+		default:
+			result = append(result, term)
+		}
+	}
+	for len(result) > 0 && isOperator(result[len(result)-1]) {
+		result = result[:len(result)-1]
+	}
+	// Close the parentheses left open, last first.
+	for i := len(open) - 1; i >= 0; i-- {
+		if len(result) == open[i]+1 {
+			result = result[:open[i]]
+			continue
+		}
+		for len(result) > 0 && isOperator(result[len(result)-1]) {
+			result = result[:len(result)-1]
+		}
+		result = append(result, ")")
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFTSQuery(t *testing.T) {
+	t.Parallel()
+
+	for query, want := range map[string]string{
+		"parser":                       `"parser"`,
+		"fix the parser":               `"fix" "the" "parser"`,
+		`"context deadline" exceeded`:  `"context deadline" "exceeded"`,
+		"pars*":                        `"pars"*`,
+		"(parser OR lexer) NOT test*":  `( "parser" OR "lexer" ) NOT "test"*`,
+		"what's up-to-date?":           `"what's" "up-to-date?"`,
+		"OR parser AND":                `"parser"`,
+		"(parser OR":                   `( "parser" )`,
+		"parser) ()":                   `"parser"`,
+		`say "hi""`:                    `"say" "hi"`,
+		"  ":                           ``,
+		"*":                            ``,
+		"parser and lexer":             `"parser" "and" "lexer"`,
+		`file_path":"internal/agent"`:  `"file_path" ":" "internal/agent"`,
+		"NOT parser":                   `"parser"`,
+		"(parser (lexer OR) AND) tree": `( "parser" ( "lexer" ) ) "tree"`,
+	} {
+		require.Equal(t, want, ftsQuery(query), query)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sessions := session.NewService(q)
+	messages := NewService(q, conn)
+
+	first, err := sessions.Create(t.Context(), "Parser")
+	require.NoError(t, err)
+	second, err := sessions.Create(t.Context(), "Lexer")
+	require.NoError(t, err)
+
+	_, err = messages.Create(t.Context(), first.ID, CreateMessageParams{
+		Role:  User,
+		Parts: []ContentPart{TextContent{Text: "The parser fails on nested expressions"}},
+	})
+	require.NoError(t, err)
+	assistant, err := messages.Create(t.Context(), first.ID, CreateMessageParams{Role: Assistant})
+	require.NoError(t, err)
+	assistant.AddToolCall(ToolCall{ID: "1", Name: "grep", Input: `{"pattern":"tokenize"}`, Finished: true})
+	require.NoError(t, messages.Update(t.Context(), assistant))
+	_, err = messages.Create(t.Context(), second.ID, CreateMessageParams{
+		Role:  User,
+		Parts: []ContentPart{TextContent{Text: "The lexer drops the last token of the parser input"}},
+	})
+	require.NoError(t, err)
+
+	results, err := messages.Search(t.Context(), "parser", SearchOptions{HighlightStart: "[", HighlightEnd: "]"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	// The shorter message mentioning it ranks first.
+	require.Equal(t, first.ID, results[0].SessionID)
+	require.Equal(t, "Parser", results[0].SessionTitle)
+	require.Equal(t, User, results[0].Role)
+	require.Equal(t, "The [parser] fails on nested expressions", results[0].Snippet)
+
+	results, err = messages.Search(t.Context(), "parser NOT lexer", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, first.ID, results[0].SessionID)
+
+	results, err = messages.Search(t.Context(), "parser", SearchOptions{SessionID: second.ID})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, second.ID, results[0].SessionID)
+
+	// Not indexed while streaming, but once finished, and stemmed: token
+	// matches too.
+	results, err = messages.Search(t.Context(), "tokenizing", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assistant.AddFinish(FinishReasonToolUse, "", "")
+	require.NoError(t, messages.Update(t.Context(), assistant))
+	results, err = messages.Search(t.Context(), "tokenizing", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, assistant.ID, results[0].MessageID)
+
+	// Deleted messages leave the index.
+	require.NoError(t, messages.Delete(t.Context(), assistant.ID))
+	results, err = messages.Search(t.Context(), "tokenizing", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, second.ID, results[0].SessionID)
+
+	results, err = messages.Search(t.Context(), `"nested expressions" OR "last token"`, SearchOptions{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+}
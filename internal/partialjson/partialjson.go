@@ -0,0 +1,124 @@
+// Package partialjson reads the fields of JSON objects that are still being
+// streamed, such as the input of a tool call while the model writes it.
+package partialjson
+
+import (
+	"encoding/json"
+	"maps"
+	"strings"
+)
+
+// Parser incrementally reads the top-level fields of a streamed JSON object.
+// A field is only reported once its value is complete and can't change with
+// more input.
+type Parser struct {
+	buf    strings.Builder
+	fields map[string]json.RawMessage
+	// pos is the offset in buf after the last complete field.
+	pos     int
+	started bool
+	done    bool
+}
+
+// NewParser returns a parser for a streamed JSON object.
+func NewParser() *Parser {
+	return &Parser{fields: map[string]json.RawMessage{}}
+}
+
+// Append adds the next chunk of the object and parses the fields it
+// completes.
+func (p *Parser) Append(delta string) {
+	p.buf.WriteString(delta)
+	p.parse()
+}
+
+// Fields returns the complete fields read so far.
+func (p *Parser) Fields() map[string]json.RawMessage {
+	return maps.Clone(p.fields)
+}
+
+// Done reports whether the whole object was read.
+func (p *Parser) Done() bool {
+	return p.done
+}
+
+// Decode stores the complete fields read so far in v, like json.Unmarshal.
+func (p *Parser) Decode(v any) error {
+	data, err := json.Marshal(p.fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (p *Parser) parse() {
+	if p.done {
+		return
+	}
+	input := p.buf.String()
+	rest := strings.TrimLeft(input[p.pos:], " \t\r\n")
+	if !p.started {
+		if rest == "" {
+			return
+		}
+		if rest[0] != '{' {
+			// Not an object, there are no fields to read.
+			p.done = true
+			return
+		}
+		p.started = true
+	} else {
+		rest = strings.TrimPrefix(rest, ",")
+	}
+	// The decoder starts over from the last complete field, so it gets the
+	// rest of the object as if it was a new one.
+	offset := len(input) - len(rest)
+	if p.pos == 0 {
+		rest = strings.TrimPrefix(rest, "{")
+		offset++
+	}
+	dec := json.NewDecoder(strings.NewReader("{" + rest))
+	if _, err := dec.Token(); err != nil {
+		return
+	}
+	for {
+		if !dec.More() {
+			if _, err := dec.Token(); err == nil {
+				p.done = true
+			}
+			return
+		}
+		key, err := dec.Token()
+		if err != nil {
+			return
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return
+		}
+		// Numbers can still grow until something follows them.
+		end := int(dec.InputOffset()) - 1
+		if strings.TrimSpace(rest[end:]) == "" {
+			return
+		}
+		name, _ := key.(string)
+		p.fields[name] = value
+		p.pos = offset + end
+	}
+}
+
+// Fields returns the complete top-level fields of a possibly partial JSON
+// object.
+func Fields(input string) map[string]json.RawMessage {
+	p := NewParser()
+	p.Append(input)
+	return p.fields
+}
+
+// Unmarshal stores the complete top-level fields of a possibly partial JSON
+// object in v.
+func Unmarshal(input string, v any) error {
+	p := NewParser()
+	p.Append(input)
+	return p.Decode(v)
+}
@@ -0,0 +1,72 @@
+package partialjson
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser(t *testing.T) {
+	t.Parallel()
+
+	const input = `{"file_path": "/tmp/main.go", "limit": 120, "nested": {"a": [1, 2]}, "content": "package main\n"}`
+
+	// Whatever the chunk size, all the fields are read.
+	for _, size := range []int{1, 3, 7, len(input)} {
+		p := NewParser()
+		var seen []string
+		for i := 0; i < len(input); i += size {
+			p.Append(input[i:min(i+size, len(input))])
+			for name := range p.Fields() {
+				if !slices.Contains(seen, name) {
+					seen = append(seen, name)
+				}
+			}
+		}
+		require.True(t, p.Done())
+		require.ElementsMatch(t, []string{"file_path", "limit", "nested", "content"}, seen, "chunk size %d", size)
+
+		var params struct {
+			FilePath string `json:"file_path"`
+			Limit    int    `json:"limit"`
+			Content  string `json:"content"`
+		}
+		require.NoError(t, p.Decode(&params))
+		require.Equal(t, "/tmp/main.go", params.FilePath)
+		require.Equal(t, 120, params.Limit)
+		require.Equal(t, "package main\n", params.Content)
+	}
+}
+
+func TestFields(t *testing.T) {
+	t.Parallel()
+
+	for input, expected := range map[string]map[string]json.RawMessage{
+		``:                         {},
+		`{`:                        {},
+		`{"file_path": "/tmp/ma`:   {},
+		`{"file_path": "/tmp/a"`:   {},
+		`{"file_path": "/tmp/a",`:  {"file_path": json.RawMessage(`"/tmp/a"`)},
+		`{"limit": 12`:             {},
+		`{"limit": 12 `:            {},
+		`{"limit": 12, "old": "x`:  {"limit": json.RawMessage(`12`)},
+		`{"a": "}", "b": {"c": 1}`: {"a": json.RawMessage(`"}"`)},
+		`[1, 2`:                    {},
+	} {
+		require.Equal(t, expected, Fields(input), "input %q", input)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	t.Parallel()
+
+	var params struct {
+		Command string `json:"command"`
+		Timeout int    `json:"timeout"`
+	}
+	require.NoError(t, Unmarshal(`{"command": "go test ./...", "timeout": 6`, &params))
+	require.Equal(t, "go test ./...", params.Command)
+	require.Zero(t, params.Timeout)
+}
@@ -0,0 +1,161 @@
+package pubsub
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// slowSubscriberTimeout is how long the bus waits for a subscriber with a
+// full buffer before dropping an event for it.
+const slowSubscriberTimeout = 2 * time.Second
+
+// Topic names the component events come from, e.g. sessions or messages.
+type Topic string
+
+// Envelope is an event carried by a Bus, along with the topic it was
+// published on.
+type Envelope struct {
+	Topic Topic
+	Type  EventType
+	// Event is the Event[T] published by the component.
+	Event any
+}
+
+// Filter selects the envelopes delivered to a bus subscriber.
+type Filter func(Envelope) bool
+
+// Topics selects the envelopes published on any of the topics.
+func Topics(topics ...Topic) Filter {
+	return func(e Envelope) bool {
+		return slices.Contains(topics, e.Topic)
+	}
+}
+
+// Types selects the envelopes of any of the event types.
+func Types(types ...EventType) Filter {
+	return func(e Envelope) bool {
+		return slices.Contains(types, e.Type)
+	}
+}
+
+// Not selects the envelopes the filter rejects.
+func Not(filter Filter) Filter {
+	return func(e Envelope) bool {
+		return !filter(e)
+	}
+}
+
+type busSubscriber struct {
+	ch      chan Envelope
+	filters []Filter
+}
+
+func (s *busSubscriber) wants(e Envelope) bool {
+	for _, filter := range s.filters {
+		if !filter(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// Bus carries the events of every component, so several observers such as
+// the TUI, the control server or logging can follow them without depending
+// on each component.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[*busSubscriber]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*busSubscriber]struct{})}
+}
+
+// Subscribe returns the envelopes accepted by all the filters. The channel
+// is closed when ctx is done.
+func (b *Bus) Subscribe(ctx context.Context, filters ...Filter) <-chan Envelope {
+	sub := &busSubscriber{
+		ch:      make(chan Envelope, bufferSize),
+		filters: filters,
+	}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, sub)
+		close(sub.ch)
+	}()
+	return sub.ch
+}
+
+// Publish delivers an envelope to the subscribers that want it. Slow
+// subscribers are waited for a while before the envelope is dropped for
+// them, so that bursts such as streamed messages aren't lost.
+func (b *Bus) Publish(ctx context.Context, e Envelope) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if !sub.wants(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+			continue
+		default:
+		}
+		select {
+		case sub.ch <- e:
+		case <-time.After(slowSubscriberTimeout):
+			slog.Warn("Event dropped due to slow subscriber", "topic", e.Topic, "type", e.Type)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Forward publishes the events of a component on the bus under topic until
+// ctx is done or the component closes its subscription.
+func Forward[T any](ctx context.Context, bus *Bus, topic Topic, subscribe func(context.Context) <-chan Event[T]) {
+	events := subscribe(ctx)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				slog.Debug("Subscription channel closed", "topic", topic)
+				return
+			}
+			bus.Publish(ctx, Envelope{Topic: topic, Type: event.Type, Event: event})
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SubscribeTo returns the events with payloads of type T that are accepted
+// by all the filters, for observers interested in a single component.
+func SubscribeTo[T any](ctx context.Context, bus *Bus, filters ...Filter) <-chan Event[T] {
+	filters = append(slices.Clip(filters), func(e Envelope) bool {
+		_, ok := e.Event.(Event[T])
+		return ok
+	})
+	envelopes := bus.Subscribe(ctx, filters...)
+	events := make(chan Event[T], bufferSize)
+	go func() {
+		defer close(events)
+		for e := range envelopes {
+			select {
+			case events <- e.Event.(Event[T]):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
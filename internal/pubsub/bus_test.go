@@ -0,0 +1,82 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		var zero T
+		return zero
+	}
+}
+
+func TestBus(t *testing.T) {
+	t.Parallel()
+
+	bus := NewBus()
+	all := bus.Subscribe(t.Context())
+	created := bus.Subscribe(t.Context(), Topics("sessions"), Types(CreatedEvent))
+	counts := SubscribeTo[int](t.Context(), bus, Not(Types(DeletedEvent)))
+
+	bus.Publish(t.Context(), Envelope{Topic: "sessions", Type: UpdatedEvent, Event: Event[string]{UpdatedEvent, "a"}})
+	bus.Publish(t.Context(), Envelope{Topic: "counts", Type: DeletedEvent, Event: Event[int]{DeletedEvent, 1}})
+	bus.Publish(t.Context(), Envelope{Topic: "counts", Type: CreatedEvent, Event: Event[int]{CreatedEvent, 2}})
+	bus.Publish(t.Context(), Envelope{Topic: "sessions", Type: CreatedEvent, Event: Event[string]{CreatedEvent, "b"}})
+
+	for _, topic := range []Topic{"sessions", "counts", "counts", "sessions"} {
+		require.Equal(t, topic, receive(t, all).Topic)
+	}
+	require.Equal(t, Event[string]{CreatedEvent, "b"}, receive(t, created).Event)
+	require.Equal(t, Event[int]{CreatedEvent, 2}, receive(t, counts))
+	require.Empty(t, created)
+	require.Empty(t, counts)
+}
+
+func TestForward(t *testing.T) {
+	t.Parallel()
+
+	broker := NewBroker[string]()
+	bus := NewBus()
+	events := SubscribeTo[string](t.Context(), bus, Topics("names"))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Forward(ctx, bus, "names", broker.Subscribe)
+	}()
+	require.Eventually(t, func() bool { return broker.GetSubscriberCount() == 1 }, time.Second, 10*time.Millisecond)
+
+	broker.Publish(CreatedEvent, "crush")
+	require.Equal(t, Event[string]{CreatedEvent, "crush"}, receive(t, events))
+
+	cancel()
+	receive(t, done)
+}
+
+func TestBusUnsubscribe(t *testing.T) {
+	t.Parallel()
+
+	bus := NewBus()
+	ctx, cancel := context.WithCancel(t.Context())
+	ch := bus.Subscribe(ctx)
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("subscription was not closed")
+	}
+	bus.Publish(t.Context(), Envelope{Topic: "sessions"})
+}
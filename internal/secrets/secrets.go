@@ -0,0 +1,295 @@
+// Package secrets keeps the credentials of a project, like database URLs or
+// API tokens, encrypted on disk. They're given to the commands the agent
+// runs as environment variables, and redacted from everything the model,
+// the logs and the exports see.
+package secrets
+
+import (
+	"cmp"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+const (
+	vaultFile = "secrets.enc"
+	keyFile   = "secrets.key"
+	keySize   = 32
+)
+
+var (
+	ErrNotFound    = errors.New("secret not found")
+	ErrInvalidName = errors.New("secret names must be valid environment variable names")
+
+	namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// KeyPath is where the key the vaults are encrypted with is kept, outside
+// of the projects so it isn't committed or shared along with them.
+func KeyPath() string {
+	return filepath.Join(filepath.Dir(config.GlobalConfigData()), keyFile)
+}
+
+// Vault holds the secrets of a project. It reloads them when they're
+// changed on disk, so secrets set while Crush runs are picked up. A nil
+// Vault has no secrets.
+type Vault struct {
+	path    string
+	keyPath string
+
+	mu       sync.Mutex
+	modTime  time.Time
+	values   map[string]string
+	replacer *strings.Replacer
+}
+
+// Open opens the vault of the project with the given data directory. The
+// vault doesn't need to exist yet.
+func Open(dataDir string) (*Vault, error) {
+	return open(filepath.Join(dataDir, vaultFile), KeyPath())
+}
+
+func open(path, keyPath string) (*Vault, error) {
+	v := &Vault{path: path, keyPath: keyPath, values: map[string]string{}}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.load(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Set stores the secret and saves the vault.
+func (v *Vault) Set(name, value string) error {
+	if !namePattern.MatchString(name) {
+		return ErrInvalidName
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.load(); err != nil {
+		return err
+	}
+	values := maps.Clone(v.values)
+	values[name] = value
+	return v.save(values)
+}
+
+// Delete removes the secret and saves the vault.
+func (v *Vault) Delete(name string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.load(); err != nil {
+		return err
+	}
+	if _, ok := v.values[name]; !ok {
+		return ErrNotFound
+	}
+	values := maps.Clone(v.values)
+	delete(values, name)
+	return v.save(values)
+}
+
+// Names returns the names of the secrets, sorted.
+func (v *Vault) Names() []string {
+	if v == nil {
+		return nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reload()
+	return slices.Sorted(maps.Keys(v.values))
+}
+
+// Environ returns the secrets as environment variables, in the form of
+// NAME=value.
+func (v *Vault) Environ() []string {
+	if v == nil {
+		return nil
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reload()
+	env := make([]string, 0, len(v.values))
+	for _, name := range slices.Sorted(maps.Keys(v.values)) {
+		env = append(env, name+"="+v.values[name])
+	}
+	return env
+}
+
+// Redact replaces the values of the secrets in s with their names.
+func (v *Vault) Redact(s string) string {
+	if v == nil || s == "" {
+		return s
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.reload()
+	if v.replacer == nil {
+		return s
+	}
+	return v.replacer.Replace(s)
+}
+
+// reload loads the vault again if it changed on disk. Errors are ignored so
+// the secrets loaded before keep being used and redacted.
+func (v *Vault) reload() {
+	_ = v.load()
+}
+
+func (v *Vault) load() error {
+	info, err := os.Stat(v.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		v.set(map[string]string{}, time.Time{})
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read secrets: %w", err)
+	}
+	if info.ModTime().Equal(v.modTime) && v.values != nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(v.path)
+	if err != nil {
+		return fmt.Errorf("failed to read secrets: %w", err)
+	}
+	key, err := v.key(false)
+	if err != nil {
+		return err
+	}
+	plain, err := decrypt(key, data)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt secrets: %w", err)
+	}
+	values := map[string]string{}
+	if err := json.Unmarshal(plain, &values); err != nil {
+		return fmt.Errorf("failed to decode secrets: %w", err)
+	}
+	v.set(values, info.ModTime())
+	return nil
+}
+
+func (v *Vault) save(values map[string]string) error {
+	plain, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to encode secrets: %w", err)
+	}
+	key, err := v.key(true)
+	if err != nil {
+		return err
+	}
+	data, err := encrypt(key, plain)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt secrets: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(v.path), 0o700); err != nil {
+		return fmt.Errorf("failed to save secrets: %w", err)
+	}
+	if err := os.WriteFile(v.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save secrets: %w", err)
+	}
+	info, err := os.Stat(v.path)
+	if err != nil {
+		return fmt.Errorf("failed to save secrets: %w", err)
+	}
+	v.set(values, info.ModTime())
+	return nil
+}
+
+func (v *Vault) set(values map[string]string, modTime time.Time) {
+	v.values = values
+	v.modTime = modTime
+	v.replacer = newReplacer(values)
+}
+
+// key reads the key of the vaults, creating it if create is true and there
+// is none yet.
+func (v *Vault) key(create bool) ([]byte, error) {
+	key, err := os.ReadFile(v.keyPath)
+	if err == nil {
+		if len(key) != keySize {
+			return nil, fmt.Errorf("invalid secrets key in %s", v.keyPath)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) || !create {
+		return nil, fmt.Errorf("failed to read secrets key: %w", err)
+	}
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to create secrets key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(v.keyPath), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create secrets key: %w", err)
+	}
+	if err := os.WriteFile(v.keyPath, key, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to create secrets key: %w", err)
+	}
+	return key, nil
+}
+
+// newReplacer replaces the longest values first, so a secret containing
+// another one is redacted whole.
+func newReplacer(values map[string]string) *strings.Replacer {
+	names := slices.Collect(maps.Keys(values))
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(len(values[b])-len(values[a]), strings.Compare(a, b))
+	})
+	var oldnew []string
+	for _, name := range names {
+		if values[name] == "" {
+			continue
+		}
+		oldnew = append(oldnew, values[name], "[REDACTED:"+name+"]")
+	}
+	if len(oldnew) == 0 {
+		return nil
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+func encrypt(key, plain []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func decrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("secrets file is too short")
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
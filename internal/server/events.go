@@ -0,0 +1,150 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// Types of the events streamed to clients.
+const (
+	// EventMessage is sent when a message is created, with what it holds
+	// so far: all of it for user messages, usually nothing for the others.
+	EventMessage = "message"
+	// EventTextDelta and EventReasoningDelta carry the text the model added
+	// to its response and its reasoning.
+	EventTextDelta      = "text_delta"
+	EventReasoningDelta = "reasoning_delta"
+	// EventToolCallStart is sent when the model starts calling a tool,
+	// EventToolInputDelta as it writes the input and EventToolCall once the
+	// call is complete.
+	EventToolCallStart  = "tool_call_start"
+	EventToolInputDelta = "tool_input_delta"
+	EventToolCall       = "tool_call"
+	EventToolResult     = "tool_result"
+	// EventFinish is sent when a response is finished, once per step.
+	EventFinish = "finish"
+	// EventRunFinished is sent when the agent is done with a prompt sent
+	// through the server, with its result as encoded by agent.MarshalResult
+	// and the error that stopped it, if any.
+	EventRunFinished = "run_finished"
+)
+
+// Event is a change to a session streamed to clients. Offset is where Delta
+// starts in the text, reasoning or tool input, so clients that read the
+// message first can skip what they already have.
+type Event struct {
+	Type       string             `json:"type"`
+	SessionID  string             `json:"session_id"`
+	MessageID  string             `json:"message_id,omitempty"`
+	Delta      string             `json:"delta,omitempty"`
+	Offset     int                `json:"offset,omitempty"`
+	Message    *export.Message    `json:"message,omitempty"`
+	ToolCall   *message.ToolCall  `json:"tool_call,omitempty"`
+	ToolResult *export.ToolResult `json:"tool_result,omitempty"`
+	Finish     *message.Finish    `json:"finish,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	Result     json.RawMessage    `json:"result,omitempty"`
+}
+
+// tracker turns the updates of messages, which carry their whole content,
+// into the events of what changed since the previous update.
+type tracker struct {
+	messages map[string]*messageState
+}
+
+type messageState struct {
+	text      int
+	reasoning int
+	inputs    map[string]int
+	calls     map[string]bool
+	finished  map[string]bool
+	results   map[string]bool
+	finish    bool
+}
+
+func newTracker() *tracker {
+	return &tracker{messages: make(map[string]*messageState)}
+}
+
+// created returns the events of a new message.
+func (t *tracker) created(msg message.Message) []Event {
+	state := &messageState{
+		inputs:   make(map[string]int),
+		calls:    make(map[string]bool),
+		finished: make(map[string]bool),
+		results:  make(map[string]bool),
+	}
+	t.messages[msg.ID] = state
+	exported := export.JSON(session.Session{}, []message.Message{msg}).Messages[0]
+	events := []Event{{Type: EventMessage, SessionID: msg.SessionID, MessageID: msg.ID, Message: &exported}}
+	// What the message holds was sent along with it.
+	state.text = len(msg.Content().Text)
+	state.reasoning = len(msg.ReasoningContent().Thinking)
+	// User messages are created finished.
+	state.finish = msg.FinishPart() != nil
+	return append(events, t.changes(state, msg)...)
+}
+
+// updated returns the events of what changed in the message.
+func (t *tracker) updated(msg message.Message) []Event {
+	state, ok := t.messages[msg.ID]
+	if !ok {
+		// Created before the server started, its beginning is in the export
+		// of the session.
+		return t.created(msg)
+	}
+	var events []Event
+	if text := msg.Content().Text; len(text) > state.text {
+		events = append(events, Event{Type: EventTextDelta, SessionID: msg.SessionID, MessageID: msg.ID, Delta: text[state.text:], Offset: state.text})
+		state.text = len(text)
+	}
+	if reasoning := msg.ReasoningContent().Thinking; len(reasoning) > state.reasoning {
+		events = append(events, Event{Type: EventReasoningDelta, SessionID: msg.SessionID, MessageID: msg.ID, Delta: reasoning[state.reasoning:], Offset: state.reasoning})
+		state.reasoning = len(reasoning)
+	}
+	return append(events, t.changes(state, msg)...)
+}
+
+func (t *tracker) deleted(msg message.Message) {
+	delete(t.messages, msg.ID)
+}
+
+// changes returns the events of the tool calls, results and finish of the
+// message that weren't sent yet.
+func (t *tracker) changes(state *messageState, msg message.Message) []Event {
+	var events []Event
+	for _, call := range msg.ToolCalls() {
+		if !state.calls[call.ID] {
+			state.calls[call.ID] = true
+			events = append(events, Event{Type: EventToolCallStart, SessionID: msg.SessionID, MessageID: msg.ID, ToolCall: &message.ToolCall{ID: call.ID, Name: call.Name}})
+		}
+		if len(call.Input) > state.inputs[call.ID] {
+			events = append(events, Event{Type: EventToolInputDelta, SessionID: msg.SessionID, MessageID: msg.ID, ToolCall: &message.ToolCall{ID: call.ID, Name: call.Name}, Delta: call.Input[state.inputs[call.ID]:], Offset: state.inputs[call.ID]})
+			state.inputs[call.ID] = len(call.Input)
+		}
+		if call.Finished && !state.finished[call.ID] {
+			state.finished[call.ID] = true
+			events = append(events, Event{Type: EventToolCall, SessionID: msg.SessionID, MessageID: msg.ID, ToolCall: &call})
+		}
+	}
+	for _, result := range msg.ToolResults() {
+		if state.results[result.ToolCallID] {
+			continue
+		}
+		state.results[result.ToolCallID] = true
+		events = append(events, Event{Type: EventToolResult, SessionID: msg.SessionID, MessageID: msg.ID, ToolResult: &export.ToolResult{
+			ToolCallID: result.ToolCallID,
+			Name:       result.Name,
+			Content:    result.Content,
+			IsError:    result.IsError,
+		}})
+	}
+	if finish := msg.FinishPart(); finish != nil && !state.finish {
+		state.finish = true
+		events = append(events, Event{Type: EventFinish, SessionID: msg.SessionID, MessageID: msg.ID, Finish: finish})
+	}
+	return events
+}
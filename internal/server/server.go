@@ -0,0 +1,390 @@
+// Package server exposes the sessions of a crush instance over HTTP, so other
+// programs, like web or editor UIs, can follow and drive the agent.
+//
+// Sessions are read and created with JSON requests, and prompts are sent to
+// the agent, which runs in the background. What the agent does is streamed
+// with server-sent events, each a JSON Event named after its type:
+//
+//	GET  /sessions              the sessions
+//	POST /sessions              creates a session, {"title": "..."}
+//	GET  /sessions/{id}         the session with its messages
+//	GET  /sessions/{id}/status  whether the agent is busy with the session
+//	POST /sessions/{id}/prompt  sends a prompt to the agent, {"prompt": "..."}
+//	POST /sessions/{id}/cancel  cancels the agent
+//	GET  /sessions/{id}/events  the events of the session
+//	GET  /events                the events of every session
+//
+// The agent writes its response to the messages of the session as it
+// streams it, so events are made of the changes to the messages rather than
+// of the stream itself, and are the same whether the prompt came from the
+// server or from elsewhere.
+//
+// Prompts run tools without asking for permissions, so every request must
+// carry the token of the server as a bearer token, POST requests must send
+// JSON and, unless the server is remote, the Host must be the local machine
+// to keep web pages from reaching the server through DNS rebinding. Requests
+// over a Unix socket, which web pages can't reach and whose permissions
+// restrict who connects, are trusted.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent"
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// keepAliveInterval is how often an idle event stream is written to, so
+// proxies don't close it.
+const keepAliveInterval = 15 * time.Second
+
+// Agent runs prompts in sessions.
+type Agent interface {
+	Run(ctx context.Context, sessionID, prompt string, attachments ...message.Attachment) (*fantasy.AgentResult, error)
+	Cancel(sessionID string)
+	IsSessionBusy(sessionID string) bool
+}
+
+// Options configure a server.
+type Options struct {
+	Sessions    session.Service
+	Messages    message.Service
+	Permissions permission.Service
+	Agent       Agent
+	// Token must be sent by clients as a bearer token. Without one every
+	// request is refused.
+	Token string
+	// Remote accepts requests for any host, for servers listening beyond
+	// the local machine. Otherwise the Host of requests must be loopback.
+	Remote bool
+}
+
+// Server serves the HTTP API.
+type Server struct {
+	ctx    context.Context
+	opts   Options
+	events *pubsub.Broker[Event]
+	// runs receives the events of the runs that finished, to publish them
+	// after the changes the runs made.
+	runs chan Event
+	mux  *http.ServeMux
+}
+
+// New creates a server and starts following the messages. Prompts sent
+// through the server run until ctx is done.
+func New(ctx context.Context, opts Options) *Server {
+	s := &Server{
+		ctx:    ctx,
+		opts:   opts,
+		events: pubsub.NewBroker[Event](),
+		runs:   make(chan Event),
+		mux:    http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /sessions", s.listSessions)
+	s.mux.HandleFunc("POST /sessions", s.createSession)
+	s.mux.HandleFunc("GET /sessions/{id}", s.getSession)
+	s.mux.HandleFunc("GET /sessions/{id}/status", s.status)
+	s.mux.HandleFunc("POST /sessions/{id}/prompt", s.prompt)
+	s.mux.HandleFunc("POST /sessions/{id}/cancel", s.cancel)
+	s.mux.HandleFunc("GET /sessions/{id}/events", s.streamEvents)
+	s.mux.HandleFunc("GET /events", s.streamEvents)
+
+	ready := make(chan struct{})
+	go s.follow(ready)
+	<-ready
+	return s
+}
+
+// follow publishes the events of the changes to the messages until the
+// context of the server is done.
+func (s *Server) follow(ready chan<- struct{}) {
+	defer s.events.Shutdown()
+	messages := s.opts.Messages.Subscribe(s.ctx)
+	close(ready)
+
+	t := newTracker()
+	handle := func(event pubsub.Event[message.Message]) {
+		var events []Event
+		switch event.Type {
+		case pubsub.CreatedEvent:
+			events = t.created(event.Payload)
+		case pubsub.UpdatedEvent:
+			events = t.updated(event.Payload)
+		case pubsub.DeletedEvent:
+			t.deleted(event.Payload)
+		}
+		for _, e := range events {
+			s.events.Publish(pubsub.UpdatedEvent, e)
+		}
+	}
+	for {
+		select {
+		case event, ok := <-messages:
+			if !ok {
+				return
+			}
+			handle(event)
+		case run := <-s.runs:
+			// The changes made by the run were published before it returned,
+			// publish those still waiting first.
+			for pending := true; pending; {
+				select {
+				case event, ok := <-messages:
+					if !ok {
+						return
+					}
+					handle(event)
+				default:
+					pending = false
+				}
+			}
+			s.events.Publish(pubsub.UpdatedEvent, run)
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := r.Context().Value(http.LocalAddrContextKey).(*net.UnixAddr); ok {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+	if !s.opts.Remote && !isLoopbackHost(r.Host) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not allowed", r.Host))
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || s.opts.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) != 1 {
+		writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+		return
+	}
+	if r.Method == http.MethodPost {
+		// Forms can't send JSON, so web pages can't post without a
+		// preflight request.
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+			writeError(w, http.StatusUnsupportedMediaType, errors.New("content type must be application/json"))
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// isLoopbackHost reports whether the Host of a request is the local
+// machine.
+func isLoopbackHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.opts.Sessions.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	out := make([]export.Session, 0, len(sessions))
+	for _, sess := range sessions {
+		exported := export.JSON(sess, nil)
+		exported.Messages = nil
+		out = append(out, exported)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Title string `json:"title"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Title == "" {
+		req.Title = "New Session"
+	}
+	sess, err := s.opts.Sessions.Create(r.Context(), req.Title)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, export.JSON(sess, nil))
+}
+
+func (s *Server) getSession(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	msgs, err := s.opts.Messages.List(r.Context(), sess.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, export.JSON(sess, msgs))
+}
+
+// SessionStatus is the state of the agent in a session.
+type SessionStatus struct {
+	Busy bool `json:"busy"`
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, SessionStatus{Busy: s.opts.Agent.IsSessionBusy(sess.ID)})
+}
+
+func (s *Server) prompt(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := decode(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("prompt is required"))
+		return
+	}
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+
+	// There is nobody to ask for permissions, as with crush run.
+	s.opts.Permissions.AutoApproveSession(sess.ID)
+	go func() {
+		event := Event{Type: EventRunFinished, SessionID: sess.ID}
+		result, err := s.opts.Agent.Run(s.ctx, sess.ID, req.Prompt)
+		if err != nil {
+			slog.Error("Agent run started over HTTP failed", "session", sess.ID, "error", err)
+			event.Error = err.Error()
+		}
+		if result != nil {
+			if event.Result, err = agent.MarshalResult(result); err != nil {
+				slog.Error("Failed to encode the result of an agent run", "session", sess.ID, "error", err)
+			}
+		}
+		select {
+		case s.runs <- event:
+		case <-s.ctx.Done():
+		}
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) cancel(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	s.opts.Agent.Cancel(sess.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// streamEvents streams the events of the session in the path, or of every
+// session, as server-sent events until the client goes away.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.PathValue("id")
+	if sessionID != "" {
+		if _, ok := s.session(w, r); !ok {
+			return
+		}
+	}
+
+	events := s.events.Subscribe(r.Context())
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		slog.Debug("Failed to start event stream", "error", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if sessionID != "" && event.Payload.SessionID != sessionID {
+				continue
+			}
+			data, err := json.Marshal(event.Payload)
+			if err != nil {
+				slog.Error("Failed to encode event", "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Payload.Type, data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// session returns the session in the path, writing the error if there is
+// none.
+func (s *Server) session(w http.ResponseWriter, r *http.Request) (session.Session, bool) {
+	sess, err := s.opts.Sessions.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("session not found: %s", r.PathValue("id")))
+		return session.Session{}, false
+	}
+	return sess, true
+}
+
+func decode(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("Failed to write response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
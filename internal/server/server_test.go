@@ -0,0 +1,249 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/crush/internal/agent"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgent writes a response with reasoning and a tool call, the way the
+// agent does as it streams.
+type fakeAgent struct {
+	messages message.Service
+}
+
+func (a *fakeAgent) Run(ctx context.Context, sessionID, prompt string, _ ...message.Attachment) (*fantasy.AgentResult, error) {
+	if _, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: prompt}},
+	}); err != nil {
+		return nil, err
+	}
+	msg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{Role: message.Assistant})
+	if err != nil {
+		return nil, err
+	}
+	steps := []func(){
+		func() { msg.AppendReasoningContent("Checking") },
+		func() { msg.AppendContent("Running ") },
+		func() { msg.AppendContent("the tests.") },
+		func() { msg.AddToolCall(message.ToolCall{ID: "call-1", Name: "bash", Input: `{"command":`}) },
+		func() { msg.AppendToolCallInput("call-1", `"go test"}`) },
+		func() { msg.FinishToolCall("call-1") },
+		func() { msg.AddFinish(message.FinishReasonToolUse, "", "") },
+	}
+	for _, step := range steps {
+		step()
+		// The parts are copied so later steps don't change those published.
+		update := msg
+		update.Parts = slices.Clone(msg.Parts)
+		if err := a.messages.Update(ctx, update); err != nil {
+			return nil, err
+		}
+	}
+	return &fantasy.AgentResult{Response: fantasy.Response{
+		Content:      fantasy.ResponseContent{fantasy.TextContent{Text: "Running the tests."}},
+		FinishReason: fantasy.FinishReasonToolCalls,
+	}}, nil
+}
+
+func (a *fakeAgent) Cancel(string) {}
+
+func (a *fakeAgent) IsSessionBusy(string) bool { return false }
+
+const testToken = "secret"
+
+func newTestServer(t *testing.T) (*httptest.Server, session.Service) {
+	t.Helper()
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sessions := session.NewService(q)
+	messages := message.NewService(q, conn)
+
+	srv := New(t.Context(), Options{
+		Sessions:    sessions,
+		Messages:    messages,
+		Permissions: permission.NewPermissionService(t.TempDir(), false, nil),
+		Agent:       &fakeAgent{messages: messages},
+		Token:       testToken,
+	})
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return ts, sessions
+}
+
+// request sends an authenticated request, with a JSON body if not empty.
+func request(t *testing.T, method, url, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestServerStreamsEvents(t *testing.T) {
+	t.Parallel()
+
+	ts, sessions := newTestServer(t)
+	other, err := sessions.Create(t.Context(), "Other")
+	require.NoError(t, err)
+
+	resp := request(t, http.MethodPost, ts.URL+"/sessions", `{"title":"Fix the tests"}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var sess export.Session
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&sess))
+	resp.Body.Close()
+	require.Equal(t, "Fix the tests", sess.Title)
+
+	stream := request(t, http.MethodGet, ts.URL+"/sessions/"+sess.ID+"/events", "")
+	defer stream.Body.Close()
+	require.Equal(t, "text/event-stream", stream.Header.Get("Content-Type"))
+
+	// Prompts sent to other sessions aren't streamed.
+	request(t, http.MethodPost, ts.URL+"/sessions/"+other.ID+"/prompt", `{"prompt":"Hello"}`).Body.Close()
+	resp = request(t, http.MethodPost, ts.URL+"/sessions/"+sess.ID+"/prompt", `{"prompt":"Run the tests"}`)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	var got []string
+	scanner := bufio.NewScanner(stream.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event Event
+		require.NoError(t, json.Unmarshal([]byte(data), &event))
+		require.Equal(t, sess.ID, event.SessionID)
+		switch event.Type {
+		case EventMessage:
+			got = append(got, event.Type+" "+string(event.Message.Role)+" "+event.Message.Text)
+		case EventToolCallStart, EventToolCall:
+			got = append(got, event.Type+" "+event.ToolCall.Name+" "+event.ToolCall.Input)
+		case EventFinish:
+			got = append(got, event.Type+" "+string(event.Finish.Reason))
+		default:
+			got = append(got, event.Type+" "+event.Delta)
+		}
+		if event.Type == EventRunFinished {
+			result, err := agent.UnmarshalResult(event.Result)
+			require.NoError(t, err)
+			require.Equal(t, "Running the tests.", result.Response.Content.Text())
+			break
+		}
+	}
+	require.Equal(t, []string{
+		"message user Run the tests",
+		"message assistant ",
+		"reasoning_delta Checking",
+		"text_delta Running ",
+		"text_delta the tests.",
+		"tool_call_start bash ",
+		`tool_input_delta {"command":`,
+		`tool_input_delta "go test"}`,
+		`tool_call bash {"command":"go test"}`,
+		"finish tool_use",
+		"run_finished ",
+	}, got)
+
+	resp = request(t, http.MethodGet, ts.URL+"/sessions/"+sess.ID, "")
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&sess))
+	resp.Body.Close()
+	require.Len(t, sess.Messages, 2)
+	require.Equal(t, "Running the tests.", sess.Messages[1].Text)
+}
+
+func TestServerToken(t *testing.T) {
+	t.Parallel()
+
+	ts, _ := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/sessions")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp = request(t, http.MethodGet, ts.URL+"/sessions", "")
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, ts.URL+"/sessions", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer wrong")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestServerRejectsCrossSiteRequests(t *testing.T) {
+	t.Parallel()
+
+	ts, _ := newTestServer(t)
+
+	// A form post, which web pages can send without a preflight request.
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, ts.URL+"/sessions", strings.NewReader(`{"title":"x"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Header.Set("Content-Type", "text/plain")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	// A page on another host resolving to the server.
+	req, err = http.NewRequestWithContext(t.Context(), http.MethodGet, ts.URL+"/sessions", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	req.Host = "attacker.example:7600"
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestIsLoopbackHost(t *testing.T) {
+	t.Parallel()
+
+	for host, want := range map[string]bool{
+		"localhost:7600":   true,
+		"127.0.0.1:7600":   true,
+		"[::1]:7600":       true,
+		"LOCALHOST":        true,
+		"example.com:7600": false,
+		"192.168.1.2:7600": false,
+		"":                 false,
+	} {
+		require.Equal(t, want, isLoopbackHost(host), host)
+	}
+}
+
+func TestServerUnknownSession(t *testing.T) {
+	t.Parallel()
+
+	ts, _ := newTestServer(t)
+	resp := request(t, http.MethodPost, ts.URL+"/sessions/missing/prompt", `{"prompt":"Hi"}`)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -3,7 +3,11 @@ package session
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/crush/internal/db"
@@ -23,6 +27,27 @@ type Session struct {
 	Cost             float64
 	CreatedAt        int64
 	UpdatedAt        int64
+	// WorkingDir overrides the directory the session works in, empty means
+	// the directory crush was started in.
+	WorkingDir string
+	// Env holds extra environment variables for the commands of the session.
+	Env map[string]string
+	// Handoff is a document shared with the child sessions spawned by the
+	// agent, describing what they need to know about this session.
+	Handoff string
+	// PinnedMessageIDs are the messages shared with the child sessions
+	// spawned by the agent.
+	PinnedMessageIDs []string
+}
+
+// Environ returns the environment variables of the session in the
+// "key=value" form, sorted by key.
+func (s Session) Environ() []string {
+	env := make([]string, 0, len(s.Env))
+	for _, key := range slices.Sorted(maps.Keys(s.Env)) {
+		env = append(env, key+"="+s.Env[key])
+	}
+	return env
 }
 
 type Service interface {
@@ -32,7 +57,14 @@ type Service interface {
 	CreateTaskSession(ctx context.Context, toolCallID, parentSessionID, title string) (Session, error)
 	Get(ctx context.Context, id string) (Session, error)
 	List(ctx context.Context) ([]Session, error)
+	// ListChildren lists the sessions spawned from the session, oldest
+	// first.
+	ListChildren(ctx context.Context, parentSessionID string) ([]Session, error)
 	Save(ctx context.Context, session Session) (Session, error)
+	SetEnvironment(ctx context.Context, id, workingDir string, env map[string]string) (Session, error)
+	SetHandoff(ctx context.Context, id, handoff string) (Session, error)
+	PinMessage(ctx context.Context, id, messageID string) (Session, error)
+	UnpinMessage(ctx context.Context, id, messageID string) (Session, error)
 	Delete(ctx context.Context, id string) error
 
 	// Agent tool session management
@@ -130,6 +162,85 @@ func (s *service) Save(ctx context.Context, session Session) (Session, error) {
 	return session, nil
 }
 
+func (s *service) SetEnvironment(ctx context.Context, id, workingDir string, env map[string]string) (Session, error) {
+	if env == nil {
+		env = map[string]string{}
+	}
+	envJSON, err := json.Marshal(env)
+	if err != nil {
+		return Session{}, err
+	}
+	dbSession, err := s.q.UpdateSessionEnvironment(ctx, db.UpdateSessionEnvironmentParams{
+		ID:         id,
+		WorkingDir: workingDir,
+		Env:        string(envJSON),
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.UpdatedEvent, session)
+	return session, nil
+}
+
+func (s *service) SetHandoff(ctx context.Context, id, handoff string) (Session, error) {
+	dbSession, err := s.q.UpdateSessionHandoff(ctx, db.UpdateSessionHandoffParams{
+		ID:      id,
+		Handoff: handoff,
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.UpdatedEvent, session)
+	return session, nil
+}
+
+func (s *service) PinMessage(ctx context.Context, id, messageID string) (Session, error) {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return Session{}, err
+	}
+	if slices.Contains(session.PinnedMessageIDs, messageID) {
+		return session, nil
+	}
+	return s.setPinnedMessages(ctx, id, append(session.PinnedMessageIDs, messageID))
+}
+
+func (s *service) UnpinMessage(ctx context.Context, id, messageID string) (Session, error) {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return Session{}, err
+	}
+	if !slices.Contains(session.PinnedMessageIDs, messageID) {
+		return session, nil
+	}
+	pinned := slices.DeleteFunc(session.PinnedMessageIDs, func(pinnedID string) bool {
+		return pinnedID == messageID
+	})
+	return s.setPinnedMessages(ctx, id, pinned)
+}
+
+func (s *service) setPinnedMessages(ctx context.Context, id string, messageIDs []string) (Session, error) {
+	if messageIDs == nil {
+		messageIDs = []string{}
+	}
+	pinnedJSON, err := json.Marshal(messageIDs)
+	if err != nil {
+		return Session{}, err
+	}
+	dbSession, err := s.q.UpdateSessionPinnedMessages(ctx, db.UpdateSessionPinnedMessagesParams{
+		ID:               id,
+		PinnedMessageIds: string(pinnedJSON),
+	})
+	if err != nil {
+		return Session{}, err
+	}
+	session := s.fromDBItem(dbSession)
+	s.Publish(pubsub.UpdatedEvent, session)
+	return session, nil
+}
+
 func (s *service) List(ctx context.Context) ([]Session, error) {
 	dbSessions, err := s.q.ListSessions(ctx)
 	if err != nil {
@@ -142,7 +253,27 @@ func (s *service) List(ctx context.Context) ([]Session, error) {
 	return sessions, nil
 }
 
+func (s *service) ListChildren(ctx context.Context, parentSessionID string) ([]Session, error) {
+	dbSessions, err := s.q.ListChildSessions(ctx, sql.NullString{String: parentSessionID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i] = s.fromDBItem(dbSession)
+	}
+	return sessions, nil
+}
+
 func (s service) fromDBItem(item db.Session) Session {
+	var env map[string]string
+	if err := json.Unmarshal([]byte(item.Env), &env); err != nil {
+		slog.Warn("Failed to parse session environment", "session", item.ID, "error", err)
+	}
+	var pinned []string
+	if err := json.Unmarshal([]byte(item.PinnedMessageIds), &pinned); err != nil {
+		slog.Warn("Failed to parse session pinned messages", "session", item.ID, "error", err)
+	}
 	return Session{
 		ID:               item.ID,
 		ParentSessionID:  item.ParentSessionID.String,
@@ -154,6 +285,10 @@ func (s service) fromDBItem(item db.Session) Session {
 		Cost:             item.Cost,
 		CreatedAt:        item.CreatedAt,
 		UpdatedAt:        item.UpdatedAt,
+		WorkingDir:       item.WorkingDir,
+		Env:              env,
+		Handoff:          item.Handoff,
+		PinnedMessageIDs: pinned,
 	}
 }
 
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextSharing(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	svc := NewService(db.New(conn))
+	created, err := svc.Create(t.Context(), "Parent")
+	require.NoError(t, err)
+	require.Empty(t, created.Handoff)
+	require.Empty(t, created.PinnedMessageIDs)
+
+	updated, err := svc.SetHandoff(t.Context(), created.ID, "Use the new API.")
+	require.NoError(t, err)
+	require.Equal(t, "Use the new API.", updated.Handoff)
+
+	_, err = svc.PinMessage(t.Context(), created.ID, "first")
+	require.NoError(t, err)
+	_, err = svc.PinMessage(t.Context(), created.ID, "second")
+	require.NoError(t, err)
+	updated, err = svc.PinMessage(t.Context(), created.ID, "first")
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, updated.PinnedMessageIDs)
+
+	updated, err = svc.UnpinMessage(t.Context(), created.ID, "first")
+	require.NoError(t, err)
+	require.Equal(t, []string{"second"}, updated.PinnedMessageIDs)
+
+	got, err := svc.Get(t.Context(), created.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Use the new API.", got.Handoff)
+	require.Equal(t, []string{"second"}, got.PinnedMessageIDs)
+
+	sessions, err := svc.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, got, sessions[0])
+}
@@ -0,0 +1,155 @@
+// Package sessionfork forks sessions, copying them up to one of their
+// messages into a new session to explore another continuation without
+// changing them.
+package sessionfork
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/google/uuid"
+)
+
+// ErrMessageNotFound is returned when forking at a message the session
+// doesn't have.
+var ErrMessageNotFound = errors.New("message not found in the session")
+
+// Options configures a fork.
+type Options struct {
+	// AtMessageID is the last message copied to the fork, all of them are
+	// when empty.
+	AtMessageID string
+	// Title is the title of the fork, the one of the session followed by
+	// "(fork)" when empty.
+	Title string
+}
+
+// Result describes a fork.
+type Result struct {
+	SessionID string
+	// Messages is the number of messages copied to the fork.
+	Messages int
+}
+
+// Fork creates a session holding the messages of the session up to the
+// message of the options. The fork keeps the environment, handoff and
+// pinned messages of the session, and records the session and message it
+// was forked at. It starts without token counts or costs, those were spent
+// by the session forked, which is left as it is.
+func Fork(ctx context.Context, conn *sql.DB, sessionID string, opts Options) (Result, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	q := db.New(conn).WithTx(tx)
+
+	source, err := q.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get session %s: %w", sessionID, err)
+	}
+	messages, err := q.ListMessagesBySession(ctx, source.ID)
+	if err != nil {
+		return Result{}, err
+	}
+	if opts.AtMessageID != "" {
+		at := -1
+		for i, msg := range messages {
+			if msg.ID == opts.AtMessageID {
+				at = i
+				break
+			}
+		}
+		if at < 0 {
+			return Result{}, fmt.Errorf("%w: %s", ErrMessageNotFound, opts.AtMessageID)
+		}
+		messages = messages[:at+1]
+	}
+
+	// The copies get new IDs, the references to them follow.
+	ids := make(map[string]string, len(messages))
+	for _, msg := range messages {
+		ids[msg.ID] = uuid.New().String()
+	}
+	summaryID := sql.NullString{}
+	if id, ok := ids[source.SummaryMessageID.String]; ok && source.SummaryMessageID.Valid {
+		summaryID = sql.NullString{String: id, Valid: true}
+	}
+	var pinned []string
+	_ = json.Unmarshal([]byte(source.PinnedMessageIds), &pinned)
+	forkPinned := []string{}
+	for _, id := range pinned {
+		if forkID, ok := ids[id]; ok {
+			forkPinned = append(forkPinned, forkID)
+		}
+	}
+	pinnedJSON, err := json.Marshal(forkPinned)
+	if err != nil {
+		return Result{}, err
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = source.Title + " (fork)"
+	}
+	forkID := uuid.New().String()
+	now := time.Now().Unix()
+	if err := q.ImportSession(ctx, db.ImportSessionParams{
+		ID:               forkID,
+		Title:            title,
+		SummaryMessageID: summaryID,
+		Handoff:          source.Handoff,
+		PinnedMessageIds: string(pinnedJSON),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}); err != nil {
+		return Result{}, fmt.Errorf("failed to create session: %w", err)
+	}
+	if source.WorkingDir != "" || source.Env != "" {
+		if _, err := q.UpdateSessionEnvironment(ctx, db.UpdateSessionEnvironmentParams{
+			ID:         forkID,
+			WorkingDir: source.WorkingDir,
+			Env:        source.Env,
+		}); err != nil {
+			return Result{}, fmt.Errorf("failed to copy session environment: %w", err)
+		}
+	}
+	for _, msg := range messages {
+		if err := q.ImportMessage(ctx, db.ImportMessageParams{
+			ID:               ids[msg.ID],
+			SessionID:        forkID,
+			Role:             msg.Role,
+			Parts:            msg.Parts,
+			Model:            msg.Model,
+			Provider:         msg.Provider,
+			IsSummaryMessage: msg.IsSummaryMessage,
+			CreatedAt:        msg.CreatedAt,
+			UpdatedAt:        msg.UpdatedAt,
+			FinishedAt:       msg.FinishedAt,
+		}); err != nil {
+			return Result{}, fmt.Errorf("failed to copy message: %w", err)
+		}
+	}
+
+	atMessage := sql.NullString{}
+	if len(messages) > 0 {
+		atMessage = sql.NullString{String: messages[len(messages)-1].ID, Valid: true}
+	}
+	if err := q.CreateSessionFork(ctx, db.CreateSessionForkParams{
+		SessionID:       forkID,
+		SourceSessionID: source.ID,
+		SourceMessageID: atMessage,
+		MessageCount:    int64(len(messages)),
+	}); err != nil {
+		return Result{}, fmt.Errorf("failed to record fork: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Result{}, err
+	}
+	return Result{SessionID: forkID, Messages: len(messages)}, nil
+}
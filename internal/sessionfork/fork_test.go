@@ -0,0 +1,92 @@
+package sessionfork
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFork(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sessions := session.NewService(q)
+	messages := message.NewService(q, conn)
+
+	source, err := sessions.Create(t.Context(), "Parser")
+	require.NoError(t, err)
+	var created []message.Message
+	for i, text := range []string{"Fix the parser", "Fixed.", "Now the lexer", "Done."} {
+		role := message.User
+		if i%2 == 1 {
+			role = message.Assistant
+		}
+		msg, err := messages.Create(t.Context(), source.ID, message.CreateMessageParams{
+			Role:  role,
+			Parts: []message.ContentPart{message.TextContent{Text: text}},
+		})
+		require.NoError(t, err)
+		created = append(created, msg)
+	}
+	_, err = sessions.SetEnvironment(t.Context(), source.ID, "/work", map[string]string{"GOFLAGS": "-mod=mod"})
+	require.NoError(t, err)
+	_, err = sessions.PinMessage(t.Context(), source.ID, created[0].ID)
+	require.NoError(t, err)
+	_, err = sessions.PinMessage(t.Context(), source.ID, created[2].ID)
+	require.NoError(t, err)
+	source.Cost = 1.5
+	source, err = sessions.Save(t.Context(), source)
+	require.NoError(t, err)
+
+	_, err = Fork(t.Context(), conn, source.ID, Options{AtMessageID: "missing"})
+	require.ErrorIs(t, err, ErrMessageNotFound)
+
+	result, err := Fork(t.Context(), conn, source.ID, Options{AtMessageID: created[1].ID})
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Messages)
+
+	fork, err := sessions.Get(t.Context(), result.SessionID)
+	require.NoError(t, err)
+	require.Equal(t, "Parser (fork)", fork.Title)
+	require.Empty(t, fork.ParentSessionID)
+	require.Zero(t, fork.Cost)
+	require.Equal(t, "/work", fork.WorkingDir)
+	require.Equal(t, map[string]string{"GOFLAGS": "-mod=mod"}, fork.Env)
+
+	forked, err := messages.List(t.Context(), fork.ID)
+	require.NoError(t, err)
+	var texts []string
+	for _, msg := range forked {
+		require.NotEqual(t, created[0].ID, msg.ID)
+		texts = append(texts, msg.Content().Text)
+	}
+	require.Equal(t, []string{"Fix the parser", "Fixed."}, texts)
+	// Only the pinned messages copied are pinned in the fork.
+	require.Equal(t, []string{forked[0].ID}, fork.PinnedMessageIDs)
+
+	record, err := q.GetSessionFork(t.Context(), fork.ID)
+	require.NoError(t, err)
+	require.Equal(t, source.ID, record.SourceSessionID)
+	require.Equal(t, sql.NullString{String: created[1].ID, Valid: true}, record.SourceMessageID)
+	require.Equal(t, int64(2), record.MessageCount)
+
+	// Without a message the whole session is copied.
+	result, err = Fork(t.Context(), conn, source.ID, Options{Title: "Lexer"})
+	require.NoError(t, err)
+	require.Equal(t, 4, result.Messages)
+	forks, err := q.ListSessionForks(t.Context(), source.ID)
+	require.NoError(t, err)
+	require.Len(t, forks, 2)
+
+	// The session forked is left as it is.
+	count, err := messages.CountBySession(t.Context(), source.ID, message.Filter{})
+	require.NoError(t, err)
+	require.Equal(t, int64(4), count)
+}
@@ -0,0 +1,185 @@
+// Package sessionmerge merges two sessions into a new one, for work that was
+// split across sessions by accident.
+package sessionmerge
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/google/uuid"
+)
+
+// ErrSameSession is returned when merging a session with itself.
+var ErrSameSession = errors.New("cannot merge a session with itself")
+
+// Options configures a merge.
+type Options struct {
+	// Interleave orders the messages of both sessions by the time they were
+	// created, instead of putting those of the second session after the
+	// ones of the first.
+	Interleave bool
+	// Title is the title of the merged session, the one of the first
+	// session when empty.
+	Title string
+}
+
+// Result describes a merge.
+type Result struct {
+	SessionID string
+	// Messages is the number of messages of the merged session.
+	Messages int
+	// Duplicates is the number of messages of the second session left out
+	// as the first one has them too.
+	Duplicates int
+}
+
+// Merge creates a session holding the messages of both sessions. The
+// messages of the second session the first one has too, like the context
+// copied over when the work was split, are left out. The token counts and
+// costs of both sessions add up, and where the messages come from is
+// recorded. The sessions merged are left as they are.
+func Merge(ctx context.Context, conn *sql.DB, firstID, secondID string, opts Options) (Result, error) {
+	if firstID == secondID {
+		return Result{}, ErrSameSession
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	q := db.New(conn).WithTx(tx)
+
+	first, err := q.GetSessionByID(ctx, firstID)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get session %s: %w", firstID, err)
+	}
+	second, err := q.GetSessionByID(ctx, secondID)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get session %s: %w", secondID, err)
+	}
+	firstMessages, err := q.ListMessagesBySession(ctx, first.ID)
+	if err != nil {
+		return Result{}, err
+	}
+	secondMessages, err := q.ListMessagesBySession(ctx, second.ID)
+	if err != nil {
+		return Result{}, err
+	}
+
+	seen := make(map[string]bool, len(firstMessages))
+	for _, msg := range firstMessages {
+		seen[messageKey(msg)] = true
+	}
+	var kept []db.Message
+	for _, msg := range secondMessages {
+		if !seen[messageKey(msg)] {
+			kept = append(kept, msg)
+		}
+	}
+	duplicates := len(secondMessages) - len(kept)
+
+	messages := append(slices.Clone(firstMessages), kept...)
+	if opts.Interleave {
+		// Stable, so messages created in the same second keep their order.
+		slices.SortStableFunc(messages, func(a, b db.Message) int {
+			return cmp.Compare(a.CreatedAt, b.CreatedAt)
+		})
+	} else if len(firstMessages) > 0 {
+		// Messages are listed by the time they were created, those of the
+		// second session sent before the end of the first one are moved to
+		// its end. Messages created in the same second are listed in the
+		// order they were inserted.
+		last := firstMessages[len(firstMessages)-1].CreatedAt
+		for i := len(firstMessages); i < len(messages); i++ {
+			messages[i].CreatedAt = max(messages[i].CreatedAt, last)
+		}
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = first.Title
+	}
+	sessionID := uuid.New().String()
+	if err := q.ImportSession(ctx, db.ImportSessionParams{
+		ID:               sessionID,
+		Title:            title,
+		PromptTokens:     first.PromptTokens + second.PromptTokens,
+		CompletionTokens: first.CompletionTokens + second.CompletionTokens,
+		Cost:             first.Cost + second.Cost,
+		PinnedMessageIds: "[]",
+		CreatedAt:        min(first.CreatedAt, second.CreatedAt),
+		UpdatedAt:        time.Now().Unix(),
+	}); err != nil {
+		return Result{}, fmt.Errorf("failed to create session: %w", err)
+	}
+	for _, msg := range messages {
+		if err := q.ImportMessage(ctx, db.ImportMessageParams{
+			ID:               uuid.New().String(),
+			SessionID:        sessionID,
+			Role:             msg.Role,
+			Parts:            msg.Parts,
+			Model:            msg.Model,
+			Provider:         msg.Provider,
+			IsSummaryMessage: msg.IsSummaryMessage,
+			CreatedAt:        msg.CreatedAt,
+			UpdatedAt:        max(msg.UpdatedAt, msg.CreatedAt),
+			FinishedAt:       msg.FinishedAt,
+		}); err != nil {
+			return Result{}, fmt.Errorf("failed to copy message: %w", err)
+		}
+	}
+
+	for _, source := range []struct {
+		session    db.Session
+		messages   int
+		duplicates int
+	}{
+		{first, len(firstMessages), 0},
+		{second, len(kept), duplicates},
+	} {
+		if err := q.CreateSessionMerge(ctx, db.CreateSessionMergeParams{
+			SessionID:       sessionID,
+			SourceSessionID: source.session.ID,
+			SourceTitle:     source.session.Title,
+			MessageCount:    int64(source.messages),
+			DuplicateCount:  int64(source.duplicates),
+		}); err != nil {
+			return Result{}, fmt.Errorf("failed to record merge: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return Result{}, err
+	}
+	return Result{SessionID: sessionID, Messages: len(messages), Duplicates: duplicates}, nil
+}
+
+// storedPart is a part as stored in the messages table.
+type storedPart struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// messageKey identifies the content of a message, leaving out the parts
+// that differ between copies, like the time it finished.
+func messageKey(msg db.Message) string {
+	var parts []storedPart
+	if err := json.Unmarshal([]byte(msg.Parts), &parts); err != nil {
+		return msg.Role + "\x00" + msg.Parts
+	}
+	parts = slices.DeleteFunc(parts, func(p storedPart) bool {
+		switch p.Type {
+		case "finish", "run_summary", "logprobs":
+			return true
+		}
+		return false
+	})
+	data, _ := json.Marshal(parts)
+	return msg.Role + "\x00" + string(data)
+}
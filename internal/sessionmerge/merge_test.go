@@ -0,0 +1,117 @@
+package sessionmerge
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func createSession(t *testing.T, q *db.Queries, id, title string, cost float64, msgs ...db.ImportMessageParams) {
+	t.Helper()
+	require.NoError(t, q.ImportSession(t.Context(), db.ImportSessionParams{
+		ID:               id,
+		Title:            title,
+		PromptTokens:     100,
+		CompletionTokens: 10,
+		Cost:             cost,
+		PinnedMessageIds: "[]",
+		CreatedAt:        msgs[0].CreatedAt,
+		UpdatedAt:        msgs[len(msgs)-1].CreatedAt,
+	}))
+	for _, msg := range msgs {
+		msg.SessionID = id
+		require.NoError(t, q.ImportMessage(t.Context(), msg))
+	}
+}
+
+func textMessage(t *testing.T, id string, role message.MessageRole, text string, at int64) db.ImportMessageParams {
+	t.Helper()
+	parts, err := message.MarshalParts([]message.ContentPart{
+		message.TextContent{Text: text},
+		message.Finish{Reason: message.FinishReasonEndTurn, Time: at},
+	})
+	require.NoError(t, err)
+	return db.ImportMessageParams{
+		ID:         id,
+		Role:       string(role),
+		Parts:      parts,
+		CreatedAt:  at,
+		UpdatedAt:  at,
+		FinishedAt: sql.NullInt64{Int64: at, Valid: true},
+	}
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+
+	createSession(t, q, "first", "Parser", 0.5,
+		textMessage(t, "f1", message.User, "Fix the parser", 10),
+		textMessage(t, "f2", message.Assistant, "Fixed.", 20),
+		textMessage(t, "f3", message.User, "Now the lexer", 40),
+	)
+	createSession(t, q, "second", "Parser again", 0.25,
+		// Copied over, finishing at another time.
+		textMessage(t, "s1", message.User, "Fix the parser", 30),
+		textMessage(t, "s2", message.Assistant, "Add a test too", 35),
+	)
+
+	_, err = Merge(t.Context(), conn, "first", "first", Options{})
+	require.ErrorIs(t, err, ErrSameSession)
+
+	result, err := Merge(t.Context(), conn, "first", "second", Options{})
+	require.NoError(t, err)
+	require.Equal(t, 4, result.Messages)
+	require.Equal(t, 1, result.Duplicates)
+
+	merged, err := q.GetSessionByID(t.Context(), result.SessionID)
+	require.NoError(t, err)
+	require.Equal(t, "Parser", merged.Title)
+	require.Equal(t, int64(200), merged.PromptTokens)
+	require.Equal(t, int64(20), merged.CompletionTokens)
+	require.InDelta(t, 0.75, merged.Cost, 1e-9)
+	require.Equal(t, int64(10), merged.CreatedAt)
+	require.Equal(t, int64(4), merged.MessageCount)
+
+	msgs, err := message.NewService(q, conn).List(t.Context(), result.SessionID)
+	require.NoError(t, err)
+	var texts []string
+	for _, msg := range msgs {
+		texts = append(texts, msg.Content().Text)
+	}
+	require.Equal(t, []string{"Fix the parser", "Fixed.", "Now the lexer", "Add a test too"}, texts)
+
+	sources, err := q.ListSessionMerges(t.Context(), result.SessionID)
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	require.Equal(t, "first", sources[0].SourceSessionID)
+	require.Equal(t, int64(3), sources[0].MessageCount)
+	require.Equal(t, "second", sources[1].SourceSessionID)
+	require.Equal(t, "Parser again", sources[1].SourceTitle)
+	require.Equal(t, int64(1), sources[1].MessageCount)
+	require.Equal(t, int64(1), sources[1].DuplicateCount)
+
+	result, err = Merge(t.Context(), conn, "first", "second", Options{Interleave: true, Title: "Both"})
+	require.NoError(t, err)
+	merged, err = q.GetSessionByID(t.Context(), result.SessionID)
+	require.NoError(t, err)
+	require.Equal(t, "Both", merged.Title)
+	msgs, err = message.NewService(q, conn).List(t.Context(), result.SessionID)
+	require.NoError(t, err)
+	texts = nil
+	for _, msg := range msgs {
+		texts = append(texts, msg.Content().Text)
+	}
+	require.Equal(t, []string{"Fix the parser", "Fixed.", "Add a test too", "Now the lexer"}, texts)
+
+	// The sessions merged are kept.
+	_, err = q.GetSessionByID(t.Context(), "second")
+	require.NoError(t, err)
+}
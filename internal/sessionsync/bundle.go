@@ -0,0 +1,120 @@
+package sessionsync
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+)
+
+// Bundle is the synced form of a session together with the sessions spawned
+// from it, such as the ones of sub-agents.
+type Bundle struct {
+	// Sessions holds the root session first.
+	Sessions []SessionRecord `json:"sessions"`
+	// Messages are ordered by session and creation time.
+	Messages []MessageRecord `json:"messages"`
+}
+
+type SessionRecord struct {
+	ID               string  `json:"id"`
+	ParentSessionID  string  `json:"parent_session_id,omitempty"`
+	Title            string  `json:"title"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	SummaryMessageID string  `json:"summary_message_id,omitempty"`
+	Handoff          string  `json:"handoff,omitempty"`
+	PinnedMessageIDs string  `json:"pinned_message_ids"`
+	CreatedAt        int64   `json:"created_at"`
+	UpdatedAt        int64   `json:"updated_at"`
+}
+
+type MessageRecord struct {
+	ID               string `json:"id"`
+	SessionID        string `json:"session_id"`
+	Role             string `json:"role"`
+	Parts            string `json:"parts"`
+	Model            string `json:"model,omitempty"`
+	Provider         string `json:"provider,omitempty"`
+	IsSummaryMessage bool   `json:"is_summary_message,omitempty"`
+	CreatedAt        int64  `json:"created_at"`
+	UpdatedAt        int64  `json:"updated_at"`
+	FinishedAt       int64  `json:"finished_at,omitempty"`
+}
+
+// Root returns the session the bundle was built from.
+func (b Bundle) Root() SessionRecord {
+	if len(b.Sessions) == 0 {
+		return SessionRecord{}
+	}
+	return b.Sessions[0]
+}
+
+// Hash identifies the content of the bundle. The update time of sessions is
+// left out, the database bumps it whenever messages are added.
+func (b Bundle) Hash() string {
+	sessions := slices.Clone(b.Sessions)
+	for i := range sessions {
+		sessions[i].UpdatedAt = 0
+	}
+	data, _ := json.Marshal(Bundle{Sessions: sessions, Messages: b.Messages})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalize orders the sessions and messages so that bundles with the same
+// content are equal. Messages created in the same second keep their order.
+func (b *Bundle) normalize() {
+	if len(b.Sessions) > 1 {
+		slices.SortFunc(b.Sessions[1:], func(a, b SessionRecord) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+	}
+	order := make(map[string]int, len(b.Sessions))
+	for i, s := range b.Sessions {
+		order[s.ID] = i
+	}
+	slices.SortStableFunc(b.Messages, func(a, b MessageRecord) int {
+		return cmp.Or(
+			cmp.Compare(order[a.SessionID], order[b.SessionID]),
+			cmp.Compare(a.CreatedAt, b.CreatedAt),
+		)
+	})
+}
+
+// Merge combines two versions of a bundle that were changed independently.
+// Sessions and messages present in both keep the most recently updated
+// version, preferring the local one on ties, and nothing is dropped.
+func Merge(local, remote Bundle) Bundle {
+	merged := Bundle{
+		Sessions: mergeRecords(local.Sessions, remote.Sessions,
+			func(s SessionRecord) string { return s.ID },
+			func(s SessionRecord) int64 { return s.UpdatedAt }),
+		Messages: mergeRecords(local.Messages, remote.Messages,
+			func(m MessageRecord) string { return m.ID },
+			func(m MessageRecord) int64 { return m.UpdatedAt }),
+	}
+	merged.normalize()
+	return merged
+}
+
+func mergeRecords[T any](local, remote []T, id func(T) string, updatedAt func(T) int64) []T {
+	merged := slices.Clone(local)
+	index := make(map[string]int, len(local))
+	for i, record := range local {
+		index[id(record)] = i
+	}
+	for _, record := range remote {
+		i, ok := index[id(record)]
+		if !ok {
+			merged = append(merged, record)
+			continue
+		}
+		if updatedAt(record) > updatedAt(merged[i]) {
+			merged[i] = record
+		}
+	}
+	return merged
+}
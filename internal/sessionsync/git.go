@@ -0,0 +1,76 @@
+package sessionsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitRemote stores the files in a git repository, through a clone kept in
+// the data directory. Changes are pushed on commit.
+type gitRemote struct {
+	dirRemote
+	dir string
+}
+
+func openGitRemote(ctx context.Context, repoURL, cloneDir string) (*gitRemote, error) {
+	sum := sha256.Sum256([]byte(repoURL))
+	dir := filepath.Join(cloneDir, hex.EncodeToString(sum[:8]))
+	g := &gitRemote{dirRemote: dirRemote(dir), dir: dir}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(cloneDir, 0o755); err != nil {
+			return nil, err
+		}
+		if _, err := runGit(ctx, cloneDir, "clone", "--quiet", repoURL, dir); err != nil {
+			return nil, err
+		}
+		return g, nil
+	}
+
+	// An empty repository has nothing to pull yet.
+	if _, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return g, nil
+	}
+	if _, err := runGit(ctx, dir, "pull", "--quiet", "--ff-only"); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *gitRemote) Commit(ctx context.Context, message string) error {
+	if _, err := runGit(ctx, g.dir, "add", "--all"); err != nil {
+		return err
+	}
+	status, err := runGit(ctx, g.dir, "status", "--porcelain")
+	if err != nil {
+		return err
+	}
+	if status == "" {
+		return nil
+	}
+	if _, err := runGit(ctx, g.dir, "commit", "--quiet", "--message", message); err != nil {
+		return err
+	}
+	_, err = runGit(ctx, g.dir, "push", "--quiet", "origin", "HEAD")
+	return err
+}
+
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,96 @@
+package sessionsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// ErrNotFound is returned by remotes when a file does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Remote stores the synced files. Names are slash separated paths relative
+// to the root of the remote.
+type Remote interface {
+	Get(ctx context.Context, name string) ([]byte, error)
+	Put(ctx context.Context, name string, data []byte) error
+	Delete(ctx context.Context, name string) error
+	// Commit publishes the changes made since the remote was opened, for
+	// remotes that don't apply them right away.
+	Commit(ctx context.Context, message string) error
+}
+
+// Open returns the remote configured for syncing. dataDir is where remotes
+// keep local state, such as the clone of a git repository.
+func Open(ctx context.Context, cfg config.Sync, resolver config.VariableResolver, dataDir string) (Remote, error) {
+	remote := strings.TrimSpace(cfg.Remote)
+	switch {
+	case remote == "":
+		return nil, errors.New("no sync remote configured")
+	case strings.HasPrefix(remote, "s3://"):
+		return newS3Remote(ctx, remote, cfg.Region, cfg.Endpoint)
+	case strings.HasPrefix(remote, "git+"), strings.HasPrefix(remote, "git@"):
+		return openGitRemote(ctx, strings.TrimPrefix(remote, "git+"), filepath.Join(dataDir, "sync"))
+	case strings.HasPrefix(remote, "https://"), strings.HasPrefix(remote, "http://"):
+		password := cfg.Password
+		if password != "" && resolver != nil {
+			resolved, err := resolver.ResolveValue(password)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve sync password: %w", err)
+			}
+			password = resolved
+		}
+		return newWebDAVRemote(remote, cfg.Username, password), nil
+	case strings.HasPrefix(remote, "file://"):
+		return dirRemote(strings.TrimPrefix(remote, "file://")), nil
+	case filepath.IsAbs(remote):
+		return dirRemote(remote), nil
+	default:
+		return nil, fmt.Errorf("unsupported sync remote %q", remote)
+	}
+}
+
+// dirRemote stores the files in a local directory, e.g. one shared through a
+// network drive.
+type dirRemote string
+
+func (d dirRemote) path(name string) string {
+	return filepath.Join(string(d), filepath.FromSlash(name))
+}
+
+func (d dirRemote) Get(_ context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(d.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return data, err
+}
+
+func (d dirRemote) Put(_ context.Context, name string, data []byte) error {
+	path := d.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Write through a temporary file so readers never see partial content.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (d dirRemote) Delete(_ context.Context, name string) error {
+	if err := os.Remove(d.path(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (d dirRemote) Commit(context.Context, string) error {
+	return nil
+}
@@ -0,0 +1,135 @@
+package sessionsync
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+)
+
+// s3Remote stores the files in an S3 bucket, or one of an S3 compatible
+// storage, using the credentials of the AWS configuration.
+type s3Remote struct {
+	bucket   string
+	prefix   string
+	region   string
+	endpoint string
+	creds    aws.CredentialsProvider
+	signer   *v4.Signer
+	client   *http.Client
+}
+
+func newS3Remote(ctx context.Context, remote, region, endpoint string) (*s3Remote, error) {
+	u, err := url.Parse(remote)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 remote: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 remote %q: missing bucket", remote)
+	}
+
+	var opts []func(*awsconfig.LoadOptions) error
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	return &s3Remote{
+		bucket:   u.Host,
+		prefix:   strings.Trim(u.Path, "/"),
+		region:   cfg.Region,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		creds:    cfg.Credentials,
+		signer:   v4.NewSigner(),
+		client:   &http.Client{Timeout: 2 * time.Minute},
+	}, nil
+}
+
+// objectURL returns the URL of an object, using virtual hosted buckets on
+// AWS and path style ones on other endpoints.
+func (s *s3Remote) objectURL(name string) string {
+	key := path.Join(s.prefix, name)
+	if s.endpoint != "" {
+		return s.endpoint + "/" + s.bucket + "/" + key
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.region, key)
+}
+
+func (s *s3Remote) do(ctx context.Context, method, name string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(name), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	creds, err := s.creds.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+	if err := s.signer.SignHTTP(ctx, creds, req, payloadHash, "s3", s.region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+	return s.client.Do(req)
+}
+
+func (s *s3Remote) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to get %s: %s", name, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *s3Remote) Put(ctx context.Context, name string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, name, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to put %s: %s", name, resp.Status)
+	}
+	return nil
+}
+
+func (s *s3Remote) Delete(ctx context.Context, name string) error {
+	resp, err := s.do(ctx, http.MethodDelete, name, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to delete %s: %s", name, resp.Status)
+	}
+	return nil
+}
+
+func (s *s3Remote) Commit(context.Context, string) error {
+	return nil
+}
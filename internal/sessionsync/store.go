@@ -0,0 +1,177 @@
+package sessionsync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/db"
+)
+
+// sessionTree returns root followed by all the sessions spawned from it,
+// parents always before their children.
+func sessionTree(ctx context.Context, q *db.Queries, root db.Session) ([]db.Session, error) {
+	sessions := []db.Session{root}
+	for i := 0; i < len(sessions); i++ {
+		children, err := q.ListChildSessions(ctx, sql.NullString{String: sessions[i].ID, Valid: true})
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, children...)
+	}
+	return sessions, nil
+}
+
+func loadBundle(ctx context.Context, q *db.Queries, root db.Session) (Bundle, error) {
+	sessions, err := sessionTree(ctx, q, root)
+	if err != nil {
+		return Bundle{}, err
+	}
+	var bundle Bundle
+	for _, session := range sessions {
+		bundle.Sessions = append(bundle.Sessions, SessionRecord{
+			ID:               session.ID,
+			ParentSessionID:  session.ParentSessionID.String,
+			Title:            session.Title,
+			PromptTokens:     session.PromptTokens,
+			CompletionTokens: session.CompletionTokens,
+			Cost:             session.Cost,
+			SummaryMessageID: session.SummaryMessageID.String,
+			Handoff:          session.Handoff,
+			PinnedMessageIDs: session.PinnedMessageIds,
+			CreatedAt:        session.CreatedAt,
+			UpdatedAt:        session.UpdatedAt,
+		})
+		messages, err := q.ListMessagesBySession(ctx, session.ID)
+		if err != nil {
+			return Bundle{}, err
+		}
+		for _, msg := range messages {
+			bundle.Messages = append(bundle.Messages, messageRecord(msg))
+		}
+	}
+	bundle.normalize()
+	return bundle, nil
+}
+
+func messageRecord(msg db.Message) MessageRecord {
+	return MessageRecord{
+		ID:               msg.ID,
+		SessionID:        msg.SessionID,
+		Role:             msg.Role,
+		Parts:            msg.Parts,
+		Model:            msg.Model.String,
+		Provider:         msg.Provider.String,
+		IsSummaryMessage: msg.IsSummaryMessage != 0,
+		CreatedAt:        msg.CreatedAt,
+		UpdatedAt:        msg.UpdatedAt,
+		FinishedAt:       msg.FinishedAt.Int64,
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// importBundle replaces the local copy of the bundle sessions with the ones
+// of the bundle, so that loading it back yields the same bundle.
+func importBundle(ctx context.Context, conn *sql.DB, bundle Bundle) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+	q := db.New(conn).WithTx(tx)
+
+	// Remove the sessions dropped from the tree, e.g. when a sub-agent
+	// session was deleted on the other side.
+	kept := make(map[string]bool, len(bundle.Sessions))
+	for _, session := range bundle.Sessions {
+		kept[session.ID] = true
+	}
+	if existing, err := q.GetSessionByID(ctx, bundle.Root().ID); err == nil {
+		sessions, err := sessionTree(ctx, q, existing)
+		if err != nil {
+			return err
+		}
+		for _, session := range slices.Backward(sessions) {
+			if kept[session.ID] {
+				continue
+			}
+			if err := q.DeleteSession(ctx, session.ID); err != nil {
+				return err
+			}
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	messages := make(map[string][]MessageRecord, len(bundle.Sessions))
+	for _, msg := range bundle.Messages {
+		messages[msg.SessionID] = append(messages[msg.SessionID], msg)
+	}
+	for _, session := range bundle.Sessions {
+		if err := q.ImportSession(ctx, db.ImportSessionParams{
+			ID:               session.ID,
+			ParentSessionID:  nullString(session.ParentSessionID),
+			Title:            session.Title,
+			PromptTokens:     session.PromptTokens,
+			CompletionTokens: session.CompletionTokens,
+			Cost:             session.Cost,
+			SummaryMessageID: nullString(session.SummaryMessageID),
+			Handoff:          session.Handoff,
+			PinnedMessageIds: session.PinnedMessageIDs,
+			UpdatedAt:        session.UpdatedAt,
+			CreatedAt:        session.CreatedAt,
+		}); err != nil {
+			return err
+		}
+		if err := importMessages(ctx, q, session.ID, messages[session.ID]); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// importMessages replaces the messages of a session when they differ from
+// the given ones. They are inserted again in order, as messages created in
+// the same second are listed in insertion order.
+func importMessages(ctx context.Context, q *db.Queries, sessionID string, messages []MessageRecord) error {
+	existing, err := q.ListMessagesBySession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	current := make([]MessageRecord, 0, len(existing))
+	for _, msg := range existing {
+		current = append(current, messageRecord(msg))
+	}
+	if slices.Equal(current, messages) {
+		return nil
+	}
+
+	if err := q.DeleteSessionMessages(ctx, sessionID); err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		var summary int64
+		if msg.IsSummaryMessage {
+			summary = 1
+		}
+		if err := q.ImportMessage(ctx, db.ImportMessageParams{
+			ID:               msg.ID,
+			SessionID:        sessionID,
+			Role:             msg.Role,
+			Parts:            msg.Parts,
+			Model:            nullString(msg.Model),
+			Provider:         nullString(msg.Provider),
+			IsSummaryMessage: summary,
+			CreatedAt:        msg.CreatedAt,
+			UpdatedAt:        msg.UpdatedAt,
+			FinishedAt:       sql.NullInt64{Int64: msg.FinishedAt, Valid: msg.FinishedAt != 0},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+// Package sessionsync syncs the sessions of a project with a remote, so the
+// same conversation history is available on several machines.
+//
+// Every session is stored in the remote as a bundle holding it, the sessions
+// spawned from it and all their messages, next to an index with the hash of
+// each bundle. The hash of each session at the last sync is kept locally, so
+// a sync only transfers the sessions that changed on either side and merges
+// the ones that changed on both.
+package sessionsync
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/db"
+)
+
+const indexName = "index.json"
+
+// Index lists the sessions stored in a remote.
+type Index struct {
+	Sessions map[string]IndexEntry `json:"sessions"`
+}
+
+type IndexEntry struct {
+	Hash      string `json:"hash"`
+	Title     string `json:"title"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// Action is what a sync did to a session.
+type Action string
+
+const (
+	ActionPushed        Action = "pushed"
+	ActionPulled        Action = "pulled"
+	ActionMerged        Action = "merged"
+	ActionDeletedLocal  Action = "deleted locally"
+	ActionDeletedRemote Action = "deleted from remote"
+)
+
+// Change is a session changed by a sync.
+type Change struct {
+	SessionID string
+	Title     string
+	Action    Action
+}
+
+// Syncer syncs the sessions of a database with a remote.
+type Syncer struct {
+	conn   *sql.DB
+	q      *db.Queries
+	remote Remote
+	// name identifies the remote in the local sync state.
+	name string
+	// prefix is the folder of the remote holding the sessions.
+	prefix string
+}
+
+// New returns a syncer of the sessions in conn with the folder prefix of the
+// remote, which is identified by name in the local sync state.
+func New(conn *sql.DB, remote Remote, name, prefix string) *Syncer {
+	return &Syncer{
+		conn:   conn,
+		q:      db.New(conn),
+		remote: remote,
+		name:   name,
+		prefix: prefix,
+	}
+}
+
+func (s *Syncer) file(name string) string {
+	return path.Join(s.prefix, name)
+}
+
+func bundleFile(sessionID string) string {
+	return path.Join("sessions", sessionID+".json")
+}
+
+// Sync pushes the sessions changed locally, pulls the ones changed in the
+// remote and merges the ones changed on both sides since the last sync.
+// Sessions deleted on one side are deleted on the other, unless they changed
+// there in the meantime.
+func (s *Syncer) Sync(ctx context.Context) ([]Change, error) {
+	index, indexExists, err := s.readIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := s.q.ListSyncStates(ctx, s.name)
+	if err != nil {
+		return nil, err
+	}
+	synced := make(map[string]string, len(states))
+	for _, state := range states {
+		synced[state.SessionID] = state.Hash
+	}
+
+	roots, err := s.q.ListSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	locals := make(map[string]db.Session, len(roots))
+	for _, root := range roots {
+		locals[root.ID] = root
+	}
+
+	ids := slices.Sorted(maps.Keys(locals))
+	for id := range index.Sessions {
+		if _, ok := locals[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+
+	var changes []Change
+	// The sync state is only updated once the index is written, so an
+	// interrupted sync is never mistaken for a deletion.
+	newStates := make(map[string]string)
+	var deletedStates []string
+	for _, id := range ids {
+		base := synced[id]
+		entry, inRemote := index.Sessions[id]
+		root, inLocal := locals[id]
+
+		var local Bundle
+		var localHash string
+		if inLocal {
+			if local, err = loadBundle(ctx, s.q, root); err != nil {
+				return nil, err
+			}
+			localHash = local.Hash()
+		}
+
+		switch {
+		case inLocal && !inRemote:
+			// Only trust a missing entry when the index exists, a new or
+			// wiped remote must not delete anything.
+			if indexExists && base != "" && base == localHash {
+				if err := s.deleteLocal(ctx, root); err != nil {
+					return nil, err
+				}
+				deletedStates = append(deletedStates, id)
+				changes = append(changes, Change{id, root.Title, ActionDeletedLocal})
+				continue
+			}
+			if err := s.push(ctx, local, index); err != nil {
+				return nil, err
+			}
+			newStates[id] = localHash
+			changes = append(changes, Change{id, root.Title, ActionPushed})
+
+		case !inLocal && inRemote:
+			if base != "" && base == entry.Hash {
+				if err := s.remote.Delete(ctx, s.file(bundleFile(id))); err != nil {
+					return nil, err
+				}
+				delete(index.Sessions, id)
+				deletedStates = append(deletedStates, id)
+				changes = append(changes, Change{id, entry.Title, ActionDeletedRemote})
+				continue
+			}
+			remote, err := s.fetch(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			if err := importBundle(ctx, s.conn, remote); err != nil {
+				return nil, err
+			}
+			newStates[id] = entry.Hash
+			changes = append(changes, Change{id, entry.Title, ActionPulled})
+
+		case localHash == entry.Hash:
+			if base != localHash {
+				newStates[id] = localHash
+			}
+
+		case localHash == base:
+			remote, err := s.fetch(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			if err := importBundle(ctx, s.conn, remote); err != nil {
+				return nil, err
+			}
+			newStates[id] = entry.Hash
+			changes = append(changes, Change{id, entry.Title, ActionPulled})
+
+		case entry.Hash == base:
+			if err := s.push(ctx, local, index); err != nil {
+				return nil, err
+			}
+			newStates[id] = localHash
+			changes = append(changes, Change{id, root.Title, ActionPushed})
+
+		default:
+			remote, err := s.fetch(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			merged := Merge(local, remote)
+			if err := importBundle(ctx, s.conn, merged); err != nil {
+				return nil, err
+			}
+			if err := s.push(ctx, merged, index); err != nil {
+				return nil, err
+			}
+			newStates[id] = merged.Hash()
+			changes = append(changes, Change{id, merged.Root().Title, ActionMerged})
+		}
+	}
+
+	if len(changes) > 0 {
+		if err := s.writeIndex(ctx, index); err != nil {
+			return nil, err
+		}
+		if err := s.remote.Commit(ctx, fmt.Sprintf("Sync %d sessions", len(changes))); err != nil {
+			return nil, err
+		}
+	}
+	for id, hash := range newStates {
+		if err := s.q.SetSyncState(ctx, db.SetSyncStateParams{Remote: s.name, SessionID: id, Hash: hash}); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range deletedStates {
+		if err := s.q.DeleteSyncState(ctx, db.DeleteSyncStateParams{Remote: s.name, SessionID: id}); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+func (s *Syncer) readIndex(ctx context.Context) (Index, bool, error) {
+	index := Index{Sessions: make(map[string]IndexEntry)}
+	data, err := s.remote.Get(ctx, s.file(indexName))
+	if errors.Is(err, ErrNotFound) {
+		return index, false, nil
+	}
+	if err != nil {
+		return index, false, fmt.Errorf("failed to read the sync index: %w", err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return index, false, fmt.Errorf("failed to parse the sync index: %w", err)
+	}
+	if index.Sessions == nil {
+		index.Sessions = make(map[string]IndexEntry)
+	}
+	return index, true, nil
+}
+
+func (s *Syncer) writeIndex(ctx context.Context, index Index) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := s.remote.Put(ctx, s.file(indexName), data); err != nil {
+		return fmt.Errorf("failed to write the sync index: %w", err)
+	}
+	return nil
+}
+
+func (s *Syncer) push(ctx context.Context, bundle Bundle, index Index) error {
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return err
+	}
+	root := bundle.Root()
+	if err := s.remote.Put(ctx, s.file(bundleFile(root.ID)), data); err != nil {
+		return fmt.Errorf("failed to push session %s: %w", root.ID, err)
+	}
+	index.Sessions[root.ID] = IndexEntry{
+		Hash:      bundle.Hash(),
+		Title:     root.Title,
+		UpdatedAt: root.UpdatedAt,
+	}
+	return nil
+}
+
+func (s *Syncer) fetch(ctx context.Context, sessionID string) (Bundle, error) {
+	data, err := s.remote.Get(ctx, s.file(bundleFile(sessionID)))
+	if err != nil {
+		return Bundle{}, fmt.Errorf("failed to pull session %s: %w", sessionID, err)
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return Bundle{}, fmt.Errorf("failed to parse session %s: %w", sessionID, err)
+	}
+	if bundle.Root().ID != sessionID {
+		return Bundle{}, fmt.Errorf("session %s holds a different session", sessionID)
+	}
+	bundle.normalize()
+	return bundle, nil
+}
+
+func (s *Syncer) deleteLocal(ctx context.Context, root db.Session) error {
+	sessions, err := sessionTree(ctx, s.q, root)
+	if err != nil {
+		return err
+	}
+	for _, session := range slices.Backward(sessions) {
+		if err := s.q.DeleteSession(ctx, session.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package sessionsync
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+type machine struct {
+	conn     *sql.DB
+	sessions session.Service
+	messages message.Service
+	syncer   *Syncer
+}
+
+func newMachine(t *testing.T, remote Remote) *machine {
+	t.Helper()
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	q := db.New(conn)
+	return &machine{
+		conn:     conn,
+		sessions: session.NewService(q),
+		messages: message.NewService(q, conn),
+		syncer:   New(conn, remote, "shared", "project"),
+	}
+}
+
+func (m *machine) say(t *testing.T, sessionID, text string) {
+	t.Helper()
+	_, err := m.messages.Create(t.Context(), sessionID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: text}},
+	})
+	require.NoError(t, err)
+}
+
+func (m *machine) sync(t *testing.T) map[string]Action {
+	t.Helper()
+	changes, err := m.syncer.Sync(t.Context())
+	require.NoError(t, err)
+	actions := make(map[string]Action, len(changes))
+	for _, change := range changes {
+		actions[change.SessionID] = change.Action
+	}
+	return actions
+}
+
+func (m *machine) bundle(t *testing.T, sessionID string) Bundle {
+	t.Helper()
+	q := db.New(m.conn)
+	root, err := q.GetSessionByID(t.Context(), sessionID)
+	require.NoError(t, err)
+	bundle, err := loadBundle(t.Context(), q, root)
+	require.NoError(t, err)
+	return bundle
+}
+
+func (m *machine) texts(t *testing.T, sessionID string) []string {
+	t.Helper()
+	msgs, err := m.messages.List(t.Context(), sessionID)
+	require.NoError(t, err)
+	var texts []string
+	for _, msg := range msgs {
+		texts = append(texts, msg.Content().Text)
+	}
+	return texts
+}
+
+func TestSync(t *testing.T) {
+	t.Parallel()
+
+	remote := dirRemote(t.TempDir())
+	laptop := newMachine(t, remote)
+	desktop := newMachine(t, remote)
+
+	plan, err := laptop.sessions.Create(t.Context(), "Plan")
+	require.NoError(t, err)
+	laptop.say(t, plan.ID, "hello")
+	task, err := laptop.sessions.CreateTaskSession(t.Context(), "call", plan.ID, "Task")
+	require.NoError(t, err)
+	laptop.say(t, task.ID, "look around")
+
+	require.Equal(t, map[string]Action{plan.ID: ActionPushed}, laptop.sync(t))
+	require.Equal(t, map[string]Action{plan.ID: ActionPulled}, desktop.sync(t))
+	require.Equal(t, laptop.bundle(t, plan.ID).Hash(), desktop.bundle(t, plan.ID).Hash())
+	require.Equal(t, []string{"look around"}, desktop.texts(t, task.ID))
+	require.Empty(t, laptop.sync(t))
+	require.Empty(t, desktop.sync(t))
+
+	t.Run("changes on one side are pushed then pulled", func(t *testing.T) {
+		desktop.say(t, plan.ID, "from the desktop")
+		require.Equal(t, map[string]Action{plan.ID: ActionPushed}, desktop.sync(t))
+		require.Equal(t, map[string]Action{plan.ID: ActionPulled}, laptop.sync(t))
+		require.Equal(t, []string{"hello", "from the desktop"}, laptop.texts(t, plan.ID))
+	})
+
+	t.Run("changes on both sides are merged", func(t *testing.T) {
+		laptop.say(t, plan.ID, "laptop")
+		desktop.say(t, plan.ID, "desktop")
+
+		require.Equal(t, map[string]Action{plan.ID: ActionPushed}, laptop.sync(t))
+		require.Equal(t, map[string]Action{plan.ID: ActionMerged}, desktop.sync(t))
+		require.Equal(t, map[string]Action{plan.ID: ActionPulled}, laptop.sync(t))
+
+		require.ElementsMatch(t, []string{"hello", "from the desktop", "laptop", "desktop"}, laptop.texts(t, plan.ID))
+		require.Equal(t, desktop.texts(t, plan.ID), laptop.texts(t, plan.ID))
+		require.Equal(t, laptop.bundle(t, plan.ID).Hash(), desktop.bundle(t, plan.ID).Hash())
+		require.Empty(t, desktop.sync(t))
+	})
+
+	t.Run("deletions are propagated", func(t *testing.T) {
+		require.NoError(t, desktop.sessions.Delete(t.Context(), task.ID))
+		require.NoError(t, desktop.sessions.Delete(t.Context(), plan.ID))
+		require.Equal(t, map[string]Action{plan.ID: ActionDeletedRemote}, desktop.sync(t))
+		require.Equal(t, map[string]Action{plan.ID: ActionDeletedLocal}, laptop.sync(t))
+
+		_, err := laptop.sessions.Get(t.Context(), plan.ID)
+		require.Error(t, err)
+		_, err = laptop.sessions.Get(t.Context(), task.ID)
+		require.Error(t, err)
+		require.Empty(t, laptop.sync(t))
+	})
+}
+
+func TestSyncKeepsSessionsOfEmptyRemote(t *testing.T) {
+	t.Parallel()
+
+	laptop := newMachine(t, dirRemote(t.TempDir()))
+	plan, err := laptop.sessions.Create(t.Context(), "Plan")
+	require.NoError(t, err)
+	require.Equal(t, map[string]Action{plan.ID: ActionPushed}, laptop.sync(t))
+
+	// A wiped remote must not delete the sessions synced before.
+	laptop.syncer.remote = dirRemote(t.TempDir())
+	require.Equal(t, map[string]Action{plan.ID: ActionPushed}, laptop.sync(t))
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	local := Bundle{
+		Sessions: []SessionRecord{{ID: "root", Title: "Local", UpdatedAt: 20}},
+		Messages: []MessageRecord{
+			{ID: "1", SessionID: "root", Parts: "one", CreatedAt: 1, UpdatedAt: 1},
+			{ID: "2", SessionID: "root", Parts: "local", CreatedAt: 2, UpdatedAt: 2},
+		},
+	}
+	remote := Bundle{
+		Sessions: []SessionRecord{
+			{ID: "root", Title: "Remote", UpdatedAt: 10},
+			{ID: "child", ParentSessionID: "root", Title: "Child", UpdatedAt: 10},
+		},
+		Messages: []MessageRecord{
+			{ID: "1", SessionID: "root", Parts: "one edited", CreatedAt: 1, UpdatedAt: 5},
+			{ID: "3", SessionID: "root", Parts: "remote", CreatedAt: 3, UpdatedAt: 3},
+			{ID: "4", SessionID: "child", Parts: "child", CreatedAt: 1, UpdatedAt: 1},
+		},
+	}
+
+	merged := Merge(local, remote)
+	require.Equal(t, "Local", merged.Root().Title)
+	require.Len(t, merged.Sessions, 2)
+
+	var parts []string
+	for _, msg := range merged.Messages {
+		parts = append(parts, msg.Parts)
+	}
+	require.Equal(t, []string{"one edited", "local", "remote", "child"}, parts)
+	require.Equal(t, merged.Hash(), Merge(local, remote).Hash())
+}
@@ -0,0 +1,112 @@
+package sessionsync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// webDAVRemote stores the files in a WebDAV folder.
+type webDAVRemote struct {
+	baseURL  string
+	username string
+	password string
+	client   *http.Client
+}
+
+func newWebDAVRemote(baseURL, username, password string) *webDAVRemote {
+	return &webDAVRemote{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+func (w *webDAVRemote) do(ctx context.Context, method, name string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, w.baseURL+"/"+name, reader)
+	if err != nil {
+		return nil, err
+	}
+	if w.username != "" || w.password != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+	return w.client.Do(req)
+}
+
+func (w *webDAVRemote) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := w.do(ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to get %s: %s", name, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (w *webDAVRemote) Put(ctx context.Context, name string, data []byte) error {
+	resp, err := w.do(ctx, http.MethodPut, name, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	// Servers answer with a conflict when the parent folder is missing.
+	if resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusNotFound {
+		if err := w.makeParents(ctx, name); err != nil {
+			return err
+		}
+		if resp, err = w.do(ctx, http.MethodPut, name, data); err != nil {
+			return err
+		}
+		resp.Body.Close()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to put %s: %s", name, resp.Status)
+	}
+	return nil
+}
+
+func (w *webDAVRemote) makeParents(ctx context.Context, name string) error {
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		resp, err := w.do(ctx, "MKCOL", strings.Join(parts[:i], "/")+"/", nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		// Existing folders are reported as not allowed.
+		if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
+			return fmt.Errorf("failed to create folder for %s: %s", name, resp.Status)
+		}
+	}
+	return nil
+}
+
+func (w *webDAVRemote) Delete(ctx context.Context, name string) error {
+	resp, err := w.do(ctx, http.MethodDelete, name, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return fmt.Errorf("failed to delete %s: %s", name, resp.Status)
+	}
+	return nil
+}
+
+func (w *webDAVRemote) Commit(context.Context, string) error {
+	return nil
+}
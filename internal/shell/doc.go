@@ -28,3 +28,23 @@ package shell
 //	shell.SetWorkingDir("/tmp")
 //	cwd := shell.GetWorkingDir()
 //	env := shell.GetEnv()
+//
+// 5. For concurrent commands of several sessions:
+//
+//	pool := shell.NewPool(shell.PoolOptions{
+//	    Options: shell.Options{WorkingDir: "/path/to/cwd"},
+//	    Size:    4,
+//	})
+//	defer pool.Close()
+//	sh, err := pool.Checkout(sessionID)
+//	stdout, stderr, err := sh.Exec(ctx, "go test ./...")
+//	pool.Checkin(sh)
+//
+// 6. For commands that need a terminal:
+//
+//	shell := shell.NewShell(nil)
+//	cmd, err := shell.ExecInteractive(ctx, "git rebase -i HEAD~3", shell.InteractiveOptions{
+//	    OnOutput: func(p []byte) { os.Stdout.Write(p) },
+//	})
+//	cmd.Write([]byte(":wq\n"))
+//	output, err := cmd.Wait()
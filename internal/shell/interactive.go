@@ -0,0 +1,235 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creack/pty"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+)
+
+const (
+	defaultTerminalRows = 24
+	defaultTerminalCols = 80
+	// outputDrainTimeout is how long the output is still read once the
+	// command finished, for commands left in the background holding the
+	// terminal open.
+	outputDrainTimeout = time.Second
+)
+
+// InteractiveOptions configure a command run with ExecInteractive.
+type InteractiveOptions struct {
+	// OnOutput is called with the output of the command as it's produced,
+	// from another goroutine. The slice is only valid during the call.
+	OnOutput func([]byte)
+	// Rows and Cols are the size of the terminal, 24x80 by default.
+	Rows, Cols uint16
+}
+
+// InteractiveCommand is a command running in a pseudo-terminal.
+type InteractiveCommand struct {
+	ptmx *os.File
+	done chan struct{}
+
+	mu     sync.Mutex
+	output bytes.Buffer
+	err    error
+}
+
+// ExecInteractive starts a command in a pseudo-terminal, for commands that
+// need one such as editors, pagers or password prompts. Standard output and
+// error are both written to the terminal, and input can be sent while the
+// command runs. The shell is busy until the command finishes, its working
+// directory and environment are updated as with Exec.
+//
+// Pseudo-terminals are not supported on Windows.
+func (s *Shell) ExecInteractive(ctx context.Context, command string, opts InteractiveOptions) (*InteractiveCommand, error) {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return nil, fmt.Errorf("could not open terminal: %w", err)
+	}
+	rows, cols := opts.Rows, opts.Cols
+	if rows == 0 {
+		rows = defaultTerminalRows
+	}
+	if cols == 0 {
+		cols = defaultTerminalCols
+	}
+	if err := pty.Setsize(ptmx, &pty.Winsize{Rows: rows, Cols: cols}); err != nil {
+		ptmx.Close()
+		tty.Close()
+		return nil, fmt.Errorf("could not size terminal: %w", err)
+	}
+
+	c := &InteractiveCommand{
+		ptmx: ptmx,
+		done: make(chan struct{}),
+	}
+	read := make(chan struct{})
+	go func() {
+		defer close(read)
+		c.readOutput(opts.OnOutput)
+	}()
+
+	s.mu.Lock()
+	go func() {
+		defer s.mu.Unlock()
+		defer close(c.done)
+
+		env := s.env
+		if !hasEnv(env, "TERM") {
+			env = append(env[:len(env):len(env)], "TERM=xterm-256color")
+		}
+		err := s.runWith(ctx, command, env,
+			interp.StdIO(tty, tty, tty),
+			interp.ExecHandlers(s.blockHandler(), ttyExecHandler(tty)),
+			interp.OpenHandler(ttyOpenHandler(tty)),
+		)
+		// The output ends once no process holds the terminal anymore.
+		tty.Close()
+		select {
+		case <-read:
+		case <-time.After(outputDrainTimeout):
+		}
+		ptmx.Close()
+		<-read
+
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+	}()
+	return c, nil
+}
+
+func (c *InteractiveCommand) readOutput(onOutput func([]byte)) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := c.ptmx.Read(buf)
+		if n > 0 {
+			c.mu.Lock()
+			c.output.Write(buf[:n])
+			c.mu.Unlock()
+			if onOutput != nil {
+				onOutput(buf[:n])
+			}
+		}
+		// Reading fails once the terminal is closed.
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Write sends input to the command, as if typed in the terminal.
+func (c *InteractiveCommand) Write(p []byte) (int, error) {
+	return c.ptmx.Write(p)
+}
+
+// Resize changes the size of the terminal.
+func (c *InteractiveCommand) Resize(rows, cols uint16) error {
+	return pty.Setsize(c.ptmx, &pty.Winsize{Rows: rows, Cols: cols})
+}
+
+// Done is closed once the command finished.
+func (c *InteractiveCommand) Done() <-chan struct{} {
+	return c.done
+}
+
+// Output returns the output of the command so far.
+func (c *InteractiveCommand) Output() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.output.String()
+}
+
+// Wait waits for the command to finish and returns its output and error.
+func (c *InteractiveCommand) Wait() (string, error) {
+	<-c.done
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.output.String(), c.err
+}
+
+// ttyExecHandler runs programs with the terminal as their controlling
+// terminal, so they can open /dev/tty and receive its signals.
+func ttyExecHandler(tty *os.File) func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			hc := interp.HandlerCtx(ctx)
+			path, err := interp.LookPathDir(hc.Dir, hc.Env, args[0])
+			if err != nil {
+				fmt.Fprintln(hc.Stderr, err)
+				return interp.ExitStatus(127)
+			}
+			cmd := &exec.Cmd{
+				Path:   path,
+				Args:   args,
+				Env:    execEnv(hc.Env),
+				Dir:    hc.Dir,
+				Stdin:  hc.Stdin,
+				Stdout: hc.Stdout,
+				Stderr: hc.Stderr,
+			}
+			setControllingTerminal(cmd, tty)
+			if err := cmd.Start(); err != nil {
+				fmt.Fprintln(hc.Stderr, err)
+				return interp.ExitStatus(127)
+			}
+			stop := context.AfterFunc(ctx, func() {
+				_ = killProcessGroup(cmd)
+			})
+			defer stop()
+
+			err = cmd.Wait()
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return interp.ExitStatus(exitErr.ExitCode())
+			}
+			return err
+		}
+	}
+}
+
+// ttyOpenHandler opens the terminal for redirections to /dev/tty, which
+// would otherwise be the terminal of crush.
+func ttyOpenHandler(tty *os.File) interp.OpenHandlerFunc {
+	open := interp.DefaultOpenHandler()
+	return func(ctx context.Context, path string, flag int, perm os.FileMode) (io.ReadWriteCloser, error) {
+		if path == "/dev/tty" {
+			path = tty.Name()
+		}
+		return open(ctx, path, flag, perm)
+	}
+}
+
+// execEnv returns the exported variables of env for a program.
+func execEnv(env expand.Environ) []string {
+	var list []string
+	for name, vr := range env.Each {
+		if vr.Exported && vr.IsSet() && vr.Kind == expand.String {
+			list = append(list, name+"="+vr.Str)
+		}
+	}
+	return list
+}
+
+func hasEnv(env []string, name string) bool {
+	for _, kv := range env {
+		if strings.HasPrefix(kv, name+"=") {
+			return true
+		}
+	}
+	return false
+}
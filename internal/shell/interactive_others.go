@@ -0,0 +1,28 @@
+//go:build !windows
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setControllingTerminal starts the command in a new session whose
+// controlling terminal is tty.
+func setControllingTerminal(cmd *exec.Cmd, tty *os.File) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if cmd.Stdin == tty {
+		cmd.SysProcAttr.Ctty = 0
+		return
+	}
+	// The terminal is passed as an extra file when the standard input of
+	// the command is redirected.
+	cmd.ExtraFiles = []*os.File{tty}
+	cmd.SysProcAttr.Ctty = 3
+}
+
+// killProcessGroup kills the command and the processes it started.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
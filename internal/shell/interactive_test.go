@@ -0,0 +1,84 @@
+package shell
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecInteractive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("pseudo-terminals are not supported on Windows")
+	}
+	t.Parallel()
+
+	dir := t.TempDir()
+	sh := NewShell(&Options{WorkingDir: dir})
+
+	var mu sync.Mutex
+	var streamed strings.Builder
+	cmd, err := sh.ExecInteractive(t.Context(), `[ -t 0 ] && echo tty; read -r name; echo "hello $name"; mkdir sub && cd sub`, InteractiveOptions{
+		OnOutput: func(p []byte) {
+			mu.Lock()
+			streamed.Write(p)
+			mu.Unlock()
+		},
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(cmd.Output(), "tty")
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = cmd.Write([]byte("crush\n"))
+	require.NoError(t, err)
+
+	output, err := cmd.Wait()
+	require.NoError(t, err)
+	require.Contains(t, output, "hello crush")
+	mu.Lock()
+	require.Equal(t, output, streamed.String())
+	mu.Unlock()
+	require.Equal(t, dir+"/sub", sh.GetWorkingDir())
+}
+
+func TestExecInteractiveProgram(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("pseudo-terminals are not supported on Windows")
+	}
+	t.Parallel()
+
+	sh := NewShell(&Options{WorkingDir: t.TempDir(), Env: []string{"PATH=" + os.Getenv("PATH")}})
+	cmd, err := sh.ExecInteractive(t.Context(), "stty size < /dev/tty; echo $TERM; exit 3", InteractiveOptions{Rows: 40, Cols: 100})
+	require.NoError(t, err)
+	output, err := cmd.Wait()
+	require.Equal(t, 3, ExitCode(err))
+	require.Contains(t, output, "40 100")
+	require.Contains(t, output, "xterm-256color")
+}
+
+func TestExecInteractiveCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("pseudo-terminals are not supported on Windows")
+	}
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	sh := NewShell(&Options{WorkingDir: t.TempDir()})
+	cmd, err := sh.ExecInteractive(ctx, "cat", InteractiveOptions{})
+	require.NoError(t, err)
+	cancel()
+
+	select {
+	case <-cmd.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("command wasn't interrupted")
+	}
+	_, err = cmd.Wait()
+	require.True(t, IsInterrupt(err))
+}
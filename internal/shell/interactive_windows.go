@@ -0,0 +1,17 @@
+//go:build windows
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setControllingTerminal does nothing, pseudo-terminals are not supported
+// on Windows.
+func setControllingTerminal(cmd *exec.Cmd, tty *os.File) {}
+
+// killProcessGroup kills the command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
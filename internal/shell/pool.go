@@ -0,0 +1,254 @@
+package shell
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned when checking out a shell from a closed pool.
+var ErrPoolClosed = errors.New("shell pool is closed")
+
+const (
+	defaultPoolSize        = 4
+	defaultPoolIdleTimeout = 10 * time.Minute
+	defaultPoolMaxLifetime = time.Hour
+)
+
+// PoolOptions configure a pool of shells.
+type PoolOptions struct {
+	// Options are used to create the shells of the pool.
+	Options
+	// Size is how many shells are kept ready to be checked out, defaults
+	// to 4.
+	Size int
+	// IdleTimeout is how long a shell, and the state of its session, is
+	// kept once checked in. Defaults to 10 minutes.
+	IdleTimeout time.Duration
+	// MaxLifetime is how long a shell is used before being replaced by a
+	// new one with the same state. Defaults to an hour.
+	MaxLifetime time.Duration
+}
+
+// Pool keeps shells ready for sessions to run commands in. The working
+// directory and environment of the commands of a session carry over from
+// one checkout to the next, and concurrent checkouts for the same session
+// get different shells starting from the last state of the session.
+type Pool struct {
+	opts PoolOptions
+	now  func() time.Time
+
+	mu sync.Mutex
+	// warm are the shells not used by any session yet.
+	warm     []*pooledShell
+	sessions map[string]*sessionState
+	// checkedOut are the shells in use, with their session.
+	checkedOut map[*Shell]*pooledShell
+	closed     bool
+	done       chan struct{}
+}
+
+type pooledShell struct {
+	shell     *Shell
+	sessionID string
+	created   time.Time
+	lastUsed  time.Time
+}
+
+// sessionState is the state of the shells of a session.
+type sessionState struct {
+	cwd      string
+	env      []string
+	idle     []*pooledShell
+	lastUsed time.Time
+}
+
+// NewPool creates a pool of shells and starts evicting those idle or too
+// old in the background, until the pool is closed.
+func NewPool(opts PoolOptions) *Pool {
+	if opts.Size <= 0 {
+		opts.Size = defaultPoolSize
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = defaultPoolIdleTimeout
+	}
+	if opts.MaxLifetime <= 0 {
+		opts.MaxLifetime = defaultPoolMaxLifetime
+	}
+	p := &Pool{
+		opts:       opts,
+		now:        time.Now,
+		sessions:   make(map[string]*sessionState),
+		checkedOut: make(map[*Shell]*pooledShell),
+		done:       make(chan struct{}),
+	}
+	p.fill()
+	go p.evictLoop(min(opts.IdleTimeout, opts.MaxLifetime) / 2)
+	return p
+}
+
+// Checkout returns a shell with the state of the session. It must be given
+// back with Checkin once the commands are done.
+func (p *Pool) Checkout(sessionID string) (*Shell, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+
+	now := p.now()
+	state, ok := p.sessions[sessionID]
+	if ok {
+		for len(state.idle) > 0 {
+			ps := state.idle[len(state.idle)-1]
+			state.idle = state.idle[:len(state.idle)-1]
+			if p.expired(ps, now) {
+				continue
+			}
+			return p.checkout(ps, state, now), nil
+		}
+	} else {
+		state = &sessionState{}
+		p.sessions[sessionID] = state
+	}
+
+	var ps *pooledShell
+	if len(p.warm) > 0 {
+		ps = p.warm[len(p.warm)-1]
+		p.warm = p.warm[:len(p.warm)-1]
+	} else {
+		ps = p.newShell(now)
+	}
+	ps.sessionID = sessionID
+	if state.cwd != "" {
+		ps.shell.cwd = state.cwd
+		ps.shell.env = slices.Clone(state.env)
+	}
+	p.fill()
+	return p.checkout(ps, state, now), nil
+}
+
+func (p *Pool) checkout(ps *pooledShell, state *sessionState, now time.Time) *Shell {
+	ps.lastUsed = now
+	state.lastUsed = now
+	p.checkedOut[ps.shell] = ps
+	return ps.shell
+}
+
+// Checkin gives back a shell returned by Checkout, saving its state as the
+// state of its session.
+func (p *Pool) Checkin(sh *Shell) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ps, ok := p.checkedOut[sh]
+	if !ok {
+		return
+	}
+	delete(p.checkedOut, sh)
+	if p.closed {
+		return
+	}
+
+	now := p.now()
+	state, ok := p.sessions[ps.sessionID]
+	if !ok {
+		state = &sessionState{}
+		p.sessions[ps.sessionID] = state
+	}
+	state.cwd = sh.GetWorkingDir()
+	state.env = sh.GetEnv()
+	state.lastUsed = now
+	ps.lastUsed = now
+	// Too old shells are dropped, the next checkout starts a new one with
+	// the state of the session.
+	if !p.expired(ps, now) {
+		state.idle = append(state.idle, ps)
+	}
+}
+
+// Evict drops the shells idle for longer than the idle timeout or older
+// than the maximum lifetime, and forgets the state of the sessions that
+// have been idle for too long. It runs periodically in the background.
+func (p *Pool) Evict() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+
+	now := p.now()
+	for id, state := range p.sessions {
+		state.idle = slices.DeleteFunc(state.idle, func(ps *pooledShell) bool {
+			return p.expired(ps, now)
+		})
+		if len(state.idle) == 0 && !p.inUse(id) && now.Sub(state.lastUsed) > p.opts.IdleTimeout {
+			delete(p.sessions, id)
+		}
+	}
+	p.warm = slices.DeleteFunc(p.warm, func(ps *pooledShell) bool {
+		return now.Sub(ps.created) > p.opts.MaxLifetime
+	})
+	p.fill()
+}
+
+// Close stops the pool. Shells checked out can still be used, but checking
+// out more fails.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.done)
+	p.warm = nil
+	p.sessions = nil
+}
+
+func (p *Pool) evictLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.Evict()
+		}
+	}
+}
+
+// expired reports whether the shell was idle or lived for too long.
+func (p *Pool) expired(ps *pooledShell, now time.Time) bool {
+	return now.Sub(ps.lastUsed) > p.opts.IdleTimeout || now.Sub(ps.created) > p.opts.MaxLifetime
+}
+
+func (p *Pool) inUse(sessionID string) bool {
+	for _, ps := range p.checkedOut {
+		if ps.sessionID == sessionID {
+			return true
+		}
+	}
+	return false
+}
+
+// fill creates shells until enough are ready for new sessions.
+func (p *Pool) fill() {
+	now := p.now()
+	for len(p.warm) < p.opts.Size {
+		p.warm = append(p.warm, p.newShell(now))
+	}
+}
+
+func (p *Pool) newShell(now time.Time) *pooledShell {
+	opts := p.opts.Options
+	if opts.Env != nil {
+		opts.Env = slices.Clone(opts.Env)
+	}
+	return &pooledShell{
+		shell:    NewShell(&opts),
+		created:  now,
+		lastUsed: now,
+	}
+}
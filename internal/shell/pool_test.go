@@ -0,0 +1,127 @@
+package shell
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPool returns a pool whose clock is moved by the returned function.
+func newTestPool(t *testing.T, opts PoolOptions) (*Pool, func(time.Duration)) {
+	t.Helper()
+	p := NewPool(opts)
+	t.Cleanup(p.Close)
+	now := time.Now()
+	p.mu.Lock()
+	p.now = func() time.Time { return now }
+	p.mu.Unlock()
+	return p, func(d time.Duration) {
+		p.mu.Lock()
+		now = now.Add(d)
+		p.mu.Unlock()
+	}
+}
+
+func TestPoolKeepsSessionState(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	p, _ := newTestPool(t, PoolOptions{Options: Options{WorkingDir: dir}, Size: 2})
+	require.Len(t, p.warm, 2)
+
+	sh, err := p.Checkout("a")
+	require.NoError(t, err)
+	require.Len(t, p.warm, 2, "warm shells are refilled")
+	_, _, err = sh.Exec(t.Context(), "mkdir sub && cd sub && export FOO=bar")
+	require.NoError(t, err)
+	p.Checkin(sh)
+
+	again, err := p.Checkout("a")
+	require.NoError(t, err)
+	require.Same(t, sh, again, "idle shells of the session are reused")
+
+	// A concurrent checkout gets another shell with the state of the
+	// session.
+	other, err := p.Checkout("a")
+	require.NoError(t, err)
+	require.NotSame(t, sh, other)
+	require.Equal(t, filepath.Join(dir, "sub"), other.GetWorkingDir())
+	stdout, _, err := other.Exec(t.Context(), "echo $FOO")
+	require.NoError(t, err)
+	require.Equal(t, "bar", strings.TrimSpace(stdout))
+
+	fresh, err := p.Checkout("b")
+	require.NoError(t, err)
+	require.Equal(t, dir, fresh.GetWorkingDir(), "sessions don't share state")
+}
+
+func TestPoolEvictsIdleShells(t *testing.T) {
+	t.Parallel()
+
+	p, advance := newTestPool(t, PoolOptions{IdleTimeout: time.Minute})
+	sh, err := p.Checkout("a")
+	require.NoError(t, err)
+	p.Checkin(sh)
+
+	advance(30 * time.Second)
+	p.Evict()
+	require.Len(t, p.sessions["a"].idle, 1)
+
+	advance(time.Minute)
+	p.Evict()
+	require.NotContains(t, p.sessions, "a", "the state of idle sessions is forgotten")
+
+	// Sessions with shells checked out are kept.
+	sh, err = p.Checkout("b")
+	require.NoError(t, err)
+	advance(2 * time.Minute)
+	p.Evict()
+	require.Contains(t, p.sessions, "b")
+	p.Checkin(sh)
+}
+
+func TestPoolRecyclesOldShells(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	p, advance := newTestPool(t, PoolOptions{Options: Options{WorkingDir: dir}, Size: 1, IdleTimeout: time.Hour, MaxLifetime: time.Minute})
+	sh, err := p.Checkout("a")
+	require.NoError(t, err)
+	_, _, err = sh.Exec(t.Context(), "mkdir sub && cd sub")
+	require.NoError(t, err)
+
+	advance(2 * time.Minute)
+	p.Checkin(sh)
+	require.Empty(t, p.sessions["a"].idle, "old shells aren't kept")
+
+	recycled, err := p.Checkout("a")
+	require.NoError(t, err)
+	require.NotSame(t, sh, recycled)
+	require.Equal(t, filepath.Join(dir, "sub"), recycled.GetWorkingDir())
+	p.Checkin(recycled)
+
+	warm := p.warm[0]
+	advance(2 * time.Minute)
+	p.Evict()
+	require.Len(t, p.warm, 1)
+	require.NotSame(t, warm, p.warm[0], "old warm shells are replaced")
+}
+
+func TestPoolClose(t *testing.T) {
+	t.Parallel()
+
+	p := NewPool(PoolOptions{})
+	sh, err := p.Checkout("a")
+	require.NoError(t, err)
+	p.Close()
+	p.Close()
+
+	_, err = p.Checkout("a")
+	require.ErrorIs(t, err, ErrPoolClosed)
+	p.Checkin(sh)
+	_, _, err = sh.Exec(t.Context(), "true")
+	require.NoError(t, err, "shells checked out can still be used")
+}
@@ -1,8 +1,9 @@
 // Package shell provides cross-platform shell execution capabilities.
 //
-// This package offers two main types:
+// This package offers three main types:
 // - Shell: A general-purpose shell executor for one-off or managed commands
 // - PersistentShell: A singleton shell that maintains state across the application
+// - Pool: Shells kept ready for concurrent commands, with state per session
 //
 // WINDOWS COMPATIBILITY:
 // This implementation provides both POSIX shell emulation (mvdan.cc/sh/v3),
@@ -15,6 +16,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"slices"
 	"strings"
@@ -100,7 +102,26 @@ func (s *Shell) Exec(ctx context.Context, command string) (string, string, error
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return s.execPOSIX(ctx, command)
+	return s.execPOSIX(ctx, command, nil)
+}
+
+// ExecStream executes a command in the shell like Exec, also writing its
+// output to out as it's produced. Standard output and error may be written
+// to out concurrently.
+func (s *Shell) ExecStream(ctx context.Context, command string, out io.Writer) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.execPOSIX(ctx, command, out)
+}
+
+// Run executes a command in the shell with the given standard input and
+// output, without capturing it.
+func (s *Shell) Run(ctx context.Context, command string, stdin io.Reader, stdout, stderr io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.run(ctx, command, stdin, stdout, stderr)
 }
 
 // GetWorkingDir returns the current working directory
@@ -229,22 +250,39 @@ func (s *Shell) blockHandler() func(next interp.ExecHandlerFunc) interp.ExecHand
 }
 
 // execPOSIX executes commands using POSIX shell emulation (cross-platform)
-func (s *Shell) execPOSIX(ctx context.Context, command string) (string, string, error) {
+func (s *Shell) execPOSIX(ctx context.Context, command string, out io.Writer) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	var stdoutW, stderrW io.Writer = &stdout, &stderr
+	if out != nil {
+		stdoutW = io.MultiWriter(&stdout, out)
+		stderrW = io.MultiWriter(&stderr, out)
+	}
+	err := s.run(ctx, command, nil, stdoutW, stderrW)
+	return stdout.String(), stderr.String(), err
+}
+
+func (s *Shell) run(ctx context.Context, command string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return s.runWith(ctx, command, s.env,
+		interp.StdIO(stdin, stdout, stderr),
+		interp.ExecHandlers(s.blockHandler(), coreutils.ExecHandler),
+	)
+}
+
+// runWith runs a command with the given environment and options for its
+// input, output and the programs it executes.
+func (s *Shell) runWith(ctx context.Context, command string, env []string, opts ...interp.RunnerOption) error {
 	line, err := syntax.NewParser().Parse(strings.NewReader(command), "")
 	if err != nil {
-		return "", "", fmt.Errorf("could not parse command: %w", err)
+		return fmt.Errorf("could not parse command: %w", err)
 	}
 
-	var stdout, stderr bytes.Buffer
-	runner, err := interp.New(
-		interp.StdIO(nil, &stdout, &stderr),
+	runner, err := interp.New(append([]interp.RunnerOption{
 		interp.Interactive(false),
-		interp.Env(expand.ListEnviron(s.env...)),
+		interp.Env(expand.ListEnviron(env...)),
 		interp.Dir(s.cwd),
-		interp.ExecHandlers(s.blockHandler(), coreutils.ExecHandler),
-	)
+	}, opts...)...)
 	if err != nil {
-		return "", "", fmt.Errorf("could not run command: %w", err)
+		return fmt.Errorf("could not run command: %w", err)
 	}
 
 	err = runner.Run(ctx, line)
@@ -254,7 +292,7 @@ func (s *Shell) execPOSIX(ctx context.Context, command string) (string, string,
 		s.env = append(s.env, fmt.Sprintf("%s=%s", name, vr.Str))
 	}
 	s.logger.InfoPersist("POSIX command finished", "command", command, "err", err)
-	return stdout.String(), stderr.String(), err
+	return err
 }
 
 // IsInterrupt checks if an error is due to interruption
@@ -1,6 +1,7 @@
 package shell
 
 import (
+	"bytes"
 	"context"
 	"path/filepath"
 	"runtime"
@@ -118,3 +119,18 @@ func TestCrossPlatformExecution(t *testing.T) {
 		t.Errorf("Echo output should contain 'hello', got: %q", stdout)
 	}
 }
+
+func TestExecStream(t *testing.T) {
+	shell := NewShell(&Options{WorkingDir: t.TempDir()})
+	var out bytes.Buffer
+	stdout, stderr, err := shell.ExecStream(t.Context(), "echo out; echo err >&2", &out)
+	if err != nil {
+		t.Fatalf("failed to run command: %v", err)
+	}
+	if stdout != "out\n" || stderr != "err\n" {
+		t.Fatalf("expected separate stdout and stderr, got %q and %q", stdout, stderr)
+	}
+	if out.String() != "out\nerr\n" {
+		t.Fatalf("expected streamed output %q, got %q", "out\nerr\n", out.String())
+	}
+}
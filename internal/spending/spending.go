@@ -0,0 +1,220 @@
+// Package spending records what model calls cost, to report the spending
+// per day, week and month and warn when it goes over the configured limits.
+package spending
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+// Record is the cost of a model call, in US dollars.
+type Record struct {
+	ID        string
+	SessionID string
+	Provider  string
+	Model     string
+	Cost      float64
+	CreatedAt int64
+}
+
+// Period is a calendar period spending is summed over.
+type Period string
+
+const (
+	Day   Period = "day"
+	Week  Period = "week"
+	Month Period = "month"
+)
+
+// Periods are the periods spending is reported for, shortest first.
+var Periods = []Period{Day, Week, Month}
+
+// Start returns the start of the period containing t, in the location of
+// t. Weeks start on Monday.
+func (p Period) Start(t time.Time) time.Time {
+	year, month, day := t.Date()
+	switch p {
+	case Week:
+		weekday := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-weekday, 0, 0, 0, 0, t.Location())
+	case Month:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// Limit returns the limit of the period, zero when there is none.
+func (p Period) Limit(limits config.SpendingLimits) float64 {
+	switch p {
+	case Day:
+		return limits.Daily
+	case Week:
+		return limits.Weekly
+	case Month:
+		return limits.Monthly
+	}
+	return 0
+}
+
+// Service records the cost of model calls.
+type Service interface {
+	pubsub.Suscriber[Record]
+	Record(ctx context.Context, record Record) (Record, error)
+	// Since returns the records created at or after t, oldest first.
+	Since(ctx context.Context, t time.Time) ([]Record, error)
+}
+
+type service struct {
+	*pubsub.Broker[Record]
+	q db.Querier
+}
+
+func NewService(q db.Querier) Service {
+	return &service{
+		Broker: pubsub.NewBroker[Record](),
+		q:      q,
+	}
+}
+
+func (s *service) Record(ctx context.Context, record Record) (Record, error) {
+	dbRecord, err := s.q.CreateSpending(ctx, db.CreateSpendingParams{
+		ID:        uuid.New().String(),
+		SessionID: record.SessionID,
+		Provider:  record.Provider,
+		Model:     record.Model,
+		Cost:      record.Cost,
+	})
+	if err != nil {
+		return Record{}, err
+	}
+	record = fromDBItem(dbRecord)
+	s.Publish(pubsub.CreatedEvent, record)
+	return record, nil
+}
+
+func (s *service) Since(ctx context.Context, t time.Time) ([]Record, error) {
+	dbRecords, err := s.q.ListSpendingSince(ctx, t.Unix())
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, len(dbRecords))
+	for i, dbRecord := range dbRecords {
+		records[i] = fromDBItem(dbRecord)
+	}
+	return records, nil
+}
+
+func fromDBItem(item db.Spending) Record {
+	return Record{
+		ID:        item.ID,
+		SessionID: item.SessionID,
+		Provider:  item.Provider,
+		Model:     item.Model,
+		Cost:      item.Cost,
+		CreatedAt: item.CreatedAt,
+	}
+}
+
+// Report sums the spending of the current day, week and month.
+type Report struct {
+	// Totals are the spending of each period.
+	Totals map[Period]float64
+	// Days are the spending of each day of the month, oldest first. Days
+	// without spending are left out.
+	Days []Total
+	// Models are the spending of each model during the month, most
+	// expensive first.
+	Models []Total
+}
+
+// Total is the spending of a day or a model.
+type Total struct {
+	Name string
+	Cost float64
+}
+
+// Since returns the start of the records needed to report the spending of
+// the periods containing now.
+func Since(now time.Time) time.Time {
+	since := Month.Start(now)
+	if week := Week.Start(now); week.Before(since) {
+		since = week
+	}
+	return since
+}
+
+// NewReport sums the records by period, day and model. The records must
+// start at Since(now) at the latest.
+func NewReport(records []Record, now time.Time) Report {
+	report := Report{Totals: make(map[Period]float64, len(Periods))}
+	monthStart := Month.Start(now)
+	var days, models []Total
+	for _, record := range records {
+		at := time.Unix(record.CreatedAt, 0).In(now.Location())
+		for _, period := range Periods {
+			if !at.Before(period.Start(now)) {
+				report.Totals[period] += record.Cost
+			}
+		}
+		if at.Before(monthStart) {
+			continue
+		}
+		days = addTotal(days, at.Format(time.DateOnly), record.Cost)
+		models = addTotal(models, record.Provider+"/"+record.Model, record.Cost)
+	}
+	slices.SortFunc(days, func(a, b Total) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	slices.SortFunc(models, func(a, b Total) int {
+		return cmp.Or(cmp.Compare(b.Cost, a.Cost), cmp.Compare(a.Name, b.Name))
+	})
+	report.Days = days
+	report.Models = models
+	return report
+}
+
+func addTotal(totals []Total, name string, cost float64) []Total {
+	for i := range totals {
+		if totals[i].Name == name {
+			totals[i].Cost += cost
+			return totals
+		}
+	}
+	return append(totals, Total{Name: name, Cost: cost})
+}
+
+// Alert is a period whose spending reached its limit.
+type Alert struct {
+	Period Period
+	// Start is the start of the period.
+	Start time.Time
+	Spent float64
+	Limit float64
+}
+
+// Alerts returns the periods containing now whose spending reached their
+// limit.
+func (r Report) Alerts(limits config.SpendingLimits, now time.Time) []Alert {
+	var alerts []Alert
+	for _, period := range Periods {
+		limit := period.Limit(limits)
+		if limit <= 0 || r.Totals[period] < limit {
+			continue
+		}
+		alerts = append(alerts, Alert{
+			Period: period,
+			Start:  period.Start(now),
+			Spent:  r.Totals[period],
+			Limit:  limit,
+		})
+	}
+	return alerts
+}
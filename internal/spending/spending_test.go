@@ -0,0 +1,75 @@
+package spending
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	svc := NewService(db.New(conn))
+	events := svc.Subscribe(t.Context())
+
+	start := time.Now().Add(-time.Second)
+	record, err := svc.Record(t.Context(), Record{SessionID: "session", Provider: "openai", Model: "gpt-4o", Cost: 0.25})
+	require.NoError(t, err)
+	require.NotEmpty(t, record.ID)
+	require.GreaterOrEqual(t, record.CreatedAt, start.Unix())
+
+	event := <-events
+	require.Equal(t, record, event.Payload)
+
+	records, err := svc.Since(t.Context(), start)
+	require.NoError(t, err)
+	require.Equal(t, []Record{record}, records)
+
+	records, err = svc.Since(t.Context(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
+
+func TestPeriodStart(t *testing.T) {
+	t.Parallel()
+
+	// A Sunday.
+	now := time.Date(2025, time.March, 2, 15, 4, 5, 0, time.UTC)
+	require.Equal(t, time.Date(2025, time.March, 2, 0, 0, 0, 0, time.UTC), Day.Start(now))
+	require.Equal(t, time.Date(2025, time.February, 24, 0, 0, 0, 0, time.UTC), Week.Start(now))
+	require.Equal(t, time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), Month.Start(now))
+	require.Equal(t, Week.Start(now), Since(now), "the week started the previous month")
+}
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.March, 2, 15, 0, 0, 0, time.UTC)
+	at := func(day, hour int) int64 {
+		return time.Date(2025, time.March, day, hour, 0, 0, 0, time.UTC).Unix()
+	}
+	records := []Record{
+		{Provider: "openai", Model: "gpt-4o", Cost: 4, CreatedAt: time.Date(2025, time.February, 28, 10, 0, 0, 0, time.UTC).Unix()},
+		{Provider: "openai", Model: "gpt-4o", Cost: 1, CreatedAt: at(1, 9)},
+		{Provider: "anthropic", Model: "claude", Cost: 3, CreatedAt: at(2, 8)},
+		{Provider: "openai", Model: "gpt-4o", Cost: 0.5, CreatedAt: at(2, 14)},
+	}
+	report := NewReport(records, now)
+	require.Equal(t, map[Period]float64{Day: 3.5, Week: 8.5, Month: 4.5}, report.Totals)
+	require.Equal(t, []Total{{Name: "2025-03-01", Cost: 1}, {Name: "2025-03-02", Cost: 3.5}}, report.Days)
+	require.Equal(t, []Total{{Name: "anthropic/claude", Cost: 3}, {Name: "openai/gpt-4o", Cost: 1.5}}, report.Models)
+
+	alerts := report.Alerts(config.SpendingLimits{Daily: 3, Weekly: 10, Monthly: 4.5}, now)
+	require.Equal(t, []Alert{
+		{Period: Day, Start: Day.Start(now), Spent: 3.5, Limit: 3},
+		{Period: Month, Start: Month.Start(now), Spent: 4.5, Limit: 4.5},
+	}, alerts)
+	require.Empty(t, report.Alerts(config.SpendingLimits{}, now))
+}
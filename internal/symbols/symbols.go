@@ -0,0 +1,209 @@
+// Package symbols indexes the functions and types declared in the files of
+// the workspace, to reference them in prompts.
+//
+// Declarations are found with a few patterns per language rather than by
+// parsing, which is fast enough to run on every completion and good enough
+// to locate them, at the cost of missing some unusual declarations.
+package symbols
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// maxFileSize is the size of the largest file indexed, larger ones are
+	// usually generated.
+	maxFileSize = 1 << 20
+	// maxSymbols is the most symbols kept in the index.
+	maxSymbols = 50_000
+)
+
+// Kind is the kind of a symbol.
+type Kind string
+
+const (
+	KindFunction Kind = "func"
+	KindMethod   Kind = "method"
+	KindType     Kind = "type"
+	KindClass    Kind = "class"
+)
+
+// Symbol is a declaration in a file.
+type Symbol struct {
+	Name string
+	Kind Kind
+	// Path is relative to the root of the index and slash separated.
+	Path string
+	Line int
+}
+
+// pattern finds the declarations of a kind, the name is its first group.
+type pattern struct {
+	kind Kind
+	re   *regexp.Regexp
+}
+
+var (
+	goPatterns = []pattern{
+		{KindMethod, regexp.MustCompile(`^func\s+\([^)]*\)\s*([A-Za-z_]\w*)`)},
+		{KindFunction, regexp.MustCompile(`^func\s+([A-Za-z_]\w*)`)},
+		{KindType, regexp.MustCompile(`^type\s+([A-Za-z_]\w*)`)},
+	}
+	pythonPatterns = []pattern{
+		{KindFunction, regexp.MustCompile(`^\s*(?:async\s+)?def\s+([A-Za-z_]\w*)`)},
+		{KindClass, regexp.MustCompile(`^\s*class\s+([A-Za-z_]\w*)`)},
+	}
+	jsPatterns = []pattern{
+		{KindFunction, regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\*?\s+([A-Za-z_$][\w$]*)`)},
+		{KindClass, regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)`)},
+		{KindType, regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?(?:interface|type|enum)\s+([A-Za-z_$][\w$]*)`)},
+		{KindFunction, regexp.MustCompile(`^\s*(?:export\s+)?const\s+([A-Za-z_$][\w$]*)\s*=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*=>|[A-Za-z_$][\w$]*\s*=>)`)},
+	}
+	rustPatterns = []pattern{
+		{KindFunction, regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?fn\s+([A-Za-z_]\w*)`)},
+		{KindType, regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:struct|enum|trait|type|union)\s+([A-Za-z_]\w*)`)},
+	}
+	rubyPatterns = []pattern{
+		{KindFunction, regexp.MustCompile(`^\s*def\s+(?:self\.)?([A-Za-z_]\w*[?!=]?)`)},
+		{KindClass, regexp.MustCompile(`^\s*(?:class|module)\s+([A-Z]\w*)`)},
+	}
+	javaPatterns = []pattern{
+		{KindClass, regexp.MustCompile(`^\s*(?:(?:public|private|protected|internal|static|abstract|final|sealed|partial|data|open)\s+)*(?:class|interface|enum|record|struct|object)\s+([A-Za-z_]\w*)`)},
+	}
+
+	patterns = map[string][]pattern{
+		".go":   goPatterns,
+		".py":   pythonPatterns,
+		".js":   jsPatterns,
+		".jsx":  jsPatterns,
+		".mjs":  jsPatterns,
+		".cjs":  jsPatterns,
+		".ts":   jsPatterns,
+		".tsx":  jsPatterns,
+		".mts":  jsPatterns,
+		".rs":   rustPatterns,
+		".rb":   rubyPatterns,
+		".java": javaPatterns,
+		".kt":   javaPatterns,
+		".cs":   javaPatterns,
+	}
+)
+
+// Supported reports whether symbols are extracted from the file.
+func Supported(file string) bool {
+	_, ok := patterns[path.Ext(file)]
+	return ok
+}
+
+// Extract returns the symbols declared in the content of the file.
+func Extract(file string, content []byte) []Symbol {
+	langPatterns, ok := patterns[path.Ext(file)]
+	if !ok {
+		return nil
+	}
+	var symbols []Symbol
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFileSize)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		for _, p := range langPatterns {
+			if match := p.re.FindStringSubmatch(text); match != nil {
+				symbols = append(symbols, Symbol{Name: match[1], Kind: p.kind, Path: file, Line: line})
+				break
+			}
+		}
+	}
+	return symbols
+}
+
+// Index caches the symbols of the files of a directory. Files are only
+// read again once they changed.
+type Index struct {
+	root  string
+	files func() ([]string, error)
+
+	mu    sync.Mutex
+	cache map[string]fileSymbols
+}
+
+// fileSymbols are the symbols of a file, as of when it had its size and
+// modification time.
+type fileSymbols struct {
+	size    int64
+	modTime time.Time
+	symbols []Symbol
+}
+
+// NewIndex creates an index of the symbols of the files under root. files
+// returns the paths of the files to index, relative to root and slash
+// separated.
+func NewIndex(root string, files func() ([]string, error)) *Index {
+	return &Index{
+		root:  root,
+		files: files,
+		cache: make(map[string]fileSymbols),
+	}
+}
+
+// Symbols returns the symbols of the files, sorted by name.
+func (idx *Index) Symbols() ([]Symbol, error) {
+	files, err := idx.files()
+	if err != nil {
+		return nil, err
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var symbols []Symbol
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		if !Supported(file) || len(symbols) >= maxSymbols {
+			continue
+		}
+		seen[file] = true
+		symbols = append(symbols, idx.load(file)...)
+	}
+	// Forget the files that are gone.
+	for file := range idx.cache {
+		if !seen[file] {
+			delete(idx.cache, file)
+		}
+	}
+
+	symbols = symbols[:min(len(symbols), maxSymbols)]
+	slices.SortStableFunc(symbols, func(a, b Symbol) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.Path, b.Path), cmp.Compare(a.Line, b.Line))
+	})
+	return symbols, nil
+}
+
+// load returns the symbols of the file, from the cache when it didn't
+// change.
+func (idx *Index) load(file string) []Symbol {
+	fullPath := filepath.Join(idx.root, filepath.FromSlash(file))
+	info, err := os.Stat(fullPath)
+	if err != nil || info.Size() > maxFileSize {
+		delete(idx.cache, file)
+		return nil
+	}
+	if cached, ok := idx.cache[file]; ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.symbols
+	}
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		delete(idx.cache, file)
+		return nil
+	}
+	symbols := Extract(file, content)
+	idx.cache[file] = fileSymbols{size: info.Size(), modTime: info.ModTime(), symbols: symbols}
+	return symbols
+}
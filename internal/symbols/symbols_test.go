@@ -0,0 +1,113 @@
+package symbols
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		file    string
+		content string
+		want    []Symbol
+	}{
+		{
+			file: "main.go",
+			content: `package main
+
+type Server struct{}
+
+func (s *Server) Start() error { return nil }
+
+func main() {}
+`,
+			want: []Symbol{
+				{Name: "Server", Kind: KindType, Line: 3},
+				{Name: "Start", Kind: KindMethod, Line: 5},
+				{Name: "main", Kind: KindFunction, Line: 7},
+			},
+		},
+		{
+			file: "app.py",
+			content: `class App:
+    async def run(self):
+        pass
+`,
+			want: []Symbol{
+				{Name: "App", Kind: KindClass, Line: 1},
+				{Name: "run", Kind: KindFunction, Line: 2},
+			},
+		},
+		{
+			file: "index.ts",
+			content: `export interface Props {}
+export default class Widget {}
+export async function load() {}
+const render = (props: Props) => null
+`,
+			want: []Symbol{
+				{Name: "Props", Kind: KindType, Line: 1},
+				{Name: "Widget", Kind: KindClass, Line: 2},
+				{Name: "load", Kind: KindFunction, Line: 3},
+				{Name: "render", Kind: KindFunction, Line: 4},
+			},
+		},
+		{
+			file: "lib.rs",
+			content: `pub struct Config;
+pub(crate) async fn parse() {}
+`,
+			want: []Symbol{
+				{Name: "Config", Kind: KindType, Line: 1},
+				{Name: "parse", Kind: KindFunction, Line: 2},
+			},
+		},
+		{
+			file:    "README.md",
+			content: "func main() {}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			t.Parallel()
+			for i := range tt.want {
+				tt.want[i].Path = tt.file
+			}
+			require.Equal(t, tt.want, Extract(tt.file, []byte(tt.content)))
+		})
+	}
+}
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("b.go", "package b\n\nfunc Beta() {}\n")
+	write("a.py", "def alpha():\n    pass\n")
+	write("notes.txt", "def ignored():\n")
+
+	files := []string{"b.go", "a.py", "notes.txt"}
+	idx := NewIndex(dir, func() ([]string, error) { return files, nil })
+	syms, err := idx.Symbols()
+	require.NoError(t, err)
+	require.Equal(t, []Symbol{
+		{Name: "Beta", Kind: KindFunction, Path: "b.go", Line: 3},
+		{Name: "alpha", Kind: KindFunction, Path: "a.py", Line: 1},
+	}, syms)
+
+	// Changed files are read again, removed ones are forgotten.
+	write("b.go", "package b\n\ntype Gamma int\n")
+	files = []string{"b.go"}
+	syms, err = idx.Symbols()
+	require.NoError(t, err)
+	require.Equal(t, []Symbol{{Name: "Gamma", Kind: KindType, Path: "b.go", Line: 3}}, syms)
+	require.Len(t, idx.cache, 1)
+}
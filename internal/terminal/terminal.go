@@ -0,0 +1,86 @@
+// Package terminal streams the output of the commands the agent runs, so
+// it can be followed while they run.
+package terminal
+
+import (
+	"io"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/pubsub"
+)
+
+// Output is a chunk of the output of a command. A created event is
+// published when the command starts and an updated one for every chunk,
+// the last one with Done set.
+type Output struct {
+	SessionID  string
+	ToolCallID string
+	Command    string
+	Chunk      string
+	Done       bool
+}
+
+type Service interface {
+	pubsub.Suscriber[Output]
+	// Writer returns a writer that publishes what is written to it as the
+	// output of the command, closing it marks the command as done. It's
+	// safe for concurrent use.
+	Writer(sessionID, toolCallID, command string) io.WriteCloser
+}
+
+type service struct {
+	*pubsub.Broker[Output]
+}
+
+func NewService() Service {
+	return &service{
+		Broker: pubsub.NewBroker[Output](),
+	}
+}
+
+func (s *service) Writer(sessionID, toolCallID, command string) io.WriteCloser {
+	w := &writer{
+		broker: s.Broker,
+		output: Output{
+			SessionID:  sessionID,
+			ToolCallID: toolCallID,
+			Command:    command,
+		},
+	}
+	s.Publish(pubsub.CreatedEvent, w.output)
+	return w
+}
+
+type writer struct {
+	mu     sync.Mutex
+	broker *pubsub.Broker[Output]
+	output Output
+	closed bool
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if len(p) > 0 {
+		output := w.output
+		output.Chunk = string(p)
+		w.broker.Publish(pubsub.UpdatedEvent, output)
+	}
+	return len(p), nil
+}
+
+func (w *writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	output := w.output
+	output.Done = true
+	w.broker.Publish(pubsub.UpdatedEvent, output)
+	return nil
+}
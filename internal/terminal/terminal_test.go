@@ -0,0 +1,40 @@
+package terminal
+
+import (
+	"io"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	svc := NewService()
+	events := svc.Subscribe(t.Context())
+
+	w := svc.Writer("session", "call", "echo hi")
+	_, err := w.Write([]byte("hi\n"))
+	require.NoError(t, err)
+	_, err = w.Write(nil)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	_, err = w.Write([]byte("late"))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+
+	started := <-events
+	require.Equal(t, pubsub.CreatedEvent, started.Type)
+	require.Equal(t, Output{SessionID: "session", ToolCallID: "call", Command: "echo hi"}, started.Payload)
+
+	chunk := <-events
+	require.Equal(t, pubsub.UpdatedEvent, chunk.Type)
+	require.Equal(t, "hi\n", chunk.Payload.Chunk)
+	require.False(t, chunk.Payload.Done)
+
+	done := <-events
+	require.True(t, done.Payload.Done)
+	require.Empty(t, done.Payload.Chunk)
+	require.Empty(t, events)
+}
@@ -0,0 +1,133 @@
+package tokencount
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+)
+
+// anthropicVersion is the API version the requests are made for.
+const anthropicVersion = "2023-06-01"
+
+// anthropicCounter uses the token counting endpoint of the messages API.
+type anthropicCounter struct {
+	model   string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newAnthropic(model, baseURL, apiKey string, headers map[string]string) *anthropicCounter {
+	if baseURL == "" {
+		baseURL = anthropic.DefaultURL
+	}
+	h := map[string]string{"anthropic-version": anthropicVersion}
+	// Keys starting with Bearer are sent as is, as the provider does.
+	if strings.HasPrefix(apiKey, "Bearer ") {
+		h["Authorization"] = apiKey
+	} else if apiKey != "" {
+		h["X-Api-Key"] = apiKey
+	}
+	for k, v := range headers {
+		h[k] = v
+	}
+	return &anthropicCounter{
+		model:   model,
+		url:     strings.TrimSuffix(baseURL, "/") + "/v1/messages/count_tokens",
+		headers: h,
+		client:  http.DefaultClient,
+	}
+}
+
+func (c *anthropicCounter) CountTokens(ctx context.Context, prompt Prompt) (int64, error) {
+	system := []string{}
+	if prompt.System != "" {
+		system = append(system, prompt.System)
+	}
+	messages := []map[string]any{}
+	for _, msg := range prompt.Messages {
+		if msg.Role == fantasy.MessageRoleSystem {
+			for _, part := range msg.Content {
+				if text, ok := fantasy.AsMessagePart[fantasy.TextPart](part); ok {
+					system = append(system, text.Text)
+				}
+			}
+			continue
+		}
+		role := "user"
+		if msg.Role == fantasy.MessageRoleAssistant {
+			role = "assistant"
+		}
+		content := anthropicContent(msg)
+		if len(content) == 0 {
+			continue
+		}
+		messages = append(messages, map[string]any{"role": role, "content": content})
+	}
+	tools := make([]map[string]any, 0, len(prompt.Tools))
+	for _, tool := range prompt.Tools {
+		tools = append(tools, map[string]any{
+			"name":         tool.Name,
+			"description":  tool.Description,
+			"input_schema": inputSchema(tool),
+		})
+	}
+
+	body := map[string]any{"model": c.model, "messages": messages}
+	if len(system) > 0 {
+		body["system"] = strings.Join(system, "\n\n")
+	}
+	if len(tools) > 0 {
+		body["tools"] = tools
+	}
+	var out struct {
+		InputTokens int64 `json:"input_tokens"`
+	}
+	if err := post(ctx, c.client, c.url, c.headers, body, &out); err != nil {
+		return 0, err
+	}
+	return out.InputTokens, nil
+}
+
+// anthropicContent returns the content blocks of the message. Reasoning is
+// left out, as the API drops the thinking of the previous turns.
+func anthropicContent(msg fantasy.Message) []map[string]any {
+	var blocks []map[string]any
+	for _, part := range msg.Content {
+		if text, ok := fantasy.AsMessagePart[fantasy.TextPart](part); ok {
+			if text.Text != "" {
+				blocks = append(blocks, map[string]any{"type": "text", "text": text.Text})
+			}
+		} else if file, ok := fantasy.AsMessagePart[fantasy.FilePart](part); ok {
+			if isImage(file.MediaType) {
+				blocks = append(blocks, map[string]any{
+					"type":   "image",
+					"source": map[string]any{"type": "base64", "media_type": file.MediaType, "data": encode(file.Data)},
+				})
+			} else {
+				blocks = append(blocks, map[string]any{"type": "text", "text": string(file.Data)})
+			}
+		} else if call, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part); ok {
+			blocks = append(blocks, map[string]any{
+				"type":  "tool_use",
+				"id":    call.ToolCallID,
+				"name":  call.ToolName,
+				"input": rawInput(call.Input),
+			})
+		} else if result, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part); ok {
+			text, media := resultText(result.Output)
+			block := map[string]any{"type": "tool_result", "tool_use_id": result.ToolCallID, "content": text}
+			if media != nil {
+				block["content"] = []map[string]any{{
+					"type":   "image",
+					"source": map[string]any{"type": "base64", "media_type": media.MediaType, "data": media.Data},
+				}}
+			}
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
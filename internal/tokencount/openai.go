@@ -0,0 +1,117 @@
+package tokencount
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/openai"
+)
+
+// openAICounter uses the input token counting endpoint of the responses API,
+// which counts for the chat completions models as well.
+type openAICounter struct {
+	model   string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newOpenAI(model, baseURL, apiKey string, headers map[string]string) *openAICounter {
+	if baseURL == "" {
+		baseURL = openai.DefaultURL
+	}
+	h := map[string]string{}
+	if apiKey != "" {
+		h["Authorization"] = "Bearer " + apiKey
+	}
+	for k, v := range headers {
+		h[k] = v
+	}
+	return &openAICounter{
+		model:   model,
+		url:     strings.TrimSuffix(baseURL, "/") + "/responses/input_tokens",
+		headers: h,
+		client:  http.DefaultClient,
+	}
+}
+
+func (c *openAICounter) CountTokens(ctx context.Context, prompt Prompt) (int64, error) {
+	input := []map[string]any{}
+	if prompt.System != "" {
+		input = append(input, map[string]any{"role": "system", "content": prompt.System})
+	}
+	for _, msg := range prompt.Messages {
+		input = append(input, openAIItems(msg)...)
+	}
+	tools := make([]map[string]any, 0, len(prompt.Tools))
+	for _, tool := range prompt.Tools {
+		tools = append(tools, map[string]any{
+			"type":        "function",
+			"name":        tool.Name,
+			"description": tool.Description,
+			"parameters":  inputSchema(tool),
+		})
+	}
+
+	body := map[string]any{"model": c.model, "input": input}
+	if len(tools) > 0 {
+		body["tools"] = tools
+	}
+	var out struct {
+		InputTokens int64 `json:"input_tokens"`
+	}
+	if err := post(ctx, c.client, c.url, c.headers, body, &out); err != nil {
+		return 0, err
+	}
+	return out.InputTokens, nil
+}
+
+// openAIItems returns the input items of the message: the message itself,
+// and the tool calls and results, which are items of their own.
+func openAIItems(msg fantasy.Message) []map[string]any {
+	var (
+		items   []map[string]any
+		content []map[string]any
+	)
+	textType := "input_text"
+	if msg.Role == fantasy.MessageRoleAssistant {
+		textType = "output_text"
+	}
+	for _, part := range msg.Content {
+		if text, ok := fantasy.AsMessagePart[fantasy.TextPart](part); ok {
+			if text.Text != "" {
+				content = append(content, map[string]any{"type": textType, "text": text.Text})
+			}
+		} else if file, ok := fantasy.AsMessagePart[fantasy.FilePart](part); ok {
+			if isImage(file.MediaType) {
+				content = append(content, map[string]any{"type": "input_image", "image_url": dataURL(file.MediaType, encode(file.Data))})
+			} else {
+				content = append(content, map[string]any{"type": textType, "text": string(file.Data)})
+			}
+		} else if call, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part); ok {
+			items = append(items, map[string]any{
+				"type":      "function_call",
+				"call_id":   call.ToolCallID,
+				"name":      call.ToolName,
+				"arguments": call.Input,
+			})
+		} else if result, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part); ok {
+			text, media := resultText(result.Output)
+			item := map[string]any{"type": "function_call_output", "call_id": result.ToolCallID, "output": text}
+			if media != nil {
+				item["output"] = []map[string]any{{"type": "input_image", "image_url": dataURL(media.MediaType, media.Data)}}
+			}
+			items = append(items, item)
+		}
+	}
+	if len(content) == 0 {
+		return items
+	}
+	role := string(msg.Role)
+	if msg.Role == fantasy.MessageRoleTool {
+		role = "user"
+	}
+	return append([]map[string]any{{"role": role, "content": content}}, items...)
+}
@@ -0,0 +1,143 @@
+// Package tokencount counts the tokens of prompts with the counting APIs of
+// the providers, for when the estimates are too rough, like when a prompt is
+// about to fill the context window.
+package tokencount
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/openai"
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// ErrUnsupported is returned for providers without a counting API.
+var ErrUnsupported = errors.New("provider does not support counting tokens")
+
+// Prompt is what's sent to the model.
+type Prompt struct {
+	System   string
+	Messages []fantasy.Message
+	Tools    []fantasy.ToolInfo
+}
+
+// Counter counts the tokens of prompts the way the model does.
+type Counter interface {
+	CountTokens(ctx context.Context, prompt Prompt) (int64, error)
+}
+
+// New returns the counter of the model with the given ID of the provider,
+// whose variables are resolved with resolver. Anthropic and OpenAI are
+// supported.
+func New(resolver config.VariableResolver, provider config.ProviderConfig, modelID string) (Counter, error) {
+	headers, err := provider.ResolvedExtraHeaders(resolver)
+	if err != nil {
+		return nil, err
+	}
+	apiKey, _ := resolver.ResolveValue(provider.APIKey)
+	baseURL, _ := resolver.ResolveValue(provider.BaseURL)
+
+	switch provider.Type {
+	case anthropic.Name:
+		return newAnthropic(modelID, baseURL, apiKey, headers), nil
+	case openai.Name:
+		return newOpenAI(modelID, baseURL, apiKey, headers), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, provider.ID)
+	}
+}
+
+// post sends the request body to url and decodes the response into out.
+func post(ctx context.Context, client *http.Client, url string, headers map[string]string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to count tokens: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to count tokens: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to read token count: %w", err)
+	}
+	return nil
+}
+
+// inputSchema returns the JSON schema of the parameters of the tool.
+func inputSchema(tool fantasy.ToolInfo) map[string]any {
+	schema := map[string]any{
+		"type":       "object",
+		"properties": tool.Parameters,
+	}
+	if len(tool.Required) > 0 {
+		schema["required"] = tool.Required
+	}
+	return schema
+}
+
+// resultText returns the text of a tool result, and the image it holds if
+// any.
+func resultText(output fantasy.ToolResultOutputContent) (text string, media *fantasy.ToolResultOutputContentMedia) {
+	switch output := output.(type) {
+	case fantasy.ToolResultOutputContentText:
+		return output.Text, nil
+	case *fantasy.ToolResultOutputContentText:
+		return output.Text, nil
+	case fantasy.ToolResultOutputContentError:
+		if output.Error != nil {
+			return output.Error.Error(), nil
+		}
+	case *fantasy.ToolResultOutputContentError:
+		if output.Error != nil {
+			return output.Error.Error(), nil
+		}
+	case fantasy.ToolResultOutputContentMedia:
+		return "", &output
+	case *fantasy.ToolResultOutputContentMedia:
+		return "", output
+	}
+	return "", nil
+}
+
+func isImage(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "image/")
+}
+
+func dataURL(mediaType, data string) string {
+	return "data:" + mediaType + ";base64," + data
+}
+
+func encode(data []byte) string {
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// rawInput returns the JSON input of a tool call as is, or an empty object
+// when it's not valid JSON.
+func rawInput(input string) json.RawMessage {
+	if !json.Valid([]byte(input)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(input)
+}
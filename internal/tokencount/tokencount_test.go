@@ -0,0 +1,143 @@
+package tokencount
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/openai"
+	"charm.land/fantasy/providers/openaicompat"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/env"
+	"github.com/stretchr/testify/require"
+)
+
+func newResolver() config.VariableResolver {
+	return config.NewEnvironmentVariableResolver(env.NewFromMap(map[string]string{"KEY": "secret"}))
+}
+
+var testPrompt = Prompt{
+	System: "You are crush.",
+	Messages: []fantasy.Message{
+		fantasy.NewSystemMessage("Project context."),
+		fantasy.NewUserMessage("list the files"),
+		{
+			Role: fantasy.MessageRoleAssistant,
+			Content: []fantasy.MessagePart{
+				fantasy.ReasoningPart{Text: "Let me look."},
+				fantasy.ToolCallPart{ToolCallID: "call-1", ToolName: "ls", Input: `{"path":"."}`},
+			},
+		},
+		{
+			Role: fantasy.MessageRoleTool,
+			Content: []fantasy.MessagePart{
+				fantasy.ToolResultPart{ToolCallID: "call-1", Output: fantasy.ToolResultOutputContentText{Text: "main.go"}},
+			},
+		},
+	},
+	Tools: []fantasy.ToolInfo{{
+		Name:        "ls",
+		Description: "List files",
+		Parameters:  map[string]any{"path": map[string]any{"type": "string"}},
+		Required:    []string{"path"},
+	}},
+}
+
+func TestAnthropicCountTokens(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/messages/count_tokens", r.URL.Path)
+		require.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+		require.Equal(t, anthropicVersion, r.Header.Get("Anthropic-Version"))
+		var req struct {
+			Model    string `json:"model"`
+			System   string `json:"system"`
+			Messages []struct {
+				Role    string           `json:"role"`
+				Content []map[string]any `json:"content"`
+			} `json:"messages"`
+			Tools []map[string]any `json:"tools"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "claude-sonnet-4", req.Model)
+		require.Equal(t, "You are crush.\n\nProject context.", req.System)
+		require.Len(t, req.Messages, 3)
+		require.Equal(t, "assistant", req.Messages[1].Role)
+		require.Len(t, req.Messages[1].Content, 1)
+		require.Equal(t, "tool_use", req.Messages[1].Content[0]["type"])
+		require.Equal(t, map[string]any{"path": "."}, req.Messages[1].Content[0]["input"])
+		require.Equal(t, "user", req.Messages[2].Role)
+		require.Equal(t, "tool_result", req.Messages[2].Content[0]["type"])
+		require.Equal(t, "ls", req.Tools[0]["name"])
+		_ = json.NewEncoder(w).Encode(map[string]any{"input_tokens": 42})
+	}))
+	t.Cleanup(srv.Close)
+
+	counter, err := New(newResolver(), config.ProviderConfig{
+		ID:      "anthropic",
+		Type:    anthropic.Name,
+		BaseURL: srv.URL,
+		APIKey:  "$KEY",
+	}, "claude-sonnet-4")
+	require.NoError(t, err)
+	count, err := counter.CountTokens(t.Context(), testPrompt)
+	require.NoError(t, err)
+	require.Equal(t, int64(42), count)
+}
+
+func TestOpenAICountTokens(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/responses/input_tokens", r.URL.Path)
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var req struct {
+			Model string           `json:"model"`
+			Input []map[string]any `json:"input"`
+			Tools []map[string]any `json:"tools"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "gpt-5", req.Model)
+		var types []any
+		for _, item := range req.Input {
+			types = append(types, item["type"])
+		}
+		// System prompt, system message, user message, call and output.
+		require.Equal(t, []any{nil, nil, nil, "function_call", "function_call_output"}, types)
+		require.Equal(t, "function", req.Tools[0]["type"])
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"object": "response.input_tokens", "input_tokens": 36})
+	}))
+	t.Cleanup(srv.Close)
+
+	counter, err := New(newResolver(), config.ProviderConfig{
+		ID:      "openai",
+		Type:    openai.Name,
+		BaseURL: srv.URL + "/v1",
+		APIKey:  "$KEY",
+	}, "gpt-5")
+	require.NoError(t, err)
+	count, err := counter.CountTokens(t.Context(), testPrompt)
+	require.NoError(t, err)
+	require.Equal(t, int64(36), count)
+}
+
+func TestCountTokensErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(newResolver(), config.ProviderConfig{ID: "local", Type: openaicompat.Name}, "llama")
+	require.ErrorIs(t, err, ErrUnsupported)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"bad model"}`, http.StatusBadRequest)
+	}))
+	t.Cleanup(srv.Close)
+	counter, err := New(newResolver(), config.ProviderConfig{ID: "anthropic", Type: anthropic.Name, BaseURL: srv.URL}, "claude")
+	require.NoError(t, err)
+	_, err = counter.CountTokens(t.Context(), testPrompt)
+	require.ErrorContains(t, err, "bad model")
+}
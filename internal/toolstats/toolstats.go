@@ -0,0 +1,99 @@
+package toolstats
+
+import (
+	"context"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+// Execution is a single run of a tool.
+type Execution struct {
+	ID         string
+	SessionID  string
+	ToolName   string
+	Duration   time.Duration
+	OutputSize int64
+	IsError    bool
+	CreatedAt  int64
+}
+
+// Stat aggregates the executions of a tool across all sessions.
+type Stat struct {
+	ToolName      string
+	Executions    int64
+	Errors        int64
+	AvgDuration   time.Duration
+	MaxDuration   time.Duration
+	AvgOutputSize int64
+}
+
+// ErrorRate returns the fraction of executions that failed.
+func (s Stat) ErrorRate() float64 {
+	if s.Executions == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Executions)
+}
+
+type Service interface {
+	pubsub.Suscriber[Execution]
+	Record(ctx context.Context, execution Execution) (Execution, error)
+	List(ctx context.Context) ([]Stat, error)
+}
+
+type service struct {
+	*pubsub.Broker[Execution]
+	q db.Querier
+}
+
+func NewService(q db.Querier) Service {
+	return &service{
+		Broker: pubsub.NewBroker[Execution](),
+		q:      q,
+	}
+}
+
+func (s *service) Record(ctx context.Context, execution Execution) (Execution, error) {
+	var isError int64
+	if execution.IsError {
+		isError = 1
+	}
+	dbExecution, err := s.q.CreateToolExecution(ctx, db.CreateToolExecutionParams{
+		ID:         uuid.New().String(),
+		SessionID:  execution.SessionID,
+		ToolName:   execution.ToolName,
+		DurationMs: execution.Duration.Milliseconds(),
+		OutputSize: execution.OutputSize,
+		IsError:    isError,
+	})
+	if err != nil {
+		return Execution{}, err
+	}
+	// Keep the full precision duration for subscribers.
+	execution.ID = dbExecution.ID
+	execution.CreatedAt = dbExecution.CreatedAt
+	s.Publish(pubsub.CreatedEvent, execution)
+	return execution, nil
+}
+
+func (s *service) List(ctx context.Context) ([]Stat, error) {
+	rows, err := s.q.ListToolStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]Stat, len(rows))
+	for i, row := range rows {
+		stats[i] = Stat{
+			ToolName:      row.ToolName,
+			Executions:    row.Executions,
+			Errors:        row.Errors,
+			AvgDuration:   time.Duration(row.AvgDurationMs) * time.Millisecond,
+			MaxDuration:   time.Duration(row.MaxDurationMs) * time.Millisecond,
+			AvgOutputSize: row.AvgOutputSize,
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,59 @@
+package toolstats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	t.Parallel()
+
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	q := db.New(conn)
+	_, err = q.CreateSession(t.Context(), db.CreateSessionParams{ID: "session", Title: "Test"})
+	require.NoError(t, err)
+
+	svc := NewService(q)
+	events := svc.Subscribe(t.Context())
+
+	for _, execution := range []Execution{
+		{SessionID: "session", ToolName: "bash", Duration: 3 * time.Second, OutputSize: 100},
+		{SessionID: "session", ToolName: "bash", Duration: time.Second, OutputSize: 300, IsError: true},
+		{SessionID: "session", ToolName: "view", Duration: 10 * time.Millisecond, OutputSize: 50},
+	} {
+		_, err := svc.Record(t.Context(), execution)
+		require.NoError(t, err)
+	}
+
+	event := <-events
+	require.Equal(t, "bash", event.Payload.ToolName)
+	require.Equal(t, 3*time.Second, event.Payload.Duration)
+	require.NotEmpty(t, event.Payload.ID)
+
+	stats, err := svc.List(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []Stat{
+		{
+			ToolName:      "bash",
+			Executions:    2,
+			Errors:        1,
+			AvgDuration:   2 * time.Second,
+			MaxDuration:   3 * time.Second,
+			AvgOutputSize: 200,
+		},
+		{
+			ToolName:      "view",
+			Executions:    1,
+			AvgDuration:   10 * time.Millisecond,
+			MaxDuration:   10 * time.Millisecond,
+			AvgOutputSize: 50,
+		},
+	}, stats)
+	require.Equal(t, 0.5, stats[0].ErrorRate())
+}
@@ -0,0 +1,206 @@
+// Package actions is the registry of the actions of the TUI. Each action
+// has a title for the command palette and the keys it's bound to, which
+// users can change in the configuration.
+package actions
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+)
+
+// ID identifies an action, it's the name used to bind keys to it in the
+// configuration.
+type ID string
+
+const (
+	Commands          ID = "commands"
+	ToggleHelp        ID = "toggle_help"
+	Quit              ID = "quit"
+	Suspend           ID = "suspend"
+	NewSession        ID = "new_session"
+	SwitchSession     ID = "switch_session"
+	DraftHistory      ID = "draft_history"
+	SwitchModel       ID = "switch_model"
+	Summarize         ID = "summarize"
+	ExportSession     ID = "export_session"
+	InspectLogprobs   ID = "inspect_logprobs"
+	AddAttachment     ID = "add_attachment"
+	OpenEditor        ID = "open_external_editor"
+	ToggleDetails     ID = "toggle_details"
+	ToggleSidebar     ID = "toggle_sidebar"
+	GrowSidebar       ID = "grow_sidebar"
+	ShrinkSidebar     ID = "shrink_sidebar"
+	ToggleTerminal    ID = "toggle_terminal"
+	FollowLink        ID = "follow_link"
+	ToggleCompactMode ID = "toggle_compact_mode"
+	ToggleToolOutput  ID = "toggle_tool_output"
+	ToggleThinking    ID = "toggle_thinking"
+	ReasoningEffort   ID = "select_reasoning_effort"
+	ToggleYolo        ID = "toggle_yolo"
+	ToggleDryRun      ID = "toggle_dry_run"
+	TogglePlanMode    ID = "toggle_plan_mode"
+	SessionEnv        ID = "session_environment"
+	InitProject       ID = "init"
+	Inspector         ID = "inspector"
+)
+
+// Action is something the user can do in the TUI.
+type Action struct {
+	ID          ID
+	Title       string
+	Description string
+	// Keys are the keys bound to the action by default, the first one is
+	// shown in the help.
+	Keys []string
+	// Help is the short description shown next to the key in the help.
+	Help string
+}
+
+// registry holds the actions in the order they're listed in the command
+// palette.
+var registry = []Action{
+	{ID: NewSession, Title: "New Session", Description: "Start a new session", Keys: []string{"ctrl+n"}, Help: "new session"},
+	{ID: SwitchSession, Title: "Switch Session", Description: "Switch to a different session", Keys: []string{"ctrl+s"}, Help: "sessions"},
+	{ID: DraftHistory, Title: "Restore Draft", Description: "Restore a prompt that wasn't sent", Keys: []string{"alt+h"}, Help: "drafts"},
+	{ID: SwitchModel, Title: "Switch Model", Description: "Switch to a different model"},
+	{ID: Summarize, Title: "Summarize Session", Description: "Summarize the current session and create a new one with the summary"},
+	{ID: ExportSession, Title: "Export Session", Description: "Save the conversation of the current session as Markdown"},
+	{ID: InspectLogprobs, Title: "Inspect Token Confidence", Description: "Color the tokens of the responses by how confident the model was"},
+	{ID: ToggleDryRun, Title: "Toggle Dry Run", Description: "Describe file changes and commands instead of executing them"},
+	{ID: TogglePlanMode, Title: "Toggle Plan Mode", Description: "Review and approve a plan before the agent makes changes"},
+	{ID: SessionEnv, Title: "Set Session Environment", Description: "Run this session in another directory or with extra environment variables"},
+	{ID: ToggleThinking, Title: "Toggle Thinking Mode", Description: "Toggle model thinking for reasoning-capable models"},
+	{ID: ReasoningEffort, Title: "Select Reasoning Effort", Description: "Choose reasoning effort level (low/medium/high)"},
+	{ID: ToggleToolOutput, Title: "Toggle Tool Output", Description: "Show or hide the output of tool calls in the chat"},
+	{ID: ToggleSidebar, Title: "Toggle Sidebar", Description: "Show or hide the sidebar", Keys: []string{"alt+s"}, Help: "toggle sidebar"},
+	{ID: GrowSidebar, Title: "Widen Sidebar", Description: "Make the sidebar wider", Keys: []string{"alt+="}, Help: "wider sidebar"},
+	{ID: ShrinkSidebar, Title: "Narrow Sidebar", Description: "Make the sidebar narrower", Keys: []string{"alt+-"}, Help: "narrower sidebar"},
+	{ID: ToggleTerminal, Title: "Toggle Terminal Pane", Description: "Stream the output of shell commands into a pane next to the chat", Keys: []string{"alt+t"}, Help: "toggle terminal"},
+	{ID: FollowLink, Title: "Follow Link", Description: "Open a file or URL from the conversation by typing its hint", Keys: []string{"alt+o"}, Help: "follow link"},
+	{ID: ToggleCompactMode, Title: "Toggle Compact Layout", Description: "Toggle between compact and normal layout"},
+	{ID: ToggleDetails, Title: "Toggle Details", Description: "Show or hide the session details in compact layout", Keys: []string{"ctrl+d"}, Help: "toggle details"},
+	{ID: AddAttachment, Title: "Open File Picker", Description: "Open file picker", Keys: []string{"ctrl+f"}, Help: "add attachment"},
+	{ID: OpenEditor, Title: "Open External Editor", Description: "Open external editor to compose message", Keys: []string{"ctrl+o"}, Help: "open editor"},
+	{ID: ToggleYolo, Title: "Toggle Yolo Mode", Description: "Toggle yolo mode"},
+	{ID: ToggleHelp, Title: "Toggle Help", Description: "Toggle help", Keys: []string{"ctrl+g"}, Help: "more"},
+	{ID: InitProject, Title: "Initialize Project", Description: "Create/Update the CRUSH.md memory file"},
+	{ID: Commands, Title: "Commands", Description: "Open the command palette", Keys: []string{"ctrl+p"}, Help: "commands"},
+	{ID: Inspector, Title: "Inspect Requests", Description: "Inspect the requests sent to the provider", Keys: []string{"ctrl+alt+d"}, Help: "inspect requests"},
+	{ID: Suspend, Title: "Suspend", Description: "Suspend crush", Keys: []string{"ctrl+z"}, Help: "suspend"},
+	{ID: Quit, Title: "Quit", Description: "Quit", Keys: []string{"ctrl+c"}, Help: "quit"},
+}
+
+// keybindings are the keys the user bound to actions in place of the
+// defaults.
+var keybindings atomic.Pointer[map[ID][]string]
+
+// All returns the actions, in the order of the command palette.
+func All() []Action {
+	all := make([]Action, len(registry))
+	for i, action := range registry {
+		action.Keys = Keys(action.ID)
+		all[i] = action
+	}
+	return all
+}
+
+// Get returns the action with the ID.
+func Get(id ID) (Action, bool) {
+	for _, action := range registry {
+		if action.ID == id {
+			action.Keys = Keys(id)
+			return action, true
+		}
+	}
+	return Action{}, false
+}
+
+// Keys returns the keys bound to the action.
+func Keys(id ID) []string {
+	if bound := keybindings.Load(); bound != nil {
+		if keys, ok := (*bound)[id]; ok {
+			return keys
+		}
+	}
+	for _, action := range registry {
+		if action.ID == id {
+			return action.Keys
+		}
+	}
+	return nil
+}
+
+// Shortcut returns the key shown for the action, empty when it has none.
+func Shortcut(id ID) string {
+	keys := Keys(id)
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[0]
+}
+
+// Binding returns the key binding of the action. Actions without keys get
+// a disabled binding, it never matches and isn't shown in the help.
+func Binding(id ID) key.Binding {
+	action, _ := Get(id)
+	if len(action.Keys) == 0 {
+		return key.NewBinding(key.WithDisabled())
+	}
+	return key.NewBinding(
+		key.WithKeys(action.Keys...),
+		key.WithHelp(action.Keys[0], action.Help),
+	)
+}
+
+// SetKeybindings binds keys to actions in place of their defaults, an empty
+// list of keys unbinds the action. It returns an error naming the unknown
+// actions or keys bound to several actions, the other bindings are still
+// applied.
+func SetKeybindings(bindings map[string][]string) error {
+	bound := make(map[ID][]string, len(bindings))
+	var unknown []string
+	for name, keys := range bindings {
+		id := ID(name)
+		if _, ok := Get(id); !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		bound[id] = keys
+	}
+	keybindings.Store(&bound)
+
+	var errs []string
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		errs = append(errs, "unknown actions: "+strings.Join(unknown, ", "))
+	}
+	if conflicts := conflicts(); len(conflicts) > 0 {
+		errs = append(errs, "keys bound to several actions: "+strings.Join(conflicts, ", "))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid keybindings: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// conflicts returns the keys bound to more than one action.
+func conflicts() []string {
+	actions := make(map[string]int)
+	for _, action := range All() {
+		for _, k := range action.Keys {
+			actions[k]++
+		}
+	}
+	var keys []string
+	for k, n := range actions {
+		if n > 1 {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaults(t *testing.T) {
+	t.Parallel()
+
+	seen := make(map[ID]bool)
+	for _, action := range registry {
+		require.False(t, seen[action.ID], "duplicate action %s", action.ID)
+		seen[action.ID] = true
+		require.NotEmpty(t, action.Title, action.ID)
+		if len(action.Keys) > 0 {
+			require.NotEmpty(t, action.Help, action.ID)
+		}
+	}
+
+	defaults := make(map[string]ID)
+	for _, action := range registry {
+		for _, k := range action.Keys {
+			other, ok := defaults[k]
+			require.False(t, ok, "%s is bound to %s and %s", k, other, action.ID)
+			defaults[k] = action.ID
+		}
+	}
+}
+
+// Not parallel: the keybindings apply to the whole package.
+func TestSetKeybindings(t *testing.T) {
+	t.Cleanup(func() { require.NoError(t, SetKeybindings(nil)) })
+
+	require.Equal(t, "ctrl+n", Shortcut(NewSession))
+	require.Equal(t, "ctrl+n", Binding(NewSession).Help().Key)
+	require.Empty(t, Shortcut(SwitchModel))
+	require.False(t, Binding(SwitchModel).Enabled())
+
+	err := SetKeybindings(map[string][]string{
+		"new_session":  {"alt+n"},
+		"switch_model": {"ctrl+l"},
+		"suspend":      {},
+		"fly":          {"ctrl+y"},
+	})
+	require.EqualError(t, err, "invalid keybindings: unknown actions: fly")
+
+	require.True(t, key.Matches(tea.KeyPressMsg{Code: 'n', Mod: tea.ModAlt}, Binding(NewSession)))
+	require.False(t, key.Matches(tea.KeyPressMsg{Code: 'n', Mod: tea.ModCtrl}, Binding(NewSession)))
+	require.Equal(t, "ctrl+l", Shortcut(SwitchModel))
+	require.False(t, Binding(Suspend).Enabled())
+
+	action, ok := Get(NewSession)
+	require.True(t, ok)
+	require.Equal(t, []string{"alt+n"}, action.Keys)
+
+	err = SetKeybindings(map[string][]string{"switch_model": {"ctrl+n"}})
+	require.EqualError(t, err, "invalid keybindings: keys bound to several actions: ctrl+n")
+}
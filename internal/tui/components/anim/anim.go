@@ -16,20 +16,19 @@ import (
 	"github.com/lucasb-eyer/go-colorful"
 
 	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 )
 
 const (
-	fps           = 20
+	defaultFPS    = 20
+	maxFPS        = 60
 	initialChar   = '.'
 	labelGap      = " "
 	labelGapWidth = 1
 
-	// Periods of ellipsis animation speed in steps.
-	//
-	// If the FPS is 20 (50 milliseconds) this means that the ellipsis will
-	// change every 8 frames (400 milliseconds).
-	ellipsisAnimSpeed = 8
+	// How long each frame of the ellipsis animation lasts.
+	ellipsisInterval = 400 * time.Millisecond
 
 	// The maximum amount of time that can pass before a character appears.
 	// This is used to create a staggered entrance effect.
@@ -44,18 +43,42 @@ const (
 	defaultNumCyclingChars = 10
 )
 
-// Default colors for gradient.
-var (
-	defaultGradColorA = color.RGBA{R: 0xff, G: 0, B: 0, A: 0xff}
-	defaultGradColorB = color.RGBA{R: 0, G: 0, B: 0xff, A: 0xff}
-	defaultLabelColor = color.RGBA{R: 0xcc, G: 0xcc, B: 0xcc, A: 0xff}
-)
-
 var (
 	availableRunes = []rune("0123456789abcdefABCDEF~!@#$£€%^&*()+=_")
 	ellipsisFrames = []string{".", "..", "...", ""}
 )
 
+// Profile controls how every animation moves, so that all spinners obey
+// the preferences of the user.
+type Profile struct {
+	// FPS is the frame rate of animations.
+	FPS int
+	// ReducedMotion replaces animations with a static indicator.
+	ReducedMotion bool
+	// StaticColors keeps the gradient of spinners still instead of cycling
+	// it.
+	StaticColors bool
+}
+
+var profile atomic.Pointer[Profile]
+
+// SetProfile sets the profile of the animations created from now on.
+func SetProfile(p Profile) {
+	if p.FPS < 1 {
+		p.FPS = defaultFPS
+	}
+	p.FPS = min(p.FPS, maxFPS)
+	profile.Store(&p)
+}
+
+// CurrentProfile returns the profile animations are created with.
+func CurrentProfile() Profile {
+	if p := profile.Load(); p != nil {
+		return *p
+	}
+	return Profile{FPS: defaultFPS}
+}
+
 // Internal ID management. Used during animating to ensure that frame messages
 // are received only by spinner components that sent them.
 var lastID int64
@@ -87,7 +110,8 @@ func settingsHash(opts Settings) string {
 // StepMsg is a message type used to trigger the next step in the animation.
 type StepMsg struct{ id int }
 
-// Settings defines settings for the animation.
+// Settings defines settings for the animation. Unset colors are taken from
+// the current theme.
 type Settings struct {
 	Size        int
 	Label       string
@@ -97,9 +121,6 @@ type Settings struct {
 	CycleColors bool
 }
 
-// Default settings.
-const ()
-
 // Anim is a Bubble for an animated spinner.
 type Anim struct {
 	width            int
@@ -115,24 +136,36 @@ type Anim struct {
 	step             atomic.Int64         // current main frame step
 	ellipsisStep     atomic.Int64         // current ellipsis frame step
 	ellipsisFrames   *csync.Slice[string] // ellipsis animation frames
+	ellipsisSpeed    int                  // steps per ellipsis frame
+	fps              int
+	reducedMotion    bool
 	id               int
 }
 
 // New creates a new Anim instance with the specified width and label.
 func New(opts Settings) *Anim {
-	a := &Anim{}
+	p := CurrentProfile()
+	a := &Anim{
+		fps:           p.FPS,
+		ellipsisSpeed: max(1, int(ellipsisInterval*time.Duration(p.FPS)/time.Second)),
+		reducedMotion: p.ReducedMotion,
+	}
 	// Validate settings.
 	if opts.Size < 1 {
 		opts.Size = defaultNumCyclingChars
 	}
+	t := styles.CurrentTheme()
 	if colorIsUnset(opts.GradColorA) {
-		opts.GradColorA = defaultGradColorA
+		opts.GradColorA = t.Primary
 	}
 	if colorIsUnset(opts.GradColorB) {
-		opts.GradColorB = defaultGradColorB
+		opts.GradColorB = t.Secondary
 	}
 	if colorIsUnset(opts.LabelColor) {
-		opts.LabelColor = defaultLabelColor
+		opts.LabelColor = t.FgBase
+	}
+	if p.StaticColors || p.ReducedMotion {
+		opts.CycleColors = false
 	}
 
 	a.id = nextID()
@@ -318,6 +351,12 @@ func (a *Anim) Init() tea.Cmd {
 	return a.Step()
 }
 
+// ReducedMotion reports whether the animation is replaced with a static
+// indicator.
+func (a *Anim) ReducedMotion() bool {
+	return a.reducedMotion
+}
+
 // Update processes animation steps (or not).
 func (a *Anim) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 	switch msg := msg.(type) {
@@ -335,7 +374,7 @@ func (a *Anim) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		if a.initialized.Load() && a.labelWidth > 0 {
 			// Manage the ellipsis animation.
 			ellipsisStep := a.ellipsisStep.Add(1)
-			if int(ellipsisStep) >= ellipsisAnimSpeed*len(ellipsisFrames) {
+			if int(ellipsisStep) >= a.ellipsisSpeed*len(ellipsisFrames) {
 				a.ellipsisStep.Store(0)
 			}
 		} else if !a.initialized.Load() && time.Since(a.startTime) >= maxBirthOffset {
@@ -349,6 +388,10 @@ func (a *Anim) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 
 // View renders the current state of the animation.
 func (a *Anim) View() string {
+	if a.reducedMotion {
+		return a.staticView()
+	}
+
 	var b strings.Builder
 	step := int(a.step.Load())
 	for i := range a.width {
@@ -373,7 +416,7 @@ func (a *Anim) View() string {
 	// have been initialized.
 	if a.initialized.Load() && a.labelWidth > 0 {
 		ellipsisStep := int(a.ellipsisStep.Load())
-		if ellipsisFrame, ok := a.ellipsisFrames.Get(ellipsisStep / ellipsisAnimSpeed); ok {
+		if ellipsisFrame, ok := a.ellipsisFrames.Get(ellipsisStep / a.ellipsisSpeed); ok {
 			b.WriteString(ellipsisFrame)
 		}
 	}
@@ -381,9 +424,37 @@ func (a *Anim) View() string {
 	return b.String()
 }
 
-// Step is a command that triggers the next step in the animation.
+// staticView renders the indicator shown instead of the animation when
+// motion is reduced: the initial characters, the label and a full ellipsis.
+func (a *Anim) staticView() string {
+	var b strings.Builder
+	for i := range a.width {
+		switch {
+		case i < a.cyclingCharWidth:
+			b.WriteString(a.initialFrames[0][i])
+		case i == a.cyclingCharWidth:
+			b.WriteString(labelGap)
+		default:
+			if labelChar, ok := a.label.Get(i - a.cyclingCharWidth - labelGapWidth); ok {
+				b.WriteString(labelChar)
+			}
+		}
+	}
+	if a.labelWidth > 0 {
+		if ellipsisFrame, ok := a.ellipsisFrames.Get(len(ellipsisFrames) - 2); ok {
+			b.WriteString(ellipsisFrame)
+		}
+	}
+	return b.String()
+}
+
+// Step is a command that triggers the next step in the animation. It does
+// nothing when motion is reduced.
 func (a *Anim) Step() tea.Cmd {
-	return tea.Tick(time.Second/time.Duration(fps), func(t time.Time) tea.Msg {
+	if a.reducedMotion {
+		return nil
+	}
+	return tea.Tick(time.Second/time.Duration(a.fps), func(t time.Time) tea.Msg {
 		return StepMsg{id: a.id}
 	})
 }
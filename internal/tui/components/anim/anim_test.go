@@ -0,0 +1,33 @@
+package anim
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/require"
+)
+
+// Not parallel: the profile applies to the whole package.
+func TestProfile(t *testing.T) {
+	t.Cleanup(func() { SetProfile(Profile{}) })
+
+	SetProfile(Profile{FPS: 200})
+	require.Equal(t, maxFPS, CurrentProfile().FPS)
+
+	SetProfile(Profile{FPS: 5})
+	a := New(Settings{Size: 3, Label: "Working", CycleColors: true})
+	require.Equal(t, 5, a.fps)
+	require.Equal(t, 2, a.ellipsisSpeed)
+	require.NotNil(t, a.Init())
+
+	SetProfile(Profile{ReducedMotion: true})
+	a = New(Settings{Size: 3, Label: "Working", CycleColors: true})
+	require.True(t, a.ReducedMotion())
+	require.Nil(t, a.Init())
+	require.Nil(t, a.Step())
+
+	view := a.View()
+	require.Equal(t, "... Working...", ansi.Strip(view))
+	require.Equal(t, view, a.View())
+	require.LessOrEqual(t, ansi.StringWidth(view), a.Width())
+}
@@ -10,6 +10,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/agent"
 	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
@@ -30,6 +31,13 @@ type SessionSelectedMsg = session.Session
 
 type SessionClearedMsg struct{}
 
+// ToggleToolOutputMsg shows or hides the output of the tool calls.
+type ToggleToolOutputMsg struct{}
+
+// RefreshMsg renders the messages of the session again, after a change to
+// how they're rendered.
+type RefreshMsg struct{}
+
 type SelectionCopyMsg struct {
 	clickCount   int
 	endSelection bool
@@ -52,6 +60,9 @@ type MessageListCmp interface {
 	GoToBottom() tea.Cmd
 	GetSelectedText() string
 	CopySelectedText(bool) tea.Cmd
+	// IsMessageOffscreen reports whether the message is rendered outside
+	// of the visible part of the chat.
+	IsMessageOffscreen(id string) bool
 }
 
 // messageListCmp implements MessageListCmp, providing a virtualized list
@@ -186,7 +197,11 @@ func (m *messageListCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				m.listCmp.EndSelection(msg.x, msg.y)
 			}
 			m.listCmp.SelectionStop()
-			cmds = append(cmds, m.CopySelectedText(true))
+			// Without copy on select the selection stays until it's copied
+			// or cleared.
+			if config.Get().Options.TUI.CopyOnSelectEnabled() {
+				cmds = append(cmds, m.CopySelectedText(true))
+			}
 			return m, tea.Batch(cmds...)
 		}
 	case pubsub.Event[permission.PermissionNotification]:
@@ -197,6 +212,14 @@ func (m *messageListCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			cmds = append(cmds, m.SetSession(msg))
 		}
 		return m, tea.Batch(cmds...)
+	case ToggleToolOutputMsg:
+		messages.SetToolOutputHidden(!messages.ToolOutputHidden())
+		return m, util.CmdHandler(RefreshMsg{})
+	case RefreshMsg:
+		current := m.session
+		m.session = session.Session{}
+		cmds = append(cmds, m.SetSession(current))
+		return m, tea.Batch(cmds...)
 	case SessionClearedMsg:
 		m.session = session.Session{}
 		cmds = append(cmds, m.listCmp.SetItems([]list.Item{}))
@@ -219,6 +242,10 @@ func (m *messageListCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+func (m *messageListCmp) IsMessageOffscreen(id string) bool {
+	return m.listCmp.IsItemOffscreen(id)
+}
+
 // View renders the message list or an initial screen if empty.
 func (m *messageListCmp) View() string {
 	t := styles.CurrentTheme()
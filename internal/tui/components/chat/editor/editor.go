@@ -3,6 +3,7 @@ package editor
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"os"
@@ -11,25 +12,31 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/charmbracelet/bubbles/v2/key"
 	"github.com/charmbracelet/bubbles/v2/textarea"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/draft"
 	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/symbols"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/completions"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/drafts"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/quickopen"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/quit"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/google/uuid"
 )
 
 type Editor interface {
@@ -49,6 +56,18 @@ type FileCompletionItem struct {
 	Path string // The file path
 }
 
+// SymbolCompletionItem is a function or type of the workspace, completed
+// after a #.
+type SymbolCompletionItem struct {
+	Symbol symbols.Symbol
+}
+
+// Reference returns how the symbol is inserted in the prompt, with its
+// location so the agent doesn't have to look for it.
+func (s SymbolCompletionItem) Reference() string {
+	return fmt.Sprintf("%s (%s:%d)", s.Symbol.Name, s.Symbol.Path, s.Symbol.Line)
+}
+
 type editorCmp struct {
 	width              int
 	height             int
@@ -63,10 +82,14 @@ type editorCmp struct {
 
 	keyMap EditorKeyMap
 
-	// File path completions
+	// File path and symbol completions
 	currentQuery          string
 	completionsStartIndex int
 	isCompletionsOpen     bool
+
+	// draft is what the prompt was last saved as, so it can be restored
+	// after a crash or restart.
+	draft draft.Draft
 }
 
 var DeleteKeyMaps = DeleteAttachmentKeyMaps{
@@ -85,10 +108,18 @@ var DeleteKeyMaps = DeleteAttachmentKeyMaps{
 }
 
 const (
-	maxAttachments = 5
-	maxFileResults = 25
+	maxAttachments   = 5
+	maxFileResults   = 25
+	maxSymbolResults = 25
+
+	// draftSaveInterval is how often the prompt is saved as a draft.
+	draftSaveInterval = 3 * time.Second
 )
 
+// SaveDraftMsg saves the prompt as a draft if it changed, it comes every
+// draftSaveInterval.
+type SaveDraftMsg struct{}
+
 type OpenEditorMsg struct {
 	Text string
 }
@@ -135,7 +166,49 @@ func (m *editorCmp) openEditor(value string) tea.Cmd {
 }
 
 func (m *editorCmp) Init() tea.Cmd {
-	return nil
+	return m.scheduleDraftSave()
+}
+
+func (m *editorCmp) scheduleDraftSave() tea.Cmd {
+	return tea.Tick(draftSaveInterval, func(time.Time) tea.Msg {
+		return SaveDraftMsg{}
+	})
+}
+
+// saveDraft saves the prompt as a draft of the session if it changed since
+// it was last saved.
+func (m *editorCmp) saveDraft() {
+	text := m.textarea.Value()
+	if text == m.draft.Text {
+		return
+	}
+	if m.draft.ID == "" {
+		m.draft.ID = uuid.NewString()
+	}
+	m.draft.SessionID = m.session.ID
+	m.draft.Text = text
+	m.draft.UpdatedAt = time.Now()
+	if err := m.app.Drafts.Save(m.draft); err != nil {
+		slog.Warn("Failed to save draft", "error", err)
+	}
+}
+
+// restoreDraft puts the draft in the editor, it's saved again as the prompt
+// is edited.
+func (m *editorCmp) restoreDraft(d draft.Draft) {
+	m.draft = d
+	m.textarea.SetValue(d.Text)
+	m.textarea.MoveToEnd()
+}
+
+// discardDraft forgets the draft once the prompt is sent.
+func (m *editorCmp) discardDraft() {
+	if m.draft.ID != "" {
+		if err := m.app.Drafts.Delete(m.draft.ID); err != nil {
+			slog.Warn("Failed to delete draft", "error", err)
+		}
+	}
+	m.draft = draft.Draft{}
 }
 
 func (m *editorCmp) send() tea.Cmd {
@@ -148,8 +221,14 @@ func (m *editorCmp) send() tea.Cmd {
 		return util.CmdHandler(dialogs.OpenDialogMsg{Model: quit.NewQuitDialog()})
 	}
 
+	mentioned, err := resolveMentions(m.app.Config().WorkingDir(), value)
+	if err != nil {
+		return util.ReportError(err)
+	}
+
 	m.textarea.Reset()
-	attachments := m.attachments
+	m.discardDraft()
+	attachments := append(m.attachments, mentioned...)
 
 	m.attachments = nil
 	if value == "" {
@@ -178,12 +257,23 @@ func (m *editorCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		return m, m.repositionCompletions
+	case SaveDraftMsg:
+		m.saveDraft()
+		return m, m.scheduleDraftSave()
+	case drafts.DraftSelectedMsg:
+		// Keep what's being written, it's listed with the drafts too.
+		m.saveDraft()
+		m.restoreDraft(msg.Draft)
+		return m, nil
 	case filepicker.FilePickedMsg:
 		if len(m.attachments) >= maxAttachments {
 			return m, util.ReportError(fmt.Errorf("cannot add more than %d images", maxAttachments))
 		}
 		m.attachments = append(m.attachments, msg.Attachment)
 		return m, nil
+	case quickopen.FileSelectedMsg:
+		m.textarea.InsertString(msg.Path + " ")
+		return m, nil
 	case completions.CompletionsOpenedMsg:
 		m.isCompletionsOpen = true
 	case completions.CompletionsClosedMsg:
@@ -194,12 +284,20 @@ func (m *editorCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		if !m.isCompletionsOpen {
 			return m, nil
 		}
-		if item, ok := msg.Value.(FileCompletionItem); ok {
+		var insert string
+		switch item := msg.Value.(type) {
+		case FileCompletionItem:
+			insert = item.Path
+		case SymbolCompletionItem:
+			insert = item.Reference()
+		}
+		if insert != "" {
 			word := m.textarea.Word()
-			// If the selected item is a file, insert its path into the textarea
+			// Insert the path of the file, or the reference to the symbol,
+			// into the textarea
 			value := m.textarea.Value()
 			value = value[:m.completionsStartIndex] + // Remove the current query
-				item.Path + // Insert the file path
+				insert + // Insert the completion
 				value[m.completionsStartIndex+len(word):] // Append the rest of the value
 			// XXX: This will always move the cursor to the end of the textarea.
 			m.textarea.SetValue(value)
@@ -272,6 +370,17 @@ func (m *editorCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			m.currentQuery = ""
 			m.completionsStartIndex = curIdx
 			cmds = append(cmds, m.startCompletions)
+		case msg.String() == "#" && !m.isCompletionsOpen &&
+			// only complete symbols at the beginning of a word:
+			(len(m.textarea.Value()) == 0 || unicode.IsSpace(rune(m.textarea.Value()[len(m.textarea.Value())-1]))):
+			m.isCompletionsOpen = true
+			m.currentQuery = ""
+			m.completionsStartIndex = curIdx
+			cmds = append(cmds, m.startSymbolCompletions)
+		case msg.String() == "@" && !m.isCompletionsOpen &&
+			// only mention files at the beginning of a word:
+			(len(m.textarea.Value()) == 0 || unicode.IsSpace(rune(m.textarea.Value()[len(m.textarea.Value())-1]))):
+			cmds = append(cmds, m.openQuickOpen)
 		case m.isCompletionsOpen && curIdx <= m.completionsStartIndex:
 			cmds = append(cmds, util.CmdHandler(completions.CloseCompletionsMsg{}))
 		}
@@ -337,7 +446,7 @@ func (m *editorCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				cmds = append(cmds, util.CmdHandler(completions.CloseCompletionsMsg{}))
 			} else {
 				word := m.textarea.Word()
-				if strings.HasPrefix(word, "/") {
+				if strings.HasPrefix(word, "/") || strings.HasPrefix(word, "#") {
 					// XXX: wont' work if editing in the middle of the field.
 					m.completionsStartIndex = strings.LastIndex(m.textarea.Value(), word)
 					m.currentQuery = word[1:]
@@ -505,6 +614,42 @@ func (m *editorCmp) startCompletions() tea.Msg {
 	}
 }
 
+// startSymbolCompletions completes the functions and types declared in the
+// files of the workspace.
+func (m *editorCmp) startSymbolCompletions() tea.Msg {
+	syms, err := m.app.Symbols.Symbols()
+	if err != nil {
+		return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+	}
+	completionItems := make([]completions.Completion, 0, len(syms))
+	for _, sym := range syms {
+		completionItems = append(completionItems, completions.Completion{
+			Title: fmt.Sprintf("%s %s %s:%d", sym.Name, sym.Kind, sym.Path, sym.Line),
+			Value: SymbolCompletionItem{Symbol: sym},
+		})
+	}
+
+	x, y := m.completionsPosition()
+	return completions.OpenCompletionsMsg{
+		Completions: completionItems,
+		X:           x,
+		Y:           y,
+		MaxResults:  maxSymbolResults,
+	}
+}
+
+// openQuickOpen opens the file finder, the chosen file is inserted after
+// the @ typed in the prompt.
+func (m *editorCmp) openQuickOpen() tea.Msg {
+	files, err := m.app.Files.Files()
+	if err != nil {
+		return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+	}
+	return dialogs.OpenDialogMsg{
+		Model: quickopen.NewQuickOpenDialogCmp(files),
+	}
+}
+
 // Blur implements Container.
 func (c *editorCmp) Blur() tea.Cmd {
 	c.textarea.Blur()
@@ -529,10 +674,29 @@ func (c *editorCmp) Bindings() []key.Binding {
 // TODO: most likely we do not need to have the session here
 // we need to move some functionality to the page level
 func (c *editorCmp) SetSession(session session.Session) tea.Cmd {
+	if session.ID == c.session.ID {
+		return nil
+	}
+	// Each session has its own draft.
+	c.saveDraft()
 	c.session = session
+	c.loadDraft()
 	return nil
 }
 
+// loadDraft restores the latest draft of the session, or empties the
+// editor if there's none.
+func (c *editorCmp) loadDraft() {
+	d, ok, err := c.app.Drafts.Latest(c.session.ID)
+	if err != nil {
+		slog.Warn("Failed to load draft", "error", err)
+	}
+	if !ok {
+		d = draft.Draft{}
+	}
+	c.restoreDraft(d)
+}
+
 func (c *editorCmp) IsCompletionsOpen() bool {
 	return c.isCompletionsOpen
 }
@@ -585,6 +749,7 @@ func New(app *app.App) Editor {
 
 	e.randomizePlaceholders()
 	e.textarea.Placeholder = e.readyPlaceholder
+	e.loadDraft()
 
 	return e
 }
@@ -2,6 +2,7 @@ package editor
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/actions"
 )
 
 type EditorKeyMap struct {
@@ -21,10 +22,7 @@ func DefaultEditorKeyMap() EditorKeyMap {
 			key.WithKeys("enter"),
 			key.WithHelp("enter", "send"),
 		),
-		OpenEditor: key.NewBinding(
-			key.WithKeys("ctrl+o"),
-			key.WithHelp("ctrl+o", "open editor"),
-		),
+		OpenEditor: actions.Binding(actions.OpenEditor),
 		Newline: key.NewBinding(
 			key.WithKeys("shift+enter", "ctrl+j"),
 			// "ctrl+j" is a common keybinding for newline in many editors. If
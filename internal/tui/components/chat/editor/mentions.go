@@ -0,0 +1,163 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+)
+
+// maxSnippetSize is the most of a mentioned file included in the prompt.
+const maxSnippetSize = 64 * 1024
+
+// mentionRe matches @file mentions at the start of the prompt or after a
+// space, with an optional line or line range: @path, @path:10, @path:10-20.
+var mentionRe = regexp.MustCompile(`(?:^|\s)@(\S+)`)
+
+var lineRangeRe = regexp.MustCompile(`^(.+):(\d+)(?:-(\d+))?$`)
+
+// mention is a file referenced in the prompt, optionally limited to a
+// range of lines.
+type mention struct {
+	path      string
+	startLine int
+	endLine   int
+}
+
+// parseMentions returns the files mentioned in the prompt that exist, in
+// order and without duplicates. Paths are relative to the working
+// directory.
+func parseMentions(workingDir, prompt string) []mention {
+	var mentions []mention
+	for _, match := range mentionRe.FindAllStringSubmatch(prompt, -1) {
+		m, ok := parseMention(workingDir, match[1])
+		if ok && !slices.Contains(mentions, m) {
+			mentions = append(mentions, m)
+		}
+	}
+	return mentions
+}
+
+func parseMention(workingDir, token string) (mention, bool) {
+	// Punctuation ending the sentence isn't part of the path.
+	candidates := []string{token}
+	if trimmed := strings.TrimRight(token, ".,;:!?)"); trimmed != token {
+		candidates = append(candidates, trimmed)
+	}
+	for _, candidate := range candidates {
+		m := mention{path: candidate}
+		if match := lineRangeRe.FindStringSubmatch(candidate); match != nil {
+			m.path = match[1]
+			m.startLine, _ = strconv.Atoi(match[2])
+			m.endLine = m.startLine
+			if match[3] != "" {
+				m.endLine, _ = strconv.Atoi(match[3])
+			}
+			if m.startLine < 1 || m.endLine < m.startLine {
+				continue
+			}
+		}
+		if info, err := os.Stat(resolvePath(workingDir, m.path)); err == nil && !info.IsDir() {
+			return m, true
+		}
+	}
+	return mention{}, false
+}
+
+func resolvePath(workingDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(workingDir, filepath.FromSlash(path))
+}
+
+// resolveMentions turns the files mentioned in the prompt into
+// attachments: images are sent to the model and the content of text files
+// is added to the prompt. Other files are left as plain mentions.
+func resolveMentions(workingDir, prompt string) ([]message.Attachment, error) {
+	var attachments []message.Attachment
+	for _, m := range parseMentions(workingDir, prompt) {
+		path := resolvePath(workingDir, m.path)
+		if isImage(path) {
+			if m.startLine > 0 {
+				continue
+			}
+			tooBig, err := filepicker.IsFileTooBig(path, filepicker.MaxAttachmentSize)
+			if err != nil {
+				return nil, err
+			}
+			if tooBig {
+				return nil, fmt.Errorf("%s is too big to attach, the limit is 5MB", m.path)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			attachments = append(attachments, message.Attachment{
+				FilePath: path,
+				FileName: filepath.Base(path),
+				MimeType: http.DetectContentType(content[:min(512, len(content))]),
+				Content:  content,
+			})
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if !isText(content) {
+			continue
+		}
+		attachments = append(attachments, message.Attachment{
+			FilePath: path,
+			FileName: filepath.Base(path),
+			MimeType: "text/plain",
+			Content:  []byte(formatSnippet(m, string(content))),
+		})
+	}
+	return attachments, nil
+}
+
+func isImage(path string) bool {
+	return slices.Contains(filepicker.AllowedTypes, strings.ToLower(filepath.Ext(path)))
+}
+
+func isText(content []byte) bool {
+	head := content[:min(512, len(content))]
+	return !bytes.ContainsRune(head, 0) && strings.HasPrefix(http.DetectContentType(head), "text/")
+}
+
+// formatSnippet wraps the mentioned lines of the file in a tag naming it,
+// truncating it when it's too long.
+func formatSnippet(m mention, content string) string {
+	if m.startLine > 0 {
+		lines := strings.SplitAfter(content, "\n")
+		start := min(m.startLine-1, len(lines))
+		end := min(m.endLine, len(lines))
+		content = strings.Join(lines[start:end], "")
+	}
+	var truncated bool
+	if len(content) > maxSnippetSize {
+		content = strings.ToValidUTF8(content[:maxSnippetSize], "")
+		truncated = true
+	}
+	content = strings.TrimRight(content, "\n")
+
+	attrs := fmt.Sprintf("path=%q", m.path)
+	if m.startLine > 0 {
+		attrs += fmt.Sprintf(" lines=\"%d-%d\"", m.startLine, m.endLine)
+	}
+	if truncated {
+		attrs += ` truncated="true"`
+	}
+	return fmt.Sprintf("<file %s>\n%s\n</file>", attrs, content)
+}
@@ -0,0 +1,70 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveMentions(t *testing.T) {
+	t.Parallel()
+
+	tmp := t.TempDir()
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	for name, content := range map[string][]byte{
+		"main.go":       []byte("package main\n\nfunc main() {\n\tprintln(1)\n}\n"),
+		"docs/shot.png": png,
+		"bin/crush":     {0x7f, 'E', 'L', 'F', 0, 0},
+	} {
+		fp := filepath.Join(tmp, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fp), 0o755))
+		require.NoError(t, os.WriteFile(fp, content, 0o644))
+	}
+
+	t.Run("parses mentions", func(t *testing.T) {
+		t.Parallel()
+		mentions := parseMentions(tmp, "@main.go explain @main.go:3-4, and @main.go:3. see @docs/shot.png email@main.go @missing.go @bin/ @main.go:4-2")
+		require.Equal(t, []mention{
+			{path: "main.go"},
+			{path: "main.go", startLine: 3, endLine: 4},
+			{path: "main.go", startLine: 3, endLine: 3},
+			{path: "docs/shot.png"},
+		}, mentions)
+	})
+
+	t.Run("resolves attachments", func(t *testing.T) {
+		t.Parallel()
+		attachments, err := resolveMentions(tmp, "look at @main.go:3-4 and @docs/shot.png and @bin/crush")
+		require.NoError(t, err)
+		require.Len(t, attachments, 2)
+
+		require.True(t, attachments[0].IsText())
+		require.Equal(t, "main.go", attachments[0].FileName)
+		require.Equal(t, "<file path=\"main.go\" lines=\"3-4\">\nfunc main() {\n\tprintln(1)\n</file>", string(attachments[0].Content))
+
+		require.False(t, attachments[1].IsText())
+		require.Equal(t, "image/png", attachments[1].MimeType)
+		require.Equal(t, png, attachments[1].Content)
+	})
+
+	t.Run("no mentions", func(t *testing.T) {
+		t.Parallel()
+		attachments, err := resolveMentions(tmp, "what does main.go do?")
+		require.NoError(t, err)
+		require.Empty(t, attachments)
+	})
+}
+
+func TestFormatSnippet(t *testing.T) {
+	t.Parallel()
+
+	content := string(make([]byte, maxSnippetSize+10))
+	snippet := formatSnippet(mention{path: "big.txt"}, content)
+	require.Contains(t, snippet, `<file path="big.txt" truncated="true">`)
+	require.Less(t, len(snippet), maxSnippetSize+100)
+
+	snippet = formatSnippet(mention{path: "a.go", startLine: 10, endLine: 12}, "one\ntwo\n")
+	require.Equal(t, "<file path=\"a.go\" lines=\"10-12\">\n\n</file>", snippet)
+}
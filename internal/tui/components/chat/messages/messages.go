@@ -22,6 +22,7 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
 	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/highlight"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 )
@@ -58,6 +59,9 @@ type messageCmp struct {
 
 	// Thinking viewport for displaying reasoning content
 	thinkingViewport viewport.Model
+
+	// summaryExpanded is whether the summary of the turn shows its details.
+	summaryExpanded bool
 }
 
 var focusedMessageBorder = lipgloss.Border{
@@ -66,8 +70,6 @@ var focusedMessageBorder = lipgloss.Border{
 
 // NewMessageCmp creates a new message component with the given message and options
 func NewMessageCmp(msg message.Message) MessageCmp {
-	t := styles.CurrentTheme()
-
 	thinkingViewport := viewport.New()
 	thinkingViewport.SetHeight(1)
 	thinkingViewport.KeyMap = viewport.KeyMap{}
@@ -76,8 +78,6 @@ func NewMessageCmp(msg message.Message) MessageCmp {
 		message: msg,
 		anim: anim.New(anim.Settings{
 			Size:        15,
-			GradColorA:  t.Primary,
-			GradColorB:  t.Secondary,
 			CycleColors: true,
 		}),
 		thinkingViewport: thinkingViewport,
@@ -114,6 +114,10 @@ func (m *messageCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 				util.ReportInfo("Message copied to clipboard"),
 			)
 		}
+		if key.Matches(msg, SummaryKey) && m.message.RunSummary() != nil {
+			m.summaryExpanded = !m.summaryExpanded
+			return m, nil
+		}
 	}
 	return m, nil
 }
@@ -192,7 +196,7 @@ func (m *messageCmp) renderAssistantMessage() string {
 		thinkingContent = m.renderThinkingContent()
 	} else if finished && content == "" && finishedData.Reason == message.FinishReasonEndTurn {
 		content = ""
-	} else if finished && content == "" && finishedData.Reason == message.FinishReasonCanceled {
+	} else if finished && content == "" && finishedData.Reason == message.FinishReasonCanceled && finishedData.CancelReason == "" {
 		content = "*Canceled*"
 	} else if finished && content == "" && finishedData.Reason == message.FinishReasonError {
 		errTag := t.S().Base.Padding(0, 1).Background(t.Red).Foreground(t.White).Render("ERROR")
@@ -211,7 +215,30 @@ func (m *messageCmp) renderAssistantMessage() string {
 		if thinkingContent != "" {
 			parts = append(parts, "")
 		}
-		parts = append(parts, m.toMarkdown(content))
+		parts = append(parts, m.toMarkdown(highlight.FenceCode(content)))
+	}
+
+	if finished && (finishedData.Reason == message.FinishReasonLoopDetected || finishedData.Reason == message.FinishReasonStopPhrase) {
+		loopTag := t.S().Base.Padding(0, 1).Background(t.Warning).Foreground(t.White).Render("STOPPED")
+		details := ansi.Truncate(finishedData.Details, m.textWidth()-2-lipgloss.Width(loopTag), "...")
+		parts = append(parts, "", fmt.Sprintf("%s %s", loopTag, t.S().Base.Foreground(t.FgHalfMuted).Render(details)))
+	}
+
+	if finished && finishedData.Reason == message.FinishReasonCanceled && finishedData.CancelReason != "" {
+		if len(parts) > 0 {
+			parts = append(parts, "")
+		}
+		cancelTag := t.S().Base.Padding(0, 1).Background(t.Warning).Foreground(t.White).Render("CANCELED")
+		reason := finishedData.CancelReason.Description()
+		if finishedData.Details != "" {
+			reason += ": " + finishedData.Details
+		}
+		reason = ansi.Truncate(reason, m.textWidth()-2-lipgloss.Width(cancelTag), "...")
+		parts = append(parts, fmt.Sprintf("%s %s", cancelTag, t.S().Base.Foreground(t.FgHalfMuted).Render(reason)))
+	}
+
+	if summary := m.message.RunSummary(); summary != nil {
+		parts = append(parts, "", m.renderRunSummary(*summary))
 	}
 
 	joined := lipgloss.JoinVertical(lipgloss.Left, parts...)
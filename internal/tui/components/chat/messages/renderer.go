@@ -3,7 +3,9 @@ package messages
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/charmbracelet/crush/internal/agent"
@@ -21,6 +23,31 @@ import (
 // responseContextHeight limits the number of lines displayed in tool output
 const responseContextHeight = 10
 
+// toolOutputHidden hides the output of tool calls, showing only their
+// headers.
+var toolOutputHidden atomic.Bool
+
+// SetToolOutputHidden sets whether the output of tool calls rendered from
+// now on is hidden.
+func SetToolOutputHidden(hidden bool) {
+	toolOutputHidden.Store(hidden)
+}
+
+// ToolOutputHidden reports whether the output of tool calls is hidden.
+func ToolOutputHidden() bool {
+	return toolOutputHidden.Load()
+}
+
+// shellOutputInPane hides the output of bash tool calls, which is shown in
+// the terminal pane instead.
+var shellOutputInPane atomic.Bool
+
+// SetShellOutputInPane sets whether the output of bash tool calls rendered
+// from now on is left to the terminal pane.
+func SetShellOutputInPane(inPane bool) {
+	shellOutputInPane.Store(inPane)
+}
+
 // renderer defines the interface for tool-specific rendering implementations
 type renderer interface {
 	// Render returns the complete (already styled) tool‑call view, not
@@ -167,6 +194,7 @@ func init() {
 	registry.register(tools.ViewToolName, func() renderer { return viewRenderer{} })
 	registry.register(tools.EditToolName, func() renderer { return editRenderer{} })
 	registry.register(tools.MultiEditToolName, func() renderer { return multiEditRenderer{} })
+	registry.register(tools.MultiFileEditToolName, func() renderer { return multiFileEditRenderer{} })
 	registry.register(tools.WriteToolName, func() renderer { return writeRenderer{} })
 	registry.register(tools.FetchToolName, func() renderer { return fetchRenderer{} })
 	registry.register(tools.GlobToolName, func() renderer { return globRenderer{} })
@@ -174,6 +202,8 @@ func init() {
 	registry.register(tools.LSToolName, func() renderer { return lsRenderer{} })
 	registry.register(tools.SourcegraphToolName, func() renderer { return sourcegraphRenderer{} })
 	registry.register(tools.DiagnosticsToolName, func() renderer { return diagnosticsRenderer{} })
+	registry.register(tools.ArtifactToolName, func() renderer { return artifactRenderer{} })
+	registry.register(tools.FactsToolName, func() renderer { return factsRenderer{} })
 	registry.register(agent.AgentToolName, func() renderer { return agentRenderer{} })
 }
 
@@ -214,6 +244,9 @@ func (br bashRenderer) Render(v *toolCallCmp) string {
 	args := newParamBuilder().addMain(cmd).build()
 
 	return br.renderWithParams(v, "Bash", args, func() string {
+		if shellOutputInPane.Load() {
+			return ""
+		}
 		var meta tools.BashResponseMetadata
 		if err := br.unmarshalParams(v.result.Metadata, &meta); err != nil {
 			return renderPlainContent(v, v.result.Content)
@@ -248,7 +281,7 @@ func (vr viewRenderer) Render(v *toolCallCmp) string {
 
 	file := fsext.PrettyPath(params.FilePath)
 	args := newParamBuilder().
-		addMain(file).
+		addMain(styles.FileHyperlink(params.FilePath, file)).
 		addKeyValue("limit", formatNonZero(params.Limit)).
 		addKeyValue("offset", formatNonZero(params.Offset)).
 		build()
@@ -258,6 +291,9 @@ func (vr viewRenderer) Render(v *toolCallCmp) string {
 		if err := vr.unmarshalParams(v.result.Metadata, &meta); err != nil {
 			return renderPlainContent(v, v.result.Content)
 		}
+		if meta.Outline {
+			return renderPlainContent(v, meta.Content)
+		}
 		return renderCodeContent(v, meta.FilePath, meta.Content, params.Offset)
 	})
 }
@@ -286,7 +322,7 @@ func (er editRenderer) Render(v *toolCallCmp) string {
 	var args []string
 	if err := er.unmarshalParams(v.call.Input, &params); err == nil {
 		file := fsext.PrettyPath(params.FilePath)
-		args = newParamBuilder().addMain(file).build()
+		args = newParamBuilder().addMain(styles.FileHyperlink(params.FilePath, file)).build()
 	}
 
 	return er.renderWithParams(v, "Edit", args, func() string {
@@ -335,7 +371,7 @@ func (mer multiEditRenderer) Render(v *toolCallCmp) string {
 		file := fsext.PrettyPath(params.FilePath)
 		editsCount := len(params.Edits)
 		args = newParamBuilder().
-			addMain(file).
+			addMain(styles.FileHyperlink(params.FilePath, file)).
 			addKeyValue("edits", fmt.Sprintf("%d", editsCount)).
 			build()
 	}
@@ -368,6 +404,100 @@ func (mer multiEditRenderer) Render(v *toolCallCmp) string {
 	})
 }
 
+// -----------------------------------------------------------------------------
+//  Multi-file edit renderer
+// -----------------------------------------------------------------------------
+
+// multiFileEditRenderer handles search and replace across files
+type multiFileEditRenderer struct {
+	baseRenderer
+}
+
+// Render displays the edited files with their replacement counts
+func (mfr multiFileEditRenderer) Render(v *toolCallCmp) string {
+	var params tools.MultiFileEditParams
+	var args []string
+	if err := mfr.unmarshalParams(v.call.Input, &params); err == nil {
+		main := params.Pattern
+		if len(params.Files) > 0 {
+			main = fmt.Sprintf("%d files", len(params.Files))
+		}
+		args = newParamBuilder().
+			addMain(main).
+			addKeyValue("path", params.Path).
+			addKeyValue("edits", fmt.Sprintf("%d", len(params.Edits))).
+			build()
+	}
+
+	return mfr.renderWithParams(v, "Multi-File Edit", args, func() string {
+		var meta tools.MultiFileEditResponseMetadata
+		if err := mfr.unmarshalParams(v.result.Metadata, &meta); err != nil {
+			return renderPlainContent(v, v.result.Content)
+		}
+
+		lines := make([]string, 0, len(meta.Files))
+		for _, file := range meta.Files {
+			if file.Skipped != "" {
+				lines = append(lines, fmt.Sprintf("%s (skipped: %s)", fsext.PrettyPath(file.FilePath), file.Skipped))
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s +%d -%d (%d replacements)", fsext.PrettyPath(file.FilePath), file.Additions, file.Removals, file.Replacements))
+		}
+		return renderPlainContent(v, strings.Join(lines, "\n"))
+	})
+}
+
+// -----------------------------------------------------------------------------
+//  Artifact renderer
+// -----------------------------------------------------------------------------
+
+// artifactRenderer handles saving generated files as session artifacts
+type artifactRenderer struct {
+	baseRenderer
+}
+
+// Render displays the saved file with its name and kind
+func (ar artifactRenderer) Render(v *toolCallCmp) string {
+	var params tools.ArtifactParams
+	var args []string
+	if err := ar.unmarshalParams(v.call.Input, &params); err == nil {
+		args = newParamBuilder().
+			addMain(styles.FileHyperlink(params.FilePath, fsext.PrettyPath(params.FilePath))).
+			addKeyValue("name", params.Name).
+			addKeyValue("kind", params.Kind).
+			build()
+	}
+
+	return ar.renderWithParams(v, "Artifact", args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
+// -----------------------------------------------------------------------------
+//  Facts renderer
+// -----------------------------------------------------------------------------
+
+// factsRenderer handles recording the facts of the session
+type factsRenderer struct {
+	baseRenderer
+}
+
+// Render displays the key and category of the recorded fact
+func (fr factsRenderer) Render(v *toolCallCmp) string {
+	var params tools.FactsParams
+	var args []string
+	if err := fr.unmarshalParams(v.call.Input, &params); err == nil {
+		args = newParamBuilder().
+			addMain(params.Key).
+			addKeyValue("category", params.Category).
+			build()
+	}
+
+	return fr.renderWithParams(v, "Facts", args, func() string {
+		return renderPlainContent(v, params.Value)
+	})
+}
+
 // -----------------------------------------------------------------------------
 //  Write renderer
 // -----------------------------------------------------------------------------
@@ -384,7 +514,7 @@ func (wr writeRenderer) Render(v *toolCallCmp) string {
 	var file string
 	if err := wr.unmarshalParams(v.call.Input, &params); err == nil {
 		file = fsext.PrettyPath(params.FilePath)
-		args = newParamBuilder().addMain(file).build()
+		args = newParamBuilder().addMain(styles.FileHyperlink(params.FilePath, file)).build()
 	}
 
 	return wr.renderWithParams(v, "Write", args, func() string {
@@ -407,7 +537,7 @@ func (fr fetchRenderer) Render(v *toolCallCmp) string {
 	var args []string
 	if err := fr.unmarshalParams(v.call.Input, &params); err == nil {
 		args = newParamBuilder().
-			addMain(params.URL).
+			addMain(styles.Hyperlink(params.URL, params.URL)).
 			addKeyValue("format", params.Format).
 			addKeyValue("timeout", formatTimeout(params.Timeout)).
 			build()
@@ -454,8 +584,8 @@ func (dr downloadRenderer) Render(v *toolCallCmp) string {
 	var args []string
 	if err := dr.unmarshalParams(v.call.Input, &params); err == nil {
 		args = newParamBuilder().
-			addMain(params.URL).
-			addKeyValue("file_path", fsext.PrettyPath(params.FilePath)).
+			addMain(styles.Hyperlink(params.URL, params.URL)).
+			addKeyValue("file_path", styles.FileHyperlink(params.FilePath, fsext.PrettyPath(params.FilePath))).
 			addKeyValue("timeout", formatTimeout(params.Timeout)).
 			build()
 	}
@@ -513,7 +643,7 @@ func (gr grepRenderer) Render(v *toolCallCmp) string {
 	}
 
 	return gr.renderWithParams(v, "Grep", args, func() string {
-		return renderPlainContent(v, v.result.Content)
+		return renderGrepContent(v, v.result.Content)
 	})
 }
 
@@ -535,9 +665,8 @@ func (lr lsRenderer) Render(v *toolCallCmp) string {
 		if path == "" {
 			path = "."
 		}
-		path = fsext.PrettyPath(path)
 
-		args = newParamBuilder().addMain(path).build()
+		args = newParamBuilder().addMain(styles.FileHyperlink(path, fsext.PrettyPath(path))).build()
 	}
 
 	return lr.renderWithParams(v, "List", args, func() string {
@@ -739,7 +868,7 @@ func earlyState(header string, v *toolCallCmp) (string, bool) {
 
 func joinHeaderBody(header, body string) string {
 	t := styles.CurrentTheme()
-	if body == "" {
+	if body == "" || ToolOutputHidden() {
 		return header
 	}
 	body = t.S().Base.PaddingLeft(2).Render(body)
@@ -797,6 +926,48 @@ func getDigits(n int) int {
 	return digits
 }
 
+// grepMatch matches the lines of grep results, after the file they're in.
+var grepMatch = regexp.MustCompile(`^(\s+Line \d+(?:, Char \d+)?: )(.*)$`)
+
+// renderGrepContent renders grep results like plain content, with the
+// matched lines highlighted for the language of their file.
+func renderGrepContent(v *toolCallCmp, content string) string {
+	t := styles.CurrentTheme()
+	content = strings.ReplaceAll(content, "\r\n", "\n") // Normalize line endings
+	content = strings.ReplaceAll(content, "\t", "    ") // Replace tabs with spaces
+	content = strings.TrimSpace(content)
+	lines := strings.Split(content, "\n")
+
+	bg := t.BgBaseLighter
+	lineStyle := t.S().Muted.Background(bg)
+	width := v.textWidth() - 2 // -2 for left padding
+	var out []string
+	var path string
+	for i, ln := range lines {
+		if i >= responseContextHeight {
+			break
+		}
+		ln = ansiext.Escape(ln)
+		rendered := lineStyle.Render(" " + ln) // left padding
+		if m := grepMatch.FindStringSubmatch(ln); m != nil && path != "" {
+			if highlighted, err := highlight.SyntaxHighlight(m[2], path, bg); err == nil {
+				rendered = lineStyle.Render(" "+m[1]) + strings.TrimSuffix(highlighted, "\n")
+			}
+		} else if !strings.HasPrefix(ln, " ") && strings.HasSuffix(ln, ":") {
+			path = strings.TrimSuffix(ln, ":")
+		}
+		out = append(out, lineStyle.Width(width).Render(v.fit(rendered, width)))
+	}
+
+	if len(lines) > responseContextHeight {
+		out = append(out, lineStyle.
+			Width(width).
+			Render(fmt.Sprintf("… (%d lines)", len(lines)-responseContextHeight)))
+	}
+
+	return strings.Join(out, "\n")
+}
+
 func renderCodeContent(v *toolCallCmp, path, content string, offset int) string {
 	t := styles.CurrentTheme()
 	content = strings.ReplaceAll(content, "\r\n", "\n") // Normalize line endings
@@ -872,6 +1043,8 @@ func prettifyToolName(name string) string {
 		return "Edit"
 	case tools.MultiEditToolName:
 		return "Multi-Edit"
+	case tools.MultiFileEditToolName:
+		return "Multi-File Edit"
 	case tools.FetchToolName:
 		return "Fetch"
 	case tools.GlobToolName:
@@ -886,6 +1059,10 @@ func prettifyToolName(name string) string {
 		return "View"
 	case tools.WriteToolName:
 		return "Write"
+	case tools.ArtifactToolName:
+		return "Artifact"
+	case tools.FactsToolName:
+		return "Facts"
 	default:
 		return name
 	}
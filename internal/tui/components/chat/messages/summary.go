@@ -0,0 +1,94 @@
+package messages
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// SummaryKey is the key binding for expanding and collapsing the summary of
+// the turn at the end of a message.
+var SummaryKey = key.NewBinding(key.WithKeys("s", "S"), key.WithHelp("s", "toggle summary"))
+
+// renderRunSummary renders the summary of the turn as a footer: a single
+// line with the counts, and the files, commands and follow-ups below it when
+// expanded.
+func (m *messageCmp) renderRunSummary(summary message.RunSummary) string {
+	t := styles.CurrentTheme()
+	width := m.textWidth() - 2
+
+	counts := []string{fmt.Sprintf("%d steps", summary.Steps)}
+	if n := len(summary.FilesTouched); n > 0 {
+		counts = append(counts, plural(n, "file", "files")+" changed")
+	}
+	if n := len(summary.Commands); n > 0 {
+		counts = append(counts, plural(n, "command", "commands"))
+	}
+	switch summary.Tests {
+	case message.TestsPassed:
+		counts = append(counts, t.S().Base.Foreground(t.Success).Render("tests passed"))
+	case message.TestsFailed:
+		counts = append(counts, t.S().Base.Foreground(t.Error).Render("tests failed"))
+	}
+
+	arrow := "▸"
+	if m.summaryExpanded {
+		arrow = "▾"
+	}
+	header := t.S().Base.Foreground(t.FgMuted).Render(arrow+" Summary") + " " +
+		strings.Join(counts, t.S().Subtle.Render(" · "))
+	header = ansi.Truncate(header, width, "…")
+	if !m.summaryExpanded {
+		return header
+	}
+
+	muted := t.S().Base.Foreground(t.FgHalfMuted)
+	lines := []string{header}
+	section := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		lines = append(lines, "", t.S().Subtle.Render(title))
+		for _, item := range items {
+			lines = append(lines, ansi.Truncate(item, width, "…"))
+		}
+	}
+
+	files := make([]string, len(summary.FilesTouched))
+	for i, file := range summary.FilesTouched {
+		files[i] = "  " + muted.Render(fsext.PrettyPath(file))
+	}
+	section("Files", files)
+
+	commands := make([]string, len(summary.Commands))
+	for i, command := range summary.Commands {
+		icon := t.S().Base.Foreground(t.Success).Render(styles.ToolSuccess)
+		if command.Failed {
+			icon = t.S().Base.Foreground(t.Error).Render(styles.ToolError)
+		}
+		// Only the first line of multi-line commands fits.
+		first, _, _ := strings.Cut(command.Command, "\n")
+		commands[i] = "  " + icon + " " + muted.Render(first)
+	}
+	section("Commands", commands)
+
+	followUps := make([]string, len(summary.FollowUps))
+	for i, followUp := range summary.FollowUps {
+		followUps[i] = "  " + t.S().Base.Foreground(t.Warning).Render("•") + " " + muted.Render(strings.ReplaceAll(followUp, "`", ""))
+	}
+	section("Follow-ups", followUps)
+
+	return strings.Join(lines, "\n")
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, one)
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
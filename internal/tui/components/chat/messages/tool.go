@@ -15,6 +15,7 @@ import (
 	"github.com/charmbracelet/crush/internal/diff"
 	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/partialjson"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/tui/components/anim"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
@@ -118,20 +119,14 @@ func NewToolCallCmp(parentMessageID string, tc message.ToolCall, permissions per
 	for _, opt := range opts {
 		opt(m)
 	}
-	t := styles.CurrentTheme()
 	m.anim = anim.New(anim.Settings{
 		Size:        15,
 		Label:       "Working",
-		GradColorA:  t.Primary,
-		GradColorB:  t.Secondary,
-		LabelColor:  t.FgBase,
 		CycleColors: true,
 	})
 	if m.isNested {
 		m.anim = anim.New(anim.Settings{
 			Size:        10,
-			GradColorA:  t.Primary,
-			GradColorB:  t.Secondary,
 			CycleColors: true,
 		})
 	}
@@ -279,6 +274,18 @@ func (m *toolCallCmp) formatParametersForCopy() string {
 			parts = append(parts, fmt.Sprintf("**Edits:** %d", len(params.Edits)))
 			return strings.Join(parts, "\n")
 		}
+	case tools.MultiFileEditToolName:
+		var params tools.MultiFileEditParams
+		if json.Unmarshal([]byte(m.call.Input), &params) == nil {
+			var parts []string
+			if len(params.Files) > 0 {
+				parts = append(parts, fmt.Sprintf("**Files:** %d", len(params.Files)))
+			} else {
+				parts = append(parts, fmt.Sprintf("**Pattern:** %s", params.Pattern))
+			}
+			parts = append(parts, fmt.Sprintf("**Edits:** %d", len(params.Edits)))
+			return strings.Join(parts, "\n")
+		}
 	case tools.WriteToolName:
 		var params tools.WriteParams
 		if json.Unmarshal([]byte(m.call.Input), &params) == nil {
@@ -702,12 +709,39 @@ func (m *toolCallCmp) SetIsNested(isNested bool) {
 func (m *toolCallCmp) renderPending() string {
 	t := styles.CurrentTheme()
 	icon := t.S().Base.Foreground(t.GreenDark).Render(styles.ToolPending)
+	var tool string
 	if m.isNested {
-		tool := t.S().Base.Foreground(t.FgHalfMuted).Render(prettifyToolName(m.call.Name))
-		return fmt.Sprintf("%s %s %s", icon, tool, m.anim.View())
+		tool = t.S().Base.Foreground(t.FgHalfMuted).Render(prettifyToolName(m.call.Name))
+	} else {
+		tool = t.S().Base.Foreground(t.Blue).Render(prettifyToolName(m.call.Name))
 	}
-	tool := t.S().Base.Foreground(t.Blue).Render(prettifyToolName(m.call.Name))
-	return fmt.Sprintf("%s %s %s", icon, tool, m.anim.View())
+	prefix := fmt.Sprintf("%s %s ", icon, tool)
+	if param := m.pendingParam(); param != "" {
+		width := m.textWidth() - lipgloss.Width(prefix) - lipgloss.Width(m.anim.View()) - 1
+		prefix += t.S().Subtle.Render(ansi.Truncate(param, max(width, 0), "…")) + " "
+	}
+	return prefix + m.anim.View()
+}
+
+// pendingParams are the parameters shown while the input of a tool call is
+// streamed, in order of preference.
+var pendingParams = []string{"file_path", "path", "command", "pattern", "url", "query"}
+
+// pendingParam returns the main parameter of a tool call whose input is
+// still streamed, once the model finished writing it.
+func (m *toolCallCmp) pendingParam() string {
+	fields := partialjson.Fields(m.call.Input)
+	for _, name := range pendingParams {
+		var value string
+		if err := json.Unmarshal(fields[name], &value); err != nil || value == "" {
+			continue
+		}
+		if name == "file_path" || name == "path" {
+			value = fsext.PrettyPath(value)
+		}
+		return strings.ReplaceAll(value, "\n", " ")
+	}
+	return ""
 }
 
 // style returns the lipgloss style for the tool call component.
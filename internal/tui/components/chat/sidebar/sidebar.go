@@ -8,6 +8,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/artifact"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/diff"
@@ -44,7 +45,10 @@ const (
 	DefaultMaxFilesShown = 10
 	DefaultMaxLSPsShown  = 8
 	DefaultMaxMCPsShown  = 8
-	MinItemsPerSection   = 2 // Minimum items to show per section
+	// Artifacts are only shown when the session has any, so they don't
+	// take part in the dynamic limits.
+	DefaultMaxArtifactsShown = 5
+	MinItemsPerSection       = 2 // Minimum items to show per section
 )
 
 type SessionFile struct {
@@ -57,6 +61,11 @@ type SessionFilesMsg struct {
 	Files []SessionFile
 }
 
+type SessionArtifactsMsg struct {
+	SessionID string
+	Artifacts []artifact.Artifact
+}
+
 type Sidebar interface {
 	util.Model
 	layout.Sizeable
@@ -73,12 +82,17 @@ type sidebarCmp struct {
 	compactMode   bool
 	history       history.Service
 	files         *csync.Map[string, SessionFile]
+	artifacts     artifact.Service
+	// sessionArtifacts holds the artifacts of the current session, oldest
+	// first.
+	sessionArtifacts []artifact.Artifact
 }
 
-func New(history history.Service, lspClients *csync.Map[string, *lsp.Client], compact bool) Sidebar {
+func New(history history.Service, artifacts artifact.Service, lspClients *csync.Map[string, *lsp.Client], compact bool) Sidebar {
 	return &sidebarCmp{
 		lspClients:  lspClients,
 		history:     history,
+		artifacts:   artifacts,
 		compactMode: compact,
 		files:       csync.NewMap[string, SessionFile](),
 	}
@@ -96,9 +110,19 @@ func (m *sidebarCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			m.files.Set(file.FilePath, file)
 		}
 		return m, nil
+	case SessionArtifactsMsg:
+		if msg.SessionID == m.session.ID {
+			m.sessionArtifacts = msg.Artifacts
+		}
+		return m, nil
 
 	case chat.SessionClearedMsg:
 		m.session = session.Session{}
+		m.sessionArtifacts = nil
+	case pubsub.Event[artifact.Artifact]:
+		if msg.Type == pubsub.CreatedEvent && msg.Payload.SessionID == m.session.ID {
+			m.sessionArtifacts = append(m.sessionArtifacts, msg.Payload)
+		}
 	case pubsub.Event[history.File]:
 		return m, m.handleFileHistoryEvent(msg)
 	case pubsub.Event[session.Session]:
@@ -161,6 +185,9 @@ func (m *sidebarCmp) View() string {
 		// Vertical layout (default)
 		if m.session.ID != "" {
 			parts = append(parts, "", m.filesBlock())
+			if len(m.sessionArtifacts) > 0 {
+				parts = append(parts, "", m.artifactsBlock())
+			}
 		}
 		parts = append(parts,
 			"",
@@ -265,6 +292,24 @@ func (m *sidebarCmp) loadSessionFiles() tea.Msg {
 	}
 }
 
+func (m *sidebarCmp) loadSessionArtifacts() tea.Msg {
+	if m.artifacts == nil {
+		return nil
+	}
+	sessionID := m.session.ID
+	artifacts, err := m.artifacts.ListBySession(context.Background(), sessionID)
+	if err != nil {
+		return util.InfoMsg{
+			Type: util.InfoTypeError,
+			Msg:  err.Error(),
+		}
+	}
+	return SessionArtifactsMsg{
+		SessionID: sessionID,
+		Artifacts: artifacts,
+	}
+}
+
 func (m *sidebarCmp) SetSize(width, height int) tea.Cmd {
 	m.logo = m.logoBlock()
 	m.cwd = cwd()
@@ -477,6 +522,44 @@ func (m *sidebarCmp) filesBlock() string {
 	}, true)
 }
 
+func (m *sidebarCmp) artifactsBlock() string {
+	t := styles.CurrentTheme()
+	maxWidth := m.getMaxWidth()
+	list := []string{t.S().Subtle.Render(core.Section("Artifacts", maxWidth)), ""}
+
+	// Show the most recent artifacts first.
+	shown := 0
+	for _, a := range slices.Backward(m.sessionArtifacts) {
+		if shown >= DefaultMaxArtifactsShown {
+			break
+		}
+		size := t.S().Base.Foreground(t.FgSubtle).Render(formatSize(a.Size))
+		list = append(list, core.Status(core.StatusOpts{
+			Title:        a.Name,
+			Description:  a.Kind,
+			ExtraContent: size,
+		}, maxWidth))
+		shown++
+	}
+	if remaining := len(m.sessionArtifacts) - shown; remaining > 0 {
+		list = append(list, t.S().Base.Foreground(t.FgSubtle).Render(fmt.Sprintf("…and %d more", remaining)))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, list...)
+}
+
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 func (m *sidebarCmp) lspBlock() string {
 	// Limit the number of LSPs shown
 	_, maxLSPs, _ := m.getDynamicLimits()
@@ -598,7 +681,8 @@ func (s *sidebarCmp) currentModelBlock() string {
 // SetSession implements Sidebar.
 func (m *sidebarCmp) SetSession(session session.Session) tea.Cmd {
 	m.session = session
-	return m.loadSessionFiles
+	m.sessionArtifacts = nil
+	return tea.Batch(m.loadSessionFiles, m.loadSessionArtifacts)
 }
 
 // SetCompactMode sets the compact mode for the sidebar.
@@ -0,0 +1,222 @@
+// Package terminalpane is the pane next to the chat where the output of the
+// shell commands of the agent streams while they run.
+package terminalpane
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/terminal"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// maxRuns is the number of commands kept in the scrollback.
+	maxRuns = 50
+	// maxRunOutput is the most output kept for a command, the start of
+	// longer outputs is dropped.
+	maxRunOutput = 256 * 1024
+)
+
+type TerminalPane interface {
+	util.Model
+	layout.Sizeable
+	layout.Focusable
+	layout.Help
+	SetSession(sessionID string)
+}
+
+// run is a command run in the session and its output.
+type run struct {
+	toolCallID string
+	command    string
+	output     string
+	done       bool
+}
+
+type terminalPane struct {
+	width, height int
+	focused       bool
+	sessionID     string
+	runs          []*run
+	viewport      viewport.Model
+}
+
+func New() TerminalPane {
+	vp := viewport.New()
+	vp.SoftWrap = true
+	return &terminalPane{viewport: vp}
+}
+
+func (p *terminalPane) Init() tea.Cmd {
+	return nil
+}
+
+func (p *terminalPane) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case pubsub.Event[terminal.Output]:
+		if msg.Payload.SessionID != p.sessionID || p.sessionID == "" {
+			return p, nil
+		}
+		p.record(msg.Type, msg.Payload)
+		p.refresh()
+		return p, nil
+	case tea.KeyPressMsg:
+		if !p.focused {
+			return p, nil
+		}
+		var cmd tea.Cmd
+		p.viewport, cmd = p.viewport.Update(msg)
+		return p, cmd
+	case tea.MouseWheelMsg:
+		var cmd tea.Cmd
+		p.viewport, cmd = p.viewport.Update(msg)
+		return p, cmd
+	}
+	return p, nil
+}
+
+// record adds the output to the run of its tool call.
+func (p *terminalPane) record(eventType pubsub.EventType, output terminal.Output) {
+	if eventType == pubsub.CreatedEvent {
+		p.runs = append(p.runs, &run{toolCallID: output.ToolCallID, command: output.Command})
+		if len(p.runs) > maxRuns {
+			p.runs = slices.Delete(p.runs, 0, len(p.runs)-maxRuns)
+		}
+		return
+	}
+	idx := slices.IndexFunc(p.runs, func(r *run) bool {
+		return r.toolCallID == output.ToolCallID
+	})
+	if idx < 0 {
+		return
+	}
+	r := p.runs[idx]
+	r.output += output.Chunk
+	if len(r.output) > maxRunOutput {
+		r.output = strings.ToValidUTF8(r.output[len(r.output)-maxRunOutput:], "")
+	}
+	r.done = output.Done
+}
+
+// refresh renders the runs again, following the output unless the user
+// scrolled up.
+func (p *terminalPane) refresh() {
+	follow := p.viewport.AtBottom()
+	p.viewport.SetContent(p.content())
+	if follow {
+		p.viewport.GotoBottom()
+	}
+}
+
+func (p *terminalPane) content() string {
+	t := styles.CurrentTheme()
+	if len(p.runs) == 0 {
+		return t.S().Subtle.Render("The output of the commands run by the agent shows up here.")
+	}
+	var parts []string
+	for _, r := range p.runs {
+		command := strings.ReplaceAll(r.command, "\n", " ")
+		header := t.S().Base.Foreground(t.Primary).Render("$ ") + t.S().Text.Render(command)
+		if !r.done {
+			header += t.S().Subtle.Render(" (running)")
+		}
+		parts = append(parts, header)
+		if output := cleanOutput(r.output); output != "" {
+			parts = append(parts, t.S().Muted.Render(output))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// cleanOutput removes the escape sequences and carriage returns of the
+// output, which would break the layout of the pane.
+func cleanOutput(output string) string {
+	output = ansi.Strip(output)
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	for i, line := range lines {
+		// Keep what was last written over the line, like a progress bar.
+		if idx := strings.LastIndex(strings.TrimRight(line, "\r"), "\r"); idx >= 0 {
+			line = line[idx+1:]
+		}
+		lines[i] = strings.ReplaceAll(strings.TrimRight(line, "\r"), "\t", "    ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *terminalPane) View() string {
+	t := styles.CurrentTheme()
+	borderColor := t.Border
+	if p.focused {
+		borderColor = t.BorderFocus
+	}
+	style := t.S().Base.
+		Width(p.width).
+		Height(p.height).
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(borderColor).
+		PaddingLeft(1)
+	return style.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		core.Title("Terminal", p.innerWidth()),
+		"",
+		p.viewport.View(),
+	))
+}
+
+func (p *terminalPane) innerWidth() int {
+	return max(0, p.width-2) // border and padding
+}
+
+func (p *terminalPane) SetSize(width, height int) tea.Cmd {
+	p.width = width
+	p.height = height
+	p.viewport.SetWidth(p.innerWidth())
+	p.viewport.SetHeight(max(0, height-2)) // title and gap
+	p.refresh()
+	return nil
+}
+
+func (p *terminalPane) GetSize() (int, int) {
+	return p.width, p.height
+}
+
+func (p *terminalPane) Focus() tea.Cmd {
+	p.focused = true
+	return nil
+}
+
+func (p *terminalPane) Blur() tea.Cmd {
+	p.focused = false
+	return nil
+}
+
+func (p *terminalPane) IsFocused() bool {
+	return p.focused
+}
+
+// SetSession shows the output of the commands of the session, starting
+// with an empty scrollback.
+func (p *terminalPane) SetSession(sessionID string) {
+	if p.sessionID == sessionID {
+		return
+	}
+	p.sessionID = sessionID
+	p.runs = nil
+	p.refresh()
+}
+
+func (p *terminalPane) Bindings() []key.Binding {
+	km := p.viewport.KeyMap
+	return []key.Binding{km.Up, km.Down, km.PageUp, km.PageDown}
+}
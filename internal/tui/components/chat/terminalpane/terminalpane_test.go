@@ -0,0 +1,52 @@
+package terminalpane
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/terminal"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanOutput(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "red\nline", cleanOutput("\x1b[31mred\x1b[0m\r\nline\n"))
+	require.Equal(t, "100%", cleanOutput("10%\r50%\r100%\n"))
+	require.Equal(t, "    indented", cleanOutput("\tindented"))
+}
+
+func TestTerminalPane(t *testing.T) {
+	t.Parallel()
+
+	pane := New().(*terminalPane)
+	pane.SetSize(40, 10)
+	pane.SetSession("session")
+
+	send := func(eventType pubsub.EventType, output terminal.Output) {
+		pane.Update(pubsub.Event[terminal.Output]{Type: eventType, Payload: output})
+	}
+	send(pubsub.CreatedEvent, terminal.Output{SessionID: "session", ToolCallID: "1", Command: "make"})
+	send(pubsub.UpdatedEvent, terminal.Output{SessionID: "session", ToolCallID: "1", Chunk: "building\n"})
+	// Output of other sessions is ignored.
+	send(pubsub.CreatedEvent, terminal.Output{SessionID: "other", ToolCallID: "2", Command: "ls"})
+
+	require.Len(t, pane.runs, 1)
+	require.Contains(t, ansi.Strip(pane.View()), "$ make (running)")
+	require.Contains(t, ansi.Strip(pane.View()), "building")
+
+	send(pubsub.UpdatedEvent, terminal.Output{SessionID: "session", ToolCallID: "1", Done: true})
+	require.True(t, pane.runs[0].done)
+	require.NotContains(t, ansi.Strip(pane.View()), "(running)")
+
+	for i := range maxRuns {
+		send(pubsub.CreatedEvent, terminal.Output{SessionID: "session", ToolCallID: strings.Repeat("x", i+1)})
+	}
+	require.Len(t, pane.runs, maxRuns)
+	require.Equal(t, "x", pane.runs[0].toolCallID)
+
+	pane.SetSession("other")
+	require.Empty(t, pane.runs)
+}
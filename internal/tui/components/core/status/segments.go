@@ -0,0 +1,257 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/health"
+	"github.com/charmbracelet/crush/internal/shell"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// gitBranchCommand prints the branch checked out in the working
+	// directory.
+	gitBranchCommand  = "git branch --show-current"
+	gitBranchInterval = 5 * time.Second
+
+	// commandTimeout is how long the command of a segment may run.
+	commandTimeout = 5 * time.Second
+
+	// incidentTTL is how long a provider incident is shown after it was
+	// last reported.
+	incidentTTL = 15 * time.Minute
+
+	segmentSeparator = " · "
+)
+
+// segment is a piece of information shown on the right of the status bar.
+type segment struct {
+	config.StatusSegment
+	// command is run to get the text of the segment, for git branch and
+	// command segments.
+	command  string
+	interval time.Duration
+	output   string
+}
+
+// segmentOutputMsg carries the output of the command of a segment.
+type segmentOutputMsg struct {
+	index  int
+	output string
+}
+
+// segmentTickMsg runs the command of a segment again.
+type segmentTickMsg struct {
+	index int
+}
+
+// newSegments returns the segments to show, leaving out the invalid ones.
+func newSegments(segments []config.StatusSegment) []segment {
+	var result []segment
+	for _, cfg := range segments {
+		s := segment{StatusSegment: cfg, interval: cfg.RefreshInterval()}
+		switch cfg.Type {
+		case config.StatusSegmentModel, config.StatusSegmentTokens, config.StatusSegmentCost, config.StatusSegmentHealth, config.StatusSegmentRequests:
+		case config.StatusSegmentGitBranch:
+			s.command = gitBranchCommand
+			s.interval = gitBranchInterval
+		case config.StatusSegmentCommand:
+			if strings.TrimSpace(cfg.Command) == "" {
+				slog.Warn("Ignoring status bar command segment without a command")
+				continue
+			}
+			s.command = cfg.Command
+		default:
+			slog.Warn("Ignoring unknown status bar segment", "type", cfg.Type)
+			continue
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// runSegment runs the command of the segment in the working directory.
+func runSegment(index int, command, workingDir string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+		sh := shell.NewShell(&shell.Options{WorkingDir: workingDir})
+		stdout, _, err := sh.Exec(ctx, command)
+		if err != nil {
+			slog.Debug("Status bar segment command failed", "command", command, "error", err)
+			return segmentOutputMsg{index: index}
+		}
+		line, _, _ := strings.Cut(strings.TrimSpace(stdout), "\n")
+		return segmentOutputMsg{index: index, output: strings.TrimSpace(line)}
+	}
+}
+
+func (m *statusCmp) segmentCmds() []tea.Cmd {
+	var cmds []tea.Cmd
+	for i, s := range m.segments {
+		if s.command != "" {
+			cmds = append(cmds, runSegment(i, s.command, m.workingDir))
+		}
+	}
+	return cmds
+}
+
+func (m *statusCmp) handleSegmentOutput(msg segmentOutputMsg) tea.Cmd {
+	if msg.index >= len(m.segments) {
+		return nil
+	}
+	m.segments[msg.index].output = msg.output
+	return tea.Tick(m.segments[msg.index].interval, func(time.Time) tea.Msg {
+		return segmentTickMsg{index: msg.index}
+	})
+}
+
+func (m *statusCmp) handleSegmentTick(msg segmentTickMsg) tea.Cmd {
+	if msg.index >= len(m.segments) {
+		return nil
+	}
+	return runSegment(msg.index, m.segments[msg.index].command, m.workingDir)
+}
+
+// segmentText returns the text of the segment, empty when there is
+// nothing to show.
+func (m *statusCmp) segmentText(s segment) string {
+	switch s.Type {
+	case config.StatusSegmentModel:
+		if model := currentModel(); model != nil {
+			return model.Name
+		}
+	case config.StatusSegmentTokens:
+		if m.session.ID == "" {
+			return ""
+		}
+		tokens := m.session.PromptTokens + m.session.CompletionTokens
+		text := formatTokens(tokens)
+		if model := currentModel(); model != nil && model.ContextWindow > 0 {
+			text += fmt.Sprintf(" (%d%%)", int(float64(tokens)/float64(model.ContextWindow)*100))
+		}
+		return text
+	case config.StatusSegmentCost:
+		if m.session.ID == "" {
+			return ""
+		}
+		return fmt.Sprintf("$%.2f", m.session.Cost)
+	case config.StatusSegmentHealth:
+		return m.healthText()
+	case config.StatusSegmentRequests:
+		return m.requestsText()
+	case config.StatusSegmentGitBranch, config.StatusSegmentCommand:
+		return s.output
+	}
+	return ""
+}
+
+// healthText lists the providers with an ongoing incident.
+func (m *statusCmp) healthText() string {
+	var incidents []string
+	for provider, status := range m.incidents {
+		if time.Since(status.CheckedAt) > incidentTTL {
+			delete(m.incidents, provider)
+			continue
+		}
+		name := provider
+		if cfg := config.Get(); cfg != nil {
+			if providerCfg, ok := cfg.Providers.Get(provider); ok && providerCfg.Name != "" {
+				name = providerCfg.Name
+			}
+		}
+		incidents = append(incidents, fmt.Sprintf("%s %s %s", styles.WarningIcon, name, status.Indicator))
+	}
+	slices.Sort(incidents)
+	return strings.Join(incidents, " ")
+}
+
+// requestsText counts the requests to providers in flight and waiting for
+// their turn, empty while there are none.
+func (m *statusCmp) requestsText() string {
+	if m.requests.InFlight == 0 && m.requests.Queued == 0 {
+		return ""
+	}
+	text := fmt.Sprintf("%d in flight", m.requests.InFlight)
+	if m.requests.Queued > 0 {
+		text += fmt.Sprintf(", %d queued", m.requests.Queued)
+	}
+	return text
+}
+
+func (m *statusCmp) recordHealth(status health.Status) {
+	if !status.Incident() {
+		delete(m.incidents, status.Provider)
+		return
+	}
+	m.incidents[status.Provider] = status
+}
+
+// segmentsView renders the segments that fit in the width, in order. The
+// segments that don't fit are left out, starting with the last one.
+func (m *statusCmp) segmentsView(width int) string {
+	t := styles.CurrentTheme()
+	separator := t.S().Subtle.Render(segmentSeparator)
+	var parts []string
+	used := 0
+	for _, s := range m.segments {
+		text := m.segmentText(s)
+		if text == "" {
+			continue
+		}
+		if s.MaxWidth > 0 {
+			text = ansi.Truncate(text, s.MaxWidth, "…")
+		}
+		if pad := s.MinWidth - ansi.StringWidth(text); pad > 0 {
+			text = strings.Repeat(" ", pad) + text
+		}
+		needed := ansi.StringWidth(text)
+		if len(parts) > 0 {
+			needed += ansi.StringWidth(segmentSeparator)
+		}
+		if used+needed > width {
+			break
+		}
+		used += needed
+		parts = append(parts, t.S().Muted.Render(text))
+	}
+	return strings.Join(parts, separator)
+}
+
+func currentModel() *catwalk.Model {
+	cfg := config.Get()
+	if cfg == nil {
+		return nil
+	}
+	agentCfg, ok := cfg.Agents[config.AgentCoder]
+	if !ok {
+		return nil
+	}
+	return cfg.GetModelByType(agentCfg.Model)
+}
+
+// formatTokens formats a token count in a human-readable way, like 110K or
+// 1.2M.
+func formatTokens(tokens int64) string {
+	var formatted string
+	switch {
+	case tokens >= 1_000_000:
+		formatted = fmt.Sprintf("%.1fM", float64(tokens)/1_000_000)
+	case tokens >= 1_000:
+		formatted = fmt.Sprintf("%.1fK", float64(tokens)/1_000)
+	default:
+		return fmt.Sprintf("%d", tokens)
+	}
+	// Remove .0 suffix if present
+	formatted = strings.Replace(formatted, ".0K", "K", 1)
+	return strings.Replace(formatted, ".0M", "M", 1)
+}
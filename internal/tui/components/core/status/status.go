@@ -5,6 +5,12 @@ import (
 
 	"github.com/charmbracelet/bubbles/v2/help"
 	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/health"
+	"github.com/charmbracelet/crush/internal/inflight"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/lipgloss/v2"
@@ -23,6 +29,13 @@ type statusCmp struct {
 	messageTTL time.Duration
 	help       help.Model
 	keyMap     help.KeyMap
+
+	segments   []segment
+	workingDir string
+	session    session.Session
+	// incidents holds the ongoing incident of each provider.
+	incidents map[string]health.Status
+	requests  inflight.Gauge
 }
 
 // clearMessageCmd is a command that clears status messages after a timeout
@@ -33,7 +46,7 @@ func (m *statusCmp) clearMessageCmd(ttl time.Duration) tea.Cmd {
 }
 
 func (m *statusCmp) Init() tea.Cmd {
-	return nil
+	return tea.Batch(m.segmentCmds()...)
 }
 
 func (m *statusCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
@@ -43,6 +56,24 @@ func (m *statusCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		m.help.Width = msg.Width - 2
 		return m, nil
 
+	// Segments
+	case segmentOutputMsg:
+		return m, m.handleSegmentOutput(msg)
+	case segmentTickMsg:
+		return m, m.handleSegmentTick(msg)
+	case chat.SessionSelectedMsg:
+		m.session = msg
+	case chat.SessionClearedMsg:
+		m.session = session.Session{}
+	case pubsub.Event[session.Session]:
+		if msg.Payload.ID == m.session.ID {
+			m.session = msg.Payload
+		}
+	case pubsub.Event[health.Status]:
+		m.recordHealth(msg.Payload)
+	case pubsub.Event[inflight.Gauge]:
+		m.requests = msg.Payload
+
 	// Handle status info
 	case util.InfoMsg:
 		m.info = msg
@@ -59,11 +90,17 @@ func (m *statusCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 
 func (m *statusCmp) View() string {
 	t := styles.CurrentTheme()
-	status := t.S().Base.Padding(0, 1, 1, 1).Render(m.help.View(m.keyMap))
 	if m.info.Msg != "" {
-		status = m.infoMsg()
+		return m.infoMsg()
+	}
+	segments := m.segmentsView(m.width / 2)
+	if segments == "" {
+		m.help.Width = m.width - 2
+		return t.S().Base.Padding(0, 1, 1, 1).Render(m.help.View(m.keyMap))
 	}
-	return status
+	m.help.Width = m.width - lipgloss.Width(segments) - 3 // padding and a space before the segments
+	helpView := t.S().Base.Width(m.help.Width+2).Padding(0, 1, 1, 1).Render(m.help.View(m.keyMap))
+	return lipgloss.JoinHorizontal(lipgloss.Top, helpView, segments+" ")
 }
 
 func (m *statusCmp) infoMsg() string {
@@ -98,12 +135,17 @@ func (m *statusCmp) SetKeyMap(keyMap help.KeyMap) {
 	m.keyMap = keyMap
 }
 
-func NewStatusCmp() StatusCmp {
+// NewStatusCmp creates the status bar, showing the segments next to the
+// key help. Commands of the segments run in the working directory.
+func NewStatusCmp(segments []config.StatusSegment, workingDir string) StatusCmp {
 	t := styles.CurrentTheme()
 	help := help.New()
 	help.Styles = t.S().Help
 	return &statusCmp{
 		messageTTL: 5 * time.Second,
 		help:       help,
+		segments:   newSegments(segments),
+		workingDir: workingDir,
+		incidents:  make(map[string]health.Status),
 	}
 }
@@ -0,0 +1,92 @@
+package status
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/health"
+	"github.com/charmbracelet/crush/internal/inflight"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/tui/components/chat"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegments(t *testing.T) {
+	t.Parallel()
+
+	m := NewStatusCmp([]config.StatusSegment{
+		{Type: config.StatusSegmentTokens},
+		{Type: config.StatusSegmentCost, MinWidth: 7},
+		{Type: config.StatusSegmentCommand},
+		{Type: "weather"},
+		{Type: config.StatusSegmentHealth},
+		{Type: config.StatusSegmentCommand, Command: "echo deploying", MaxWidth: 6},
+	}, t.TempDir()).(*statusCmp)
+	m.SetKeyMap(core.NewSimpleHelp([]key.Binding{
+		key.NewBinding(key.WithKeys("ctrl+p"), key.WithHelp("ctrl+p", "commands")),
+	}, nil))
+	m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	require.Len(t, m.segments, 4, "invalid segments are left out")
+
+	require.Empty(t, m.segmentsView(40), "nothing to show without a session")
+
+	m.Update(chat.SessionSelectedMsg{ID: "1", PromptTokens: 1200, CompletionTokens: 300, Cost: 0.5})
+	m.Update(pubsub.Event[session.Session]{Payload: session.Session{ID: "1", PromptTokens: 2000, CompletionTokens: 500, Cost: 1.25}})
+	m.Update(pubsub.Event[session.Session]{Payload: session.Session{ID: "2", Cost: 9}})
+	require.Equal(t, "2.5K ·   $1.25", ansi.Strip(m.segmentsView(40)))
+
+	_, cmd := m.Update(segmentOutputMsg{index: 3, output: "deploying to staging"})
+	require.NotNil(t, cmd, "the command runs again later")
+	m.Update(pubsub.Event[health.Status]{Payload: health.Status{Provider: "openai", Indicator: health.IndicatorMajor, CheckedAt: time.Now()}})
+	require.Equal(t, "2.5K ·   $1.25 · ⚠ openai major · deplo…", ansi.Strip(m.segmentsView(40)))
+	require.Equal(t, "2.5K ·   $1.25", ansi.Strip(m.segmentsView(20)), "the last segments are left out")
+
+	view := ansi.Strip(m.View())
+	require.Contains(t, view, "ctrl+p commands")
+	require.Contains(t, view, "⚠ openai major · deplo…")
+	require.Equal(t, 80, ansi.StringWidth(strings.Split(view, "\n")[0]))
+
+	m.Update(pubsub.Event[health.Status]{Payload: health.Status{Provider: "openai", Indicator: health.IndicatorNone}})
+	m.Update(chat.SessionClearedMsg{})
+	require.Equal(t, "deplo…", ansi.Strip(m.segmentsView(40)))
+}
+
+func TestRequestsSegment(t *testing.T) {
+	t.Parallel()
+
+	m := NewStatusCmp([]config.StatusSegment{{Type: config.StatusSegmentRequests}}, t.TempDir()).(*statusCmp)
+	require.Empty(t, m.segmentsView(40))
+
+	m.Update(pubsub.Event[inflight.Gauge]{Payload: inflight.Gauge{InFlight: 2}})
+	require.Equal(t, "2 in flight", ansi.Strip(m.segmentsView(40)))
+	m.Update(pubsub.Event[inflight.Gauge]{Payload: inflight.Gauge{InFlight: 4, Queued: 3}})
+	require.Equal(t, "4 in flight, 3 queued", ansi.Strip(m.segmentsView(40)))
+	m.Update(pubsub.Event[inflight.Gauge]{Payload: inflight.Gauge{}})
+	require.Empty(t, m.segmentsView(40))
+}
+
+func TestRunSegment(t *testing.T) {
+	t.Parallel()
+
+	msg := runSegment(3, "echo main; echo other", t.TempDir())()
+	require.Equal(t, segmentOutputMsg{index: 3, output: "main"}, msg)
+
+	msg = runSegment(1, "exit 1", t.TempDir())()
+	require.Equal(t, segmentOutputMsg{index: 1}, msg)
+}
+
+func TestFormatTokens(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "999", formatTokens(999))
+	require.Equal(t, "1K", formatTokens(1000))
+	require.Equal(t, "110.5K", formatTokens(110_500))
+	require.Equal(t, "1.2M", formatTokens(1_200_000))
+}
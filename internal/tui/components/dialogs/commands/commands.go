@@ -1,7 +1,9 @@
 package commands
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/v2/help"
 	"github.com/charmbracelet/bubbles/v2/key"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/charmbracelet/crush/internal/agent"
 	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/tui/actions"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
@@ -61,6 +64,7 @@ type commandDialogCmp struct {
 
 type (
 	SwitchSessionsMsg      struct{}
+	OpenDraftsMsg          struct{}
 	NewSessionsMsg         struct{}
 	SwitchModelMsg         struct{}
 	QuitMsg                struct{}
@@ -71,9 +75,29 @@ type (
 	OpenReasoningDialogMsg struct{}
 	OpenExternalEditorMsg  struct{}
 	ToggleYoloModeMsg      struct{}
+	ToggleSidebarMsg       struct{}
+	ToggleTerminalMsg      struct{}
+	FollowLinkMsg          struct{}
 	CompactMsg             struct {
 		SessionID string
 	}
+	ExportSessionMsg struct {
+		SessionID string
+	}
+	OpenLogprobsMsg struct {
+		SessionID string
+	}
+	ToggleDryRunMsg struct {
+		SessionID string
+	}
+	TogglePlanModeMsg struct {
+		SessionID string
+	}
+	SetSessionEnvironmentMsg struct {
+		SessionID  string
+		WorkingDir string
+		Env        map[string]string
+	}
 )
 
 func NewCommandDialog(sessionID string) CommandsDialog {
@@ -257,48 +281,61 @@ func (c *commandDialogCmp) Position() (int, int) {
 	return row, col
 }
 
+// command returns the command running the action, with the title and keys
+// of the action registry.
+func command(id actions.ID, handler func(cmd Command) tea.Cmd) Command {
+	action, _ := actions.Get(id)
+	return Command{
+		ID:          string(id),
+		Title:       action.Title,
+		Description: action.Description,
+		Shortcut:    actions.Shortcut(id),
+		Handler:     handler,
+	}
+}
+
+// send returns a handler sending the message.
+func send(msg tea.Msg) func(cmd Command) tea.Cmd {
+	return func(Command) tea.Cmd {
+		return util.CmdHandler(msg)
+	}
+}
+
 func (c *commandDialogCmp) defaultCommands() []Command {
 	commands := []Command{
-		{
-			ID:          "new_session",
-			Title:       "New Session",
-			Description: "start a new session",
-			Shortcut:    "ctrl+n",
-			Handler: func(cmd Command) tea.Cmd {
-				return util.CmdHandler(NewSessionsMsg{})
-			},
-		},
-		{
-			ID:          "switch_session",
-			Title:       "Switch Session",
-			Description: "Switch to a different session",
-			Shortcut:    "ctrl+s",
-			Handler: func(cmd Command) tea.Cmd {
-				return util.CmdHandler(SwitchSessionsMsg{})
-			},
-		},
-		{
-			ID:          "switch_model",
-			Title:       "Switch Model",
-			Description: "Switch to a different model",
-			Handler: func(cmd Command) tea.Cmd {
-				return util.CmdHandler(SwitchModelMsg{})
-			},
-		},
+		command(actions.NewSession, send(NewSessionsMsg{})),
+		command(actions.SwitchSession, send(SwitchSessionsMsg{})),
+		command(actions.DraftHistory, send(OpenDraftsMsg{})),
+		command(actions.SwitchModel, send(SwitchModelMsg{})),
 	}
 
-	// Only show compact command if there's an active session
+	// Only show session commands if there's an active session
 	if c.sessionID != "" {
-		commands = append(commands, Command{
-			ID:          "Summarize",
-			Title:       "Summarize Session",
-			Description: "Summarize the current session and create a new one with the summary",
-			Handler: func(cmd Command) tea.Cmd {
-				return util.CmdHandler(CompactMsg{
-					SessionID: c.sessionID,
-				})
-			},
-		})
+		commands = append(commands,
+			command(actions.Summarize, send(CompactMsg{SessionID: c.sessionID})),
+			command(actions.ExportSession, send(ExportSessionMsg{SessionID: c.sessionID})),
+			command(actions.InspectLogprobs, send(OpenLogprobsMsg{SessionID: c.sessionID})),
+			command(actions.ToggleDryRun, send(ToggleDryRunMsg{SessionID: c.sessionID})),
+			command(actions.TogglePlanMode, send(TogglePlanModeMsg{SessionID: c.sessionID})),
+		)
+		sessionID := c.sessionID
+		commands = append(commands, command(actions.SessionEnv, func(cmd Command) tea.Cmd {
+			return util.CmdHandler(ShowArgumentsDialogMsg{
+				CommandID: cmd.ID,
+				ArgNames:  []string{"WORKING_DIR", "ENV"},
+				OnSubmit: func(args map[string]string) tea.Cmd {
+					env, err := ParseEnv(args["ENV"])
+					if err != nil {
+						return util.ReportError(err)
+					}
+					return util.CmdHandler(SetSessionEnvironmentMsg{
+						SessionID:  sessionID,
+						WorkingDir: strings.TrimSpace(args["WORKING_DIR"]),
+						Env:        env,
+					})
+				},
+			})
+		}))
 	}
 
 	// Add reasoning toggle for models that support it
@@ -315,109 +352,65 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				if selectedModel.Think {
 					status = "Disable"
 				}
-				commands = append(commands, Command{
-					ID:          "toggle_thinking",
-					Title:       status + " Thinking Mode",
-					Description: "Toggle model thinking for reasoning-capable models",
-					Handler: func(cmd Command) tea.Cmd {
-						return util.CmdHandler(ToggleThinkingMsg{})
-					},
-				})
+				toggle := command(actions.ToggleThinking, send(ToggleThinkingMsg{}))
+				toggle.Title = status + " Thinking Mode"
+				commands = append(commands, toggle)
 			}
 
 			// OpenAI models: reasoning effort dialog
 			if len(model.ReasoningLevels) > 0 {
-				commands = append(commands, Command{
-					ID:          "select_reasoning_effort",
-					Title:       "Select Reasoning Effort",
-					Description: "Choose reasoning effort level (low/medium/high)",
-					Handler: func(cmd Command) tea.Cmd {
-						return util.CmdHandler(OpenReasoningDialogMsg{})
-					},
-				})
+				commands = append(commands, command(actions.ReasoningEffort, send(OpenReasoningDialogMsg{})))
 			}
 		}
 	}
+	if c.sessionID != "" {
+		commands = append(commands,
+			command(actions.ToggleToolOutput, send(chat.ToggleToolOutputMsg{})),
+			command(actions.ToggleSidebar, send(ToggleSidebarMsg{})),
+			command(actions.ToggleTerminal, send(ToggleTerminalMsg{})),
+			command(actions.FollowLink, send(FollowLinkMsg{})),
+		)
+	}
 	// Only show toggle compact mode command if window width is larger than compact breakpoint (90)
 	if c.wWidth > 120 && c.sessionID != "" {
-		commands = append(commands, Command{
-			ID:          "toggle_sidebar",
-			Title:       "Toggle Sidebar",
-			Description: "Toggle between compact and normal layout",
-			Handler: func(cmd Command) tea.Cmd {
-				return util.CmdHandler(ToggleCompactModeMsg{})
-			},
-		})
+		commands = append(commands, command(actions.ToggleCompactMode, send(ToggleCompactModeMsg{})))
 	}
 	if c.sessionID != "" {
 		agentCfg := config.Get().Agents[config.AgentCoder]
 		model := config.Get().GetModelByType(agentCfg.Model)
 		if model.SupportsImages {
-			commands = append(commands, Command{
-				ID:          "file_picker",
-				Title:       "Open File Picker",
-				Shortcut:    "ctrl+f",
-				Description: "Open file picker",
-				Handler: func(cmd Command) tea.Cmd {
-					return util.CmdHandler(OpenFilePickerMsg{})
-				},
-			})
+			commands = append(commands, command(actions.AddAttachment, send(OpenFilePickerMsg{})))
 		}
 	}
 
 	// Add external editor command if $EDITOR is available
 	if os.Getenv("EDITOR") != "" {
-		commands = append(commands, Command{
-			ID:          "open_external_editor",
-			Title:       "Open External Editor",
-			Shortcut:    "ctrl+o",
-			Description: "Open external editor to compose message",
-			Handler: func(cmd Command) tea.Cmd {
-				return util.CmdHandler(OpenExternalEditorMsg{})
-			},
-		})
+		commands = append(commands, command(actions.OpenEditor, send(OpenExternalEditorMsg{})))
 	}
 
-	return append(commands, []Command{
-		{
-			ID:          "toggle_yolo",
-			Title:       "Toggle Yolo Mode",
-			Description: "Toggle yolo mode",
-			Handler: func(cmd Command) tea.Cmd {
-				return util.CmdHandler(ToggleYoloModeMsg{})
-			},
-		},
-		{
-			ID:          "toggle_help",
-			Title:       "Toggle Help",
-			Shortcut:    "ctrl+g",
-			Description: "Toggle help",
-			Handler: func(cmd Command) tea.Cmd {
-				return util.CmdHandler(ToggleHelpMsg{})
-			},
-		},
-		{
-			ID:          "init",
-			Title:       "Initialize Project",
-			Description: "Create/Update the CRUSH.md memory file",
-			Handler: func(cmd Command) tea.Cmd {
-				return util.CmdHandler(chat.SendMsg{
-					Text: agent.InitializePrompt(),
-				})
-			},
-		},
-		{
-			ID:          "quit",
-			Title:       "Quit",
-			Description: "Quit",
-			Shortcut:    "ctrl+c",
-			Handler: func(cmd Command) tea.Cmd {
-				return util.CmdHandler(QuitMsg{})
-			},
-		},
-	}...)
+	return append(commands,
+		command(actions.ToggleYolo, send(ToggleYoloModeMsg{})),
+		command(actions.ToggleHelp, send(ToggleHelpMsg{})),
+		command(actions.InitProject, send(chat.SendMsg{
+			Text: agent.InitializePrompt(),
+		})),
+		command(actions.Quit, send(QuitMsg{})),
+	)
 }
 
 func (c *commandDialogCmp) ID() dialogs.DialogID {
 	return CommandsDialogID
 }
+
+// ParseEnv parses space separated KEY=value pairs.
+func ParseEnv(s string) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, kv := range strings.Fields(s) {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid environment variable %q, expected KEY=value", kv)
+		}
+		env[key] = value
+	}
+	return env, nil
+}
@@ -45,23 +45,13 @@ func LoadCustomCommands() ([]Command, error) {
 
 func buildCommandSources(cfg *config.Config) []commandSource {
 	var sources []commandSource
-
-	// XDG config directory
-	if dir := getXDGCommandsDir(); dir != "" {
+	for _, dir := range UserCommandDirs() {
 		sources = append(sources, commandSource{
 			path:   dir,
 			prefix: UserCommandPrefix,
 		})
 	}
 
-	// Home directory
-	if home := home.Dir(); home != "" {
-		sources = append(sources, commandSource{
-			path:   filepath.Join(home, ".crush", "commands"),
-			prefix: UserCommandPrefix,
-		})
-	}
-
 	// Project directory
 	sources = append(sources, commandSource{
 		path:   filepath.Join(cfg.Options.DataDirectory, "commands"),
@@ -71,6 +61,19 @@ func buildCommandSources(cfg *config.Config) []commandSource {
 	return sources
 }
 
+// UserCommandDirs returns the directories of the user commands, the XDG
+// config directory first.
+func UserCommandDirs() []string {
+	var dirs []string
+	if dir := getXDGCommandsDir(); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if home := home.Dir(); home != "" {
+		dirs = append(dirs, filepath.Join(home, ".crush", "commands"))
+	}
+	return dirs
+}
+
 func getXDGCommandsDir() string {
 	xdgHome := os.Getenv("XDG_CONFIG_HOME")
 	if xdgHome == "" {
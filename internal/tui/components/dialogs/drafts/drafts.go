@@ -0,0 +1,210 @@
+package drafts
+
+import (
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/draft"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/sessions"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	DraftsDialogID dialogs.DialogID = "drafts"
+
+	// minPreviewWidth is the narrowest dialog that shows the preview pane.
+	minPreviewWidth = 80
+)
+
+// DraftSelectedMsg asks the editor to restore the draft.
+type DraftSelectedMsg struct {
+	Draft draft.Draft
+}
+
+type DraftsList = list.FilterableList[list.CompletionItem[draft.Draft]]
+
+type draftsDialogCmp struct {
+	wWidth     int
+	wHeight    int
+	width      int
+	keyMap     KeyMap
+	draftsList DraftsList
+	help       help.Model
+}
+
+// NewDraftsDialogCmp creates the dialog listing the drafts that weren't
+// sent, most recent first.
+func NewDraftsDialogCmp(drafts []draft.Draft) dialogs.DialogModel {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	draftsList := list.NewFilterableList(
+		items(drafts, time.Now()),
+		list.WithFilterPlaceholder("Search drafts"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &draftsDialogCmp{
+		keyMap:     keyMap,
+		draftsList: draftsList,
+		help:       help,
+	}
+}
+
+// items returns the list items of the drafts, titled by their first line
+// and with their age next to it.
+func items(drafts []draft.Draft, now time.Time) []list.CompletionItem[draft.Draft] {
+	items := make([]list.CompletionItem[draft.Draft], len(drafts))
+	for i, d := range drafts {
+		title, _, _ := strings.Cut(strings.TrimSpace(d.Text), "\n")
+		items[i] = list.NewCompletionItem(
+			title,
+			d,
+			list.WithCompletionID(d.ID),
+			list.WithCompletionShortcut(sessions.FormatAge(now.Sub(d.UpdatedAt))),
+		)
+	}
+	return items
+}
+
+func (d *draftsDialogCmp) Init() tea.Cmd {
+	return tea.Sequence(d.draftsList.Init(), d.draftsList.Focus())
+}
+
+func (d *draftsDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		d.wWidth = msg.Width
+		d.wHeight = msg.Height
+		d.width = min(120, d.wWidth-8)
+		d.draftsList.SetInputWidth(d.listWidth() - 2)
+		return d, d.draftsList.SetSize(d.listWidth(), d.listHeight())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, d.keyMap.Select):
+			selected := d.draftsList.SelectedItem()
+			if selected == nil {
+				return d, nil
+			}
+			return d, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				util.CmdHandler(DraftSelectedMsg{Draft: (*selected).Value()}),
+			)
+		case key.Matches(msg, d.keyMap.Close):
+			return d, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := d.draftsList.Update(msg)
+			d.draftsList = u.(DraftsList)
+			return d, cmd
+		}
+	}
+	return d, nil
+}
+
+func (d *draftsDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	listView := d.draftsList.View()
+	if len(d.draftsList.Items()) == 0 {
+		listView = t.S().Base.PaddingLeft(1).Width(d.width - 2).Render(t.S().Subtle.Render("No drafts"))
+	} else if d.showPreview() {
+		listView = lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			listView,
+			d.previewView(d.width-2-d.listWidth(), d.listHeight()),
+		)
+	}
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Drafts", d.width-4)),
+		listView,
+		"",
+		t.S().Base.Width(d.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(d.help.View(d.keyMap)),
+	)
+	return t.S().Base.
+		Width(d.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+// previewView renders the whole text of the highlighted draft.
+func (d *draftsDialogCmp) previewView(width, height int) string {
+	t := styles.CurrentTheme()
+	style := t.S().Base.
+		Width(width).
+		Height(height).
+		PaddingLeft(1).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderLeft(true).
+		BorderForeground(t.Border)
+
+	selected := d.draftsList.SelectedItem()
+	if selected == nil {
+		return style.Render("")
+	}
+	wrapped := ansi.Wrap(strings.TrimSpace((*selected).Value().Text), width-3, "")
+	lines := strings.Split(wrapped, "\n")
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return style.Render(t.S().Muted.Render(strings.Join(lines, "\n")))
+}
+
+func (d *draftsDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := d.draftsList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			row, col := d.Position()
+			cursor.Y += row + 3 // Border + title
+			cursor.X += col + 2
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (d *draftsDialogCmp) listHeight() int {
+	return d.wHeight/2 - 6 // 5 for the border, title and help
+}
+
+func (d *draftsDialogCmp) listWidth() int {
+	if d.showPreview() {
+		return (d.width - 2) / 2
+	}
+	return d.width - 2 // 2 for the border
+}
+
+func (d *draftsDialogCmp) showPreview() bool {
+	return d.width >= minPreviewWidth
+}
+
+func (d *draftsDialogCmp) Position() (int, int) {
+	row := d.wHeight/4 - 2 // just a bit above the center
+	col := d.wWidth/2 - d.width/2
+	return row, col
+}
+
+// ID implements dialogs.DialogModel.
+func (d *draftsDialogCmp) ID() dialogs.DialogID {
+	return DraftsDialogID
+}
@@ -0,0 +1,25 @@
+package drafts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/draft"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItems(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	items := items([]draft.Draft{
+		{ID: "a", Text: "\nRefactor the parser\nso errors point at the token", UpdatedAt: now.Add(-5 * time.Minute)},
+		{ID: "b", Text: "Add tests", UpdatedAt: now.Add(-3 * time.Hour)},
+	}, now)
+
+	require.Len(t, items, 2)
+	require.Equal(t, "Refactor the parser", items[0].Text())
+	require.Equal(t, "a", items[0].ID())
+	require.Equal(t, "Add tests", items[1].Text())
+	require.Equal(t, "b", items[1].Value().ID)
+}
@@ -0,0 +1,201 @@
+package inspector
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/log"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const InspectorDialogID dialogs.DialogID = "inspector"
+
+// InspectorDialog shows the most recent provider requests and responses.
+type InspectorDialog interface {
+	dialogs.DialogModel
+}
+
+type inspectorDialogCmp struct {
+	wWidth  int
+	wHeight int
+	width   int
+	height  int
+
+	exchanges []log.HTTPExchange
+	selected  int
+	dumpPath  string
+
+	viewport viewport.Model
+	keyMap   KeyMap
+	help     help.Model
+}
+
+// NewInspectorDialog creates a new provider request inspector. The current
+// snapshot of requests is also written to the log directory.
+func NewInspectorDialog() InspectorDialog {
+	t := styles.CurrentTheme()
+	help := help.New()
+	help.Styles = t.S().Help
+	return &inspectorDialogCmp{
+		exchanges: log.HTTPExchanges(),
+		viewport:  viewport.New(),
+		keyMap:    DefaultKeyMap(),
+		help:      help,
+	}
+}
+
+func (i *inspectorDialogCmp) Init() tea.Cmd {
+	path, err := log.WriteHTTPExchanges()
+	if err != nil {
+		return util.ReportError(err)
+	}
+	i.dumpPath = path
+	return nil
+}
+
+func (i *inspectorDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		i.wWidth = msg.Width
+		i.wHeight = msg.Height
+		i.width = min(120, i.wWidth-8)
+		i.height = i.wHeight - 8
+		i.viewport.SetWidth(i.width - 4)
+		i.viewport.SetHeight(max(1, i.height-8))
+		i.refreshContent()
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, i.keyMap.Close):
+			return i, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case key.Matches(msg, i.keyMap.Previous):
+			if i.selected > 0 {
+				i.selected--
+				i.refreshContent()
+			}
+		case key.Matches(msg, i.keyMap.Next):
+			if i.selected < len(i.exchanges)-1 {
+				i.selected++
+				i.refreshContent()
+			}
+		case key.Matches(msg, i.keyMap.ScrollUp):
+			i.viewport.ScrollUp(1)
+		case key.Matches(msg, i.keyMap.ScrollDown):
+			i.viewport.ScrollDown(1)
+		}
+	case tea.MouseWheelMsg:
+		switch msg.Button {
+		case tea.MouseWheelDown:
+			i.viewport.ScrollDown(1)
+		case tea.MouseWheelUp:
+			i.viewport.ScrollUp(1)
+		}
+	}
+	return i, nil
+}
+
+func (i *inspectorDialogCmp) refreshContent() {
+	i.viewport.SetContent(i.renderExchange())
+	i.viewport.GotoTop()
+}
+
+func (i *inspectorDialogCmp) renderExchange() string {
+	t := styles.CurrentTheme()
+	if len(i.exchanges) == 0 {
+		return t.S().Muted.Render("No provider requests captured yet. Requests are only captured in debug mode.")
+	}
+	ex := i.exchanges[i.selected]
+	width := i.width - 4
+
+	var sb strings.Builder
+	field := func(name, value string) {
+		if value == "" {
+			return
+		}
+		sb.WriteString(t.S().Muted.Render(name+": ") + value + "\n")
+	}
+	field("Time", ex.Time.Format(time.DateTime))
+	field("Duration", ex.Duration.Truncate(time.Millisecond).String())
+	field("Request ID", ex.RequestID)
+	field("Rate limit remaining", ex.RateLimitRemaining)
+	field("Error", ex.Error)
+	sb.WriteString("\n")
+
+	sb.WriteString(core.Section("Request headers", width) + "\n")
+	sb.WriteString(renderHeaders(ex.RequestHeaders))
+	sb.WriteString(core.Section("Request body", width) + "\n")
+	sb.WriteString(ex.RequestBody + "\n\n")
+	sb.WriteString(core.Section("Response headers", width) + "\n")
+	sb.WriteString(renderHeaders(ex.ResponseHeaders))
+	sb.WriteString(core.Section("Response body", width) + "\n")
+	sb.WriteString(ex.ResponseBody + "\n")
+	return t.S().Base.Width(width).Render(sb.String())
+}
+
+func renderHeaders(headers map[string][]string) string {
+	var sb strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		fmt.Fprintf(&sb, "%s: %s\n", name, strings.Join(headers[name], ", "))
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func (i *inspectorDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	title := core.Title("Provider Requests", i.width-4)
+
+	summary := t.S().Muted.Render("No requests")
+	if len(i.exchanges) > 0 {
+		ex := i.exchanges[i.selected]
+		status := t.S().Base.Foreground(t.Success).Render(fmt.Sprintf("%d", ex.StatusCode))
+		if ex.StatusCode == 0 || ex.StatusCode >= 400 {
+			status = t.S().Base.Foreground(t.Error).Render(fmt.Sprintf("%d", ex.StatusCode))
+		}
+		summary = fmt.Sprintf("%d/%d  %s %s  %s", i.selected+1, len(i.exchanges), ex.Method, ex.URL, status)
+	}
+	summary = t.S().Base.Width(i.width - 4).MaxHeight(1).Render(summary)
+
+	var path string
+	if i.dumpPath != "" {
+		path = t.S().Subtle.Width(i.width - 4).Render("Saved to " + i.dumpPath)
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		"",
+		summary,
+		path,
+		"",
+		i.viewport.View(),
+		"",
+		i.help.View(i.keyMap),
+	)
+	return t.S().Base.
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Width(i.width).
+		Render(content)
+}
+
+func (i *inspectorDialogCmp) Position() (int, int) {
+	row := (i.wHeight - i.height) / 2
+	col := (i.wWidth - i.width) / 2
+	return max(0, row-2), max(0, col)
+}
+
+func (i *inspectorDialogCmp) ID() dialogs.DialogID {
+	return InspectorDialogID
+}
@@ -0,0 +1,65 @@
+package inspector
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+)
+
+// KeyMap defines the keyboard bindings for the provider request inspector.
+type KeyMap struct {
+	Previous,
+	Next,
+	ScrollUp,
+	ScrollDown,
+	Close key.Binding
+}
+
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Previous: key.NewBinding(
+			key.WithKeys("left", "h"),
+			key.WithHelp("←", "newer"),
+		),
+		Next: key.NewBinding(
+			key.WithKeys("right", "l"),
+			key.WithHelp("→", "older"),
+		),
+		ScrollUp: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑", "scroll up"),
+		),
+		ScrollDown: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓", "scroll down"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc", "alt+esc"),
+			key.WithHelp("esc", "close"),
+		),
+	}
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k KeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.Previous,
+		k.Next,
+		k.ScrollUp,
+		k.ScrollDown,
+		k.Close,
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.KeyBindings()}
+}
+
+// ShortHelp implements help.KeyMap.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		k.Previous,
+		k.Next,
+		k.ScrollDown,
+		k.Close,
+	}
+}
@@ -0,0 +1,172 @@
+package links
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	LinksDialogID dialogs.DialogID = "links"
+
+	// MaxLinks is the most links offered in the dialog.
+	MaxLinks = 36
+
+	// hintAlphabet are the keys hints are made of, from the home row.
+	hintAlphabet = "asdfghjkl"
+)
+
+// LinksDialog lets the user follow one of the links of the conversation by
+// typing its hint, like the follow mode of vimium.
+type LinksDialog interface {
+	dialogs.DialogModel
+}
+
+type linksDialogCmp struct {
+	wWidth     int
+	wHeight    int
+	width      int
+	workingDir string
+	links      []Link
+	hints      []string
+	typed      string
+	keyMap     KeyMap
+	help       help.Model
+}
+
+// NewLinksDialog creates a new dialog to follow one of the links, the
+// first ones get the shortest hints.
+func NewLinksDialog(workingDir string, links []Link) LinksDialog {
+	t := styles.CurrentTheme()
+	links = links[:min(len(links), MaxLinks)]
+	help := help.New()
+	help.Styles = t.S().Help
+	return &linksDialogCmp{
+		workingDir: workingDir,
+		links:      links,
+		hints:      hintLabels(len(links)),
+		keyMap:     DefaultKeyMap(),
+		help:       help,
+	}
+}
+
+// hintLabels returns n hints of the same length, so none is the prefix of
+// another.
+func hintLabels(n int) []string {
+	labels := []string{""}
+	for len(labels) < n {
+		var next []string
+		for _, label := range labels {
+			for _, r := range hintAlphabet {
+				next = append(next, label+string(r))
+			}
+		}
+		labels = next
+	}
+	return labels[:n]
+}
+
+func (l *linksDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (l *linksDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		l.wWidth = msg.Width
+		l.wHeight = msg.Height
+		l.width = min(80, l.wWidth-8)
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, l.keyMap.Close):
+			return l, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case key.Matches(msg, l.keyMap.Backspace):
+			if l.typed != "" {
+				l.typed = l.typed[:len(l.typed)-1]
+			}
+		case key.Matches(msg, l.keyMap.Hint):
+			return l, l.typeHint(msg.String())
+		}
+	}
+	return l, nil
+}
+
+// typeHint adds the key to the typed hint, following the link once the
+// hint is complete. Keys that don't lead to a hint are ignored.
+func (l *linksDialogCmp) typeHint(k string) tea.Cmd {
+	typed := l.typed + k
+	for i, hint := range l.hints {
+		if hint == typed {
+			return tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				Open(l.workingDir, l.links[i]),
+			)
+		}
+		if strings.HasPrefix(hint, typed) {
+			l.typed = typed
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *linksDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	var rows []string
+	for i, link := range l.links {
+		if !strings.HasPrefix(l.hints[i], l.typed) {
+			continue
+		}
+		hint := t.S().Base.Foreground(t.Primary).Render(l.typed) +
+			t.S().Base.Foreground(t.Secondary).Bold(true).Render(strings.TrimPrefix(l.hints[i], l.typed))
+		text := t.S().Text
+		if link.URL {
+			text = text.Underline(true)
+		}
+		label := ansi.Truncate(link.String(), l.width-len(l.hints[i])-6, "…")
+		rows = append(rows, hint+"  "+text.Render(label))
+		if len(rows) >= l.listHeight() {
+			break
+		}
+	}
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Follow Link", l.width-4)),
+		t.S().Base.PaddingLeft(1).Render(strings.Join(rows, "\n")),
+		"",
+		t.S().Base.Width(l.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(l.help.View(l.keyMap)),
+	)
+	return l.style().Render(content)
+}
+
+func (l *linksDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(l.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (l *linksDialogCmp) listHeight() int {
+	return max(1, l.wHeight/2-6) // 5 for the border, title and help
+}
+
+func (l *linksDialogCmp) Position() (int, int) {
+	row := l.wHeight/4 - 2 // just a bit above the center
+	col := l.wWidth / 2
+	col -= l.width / 2
+	return row, col
+}
+
+// ID implements LinksDialog.
+func (l *linksDialogCmp) ID() dialogs.DialogID {
+	return LinksDialogID
+}
@@ -0,0 +1,55 @@
+package links
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+)
+
+type KeyMap struct {
+	Hint,
+	Backspace,
+	Close key.Binding
+}
+
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Hint: key.NewBinding(
+			key.WithKeys(hintKeys()...),
+			key.WithHelp(hintAlphabet[:1]+"-"+hintAlphabet[len(hintAlphabet)-1:], "follow hint"),
+		),
+		Backspace: key.NewBinding(
+			key.WithKeys("backspace"),
+			key.WithHelp("backspace", "erase"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc", "alt+esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+	}
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k KeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.Hint,
+		k.Backspace,
+		k.Close,
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{k.KeyBindings()}
+}
+
+// ShortHelp implements help.KeyMap.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return k.KeyBindings()
+}
+
+func hintKeys() []string {
+	keys := make([]string, len(hintAlphabet))
+	for i, r := range hintAlphabet {
+		keys[i] = string(r)
+	}
+	return keys
+}
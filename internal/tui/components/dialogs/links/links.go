@@ -0,0 +1,173 @@
+package links
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/home"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+var (
+	urlRe = regexp.MustCompile("https?://[^\\s<>\"'`)\\]]+")
+	// pathRe matches paths after a space or an opening quote or bracket,
+	// with an optional line and column: path, path:10, path:10:5.
+	pathRe = regexp.MustCompile("(?:^|[\\s(\\[{\"'`])((?:~/|\\.{1,2}/|/)?[\\w.@+-]+(?:/[\\w.@+-]+)*)(?::(\\d+))?(?::\\d+)?")
+)
+
+// Link is a URL or a file path mentioned in a message.
+type Link struct {
+	// Target is the URL, or the path of the file relative to the working
+	// directory or absolute.
+	Target string
+	// Line is the line of the file referenced, zero when there's none.
+	Line int
+	URL  bool
+}
+
+// String returns the link the way it's shown.
+func (l Link) String() string {
+	if l.Line > 0 {
+		return fmt.Sprintf("%s:%d", l.Target, l.Line)
+	}
+	return l.Target
+}
+
+// Find returns the URLs and the paths of existing files in the text, in
+// order and without duplicates.
+func Find(workingDir, text string) []Link {
+	var links []Link
+	add := func(link Link) {
+		if !slices.Contains(links, link) {
+			links = append(links, link)
+		}
+	}
+	for _, url := range urlRe.FindAllString(text, -1) {
+		add(Link{Target: strings.TrimRight(url, ".,;:!?"), URL: true})
+	}
+	// URLs would otherwise be matched as paths too.
+	text = urlRe.ReplaceAllString(text, " ")
+	for _, match := range pathRe.FindAllStringSubmatch(text, -1) {
+		path := strings.TrimRight(match[1], ".")
+		if !strings.ContainsAny(path, "./") {
+			continue
+		}
+		if info, err := os.Stat(resolvePath(workingDir, path)); err != nil || info.IsDir() {
+			continue
+		}
+		line, _ := strconv.Atoi(match[2])
+		add(Link{Target: path, Line: line})
+	}
+	return links
+}
+
+// FromMessages returns the links of the messages, the ones of the latest
+// messages first, up to MaxLinks.
+func FromMessages(workingDir string, msgs []message.Message) []Link {
+	var links []Link
+	for _, msg := range slices.Backward(msgs) {
+		texts := []string{msg.Content().Text}
+		for _, result := range msg.ToolResults() {
+			texts = append(texts, result.Content)
+		}
+		for _, text := range texts {
+			for _, link := range Find(workingDir, text) {
+				if !slices.Contains(links, link) {
+					links = append(links, link)
+				}
+				if len(links) == MaxLinks {
+					return links
+				}
+			}
+		}
+	}
+	return links
+}
+
+func resolvePath(workingDir, path string) string {
+	path = home.Long(path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(workingDir, filepath.FromSlash(path))
+}
+
+// Open opens the URL in the browser or the file in $EDITOR, at its line.
+func Open(workingDir string, link Link) tea.Cmd {
+	if link.URL {
+		return func() tea.Msg {
+			if err := openURL(link.Target); err != nil {
+				return util.ReportError(err)()
+			}
+			return util.ReportInfo("Opened " + link.Target)()
+		}
+	}
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		// Use platform-appropriate default editor
+		if runtime.GOOS == "windows" {
+			editor = []string{"notepad"}
+		} else {
+			editor = []string{"nvim"}
+		}
+	}
+	args := editorArgs(editor, resolvePath(workingDir, link.Target), link.Line)
+	c := exec.Command(args[0], args[1:]...)
+	c.Dir = workingDir
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return tea.ExecProcess(c, func(err error) tea.Msg {
+		if err != nil {
+			return util.ReportError(err)()
+		}
+		return nil
+	})
+}
+
+// editorArgs returns the command line opening the file in the editor at the
+// line, in the syntax the editor understands.
+func editorArgs(editor []string, path string, line int) []string {
+	args := slices.Clone(editor)
+	if line <= 0 {
+		return append(args, path)
+	}
+	switch strings.TrimSuffix(filepath.Base(editor[0]), ".exe") {
+	case "code", "code-insiders", "codium", "cursor", "windsurf":
+		return append(args, "--goto", fmt.Sprintf("%s:%d", path, line))
+	case "hx", "helix", "micro", "subl", "zed":
+		return append(args, fmt.Sprintf("%s:%d", path, line))
+	case "notepad":
+		return append(args, path)
+	default:
+		// vi, vim, nvim, nano, emacs, kak and most others.
+		return append(args, fmt.Sprintf("+%d", line), path)
+	}
+}
+
+// openURL opens the URL in the default browser, without waiting for it to
+// exit.
+func openURL(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open %s: %w", url, err)
+	}
+	return cmd.Process.Release()
+}
@@ -0,0 +1,77 @@
+package links
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFind(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "internal", "app"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "internal", "app", "app.go"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), nil, 0o644))
+
+	text := "See `internal/app/app.go:42` and README.md. Docs at https://example.com/docs, " +
+		"not missing.go nor the internal/app directory. Again internal/app/app.go:42."
+	require.Equal(t, []Link{
+		{Target: "https://example.com/docs", URL: true},
+		{Target: "internal/app/app.go", Line: 42},
+		{Target: "README.md"},
+	}, Find(dir, text))
+}
+
+func TestFromMessages(t *testing.T) {
+	t.Parallel()
+
+	msgs := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "read https://old.example.com"}}},
+		{Role: message.Tool, Parts: []message.ContentPart{message.ToolResult{Content: "https://tool.example.com"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "https://new.example.com https://old.example.com"}}},
+	}
+	require.Equal(t, []Link{
+		{Target: "https://new.example.com", URL: true},
+		{Target: "https://old.example.com", URL: true},
+		{Target: "https://tool.example.com", URL: true},
+	}, FromMessages(t.TempDir(), msgs))
+}
+
+func TestEditorArgs(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"nvim", "+12", "main.go"}, editorArgs([]string{"nvim"}, "main.go", 12))
+	require.Equal(t, []string{"nvim", "main.go"}, editorArgs([]string{"nvim"}, "main.go", 0))
+	require.Equal(t, []string{"code", "-w", "--goto", "main.go:12"}, editorArgs([]string{"code", "-w"}, "main.go", 12))
+	require.Equal(t, []string{"/usr/bin/hx", "main.go:12"}, editorArgs([]string{"/usr/bin/hx"}, "main.go", 12))
+}
+
+func TestHintLabels(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{"a", "s", "d"}, hintLabels(3))
+	labels := hintLabels(MaxLinks)
+	require.Len(t, labels, MaxLinks)
+	require.Equal(t, "aa", labels[0])
+	require.Equal(t, "as", labels[1])
+}
+
+func TestTypeHint(t *testing.T) {
+	t.Parallel()
+
+	links := make([]Link, 10)
+	for i := range links {
+		links[i] = Link{Target: "https://example.com", URL: true}
+	}
+	dialog := NewLinksDialog(t.TempDir(), links).(*linksDialogCmp)
+	require.Nil(t, dialog.typeHint("s"))
+	require.Equal(t, "s", dialog.typed)
+	// No hint starts with "sz", the key is ignored.
+	require.Nil(t, dialog.typeHint("z"))
+	require.Equal(t, "s", dialog.typed)
+	require.NotNil(t, dialog.typeHint("a"))
+}
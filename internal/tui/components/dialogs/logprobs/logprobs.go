@@ -0,0 +1,247 @@
+package logprobs
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const (
+	LogprobsDialogID dialogs.DialogID = "logprobs"
+
+	// Tokens at least as likely as confident are shown as usual, those at
+	// least as likely as unsure in the warning color and the others in the
+	// error color.
+	confident = 0.9
+	unsure    = 0.5
+
+	// maxDoubts is how many of the least likely tokens are listed.
+	maxDoubts = 10
+)
+
+// LogprobsDialog colors the tokens of the responses of a session by how
+// confident the model was in them.
+type LogprobsDialog interface {
+	dialogs.DialogModel
+}
+
+type logprobsDialogCmp struct {
+	wWidth  int
+	wHeight int
+	width   int
+	height  int
+
+	// messages are the responses with log probabilities, newest first.
+	messages []message.Message
+	selected int
+
+	viewport viewport.Model
+	keyMap   KeyMap
+	help     help.Model
+}
+
+// WithLogprobs returns the messages with log probabilities, newest first.
+func WithLogprobs(msgs []message.Message) []message.Message {
+	var out []message.Message
+	for _, msg := range slices.Backward(msgs) {
+		if len(msg.Logprobs()) > 0 {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// NewLogprobsDialog creates an inspector of the log probabilities of the
+// messages, which must have some.
+func NewLogprobsDialog(msgs []message.Message) LogprobsDialog {
+	t := styles.CurrentTheme()
+	help := help.New()
+	help.Styles = t.S().Help
+	return &logprobsDialogCmp{
+		messages: WithLogprobs(msgs),
+		viewport: viewport.New(),
+		keyMap:   DefaultKeyMap(),
+		help:     help,
+	}
+}
+
+func (l *logprobsDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (l *logprobsDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		l.wWidth = msg.Width
+		l.wHeight = msg.Height
+		l.width = min(120, l.wWidth-8)
+		l.height = l.wHeight - 8
+		l.viewport.SetWidth(l.width - 4)
+		l.viewport.SetHeight(max(1, l.height-8))
+		l.refreshContent()
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, l.keyMap.Close):
+			return l, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case key.Matches(msg, l.keyMap.Previous):
+			if l.selected > 0 {
+				l.selected--
+				l.refreshContent()
+			}
+		case key.Matches(msg, l.keyMap.Next):
+			if l.selected < len(l.messages)-1 {
+				l.selected++
+				l.refreshContent()
+			}
+		case key.Matches(msg, l.keyMap.ScrollUp):
+			l.viewport.ScrollUp(1)
+		case key.Matches(msg, l.keyMap.ScrollDown):
+			l.viewport.ScrollDown(1)
+		}
+	case tea.MouseWheelMsg:
+		switch msg.Button {
+		case tea.MouseWheelDown:
+			l.viewport.ScrollDown(1)
+		case tea.MouseWheelUp:
+			l.viewport.ScrollUp(1)
+		}
+	}
+	return l, nil
+}
+
+func (l *logprobsDialogCmp) refreshContent() {
+	l.viewport.SetContent(l.renderMessage())
+	l.viewport.GotoTop()
+}
+
+func (l *logprobsDialogCmp) renderMessage() string {
+	t := styles.CurrentTheme()
+	if len(l.messages) == 0 {
+		return t.S().Muted.Render("No token probabilities in this session.")
+	}
+	msg := l.messages[l.selected]
+	tokens := msg.Logprobs()
+	width := l.width - 4
+
+	var sb strings.Builder
+	for _, token := range tokens {
+		style := tokenStyle(token.Probability())
+		// Styles are applied line by line so wrapping keeps the colors.
+		for i, line := range strings.Split(token.Token, "\n") {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			sb.WriteString(style.Render(line))
+		}
+	}
+	sb.WriteString("\n\n")
+
+	doubts := Doubts(tokens, maxDoubts)
+	if len(doubts) > 0 {
+		sb.WriteString(core.Section("Least confident tokens", width) + "\n")
+		for _, token := range doubts {
+			line := tokenStyle(token.Probability()).Render(fmt.Sprintf("%-16s %4s", strconv.Quote(token.Token), percent(token.Probability())))
+			var alternatives []string
+			for _, top := range token.TopLogprobs {
+				if top.Token == token.Token {
+					continue
+				}
+				alternatives = append(alternatives, fmt.Sprintf("%s %s", strconv.Quote(top.Token), percent(message.TokenLogprob{Logprob: top.Logprob}.Probability())))
+			}
+			if len(alternatives) > 0 {
+				line += t.S().Muted.Render("  instead of " + strings.Join(alternatives, ", "))
+			}
+			sb.WriteString(line + "\n")
+		}
+	}
+	return t.S().Base.Width(width).Render(sb.String())
+}
+
+// Doubts returns up to n of the least likely tokens, least likely first.
+func Doubts(tokens []message.TokenLogprob, n int) []message.TokenLogprob {
+	doubts := slices.DeleteFunc(slices.Clone(tokens), func(token message.TokenLogprob) bool {
+		return token.Probability() >= confident || strings.TrimSpace(token.Token) == ""
+	})
+	slices.SortStableFunc(doubts, func(a, b message.TokenLogprob) int {
+		return cmp.Compare(a.Logprob, b.Logprob)
+	})
+	return doubts[:min(n, len(doubts))]
+}
+
+func tokenStyle(probability float64) lipgloss.Style {
+	t := styles.CurrentTheme()
+	switch {
+	case probability >= confident:
+		return t.S().Base
+	case probability >= unsure:
+		return t.S().Warning
+	default:
+		return t.S().Error
+	}
+}
+
+func percent(probability float64) string {
+	return fmt.Sprintf("%.0f%%", probability*100)
+}
+
+func (l *logprobsDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	title := core.Title("Token Confidence", l.width-4)
+
+	summary := t.S().Muted.Render("No responses")
+	if len(l.messages) > 0 {
+		tokens := l.messages[l.selected].Logprobs()
+		var total float64
+		for _, token := range tokens {
+			total += token.Probability()
+		}
+		summary = fmt.Sprintf("%d/%d  %d tokens, %s confident on average", l.selected+1, len(l.messages), len(tokens), percent(total/float64(len(tokens))))
+	}
+	summary = t.S().Base.Width(l.width - 4).MaxHeight(1).Render(summary)
+	legend := fmt.Sprintf("%s  %s  %s",
+		tokenStyle(confident).Render(fmt.Sprintf("■ ≥%s", percent(confident))),
+		tokenStyle(unsure).Render(fmt.Sprintf("■ ≥%s", percent(unsure))),
+		tokenStyle(0).Render(fmt.Sprintf("■ <%s", percent(unsure))),
+	)
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		"",
+		summary,
+		legend,
+		"",
+		l.viewport.View(),
+		"",
+		l.help.View(l.keyMap),
+	)
+	return t.S().Base.
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Width(l.width).
+		Render(content)
+}
+
+func (l *logprobsDialogCmp) Position() (int, int) {
+	row := (l.wHeight - l.height) / 2
+	col := (l.wWidth - l.width) / 2
+	return max(0, row-2), max(0, col)
+}
+
+func (l *logprobsDialogCmp) ID() dialogs.DialogID {
+	return LogprobsDialogID
+}
@@ -0,0 +1,41 @@
+package logprobs
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoubts(t *testing.T) {
+	t.Parallel()
+
+	tokens := []message.TokenLogprob{
+		{Token: "The", Logprob: -0.01},
+		{Token: " answer", Logprob: -0.5},
+		{Token: "\n", Logprob: -3},
+		{Token: " is", Logprob: -0.05},
+		{Token: " 42", Logprob: -2.3},
+	}
+	require.Equal(t, []message.TokenLogprob{
+		{Token: " 42", Logprob: -2.3},
+		{Token: " answer", Logprob: -0.5},
+	}, Doubts(tokens, 10))
+	require.Equal(t, []message.TokenLogprob{{Token: " 42", Logprob: -2.3}}, Doubts(tokens, 1))
+}
+
+func TestWithLogprobs(t *testing.T) {
+	t.Parallel()
+
+	logprobs := message.Logprobs{Tokens: []message.TokenLogprob{{Token: "Hi"}}}
+	msgs := []message.Message{
+		{ID: "1", Role: message.Assistant, Parts: []message.ContentPart{logprobs}},
+		{ID: "2", Role: message.User},
+		{ID: "3", Role: message.Assistant, Parts: []message.ContentPart{logprobs}},
+		{ID: "4", Role: message.Assistant},
+	}
+	got := WithLogprobs(msgs)
+	require.Len(t, got, 2)
+	require.Equal(t, "3", got[0].ID)
+	require.Equal(t, "1", got[1].ID)
+}
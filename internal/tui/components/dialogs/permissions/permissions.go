@@ -11,6 +11,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/agent/tools"
 	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/i18n"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
@@ -243,18 +244,19 @@ func (p *permissionDialogCmp) renderButtons() string {
 
 	buttons := []core.ButtonOpts{
 		{
-			Text:           "Allow",
-			UnderlineIndex: 0, // "A"
+			Text:           i18n.T("permissions.allow"),
+			UnderlineIndex: 0,
 			Selected:       p.selectedOption == 0,
 		},
 		{
-			Text:           "Allow for Session",
-			UnderlineIndex: 10, // "S" in "Session"
+			// Underline the "s" shortcut if the translation has one.
+			Text:           i18n.T("permissions.allow_session"),
+			UnderlineIndex: strings.LastIndex(strings.ToLower(i18n.T("permissions.allow_session")), "s"),
 			Selected:       p.selectedOption == 1,
 		},
 		{
-			Text:           "Deny",
-			UnderlineIndex: 0, // "D"
+			Text:           i18n.T("permissions.deny"),
+			UnderlineIndex: 0,
 			Selected:       p.selectedOption == 2,
 		},
 	}
@@ -275,12 +277,12 @@ func (p *permissionDialogCmp) renderHeader() string {
 	t := styles.CurrentTheme()
 	baseStyle := t.S().Base
 
-	toolKey := t.S().Muted.Render("Tool")
+	toolKey := t.S().Muted.Render(i18n.T("permissions.tool"))
 	toolValue := t.S().Text.
 		Width(p.width - lipgloss.Width(toolKey)).
 		Render(fmt.Sprintf(" %s", p.permission.ToolName))
 
-	pathKey := t.S().Muted.Render("Path")
+	pathKey := t.S().Muted.Render(i18n.T("permissions.path"))
 	pathValue := t.S().Text.
 		Width(p.width - lipgloss.Width(pathKey)).
 		Render(fmt.Sprintf(" %s", fsext.PrettyPath(p.permission.Path)))
@@ -303,14 +305,14 @@ func (p *permissionDialogCmp) renderHeader() string {
 	// Add tool-specific header information
 	switch p.permission.ToolName {
 	case tools.BashToolName:
-		headerParts = append(headerParts, t.S().Muted.Width(p.width).Render("Command"))
+		headerParts = append(headerParts, t.S().Muted.Width(p.width).Render(i18n.T("permissions.command")))
 	case tools.DownloadToolName:
 		params := p.permission.Params.(tools.DownloadPermissionsParams)
 		urlKey := t.S().Muted.Render("URL")
 		urlValue := t.S().Text.
 			Width(p.width - lipgloss.Width(urlKey)).
 			Render(fmt.Sprintf(" %s", params.URL))
-		fileKey := t.S().Muted.Render("File")
+		fileKey := t.S().Muted.Render(i18n.T("permissions.file"))
 		filePath := t.S().Text.
 			Width(p.width - lipgloss.Width(fileKey)).
 			Render(fmt.Sprintf(" %s", fsext.PrettyPath(params.FilePath)))
@@ -330,7 +332,7 @@ func (p *permissionDialogCmp) renderHeader() string {
 		)
 	case tools.EditToolName:
 		params := p.permission.Params.(tools.EditPermissionsParams)
-		fileKey := t.S().Muted.Render("File")
+		fileKey := t.S().Muted.Render(i18n.T("permissions.file"))
 		filePath := t.S().Text.
 			Width(p.width - lipgloss.Width(fileKey)).
 			Render(fmt.Sprintf(" %s", fsext.PrettyPath(params.FilePath)))
@@ -345,7 +347,7 @@ func (p *permissionDialogCmp) renderHeader() string {
 
 	case tools.WriteToolName:
 		params := p.permission.Params.(tools.WritePermissionsParams)
-		fileKey := t.S().Muted.Render("File")
+		fileKey := t.S().Muted.Render(i18n.T("permissions.file"))
 		filePath := t.S().Text.
 			Width(p.width - lipgloss.Width(fileKey)).
 			Render(fmt.Sprintf(" %s", fsext.PrettyPath(params.FilePath)))
@@ -359,7 +361,7 @@ func (p *permissionDialogCmp) renderHeader() string {
 		)
 	case tools.MultiEditToolName:
 		params := p.permission.Params.(tools.MultiEditPermissionsParams)
-		fileKey := t.S().Muted.Render("File")
+		fileKey := t.S().Muted.Render(i18n.T("permissions.file"))
 		filePath := t.S().Text.
 			Width(p.width - lipgloss.Width(fileKey)).
 			Render(fmt.Sprintf(" %s", fsext.PrettyPath(params.FilePath)))
@@ -371,11 +373,25 @@ func (p *permissionDialogCmp) renderHeader() string {
 			),
 			baseStyle.Render(strings.Repeat(" ", p.width)),
 		)
+	case tools.MultiFileEditToolName:
+		params := p.permission.Params.(tools.MultiFileEditPermissionsParams)
+		filesKey := t.S().Muted.Render(i18n.T("permissions.files"))
+		filesValue := t.S().Text.
+			Width(p.width - lipgloss.Width(filesKey)).
+			Render(fmt.Sprintf(" %d", len(params.Files)))
+		headerParts = append(headerParts,
+			lipgloss.JoinHorizontal(
+				lipgloss.Left,
+				filesKey,
+				filesValue,
+			),
+			baseStyle.Render(strings.Repeat(" ", p.width)),
+		)
 	case tools.FetchToolName:
 		headerParts = append(headerParts, t.S().Muted.Width(p.width).Bold(true).Render("URL"))
 	case tools.ViewToolName:
 		params := p.permission.Params.(tools.ViewPermissionsParams)
-		fileKey := t.S().Muted.Render("File")
+		fileKey := t.S().Muted.Render(i18n.T("permissions.file"))
 		filePath := t.S().Text.
 			Width(p.width - lipgloss.Width(fileKey)).
 			Render(fmt.Sprintf(" %s", fsext.PrettyPath(params.FilePath)))
@@ -389,7 +405,7 @@ func (p *permissionDialogCmp) renderHeader() string {
 		)
 	case tools.LSToolName:
 		params := p.permission.Params.(tools.LSPermissionsParams)
-		pathKey := t.S().Muted.Render("Directory")
+		pathKey := t.S().Muted.Render(i18n.T("permissions.directory"))
 		pathValue := t.S().Text.
 			Width(p.width - lipgloss.Width(pathKey)).
 			Render(fmt.Sprintf(" %s", fsext.PrettyPath(params.Path)))
@@ -425,6 +441,8 @@ func (p *permissionDialogCmp) getOrGenerateContent() string {
 		content = p.generateWriteContent()
 	case tools.MultiEditToolName:
 		content = p.generateMultiEditContent()
+	case tools.MultiFileEditToolName:
+		content = p.generateMultiFileEditContent()
 	case tools.FetchToolName:
 		content = p.generateFetchContent()
 	case tools.ViewToolName:
@@ -557,6 +575,40 @@ func (p *permissionDialogCmp) generateMultiEditContent() string {
 	return ""
 }
 
+func (p *permissionDialogCmp) generateMultiFileEditContent() string {
+	t := styles.CurrentTheme()
+	baseStyle := t.S().Base.Background(t.BgSubtle)
+	if pr, ok := p.permission.Params.(tools.MultiFileEditPermissionsParams); ok {
+		// The combined diff spans several files, so it's shown as plain
+		// unified diff text instead of through the diff viewer.
+		lines := strings.Split(strings.TrimSpace(pr.Diff), "\n")
+
+		width := p.width - 4
+		var out []string
+		for _, ln := range lines {
+			style := t.S().Muted.Foreground(t.FgBase)
+			switch {
+			case strings.HasPrefix(ln, "+++"), strings.HasPrefix(ln, "---"):
+				style = t.S().Muted.Bold(true)
+			case strings.HasPrefix(ln, "+"):
+				style = t.S().Success
+			case strings.HasPrefix(ln, "-"):
+				style = t.S().Error
+			}
+			out = append(out, style.
+				Width(width).
+				Padding(0, 3).
+				Background(t.BgSubtle).
+				Render(ln))
+		}
+
+		return baseStyle.
+			Width(p.contentViewPort.Width()).
+			Render(strings.Join(out, "\n"))
+	}
+	return ""
+}
+
 func (p *permissionDialogCmp) generateFetchContent() string {
 	t := styles.CurrentTheme()
 	baseStyle := t.S().Base.Background(t.BgSubtle)
@@ -690,7 +742,7 @@ func (p *permissionDialogCmp) styleViewport() string {
 func (p *permissionDialogCmp) render() string {
 	t := styles.CurrentTheme()
 	baseStyle := t.S().Base
-	title := core.Title("Permission Required", p.width-4)
+	title := core.Title(i18n.T("permissions.title"), p.width-4)
 	// Render header
 	headerContent := p.renderHeader()
 	// Render buttons
@@ -773,6 +825,9 @@ func (p *permissionDialogCmp) SetSize() tea.Cmd {
 	case tools.MultiEditToolName:
 		p.width = int(float64(p.wWidth) * 0.8)
 		p.height = int(float64(p.wHeight) * 0.8)
+	case tools.MultiFileEditToolName:
+		p.width = int(float64(p.wWidth) * 0.8)
+		p.height = int(float64(p.wHeight) * 0.8)
 	case tools.FetchToolName:
 		p.width = int(float64(p.wWidth) * 0.8)
 		p.height = int(float64(p.wHeight) * 0.3)
@@ -0,0 +1,164 @@
+package plan
+
+import (
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/bubbles/v2/textarea"
+	"github.com/charmbracelet/bubbles/v2/viewport"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/i18n"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const PlanDialogID dialogs.DialogID = "plan"
+
+// ApprovedMsg is sent when the user approves the plan of a session.
+type ApprovedMsg struct {
+	SessionID string
+	Plan      string
+}
+
+// PlanDialog lets the user review, edit and approve the plan proposed by the
+// agent before it makes any changes.
+type PlanDialog interface {
+	dialogs.DialogModel
+}
+
+type planDialogCmp struct {
+	wWidth  int
+	wHeight int
+	width   int
+	height  int
+
+	sessionID string
+	editing   bool
+
+	viewport viewport.Model
+	textarea *textarea.Model
+	keyMap   KeyMap
+	help     help.Model
+}
+
+func NewPlanDialog(sessionID, plan string) PlanDialog {
+	t := styles.CurrentTheme()
+	help := help.New()
+	help.Styles = t.S().Help
+
+	ta := textarea.New()
+	ta.SetStyles(t.S().TextArea)
+	ta.ShowLineNumbers = false
+	ta.CharLimit = -1
+	ta.SetValue(plan)
+
+	return &planDialogCmp{
+		sessionID: sessionID,
+		viewport:  viewport.New(),
+		textarea:  ta,
+		keyMap:    DefaultKeyMap(),
+		help:      help,
+	}
+}
+
+func (p *planDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (p *planDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.wWidth = msg.Width
+		p.wHeight = msg.Height
+		p.width = min(100, p.wWidth-8)
+		p.height = p.wHeight - 8
+		p.viewport.SetWidth(p.width - 4)
+		p.viewport.SetHeight(max(1, p.height-6))
+		p.textarea.SetWidth(p.width - 4)
+		p.textarea.SetHeight(max(1, p.height-6))
+		p.refreshContent()
+	case tea.KeyPressMsg:
+		if p.editing {
+			if key.Matches(msg, p.keyMap.DoneEditing) {
+				p.editing = false
+				p.textarea.Blur()
+				p.refreshContent()
+				return p, nil
+			}
+			var cmd tea.Cmd
+			p.textarea, cmd = p.textarea.Update(msg)
+			return p, cmd
+		}
+		switch {
+		case key.Matches(msg, p.keyMap.Close):
+			return p, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case key.Matches(msg, p.keyMap.Approve):
+			return p, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				util.CmdHandler(ApprovedMsg{
+					SessionID: p.sessionID,
+					Plan:      p.textarea.Value(),
+				}),
+			)
+		case key.Matches(msg, p.keyMap.Edit):
+			p.editing = true
+			return p, p.textarea.Focus()
+		case key.Matches(msg, p.keyMap.ScrollUp):
+			p.viewport.ScrollUp(1)
+		case key.Matches(msg, p.keyMap.ScrollDown):
+			p.viewport.ScrollDown(1)
+		}
+	case tea.MouseWheelMsg:
+		switch msg.Button {
+		case tea.MouseWheelDown:
+			p.viewport.ScrollDown(1)
+		case tea.MouseWheelUp:
+			p.viewport.ScrollUp(1)
+		}
+	}
+	return p, nil
+}
+
+func (p *planDialogCmp) refreshContent() {
+	t := styles.CurrentTheme()
+	p.viewport.SetContent(t.S().Base.Width(p.width - 4).Render(p.textarea.Value()))
+}
+
+func (p *planDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	title := core.Title(i18n.T("plan.title"), p.width-4)
+
+	body := p.viewport.View()
+	helpView := p.help.View(p.keyMap)
+	if p.editing {
+		body = p.textarea.View()
+		helpView = p.help.ShortHelpView([]key.Binding{p.keyMap.DoneEditing})
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		"",
+		body,
+		"",
+		helpView,
+	)
+	return t.S().Base.
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Width(p.width).
+		Render(content)
+}
+
+func (p *planDialogCmp) Position() (int, int) {
+	row := (p.wHeight - p.height) / 2
+	col := (p.wWidth - p.width) / 2
+	return max(0, row-2), max(0, col)
+}
+
+func (p *planDialogCmp) ID() dialogs.DialogID {
+	return PlanDialogID
+}
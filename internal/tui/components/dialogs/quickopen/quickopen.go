@@ -0,0 +1,165 @@
+package quickopen
+
+import (
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const QuickOpenDialogID dialogs.DialogID = "quickopen"
+
+// FileSelectedMsg is sent when a file is chosen in the dialog, to be
+// mentioned in the prompt.
+type FileSelectedMsg struct {
+	Path string
+}
+
+// QuickOpenDialog is a fuzzy finder over the files of the project.
+type QuickOpenDialog interface {
+	dialogs.DialogModel
+}
+
+type FilesList = list.FilterableList[list.CompletionItem[string]]
+
+type quickOpenDialogCmp struct {
+	wWidth    int
+	wHeight   int
+	width     int
+	keyMap    KeyMap
+	filesList FilesList
+	help      help.Model
+}
+
+// NewQuickOpenDialogCmp creates a new dialog to find one of the files,
+// given relative to the working directory.
+func NewQuickOpenDialogCmp(files []string) QuickOpenDialog {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	items := make([]list.CompletionItem[string], len(files))
+	for i, file := range files {
+		items[i] = list.NewCompletionItem(file, file, list.WithCompletionID(file))
+	}
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	filesList := list.NewFilterableList(
+		items,
+		list.WithFilterPlaceholder("Find a file to mention"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &quickOpenDialogCmp{
+		keyMap:    keyMap,
+		filesList: filesList,
+		help:      help,
+	}
+}
+
+func (q *quickOpenDialogCmp) Init() tea.Cmd {
+	return tea.Sequence(q.filesList.Init(), q.filesList.Focus())
+}
+
+func (q *quickOpenDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		q.wWidth = msg.Width
+		q.wHeight = msg.Height
+		q.width = min(100, q.wWidth-8)
+		q.filesList.SetInputWidth(q.listWidth() - 2)
+		return q, q.filesList.SetSize(q.listWidth(), q.listHeight())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, q.keyMap.Select):
+			selectedItem := q.filesList.SelectedItem()
+			if selectedItem == nil {
+				return q, nil
+			}
+			return q, tea.Sequence(
+				util.CmdHandler(dialogs.CloseDialogMsg{}),
+				util.CmdHandler(FileSelectedMsg{Path: (*selectedItem).Value()}),
+			)
+		case key.Matches(msg, q.keyMap.Close):
+			return q, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := q.filesList.Update(msg)
+			q.filesList = u.(FilesList)
+			return q, cmd
+		}
+	}
+	return q, nil
+}
+
+func (q *quickOpenDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Mention File", q.width-4)),
+		q.filesList.View(),
+		"",
+		t.S().Base.Width(q.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(q.help.View(q.keyMap)),
+	)
+	return q.style().Render(content)
+}
+
+func (q *quickOpenDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := q.filesList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			cursor = q.moveCursor(cursor)
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (q *quickOpenDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(q.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (q *quickOpenDialogCmp) listHeight() int {
+	return q.wHeight/2 - 6 // 5 for the border, title and help
+}
+
+func (q *quickOpenDialogCmp) listWidth() int {
+	return q.width - 2 // 2 for the border
+}
+
+func (q *quickOpenDialogCmp) Position() (int, int) {
+	row := q.wHeight/4 - 2 // just a bit above the center
+	col := q.wWidth / 2
+	col -= q.width / 2
+	return row, col
+}
+
+func (q *quickOpenDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
+	row, col := q.Position()
+	offset := row + 3 // Border + title
+	cursor.Y += offset
+	cursor.X = cursor.X + col + 2
+	return cursor
+}
+
+// ID implements QuickOpenDialog.
+func (q *quickOpenDialogCmp) ID() dialogs.DialogID {
+	return QuickOpenDialogID
+}
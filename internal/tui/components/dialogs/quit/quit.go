@@ -3,16 +3,14 @@ package quit
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
 	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/i18n"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/lipgloss/v2"
 )
 
-const (
-	question                      = "Are you sure you want to quit?"
-	QuitDialogID dialogs.DialogID = "quit"
-)
+const QuitDialogID dialogs.DialogID = "quit"
 
 // QuitDialog represents a confirmation dialog for quitting the application.
 type QuitDialog interface {
@@ -80,10 +78,12 @@ func (q *quitDialogCmp) View() string {
 	}
 
 	const horizontalPadding = 3
-	yesButton := yesStyle.PaddingLeft(horizontalPadding).Underline(true).Render("Y") +
-		yesStyle.PaddingRight(horizontalPadding).Render("ep!")
-	noButton := noStyle.PaddingLeft(horizontalPadding).Underline(true).Render("N") +
-		noStyle.PaddingRight(horizontalPadding).Render("ope")
+	question := i18n.T("quit.question")
+	yes, no := []rune(i18n.T("quit.yes")), []rune(i18n.T("quit.no"))
+	yesButton := yesStyle.PaddingLeft(horizontalPadding).Underline(true).Render(string(yes[:1])) +
+		yesStyle.PaddingRight(horizontalPadding).Render(string(yes[1:]))
+	noButton := noStyle.PaddingLeft(horizontalPadding).Underline(true).Render(string(no[:1])) +
+		noStyle.PaddingRight(horizontalPadding).Render(string(no[1:]))
 
 	buttons := baseStyle.Width(lipgloss.Width(question)).Align(lipgloss.Right).Render(
 		lipgloss.JoinHorizontal(lipgloss.Center, yesButton, "  ", noButton),
@@ -110,7 +110,7 @@ func (q *quitDialogCmp) Position() (int, int) {
 	row := q.wHeight / 2
 	row -= 7 / 2
 	col := q.wWidth / 2
-	col -= (lipgloss.Width(question) + 4) / 2
+	col -= (lipgloss.Width(i18n.T("quit.question")) + 4) / 2
 
 	return row, col
 }
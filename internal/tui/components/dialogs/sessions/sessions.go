@@ -1,10 +1,17 @@
 package sessions
 
 import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/charmbracelet/bubbles/v2/help"
 	"github.com/charmbracelet/bubbles/v2/key"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/event"
+	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
@@ -13,9 +20,81 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	SessionsDialogID dialogs.DialogID = "sessions"
+
+	// PreviewMessages is how many of the latest messages of each session
+	// are previewed and searched.
+	PreviewMessages = 4
+
+	// minPreviewWidth is the narrowest dialog that shows the preview pane.
+	minPreviewWidth = 80
+)
+
+// sortMode is the order sessions are listed in.
+type sortMode int
+
+const (
+	sortRecent sortMode = iota
+	sortCost
+	sortSize
 )
 
-const SessionsDialogID dialogs.DialogID = "sessions"
+func (m sortMode) String() string {
+	switch m {
+	case sortCost:
+		return "cost"
+	case sortSize:
+		return "size"
+	default:
+		return "recent"
+	}
+}
+
+func (m sortMode) next() sortMode {
+	return (m + 1) % 3
+}
+
+// sortSessions orders the sessions by the mode, most relevant first.
+func sortSessions(sessions []session.Session, mode sortMode) []session.Session {
+	sorted := slices.Clone(sessions)
+	slices.SortStableFunc(sorted, func(a, b session.Session) int {
+		switch mode {
+		case sortCost:
+			return cmp.Compare(b.Cost, a.Cost)
+		case sortSize:
+			return cmp.Compare(b.MessageCount, a.MessageCount)
+		default:
+			return cmp.Compare(b.UpdatedAt, a.UpdatedAt)
+		}
+	})
+	return sorted
+}
+
+// sessionItem matches the query against the title of the session and the
+// content of its latest messages, but only highlights matches in the title.
+type sessionItem struct {
+	list.CompletionItem[session.Session]
+	title   string
+	content string
+}
+
+func (i *sessionItem) FilterValue() string {
+	return i.title + " " + i.content
+}
+
+func (i *sessionItem) MatchIndexes(indexes []int) {
+	var inTitle []int
+	for _, index := range indexes {
+		if index < len(i.title) {
+			inTitle = append(inTitle, index)
+		}
+	}
+	i.CompletionItem.MatchIndexes(inTitle)
+}
 
 // SessionDialog interface for the session switching dialog
 type SessionDialog interface {
@@ -33,10 +112,16 @@ type sessionDialogCmp struct {
 	keyMap            KeyMap
 	sessionsList      SessionsList
 	help              help.Model
+
+	sessions []session.Session
+	// previews holds the latest messages of each session, oldest first.
+	previews map[string][]message.Message
+	sort     sortMode
 }
 
-// NewSessionDialogCmp creates a new session switching dialog
-func NewSessionDialogCmp(sessions []session.Session, selectedID string) SessionDialog {
+// NewSessionDialogCmp creates a new session switching dialog, previewing
+// the given latest messages of each session.
+func NewSessionDialogCmp(sessions []session.Session, previews map[string][]message.Message, selectedID string) SessionDialog {
 	t := styles.CurrentTheme()
 	listKeyMap := list.DefaultKeyMap()
 	keyMap := DefaultKeyMap()
@@ -45,17 +130,17 @@ func NewSessionDialogCmp(sessions []session.Session, selectedID string) SessionD
 	listKeyMap.DownOneItem = keyMap.Next
 	listKeyMap.UpOneItem = keyMap.Previous
 
-	items := make([]list.CompletionItem[session.Session], len(sessions))
-	if len(sessions) > 0 {
-		for i, session := range sessions {
-			items[i] = list.NewCompletionItem(session.Title, session, list.WithCompletionID(session.ID))
-		}
+	s := &sessionDialogCmp{
+		selectedSessionID: selectedID,
+		keyMap:            keyMap,
+		sessions:          sessions,
+		previews:          previews,
 	}
 
 	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
 	sessionsList := list.NewFilterableList(
-		items,
-		list.WithFilterPlaceholder("Enter a session name"),
+		s.items(),
+		list.WithFilterPlaceholder("Search sessions and messages"),
 		list.WithFilterInputStyle(inputStyle),
 		list.WithFilterListOptions(
 			list.WithKeyMap(listKeyMap),
@@ -64,14 +149,55 @@ func NewSessionDialogCmp(sessions []session.Session, selectedID string) SessionD
 	)
 	help := help.New()
 	help.Styles = t.S().Help
-	s := &sessionDialogCmp{
-		selectedSessionID: selectedID,
-		keyMap:            DefaultKeyMap(),
-		sessionsList:      sessionsList,
-		help:              help,
+	s.sessionsList = sessionsList
+	s.help = help
+	return s
+}
+
+// items returns the list items of the sessions in the current order, with
+// the value they are sorted by next to their title.
+func (s *sessionDialogCmp) items() []list.CompletionItem[session.Session] {
+	sorted := sortSessions(s.sessions, s.sort)
+	items := make([]list.CompletionItem[session.Session], len(sorted))
+	for i, sess := range sorted {
+		var info string
+		switch s.sort {
+		case sortCost:
+			info = fmt.Sprintf("$%.2f", sess.Cost)
+		case sortSize:
+			info = fmt.Sprintf("%d msgs", sess.MessageCount)
+		default:
+			info = FormatAge(time.Since(time.Unix(sess.UpdatedAt, 0)))
+		}
+
+		var content []string
+		for _, msg := range s.previews[sess.ID] {
+			if text := strings.TrimSpace(msg.Content().Text); text != "" {
+				content = append(content, text)
+			}
+		}
+		items[i] = &sessionItem{
+			CompletionItem: list.NewCompletionItem(sess.Title, sess, list.WithCompletionID(sess.ID), list.WithCompletionShortcut(info)),
+			title:          sess.Title,
+			content:        strings.Join(content, " "),
+		}
 	}
+	return items
+}
 
-	return s
+// FormatAge returns a short description of how long ago something was, for
+// the lists of dialogs.
+func FormatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	}
 }
 
 func (s *sessionDialogCmp) Init() tea.Cmd {
@@ -110,6 +236,9 @@ func (s *sessionDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			}
 		case key.Matches(msg, s.keyMap.Close):
 			return s, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case key.Matches(msg, s.keyMap.Sort):
+			s.sort = s.sort.next()
+			return s, s.sessionsList.SetItems(s.items())
 		default:
 			u, cmd := s.sessionsList.Update(msg)
 			s.sessionsList = u.(SessionsList)
@@ -122,9 +251,16 @@ func (s *sessionDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 func (s *sessionDialogCmp) View() string {
 	t := styles.CurrentTheme()
 	listView := s.sessionsList.View()
+	if s.showPreview() {
+		listView = lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			listView,
+			s.previewView(s.previewWidth(), s.listHeight()),
+		)
+	}
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
-		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Switch Session", s.width-4)),
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Switch Session · "+s.sort.String(), s.width-4)),
 		listView,
 		"",
 		t.S().Base.Width(s.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(s.help.View(s.keyMap)),
@@ -157,9 +293,68 @@ func (s *sessionDialogCmp) listHeight() int {
 }
 
 func (s *sessionDialogCmp) listWidth() int {
+	if s.showPreview() {
+		return (s.width - 2) / 2
+	}
 	return s.width - 2 // 2 for the border
 }
 
+func (s *sessionDialogCmp) showPreview() bool {
+	return s.width >= minPreviewWidth
+}
+
+func (s *sessionDialogCmp) previewWidth() int {
+	return s.width - 2 - s.listWidth()
+}
+
+// previewView renders the latest messages of the highlighted session,
+// keeping the end of the conversation visible when they don't fit.
+func (s *sessionDialogCmp) previewView(width, height int) string {
+	t := styles.CurrentTheme()
+	style := t.S().Base.
+		Width(width).
+		Height(height).
+		PaddingLeft(1).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderLeft(true).
+		BorderForeground(t.Border)
+	innerWidth := width - 3 // border and padding
+
+	selected := s.sessionsList.SelectedItem()
+	if selected == nil {
+		return style.Render("")
+	}
+	var lines []string
+	for _, msg := range s.previews[(*selected).Value().ID] {
+		text := strings.TrimSpace(msg.Content().Text)
+		if text == "" {
+			for _, call := range msg.ToolCalls() {
+				text = strings.TrimSpace(text + " → " + call.Name)
+			}
+		}
+		if text == "" {
+			continue
+		}
+		role := "Crush"
+		roleStyle := t.S().Subtle
+		if msg.Role == message.User {
+			role = "You"
+			roleStyle = t.S().Text.Foreground(t.Secondary)
+		}
+		lines = append(lines, roleStyle.Render(role))
+		wrapped := ansi.Wrap(text, innerWidth, "")
+		lines = append(lines, strings.Split(t.S().Muted.Render(wrapped), "\n")...)
+		lines = append(lines, "")
+	}
+	if len(lines) == 0 {
+		lines = []string{t.S().Subtle.Render("No messages")}
+	}
+	if len(lines) > height {
+		lines = lines[len(lines)-height:]
+	}
+	return style.Render(strings.Join(lines, "\n"))
+}
+
 func (s *sessionDialogCmp) Position() (int, int) {
 	row := s.wHeight/4 - 2 // just a bit above the center
 	col := s.wWidth / 2
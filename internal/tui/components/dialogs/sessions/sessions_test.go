@@ -0,0 +1,55 @@
+package sessions
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortSessions(t *testing.T) {
+	t.Parallel()
+
+	sessions := []session.Session{
+		{ID: "old", UpdatedAt: 1, Cost: 3, MessageCount: 2},
+		{ID: "new", UpdatedAt: 3, Cost: 1, MessageCount: 1},
+		{ID: "mid", UpdatedAt: 2, Cost: 2, MessageCount: 9},
+	}
+	ids := func(mode sortMode) []string {
+		var ids []string
+		for _, s := range sortSessions(sessions, mode) {
+			ids = append(ids, s.ID)
+		}
+		return ids
+	}
+
+	require.Equal(t, []string{"new", "mid", "old"}, ids(sortRecent))
+	require.Equal(t, []string{"old", "mid", "new"}, ids(sortCost))
+	require.Equal(t, []string{"mid", "old", "new"}, ids(sortSize))
+	require.Equal(t, sortRecent, sortSize.next())
+}
+
+func TestSessionItemsSearchMessages(t *testing.T) {
+	t.Parallel()
+
+	dialog := NewSessionDialogCmp(
+		[]session.Session{{ID: "a", Title: "Fix login"}},
+		map[string][]message.Message{
+			"a": {{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "the token expires"}}}},
+		},
+		"",
+	).(*sessionDialogCmp)
+
+	items := dialog.items()
+	require.Len(t, items, 1)
+	item := items[0].(*sessionItem)
+	require.Equal(t, "Fix login the token expires", item.FilterValue())
+
+	// Matches in the message content are not highlighted in the title.
+	item.MatchIndexes([]int{0, 4, 12})
+	item.SetSize(40, 1)
+	require.Equal(t, "Fix login", item.Text())
+	require.Contains(t, ansi.Strip(item.View()), "Fix login")
+}
@@ -0,0 +1,140 @@
+package stopphrase
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/i18n"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const StopPhraseDialogID dialogs.DialogID = "stopphrase"
+
+// ContinueMsg is sent when the user confirmed the agent may go past the
+// stop phrase it stopped at.
+type ContinueMsg struct {
+	SessionID string
+	Phrase    string
+}
+
+// StopPhraseDialog asks the user to confirm going past the stop phrase the
+// agent stopped at. Declining keeps the session stopped, the next prompt
+// then doesn't go past the phrase.
+type StopPhraseDialog interface {
+	dialogs.DialogModel
+}
+
+type stopPhraseDialogCmp struct {
+	wWidth  int
+	wHeight int
+
+	sessionID string
+	phrase    string
+
+	selectedStop bool
+	keymap       KeyMap
+}
+
+// NewStopPhraseDialog creates the dialog for a session stopped at the
+// phrase.
+func NewStopPhraseDialog(sessionID, phrase string) StopPhraseDialog {
+	return &stopPhraseDialogCmp{
+		sessionID:    sessionID,
+		phrase:       phrase,
+		selectedStop: true,
+		keymap:       DefaultKeymap(),
+	}
+}
+
+func (s *stopPhraseDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (s *stopPhraseDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.wWidth = msg.Width
+		s.wHeight = msg.Height
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, s.keymap.LeftRight, s.keymap.Tab):
+			s.selectedStop = !s.selectedStop
+			return s, nil
+		case key.Matches(msg, s.keymap.EnterSpace):
+			if s.selectedStop {
+				return s, util.CmdHandler(dialogs.CloseDialogMsg{})
+			}
+			return s, s.confirm()
+		case key.Matches(msg, s.keymap.Yes):
+			return s, s.confirm()
+		case key.Matches(msg, s.keymap.No, s.keymap.Close):
+			return s, util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+	}
+	return s, nil
+}
+
+func (s *stopPhraseDialogCmp) confirm() tea.Cmd {
+	return tea.Sequence(
+		util.CmdHandler(dialogs.CloseDialogMsg{}),
+		util.CmdHandler(ContinueMsg{SessionID: s.sessionID, Phrase: s.phrase}),
+	)
+}
+
+func (s *stopPhraseDialogCmp) question() string {
+	return i18n.Tf("stop_phrase.question", s.phrase)
+}
+
+func (s *stopPhraseDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	baseStyle := t.S().Base
+	continueStyle := t.S().Text
+	stopStyle := continueStyle
+
+	if s.selectedStop {
+		stopStyle = stopStyle.Foreground(t.White).Background(t.Secondary)
+		continueStyle = continueStyle.Background(t.BgSubtle)
+	} else {
+		continueStyle = continueStyle.Foreground(t.White).Background(t.Secondary)
+		stopStyle = stopStyle.Background(t.BgSubtle)
+	}
+
+	const horizontalPadding = 3
+	question := s.question()
+	continueButton := continueStyle.Padding(0, horizontalPadding).Render(i18n.T("stop_phrase.continue"))
+	stopButton := stopStyle.Padding(0, horizontalPadding).Render(i18n.T("stop_phrase.stop"))
+
+	buttons := baseStyle.Width(lipgloss.Width(question)).Align(lipgloss.Right).Render(
+		lipgloss.JoinHorizontal(lipgloss.Center, continueButton, "  ", stopButton),
+	)
+
+	content := baseStyle.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			question,
+			"",
+			buttons,
+		),
+	)
+
+	return baseStyle.
+		Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Render(content)
+}
+
+func (s *stopPhraseDialogCmp) Position() (int, int) {
+	question := s.question()
+	row := s.wHeight / 2
+	row -= (lipgloss.Height(question) + 6) / 2
+	col := s.wWidth / 2
+	col -= (lipgloss.Width(question) + 4) / 2
+	return row, col
+}
+
+func (s *stopPhraseDialogCmp) ID() dialogs.DialogID {
+	return StopPhraseDialogID
+}
@@ -0,0 +1,151 @@
+package topic
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/i18n"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const TopicDialogID dialogs.DialogID = "topic"
+
+// NewTopicMsg is sent to go on with the prompt in a new session carrying a
+// brief summary of the session.
+type NewTopicMsg struct {
+	SessionID   string
+	Text        string
+	Attachments []message.Attachment
+}
+
+// KeepTopicMsg is sent to go on with the prompt in the session.
+type KeepTopicMsg struct {
+	SessionID   string
+	Text        string
+	Attachments []message.Attachment
+}
+
+// TopicDialog offers to start a new session when a prompt starts another
+// topic than the one of its session.
+type TopicDialog interface {
+	dialogs.DialogModel
+}
+
+type topicDialogCmp struct {
+	wWidth  int
+	wHeight int
+
+	sessionID   string
+	text        string
+	attachments []message.Attachment
+
+	selectedKeep bool
+	keymap       KeyMap
+}
+
+// NewTopicDialog creates the dialog for a prompt of the session.
+func NewTopicDialog(sessionID, text string, attachments []message.Attachment) TopicDialog {
+	return &topicDialogCmp{
+		sessionID:   sessionID,
+		text:        text,
+		attachments: attachments,
+		keymap:      DefaultKeymap(),
+	}
+}
+
+func (t *topicDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (t *topicDialogCmp) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		t.wWidth = msg.Width
+		t.wHeight = msg.Height
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, t.keymap.LeftRight, t.keymap.Tab):
+			t.selectedKeep = !t.selectedKeep
+			return t, nil
+		case key.Matches(msg, t.keymap.EnterSpace):
+			if t.selectedKeep {
+				return t, t.keep()
+			}
+			return t, t.newTopic()
+		case key.Matches(msg, t.keymap.Yes):
+			return t, t.newTopic()
+		case key.Matches(msg, t.keymap.No, t.keymap.Close):
+			return t, t.keep()
+		}
+	}
+	return t, nil
+}
+
+func (t *topicDialogCmp) newTopic() tea.Cmd {
+	return tea.Sequence(
+		util.CmdHandler(dialogs.CloseDialogMsg{}),
+		util.CmdHandler(NewTopicMsg{SessionID: t.sessionID, Text: t.text, Attachments: t.attachments}),
+	)
+}
+
+func (t *topicDialogCmp) keep() tea.Cmd {
+	return tea.Sequence(
+		util.CmdHandler(dialogs.CloseDialogMsg{}),
+		util.CmdHandler(KeepTopicMsg{SessionID: t.sessionID, Text: t.text, Attachments: t.attachments}),
+	)
+}
+
+func (t *topicDialogCmp) View() string {
+	th := styles.CurrentTheme()
+	baseStyle := th.S().Base
+	newStyle := th.S().Text
+	keepStyle := newStyle
+
+	if t.selectedKeep {
+		keepStyle = keepStyle.Foreground(th.White).Background(th.Secondary)
+		newStyle = newStyle.Background(th.BgSubtle)
+	} else {
+		newStyle = newStyle.Foreground(th.White).Background(th.Secondary)
+		keepStyle = keepStyle.Background(th.BgSubtle)
+	}
+
+	const horizontalPadding = 3
+	question := i18n.T("topic.question")
+	newButton := newStyle.Padding(0, horizontalPadding).Render(i18n.T("topic.new_session"))
+	keepButton := keepStyle.Padding(0, horizontalPadding).Render(i18n.T("topic.keep"))
+
+	buttons := baseStyle.Width(lipgloss.Width(question)).Align(lipgloss.Right).Render(
+		lipgloss.JoinHorizontal(lipgloss.Center, newButton, "  ", keepButton),
+	)
+
+	content := baseStyle.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Center,
+			question,
+			"",
+			buttons,
+		),
+	)
+
+	return baseStyle.
+		Padding(1, 2).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(th.BorderFocus).
+		Render(content)
+}
+
+func (t *topicDialogCmp) Position() (int, int) {
+	question := i18n.T("topic.question")
+	row := t.wHeight / 2
+	row -= (lipgloss.Height(question) + 6) / 2
+	col := t.wWidth / 2
+	col -= (lipgloss.Width(question) + 4) / 2
+	return row, col
+}
+
+func (t *topicDialogCmp) ID() dialogs.DialogID {
+	return TopicDialogID
+}
@@ -97,6 +97,7 @@ func RenderFileList(fileSlice []SessionFile, opts RenderOptions) []string {
 		}
 		filePath = fsext.DirTrim(fsext.PrettyPath(filePath), 2)
 		filePath = ansi.Truncate(filePath, opts.MaxWidth-lipgloss.Width(extraContent)-2, "…")
+		filePath = styles.FileHyperlink(file.FilePath, filePath)
 
 		fileList = append(fileList,
 			core.Status(
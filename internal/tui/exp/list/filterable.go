@@ -272,8 +272,12 @@ func (f *filterableList[T]) Filter(query string) tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
+// SetItems replaces the items, keeping the current query applied to them.
 func (f *filterableList[T]) SetItems(items []T) tea.Cmd {
 	f.items = items
+	if f.query != "" {
+		return f.Filter(f.query)
+	}
 	return f.list.SetItems(items)
 }
 
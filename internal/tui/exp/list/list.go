@@ -58,6 +58,9 @@ type List[T Item] interface {
 	SelectParagraph(col, line int)
 	GetSelectedText(paddingLeft int) string
 	HasSelection() bool
+	// IsItemOffscreen reports whether the item was rendered outside of the
+	// visible part of the list.
+	IsItemOffscreen(id string) bool
 }
 
 type direction int
@@ -98,23 +101,42 @@ type list[T Item] struct {
 
 	offset int
 
-	indexMap *csync.Map[string, int]
-	items    *csync.Slice[T]
+	// indexMap holds the index of each item minus indexBase, so prepending
+	// an item moves every other one down without updating their entries.
+	indexMap  *csync.Map[string, int]
+	indexBase int
+	items     *csync.Slice[T]
+
+	// positionsFrom is the index of the first item whose position may be
+	// outdated, so only the items from there on are positioned again.
+	positionsFrom int
 
 	renderedItems *csync.Map[string, renderedItem]
 
 	renderMu sync.Mutex
 	rendered string
+	// renderedLines are the lines of rendered, split once per render so
+	// drawing the viewport doesn't depend on the size of the list.
+	renderedLines []string
 
-	movingByItem       bool
-	selectionStartCol  int
-	selectionStartLine int
-	selectionEndCol    int
-	selectionEndLine   int
+	movingByItem bool
 
+	// The ends of the selection are anchored to items, so the selection
+	// follows its text when items are added, removed or change height.
+	selectionStart  selectionPoint
+	selectionEnd    selectionPoint
 	selectionActive bool
 }
 
+// selectionPoint is an end of the selection: the column and line of the
+// item it's in. Points past the items have no item and a line counted from
+// the top of the list.
+type selectionPoint struct {
+	itemID string
+	line   int
+	col    int
+}
+
 type ListOption func(*confOptions)
 
 // WithSize sets the size of the list.
@@ -190,13 +212,9 @@ func New[T Item](items []T, opts ...ListOption) List[T] {
 			keyMap:    DefaultKeyMap(),
 			focused:   true,
 		},
-		items:              csync.NewSliceFrom(items),
-		indexMap:           csync.NewMap[string, int](),
-		renderedItems:      csync.NewMap[string, renderedItem](),
-		selectionStartCol:  -1,
-		selectionStartLine: -1,
-		selectionEndLine:   -1,
-		selectionEndCol:    -1,
+		items:         csync.NewSliceFrom(items),
+		indexMap:      csync.NewMap[string, int](),
+		renderedItems: csync.NewMap[string, renderedItem](),
 	}
 	for _, opt := range opts {
 		opt(list.confOptions)
@@ -206,11 +224,34 @@ func New[T Item](items []T, opts ...ListOption) List[T] {
 		if i, ok := any(item).(Indexable); ok {
 			i.SetIndex(inx)
 		}
-		list.indexMap.Set(item.ID(), inx)
+		list.setIndex(item.ID(), inx)
 	}
 	return list
 }
 
+// indexOf returns the index of the item with the given ID.
+func (l *list[T]) indexOf(id string) (int, bool) {
+	inx, ok := l.indexMap.Get(id)
+	return inx + l.indexBase, ok
+}
+
+func (l *list[T]) setIndex(id string, inx int) {
+	l.indexMap.Set(id, inx-l.indexBase)
+}
+
+// invalidateItem drops the rendered item, so it's rendered and positioned
+// again along with the items after it.
+func (l *list[T]) invalidateItem(id string) {
+	l.renderedItems.Del(id)
+	if inx, ok := l.indexOf(id); ok {
+		l.invalidatePositions(inx)
+	}
+}
+
+func (l *list[T]) invalidatePositions(inx int) {
+	l.positionsFrom = max(0, min(l.positionsFrom, inx))
+}
+
 // Init implements List.
 func (l *list[T]) Init() tea.Cmd {
 	return l.render()
@@ -226,7 +267,7 @@ func (l *list[T]) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		return l, nil
 	case anim.StepMsg:
 		var cmds []tea.Cmd
-		for _, item := range slices.Collect(l.items.Seq()) {
+		for item := range l.items.Seq() {
 			if i, ok := any(item).(HasAnim); ok && i.Spinning() {
 				updated, cmd := i.Update(msg)
 				cmds = append(cmds, cmd)
@@ -294,11 +335,15 @@ func (l *list[T]) selectionView(view string, textOnly bool) string {
 	t := styles.CurrentTheme()
 	area := uv.Rect(0, 0, l.width, l.height)
 	scr := uv.NewScreenBuffer(area.Dx(), area.Dy())
+	// Measure cells the way the view was laid out.
+	scr.Method = ansi.GraphemeWidth
 	uv.NewStyledString(view).Draw(scr, area)
 
+	startCol, startLine := l.viewPoint(l.selectionStart)
+	endCol, endLine := l.viewPoint(l.selectionEnd)
 	selArea := uv.Rectangle{
-		Min: uv.Pos(l.selectionStartCol, l.selectionStartLine),
-		Max: uv.Pos(l.selectionEndCol, l.selectionEndLine),
+		Min: uv.Pos(startCol, startLine),
+		Max: uv.Pos(endCol, endLine),
 	}
 	selArea = selArea.Canon()
 
@@ -445,8 +490,7 @@ func (l *list[T]) View() string {
 		return ""
 	}
 	t := styles.CurrentTheme()
-	view := l.rendered
-	lines := strings.Split(view, "\n")
+	lines := l.renderedLines
 
 	start, end := l.viewPosition()
 	viewStart := max(0, start)
@@ -460,7 +504,7 @@ func (l *list[T]) View() string {
 	if l.resize {
 		return strings.Join(lines, "\n")
 	}
-	view = t.S().Base.
+	view := t.S().Base.
 		Height(l.height).
 		Width(l.width).
 		Render(strings.Join(lines, "\n"))
@@ -472,9 +516,19 @@ func (l *list[T]) View() string {
 	return l.selectionView(view, false)
 }
 
+func (l *list[T]) setRendered(rendered string) {
+	l.rendered = rendered
+	l.renderedLines = strings.Split(rendered, "\n")
+}
+
+// renderedHeight returns the number of lines of the rendered items.
+func (l *list[T]) renderedHeight() int {
+	return max(1, len(l.renderedLines))
+}
+
 func (l *list[T]) viewPosition() (int, int) {
 	start, end := 0, 0
-	renderedLines := lipgloss.Height(l.rendered) - 1
+	renderedLines := l.renderedHeight() - 1
 	if l.direction == DirectionForward {
 		start = max(0, l.offset)
 		end = min(l.offset+l.height-1, renderedLines)
@@ -486,11 +540,41 @@ func (l *list[T]) viewPosition() (int, int) {
 	return start, end
 }
 
+// IsItemOffscreen implements List.
+func (l *list[T]) IsItemOffscreen(id string) bool {
+	rItem, ok := l.renderedItems.Get(id)
+	if !ok {
+		return false
+	}
+	start, end := l.viewPosition()
+	return rItem.end < start || rItem.start > end
+}
+
+// recalculateItemPositions positions the items from the first outdated one,
+// after the end of the one before it. Items that aren't rendered yet are
+// skipped, and positioned again once they are.
 func (l *list[T]) recalculateItemPositions() {
 	currentContentHeight := 0
-	for _, item := range slices.Collect(l.items.Seq()) {
+	from := min(l.positionsFrom, l.items.Len())
+	if from > 0 {
+		if prev, ok := l.items.Get(from - 1); ok {
+			if rItem, ok := l.renderedItems.Get(prev.ID()); ok {
+				currentContentHeight = rItem.end + 1 + l.gap
+			} else {
+				from = 0
+			}
+		}
+	}
+	itemsLen := l.items.Len()
+	l.positionsFrom = itemsLen
+	for inx := from; inx < itemsLen; inx++ {
+		item, ok := l.items.Get(inx)
+		if !ok {
+			continue
+		}
 		rItem, ok := l.renderedItems.Get(item.ID())
 		if !ok {
+			l.invalidatePositions(inx)
 			continue
 		}
 		rItem.start = currentContentHeight
@@ -516,7 +600,8 @@ func (l *list[T]) render() tea.Cmd {
 	if l.rendered != "" {
 		// rerender everything will mostly hit cache
 		l.renderMu.Lock()
-		l.rendered, _ = l.renderIterator(0, false, "")
+		rendered, _ := l.renderIterator(0, false, "")
+		l.setRendered(rendered)
 		l.renderMu.Unlock()
 		if l.direction == DirectionBackward {
 			l.recalculateItemPositions()
@@ -529,7 +614,7 @@ func (l *list[T]) render() tea.Cmd {
 	}
 	l.renderMu.Lock()
 	rendered, finishIndex := l.renderIterator(0, true, "")
-	l.rendered = rendered
+	l.setRendered(rendered)
 	l.renderMu.Unlock()
 	// recalculate for the initial items
 	if l.direction == DirectionBackward {
@@ -540,7 +625,8 @@ func (l *list[T]) render() tea.Cmd {
 		// render the rest
 
 		l.renderMu.Lock()
-		l.rendered, _ = l.renderIterator(finishIndex, false, l.rendered)
+		rendered, _ := l.renderIterator(finishIndex, false, l.rendered)
+		l.setRendered(rendered)
 		l.renderMu.Unlock()
 		// needed for backwards
 		if l.direction == DirectionBackward {
@@ -599,12 +685,12 @@ func (l *list[T]) scrollToSelection() {
 		if l.direction == DirectionForward {
 			l.offset = rItem.start
 		} else {
-			l.offset = max(0, lipgloss.Height(l.rendered)-(rItem.start+l.height))
+			l.offset = max(0, l.renderedHeight()-(rItem.start+l.height))
 		}
 		return
 	}
 
-	renderedLines := lipgloss.Height(l.rendered) - 1
+	renderedLines := l.renderedHeight() - 1
 
 	// If item is above the viewport, make it the first item
 	if rItem.start < start {
@@ -643,7 +729,7 @@ func (l *list[T]) changeSelectionWhenScrolling() tea.Cmd {
 	if itemMiddle < start {
 		// select the first item in the viewport
 		// the item is most likely an item coming after this item
-		inx, ok := l.indexMap.Get(rItem.id)
+		inx, ok := l.indexOf(rItem.id)
 		if !ok {
 			return nil
 		}
@@ -675,7 +761,7 @@ func (l *list[T]) changeSelectionWhenScrolling() tea.Cmd {
 	} else if itemMiddle > end {
 		// select the first item in the viewport
 		// the item is most likely an item coming after this item
-		inx, ok := l.indexMap.Get(rItem.id)
+		inx, ok := l.indexOf(rItem.id)
 		if !ok {
 			return nil
 		}
@@ -766,14 +852,16 @@ func (l *list[T]) focusSelectedItem() tea.Cmd {
 		return nil
 	}
 	var cmds []tea.Cmd
-	for _, item := range slices.Collect(l.items.Seq()) {
+	for inx, item := range l.items.Seq2() {
 		if f, ok := any(item).(layout.Focusable); ok {
 			if item.ID() == l.selectedItem && !f.IsFocused() {
 				cmds = append(cmds, f.Focus())
 				l.renderedItems.Del(item.ID())
+				l.invalidatePositions(inx)
 			} else if item.ID() != l.selectedItem && f.IsFocused() {
 				cmds = append(cmds, f.Blur())
 				l.renderedItems.Del(item.ID())
+				l.invalidatePositions(inx)
 			}
 		}
 	}
@@ -785,11 +873,12 @@ func (l *list[T]) blurSelectedItem() tea.Cmd {
 		return nil
 	}
 	var cmds []tea.Cmd
-	for _, item := range slices.Collect(l.items.Seq()) {
+	for inx, item := range l.items.Seq2() {
 		if f, ok := any(item).(layout.Focusable); ok {
 			if item.ID() == l.selectedItem && f.IsFocused() {
 				cmds = append(cmds, f.Blur())
 				l.renderedItems.Del(item.ID())
+				l.invalidatePositions(inx)
 			}
 		}
 	}
@@ -807,6 +896,7 @@ type renderFragment struct {
 // we pass the rendered content around and don't use l.rendered to prevent jumping of the content
 func (l *list[T]) renderIterator(startInx int, limitHeight bool, rendered string) (string, int) {
 	var fragments []renderFragment
+	size := len(rendered)
 
 	currentContentHeight := lipgloss.Height(rendered) - 1
 	itemsLen := l.items.Len()
@@ -847,12 +937,14 @@ func (l *list[T]) renderIterator(startInx int, limitHeight bool, rendered string
 		}
 
 		fragments = append(fragments, renderFragment{view: rItem.view, gap: gap})
+		size += len(rItem.view) + gap
 
 		currentContentHeight = rItem.end + 1 + l.gap
 	}
 
 	// second pass: build rendered string efficiently
 	var b strings.Builder
+	b.Grow(size)
 	if l.direction == DirectionForward {
 		b.WriteString(rendered)
 		for _, f := range fragments {
@@ -896,10 +988,8 @@ func (l *list[T]) AppendItem(item T) tea.Cmd {
 	}
 
 	l.items.Append(item)
-	l.indexMap = csync.NewMap[string, int]()
-	for inx, item := range slices.Collect(l.items.Seq()) {
-		l.indexMap.Set(item.ID(), inx)
-	}
+	l.setIndex(item.ID(), l.items.Len()-1)
+	l.invalidatePositions(l.items.Len() - 1)
 	if l.width > 0 && l.height > 0 {
 		cmd = item.SetSize(l.width, l.height)
 		if cmd != nil {
@@ -923,7 +1013,7 @@ func (l *list[T]) AppendItem(item T) tea.Cmd {
 				if l.items.Len() > 1 {
 					newLines += l.gap
 				}
-				l.offset = min(lipgloss.Height(l.rendered)-1, l.offset+newLines)
+				l.offset = min(l.renderedHeight()-1, l.offset+newLines)
 			}
 		}
 	}
@@ -938,15 +1028,23 @@ func (l *list[T]) Blur() tea.Cmd {
 
 // DeleteItem implements List.
 func (l *list[T]) DeleteItem(id string) tea.Cmd {
-	inx, ok := l.indexMap.Get(id)
+	inx, ok := l.indexOf(id)
 	if !ok {
 		return nil
 	}
 	l.items.Delete(inx)
 	l.renderedItems.Del(id)
-	for inx, item := range slices.Collect(l.items.Seq()) {
-		l.indexMap.Set(item.ID(), inx)
+	l.indexMap.Del(id)
+	if l.selectionStart.itemID == id || l.selectionEnd.itemID == id {
+		l.SelectionClear()
+	}
+	// Only the items after the deleted one move up.
+	for i := inx; i < l.items.Len(); i++ {
+		if item, ok := l.items.Get(i); ok {
+			l.setIndex(item.ID(), i)
+		}
 	}
+	l.invalidatePositions(inx)
 
 	if l.selectedItem == id {
 		if inx > 0 {
@@ -962,7 +1060,7 @@ func (l *list[T]) DeleteItem(id string) tea.Cmd {
 	}
 	cmd := l.render()
 	if l.rendered != "" {
-		renderedHeight := lipgloss.Height(l.rendered)
+		renderedHeight := l.renderedHeight()
 		if renderedHeight <= l.height {
 			l.offset = 0
 		} else {
@@ -1013,7 +1111,7 @@ func (l *list[T]) Items() []T {
 }
 
 func (l *list[T]) incrementOffset(n int) {
-	renderedHeight := lipgloss.Height(l.rendered)
+	renderedHeight := l.renderedHeight()
 	// no need for offset
 	if renderedHeight <= l.height {
 		return
@@ -1040,6 +1138,7 @@ func (l *list[T]) decrementOffset(n int) {
 // MoveDown implements List.
 func (l *list[T]) MoveDown(n int) tea.Cmd {
 	oldOffset := l.offset
+	endCol, endLine := l.viewPoint(l.selectionEnd)
 	if l.direction == DirectionForward {
 		l.incrementOffset(n)
 	} else {
@@ -1050,29 +1149,14 @@ func (l *list[T]) MoveDown(n int) tea.Cmd {
 		// no change in offset, so no need to change selection
 		return nil
 	}
-	// if we are not actively selecting move the whole selection down
-	if l.hasSelection() && !l.selectionActive {
-		if l.selectionStartLine < l.selectionEndLine {
-			l.selectionStartLine -= n
-			l.selectionEndLine -= n
-		} else {
-			l.selectionStartLine -= n
-			l.selectionEndLine -= n
-		}
-	}
-	if l.selectionActive {
-		if l.selectionStartLine < l.selectionEndLine {
-			l.selectionStartLine -= n
-		} else {
-			l.selectionEndLine -= n
-		}
-	}
+	l.keepSelectionEnd(endCol, endLine)
 	return l.changeSelectionWhenScrolling()
 }
 
 // MoveUp implements List.
 func (l *list[T]) MoveUp(n int) tea.Cmd {
 	oldOffset := l.offset
+	endCol, endLine := l.viewPoint(l.selectionEnd)
 	if l.direction == DirectionForward {
 		l.decrementOffset(n)
 	} else {
@@ -1083,23 +1167,7 @@ func (l *list[T]) MoveUp(n int) tea.Cmd {
 		// no change in offset, so no need to change selection
 		return nil
 	}
-
-	if l.hasSelection() && !l.selectionActive {
-		if l.selectionStartLine > l.selectionEndLine {
-			l.selectionStartLine += n
-			l.selectionEndLine += n
-		} else {
-			l.selectionStartLine += n
-			l.selectionEndLine += n
-		}
-	}
-	if l.selectionActive {
-		if l.selectionStartLine > l.selectionEndLine {
-			l.selectionStartLine += n
-		} else {
-			l.selectionEndLine += n
-		}
-	}
+	l.keepSelectionEnd(endCol, endLine)
 	return l.changeSelectionWhenScrolling()
 }
 
@@ -1109,10 +1177,10 @@ func (l *list[T]) PrependItem(item T) tea.Cmd {
 		item.Init(),
 	}
 	l.items.Prepend(item)
-	l.indexMap = csync.NewMap[string, int]()
-	for inx, item := range slices.Collect(l.items.Seq()) {
-		l.indexMap.Set(item.ID(), inx)
-	}
+	// Every other item moves down by one.
+	l.indexBase++
+	l.setIndex(item.ID(), 0)
+	l.invalidatePositions(0)
 	if l.width > 0 && l.height > 0 {
 		cmds = append(cmds, item.SetSize(l.width, l.height))
 	}
@@ -1130,7 +1198,7 @@ func (l *list[T]) PrependItem(item T) tea.Cmd {
 				if l.items.Len() > 1 {
 					newLines += l.gap
 				}
-				l.offset = min(lipgloss.Height(l.rendered)-1, l.offset+newLines)
+				l.offset = min(l.renderedHeight()-1, l.offset+newLines)
 			}
 		}
 	}
@@ -1139,7 +1207,7 @@ func (l *list[T]) PrependItem(item T) tea.Cmd {
 
 // SelectItemAbove implements List.
 func (l *list[T]) SelectItemAbove() tea.Cmd {
-	inx, ok := l.indexMap.Get(l.selectedItem)
+	inx, ok := l.indexOf(l.selectedItem)
 	if !ok {
 		return nil
 	}
@@ -1175,7 +1243,7 @@ func (l *list[T]) SelectItemAbove() tea.Cmd {
 
 // SelectItemBelow implements List.
 func (l *list[T]) SelectItemBelow() tea.Cmd {
-	inx, ok := l.indexMap.Get(l.selectedItem)
+	inx, ok := l.indexOf(l.selectedItem)
 	if !ok {
 		return nil
 	}
@@ -1196,7 +1264,7 @@ func (l *list[T]) SelectItemBelow() tea.Cmd {
 
 // SelectedItem implements List.
 func (l *list[T]) SelectedItem() *T {
-	inx, ok := l.indexMap.Get(l.selectedItem)
+	inx, ok := l.indexOf(l.selectedItem)
 	if !ok {
 		return nil
 	}
@@ -1214,7 +1282,7 @@ func (l *list[T]) SelectedItem() *T {
 func (l *list[T]) SetItems(items []T) tea.Cmd {
 	l.items.SetSlice(items)
 	var cmds []tea.Cmd
-	for inx, item := range slices.Collect(l.items.Seq()) {
+	for inx, item := range l.items.Seq2() {
 		if i, ok := any(item).(Indexable); ok {
 			i.SetIndex(inx)
 		}
@@ -1232,13 +1300,15 @@ func (l *list[T]) SetSelected(id string) tea.Cmd {
 
 func (l *list[T]) reset(selectedItem string) tea.Cmd {
 	var cmds []tea.Cmd
-	l.rendered = ""
+	l.setRendered("")
 	l.offset = 0
 	l.selectedItem = selectedItem
 	l.indexMap = csync.NewMap[string, int]()
+	l.indexBase = 0
 	l.renderedItems = csync.NewMap[string, renderedItem]()
-	for inx, item := range slices.Collect(l.items.Seq()) {
-		l.indexMap.Set(item.ID(), inx)
+	l.positionsFrom = 0
+	for inx, item := range l.items.Seq2() {
+		l.setIndex(item.ID(), inx)
 		if l.width > 0 && l.height > 0 {
 			cmds = append(cmds, item.SetSize(l.width, l.height))
 		}
@@ -1262,15 +1332,15 @@ func (l *list[T]) SetSize(width int, height int) tea.Cmd {
 // UpdateItem implements List.
 func (l *list[T]) UpdateItem(id string, item T) tea.Cmd {
 	var cmds []tea.Cmd
-	if inx, ok := l.indexMap.Get(id); ok {
+	if inx, ok := l.indexOf(id); ok {
 		l.items.Set(inx, item)
 		oldItem, hasOldItem := l.renderedItems.Get(id)
 		oldPosition := l.offset
 		if l.direction == DirectionBackward {
-			oldPosition = (lipgloss.Height(l.rendered) - 1) - l.offset
+			oldPosition = (l.renderedHeight() - 1) - l.offset
 		}
 
-		l.renderedItems.Del(id)
+		l.invalidateItem(id)
 		cmd := l.render()
 
 		// need to check for nil because of sequence not handling nil
@@ -1284,14 +1354,14 @@ func (l *list[T]) UpdateItem(id string, item T) tea.Cmd {
 				newItem, ok := l.renderedItems.Get(item.ID())
 				if ok {
 					newLines := newItem.height - oldItem.height
-					l.offset = ordered.Clamp(l.offset+newLines, 0, lipgloss.Height(l.rendered)-1)
+					l.offset = ordered.Clamp(l.offset+newLines, 0, l.renderedHeight()-1)
 				}
 			}
 		} else if hasOldItem && l.offset > oldItem.start {
 			newItem, ok := l.renderedItems.Get(item.ID())
 			if ok {
 				newLines := newItem.height - oldItem.height
-				l.offset = ordered.Clamp(l.offset+newLines, 0, lipgloss.Height(l.rendered)-1)
+				l.offset = ordered.Clamp(l.offset+newLines, 0, l.renderedHeight()-1)
 			}
 		}
 	}
@@ -1299,15 +1369,53 @@ func (l *list[T]) UpdateItem(id string, item T) tea.Cmd {
 }
 
 func (l *list[T]) hasSelection() bool {
-	return l.selectionEndCol != l.selectionStartCol || l.selectionEndLine != l.selectionStartLine
+	return l.selectionStart != l.selectionEnd
+}
+
+// anchorPoint anchors the position in the viewport to the item under it.
+func (l *list[T]) anchorPoint(col, line int) selectionPoint {
+	start, _ := l.viewPosition()
+	line += start
+	for item := range l.items.Seq() {
+		rItem, ok := l.renderedItems.Get(item.ID())
+		if !ok {
+			continue
+		}
+		// Lines in the gap after an item belong to it.
+		if line >= rItem.start && line <= rItem.end+l.gap {
+			return selectionPoint{itemID: rItem.id, line: line - rItem.start, col: col}
+		}
+	}
+	return selectionPoint{line: line, col: col}
+}
+
+// viewPoint returns the position of the point in the viewport. Points in
+// items that shrank are kept within them.
+func (l *list[T]) viewPoint(p selectionPoint) (col, line int) {
+	line = p.line
+	if p.itemID != "" {
+		rItem, ok := l.renderedItems.Get(p.itemID)
+		if !ok {
+			return p.col, -1
+		}
+		line = rItem.start + min(p.line, rItem.height-1+l.gap)
+	}
+	start, _ := l.viewPosition()
+	return p.col, line - start
+}
+
+// keepSelectionEnd keeps the end of a selection in progress under the
+// mouse when the content scrolls, while the start follows its text.
+func (l *list[T]) keepSelectionEnd(col, line int) {
+	if l.selectionActive {
+		l.selectionEnd = l.anchorPoint(col, line)
+	}
 }
 
 // StartSelection implements List.
 func (l *list[T]) StartSelection(col, line int) {
-	l.selectionStartCol = col
-	l.selectionStartLine = line
-	l.selectionEndCol = col
-	l.selectionEndLine = line
+	l.selectionStart = l.anchorPoint(col, line)
+	l.selectionEnd = l.selectionStart
 	l.selectionActive = true
 }
 
@@ -1316,8 +1424,7 @@ func (l *list[T]) EndSelection(col, line int) {
 	if !l.selectionActive {
 		return
 	}
-	l.selectionEndCol = col
-	l.selectionEndLine = line
+	l.selectionEnd = l.anchorPoint(col, line)
 }
 
 func (l *list[T]) SelectionStop() {
@@ -1325,10 +1432,8 @@ func (l *list[T]) SelectionStop() {
 }
 
 func (l *list[T]) SelectionClear() {
-	l.selectionStartCol = -1
-	l.selectionStartLine = -1
-	l.selectionEndCol = -1
-	l.selectionEndLine = -1
+	l.selectionStart = selectionPoint{}
+	l.selectionEnd = selectionPoint{}
 	l.selectionActive = false
 }
 
@@ -1354,21 +1459,26 @@ func (l *list[T]) findWordBoundaries(col, line int) (startCol, endCol int) {
 		return 0, 0
 	}
 
+	// Columns are counted in cells, so wide characters take up as many
+	// columns as they do on screen.
 	currentLine := lines[line]
 	gr := uniseg.NewGraphemes(currentLine)
 	startCol = -1
-	upTo := col
+	x := 0
 	for gr.Next() {
-		if gr.IsWordBoundary() && upTo > 0 {
-			startCol = col - upTo + 1
-		} else if gr.IsWordBoundary() && upTo < 0 {
-			endCol = col - upTo + 1
-			break
-		}
-		if upTo == 0 && gr.Str() == " " {
+		next := x + gr.Width()
+		switch {
+		case gr.IsWordBoundary() && next <= col:
+			startCol = next
+		case gr.IsWordBoundary() && x > col:
+			endCol = next
+		case x <= col && col < next && gr.Str() == " ":
 			return 0, 0
 		}
-		upTo -= 1
+		if endCol > 0 {
+			break
+		}
+		x = next
 	}
 	if startCol == -1 {
 		return 0, 0
@@ -1437,10 +1547,8 @@ func (l *list[T]) findParagraphBoundaries(line int) (startLine, endLine int, fou
 // SelectWord selects the word at the given position.
 func (l *list[T]) SelectWord(col, line int) {
 	startCol, endCol := l.findWordBoundaries(col, line)
-	l.selectionStartCol = startCol
-	l.selectionStartLine = line
-	l.selectionEndCol = endCol
-	l.selectionEndLine = line
+	l.selectionStart = l.anchorPoint(startCol, line)
+	l.selectionEnd = l.anchorPoint(endCol, line)
 	l.selectionActive = false // Not actively selecting, just selected
 }
 
@@ -1450,10 +1558,8 @@ func (l *list[T]) SelectParagraph(col, line int) {
 	if !found {
 		return
 	}
-	l.selectionStartCol = 0
-	l.selectionStartLine = startLine
-	l.selectionEndCol = l.width - 1
-	l.selectionEndLine = endLine
+	l.selectionStart = l.anchorPoint(0, startLine)
+	l.selectionEnd = l.anchorPoint(l.width-1, endLine)
 	l.selectionActive = false // Not actively selecting, just selected
 }
 
@@ -0,0 +1,127 @@
+package list
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// benchItems is the size of a huge session.
+const benchItems = 10_000
+
+// newBenchList returns a list of n items of one to five lines, rendered
+// completely.
+func newBenchList(tb testing.TB, n int, direction ListOption) *list[Item] {
+	tb.Helper()
+	items := make([]Item, n)
+	for i := range items {
+		lines := make([]string, i%5+1)
+		for j := range lines {
+			lines[j] = fmt.Sprintf("Item %d line %d", i, j)
+		}
+		items[i] = NewSimpleItem(strings.Join(lines, "\n"))
+	}
+	l := New(items, direction, WithSize(80, 40)).(*list[Item])
+	execCmd(l, l.Init())
+	return l
+}
+
+func BenchmarkListRecalculateItemPositions(b *testing.B) {
+	b.Run("All", func(b *testing.B) {
+		l := newBenchList(b, benchItems, WithDirectionBackward())
+		for b.Loop() {
+			l.invalidatePositions(0)
+			l.recalculateItemPositions()
+		}
+	})
+	b.Run("Last", func(b *testing.B) {
+		l := newBenchList(b, benchItems, WithDirectionBackward())
+		for b.Loop() {
+			l.invalidatePositions(benchItems - 1)
+			l.recalculateItemPositions()
+		}
+	})
+}
+
+func BenchmarkListRender(b *testing.B) {
+	for _, direction := range []struct {
+		name   string
+		option ListOption
+	}{
+		{"Forward", WithDirectionForward()},
+		{"Backward", WithDirectionBackward()},
+	} {
+		b.Run(direction.name, func(b *testing.B) {
+			l := newBenchList(b, benchItems, direction.option)
+			for b.Loop() {
+				l.render()
+			}
+		})
+	}
+}
+
+func BenchmarkListView(b *testing.B) {
+	l := newBenchList(b, benchItems, WithDirectionBackward())
+	for b.Loop() {
+		_ = l.View()
+	}
+}
+
+func BenchmarkListUpdateItem(b *testing.B) {
+	l := newBenchList(b, benchItems, WithDirectionBackward())
+	last, _ := l.items.Get(benchItems - 1)
+	item := last.(*simpleItem)
+	for i := 0; b.Loop(); i++ {
+		// The last item grows like a streaming response.
+		item.content = fmt.Sprintf("Streaming %d\n%s", i, strings.Repeat("line\n", i%10))
+		execCmd(l, l.UpdateItem(item.ID(), item))
+	}
+}
+
+func BenchmarkListAppendItem(b *testing.B) {
+	l := newBenchList(b, benchItems, WithDirectionBackward())
+	for b.Loop() {
+		execCmd(l, l.AppendItem(NewSimpleItem("New item")))
+	}
+}
+
+// TestListPerformanceBudget guards the hot paths of huge lists against
+// regressions. Allocated bytes are measured instead of time, so the budget
+// holds on any machine, and are compared to the size of the rendered list.
+func TestListPerformanceBudget(t *testing.T) {
+	l := newBenchList(t, benchItems, WithDirectionBackward())
+	size := uint64(len(l.rendered))
+
+	// Drawing the viewport doesn't depend on the size of the list.
+	require.Less(t, bytesPerRun(func() { _ = l.View() }), size/10, "View allocates too much")
+
+	// Positions are updated in place.
+	require.Less(t, bytesPerRun(func() {
+		l.invalidatePositions(0)
+		l.recalculateItemPositions()
+	}), size/10, "recalculateItemPositions allocates too much")
+
+	// Rendering from the cache builds the rendered string once.
+	require.Less(t, bytesPerRun(func() { l.render() }), 6*size, "render allocates too much")
+
+	// Appending indexes the new item only, on top of rendering.
+	require.Less(t, bytesPerRun(func() {
+		execCmd(l, l.AppendItem(NewSimpleItem("New item")))
+	}), 12*size, "AppendItem allocates too much")
+}
+
+// bytesPerRun returns the average number of bytes allocated by f.
+func bytesPerRun(f func()) uint64 {
+	const runs = 10
+	f() // warm up caches
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for range runs {
+		f()
+	}
+	runtime.ReadMemStats(&after)
+	return (after.TotalAlloc - before.TotalAlloc) / runs
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/charmbracelet/x/exp/golden"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -570,6 +571,132 @@ type SelectableItem interface {
 	layout.Focusable
 }
 
+func TestListSelectWord(t *testing.T) {
+	t.Parallel()
+	t.Run("should count wide characters as two columns", func(t *testing.T) {
+		t.Parallel()
+		items := []Item{NewSimpleItem("漢字 hello world")}
+		l := New(items, WithDirectionForward(), WithSize(30, 5)).(*list[Item])
+		execCmd(l, l.Init())
+
+		// "hello" starts at column 5, after two wide characters and a space.
+		start, end := l.findWordBoundaries(7, 0)
+		assert.Equal(t, 5, start)
+		assert.Equal(t, 10, end)
+
+		start, end = l.findWordBoundaries(12, 0)
+		assert.Equal(t, 11, start)
+		assert.Equal(t, 16, end)
+	})
+
+	t.Run("should not select spaces", func(t *testing.T) {
+		t.Parallel()
+		items := []Item{NewSimpleItem("漢字 hello")}
+		l := New(items, WithDirectionForward(), WithSize(30, 5)).(*list[Item])
+		execCmd(l, l.Init())
+
+		start, end := l.findWordBoundaries(4, 0)
+		assert.Equal(t, 0, start)
+		assert.Equal(t, 0, end)
+	})
+}
+
+func TestListIncrementalIndex(t *testing.T) {
+	t.Parallel()
+
+	// requireConsistent checks the indexes and positions kept up to date
+	// incrementally against the ones computed from scratch.
+	requireConsistent := func(t *testing.T, l *list[Item]) {
+		t.Helper()
+		var want []renderedItem
+		for inx, item := range l.items.Seq2() {
+			got, ok := l.indexOf(item.ID())
+			require.True(t, ok)
+			require.Equal(t, inx, got)
+			rItem, ok := l.renderedItems.Get(item.ID())
+			require.True(t, ok)
+			want = append(want, rItem)
+		}
+
+		l.positionsFrom = 0
+		l.recalculateItemPositions()
+		for _, w := range want {
+			rItem, _ := l.renderedItems.Get(w.id)
+			require.Equal(t, w.start, rItem.start, w.id)
+			require.Equal(t, w.end, rItem.end, w.id)
+		}
+	}
+
+	items := []Item{}
+	for i := range 10 {
+		items = append(items, NewSimpleItem(strings.Repeat(fmt.Sprintf("Item %d\n", i), i%3+1)))
+	}
+	l := New(items, WithDirectionBackward(), WithSize(20, 10), WithGap(1)).(*list[Item])
+	execCmd(l, l.Init())
+	requireConsistent(t, l)
+
+	execCmd(l, l.PrependItem(NewSimpleItem("First\nitem")))
+	requireConsistent(t, l)
+
+	execCmd(l, l.AppendItem(NewSimpleItem("Last")))
+	requireConsistent(t, l)
+
+	execCmd(l, l.DeleteItem(items[4].ID()))
+	requireConsistent(t, l)
+	_, ok := l.indexOf(items[4].ID())
+	require.False(t, ok)
+
+	grown := items[2].(*simpleItem)
+	grown.content = "Grown\nto\nfour\nlines"
+	execCmd(l, l.UpdateItem(grown.ID(), grown))
+	requireConsistent(t, l)
+}
+
+func TestListSelectionFollowsContent(t *testing.T) {
+	t.Parallel()
+
+	items := []Item{}
+	for i := range 20 {
+		items = append(items, NewSimpleItem(fmt.Sprintf("Item %d", i)))
+	}
+	l := New(items, WithDirectionBackward(), WithSize(10, 10)).(*list[Item])
+	execCmd(l, l.Init())
+
+	lineOf := func(text string) int {
+		for i, line := range strings.Split(ansi.Strip(l.View()), "\n") {
+			if strings.TrimSpace(line) == text {
+				return i
+			}
+		}
+		return -1
+	}
+
+	line := lineOf("Item 15")
+	require.GreaterOrEqual(t, line, 0)
+	l.StartSelection(0, line)
+	l.EndSelection(7, line)
+	require.Equal(t, "Item 15", l.GetSelectedText(0))
+
+	// Appending at the bottom moves the content up.
+	execCmd(l, l.AppendItem(NewSimpleItem("Item 20")))
+	require.Equal(t, line-1, lineOf("Item 15"))
+	require.Equal(t, "Item 15", l.GetSelectedText(0))
+
+	// An item below growing moves it up further.
+	grown := items[17].(*simpleItem)
+	grown.content = "Item 17\nand\nmore"
+	execCmd(l, l.UpdateItem(grown.ID(), grown))
+	require.Equal(t, line-3, lineOf("Item 15"))
+	require.Equal(t, "Item 15", l.GetSelectedText(0))
+
+	execCmd(l, l.PrependItem(NewSimpleItem("Before")))
+	require.Equal(t, "Item 15", l.GetSelectedText(0))
+
+	// The selection goes away with its item.
+	execCmd(l, l.DeleteItem(items[15].ID()))
+	require.False(t, l.HasSelection())
+}
+
 type simpleItem struct {
 	width   int
 	content string
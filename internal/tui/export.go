@@ -0,0 +1,37 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/crush/internal/export"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// exportSession saves the conversation of the session as Markdown in dir
+// and returns the path of the file.
+func exportSession(ctx context.Context, sessions session.Service, messages message.Service, sessionID, dir string) (string, error) {
+	sess, err := sessions.Get(ctx, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get session: %w", err)
+	}
+	msgs, err := messages.List(ctx, sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list messages: %w", err)
+	}
+	path := filepath.Join(dir, "crush-session-"+shortSessionID(sess.ID)+".md")
+	if err := os.WriteFile(path, []byte(export.Markdown(sess, msgs)), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write session: %w", err)
+	}
+	return path, nil
+}
+
+func shortSessionID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,139 @@
+package highlight
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+// signal is a line pattern typical of a language.
+type signal struct {
+	lang string
+	re   *regexp.Regexp
+}
+
+var signals = []signal{
+	{"go", regexp.MustCompile(`^package \w+$|^func (\([^)]*\) )?\w+\(|^import \($|\w+ := |if err != nil|^type \w+ (struct|interface)\b`)},
+	{"python", regexp.MustCompile(`^(async )?def \w+\(.*\).*:$|^class \w+(\(.*\))?:$|^from [\w.]+ import \w|^import \w+( as \w+)?$|^\s*(elif .*|else|try|except.*|finally|with .*|for .+ in .+|while .+):$|\bself\.\w+`)},
+	{"typescript", regexp.MustCompile(`^(export )?interface \w+ \{|^(export )?type \w+ = |\w+: (string|number|boolean|void)\b`)},
+	{"javascript", regexp.MustCompile(`^(export )?(const|let|var) \w+ = |\) => \{?|^(export )?(async )?function \w+\(|console\.log\(|require\(['"]|^import .* from ['"]|^export (default )?\w+`)},
+	{"rust", regexp.MustCompile(`^(pub(\(\w+\))? )?(async )?fn \w+|let mut \w+|^use \w+(::[\w{}*, ]+)+;$|^impl\b|\w+!\(|-> [\w<>&']+ \{$`)},
+	{"java", regexp.MustCompile(`^(public |private |protected )?(static )?(final )?(class|interface|enum) \w+|System\.out\.print|^\s*(public|private|protected) [\w<>\[\]]+ \w+\(`)},
+	{"c", regexp.MustCompile(`^#include [<"]|^int main\(|printf\(`)},
+	{"bash", regexp.MustCompile(`^\$ \S|^(sudo|apt|apt-get|brew|npm|yarn|pnpm|pip|go|git|cd|export|echo|mkdir|curl|docker|make) \S|\| *(grep|awk|sed|xargs|head|tail)\b|^fi$|^done$|^then$`)},
+	{"sql", regexp.MustCompile(`^(SELECT|FROM|WHERE|(LEFT |RIGHT |INNER )?JOIN|GROUP BY|ORDER BY|INSERT INTO|UPDATE \w+ SET|DELETE FROM|CREATE (TABLE|INDEX|VIEW)|ALTER TABLE|DROP TABLE)\b`)},
+	{"html", regexp.MustCompile(`(?i)^<(!DOCTYPE|html|head|body|div|span|p|a|ul|li|script|style|template)\b[^>]*>`)},
+	{"diff", regexp.MustCompile(`^(\+\+\+|---) \S|^@@ -\d+(,\d+)? \+\d+(,\d+)? @@`)},
+	{"yaml", regexp.MustCompile(`^[a-z_][\w-]*:( [^{}\[\];]+)?$|^- [a-z_][\w-]*: `)},
+}
+
+// shebangs maps the interpreters of shebangs to their languages.
+var shebangs = map[string]string{
+	"sh":      "bash",
+	"bash":    "bash",
+	"zsh":     "bash",
+	"python":  "python",
+	"python3": "python",
+	"node":    "javascript",
+	"ruby":    "ruby",
+	"perl":    "perl",
+}
+
+// Detect guesses the language of source code from its content, returning
+// the name of a chroma lexer or "" when it can't tell.
+func Detect(source string) string {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return ""
+	}
+
+	first, _, _ := strings.Cut(source, "\n")
+	if interpreter, ok := strings.CutPrefix(first, "#!"); ok {
+		fields := strings.Fields(interpreter)
+		if len(fields) > 0 {
+			name := path.Base(fields[0])
+			if name == "env" && len(fields) > 1 {
+				name = fields[1]
+			}
+			if lang, ok := shebangs[name]; ok {
+				return lang
+			}
+		}
+	}
+
+	if (source[0] == '{' || source[0] == '[') && json.Valid([]byte(source)) {
+		return "json"
+	}
+
+	scores := make(map[string]int)
+	lines := 0
+	for line := range strings.SplitSeq(source, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines++
+		trimmed := strings.TrimLeft(line, " \t")
+		for _, s := range signals {
+			if s.re.MatchString(line) || s.re.MatchString(trimmed) {
+				scores[s.lang]++
+			}
+		}
+	}
+
+	// TypeScript is JavaScript with types.
+	if scores["typescript"] > 0 {
+		scores["typescript"] += scores["javascript"]
+	}
+	best, bestScore := "", 0
+	for _, s := range signals {
+		if score := scores[s.lang]; score > bestScore {
+			best, bestScore = s.lang, score
+		}
+	}
+	// Single signals in long sources are more likely coincidences.
+	if bestScore == 0 || bestScore*4 < lines {
+		if l := lexers.Analyse(source); l != nil {
+			return strings.ToLower(l.Config().Name)
+		}
+		return ""
+	}
+	return best
+}
+
+func matchesSignal(line string) bool {
+	trimmed := strings.TrimLeft(line, " \t")
+	for _, s := range signals {
+		if s.re.MatchString(line) || s.re.MatchString(trimmed) {
+			return true
+		}
+	}
+	return false
+}
+
+// codeLine matches lines that are typical of code rather than prose, for
+// their punctuation, keywords or indentation.
+var codeLine = regexp.MustCompile(`[{};(\[]$|^\s*[})\]]|\)$|^\s*(//|#|/\*|\*/)|^\s*[\w.]+\(.*\)|^\s*<[\w/!]|=|^\s*(return|import|from|def|class|if|for|while|pass|raise|yield|let|const|var|fn|func|package)\b|^(\t| {2,})\S`)
+
+// LooksLikeCode reports whether most of the lines of a block of text are
+// code in a language that can be detected.
+func LooksLikeCode(block string) bool {
+	var lines, code int
+	for line := range strings.SplitSeq(block, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines++
+		line = strings.TrimRight(line, " \t")
+		if codeLine.MatchString(line) || matchesSignal(line) {
+			code++
+		}
+	}
+	if lines == 0 || code*3 < lines*2 {
+		return false
+	}
+	return Detect(block) != ""
+}
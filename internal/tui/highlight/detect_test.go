@@ -0,0 +1,54 @@
+package highlight
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"go":         "package main\n\nfunc main() {\n\tx := 1\n\tif err != nil {\n\t\treturn\n\t}\n}",
+		"python":     "import os\n\ndef main():\n    for name in os.listdir('.'):\n        print(name)",
+		"javascript": "const fs = require('fs')\nconst read = (path) => fs.readFileSync(path)\nconsole.log(read('a'))",
+		"typescript": "export interface User {\n  name: string\n}\nexport const greet = (user: User) => user.name",
+		"rust":       "use std::io;\n\nfn main() {\n    let mut line = String::new();\n    println!(\"{}\", line);\n}",
+		"bash":       "#!/usr/bin/env bash\nset -e\nmake build",
+		"json":       `{"name": "crush", "tags": ["cli"]}`,
+		"sql":        "SELECT id, name\nFROM users\nWHERE id = 1;",
+		"diff":       "--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n-old\n+new",
+		"":           "This is just a sentence about the weather.",
+	}
+	for want, source := range tests {
+		t.Run(want, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, want, Detect(source))
+		})
+	}
+}
+
+func TestFenceCode(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unfenced code", func(t *testing.T) {
+		t.Parallel()
+		input := "Here is the fix:\n\nfunc main() {\n\tx := 1\n}\n\nfunc other() {\n\ty := 2\n}\n\nThat should work."
+		want := "Here is the fix:\n\n```go\nfunc main() {\n\tx := 1\n}\n\nfunc other() {\n\ty := 2\n}\n```\n\nThat should work."
+		require.Equal(t, want, FenceCode(input))
+	})
+
+	t.Run("indented code", func(t *testing.T) {
+		t.Parallel()
+		input := "Run this:\n\n    def main():\n        print('hi')\n"
+		want := "Run this:\n\n```python\ndef main():\n    print('hi')\n```\n"
+		require.Equal(t, want, FenceCode(input))
+	})
+
+	t.Run("fenced code and prose are kept", func(t *testing.T) {
+		t.Parallel()
+		input := "Some prose.\nOn two lines.\n\n```\nfunc main() {\n}\n```\n\n- a list\n\n    continued item\n\n# Title"
+		require.Equal(t, input, FenceCode(input))
+	})
+}
@@ -0,0 +1,132 @@
+package highlight
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	fenceLine = regexp.MustCompile("^\\s*(```|~~~)")
+	// notCode matches the first line of blocks that are markdown
+	// structures rather than code.
+	notCode  = regexp.MustCompile(`^\s*([-*+] |\d+[.)] |> |\|)`)
+	listItem = regexp.MustCompile(`^\s*([-*+] |\d+[.)] )`)
+	heading  = regexp.MustCompile(`^#{1,6} `)
+)
+
+// FenceCode puts the code blocks of markdown that are not fenced, whether
+// indented or not, in fences naming their language so they get
+// highlighted. Blocks are paragraphs in which most lines look like code in
+// a language that can be detected, other paragraphs are left as is.
+func FenceCode(markdown string) string {
+	if !strings.Contains(markdown, "\n") {
+		return markdown
+	}
+
+	lines := strings.Split(markdown, "\n")
+	out := make([]string, 0, len(lines))
+	var block []string
+	var fence string
+	// afterList is set when the last paragraph was a list, whose indented
+	// paragraphs continue its items.
+	afterList := false
+	// lastFence is the index in out of the end of the last fence added
+	// and its language, code blocks only separated by blank lines go in
+	// the same fence.
+	lastFence, lastLang := -1, ""
+
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		code, lang, ok := codeBlock(block, afterList)
+		afterList = listItem.MatchString(block[0]) || (afterList && isIndented(block))
+		block = nil
+		if !ok {
+			out = append(out, code...)
+			lastFence = -1
+			return
+		}
+		if lastFence >= 0 && lang == lastLang {
+			// Move the end of the last fence after this block.
+			out = append(out[:lastFence], out[lastFence+1:]...)
+		} else {
+			out = append(out, "```"+lang)
+		}
+		out = append(out, code...)
+		out = append(out, "```")
+		lastFence, lastLang = len(out)-1, lang
+	}
+
+	for _, line := range lines {
+		if fence != "" {
+			out = append(out, line)
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				fence = ""
+			}
+			continue
+		}
+		if m := fenceLine.FindStringSubmatch(line); m != nil {
+			flush()
+			lastFence = -1
+			fence = m[1]
+			out = append(out, line)
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			out = append(out, line)
+			continue
+		}
+		block = append(block, line)
+	}
+	flush()
+	return strings.Join(out, "\n")
+}
+
+// codeBlock returns the code of a paragraph and its language, or the
+// paragraph and false when it's not code.
+func codeBlock(block []string, afterList bool) ([]string, string, bool) {
+	if notCode.MatchString(block[0]) || (len(block) == 1 && heading.MatchString(block[0])) {
+		return block, "", false
+	}
+	code := block
+	if isIndented(block) {
+		if afterList {
+			return block, "", false
+		}
+		code = dedent(block)
+	} else if len(block) < 2 {
+		// Single lines are too short to tell code from prose.
+		return block, "", false
+	}
+
+	source := strings.Join(code, "\n")
+	if !LooksLikeCode(source) {
+		return block, "", false
+	}
+	return code, Detect(source), true
+}
+
+// isIndented reports whether the lines make an indented code block.
+func isIndented(lines []string) bool {
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "    ") && !strings.HasPrefix(line, "\t") {
+			return false
+		}
+	}
+	return true
+}
+
+// dedent removes the indentation of indented code blocks.
+func dedent(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if rest, ok := strings.CutPrefix(line, "\t"); ok {
+			out[i] = rest
+		} else {
+			out[i] = line[4:]
+		}
+	}
+	return out
+}
@@ -12,8 +12,14 @@ import (
 )
 
 func SyntaxHighlight(source, fileName string, bg color.Color) (string, error) {
-	// Determine the language lexer to use
+	// Determine the language lexer to use, from the name of the file or
+	// else from the content.
 	l := lexers.Match(fileName)
+	if l == nil {
+		if lang := Detect(source); lang != "" {
+			l = lexers.Get(lang)
+		}
+	}
 	if l == nil {
 		l = lexers.Analyse(source)
 	}
@@ -2,6 +2,7 @@ package tui
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/actions"
 )
 
 type KeyMap struct {
@@ -10,31 +11,22 @@ type KeyMap struct {
 	Commands key.Binding
 	Suspend  key.Binding
 	Sessions key.Binding
+	Drafts   key.Binding
+
+	// Inspector opens the provider request inspector, debug mode only.
+	Inspector key.Binding
 
 	pageBindings []key.Binding
 }
 
 func DefaultKeyMap() KeyMap {
 	return KeyMap{
-		Quit: key.NewBinding(
-			key.WithKeys("ctrl+c"),
-			key.WithHelp("ctrl+c", "quit"),
-		),
-		Help: key.NewBinding(
-			key.WithKeys("ctrl+g"),
-			key.WithHelp("ctrl+g", "more"),
-		),
-		Commands: key.NewBinding(
-			key.WithKeys("ctrl+p"),
-			key.WithHelp("ctrl+p", "commands"),
-		),
-		Suspend: key.NewBinding(
-			key.WithKeys("ctrl+z"),
-			key.WithHelp("ctrl+z", "suspend"),
-		),
-		Sessions: key.NewBinding(
-			key.WithKeys("ctrl+s"),
-			key.WithHelp("ctrl+s", "sessions"),
-		),
+		Quit:      actions.Binding(actions.Quit),
+		Help:      actions.Binding(actions.ToggleHelp),
+		Commands:  actions.Binding(actions.Commands),
+		Suspend:   actions.Binding(actions.Suspend),
+		Sessions:  actions.Binding(actions.SwitchSession),
+		Drafts:    actions.Binding(actions.DraftHistory),
+		Inspector: actions.Binding(actions.Inspector),
 	}
 }
@@ -4,19 +4,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
+	"charm.land/fantasy"
 	"github.com/charmbracelet/bubbles/v2/help"
 	"github.com/charmbracelet/bubbles/v2/key"
 	"github.com/charmbracelet/bubbles/v2/spinner"
 	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/agent"
 	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/artifact"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/i18n"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/terminal"
+	"github.com/charmbracelet/crush/internal/tui/actions"
 	"github.com/charmbracelet/crush/internal/tui/components/anim"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/editor"
@@ -24,14 +31,21 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/chat/messages"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/sidebar"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/splash"
+	"github.com/charmbracelet/crush/internal/tui/components/chat/terminalpane"
 	"github.com/charmbracelet/crush/internal/tui/components/completions"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/drafts"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/links"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/models"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/plan"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/quickopen"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/reasoning"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/stopphrase"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/topic"
 	"github.com/charmbracelet/crush/internal/tui/page"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
@@ -54,6 +68,8 @@ const (
 	PanelTypeChat   PanelType = "chat"
 	PanelTypeEditor PanelType = "editor"
 	PanelTypeSplash PanelType = "splash"
+	// PanelTypeTerminal is the terminal pane next to the chat.
+	PanelTypeTerminal PanelType = "terminal"
 )
 
 const (
@@ -78,6 +94,9 @@ type ChatPage interface {
 	util.Model
 	layout.Help
 	IsChatFocused() bool
+	// IsMessageOffscreen reports whether the message is out of view, its
+	// updates can wait longer to be rendered.
+	IsMessageOffscreen(id string) bool
 }
 
 // cancelTimerCmd creates a command that expires the cancel timer
@@ -94,9 +113,12 @@ type chatPage struct {
 	keyboardEnhancements        tea.KeyboardEnhancementsMsg
 
 	// Layout state
-	compact      bool
-	forceCompact bool
-	focusedPane  PanelType
+	compact         bool
+	forceCompact    bool
+	focusedPane     PanelType
+	layout          layoutState
+	dataDir         string
+	resizingSidebar bool
 
 	// Session
 	session session.Session
@@ -108,13 +130,18 @@ type chatPage struct {
 	chat    chat.MessageListCmp
 	editor  editor.Editor
 	splash  splash.Splash
+	// terminal shows the output of shell commands in the split layout.
+	terminal terminalpane.TerminalPane
 
 	// Simple state flags
 	showingDetails   bool
 	isCanceling      bool
 	splashFullScreen bool
-	isOnboarding     bool
-	isProjectInit    bool
+	// shellOutputInPane is set while bash output is left to the terminal
+	// pane rather than shown in the chat.
+	shellOutputInPane bool
+	isOnboarding      bool
+	isProjectInit     bool
 }
 
 func New(app *app.App) ChatPage {
@@ -122,10 +149,11 @@ func New(app *app.App) ChatPage {
 		app:         app,
 		keyMap:      DefaultKeyMap(),
 		header:      header.New(app.LSPClients),
-		sidebar:     sidebar.New(app.History, app.LSPClients, false),
+		sidebar:     sidebar.New(app.History, app.Artifacts, app.LSPClients, false),
 		chat:        chat.New(app),
 		editor:      editor.New(app),
 		splash:      splash.New(),
+		terminal:    terminalpane.New(),
 		focusedPane: PanelTypeSplash,
 	}
 }
@@ -137,6 +165,13 @@ func (p *chatPage) Init() tea.Cmd {
 	p.forceCompact = compact
 	p.sidebar.SetCompactMode(p.compact)
 
+	p.dataDir = cfg.Options.DataDirectory
+	layout, err := loadLayout(p.dataDir)
+	if err != nil {
+		slog.Warn("Failed to load layout", "error", err)
+	}
+	p.layout = layout
+
 	// Set splash state based on config
 	if !config.HasInitialDataConfig() {
 		// First-time setup: show model selection
@@ -160,6 +195,7 @@ func (p *chatPage) Init() tea.Cmd {
 		p.chat.Init(),
 		p.editor.Init(),
 		p.splash.Init(),
+		p.terminal.Init(),
 	)
 }
 
@@ -178,27 +214,38 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			p.chat = u.(chat.MessageListCmp)
 			return p, cmd
 		}
+		if p.isMouseOverTerminal(msg.X, msg.Y) {
+			u, cmd := p.terminal.Update(msg)
+			p.terminal = u.(terminalpane.TerminalPane)
+			return p, cmd
+		}
 		return p, nil
 	case tea.MouseClickMsg:
 		if p.isOnboarding {
 			return p, nil
 		}
+		if msg.Button == tea.MouseLeft && p.isOverSidebarEdge(msg.X, msg.Y) {
+			p.resizingSidebar = true
+			return p, nil
+		}
 		if p.compact {
 			msg.Y -= 1
 		}
-		if p.isMouseOverChat(msg.X, msg.Y) {
-			p.focusedPane = PanelTypeChat
-			p.chat.Focus()
-			p.editor.Blur()
-		} else {
-			p.focusedPane = PanelTypeEditor
-			p.editor.Focus()
-			p.chat.Blur()
+		switch {
+		case p.isMouseOverChat(msg.X, msg.Y):
+			p.setFocus(PanelTypeChat)
+		case p.isMouseOverTerminal(msg.X, msg.Y):
+			p.setFocus(PanelTypeTerminal)
+		default:
+			p.setFocus(PanelTypeEditor)
 		}
 		u, cmd := p.chat.Update(msg)
 		p.chat = u.(chat.MessageListCmp)
 		return p, cmd
 	case tea.MouseMotionMsg:
+		if p.resizingSidebar {
+			return p, p.resizeSidebar(p.width - msg.X)
+		}
 		if p.compact {
 			msg.Y -= 1
 		}
@@ -212,6 +259,10 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		if p.isOnboarding {
 			return p, nil
 		}
+		if p.resizingSidebar {
+			p.resizingSidebar = false
+			return p, p.saveLayout()
+		}
 		if p.compact {
 			msg.Y -= 1
 		}
@@ -232,12 +283,34 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 	case CancelTimerExpiredMsg:
 		p.isCanceling = false
 		return p, nil
-	case editor.OpenEditorMsg:
+	case editor.OpenEditorMsg, editor.SaveDraftMsg:
 		u, cmd := p.editor.Update(msg)
 		p.editor = u.(editor.Editor)
 		return p, cmd
 	case chat.SendMsg:
 		return p, p.sendMessage(msg.Text, msg.Attachments)
+	case topic.KeepTopicMsg:
+		if msg.SessionID != p.session.ID {
+			return p, nil
+		}
+		return p, p.send(p.session, msg.Text, msg.Attachments)
+	case topic.NewTopicMsg:
+		return p, p.startNewTopic(msg)
+	case plan.ApprovedMsg:
+		if msg.SessionID != p.session.ID || p.app.AgentCoordinator == nil {
+			return p, nil
+		}
+		return p, tea.Batch(
+			p.chat.GoToBottom(),
+			p.runAgent(msg.SessionID, func(ctx context.Context) (*fantasy.AgentResult, error) {
+				return p.app.AgentCoordinator.RunApprovedPlan(ctx, msg.SessionID, msg.Plan)
+			}, false),
+		)
+	case newTopicStartedMsg:
+		return p, tea.Batch(
+			util.CmdHandler(chat.SessionSelectedMsg(msg.session)),
+			p.send(msg.session, msg.text, msg.attachments),
+		)
 	case chat.SessionSelectedMsg:
 		return p, p.setSession(msg)
 	case splash.SubmitAPIKeyMsg:
@@ -256,6 +329,26 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			cmd = p.updateCompactConfig(false)
 		}
 		return p, tea.Batch(p.SetSize(p.width, p.height), cmd)
+	case commands.ToggleSidebarMsg:
+		if !p.canResizeSidebar() {
+			return p, nil
+		}
+		return p, p.toggleSidebar()
+	case commands.ToggleTerminalMsg:
+		if !p.canShowTerminal() {
+			return p, util.ReportWarn("The terminal pane isn't available in compact layout")
+		}
+		return p, p.toggleTerminal()
+	case commands.FollowLinkMsg:
+		return p, p.followLink()
+	case pubsub.Event[terminal.Output]:
+		u, cmd := p.terminal.Update(msg)
+		p.terminal = u.(terminalpane.TerminalPane)
+		return p, cmd
+	case chat.ToggleToolOutputMsg, chat.RefreshMsg:
+		u, cmd := p.chat.Update(msg)
+		p.chat = u.(chat.MessageListCmp)
+		return p, cmd
 	case commands.ToggleThinkingMsg:
 		return p, p.toggleThinking()
 	case commands.OpenReasoningDialogMsg:
@@ -286,6 +379,8 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		cmds = append(cmds, cmd)
 		return p, tea.Batch(cmds...)
 	case filepicker.FilePickedMsg,
+		quickopen.FileSelectedMsg,
+		drafts.DraftSelectedMsg,
 		completions.CompletionsClosedMsg,
 		completions.SelectCompletionMsg:
 		u, cmd := p.editor.Update(msg)
@@ -319,7 +414,7 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		u, cmd := p.editor.Update(msg)
 		p.editor = u.(editor.Editor)
 		return p, cmd
-	case pubsub.Event[history.File], sidebar.SessionFilesMsg:
+	case pubsub.Event[history.File], sidebar.SessionFilesMsg, pubsub.Event[artifact.Artifact], sidebar.SessionArtifactsMsg:
 		u, cmd := p.sidebar.Update(msg)
 		p.sidebar = u.(sidebar.Sidebar)
 		cmds = append(cmds, cmd)
@@ -393,6 +488,16 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 		case key.Matches(msg, p.keyMap.Details):
 			p.toggleDetails()
 			return p, nil
+		case key.Matches(msg, p.keyMap.ToggleSidebar) && p.canResizeSidebar():
+			return p, p.toggleSidebar()
+		case key.Matches(msg, p.keyMap.ToggleTerminal) && p.canShowTerminal():
+			return p, p.toggleTerminal()
+		case key.Matches(msg, p.keyMap.FollowLink) && p.session.ID != "":
+			return p, p.followLink()
+		case key.Matches(msg, p.keyMap.GrowSidebar) && p.canResizeSidebar():
+			return p, tea.Batch(p.resizeSidebar(p.sidebarWidth()+SideBarWidthStep), p.saveLayout())
+		case key.Matches(msg, p.keyMap.ShrinkSidebar) && p.canResizeSidebar():
+			return p, tea.Batch(p.resizeSidebar(p.sidebarWidth()-SideBarWidthStep), p.saveLayout())
 		}
 
 		switch p.focusedPane {
@@ -408,6 +513,10 @@ func (p *chatPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
 			u, cmd := p.splash.Update(msg)
 			p.splash = u.(splash.Splash)
 			cmds = append(cmds, cmd)
+		case PanelTypeTerminal:
+			u, cmd := p.terminal.Update(msg)
+			p.terminal = u.(terminalpane.TerminalPane)
+			cmds = append(cmds, cmd)
 		}
 	case tea.PasteMsg:
 		switch p.focusedPane {
@@ -475,12 +584,21 @@ func (p *chatPage) View() string {
 				editorView,
 			)
 		} else {
-			sidebarView := p.sidebar.View()
-			messages := lipgloss.JoinHorizontal(
-				lipgloss.Left,
-				messagesView,
-				sidebarView,
-			)
+			messages := messagesView
+			if p.showingTerminal() {
+				messages = lipgloss.JoinHorizontal(
+					lipgloss.Left,
+					messages,
+					p.terminal.View(),
+				)
+			}
+			if !p.layout.SidebarHidden {
+				messages = lipgloss.JoinHorizontal(
+					lipgloss.Left,
+					messages,
+					p.sidebar.View(),
+				)
+			}
 			chatView = lipgloss.JoinVertical(
 				lipgloss.Left,
 				messages,
@@ -649,12 +767,16 @@ func (p *chatPage) SetSize(width, height int) tea.Cmd {
 			cmds = append(cmds, p.editor.SetSize(width, EditorHeight))
 			cmds = append(cmds, p.header.SetWidth(width-BorderWidth))
 		} else {
-			cmds = append(cmds, p.chat.SetSize(width-SideBarWidth, height-EditorHeight))
+			sidebarWidth := p.sidebarWidth()
+			terminalWidth := p.terminalWidth()
+			cmds = append(cmds, p.chat.SetSize(width-sidebarWidth-terminalWidth, height-EditorHeight))
+			cmds = append(cmds, p.terminal.SetSize(terminalWidth, height-EditorHeight))
 			cmds = append(cmds, p.editor.SetSize(width, EditorHeight))
-			cmds = append(cmds, p.sidebar.SetSize(SideBarWidth, height-EditorHeight))
+			cmds = append(cmds, p.sidebar.SetSize(sidebarWidth, height-EditorHeight))
 		}
 		cmds = append(cmds, p.editor.SetPosition(0, height-EditorHeight))
 	}
+	cmds = append(cmds, p.syncShellOutput())
 	return tea.Batch(cmds...)
 }
 
@@ -664,11 +786,11 @@ func (p *chatPage) newSession() tea.Cmd {
 	}
 
 	p.session = session.Session{}
-	p.focusedPane = PanelTypeEditor
-	p.editor.Focus()
-	p.chat.Blur()
+	p.setFocus(PanelTypeEditor)
+	p.terminal.SetSession("")
 	p.isCanceling = false
 	return tea.Batch(
+		p.editor.SetSession(p.session),
 		util.CmdHandler(chat.SessionClearedMsg{}),
 		p.SetSize(p.width, p.height),
 	)
@@ -681,6 +803,7 @@ func (p *chatPage) setSession(session session.Session) tea.Cmd {
 
 	var cmds []tea.Cmd
 	p.session = session
+	p.terminal.SetSession(session.ID)
 
 	cmds = append(cmds, p.SetSize(p.width, p.height))
 	cmds = append(cmds, p.chat.SetSession(session))
@@ -696,17 +819,55 @@ func (p *chatPage) changeFocus() {
 		return
 	}
 	switch p.focusedPane {
-	case PanelTypeChat:
-		p.focusedPane = PanelTypeEditor
-		p.editor.Focus()
-		p.chat.Blur()
 	case PanelTypeEditor:
-		p.focusedPane = PanelTypeChat
+		p.setFocus(PanelTypeChat)
+	case PanelTypeChat:
+		if p.showingTerminal() {
+			p.setFocus(PanelTypeTerminal)
+		} else {
+			p.setFocus(PanelTypeEditor)
+		}
+	case PanelTypeTerminal:
+		p.setFocus(PanelTypeEditor)
+	}
+}
+
+// setFocus focuses the pane, blurring the others.
+func (p *chatPage) setFocus(pane PanelType) {
+	p.focusedPane = pane
+	p.chat.Blur()
+	p.editor.Blur()
+	p.terminal.Blur()
+	switch pane {
+	case PanelTypeChat:
 		p.chat.Focus()
-		p.editor.Blur()
+	case PanelTypeEditor:
+		p.editor.Focus()
+	case PanelTypeTerminal:
+		p.terminal.Focus()
 	}
 }
 
+// followLink opens the dialog to follow one of the links of the
+// conversation.
+func (p *chatPage) followLink() tea.Cmd {
+	if p.session.ID == "" {
+		return nil
+	}
+	msgs, err := p.app.Messages.List(context.TODO(), p.session.ID)
+	if err != nil {
+		return util.ReportError(err)
+	}
+	workingDir := p.app.Config().WorkingDir()
+	found := links.FromMessages(workingDir, msgs)
+	if len(found) == 0 {
+		return util.ReportInfo("No links or file paths in the conversation")
+	}
+	return util.CmdHandler(dialogs.OpenDialogMsg{
+		Model: links.NewLinksDialog(workingDir, found),
+	})
+}
+
 func (p *chatPage) cancel() tea.Cmd {
 	if p.isCanceling {
 		p.isCanceling = false
@@ -739,10 +900,58 @@ func (p *chatPage) toggleDetails() {
 	p.setShowDetails(!p.showingDetails)
 }
 
+// topicCheckTimeout bounds how long sending a prompt waits for the
+// detection of a change of topic.
+const topicCheckTimeout = 10 * time.Second
+
+// newTopicStartedMsg is sent once the session a prompt goes on with another
+// topic in is ready.
+type newTopicStartedMsg struct {
+	session     session.Session
+	text        string
+	attachments []message.Attachment
+}
+
 func (p *chatPage) sendMessage(text string, attachments []message.Attachment) tea.Cmd {
-	session := p.session
+	if p.session.ID == "" || p.app.AgentCoordinator == nil ||
+		p.app.Config().Options.TopicChange.Detector == "" ||
+		p.app.AgentCoordinator.IsSessionBusy(p.session.ID) {
+		return p.send(p.session, text, attachments)
+	}
+	// Offer to start a new session when the prompt changes the topic.
+	sess := p.session
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), topicCheckTimeout)
+		defer cancel()
+		changed, err := p.app.AgentCoordinator.TopicChanged(ctx, sess.ID, text)
+		if err != nil {
+			slog.Warn("Failed to detect a change of topic", "error", err)
+		}
+		if changed {
+			return dialogs.OpenDialogMsg{Model: topic.NewTopicDialog(sess.ID, text, attachments)}
+		}
+		return topic.KeepTopicMsg{SessionID: sess.ID, Text: text, Attachments: attachments}
+	}
+}
+
+// startNewTopic creates the session carrying a brief summary of the session
+// that the prompt goes on in.
+func (p *chatPage) startNewTopic(msg topic.NewTopicMsg) tea.Cmd {
+	return tea.Batch(
+		util.ReportInfo(i18n.T("topic.starting")),
+		func() tea.Msg {
+			newSession, err := p.app.AgentCoordinator.StartNewTopic(context.Background(), msg.SessionID)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			return newTopicStartedMsg{session: newSession, text: msg.Text, attachments: msg.Attachments}
+		},
+	)
+}
+
+func (p *chatPage) send(session session.Session, text string, attachments []message.Attachment) tea.Cmd {
 	var cmds []tea.Cmd
-	if p.session.ID == "" {
+	if session.ID == "" {
 		newSession, err := p.app.Sessions.Create(context.Background(), "New Session")
 		if err != nil {
 			return util.ReportError(err)
@@ -754,22 +963,50 @@ func (p *chatPage) sendMessage(text string, attachments []message.Attachment) te
 		return util.ReportError(fmt.Errorf("coder agent is not initialized"))
 	}
 	cmds = append(cmds, p.chat.GoToBottom())
-	cmds = append(cmds, func() tea.Msg {
-		_, err := p.app.AgentCoordinator.Run(context.Background(), session.ID, text, attachments...)
+	cmds = append(cmds, p.runAgent(session.ID, func(ctx context.Context) (*fantasy.AgentResult, error) {
+		return p.app.AgentCoordinator.Run(ctx, session.ID, text, attachments...)
+	}, true))
+	return tea.Batch(cmds...)
+}
+
+// runAgent runs the agent on the session, reporting why it failed. With
+// reviewPlan, the response of runs in plan mode is offered for approval.
+func (p *chatPage) runAgent(sessionID string, run func(context.Context) (*fantasy.AgentResult, error), reviewPlan bool) tea.Cmd {
+	return func() tea.Msg {
+		result, err := run(context.Background())
 		if err != nil {
 			isCancelErr := errors.Is(err, context.Canceled)
 			isPermissionErr := errors.Is(err, permission.ErrorPermissionDenied)
 			if isCancelErr || isPermissionErr {
 				return nil
 			}
+			var stopErr *agent.StopPhraseError
+			if errors.As(err, &stopErr) {
+				return dialogs.OpenDialogMsg{
+					Model: stopphrase.NewStopPhraseDialog(sessionID, stopErr.Phrase),
+				}
+			}
+			var overflowErr *agent.ContextOverflowError
+			if errors.As(err, &overflowErr) {
+				return util.InfoMsg{
+					Type: util.InfoTypeError,
+					Msg:  fmt.Sprintf("Prompt is too large for the model (~%d of %d tokens), summarize the session or remove large attachments", overflowErr.EstimatedTokens, overflowErr.Limit),
+				}
+			}
 			return util.InfoMsg{
 				Type: util.InfoTypeError,
 				Msg:  err.Error(),
 			}
 		}
+		// In plan mode the response is the plan, which needs approval before
+		// the agent is allowed to make changes.
+		if reviewPlan && result != nil && p.app.AgentCoordinator.IsPlanMode(sessionID) {
+			return dialogs.OpenDialogMsg{
+				Model: plan.NewPlanDialog(sessionID, result.Response.Content.Text()),
+			}
+		}
 		return nil
-	})
-	return tea.Batch(cmds...)
+	}
 }
 
 func (p *chatPage) Bindings() []key.Binding {
@@ -805,6 +1042,14 @@ func (p *chatPage) Bindings() []key.Binding {
 			),
 		}, bindings...)
 		bindings = append(bindings, p.editor.Bindings()...)
+	case PanelTypeTerminal:
+		bindings = append([]key.Binding{
+			key.NewBinding(
+				key.WithKeys("tab"),
+				key.WithHelp("tab", "focus editor"),
+			),
+		}, bindings...)
+		bindings = append(bindings, p.terminal.Bindings()...)
 	case PanelTypeSplash:
 		bindings = append(bindings, p.splash.Bindings()...)
 	}
@@ -829,10 +1074,7 @@ func (p *chatPage) Help() help.KeyMap {
 				key.WithHelp("enter", "accept"),
 			),
 			// Quit
-			key.NewBinding(
-				key.WithKeys("ctrl+c"),
-				key.WithHelp("ctrl+c", "quit"),
-			),
+			actions.Binding(actions.Quit),
 		)
 		// keep them the same
 		for _, v := range shortList {
@@ -857,22 +1099,14 @@ func (p *chatPage) Help() help.KeyMap {
 		}
 		shortList = append(shortList,
 			// Quit
-			key.NewBinding(
-				key.WithKeys("ctrl+c"),
-				key.WithHelp("ctrl+c", "quit"),
-			),
+			actions.Binding(actions.Quit),
 		)
 		// keep them the same
 		for _, v := range shortList {
 			fullList = append(fullList, []key.Binding{v})
 		}
 	case p.isProjectInit:
-		shortList = append(shortList,
-			key.NewBinding(
-				key.WithKeys("ctrl+c"),
-				key.WithHelp("ctrl+c", "quit"),
-			),
-		)
+		shortList = append(shortList, actions.Binding(actions.Quit))
 		// keep them the same
 		for _, v := range shortList {
 			fullList = append(fullList, []key.Binding{v})
@@ -929,7 +1163,13 @@ func (p *chatPage) Help() help.KeyMap {
 				key.WithKeys("tab"),
 				key.WithHelp("tab", "focus chat"),
 			)
-			if p.focusedPane == PanelTypeChat {
+			switch {
+			case p.focusedPane == PanelTypeChat && p.showingTerminal():
+				tabKey = key.NewBinding(
+					key.WithKeys("tab"),
+					key.WithHelp("tab", "focus terminal"),
+				)
+			case p.focusedPane == PanelTypeChat, p.focusedPane == PanelTypeTerminal:
 				tabKey = key.NewBinding(
 					key.WithKeys("tab"),
 					key.WithHelp("tab", "focus editor"),
@@ -938,33 +1178,26 @@ func (p *chatPage) Help() help.KeyMap {
 			shortList = append(shortList, tabKey)
 			globalBindings = append(globalBindings, tabKey)
 		}
-		commandsBinding := key.NewBinding(
-			key.WithKeys("ctrl+p"),
-			key.WithHelp("ctrl+p", "commands"),
-		)
-		helpBinding := key.NewBinding(
-			key.WithKeys("ctrl+g"),
-			key.WithHelp("ctrl+g", "more"),
-		)
+		commandsBinding := actions.Binding(actions.Commands)
+		helpBinding := actions.Binding(actions.ToggleHelp)
 		globalBindings = append(globalBindings, commandsBinding)
-		globalBindings = append(globalBindings,
-			key.NewBinding(
-				key.WithKeys("ctrl+s"),
-				key.WithHelp("ctrl+s", "sessions"),
-			),
-		)
+		globalBindings = append(globalBindings, actions.Binding(actions.SwitchSession))
 		if p.session.ID != "" {
-			globalBindings = append(globalBindings,
-				key.NewBinding(
-					key.WithKeys("ctrl+n"),
-					key.WithHelp("ctrl+n", "new sessions"),
-				))
+			globalBindings = append(globalBindings, p.keyMap.NewSession, p.keyMap.FollowLink)
 		}
 		shortList = append(shortList,
 			// Commands
 			commandsBinding,
 		)
 		fullList = append(fullList, globalBindings)
+		if p.canResizeSidebar() {
+			fullList = append(fullList, []key.Binding{
+				p.keyMap.ToggleSidebar,
+				p.keyMap.GrowSidebar,
+				p.keyMap.ShrinkSidebar,
+				p.keyMap.ToggleTerminal,
+			})
+		}
 
 		switch p.focusedPane {
 		case PanelTypeChat:
@@ -1014,9 +1247,18 @@ func (p *chatPage) Help() help.KeyMap {
 				},
 				[]key.Binding{
 					messages.CopyKey,
+					messages.SummaryKey,
 					messages.ClearSelectionKey,
 				},
 			)
+		case PanelTypeTerminal:
+			shortList = append(shortList,
+				key.NewBinding(
+					key.WithKeys("up", "down"),
+					key.WithHelp("↑↓", "scroll"),
+				),
+			)
+			fullList = append(fullList, p.terminal.Bindings())
 		case PanelTypeEditor:
 			newLineBinding := key.NewBinding(
 				key.WithKeys("shift+enter", "ctrl+j"),
@@ -1032,18 +1274,20 @@ func (p *chatPage) Help() help.KeyMap {
 			fullList = append(fullList,
 				[]key.Binding{
 					newLineBinding,
-					key.NewBinding(
-						key.WithKeys("ctrl+f"),
-						key.WithHelp("ctrl+f", "add image"),
-					),
+					p.keyMap.AddAttachment,
 					key.NewBinding(
 						key.WithKeys("/"),
 						key.WithHelp("/", "add file"),
 					),
 					key.NewBinding(
-						key.WithKeys("ctrl+o"),
-						key.WithHelp("ctrl+o", "open editor"),
+						key.WithKeys("@"),
+						key.WithHelp("@", "mention file"),
+					),
+					key.NewBinding(
+						key.WithKeys("#"),
+						key.WithHelp("#", "reference symbol"),
 					),
+					actions.Binding(actions.OpenEditor),
 				})
 
 			if p.editor.HasAttachments() {
@@ -1065,10 +1309,7 @@ func (p *chatPage) Help() help.KeyMap {
 		}
 		shortList = append(shortList,
 			// Quit
-			key.NewBinding(
-				key.WithKeys("ctrl+c"),
-				key.WithHelp("ctrl+c", "quit"),
-			),
+			actions.Binding(actions.Quit),
 			// Help
 			helpBinding,
 		)
@@ -1087,6 +1328,10 @@ func (p *chatPage) IsChatFocused() bool {
 	return p.focusedPane == PanelTypeChat
 }
 
+func (p *chatPage) IsMessageOffscreen(id string) bool {
+	return p.session.ID != "" && p.chat.IsMessageOffscreen(id)
+}
+
 // isMouseOverChat checks if the given mouse coordinates are within the chat area bounds.
 // Returns true if the mouse is over the chat area, false otherwise.
 func (p *chatPage) isMouseOverChat(x, y int) bool {
@@ -1107,7 +1352,7 @@ func (p *chatPage) isMouseOverChat(x, y int) bool {
 		// In non-compact mode: chat area spans from left edge to sidebar
 		chatX = 0
 		chatY = 0
-		chatWidth = p.width - SideBarWidth
+		chatWidth = p.width - p.sidebarWidth() - p.terminalWidth()
 		chatHeight = p.height - EditorHeight
 	}
 
@@ -2,6 +2,7 @@ package chat
 
 import (
 	"github.com/charmbracelet/bubbles/v2/key"
+	"github.com/charmbracelet/crush/internal/tui/actions"
 )
 
 type KeyMap struct {
@@ -10,18 +11,18 @@ type KeyMap struct {
 	Cancel        key.Binding
 	Tab           key.Binding
 	Details       key.Binding
+	ToggleSidebar key.Binding
+	GrowSidebar   key.Binding
+	ShrinkSidebar key.Binding
+
+	ToggleTerminal key.Binding
+	FollowLink     key.Binding
 }
 
 func DefaultKeyMap() KeyMap {
 	return KeyMap{
-		NewSession: key.NewBinding(
-			key.WithKeys("ctrl+n"),
-			key.WithHelp("ctrl+n", "new session"),
-		),
-		AddAttachment: key.NewBinding(
-			key.WithKeys("ctrl+f"),
-			key.WithHelp("ctrl+f", "add attachment"),
-		),
+		NewSession:    actions.Binding(actions.NewSession),
+		AddAttachment: actions.Binding(actions.AddAttachment),
 		Cancel: key.NewBinding(
 			key.WithKeys("esc", "alt+esc"),
 			key.WithHelp("esc", "cancel"),
@@ -30,9 +31,12 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("tab"),
 			key.WithHelp("tab", "change focus"),
 		),
-		Details: key.NewBinding(
-			key.WithKeys("ctrl+d"),
-			key.WithHelp("ctrl+d", "toggle details"),
-		),
+		Details:       actions.Binding(actions.ToggleDetails),
+		ToggleSidebar: actions.Binding(actions.ToggleSidebar),
+		GrowSidebar:   actions.Binding(actions.GrowSidebar),
+		ShrinkSidebar: actions.Binding(actions.ShrinkSidebar),
+
+		ToggleTerminal: actions.Binding(actions.ToggleTerminal),
+		FollowLink:     actions.Binding(actions.FollowLink),
 	}
 }
@@ -0,0 +1,177 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/tui/components/chat"
+	"github.com/charmbracelet/crush/internal/tui/components/chat/messages"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+const (
+	layoutFileName = "layout.json"
+
+	MinSideBarWidth  = 20 // Narrowest the sidebar can be resized to
+	SideBarWidthStep = 2  // Columns the sidebar grows or shrinks by per key press
+)
+
+// layoutState is the layout of the chat page the user chose, restored the
+// next time crush is opened in the project.
+type layoutState struct {
+	SidebarWidth  int  `json:"sidebar_width,omitempty"`
+	SidebarHidden bool `json:"sidebar_hidden,omitempty"`
+	// TerminalShown splits the chat with the terminal pane, where the
+	// output of shell commands streams.
+	TerminalShown bool `json:"terminal_shown,omitempty"`
+}
+
+func layoutPath(dataDir string) string {
+	return filepath.Join(dataDir, layoutFileName)
+}
+
+// loadLayout reads the layout saved in the data directory, falling back to
+// the default layout when there is none.
+func loadLayout(dataDir string) (layoutState, error) {
+	state := layoutState{SidebarWidth: SideBarWidth}
+	data, err := os.ReadFile(layoutPath(dataDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return layoutState{SidebarWidth: SideBarWidth}, err
+	}
+	if state.SidebarWidth < MinSideBarWidth {
+		state.SidebarWidth = SideBarWidth
+	}
+	return state, nil
+}
+
+func saveLayout(dataDir string, state layoutState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(layoutPath(dataDir), data, 0o644)
+}
+
+// clampSidebarWidth keeps the sidebar between its minimum width and half
+// of the page.
+func clampSidebarWidth(width, pageWidth int) int {
+	return max(MinSideBarWidth, min(width, pageWidth/2))
+}
+
+// sidebarWidth returns the width the sidebar takes on the page, zero when
+// it's hidden.
+func (p *chatPage) sidebarWidth() int {
+	if p.layout.SidebarHidden {
+		return 0
+	}
+	return clampSidebarWidth(p.layout.SidebarWidth, p.width)
+}
+
+// canResizeSidebar reports whether the sidebar is shown next to the chat,
+// rather than in the details panel of compact mode.
+func (p *chatPage) canResizeSidebar() bool {
+	return p.session.ID != "" && !p.compact
+}
+
+// isOverSidebarEdge reports whether the coordinates are on the left edge of
+// the sidebar, where it can be dragged to resize it.
+func (p *chatPage) isOverSidebarEdge(x, y int) bool {
+	if !p.canResizeSidebar() || p.layout.SidebarHidden {
+		return false
+	}
+	edge := p.width - p.sidebarWidth()
+	return (x == edge || x == edge-1) && y < p.height-EditorHeight
+}
+
+func (p *chatPage) resizeSidebar(width int) tea.Cmd {
+	width = clampSidebarWidth(width, p.width)
+	if width == p.layout.SidebarWidth {
+		return nil
+	}
+	p.layout.SidebarWidth = width
+	return p.SetSize(p.width, p.height)
+}
+
+func (p *chatPage) toggleSidebar() tea.Cmd {
+	p.layout.SidebarHidden = !p.layout.SidebarHidden
+	return tea.Batch(p.SetSize(p.width, p.height), p.saveLayout())
+}
+
+// canShowTerminal reports whether there is room for the terminal pane next
+// to the chat.
+func (p *chatPage) canShowTerminal() bool {
+	return p.session.ID != "" && !p.compact
+}
+
+// showingTerminal reports whether the terminal pane is shown next to the
+// chat.
+func (p *chatPage) showingTerminal() bool {
+	return p.layout.TerminalShown && p.canShowTerminal()
+}
+
+// terminalWidth returns the width the terminal pane takes on the page, half
+// of what the sidebar leaves, or zero when it's hidden.
+func (p *chatPage) terminalWidth() int {
+	if !p.showingTerminal() {
+		return 0
+	}
+	return (p.width - p.sidebarWidth()) / 2
+}
+
+// isMouseOverTerminal reports whether the coordinates are on the terminal
+// pane.
+func (p *chatPage) isMouseOverTerminal(x, y int) bool {
+	if !p.showingTerminal() {
+		return false
+	}
+	left := p.width - p.sidebarWidth() - p.terminalWidth()
+	return x >= left && x < left+p.terminalWidth() && y < p.height-EditorHeight
+}
+
+func (p *chatPage) toggleTerminal() tea.Cmd {
+	p.layout.TerminalShown = !p.layout.TerminalShown
+	if !p.showingTerminal() && p.focusedPane == PanelTypeTerminal {
+		p.setFocus(PanelTypeEditor)
+	}
+	return tea.Batch(p.SetSize(p.width, p.height), p.saveLayout())
+}
+
+// syncShellOutput leaves the output of bash tool calls out of the chat
+// while the terminal pane shows it, rendering the messages again when that
+// changes.
+func (p *chatPage) syncShellOutput() tea.Cmd {
+	if p.shellOutputInPane == p.showingTerminal() {
+		return nil
+	}
+	p.shellOutputInPane = p.showingTerminal()
+	messages.SetShellOutputInPane(p.shellOutputInPane)
+	return util.CmdHandler(chat.RefreshMsg{})
+}
+
+func (p *chatPage) saveLayout() tea.Cmd {
+	dataDir, state := p.dataDir, p.layout
+	if dataDir == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		if err := saveLayout(dataDir, state); err != nil {
+			return util.InfoMsg{
+				Type: util.InfoTypeError,
+				Msg:  "Failed to save layout: " + err.Error(),
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,42 @@
+package chat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayoutPersistence(t *testing.T) {
+	t.Parallel()
+
+	dataDir := t.TempDir()
+	state, err := loadLayout(dataDir)
+	require.NoError(t, err)
+	require.Equal(t, layoutState{SidebarWidth: SideBarWidth}, state)
+
+	saved := layoutState{SidebarWidth: 42, SidebarHidden: true, TerminalShown: true}
+	require.NoError(t, saveLayout(dataDir, saved))
+	state, err = loadLayout(dataDir)
+	require.NoError(t, err)
+	require.Equal(t, saved, state)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, layoutFileName), []byte(`{"sidebar_width": 3}`), 0o644))
+	state, err = loadLayout(dataDir)
+	require.NoError(t, err)
+	require.Equal(t, SideBarWidth, state.SidebarWidth)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, layoutFileName), []byte(`{`), 0o644))
+	state, err = loadLayout(dataDir)
+	require.Error(t, err)
+	require.Equal(t, SideBarWidth, state.SidebarWidth)
+}
+
+func TestClampSidebarWidth(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, MinSideBarWidth, clampSidebarWidth(5, 200))
+	require.Equal(t, 40, clampSidebarWidth(40, 200))
+	require.Equal(t, 60, clampSidebarWidth(90, 120))
+}
@@ -0,0 +1,111 @@
+package styles
+
+import (
+	"image/color"
+
+	"github.com/charmbracelet/colorprofile"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const (
+	charmtone256Name = "charmtone-256"
+	ansiName         = "ansi"
+)
+
+// NewCharmtone256Theme returns the charmtone theme with its colors mapped
+// to the 256 color palette, for terminals without true color.
+func NewCharmtone256Theme() *Theme {
+	t := NewCharmtoneTheme()
+	t.Name = charmtone256Name
+	t.mapColors(colorprofile.ANSI256.Convert)
+	t.setIndicatorStyles()
+	return t
+}
+
+// NewANSITheme returns a theme using only the 16 basic colors, which the
+// terminal's own palette decides the look of. Mapping the charmtone colors
+// to them loses too much, different colors end up the same.
+func NewANSITheme() *Theme {
+	t := &Theme{
+		Name:   ansiName,
+		IsDark: true,
+
+		Primary:   lipgloss.Magenta,
+		Secondary: lipgloss.BrightMagenta,
+		Tertiary:  lipgloss.BrightGreen,
+		Accent:    lipgloss.BrightYellow,
+
+		// Backgrounds
+		BgBase:        lipgloss.Black,
+		BgBaseLighter: lipgloss.Black,
+		BgSubtle:      lipgloss.Black,
+		BgOverlay:     lipgloss.Black,
+
+		// Foregrounds
+		FgBase:      lipgloss.White,
+		FgMuted:     lipgloss.BrightBlack,
+		FgHalfMuted: lipgloss.White,
+		FgSubtle:    lipgloss.BrightBlack,
+		FgSelected:  lipgloss.BrightWhite,
+
+		// Borders
+		Border:      lipgloss.BrightBlack,
+		BorderFocus: lipgloss.Magenta,
+
+		// Status
+		Success: lipgloss.Green,
+		Error:   lipgloss.Red,
+		Warning: lipgloss.Yellow,
+		Info:    lipgloss.Blue,
+
+		// Colors
+		White: lipgloss.BrightWhite,
+
+		BlueLight: lipgloss.BrightBlue,
+		Blue:      lipgloss.Blue,
+
+		Yellow: lipgloss.Yellow,
+		Citron: lipgloss.BrightYellow,
+
+		Green:      lipgloss.BrightGreen,
+		GreenDark:  lipgloss.Green,
+		GreenLight: lipgloss.BrightGreen,
+
+		Red:      lipgloss.BrightRed,
+		RedDark:  lipgloss.Red,
+		RedLight: lipgloss.BrightRed,
+		Cherry:   lipgloss.Magenta,
+	}
+	t.setIndicatorStyles()
+	return t
+}
+
+// ThemeForProfile returns the name of the theme that looks right with the
+// colors of the profile.
+func ThemeForProfile(profile colorprofile.Profile) string {
+	switch profile {
+	case colorprofile.TrueColor:
+		return "charmtone"
+	case colorprofile.ANSI256:
+		return charmtone256Name
+	}
+	return ansiName
+}
+
+// mapColors replaces every color of the theme with fn of it.
+func (t *Theme) mapColors(fn func(color.Color) color.Color) {
+	for _, c := range []*color.Color{
+		&t.Primary, &t.Secondary, &t.Tertiary, &t.Accent,
+		&t.BgBase, &t.BgBaseLighter, &t.BgSubtle, &t.BgOverlay,
+		&t.FgBase, &t.FgMuted, &t.FgHalfMuted, &t.FgSubtle, &t.FgSelected,
+		&t.Border, &t.BorderFocus,
+		&t.Success, &t.Error, &t.Warning, &t.Info,
+		&t.White,
+		&t.BlueLight, &t.Blue,
+		&t.Yellow, &t.Citron,
+		&t.Green, &t.GreenDark, &t.GreenLight,
+		&t.Red, &t.RedDark, &t.RedLight, &t.Cherry,
+	} {
+		*c = fn(*c)
+	}
+}
@@ -0,0 +1,43 @@
+package styles
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/charmbracelet/colorprofile"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThemeForProfile(t *testing.T) {
+	t.Parallel()
+
+	m := NewManager()
+	for profile, want := range map[colorprofile.Profile]string{
+		colorprofile.TrueColor: "charmtone",
+		colorprofile.ANSI256:   "charmtone-256",
+		colorprofile.ANSI:      "ansi",
+		colorprofile.Ascii:     "ansi",
+		colorprofile.NoTTY:     "ansi",
+	} {
+		name := ThemeForProfile(profile)
+		require.Equal(t, want, name, profile.String())
+		require.NoError(t, m.SetTheme(name))
+	}
+}
+
+func TestFallbackThemeColors(t *testing.T) {
+	t.Parallel()
+
+	theme := NewCharmtone256Theme()
+	theme.mapColors(func(c color.Color) color.Color {
+		require.Equal(t, c, colorprofile.ANSI256.Convert(c), "color isn't in the 256 color palette")
+		return c
+	})
+	require.Equal(t, theme.Primary, theme.TextSelection.GetBackground())
+
+	theme = NewANSITheme()
+	theme.mapColors(func(c color.Color) color.Color {
+		require.Equal(t, c, colorprofile.ANSI.Convert(c), "color isn't one of the 16 basic colors")
+		return c
+	})
+}
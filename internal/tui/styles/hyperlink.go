@@ -0,0 +1,50 @@
+package styles
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+var hyperlinks atomic.Bool
+
+// SetHyperlinks sets whether file paths and URLs are rendered as OSC 8
+// hyperlinks. Terminals that don't know them ignore the sequences, but a
+// few print them, so they're left out there regardless.
+func SetHyperlinks(enabled bool) {
+	switch os.Getenv("TERM") {
+	case "dumb", "linux":
+		enabled = false
+	}
+	hyperlinks.Store(enabled)
+}
+
+// Hyperlink makes text a link to target in terminals that support it. The
+// text is returned as is when hyperlinks are disabled or there's no target.
+func Hyperlink(target, text string) string {
+	if !hyperlinks.Load() || target == "" || text == "" {
+		return text
+	}
+	return ansi.SetHyperlink(target) + text + ansi.ResetHyperlink()
+}
+
+// FileHyperlink makes text a link to the file at path.
+func FileHyperlink(path, text string) string {
+	return Hyperlink(FileURL(path), text)
+}
+
+// FileURL returns the file:// URL of path, or an empty string if path is
+// empty.
+func FileURL(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
@@ -0,0 +1,30 @@
+package styles
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHyperlink(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+	t.Cleanup(func() { SetHyperlinks(false) })
+
+	SetHyperlinks(false)
+	require.Equal(t, "docs", Hyperlink("https://example.com", "docs"))
+
+	SetHyperlinks(true)
+	require.Equal(t, "\x1b]8;;https://example.com\x07docs\x1b]8;;\x07", Hyperlink("https://example.com", "docs"))
+	require.Equal(t, "docs", Hyperlink("", "docs"))
+
+	t.Setenv("TERM", "dumb")
+	SetHyperlinks(true)
+	require.Equal(t, "docs", Hyperlink("https://example.com", "docs"))
+}
+
+func TestFileURL(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, FileURL(""))
+	require.Equal(t, "file:///tmp/a%20b.go", FileURL("/tmp/a b.go"))
+}
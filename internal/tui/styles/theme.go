@@ -126,6 +126,24 @@ type Styles struct {
 	FilePicker filepicker.Styles
 }
 
+// setIndicatorStyles sets the styles of the text selection and of the
+// status indicators from the colors of the theme.
+func (t *Theme) setIndicatorStyles() {
+	// Text selection.
+	t.TextSelection = lipgloss.NewStyle().Foreground(t.FgSelected).Background(t.Primary)
+
+	// LSP and MCP status.
+	t.ItemOfflineIcon = lipgloss.NewStyle().Foreground(t.FgMuted).SetString("●")
+	t.ItemBusyIcon = t.ItemOfflineIcon.Foreground(t.Citron)
+	t.ItemErrorIcon = t.ItemOfflineIcon.Foreground(t.Red)
+	t.ItemOnlineIcon = t.ItemOfflineIcon.Foreground(t.Success)
+
+	t.YoloIconFocused = lipgloss.NewStyle().Foreground(t.FgSubtle).Background(t.Citron).Bold(true).SetString(" ! ")
+	t.YoloIconBlurred = t.YoloIconFocused.Foreground(t.BgBase).Background(t.FgMuted)
+	t.YoloDotsFocused = lipgloss.NewStyle().Foreground(t.Accent).SetString(":::")
+	t.YoloDotsBlurred = t.YoloDotsFocused.Foreground(t.FgMuted)
+}
+
 func (t *Theme) S() *Styles {
 	if t.styles == nil {
 		t.styles = t.buildStyles()
@@ -522,6 +540,10 @@ func NewManager() *Manager {
 	m.Register(t)
 	m.current = m.themes[t.Name]
 
+	// Fallbacks for terminals with fewer colors.
+	m.Register(NewCharmtone256Theme())
+	m.Register(NewANSITheme())
+
 	return m
 }
 
@@ -0,0 +1,90 @@
+// Package termcap detects what the terminal can display, so the TUI falls
+// back to fewer colors and to the keyboard on basic terminals instead of
+// drawing escape sequences they show as garbage.
+package termcap
+
+import (
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/colorprofile"
+)
+
+// Capabilities are the features of a terminal the TUI depends on.
+type Capabilities struct {
+	// Term is the value of $TERM.
+	Term string
+	// Program is the value of $TERM_PROGRAM, set by some terminals.
+	Program string
+	// Profile is how many colors the terminal shows.
+	Profile colorprofile.Profile
+	// Mouse reports whether the terminal reports mouse events.
+	Mouse bool
+	// Hyperlinks reports whether the terminal can be sent OSC 8 hyperlinks
+	// without printing them.
+	Hyperlinks bool
+}
+
+// noMouseTerms are terminals that don't understand mouse reporting and
+// print the sequences enabling it instead.
+var noMouseTerms = []string{
+	"dumb",
+	"linux",
+	"cons25",
+	"ansi",
+	"vt52",
+	"vt100",
+	"vt102",
+	"vt220",
+	"vt320",
+	"eterm",
+}
+
+// Detect detects the capabilities of the terminal writing to output, with
+// env the environment it runs in.
+func Detect(output io.Writer, env []string) Capabilities {
+	caps := Capabilities{
+		Term:    getenv(env, "TERM"),
+		Program: getenv(env, "TERM_PROGRAM"),
+		Profile: colorprofile.Detect(output, env),
+	}
+	caps.Mouse = caps.Profile != colorprofile.NoTTY && !basicTerm(caps.Term, noMouseTerms)
+	caps.Hyperlinks = caps.Profile != colorprofile.NoTTY && !basicTerm(caps.Term, []string{"dumb", "linux"})
+	return caps
+}
+
+// Colors returns how many colors the terminal shows, zero without colors.
+func (c Capabilities) Colors() int {
+	switch c.Profile {
+	case colorprofile.TrueColor:
+		return 1 << 24
+	case colorprofile.ANSI256:
+		return 256
+	case colorprofile.ANSI:
+		return 16
+	}
+	return 0
+}
+
+// basicTerm reports whether term is one of terms, ignoring variants like
+// "vt100-am" or "eterm-color".
+func basicTerm(term string, terms []string) bool {
+	term = strings.ToLower(term)
+	for _, t := range terms {
+		if term == t || strings.HasPrefix(term, t+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// getenv returns the last value of key in env, like the process would see.
+func getenv(env []string, key string) string {
+	var value string
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			value = v
+		}
+	}
+	return value
+}
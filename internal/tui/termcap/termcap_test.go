@@ -0,0 +1,73 @@
+package termcap
+
+import (
+	"io"
+	"testing"
+
+	"github.com/charmbracelet/colorprofile"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		env        []string
+		profile    colorprofile.Profile
+		mouse      bool
+		hyperlinks bool
+	}{
+		{
+			name:       "truecolor terminal",
+			env:        []string{"TTY_FORCE=1", "TERM=xterm-256color", "COLORTERM=truecolor", "TERM_PROGRAM=ghostty"},
+			profile:    colorprofile.TrueColor,
+			mouse:      true,
+			hyperlinks: true,
+		},
+		{
+			name:    "linux console",
+			env:     []string{"TTY_FORCE=1", "TERM=linux"},
+			profile: colorprofile.ANSI,
+		},
+		{
+			name:    "vt100 variant",
+			env:     []string{"TTY_FORCE=1", "TERM=vt100-am", "COLORTERM=truecolor"},
+			profile: colorprofile.TrueColor,
+			// Hyperlinks are only left out where terminals are known to
+			// print them.
+			hyperlinks: true,
+		},
+		{
+			name:    "not a terminal",
+			env:     []string{"TERM=xterm-256color"},
+			profile: colorprofile.NoTTY,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			caps := Detect(io.Discard, tt.env)
+			require.Equal(t, tt.profile, caps.Profile)
+			require.Equal(t, tt.mouse, caps.Mouse)
+			require.Equal(t, tt.hyperlinks, caps.Hyperlinks)
+		})
+	}
+}
+
+func TestDetectReadsEnv(t *testing.T) {
+	t.Parallel()
+
+	caps := Detect(io.Discard, []string{"TERM=xterm", "TERM=vt220", "TERM_PROGRAM=Apple_Terminal"})
+	require.Equal(t, "vt220", caps.Term)
+	require.Equal(t, "Apple_Terminal", caps.Program)
+}
+
+func TestColors(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 1<<24, Capabilities{Profile: colorprofile.TrueColor}.Colors())
+	require.Equal(t, 256, Capabilities{Profile: colorprofile.ANSI256}.Colors())
+	require.Equal(t, 16, Capabilities{Profile: colorprofile.ANSI}.Colors())
+	require.Zero(t, Capabilities{Profile: colorprofile.Ascii}.Colors())
+}
@@ -0,0 +1,88 @@
+// Package throttle paces the renders of the TUI while messages stream, so
+// rendering doesn't take up all the time of low-powered machines and SSH
+// sessions.
+package throttle
+
+import (
+	"time"
+)
+
+const (
+	DefaultFPS = 60
+	MaxFPS     = 120
+
+	// renderShare is the most of the time spent rendering while messages
+	// stream.
+	renderShare = 0.5
+	// maxInterval is the longest a visible update waits to be rendered.
+	maxInterval = 500 * time.Millisecond
+	// offscreenInterval is how long updates outside of the viewport wait
+	// to be rendered.
+	offscreenInterval = time.Second
+	// smoothing is the weight of the latest render in the average cost.
+	smoothing = 0.2
+)
+
+// Throttle measures how long frames take to render and tells how long
+// streaming updates should wait before the next render. The cost is kept
+// per cell, so it follows the size of the terminal.
+type Throttle struct {
+	frame    time.Duration
+	adaptive bool
+
+	cells       int
+	costPerCell float64
+	lastRender  time.Time
+}
+
+// New returns a throttle rendering at most fps frames per second. When
+// adaptive, frames that are expensive to render are spaced further.
+func New(fps int, adaptive bool) *Throttle {
+	if fps <= 0 {
+		fps = DefaultFPS
+	}
+	fps = min(fps, MaxFPS)
+	return &Throttle{
+		frame:    time.Second / time.Duration(fps),
+		adaptive: adaptive,
+	}
+}
+
+// SetSize sets the size of the terminal.
+func (t *Throttle) SetSize(width, height int) {
+	t.cells = max(1, width*height)
+}
+
+// Observe records a frame that started rendering at start and took d.
+func (t *Throttle) Observe(start time.Time, d time.Duration) {
+	t.lastRender = start.Add(d)
+	cost := float64(d) / float64(max(1, t.cells))
+	if t.costPerCell == 0 {
+		t.costPerCell = cost
+		return
+	}
+	t.costPerCell = smoothing*cost + (1-smoothing)*t.costPerCell
+}
+
+// Interval returns the time to leave between renders of streaming
+// updates, longer for updates outside of the viewport.
+func (t *Throttle) Interval(offscreen bool) time.Duration {
+	if !t.adaptive {
+		return t.frame
+	}
+	cost := time.Duration(t.costPerCell * float64(max(1, t.cells)))
+	interval := max(t.frame, min(time.Duration(float64(cost)/renderShare), maxInterval))
+	if offscreen {
+		interval = max(interval, offscreenInterval)
+	}
+	return interval
+}
+
+// Wait returns how long an update arriving at now should wait to be
+// rendered, zero when it can be rendered right away.
+func (t *Throttle) Wait(now time.Time, offscreen bool) time.Duration {
+	if t.lastRender.IsZero() {
+		return 0
+	}
+	return max(0, t.lastRender.Add(t.Interval(offscreen)).Sub(now))
+}
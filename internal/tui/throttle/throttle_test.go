@@ -0,0 +1,63 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterval(t *testing.T) {
+	t.Parallel()
+
+	th := New(50, true)
+	th.SetSize(100, 10)
+	require.Equal(t, 20*time.Millisecond, th.Interval(false))
+
+	// Cheap frames render at the frame rate.
+	start := time.Now()
+	th.Observe(start, time.Millisecond)
+	require.Equal(t, 20*time.Millisecond, th.Interval(false))
+	require.Equal(t, offscreenInterval, th.Interval(true))
+
+	// Expensive frames leave time between renders.
+	th = New(50, true)
+	th.SetSize(100, 10)
+	th.Observe(start, 40*time.Millisecond)
+	require.Equal(t, 80*time.Millisecond, th.Interval(false))
+
+	// The cost follows the size of the terminal.
+	th.SetSize(200, 10)
+	require.Equal(t, 160*time.Millisecond, th.Interval(false))
+
+	th.SetSize(1000, 100)
+	require.Equal(t, maxInterval, th.Interval(false))
+}
+
+func TestNotAdaptive(t *testing.T) {
+	t.Parallel()
+
+	th := New(0, false)
+	th.SetSize(100, 10)
+	th.Observe(time.Now(), time.Second)
+	require.Equal(t, time.Second/DefaultFPS, th.Interval(false))
+	require.Equal(t, time.Second/DefaultFPS, th.Interval(true))
+	require.Equal(t, time.Second/MaxFPS, New(1000, false).Interval(false))
+}
+
+func TestWait(t *testing.T) {
+	t.Parallel()
+
+	th := New(50, true)
+	th.SetSize(100, 10)
+	now := time.Now()
+	require.Zero(t, th.Wait(now, false))
+
+	th.Observe(now, 10*time.Millisecond)
+	require.Equal(t, 20*time.Millisecond, th.Wait(now.Add(10*time.Millisecond), false))
+	require.Zero(t, th.Wait(now.Add(time.Second), false))
+
+	// The average moves toward the latest frames.
+	th.Observe(now, 110*time.Millisecond)
+	require.Greater(t, th.Interval(false), 20*time.Millisecond)
+}
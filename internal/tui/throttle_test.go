@@ -0,0 +1,102 @@
+package tui
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/tui/components/core/status"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/page"
+	"github.com/charmbracelet/crush/internal/tui/throttle"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPage records the message events it receives.
+type recordingPage struct {
+	events    []pubsub.Event[message.Message]
+	offscreen bool
+}
+
+func (p *recordingPage) Init() tea.Cmd { return nil }
+
+func (p *recordingPage) Update(msg tea.Msg) (util.Model, tea.Cmd) {
+	if event, ok := msg.(pubsub.Event[message.Message]); ok {
+		p.events = append(p.events, event)
+	}
+	return p, nil
+}
+
+func (p *recordingPage) View() string { return "" }
+
+func (p *recordingPage) IsMessageOffscreen(string) bool { return p.offscreen }
+
+func TestDeferUpdate(t *testing.T) {
+	t.Parallel()
+
+	chatPage := &recordingPage{}
+	a := &appModel{
+		currentPage: "chat",
+		pages:       map[page.PageID]util.Model{"chat": chatPage},
+		status:      status.NewStatusCmp(nil, t.TempDir()),
+		dialog:      dialogs.NewDialogCmp(),
+		throttle:    throttle.New(1, true),
+		pending:     make(map[string]pubsub.Event[message.Message]),
+	}
+	a.throttle.SetSize(80, 24)
+	update := func(id, text string) pubsub.Event[message.Message] {
+		return pubsub.Event[message.Message]{
+			Type: pubsub.UpdatedEvent,
+			Payload: message.Message{
+				ID:    id,
+				Parts: []message.ContentPart{message.TextContent{Text: text}},
+			},
+		}
+	}
+
+	// Nothing was rendered yet, the update goes through.
+	_, cmd := a.Update(update("1", "a"))
+	require.Len(t, chatPage.events, 1)
+	require.False(t, a.reuseView)
+	a.View()
+
+	// Updates right after a render wait, only the latest of each message
+	// is kept.
+	_, cmd = a.Update(update("1", "ab"))
+	require.NotNil(t, cmd)
+	require.True(t, a.reuseView)
+	_, cmd = a.Update(update("2", "x"))
+	require.Nil(t, cmd)
+	_, _ = a.Update(update("1", "abc"))
+	require.Len(t, chatPage.events, 1)
+	require.Equal(t, []string{"1", "2"}, a.pendingOrder)
+
+	// Other events of the message replace the pending update.
+	deleted := pubsub.Event[message.Message]{Type: pubsub.DeletedEvent, Payload: message.Message{ID: "2"}}
+	_, _ = a.Update(deleted)
+	require.Len(t, chatPage.events, 2)
+	require.Equal(t, []string{"1"}, a.pendingOrder)
+
+	_, _ = a.Update(flushUpdatesMsg{})
+	require.Len(t, chatPage.events, 3)
+	require.Equal(t, "abc", chatPage.events[2].Payload.Content().Text)
+	require.Empty(t, a.pending)
+	require.False(t, a.flushScheduled)
+}
+
+func TestViewMeasuresRenders(t *testing.T) {
+	t.Parallel()
+
+	a := &appModel{throttle: throttle.New(1, true)}
+	a.throttle.SetSize(10, 10)
+	a.View()
+	require.Equal(t, time.Second, a.throttle.Interval(false))
+	require.NotZero(t, a.throttle.Wait(time.Now(), false))
+
+	a.reuseView = true
+	a.lastView.AltScreen = true
+	require.True(t, a.View().AltScreen)
+}
@@ -3,7 +3,11 @@ package tui
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -11,9 +15,16 @@ import (
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/app"
 	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/control"
 	"github.com/charmbracelet/crush/internal/event"
+	"github.com/charmbracelet/crush/internal/health"
+	"github.com/charmbracelet/crush/internal/home"
+	"github.com/charmbracelet/crush/internal/i18n"
+	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/spending"
+	"github.com/charmbracelet/crush/internal/toolstats"
 	cmpChat "github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/splash"
 	"github.com/charmbracelet/crush/internal/tui/components/completions"
@@ -22,20 +33,30 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/core/status"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/drafts"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/inspector"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/logprobs"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/models"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/permissions"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/quit"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/sessions"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/stopphrase"
 	"github.com/charmbracelet/crush/internal/tui/page"
 	"github.com/charmbracelet/crush/internal/tui/page/chat"
 	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/termcap"
+	"github.com/charmbracelet/crush/internal/tui/throttle"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/lipgloss/v2"
 )
 
 var lastMouseEvent time.Time
 
+// providerIncidentTTL keeps provider incidents on screen longer than other
+// warnings, they explain the failures that follow.
+const providerIncidentTTL = 30 * time.Second
+
 func MouseEventFilter(m tea.Model, msg tea.Msg) tea.Msg {
 	switch msg.(type) {
 	case tea.MouseWheelMsg, tea.MouseMotionMsg:
@@ -55,6 +76,10 @@ type appModel struct {
 	width, height   int
 	keyMap          KeyMap
 
+	// mouse is whether mouse events are enabled, off on terminals that
+	// don't report them.
+	mouse bool
+
 	currentPage  page.PageID
 	previousPage page.PageID
 	pages        map[page.PageID]util.Model
@@ -72,6 +97,35 @@ type appModel struct {
 
 	// Chat Page Specific
 	selectedSessionID string // The ID of the currently selected session
+
+	// Rendering of streaming updates
+	throttle *throttle.Throttle
+	// pending are the message updates waiting to be rendered, by message.
+	pending        map[string]pubsub.Event[message.Message]
+	pendingOrder   []string
+	flushScheduled bool
+	// reuseView is set when an update only deferred a message update, so
+	// the last view is still current.
+	reuseView bool
+	lastView  tea.View
+
+	// spendingWarned is the start of the last period warned about going
+	// over its spending limit, by period.
+	spendingWarned map[spending.Period]time.Time
+}
+
+// spendingAlertsMsg carries the spending limits reached.
+type spendingAlertsMsg struct {
+	alerts []spending.Alert
+}
+
+// flushUpdatesMsg delivers the message updates that waited to be rendered.
+type flushUpdatesMsg struct{}
+
+// offscreenChecker is implemented by pages that show messages, to tell
+// whether a message is in view.
+type offscreenChecker interface {
+	IsMessageOffscreen(id string) bool
 }
 
 // Init initializes the application model and returns initial commands.
@@ -89,16 +143,28 @@ func (a appModel) Init() tea.Cmd {
 	cmd = a.status.Init()
 	cmds = append(cmds, cmd)
 
+	if a.app.Config().Options.ReadOnly {
+		cmds = append(cmds, util.ReportInfo(i18n.T("status.read_only")))
+	} else if len(a.app.ConcurrentWriters) > 0 {
+		cmds = append(cmds, util.ReportWarn(i18n.Tf("status.concurrent_instance", a.app.ConcurrentWriters[0].PID)))
+	}
+
 	return tea.Batch(cmds...)
 }
 
 // Update handles incoming messages and updates the application state.
 func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
-	var cmd tea.Cmd
 	a.isConfigured = config.HasInitialDataConfig()
+	a.reuseView = false
 
 	switch msg := msg.(type) {
+	case pubsub.Event[message.Message]:
+		if deferCmd, deferred := a.deferUpdate(msg); deferred {
+			return a, deferCmd
+		}
+	case flushUpdatesMsg:
+		return a, a.flushUpdates()
 	case tea.KeyboardEnhancementsMsg:
 		for id, page := range a.pages {
 			m, pageCmd := page.Update(msg)
@@ -138,6 +204,7 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					msg.CommandID,
 					msg.Content,
 					msg.ArgNames,
+					msg.OnSubmit,
 				),
 			},
 		)
@@ -155,16 +222,17 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Session
 	case cmpChat.SessionSelectedMsg:
 		a.selectedSessionID = msg.ID
+		a.app.SetActiveSession(msg.ID)
 	case cmpChat.SessionClearedMsg:
 		a.selectedSessionID = ""
+		a.app.SetActiveSession("")
+	case control.SendMsg:
+		return a, util.CmdHandler(cmpChat.SendMsg{Text: msg.Text})
 	// Commands
 	case commands.SwitchSessionsMsg:
-		return a, func() tea.Msg {
-			allSessions, _ := a.app.Sessions.List(context.Background())
-			return dialogs.OpenDialogMsg{
-				Model: sessions.NewSessionDialogCmp(allSessions, a.selectedSessionID),
-			}
-		}
+		return a, a.openSessionsDialog
+	case commands.OpenDraftsMsg:
+		return a, a.openDraftsDialog
 
 	case commands.SwitchModelMsg:
 		return a, util.CmdHandler(
@@ -181,12 +249,66 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return nil
 		}
+	case commands.ExportSessionMsg:
+		return a, func() tea.Msg {
+			path, err := exportSession(context.Background(), a.app.Sessions, a.app.Messages, msg.SessionID, a.app.Config().WorkingDir())
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: i18n.Tf("status.session_exported", styles.FileHyperlink(path, path))}
+		}
+	case commands.OpenLogprobsMsg:
+		return a, func() tea.Msg {
+			msgs, err := a.app.Messages.List(context.Background(), msg.SessionID)
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			if len(logprobs.WithLogprobs(msgs)) == 0 {
+				return util.InfoMsg{Type: util.InfoTypeWarn, Msg: i18n.T("status.no_logprobs")}
+			}
+			return dialogs.OpenDialogMsg{Model: logprobs.NewLogprobsDialog(msgs)}
+		}
 	case commands.QuitMsg:
 		return a, util.CmdHandler(dialogs.OpenDialogMsg{
 			Model: quit.NewQuitDialog(),
 		})
 	case commands.ToggleYoloModeMsg:
 		a.app.Permissions.SetSkipRequests(!a.app.Permissions.SkipRequests())
+	case commands.ToggleDryRunMsg:
+		dryRun := !a.app.AgentCoordinator.IsDryRun(msg.SessionID)
+		a.app.AgentCoordinator.SetDryRun(msg.SessionID, dryRun)
+		if dryRun {
+			return a, util.ReportInfo(i18n.T("status.dry_run_enabled"))
+		}
+		return a, util.ReportInfo(i18n.T("status.dry_run_disabled"))
+	case commands.TogglePlanModeMsg:
+		planMode := !a.app.AgentCoordinator.IsPlanMode(msg.SessionID)
+		a.app.AgentCoordinator.SetPlanMode(msg.SessionID, planMode)
+		if planMode {
+			return a, util.ReportInfo(i18n.T("status.plan_mode_enabled"))
+		}
+		return a, util.ReportInfo(i18n.T("status.plan_mode_disabled"))
+	case commands.SetSessionEnvironmentMsg:
+		workingDir := msg.WorkingDir
+		if workingDir != "" {
+			workingDir = home.Long(workingDir)
+			if !filepath.IsAbs(workingDir) {
+				workingDir = filepath.Join(a.app.Config().WorkingDir(), workingDir)
+			}
+			if info, err := os.Stat(workingDir); err != nil || !info.IsDir() {
+				return a, util.ReportError(fmt.Errorf("%s is not a directory", workingDir))
+			}
+		}
+		if _, err := a.app.Sessions.SetEnvironment(context.Background(), msg.SessionID, workingDir, msg.Env); err != nil {
+			return a, util.ReportError(err)
+		}
+		return a, util.ReportInfo(i18n.T("status.session_env_updated"))
+	// Stop phrase confirmation
+	case stopphrase.ContinueMsg:
+		a.app.AgentCoordinator.ConfirmStopPhrase(msg.SessionID)
+		return a, util.CmdHandler(cmpChat.SendMsg{
+			Text: fmt.Sprintf("I confirm, go on past %q.", msg.Phrase),
+		})
 	case commands.ToggleHelpMsg:
 		a.status.ToggleFullHelp()
 		a.showingFullHelp = !a.showingFullHelp
@@ -194,7 +316,7 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Model Switch
 	case models.ModelSelectedMsg:
 		if a.app.AgentCoordinator.IsBusy() {
-			return a, util.ReportWarn("Agent is busy, please wait...")
+			return a, util.ReportWarn(i18n.T("status.agent_busy"))
 		}
 
 		config.Get().UpdatePreferredModel(msg.ModelType, msg.Model)
@@ -205,7 +327,7 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.ModelType == config.SelectedModelTypeSmall {
 			modelTypeName = "small"
 		}
-		return a, util.ReportInfo(fmt.Sprintf("%s model changed to %s", modelTypeName, msg.Model.Model))
+		return a, util.ReportInfo(i18n.Tf("status.model_changed", modelTypeName, msg.Model.Model))
 
 	// File Picker
 	case commands.OpenFilePickerMsg:
@@ -236,6 +358,47 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				DiffMode: config.Get().Options.TUI.DiffMode,
 			}),
 		})
+	// Tool stats
+	case pubsub.Event[toolstats.Execution]:
+		threshold := a.app.Config().Tools.SlowWarningThreshold()
+		if threshold == 0 || msg.Payload.Duration < threshold {
+			return a, nil
+		}
+		return a, util.ReportWarn(i18n.Tf("status.slow_tool", msg.Payload.ToolName, msg.Payload.Duration.Round(time.Second)))
+	// Spending
+	case pubsub.Event[spending.Record]:
+		limits := a.app.Config().Options.SpendingLimits
+		if limits == (config.SpendingLimits{}) {
+			return a, nil
+		}
+		return a, a.checkSpending(limits)
+	case spendingAlertsMsg:
+		var cmds []tea.Cmd
+		for _, alert := range msg.alerts {
+			if a.spendingWarned[alert.Period].Equal(alert.Start) {
+				continue
+			}
+			a.spendingWarned[alert.Period] = alert.Start
+			cmds = append(cmds, util.CmdHandler(util.InfoMsg{
+				Type: util.InfoTypeWarn,
+				Msg:  i18n.Tf("status.spending_limit_"+string(alert.Period), alert.Spent, alert.Limit),
+				TTL:  providerIncidentTTL,
+			}))
+		}
+		return a, tea.Batch(cmds...)
+	// Provider health
+	case pubsub.Event[health.Status]:
+		s, _ := a.status.Update(msg)
+		a.status = s.(status.StatusCmp)
+		name := msg.Payload.Provider
+		if providerCfg, ok := a.app.Config().Providers.Get(name); ok && providerCfg.Name != "" {
+			name = providerCfg.Name
+		}
+		return a, util.CmdHandler(util.InfoMsg{
+			Type: util.InfoTypeWarn,
+			Msg:  i18n.Tf("status.provider_incident", name, msg.Payload.Description, msg.Payload.URL),
+			TTL:  providerIncidentTTL,
+		})
 	case permissions.PermissionResponseMsg:
 		switch msg.Action {
 		case permissions.PermissionAllow:
@@ -300,12 +463,21 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return a, tea.Batch(cmds...)
 	}
-	s, _ := a.status.Update(msg)
+	cmds = append(cmds, a.updateComponents(msg))
+	return a, tea.Batch(cmds...)
+}
+
+// updateComponents sends the message to the status bar, the current page
+// and the dialogs.
+func (a *appModel) updateComponents(msg tea.Msg) tea.Cmd {
+	var cmds []tea.Cmd
+	s, statusCmd := a.status.Update(msg)
 	a.status = s.(status.StatusCmp)
+	cmds = append(cmds, statusCmd)
 
 	item, ok := a.pages[a.currentPage]
 	if !ok {
-		return a, nil
+		return tea.Batch(cmds...)
 	}
 
 	updated, cmd := item.Update(msg)
@@ -320,12 +492,69 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, dialogCmd)
 	}
 	cmds = append(cmds, cmd)
-	return a, tea.Batch(cmds...)
+	return tea.Batch(cmds...)
+}
+
+// deferUpdate holds back the update of a streaming message when it comes
+// sooner than the throttle allows, keeping only the latest update of each
+// message. Updates of messages out of view wait longer.
+func (a *appModel) deferUpdate(event pubsub.Event[message.Message]) (tea.Cmd, bool) {
+	id := event.Payload.ID
+	if event.Type != pubsub.UpdatedEvent {
+		// A newer event of the message replaces the pending update.
+		a.dropPending(id)
+		return nil, false
+	}
+	offscreen := false
+	if checker, ok := a.pages[a.currentPage].(offscreenChecker); ok {
+		offscreen = checker.IsMessageOffscreen(id)
+	}
+	wait := a.throttle.Wait(time.Now(), offscreen)
+	if wait == 0 {
+		a.dropPending(id)
+		return nil, false
+	}
+	if _, ok := a.pending[id]; !ok {
+		a.pendingOrder = append(a.pendingOrder, id)
+	}
+	a.pending[id] = event
+	a.reuseView = true
+	if a.flushScheduled {
+		return nil, true
+	}
+	a.flushScheduled = true
+	return tea.Tick(wait, func(time.Time) tea.Msg {
+		return flushUpdatesMsg{}
+	}), true
+}
+
+func (a *appModel) dropPending(id string) {
+	if _, ok := a.pending[id]; !ok {
+		return
+	}
+	delete(a.pending, id)
+	a.pendingOrder = slices.DeleteFunc(a.pendingOrder, func(pending string) bool {
+		return pending == id
+	})
+}
+
+// flushUpdates delivers the pending message updates, in the order they
+// first came.
+func (a *appModel) flushUpdates() tea.Cmd {
+	a.flushScheduled = false
+	var cmds []tea.Cmd
+	for _, id := range a.pendingOrder {
+		cmds = append(cmds, a.updateComponents(a.pending[id]))
+	}
+	clear(a.pending)
+	a.pendingOrder = a.pendingOrder[:0]
+	return tea.Batch(cmds...)
 }
 
 // handleWindowResize processes window resize events and updates all components.
 func (a *appModel) handleWindowResize(width, height int) tea.Cmd {
 	var cmds []tea.Cmd
+	a.throttle.SetSize(width, height)
 
 	// TODO: clean up these magic numbers.
 	if a.showingFullHelp {
@@ -427,18 +656,26 @@ func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 			// If the commands dialog is open, close it first
 			cmds = append(cmds, util.CmdHandler(dialogs.CloseDialogMsg{}))
 		}
-		cmds = append(cmds,
-			func() tea.Msg {
-				allSessions, _ := a.app.Sessions.List(context.Background())
-				return dialogs.OpenDialogMsg{
-					Model: sessions.NewSessionDialogCmp(allSessions, a.selectedSessionID),
-				}
-			},
-		)
+		cmds = append(cmds, a.openSessionsDialog)
 		return tea.Sequence(cmds...)
+	case key.Matches(msg, a.keyMap.Drafts):
+		if a.dialog.ActiveDialogID() == drafts.DraftsDialogID {
+			return util.CmdHandler(dialogs.CloseDialogMsg{})
+		}
+		if a.dialog.HasDialogs() {
+			return nil
+		}
+		return a.openDraftsDialog
+	case key.Matches(msg, a.keyMap.Inspector):
+		if !a.app.Config().Options.Debug {
+			return nil
+		}
+		return util.CmdHandler(dialogs.OpenDialogMsg{
+			Model: inspector.NewInspectorDialog(),
+		})
 	case key.Matches(msg, a.keyMap.Suspend):
 		if a.app.AgentCoordinator != nil && a.app.AgentCoordinator.IsBusy() {
-			return util.ReportWarn("Agent is busy, please wait...")
+			return util.ReportWarn(i18n.T("status.agent_busy"))
 		}
 		return tea.Suspend
 	default:
@@ -453,11 +690,49 @@ func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 	}
 }
 
+// openSessionsDialog opens the session switcher with a preview of the
+// latest messages of each session.
+// checkSpending reports the spending limits reached in the current periods.
+func (a *appModel) checkSpending(limits config.SpendingLimits) tea.Cmd {
+	return func() tea.Msg {
+		now := time.Now()
+		records, err := a.app.Spending.Since(context.Background(), spending.Since(now))
+		if err != nil {
+			slog.Error("failed to check spending", "error", err)
+			return nil
+		}
+		return spendingAlertsMsg{alerts: spending.NewReport(records, now).Alerts(limits, now)}
+	}
+}
+
+func (a *appModel) openSessionsDialog() tea.Msg {
+	ctx := context.Background()
+	allSessions, _ := a.app.Sessions.List(ctx)
+	previews, err := a.app.Messages.ListLatest(ctx, sessions.PreviewMessages)
+	if err != nil {
+		slog.Warn("Failed to load session previews", "error", err)
+	}
+	return dialogs.OpenDialogMsg{
+		Model: sessions.NewSessionDialogCmp(allSessions, previews, a.selectedSessionID),
+	}
+}
+
+// openDraftsDialog opens the list of the prompts that weren't sent.
+func (a *appModel) openDraftsDialog() tea.Msg {
+	list, err := a.app.Drafts.List()
+	if err != nil {
+		return util.ReportError(err)()
+	}
+	return dialogs.OpenDialogMsg{
+		Model: drafts.NewDraftsDialogCmp(list),
+	}
+}
+
 // moveToPage handles navigation between different pages in the application.
 func (a *appModel) moveToPage(pageID page.PageID) tea.Cmd {
 	if a.app.AgentCoordinator.IsBusy() {
 		// TODO: maybe remove this :  For now we don't move to any page if the agent is busy
-		return util.ReportWarn("Agent is busy, please wait...")
+		return util.ReportWarn(i18n.T("status.agent_busy"))
 	}
 
 	var cmds []tea.Cmd
@@ -478,6 +753,18 @@ func (a *appModel) moveToPage(pageID page.PageID) tea.Cmd {
 
 // View renders the complete application interface including pages, dialogs, and overlays.
 func (a *appModel) View() tea.View {
+	if a.reuseView {
+		return a.lastView
+	}
+	start := time.Now()
+	view := a.render()
+	a.throttle.Observe(start, time.Since(start))
+	a.lastView = view
+	return view
+}
+
+// render renders the whole TUI.
+func (a *appModel) render() tea.View {
 	var view tea.View
 	t := styles.CurrentTheme()
 	view.BackgroundColor = t.BgBase
@@ -552,7 +839,9 @@ func (a *appModel) View() tea.View {
 
 	view.Layer = canvas
 	view.Cursor = cursor
-	view.MouseMode = tea.MouseModeCellMotion
+	if a.mouse {
+		view.MouseMode = tea.MouseModeCellMotion
+	}
 	view.AltScreen = true
 
 	if a.app != nil && a.app.AgentCoordinator != nil && a.app.AgentCoordinator.IsBusy() {
@@ -563,18 +852,26 @@ func (a *appModel) View() tea.View {
 	return view
 }
 
-// New creates and initializes a new TUI application model.
-func New(app *app.App) tea.Model {
+// New creates and initializes a new TUI application model, falling back to
+// the colors and input the terminal described by caps supports.
+func New(app *app.App, caps termcap.Capabilities) tea.Model {
+	i18n.Init(app.Config().Options.TUI.Language)
+	if err := styles.DefaultManager().SetTheme(styles.ThemeForProfile(caps.Profile)); err != nil {
+		slog.Warn("Failed to set theme", "error", err)
+	}
+
 	chatPage := chat.New(app)
 	keyMap := DefaultKeyMap()
+	rendering := app.Config().Options.TUI.Rendering
 	keyMap.pageBindings = chatPage.Bindings()
 
 	model := &appModel{
 		currentPage: chat.ChatPageID,
 		app:         app,
-		status:      status.NewStatusCmp(),
+		status:      status.NewStatusCmp(app.Config().Options.TUI.StatusBar.Segments, app.Config().WorkingDir()),
 		loadedPages: make(map[page.PageID]bool),
 		keyMap:      keyMap,
+		mouse:       app.Config().Options.TUI.MouseEnabled(caps.Mouse),
 
 		pages: map[page.PageID]util.Model{
 			chat.ChatPageID: chatPage,
@@ -582,6 +879,11 @@ func New(app *app.App) tea.Model {
 
 		dialog:      dialogs.NewDialogCmp(),
 		completions: completions.New(),
+
+		throttle: throttle.New(rendering.MaxFPS, rendering.IsAdaptive()),
+		pending:  make(map[string]pubsub.Event[message.Message]),
+
+		spendingWarned: make(map[spending.Period]time.Time),
 	}
 
 	return model
@@ -0,0 +1,143 @@
+// Package width sets how wide East Asian ambiguous characters, like box
+// drawing and some punctuation, are measured. Terminals configured for CJK
+// often draw them two cells wide, and everything that lays out or selects
+// text needs to agree with the terminal or lines get misaligned and
+// truncated.
+package width
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+)
+
+type Mode string
+
+const (
+	// ModeAuto asks the terminal how it draws ambiguous characters, falling
+	// back to the locale.
+	ModeAuto   Mode = "auto"
+	ModeNarrow Mode = "narrow"
+	ModeWide   Mode = "wide"
+)
+
+// queryTimeout is how long to wait for the terminal to answer. Terminals
+// that don't answer cursor position reports still answer device attributes,
+// so it's only hit on terminals that answer nothing at all.
+const queryTimeout = 200 * time.Millisecond
+
+// probe is an ambiguous-width character drawn to measure the terminal.
+const probe = "─"
+
+// Apply resolves the mode and sets the width of ambiguous characters for
+// the whole process. It must be called before the TUI takes over the
+// terminal, since auto writes to it. It returns whether they are wide.
+func Apply(mode Mode) bool {
+	var wide bool
+	switch mode {
+	case ModeNarrow:
+	case ModeWide:
+		wide = true
+	default:
+		var ok bool
+		if wide, ok = Query(queryTimeout); !ok {
+			// Follows RUNEWIDTH_EASTASIAN and the locale.
+			wide = runewidth.EastAsianWidth
+		}
+	}
+	Set(wide)
+	return wide
+}
+
+// Set sets the width of ambiguous characters for both grapheme based
+// measures, used by lipgloss and the list renderer, and rune based measures,
+// used by the screen buffers, so they agree with each other.
+func Set(wide bool) {
+	uniseg.EastAsianAmbiguousWidth = 1
+	if wide {
+		uniseg.EastAsianAmbiguousWidth = 2
+	}
+	// A new condition drops the lookup table built for the previous width.
+	cond := runewidth.NewCondition()
+	cond.EastAsianWidth = wide
+	runewidth.DefaultCondition = cond
+}
+
+// Query draws an ambiguous character at the start of the line and asks the
+// terminal where the cursor ended up. ok is false when there's no terminal
+// or it didn't report the position.
+func Query(timeout time.Duration) (wide, ok bool) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false, false
+	}
+	defer tty.Close()
+
+	state, err := term.MakeRaw(tty.Fd())
+	if err != nil {
+		return false, false
+	}
+	defer term.Restore(tty.Fd(), state) //nolint:errcheck
+
+	// Device attributes are answered by nearly every terminal, and always
+	// after the cursor position, so they mark the end of the reply.
+	query := "\r" + probe + "\x1b[6n\x1b[c"
+	defer tty.WriteString("\r\x1b[2K") //nolint:errcheck
+	if _, err := tty.WriteString(query); err != nil {
+		return false, false
+	}
+	if err := tty.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return false, false
+	}
+
+	var reply []byte
+	buf := make([]byte, 64)
+	for {
+		n, err := tty.Read(buf)
+		reply = append(reply, buf[:n]...)
+		if col, done := parseReply(reply); done {
+			return col > 2, col > 0
+		}
+		if err != nil {
+			return false, false
+		}
+	}
+}
+
+// parseReply finds the column of the cursor position report in reply. done
+// is true once the device attributes reply arrived, as nothing else comes
+// after it.
+func parseReply(reply []byte) (col int, done bool) {
+	for rest := reply; ; {
+		i := bytes.Index(rest, []byte("\x1b["))
+		if i < 0 {
+			return col, false
+		}
+		rest = rest[i+2:]
+		end := bytes.IndexFunc(rest, func(r rune) bool {
+			return r >= 0x40 && r <= 0x7e
+		})
+		if end < 0 {
+			return col, false
+		}
+		params, final := rest[:end], rest[end]
+		rest = rest[end+1:]
+		switch {
+		case final == 'c' && bytes.HasPrefix(params, []byte("?")):
+			return col, true
+		case final == 'R':
+			_, c, found := bytes.Cut(params, []byte(";"))
+			if !found {
+				continue
+			}
+			if n, err := strconv.Atoi(string(c)); err == nil {
+				col = n
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package width
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReply(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		reply string
+		col   int
+		done  bool
+	}{
+		{"narrow", "\x1b[12;2R\x1b[?62;22c", 2, true},
+		{"wide", "\x1b[1;3R\x1b[?1;2c", 3, true},
+		{"partial", "\x1b[1;3R\x1b[?1", 3, false},
+		{"no position", "\x1b[?62;4c", 0, true},
+		{"empty", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			col, done := parseReply([]byte(tt.reply))
+			require.Equal(t, tt.col, col)
+			require.Equal(t, tt.done, done)
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	t.Cleanup(func() { Set(false) })
+
+	Set(true)
+	require.Equal(t, 2, ansi.StringWidth(probe))
+	require.Equal(t, 2, ansi.WcWidth.StringWidth(probe))
+	require.Equal(t, 2, ansi.StringWidth("漢"))
+
+	Set(false)
+	require.Equal(t, 1, ansi.StringWidth(probe))
+	require.Equal(t, 1, ansi.WcWidth.StringWidth(probe))
+	require.Equal(t, 2, ansi.WcWidth.StringWidth("漢"))
+}
+
+func TestApply(t *testing.T) {
+	t.Cleanup(func() { Set(false) })
+
+	require.True(t, Apply(ModeWide))
+	require.Equal(t, 2, ansi.StringWidth(probe))
+	require.False(t, Apply(ModeNarrow))
+	require.Equal(t, 1, ansi.StringWidth(probe))
+}